@@ -0,0 +1,50 @@
+/*
+ Copyright 2021 The Crossplane Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package main is a tool that compares the API types generated by terrajet in
+// two apis directories, e.g. before and after bumping the version of the
+// Terraform provider, and reports the breaking changes.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/crossplane/terrajet/pkg/pipeline"
+)
+
+func main() {
+	failOnBreaking := flag.Bool("fail-on-breaking", false, "Exit with a non-zero code if breaking changes are found.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <previous apis directory> <next apis directory>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	d, err := pipeline.CompareAPIs(flag.Arg(0), flag.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot compare APIs: %s\n", err)
+		os.Exit(1)
+	}
+	d.Print(os.Stdout)
+	if *failOnBreaking && len(d.Breaking()) != 0 {
+		os.Exit(1)
+	}
+}
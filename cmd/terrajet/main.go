@@ -0,0 +1,144 @@
+/*
+ Copyright 2021 The Crossplane Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package main is the terrajet command line tool. Its "init provider"
+// subcommand scaffolds the repository of a new terrajet-based provider and
+// its "generate" subcommand runs the code generation pipeline on the schema
+// of a Terraform provider binary.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"k8s.io/utils/exec"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/pipeline"
+	"github.com/crossplane/terrajet/pkg/scaffold"
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+const usage = `Usage: %s <command> [flags]
+
+Commands:
+  init provider   Scaffold the repository of a new provider.
+  generate        Generate the provider from the schema of a Terraform provider binary.
+`
+
+func main() {
+	switch {
+	case len(os.Args) >= 3 && os.Args[1] == "init" && os.Args[2] == "provider":
+		initProvider(os.Args[3:])
+	case len(os.Args) >= 2 && os.Args[1] == "generate":
+		generate(os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, usage, os.Args[0])
+		os.Exit(2)
+	}
+}
+
+func initProvider(args []string) {
+	fs := flag.NewFlagSet("init provider", flag.ExitOnError)
+	p := scaffold.Provider{}
+	fs.StringVar(&p.Name, "name", "", "Name of the provider in lower case, e.g. github.")
+	fs.StringVar(&p.ModulePath, "module", "", "Go module path of the provider, e.g. github.com/crossplane-contrib/provider-jet-github.")
+	fs.StringVar(&p.RootGroup, "root-group", "", "Root API group of the provider. Defaults to <name>.jet.crossplane.io.")
+	fs.StringVar(&p.ShortName, "short-name", "", "Short name of the provider. Defaults to <name>jet.")
+	fs.StringVar(&p.TerraformVersion, "terraform-version", "", "Version of the Terraform CLI the provider runs.")
+	fs.StringVar(&p.TerraformProviderSource, "terraform-provider-source", "", "Source address of the Terraform provider, e.g. integrations/github.")
+	fs.StringVar(&p.TerraformProviderVersion, "terraform-provider-version", "", "Version of the Terraform provider.")
+	fs.StringVar(&p.TerrajetVersion, "terrajet-version", "", "Version of terrajet the provider requires.")
+	fs.StringVar(&p.Copyright, "copyright", "", "Copyright holder in the license header of the Go files.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s init provider [flags] [directory]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	dir := "."
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		dir = fs.Arg(0)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	exitOnErr(p.Write(afero.NewOsFs(), dir), "cannot scaffold provider")
+	fmt.Printf("Scaffolded provider-jet-%s in %s. Run \"go mod tidy\" and \"make generate\" to generate its resources.\n", p.Name, dir)
+}
+
+func generate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	binary := fs.String("provider-binary", "", "Path of the Terraform provider binary, e.g. ./terraform-provider-github.")
+	source := fs.String("terraform-provider-source", "", "Source address of the Terraform provider, e.g. integrations/github.")
+	providerVersion := fs.String("terraform-provider-version", "", "Version of the Terraform provider.")
+	module := fs.String("module", "", "Go module path of the provider, e.g. github.com/crossplane-contrib/provider-jet-github.")
+	prefix := fs.String("prefix", "", "Prefix of the Terraform resource names. Defaults to the type of the provider in its source address.")
+	rootGroup := fs.String("root-group", "", "Root API group of the provider. Defaults to <prefix>.jet.crossplane.io.")
+	configFile := fs.String("config", "", "YAML or JSON provider configuration file applied before the generation.")
+	schemaOut := fs.String("schema-out", "", "Path to write the schema of the provider to, e.g. config/schema.json.")
+	timeout := fs.Duration("timeout", time.Minute, "Timeout of fetching the schema from the provider binary.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s generate [flags] [root directory]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if *binary == "" || *source == "" || *module == "" || fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	rootDir, err := filepath.Abs(fs.Arg(0))
+	exitOnErr(err, "cannot calculate the absolute path of the root directory")
+	if *prefix == "" {
+		*prefix = strings.TrimPrefix(path.Base(*source), "terraform-provider-")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	schema, err := terraform.GetProviderSchema(ctx, exec.New(), *binary, *source)
+	exitOnErr(err, "cannot get the schema of the provider binary")
+	if *schemaOut != "" {
+		exitOnErr(os.WriteFile(*schemaOut, schema, 0600), "cannot write the schema of the provider")
+	}
+
+	opts := []config.ProviderOption{config.WithTerraformProviderVersion(*providerVersion)}
+	if *rootGroup != "" {
+		opts = append(opts, config.WithRootGroup(*rootGroup))
+	}
+	pc := config.NewProviderWithSchema(schema, *prefix, *module, opts...)
+	pc.ConfigureResources()
+	var runOpts []pipeline.RunOption
+	if *configFile != "" {
+		runOpts = append(runOpts, pipeline.WithConfigFile(*configFile))
+	}
+	pipeline.Run(pc, rootDir, runOpts...)
+}
+
+func exitOnErr(err error, msg string) {
+	if err == nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", msg, err)
+	os.Exit(1)
+}
@@ -17,11 +17,7 @@ limitations under the License.
 package config
 
 import (
-	"strings"
-
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-
-	tjname "github.com/crossplane/terrajet/pkg/types/name"
 )
 
 // Commonly used resource configurations.
@@ -73,30 +69,19 @@ type ResourceOption func(*Resource)
 // DefaultResource keeps an initial default configuration for all resources of a
 // provider.
 func DefaultResource(name string, terraformSchema *schema.Resource, opts ...ResourceOption) *Resource {
-	words := strings.Split(name, "_")
 	// As group name we default to the second element if resource name
 	// has at least 3 elements, otherwise, we took the first element as
-	// default group name, examples:
-	// - aws_rds_cluster => rds
-	// - aws_rds_cluster_parameter_group => rds
-	// - kafka_topic => kafka
-	group := words[1]
-	// As kind, we default to camel case version of what is left after dropping
-	// elements before what is selected as group:
-	// - aws_rds_cluster => Cluster
-	// - aws_rds_cluster_parameter_group => ClusterParameterGroup
-	// - kafka_topic => Topic
-	kind := tjname.NewFromSnake(strings.Join(words[2:], "_")).Camel
-	if len(words) < 3 {
-		group = words[0]
-		kind = tjname.NewFromSnake(words[1]).Camel
-	}
-
+	// default group name, and as kind, we default to camel case version of
+	// what is left after dropping elements before what is selected as group:
+	// - aws_rds_cluster => rds, Cluster
+	// - aws_rds_cluster_parameter_group => rds, ClusterParameterGroup
+	// - kafka_topic => kafka, Topic
+	naming := DefaultKindNaming{}
 	r := &Resource{
 		Name:              name,
 		TerraformResource: terraformSchema,
-		ShortGroup:        group,
-		Kind:              kind,
+		ShortGroup:        naming.ShortGroup(name),
+		Kind:              naming.Kind(name),
 		Version:           "v1alpha1",
 		ExternalName:      NameAsIdentifier,
 		References:        map[string]Reference{},
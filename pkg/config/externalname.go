@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errFmtNoExternalNameInID = "cannot find external name in id %q using template %q"
+)
+
+var templateActionRegex = regexp.MustCompile(`{{-?\s*(.*?)\s*-?}}`)
+
+// TemplatedStringAsIdentifier accepts a template as the shape of the
+// Terraform ID and returns an ExternalName configuration that builds the ID
+// from the template and extracts the external name back from it. The
+// template can refer to the following:
+//
+//   - {{ .external_name }}: the external name of the managed resource.
+//   - {{ .parameters.<arg> }}: arguments of the resource, e.g.
+//     {{ .parameters.resource_group_name }}.
+//   - {{ .setup.configuration.<key> }}: provider configuration, e.g.
+//     {{ .setup.configuration.subscription_id }}.
+//
+// If nameFieldPath is given, the external name is set to that argument and
+// the argument is omitted from the spec. For example, the following is the
+// configuration of an azurerm resource whose name is given in "name":
+//
+//	TemplatedStringAsIdentifier("name", "/subscriptions/{{ .setup.configuration.subscription_id }}/resourceGroups/{{ .parameters.resource_group_name }}/providers/Microsoft.Sql/servers/{{ .external_name }}")
+func TemplatedStringAsIdentifier(nameFieldPath, tmpl string) ExternalName {
+	t, err := template.New("id").Parse(tmpl)
+	if err != nil {
+		panic(errors.Wrap(err, "cannot parse template"))
+	}
+	e := ExternalName{
+		SetIdentifierArgumentFn: NopSetIdentifierArgument,
+		GetIDFn: func(ctx context.Context, externalName string, parameters map[string]interface{}, providerConfig map[string]interface{}) (string, error) {
+			o := map[string]interface{}{
+				"external_name": externalName,
+				"parameters":    parameters,
+				"setup": map[string]interface{}{
+					"configuration": providerConfig,
+				},
+			}
+			b := bytes.Buffer{}
+			if err := t.Execute(&b, o); err != nil {
+				return "", errors.Wrap(err, "cannot execute template")
+			}
+			return b.String(), nil
+		},
+		GetExternalNameFn: func(tfstate map[string]interface{}) (string, error) {
+			id, ok := tfstate["id"].(string)
+			if !ok || id == "" {
+				return "", errors.New("cannot find id in tfstate")
+			}
+			return GetExternalNameFromTemplated(tmpl, id)
+		},
+	}
+	if nameFieldPath == "" {
+		e.DisableNameInitializer = true
+		return e
+	}
+	e.SetIdentifierArgumentFn = func(base map[string]interface{}, externalName string) {
+		base[nameFieldPath] = externalName
+	}
+	e.OmittedFields = []string{nameFieldPath}
+	return e
+}
+
+// GetExternalNameFromTemplated returns the external name that is used to
+// produce the given ID with the given template. It returns the ID as is if the
+// template does not refer to the external name.
+func GetExternalNameFromTemplated(tmpl, id string) (string, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	found := false
+	last := 0
+	for _, loc := range templateActionRegex.FindAllStringSubmatchIndex(tmpl, -1) {
+		expr.WriteString(regexp.QuoteMeta(tmpl[last:loc[0]]))
+		last = loc[1]
+		if tmpl[loc[2]:loc[3]] == ".external_name" && !found {
+			found = true
+			expr.WriteString("(.+?)")
+			continue
+		}
+		expr.WriteString("(?:.+?)")
+	}
+	expr.WriteString(regexp.QuoteMeta(tmpl[last:]))
+	expr.WriteString("$")
+	if !found {
+		return id, nil
+	}
+	r, err := regexp.Compile(expr.String())
+	if err != nil {
+		return "", errors.Wrap(err, "cannot compile template into regular expression")
+	}
+	m := r.FindStringSubmatch(id)
+	if m == nil {
+		return "", errors.Errorf(errFmtNoExternalNameInID, id, tmpl)
+	}
+	return m[1], nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+const azureSQLServerTemplate = "/subscriptions/{{ .setup.configuration.subscription_id }}/resourceGroups/{{ .parameters.resource_group_name }}/providers/Microsoft.Sql/servers/{{ .external_name }}"
+
+func TestTemplatedGetIDFn(t *testing.T) {
+	type args struct {
+		tmpl           string
+		externalName   string
+		parameters     map[string]interface{}
+		providerConfig map[string]interface{}
+	}
+	type want struct {
+		id  string
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NameOnly": {
+			reason: "The external name should be used as is if the template refers only to it",
+			args: args{
+				tmpl:         "{{ .external_name }}",
+				externalName: "myname",
+			},
+			want: want{
+				id: "myname",
+			},
+		},
+		"MultipleFields": {
+			reason: "Parameters and provider configuration should be used to build the ID",
+			args: args{
+				tmpl:           azureSQLServerTemplate,
+				externalName:   "myserver",
+				parameters:     map[string]interface{}{"resource_group_name": "mygroup"},
+				providerConfig: map[string]interface{}{"subscription_id": "000"},
+			},
+			want: want{
+				id: "/subscriptions/000/resourceGroups/mygroup/providers/Microsoft.Sql/servers/myserver",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := TemplatedStringAsIdentifier("name", tc.args.tmpl)
+			id, err := e.GetIDFn(context.TODO(), tc.args.externalName, tc.args.parameters, tc.args.providerConfig)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nGetIDFn(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.id, id); diff != "" {
+				t.Errorf("\n%s\nGetIDFn(...): -want id, +got id:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetExternalNameFromTemplated(t *testing.T) {
+	type args struct {
+		tmpl string
+		id   string
+	}
+	type want struct {
+		name string
+		err  error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NoExternalName": {
+			reason: "The ID should be returned as is if the template does not refer to the external name",
+			args: args{
+				tmpl: "{{ .parameters.bucket }}",
+				id:   "mybucket",
+			},
+			want: want{
+				name: "mybucket",
+			},
+		},
+		"Suffix": {
+			reason: "The external name at the end of the ID should be extracted",
+			args: args{
+				tmpl: azureSQLServerTemplate,
+				id:   "/subscriptions/000/resourceGroups/mygroup/providers/Microsoft.Sql/servers/myserver",
+			},
+			want: want{
+				name: "myserver",
+			},
+		},
+		"Middle": {
+			reason: "The external name surrounded by separators should be extracted",
+			args: args{
+				tmpl: "{{ .parameters.project }}:{{ .external_name }}:{{ .parameters.region }}",
+				id:   "myproject:myname:us-east1",
+			},
+			want: want{
+				name: "myname",
+			},
+		},
+		"NoMatch": {
+			reason: "An error should be returned if the ID is not in the shape of the template",
+			args: args{
+				tmpl: "projects/{{ .parameters.project }}/topics/{{ .external_name }}",
+				id:   "mytopic",
+			},
+			want: want{
+				err: errors.Errorf(errFmtNoExternalNameInID, "mytopic", "projects/{{ .parameters.project }}/topics/{{ .external_name }}"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := GetExternalNameFromTemplated(tc.args.tmpl, tc.args.id)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nGetExternalNameFromTemplated(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.name, got); diff != "" {
+				t.Errorf("\n%s\nGetExternalNameFromTemplated(...): -want name, +got name:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
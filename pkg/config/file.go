@@ -0,0 +1,218 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ExternalNameTypeNameAsIdentifier configures NameAsIdentifier.
+	ExternalNameTypeNameAsIdentifier = "NameAsIdentifier"
+	// ExternalNameTypeIdentifierFromProvider configures
+	// IdentifierFromProvider.
+	ExternalNameTypeIdentifierFromProvider = "IdentifierFromProvider"
+	// ExternalNameTypeTemplated configures TemplatedStringAsIdentifier.
+	ExternalNameTypeTemplated = "Templated"
+
+	errFmtUnknownExternalNameType = "unknown external name type %q"
+	errFmtNoResource              = "resource %s is not found in the provider"
+)
+
+// FileConfig is the declarative configuration of a provider that can be
+// stored in a YAML or JSON file and applied on top of the configuration
+// expressed in Go.
+type FileConfig struct {
+	// SkipList is a list of regular expressions matching the names of the
+	// Terraform resources that will be skipped during code generation.
+	SkipList []string `json:"skipList,omitempty"`
+
+	// Resources is the configuration of resources keyed by their Terraform
+	// resource names.
+	Resources map[string]FileResource `json:"resources,omitempty"`
+}
+
+// FileResource is the declarative configuration of a resource. Only the
+// given fields override the configuration of the resource.
+type FileResource struct {
+	Kind                string                   `json:"kind,omitempty"`
+	ShortGroup          string                   `json:"shortGroup,omitempty"`
+	Version             string                   `json:"version,omitempty"`
+	UseAsync            *bool                    `json:"useAsync,omitempty"`
+	ExternalName        *FileExternalName        `json:"externalName,omitempty"`
+	References          map[string]FileReference `json:"references,omitempty"`
+	LateInitializer     *FileLateInitializer     `json:"lateInitializer,omitempty"`
+	PrinterColumns      []FilePrinterColumn      `json:"printerColumns,omitempty"`
+	ObservationListCaps map[string]int           `json:"observationListCaps,omitempty"`
+}
+
+// FileExternalName is the declarative configuration of the external name of
+// a resource.
+type FileExternalName struct {
+	// Type is one of NameAsIdentifier, IdentifierFromProvider or Templated.
+	Type string `json:"type"`
+	// NameFieldPath is the argument that the external name is assigned to if
+	// the type is Templated.
+	NameFieldPath string `json:"nameFieldPath,omitempty"`
+	// Template is used to build the Terraform ID if the type is Templated.
+	Template string `json:"template,omitempty"`
+	// OmittedFields are the arguments omitted from the generated spec in
+	// addition to the ones omitted by the type.
+	OmittedFields []string `json:"omittedFields,omitempty"`
+}
+
+// FileReference is the declarative configuration of a cross resource
+// reference.
+type FileReference struct {
+	Type              string `json:"type"`
+	Extractor         string `json:"extractor,omitempty"`
+	RefFieldName      string `json:"refFieldName,omitempty"`
+	SelectorFieldName string `json:"selectorFieldName,omitempty"`
+	// ExtractorFieldPath and ExtractFromObservation configure the generated
+	// extractor if Extractor is not given.
+	ExtractorFieldPath     string `json:"extractorFieldPath,omitempty"`
+	ExtractFromObservation bool   `json:"extractFromObservation,omitempty"`
+}
+
+// FileLateInitializer is the declarative configuration of the
+// late-initialization behaviour of a resource.
+type FileLateInitializer struct {
+	IgnoredFields []string          `json:"ignoredFields,omitempty"`
+	ListMergeKeys map[string]string `json:"listMergeKeys,omitempty"`
+}
+
+// FilePrinterColumn is the declarative configuration of a printer column.
+type FilePrinterColumn struct {
+	Name      string `json:"name"`
+	FieldPath string `json:"fieldPath"`
+	Type      string `json:"type,omitempty"`
+}
+
+// LoadFileConfig reads the provider configuration file in the given path. The
+// file can be in either YAML or JSON format.
+func LoadFileConfig(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read provider configuration file")
+	}
+	return ParseFileConfig(data)
+}
+
+// ParseFileConfig parses the given provider configuration in either YAML or
+// JSON format. Unknown fields are rejected so that typos do not go unnoticed.
+func ParseFileConfig(data []byte) (*FileConfig, error) {
+	fc := &FileConfig{}
+	if err := yaml.UnmarshalStrict(data, fc); err != nil {
+		return nil, errors.Wrap(err, "cannot parse provider configuration")
+	}
+	return fc, nil
+}
+
+// Configure applies the configuration on the given Provider. Resources
+// matching SkipList are removed and the configuration of every listed
+// resource overrides its existing configuration.
+func (fc *FileConfig) Configure(p *Provider) error {
+	for name := range p.Resources {
+		if matches(name, fc.SkipList) {
+			p.skip(name, SkipReasonSkipList)
+		}
+	}
+	for name, fr := range fc.Resources {
+		r, ok := p.Resources[name]
+		if !ok {
+			if matches(name, fc.SkipList) {
+				continue
+			}
+			return errors.Errorf(errFmtNoResource, name)
+		}
+		if err := fr.configure(r); err != nil {
+			return errors.Wrapf(err, "cannot configure resource %s", name)
+		}
+	}
+	return nil
+}
+
+func (fr FileResource) configure(r *Resource) error { // nolint:gocyclo
+	if fr.Kind != "" {
+		r.Kind = fr.Kind
+	}
+	if fr.ShortGroup != "" {
+		r.ShortGroup = fr.ShortGroup
+	}
+	if fr.Version != "" {
+		r.Version = fr.Version
+	}
+	if fr.UseAsync != nil {
+		r.UseAsync = *fr.UseAsync
+	}
+	if fr.ExternalName != nil {
+		en, err := fr.ExternalName.externalName()
+		if err != nil {
+			return err
+		}
+		r.ExternalName = en
+	}
+	for field, ref := range fr.References {
+		if r.References == nil {
+			r.References = References{}
+		}
+		r.References[field] = Reference{
+			Type:                   ref.Type,
+			Extractor:              ref.Extractor,
+			ExtractorFieldPath:     ref.ExtractorFieldPath,
+			ExtractFromObservation: ref.ExtractFromObservation,
+			RefFieldName:           ref.RefFieldName,
+			SelectorFieldName:      ref.SelectorFieldName,
+		}
+	}
+	if fr.LateInitializer != nil {
+		r.LateInitializer.IgnoredFields = fr.LateInitializer.IgnoredFields
+		r.LateInitializer.ListMergeKeys = fr.LateInitializer.ListMergeKeys
+	}
+	for _, pc := range fr.PrinterColumns {
+		r.PrinterColumns = append(r.PrinterColumns, PrinterColumn(pc))
+	}
+	for attr, c := range fr.ObservationListCaps {
+		if r.ObservationListCaps == nil {
+			r.ObservationListCaps = map[string]int{}
+		}
+		r.ObservationListCaps[attr] = c
+	}
+	return nil
+}
+
+func (fe FileExternalName) externalName() (ExternalName, error) {
+	var en ExternalName
+	switch fe.Type {
+	case ExternalNameTypeNameAsIdentifier:
+		en = NameAsIdentifier
+	case ExternalNameTypeIdentifierFromProvider:
+		en = IdentifierFromProvider
+	case ExternalNameTypeTemplated:
+		en = TemplatedStringAsIdentifier(fe.NameFieldPath, fe.Template)
+	default:
+		return ExternalName{}, errors.Errorf(errFmtUnknownExternalNameType, fe.Type)
+	}
+	if len(fe.OmittedFields) != 0 {
+		en.OmittedFields = append(append([]string{}, en.OmittedFields...), fe.OmittedFields...)
+	}
+	return en, nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+)
+
+func TestFileConfigConfigure(t *testing.T) {
+	type want struct {
+		resources map[string]*Resource
+		err       error
+	}
+	cases := map[string]struct {
+		reason string
+		config string
+		want
+	}{
+		"Success": {
+			reason: "Resources matching the skip list should be removed and the listed resources should be configured",
+			config: `
+skipList:
+  - "^aws_skipped$"
+resources:
+  aws_vpc:
+    kind: VPC
+    shortGroup: ec2
+    useAsync: true
+    externalName:
+      type: IdentifierFromProvider
+    references:
+      ipv4_ipam_pool_id:
+        type: IPAMPool
+`,
+			want: want{
+				resources: map[string]*Resource{
+					"aws_vpc": {
+						Name:         "aws_vpc",
+						Kind:         "VPC",
+						ShortGroup:   "ec2",
+						UseAsync:     true,
+						ExternalName: IdentifierFromProvider,
+						References: References{
+							"ipv4_ipam_pool_id": {Type: "IPAMPool"},
+						},
+					},
+				},
+			},
+		},
+		"JSON": {
+			reason: "Configuration in JSON format should be accepted",
+			config: `{"resources": {"aws_vpc": {"version": "v1beta1"}}}`,
+			want: want{
+				resources: map[string]*Resource{
+					"aws_vpc":     {Name: "aws_vpc", Version: "v1beta1"},
+					"aws_skipped": {Name: "aws_skipped"},
+				},
+			},
+		},
+		"UnknownResource": {
+			reason: "An error should be returned if a configured resource does not exist",
+			config: `{"resources": {"aws_subnet": {"kind": "Subnet"}}}`,
+			want: want{
+				err: errors.Errorf(errFmtNoResource, "aws_subnet"),
+			},
+		},
+		"UnknownExternalName": {
+			reason: "An error should be returned if the external name type is not known",
+			config: `{"resources": {"aws_vpc": {"externalName": {"type": "Unknown"}}}}`,
+			want: want{
+				err: errors.Wrapf(errors.Errorf(errFmtUnknownExternalNameType, "Unknown"), "cannot configure resource %s", "aws_vpc"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fc, err := ParseFileConfig([]byte(tc.config))
+			if err != nil {
+				t.Fatalf("\n%s\nParseFileConfig(...): unexpected error: %s", tc.reason, err)
+			}
+			p := &Provider{Resources: map[string]*Resource{
+				"aws_vpc":     {Name: "aws_vpc"},
+				"aws_skipped": {Name: "aws_skipped"},
+			}}
+			err = fc.Configure(p)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nConfigure(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.resources, p.Resources, cmpopts.IgnoreFields(ExternalName{}, "SetIdentifierArgumentFn", "GetExternalNameFn", "GetIDFn"), cmpopts.IgnoreUnexported(Sensitive{}, LateInitializer{})); diff != "" {
+				t.Errorf("\n%s\nConfigure(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestParseFileConfigUnknownField(t *testing.T) {
+	if _, err := ParseFileConfig([]byte(`{"resources": {"aws_vpc": {"kidn": "VPC"}}}`)); err == nil {
+		t.Errorf("ParseFileConfig(...): expected an error for an unknown field")
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"go/types"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// GeneratedType is a type generated for a resource that is passed to the
+// TypeHooks of the provider.
+type GeneratedType struct {
+	// Named is the generated type. Its underlying struct can be replaced
+	// with Named.SetUnderlying to add, remove or change fields.
+	*types.Named
+
+	// Markers are added to the comment of the type, e.g.
+	// "+kubebuilder:validation:XValidation:rule=...".
+	Markers []string
+
+	// Extra is the Go source code printed after the generated types, e.g.
+	// methods attached to the type.
+	Extra string
+}
+
+// TypeHook is called for every type generated for a resource along with the
+// Terraform schema of the resource so that provider specific changes can be
+// made on the generated types without forking the templates of terrajet.
+type TypeHook func(t *GeneratedType, r *schema.Resource) error
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sort"
+	"strings"
+
+	tjname "github.com/crossplane/terrajet/pkg/types/name"
+)
+
+// KindNamingStrategy derives the names of the generated kinds and CRDs from
+// the names of the Terraform resources. It can be configured for providers
+// whose resource names do not produce clean kinds with the default
+// derivation, e.g. so that aws_cloudwatch_log_group is the Group kind in the
+// cloudwatchlogs group instead of the LogGroup kind in the cloudwatch group.
+type KindNamingStrategy interface {
+	// ShortGroup returns the short API group of the given Terraform
+	// resource, e.g. "rds" for aws_rds_cluster.
+	ShortGroup(resourceName string) string
+	// Kind returns the kind of the given Terraform resource, e.g. "Cluster"
+	// for aws_rds_cluster.
+	Kind(resourceName string) string
+	// Plural returns the plural of the given kind in lower case that is used
+	// as the resource name of its CRD, e.g. "clusters" for Cluster.
+	Plural(kind string) string
+}
+
+// DefaultKindNaming is the KindNamingStrategy that terrajet uses by default.
+// Unless configured otherwise, the second word of a Terraform resource name is
+// its short group and the rest is its kind if the name has at least three
+// words, e.g. aws_rds_cluster_parameter_group is the ClusterParameterGroup
+// kind in the rds group. Otherwise, the first word is the short group, e.g.
+// kafka_topic is the Topic kind in the kafka group. The words of the kinds
+// are cased with the known acronyms, see name.AddAcronym.
+type DefaultKindNaming struct {
+	// GroupPrefixes maps the prefixes of the Terraform resource names to the
+	// short groups of their kinds, e.g. "aws_cloudwatch_log_" to
+	// "cloudwatchlogs". The kind is derived from the rest of the name. The
+	// longest matching prefix is used.
+	GroupPrefixes map[string]string
+
+	// Plurals maps the lower case kinds whose plurals are irregular to their
+	// plurals, e.g. "index" to "indices".
+	Plurals map[string]string
+}
+
+// ShortGroup returns the short API group of the given Terraform resource.
+func (d DefaultKindNaming) ShortGroup(resourceName string) string {
+	group, _ := d.split(resourceName)
+	return group
+}
+
+// Kind returns the kind of the given Terraform resource.
+func (d DefaultKindNaming) Kind(resourceName string) string {
+	_, kind := d.split(resourceName)
+	return tjname.NewFromSnake(kind).Camel
+}
+
+// Plural returns the plural of the given kind in lower case the same way the
+// CRD generator produces the resource name for the common cases.
+func (d DefaultKindNaming) Plural(kind string) string {
+	kind = strings.ToLower(kind)
+	if p, ok := d.Plurals[kind]; ok {
+		return p
+	}
+	switch {
+	case strings.HasSuffix(kind, "s"), strings.HasSuffix(kind, "x"), strings.HasSuffix(kind, "z"),
+		strings.HasSuffix(kind, "ch"), strings.HasSuffix(kind, "sh"):
+		return kind + "es"
+	case strings.HasSuffix(kind, "y") && len(kind) > 1 && !strings.ContainsAny(kind[len(kind)-2:len(kind)-1], "aeiou"):
+		return kind[:len(kind)-1] + "ies"
+	}
+	return kind + "s"
+}
+
+// split returns the short group of the given Terraform resource and the
+// snake case remainder of its name the kind is derived from.
+func (d DefaultKindNaming) split(resourceName string) (string, string) {
+	prefixes := make([]string, 0, len(d.GroupPrefixes))
+	for p := range d.GroupPrefixes {
+		if strings.HasPrefix(resourceName, p) && len(resourceName) > len(p) {
+			prefixes = append(prefixes, p)
+		}
+	}
+	if len(prefixes) != 0 {
+		sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+		return d.GroupPrefixes[prefixes[0]], strings.TrimPrefix(resourceName, prefixes[0])
+	}
+	words := strings.Split(resourceName, "_")
+	if len(words) < 3 {
+		return words[0], strings.Join(words[1:], "_")
+	}
+	return words[1], strings.Join(words[2:], "_")
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestDefaultKindNaming(t *testing.T) {
+	type want struct {
+		shortGroup string
+		kind       string
+		plural     string
+	}
+	cases := map[string]struct {
+		reason   string
+		naming   DefaultKindNaming
+		resource string
+		want
+	}{
+		"ThreeWords": {
+			reason:   "The second word should be the group and the rest should be the kind",
+			resource: "aws_rds_cluster_parameter_group",
+			want: want{
+				shortGroup: "rds",
+				kind:       "ClusterParameterGroup",
+				plural:     "clusterparametergroups",
+			},
+		},
+		"TwoWords": {
+			reason:   "The first word should be the group if there are only two words",
+			resource: "kafka_topic",
+			want: want{
+				shortGroup: "kafka",
+				kind:       "Topic",
+				plural:     "topics",
+			},
+		},
+		"Acronym": {
+			reason:   "Known acronyms should be upper case in the kind",
+			resource: "aws_wafv2_web_acl",
+			want: want{
+				shortGroup: "wafv2",
+				kind:       "WebACL",
+				plural:     "webacls",
+			},
+		},
+		"GroupPrefix": {
+			reason: "The group of the longest matching prefix should be used and the kind should be derived from the rest",
+			naming: DefaultKindNaming{
+				GroupPrefixes: map[string]string{
+					"aws_cloudwatch_":     "cloudwatch",
+					"aws_cloudwatch_log_": "cloudwatchlogs",
+				},
+			},
+			resource: "aws_cloudwatch_log_group",
+			want: want{
+				shortGroup: "cloudwatchlogs",
+				kind:       "Group",
+				plural:     "groups",
+			},
+		},
+		"IrregularPlural": {
+			reason: "The configured plural of a kind should be used",
+			naming: DefaultKindNaming{
+				Plurals: map[string]string{"index": "indices"},
+			},
+			resource: "algolia_index",
+			want: want{
+				shortGroup: "algolia",
+				kind:       "Index",
+				plural:     "indices",
+			},
+		},
+		"RegularPlurals": {
+			reason:   "Kinds ending with a consonant and y should be pluralized with ies",
+			resource: "aws_iam_policy",
+			want: want{
+				shortGroup: "iam",
+				kind:       "Policy",
+				plural:     "policies",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{
+				shortGroup: tc.naming.ShortGroup(tc.resource),
+				kind:       tc.naming.Kind(tc.resource),
+			}
+			got.plural = tc.naming.Plural(got.kind)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nDefaultKindNaming: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewProviderKindNaming(t *testing.T) {
+	resourceMap := map[string]*schema.Resource{
+		"aws_cloudwatch_log_group": {Schema: map[string]*schema.Schema{"name": {Type: schema.TypeString}}},
+	}
+	p := NewProvider(resourceMap, "aws", "github.com/crossplane-contrib/provider-jet-aws",
+		WithKindNaming(DefaultKindNaming{GroupPrefixes: map[string]string{"aws_cloudwatch_log_": "cloudwatchlogs"}}))
+	r := p.Resources["aws_cloudwatch_log_group"]
+	if diff := cmp.Diff([]string{"cloudwatchlogs", "Group"}, []string{r.ShortGroup, r.Kind}); diff != "" {
+		t.Errorf("NewProvider(...): -want short group and kind, +got:\n%s", diff)
+	}
+}
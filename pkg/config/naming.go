@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+)
+
+// NamingStrategy returns the external name of a resource whose name is chosen
+// client-side, i.e. given as an argument of the Terraform resource. It is
+// called only when the resource does not have an external name yet and the
+// returned name is stored in the external-name annotation before the first
+// apply, from which it is set to the identifier argument of the resource by
+// SetIdentifierArgumentFn.
+type NamingStrategy func(mg xpresource.Managed) (string, error)
+
+// NameFromMetadata is a NamingStrategy that uses metadata.name as the
+// external name.
+func NameFromMetadata() NamingStrategy {
+	return func(mg xpresource.Managed) (string, error) {
+		return mg.GetName(), nil
+	}
+}
+
+// NameWithUIDSuffix is a NamingStrategy that uses metadata.name suffixed with
+// the first n characters of the UID of the resource as the external name,
+// e.g. "my-bucket-1f9a3c2e". It can be used for resources whose names need to
+// be globally unique.
+func NameWithUIDSuffix(n int) NamingStrategy {
+	return func(mg xpresource.Managed) (string, error) {
+		uid := strings.ReplaceAll(string(mg.GetUID()), "-", "")
+		if uid == "" {
+			return "", errors.New("cannot use the UID of the resource as suffix: UID is empty")
+		}
+		if n > 0 && n < len(uid) {
+			uid = uid[:n]
+		}
+		return mg.GetName() + "-" + uid, nil
+	}
+}
+
+// NameFromTemplate is a NamingStrategy that renders the given Go template to
+// produce the external name. The template can refer to the following:
+//
+//   - {{ .name }}: metadata.name of the resource.
+//   - {{ .namespace }}: metadata.namespace of the resource.
+//   - {{ .uid }}: metadata.uid of the resource.
+//   - {{ .labels.<key> }}: a label of the resource.
+//   - {{ .annotations.<key> }}: an annotation of the resource.
+//
+// For example, NameFromTemplate(`{{ .labels.env }}-{{ .name }}`).
+func NameFromTemplate(tmpl string) NamingStrategy {
+	t, err := template.New("name").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		panic(errors.Wrap(err, "cannot parse template"))
+	}
+	return func(mg xpresource.Managed) (string, error) {
+		o := map[string]interface{}{
+			"name":        mg.GetName(),
+			"namespace":   mg.GetNamespace(),
+			"uid":         string(mg.GetUID()),
+			"labels":      mg.GetLabels(),
+			"annotations": mg.GetAnnotations(),
+		}
+		b := bytes.Buffer{}
+		if err := t.Execute(&b, o); err != nil {
+			return "", errors.Wrap(err, "cannot execute template")
+		}
+		return b.String(), nil
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	mg := &fake.Managed{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "my-bucket",
+			UID:    "1f9a3c2e-0b4d-4e6f-8a9b-0c1d2e3f4a5b",
+			Labels: map[string]string{"env": "prod"},
+		},
+	}
+	type want struct {
+		name string
+		err  error
+	}
+	cases := map[string]struct {
+		reason   string
+		strategy NamingStrategy
+		mg       *fake.Managed
+		want
+	}{
+		"Metadata": {
+			reason:   "metadata.name should be used as is",
+			strategy: NameFromMetadata(),
+			mg:       mg,
+			want: want{
+				name: "my-bucket",
+			},
+		},
+		"UIDSuffix": {
+			reason:   "metadata.name should be suffixed with the given number of UID characters",
+			strategy: NameWithUIDSuffix(8),
+			mg:       mg,
+			want: want{
+				name: "my-bucket-1f9a3c2e",
+			},
+		},
+		"NoUID": {
+			reason:   "An error should be returned if the resource has no UID",
+			strategy: NameWithUIDSuffix(8),
+			mg:       &fake.Managed{ObjectMeta: metav1.ObjectMeta{Name: "my-bucket"}},
+			want: want{
+				err: errors.New("cannot use the UID of the resource as suffix: UID is empty"),
+			},
+		},
+		"Template": {
+			reason:   "The template should be rendered with the metadata of the resource",
+			strategy: NameFromTemplate("{{ .labels.env }}-{{ .name }}"),
+			mg:       mg,
+			want: want{
+				name: "prod-my-bucket",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.strategy(tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nNamingStrategy(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.name, got); diff != "" {
+				t.Errorf("\n%s\nNamingStrategy(...): -want name, +got name:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -66,6 +66,12 @@ type Provider struct {
 	// categories, see: https://kubernetes.io/docs/tasks/extend-kubernetes/custom-resources/custom-resource-definitions/#categories
 	ShortName string
 
+	// TerraformProviderVersion is the version of the Terraform provider whose
+	// schema is used for generation. If set, it is recorded on the generated
+	// CRDs along with the Terraform resource type. The annotations are
+	// emitted as a marker that requires controller-gen v0.9.0 or later.
+	TerraformProviderVersion string
+
 	// ModulePath is the go module path for the Crossplane provider repo, e.g.
 	// "github.com/crossplane-contrib/provider-jet-aws"
 	ModulePath string
@@ -96,11 +102,33 @@ type Provider struct {
 	// resource name.
 	Resources map[string]*Resource
 
+	// TypeHooks are called in the given order for every type generated for
+	// the resources of this Provider.
+	TypeHooks []TypeHook
+
+	// KindNaming derives the short groups and kinds of the resources and the
+	// resource names of their CRDs if set. It takes precedence over the
+	// short group and kind set by DefaultResourceFn, while the resource
+	// configurators can still override them per resource. If it is not set,
+	// the resource names of the CRDs are left to the CRD generator.
+	KindNaming KindNamingStrategy
+
+	// SkippedResources are the Terraform resources that are not generated
+	// keyed by their names with the reason they are skipped as value.
+	SkippedResources map[string]string
+
 	// resourceConfigurators is a map holding resource configurators where key
 	// is Terraform resource name.
 	resourceConfigurators map[string]ResourceConfiguratorChain
 }
 
+// Reasons of skipping the generation of a resource.
+const (
+	SkipReasonNoSchema    = "NoSchema"
+	SkipReasonSkipList    = "SkipList"
+	SkipReasonNotIncluded = "NotIncluded"
+)
+
 // A ProviderOption configures a Provider.
 type ProviderOption func(*Provider)
 
@@ -118,6 +146,14 @@ func WithShortName(s string) ProviderOption {
 	}
 }
 
+// WithTerraformProviderVersion configures TerraformProviderVersion for this
+// Provider.
+func WithTerraformProviderVersion(v string) ProviderOption {
+	return func(p *Provider) {
+		p.TerraformProviderVersion = v
+	}
+}
+
 // WithIncludeList configures IncludeList for this Provider.
 func WithIncludeList(l []string) ProviderOption {
 	return func(p *Provider) {
@@ -146,6 +182,20 @@ func WithDefaultResourceFn(f DefaultResourceFn) ProviderOption {
 	}
 }
 
+// WithTypeHooks configures TypeHooks for this Provider.
+func WithTypeHooks(h ...TypeHook) ProviderOption {
+	return func(p *Provider) {
+		p.TypeHooks = h
+	}
+}
+
+// WithKindNaming configures the KindNamingStrategy of this Provider.
+func WithKindNaming(n KindNamingStrategy) ProviderOption {
+	return func(p *Provider) {
+		p.KindNaming = n
+	}
+}
+
 // NewProviderWithSchema builds and returns a new Provider from provider
 // tfjson schema, that is generated using Terraform CLI with:
 // `terraform providers schema --json`
@@ -181,6 +231,7 @@ func NewProvider(resourceMap map[string]*schema.Resource, prefix string, moduleP
 			".+",
 		},
 		Resources:             map[string]*Resource{},
+		SkippedResources:      map[string]string{},
 		resourceConfigurators: map[string]ResourceConfiguratorChain{},
 	}
 
@@ -192,17 +243,25 @@ func NewProvider(resourceMap map[string]*schema.Resource, prefix string, moduleP
 		if len(terraformResource.Schema) == 0 {
 			// There are resources with no schema, that we will address later.
 			fmt.Printf("Skipping resource %s because it has no schema\n", name)
+			p.skip(name, SkipReasonNoSchema)
 			continue
 		}
 		if matches(name, p.SkipList) {
 			fmt.Printf("Skipping resource %s because it is in SkipList\n", name)
+			p.skip(name, SkipReasonSkipList)
 			continue
 		}
 		if !matches(name, p.IncludeList) {
+			p.skip(name, SkipReasonNotIncluded)
 			continue
 		}
 
-		p.Resources[name] = p.DefaultResourceFn(name, terraformResource)
+		r := p.DefaultResourceFn(name, terraformResource)
+		if p.KindNaming != nil {
+			r.ShortGroup = p.KindNaming.ShortGroup(name)
+			r.Kind = p.KindNaming.Kind(name)
+		}
+		p.Resources[name] = r
 	}
 
 	return p
@@ -233,6 +292,36 @@ func (p *Provider) ConfigureResources() {
 	}
 }
 
+// FilterResources removes the resources whose names do not match any of the
+// regular expressions in include or match any of the ones in skip. An empty
+// include list includes all resources. It returns an error if any of the
+// expressions is invalid.
+func (p *Provider) FilterResources(include, skip []string) error {
+	for _, r := range append(append([]string{}, include...), skip...) {
+		if _, err := regexp.Compile(r); err != nil {
+			return errors.Wrapf(err, "invalid regular expression %q", r)
+		}
+	}
+	for name := range p.Resources {
+		switch {
+		case len(include) != 0 && !matches(name, include):
+			p.skip(name, SkipReasonNotIncluded)
+		case matches(name, skip):
+			p.skip(name, SkipReasonSkipList)
+		}
+	}
+	return nil
+}
+
+// skip removes the given resource and records the reason it is skipped.
+func (p *Provider) skip(name, reason string) {
+	delete(p.Resources, name)
+	if p.SkippedResources == nil {
+		p.SkippedResources = map[string]string{}
+	}
+	p.SkippedResources[name] = reason
+}
+
 func matches(name string, regexList []string) bool {
 	for _, r := range regexList {
 		ok, err := regexp.MatchString(r, name)
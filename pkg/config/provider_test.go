@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+func TestFilterResources(t *testing.T) {
+	type args struct {
+		include []string
+		skip    []string
+	}
+	type want struct {
+		names   []string
+		skipped map[string]string
+		err     error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NoFilters": {
+			reason: "All resources should be kept if no filters are given",
+			want: want{
+				names: []string{"aws_instance", "aws_vpc", "aws_waf_rule"},
+			},
+		},
+		"Include": {
+			reason: "Only the resources matching the include list should be kept",
+			args: args{
+				include: []string{"aws_vpc$", "aws_waf.*"},
+			},
+			want: want{
+				names:   []string{"aws_vpc", "aws_waf_rule"},
+				skipped: map[string]string{"aws_instance": SkipReasonNotIncluded},
+			},
+		},
+		"IncludeAndSkip": {
+			reason: "Resources matching the skip list should be removed even if they are included",
+			args: args{
+				include: []string{"aws_.*"},
+				skip:    []string{"aws_waf.*"},
+			},
+			want: want{
+				names:   []string{"aws_instance", "aws_vpc"},
+				skipped: map[string]string{"aws_waf_rule": SkipReasonSkipList},
+			},
+		},
+		"InvalidExpression": {
+			reason: "An error should be returned if an expression is invalid",
+			args: args{
+				skip: []string{"aws_(waf"},
+			},
+			want: want{
+				names: []string{"aws_instance", "aws_vpc", "aws_waf_rule"},
+				err:   errors.Wrapf(errors.New("error parsing regexp: missing closing ): `aws_(waf`"), "invalid regular expression %q", "aws_(waf"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := &Provider{
+				Resources: map[string]*Resource{
+					"aws_instance": {},
+					"aws_vpc":      {},
+					"aws_waf_rule": {},
+				},
+			}
+			err := p.FilterResources(tc.args.include, tc.args.skip)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nFilterResources(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			names := make([]string, 0, len(p.Resources))
+			for n := range p.Resources {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			if diff := cmp.Diff(tc.want.names, names); diff != "" {
+				t.Errorf("\n%s\nFilterResources(...): -want resources, +got resources:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.skipped, p.SkippedResources); diff != "" {
+				t.Errorf("\n%s\nFilterResources(...): -want skipped, +got skipped:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package config
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -32,6 +33,16 @@ import (
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 )
 
+const (
+	// ExtractResourceIDFuncPath is the path of the extractor function that
+	// extracts the Terraform ID of the referenced resource.
+	ExtractResourceIDFuncPath = "github.com/crossplane/terrajet/pkg/resource.ExtractResourceID()"
+	// ExtractParamPathFuncPath is the path of the extractor function that
+	// extracts the value of a Terraform field path of the referenced
+	// resource.
+	ExtractParamPathFuncPath = "github.com/crossplane/terrajet/pkg/resource.ExtractParamPath"
+)
+
 // SetIdentifierArgumentsFn sets the name of the resource in Terraform attributes map,
 // i.e. Main HCL file.
 type SetIdentifierArgumentsFn func(base map[string]interface{}, externalName string)
@@ -90,6 +101,16 @@ type ExternalName struct {
 	// name if it was not set already.
 	GetExternalNameFn GetExternalNameFn
 
+	// DisableNameFromState disables writing the external name returned by
+	// GetExternalNameFn back to the external-name annotation after the
+	// resource is created and every time it's observed. By default, the
+	// external name is set from the state so that resources whose identifier
+	// is assigned by the provider, or differs from their name, are
+	// identifiable and importable. It needs to be enabled for resources whose
+	// state doesn't carry their identifier, in which case the external name is
+	// left as it is.
+	DisableNameFromState bool
+
 	// GetIDFn returns the string that will be used as "id" key in TF state. In
 	// many cases, external name format is the same as "id" but when it is not
 	// we may need information from other places to construct it. For example,
@@ -114,6 +135,14 @@ type ExternalName struct {
 	// assigned by the provider, like AWS VPC where it gets vpc-21kn123 identifier
 	// and not let you name it.
 	DisableNameInitializer bool
+
+	// NamingStrategy produces the external name of the resource before it is
+	// created if it does not have one yet. It replaces the name initializer
+	// that sets the external name to metadata.name and is meant for resources
+	// whose names are chosen client-side but need a different format, e.g. a
+	// suffix to make them globally unique. It is not used if
+	// DisableNameInitializer is set.
+	NamingStrategy NamingStrategy
 }
 
 // References represents reference resolver configurations for the fields of a
@@ -130,6 +159,16 @@ type Reference struct {
 	// referenced type. Defaults to getting external name.
 	// Optional
 	Extractor string
+	// ExtractorFieldPath is the Terraform field path of the referenced type
+	// whose value is extracted, e.g. "arn". If set, an extractor reading the
+	// field is generated unless Extractor is set.
+	// Optional
+	ExtractorFieldPath string
+	// ExtractFromObservation makes the generated extractor read the value of
+	// ExtractorFieldPath from the observation of the referenced type instead
+	// of its parameters.
+	// Optional
+	ExtractFromObservation bool
 	// RefFieldName is the field name for the Reference field. Defaults to
 	// <field-name>Ref or <field-name>Refs.
 	// Optional
@@ -140,6 +179,21 @@ type Reference struct {
 	SelectorFieldName string
 }
 
+// ExtractorFunc returns the extractor function expression to be used in the
+// reference marker of the field. It returns an empty string if the external
+// name of the referenced type is to be used.
+func (r Reference) ExtractorFunc() string {
+	switch {
+	case r.Extractor != "":
+		return r.Extractor
+	case r.ExtractorFieldPath == "id" && r.ExtractFromObservation:
+		return ExtractResourceIDFuncPath
+	case r.ExtractorFieldPath != "":
+		return fmt.Sprintf("%s(%q,%t)", ExtractParamPathFuncPath, r.ExtractorFieldPath, r.ExtractFromObservation)
+	}
+	return ""
+}
+
 // Sensitive represents configurations to handle sensitive information
 type Sensitive struct {
 	// AdditionalConnectionDetailsFn is the path for function adding additional
@@ -161,6 +215,14 @@ type LateInitializer struct {
 	// "block_device_mappings.ebs".
 	IgnoredFields []string
 
+	// ListMergeKeys are the key fields of the elements of the lists keyed by
+	// the Terraform field paths of the lists, e.g. "name" for
+	// "ingress_rule". By default, a list is late-initialized only if it is
+	// empty. The elements of a list that has a key field are instead merged
+	// one by one with the observed elements that have the same key and the
+	// observed elements without a match are appended to the list.
+	ListMergeKeys map[string]string
+
 	// ignoredCanonicalFieldPaths are the Canonical field paths to be skipped
 	// during late-initialization. This is filled using the `IgnoredFields`
 	// field which keeps Terraform paths by converting them to Canonical paths.
@@ -203,6 +265,106 @@ type OperationTimeouts struct {
 	Delete time.Duration
 }
 
+// PrinterColumn is an additional column printed in "kubectl get" output for
+// a spec field of the resource.
+type PrinterColumn struct {
+	// Name is the header of the column, e.g. "INSTANCE-TYPE".
+	Name string
+	// FieldPath is the Terraform path of the argument to be printed with its
+	// segments concatenated with dots, e.g. "instance_type".
+	FieldPath string
+	// Type is the OpenAPI type of the column. Defaults to "string".
+	// Optional
+	Type string
+}
+
+// DefaultDeletionProtectionErrorPattern matches the destroy failures caused
+// by the deletion protection of the external resource in most providers.
+const DefaultDeletionProtectionErrorPattern = `(?i)deletion[ _-]?protection`
+
+// DeletionProtection configures how the deletion of resources that can be
+// protected against deletion in the cloud is handled.
+type DeletionProtection struct {
+	// FieldName is the Terraform argument that enables the deletion
+	// protection, e.g. "deletion_protection".
+	FieldName string
+	// ErrorPattern is the regular expression that matches the destroy
+	// failure messages caused by the deletion protection. Defaults to
+	// DefaultDeletionProtectionErrorPattern.
+	// Optional
+	ErrorPattern string
+}
+
+// Blocks returns whether the given destroy failure message is caused by the
+// deletion protection.
+func (d *DeletionProtection) Blocks(msg string) bool {
+	p := d.ErrorPattern
+	if p == "" {
+		p = DefaultDeletionProtectionErrorPattern
+	}
+	ok, err := regexp.MatchString(p, msg)
+	return err == nil && ok
+}
+
+// ProviderOverrides configures the parameters of a resource that Terraform
+// expects in the provider configuration instead of the resource arguments,
+// e.g. the region for providers that configure it at provider level.
+type ProviderOverrides struct {
+	// Fields maps the names of the top-level arguments of the resource to
+	// the keys of the provider configuration they are moved into, e.g.
+	// {"region": "region"}. Since the Terraform schema of the resource
+	// doesn't have these arguments, they need to be added to the schema in
+	// the resource configuration so that they are generated in the spec.
+	Fields map[string]string
+	// Alias is the alias of the provider block that is configured with the
+	// moved parameters and used by the resource. If empty, the moved
+	// parameters override the default provider configuration.
+	// Optional
+	Alias string
+}
+
+// PendingStates configures the readiness of resources whose Terraform
+// provider returns before the cloud resource finishes provisioning and
+// reports its progress in an observed attribute, e.g. "status".
+type PendingStates struct {
+	// FieldPath is the Terraform path of the observed attribute that reports
+	// the state of the resource with its segments concatenated with dots,
+	// e.g. "status" or "status[0].phase".
+	FieldPath string
+	// ReadyValues are the values of the attribute with which the resource
+	// is considered available, e.g. "ACTIVE".
+	ReadyValues []string
+}
+
+// IsReady returns whether the state attribute in the given Terraform state
+// attributes has one of the ready values, together with its current value.
+// A missing attribute is considered not ready.
+func (p *PendingStates) IsReady(attr map[string]interface{}) (bool, string) {
+	v, err := fieldpath.Pave(attr).GetValue(p.FieldPath)
+	if err != nil || v == nil {
+		return false, ""
+	}
+	s := fmt.Sprint(v)
+	for _, r := range p.ReadyValues {
+		if s == r {
+			return true, s
+		}
+	}
+	return false, s
+}
+
+// QuotaCheckFn is called before a resource is created and returns an error if
+// the creation is known to fail, e.g. because a quota or a limit of the
+// account would be exceeded. The error message should tell which quota is
+// exceeded.
+type QuotaCheckFn func(ctx context.Context, kube client.Client, mg xpresource.Managed) error
+
+// EnvFn returns the environment variables the Terraform processes of a
+// resource run with in addition to the ones in the terraform.Setup, e.g. the
+// credentials of an account selected by the resource. They take precedence
+// over the ones in the terraform.Setup.
+type EnvFn func(ctx context.Context, kube client.Client, mg xpresource.Managed) (map[string]string, error)
+
 // NewInitializerFn returns the Initializer with a client.
 type NewInitializerFn func(client client.Client) managed.Initializer
 
@@ -288,9 +450,72 @@ type Resource struct {
 
 	InitializerFns []NewInitializerFn
 
+	// QuotaCheck is called before the resource is created. If it returns an
+	// error, the creation fails fast and the QuotaExceeded condition is set
+	// instead of waiting for Terraform to time out on a creation that is
+	// known to fail.
+	QuotaCheck QuotaCheckFn
+
+	// Env returns the environment variables the Terraform processes of the
+	// resource run with in addition to the ones in the terraform.Setup.
+	Env EnvFn
+
 	// OperationTimeouts allows configuring resource operation timeouts.
 	OperationTimeouts OperationTimeouts
 
+	// Parallelism is the number of concurrent operations Terraform walks the
+	// resource graph with in the apply and destroy operations of the
+	// resource, i.e. the -parallelism flag. It matters for the resources
+	// that are backed by modules or manage multiple instances. If zero, the
+	// Parallelism of the terraform.Setup is used.
+	Parallelism int
+
+	// DriftDetectionInterval is the minimum interval between the refreshes
+	// of the resource that look for the changes made outside of the
+	// controller. Within the interval, the resource is observed from the
+	// state of its latest refresh without running Terraform unless its spec
+	// or state changes in the meantime. The resource is refreshed in every
+	// reconciliation if zero.
+	DriftDetectionInterval time.Duration
+
+	// SkipUnchangedPlans makes the controller skip the Terraform plan when
+	// the Terraform configuration of the resource, i.e. its parameters
+	// including the resolved sensitive ones and the provider configuration,
+	// hasn't changed since an apply succeeded with it or a plan last found
+	// it applied and the refresh hasn't changed the state, i.e. no change
+	// was made outside of the controller, which saves a Terraform CLI
+	// invocation in most of the reconciliations of a resource.
+	SkipUnchangedPlans bool
+
+	// VerifyDeletion makes the controller refresh the resource once more after
+	// a successful destroy operation and remove the finalizer only if the
+	// resource is not found. It should be enabled for resources whose
+	// providers report success before the deletion is propagated.
+	VerifyDeletion bool
+
+	// DeletionProtection configures the detection of deletions blocked by
+	// the deletion protection of the external resource. If set, such
+	// failures are reported with the DeletionBlockedExternally condition and
+	// the protection is disabled before the deletion if the resource has the
+	// disable-deletion-protection annotation.
+	DeletionProtection *DeletionProtection
+
+	// ProviderOverrides moves the configured parameters of the resource out
+	// of the resource block into the provider configuration in the
+	// Terraform configuration of the resource.
+	ProviderOverrides *ProviderOverrides
+
+	// PendingStates makes the resource available only when its observed
+	// state attribute reaches one of the configured values instead of as
+	// soon as it exists in the Terraform state.
+	PendingStates *PendingStates
+
+	// ExplainDrift makes the controller run an additional Terraform plan
+	// when the resource is found not to be up-to-date and report the
+	// attributes that differ from the desired state, together with whether
+	// they were changed in the spec or externally, in the Drift condition.
+	ExplainDrift bool
+
 	// ExternalName allows you to specify a custom ExternalName.
 	ExternalName ExternalName
 
@@ -302,4 +527,33 @@ type Resource struct {
 
 	// LateInitializer configuration to control late-initialization behaviour
 	LateInitializer LateInitializer
+
+	// PrinterColumns are the key spec fields that are printed in
+	// "kubectl get" output in addition to READY, SYNCED, EXTERNAL-NAME and
+	// AGE columns.
+	PrinterColumns []PrinterColumn
+
+	// ObservationListCaps limits the number of elements of the given
+	// top-level observed list attributes that are copied into
+	// status.atProvider, keyed by the Terraform attribute name. For every
+	// capped attribute, a sibling <attribute>Count field that reports the
+	// total number of elements is generated. The Terraform state still keeps
+	// all elements. It is useful for attributes that may contain hundreds of
+	// elements and make the object exceed the size limit of the API server.
+	ObservationListCaps map[string]int
+
+	// Documentation of the resource that is not available in the Terraform
+	// schema, usually populated from the documentation of the provider.
+	Documentation Documentation
+}
+
+// Documentation is the documentation of a resource.
+type Documentation struct {
+	// Description of the resource that is printed on the generated kind.
+	Description string
+
+	// Examples are usage examples of the resource in HCL. They are printed
+	// as a comment in the generated types file and are available to the
+	// templates of the types file as .Examples.
+	Examples []string
 }
@@ -18,16 +18,22 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrl "sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/crossplane/terrajet/pkg/config"
+	tjmeta "github.com/crossplane/terrajet/pkg/meta"
 	"github.com/crossplane/terrajet/pkg/resource"
 	"github.com/crossplane/terrajet/pkg/terraform"
 )
@@ -59,37 +65,148 @@ func (a *APISecretClient) GetSecretValue(ctx context.Context, sel xpv1.SecretKey
 	return d[sel.Key], err
 }
 
+// APICallbacksOption configures an APICallbacks.
+type APICallbacksOption func(*APICallbacks)
+
+// WithCallbackResourceConfig configures the configuration of the resource the
+// callbacks work on. If given, the external name of the resource is set from
+// the state produced by the async apply operation as soon as it completes.
+func WithCallbackResourceConfig(cfg *config.Resource) APICallbacksOption {
+	return func(ac *APICallbacks) {
+		ac.config = cfg
+	}
+}
+
+// WithCallbackEventRecorder configures the callbacks to emit an event
+// summarizing every async operation once it completes.
+func WithCallbackEventRecorder(r event.Recorder) APICallbacksOption {
+	return func(ac *APICallbacks) {
+		ac.recorder = r
+	}
+}
+
+// WithCallbackEnqueuer configures the callbacks to request a reconciliation of
+// the resource through the given Enqueuer once its async operation completes
+// instead of relying on the watch event of the status update, which may be
+// filtered out.
+func WithCallbackEnqueuer(e Enqueuer) APICallbacksOption {
+	return func(ac *APICallbacks) {
+		ac.enqueuer = e
+	}
+}
+
+// WithCallbackStateBackup configures the callbacks to back up the state
+// produced by every successful async apply operation in the given store.
+func WithCallbackStateBackup(b StateBackupStore) APICallbacksOption {
+	return func(ac *APICallbacks) {
+		ac.backup = b
+	}
+}
+
+// WithCallbackPrivateRawStore configures the callbacks to store the private
+// attributes of the state produced by the async apply operations in the given
+// store instead of an annotation on the resources.
+func WithCallbackPrivateRawStore(s resource.PrivateRawStore) APICallbacksOption {
+	return func(ac *APICallbacks) {
+		ac.privateRaw = s
+	}
+}
+
 // NewAPICallbacks returns a new APICallbacks.
-func NewAPICallbacks(m ctrl.Manager, of xpresource.ManagedKind) *APICallbacks {
+func NewAPICallbacks(m ctrl.Manager, of xpresource.ManagedKind, opts ...APICallbacksOption) *APICallbacks {
 	nt := func() resource.Terraformed {
 		return xpresource.MustCreateObject(schema.GroupVersionKind(of), m.GetScheme()).(resource.Terraformed)
 	}
-	return &APICallbacks{
+	ac := &APICallbacks{
 		kube:           m.GetClient(),
 		newTerraformed: nt,
+		recorder:       event.NewNopRecorder(),
+		privateRaw:     resource.AnnotationPrivateRawStore{},
+	}
+	for _, o := range opts {
+		o(ac)
 	}
+	return ac
 }
 
 // APICallbacks providers callbacks that work on API resources.
 type APICallbacks struct {
 	kube           client.Client
 	newTerraformed func() resource.Terraformed
+	config         *config.Resource
+	recorder       event.Recorder
+	enqueuer       Enqueuer
+	backup         StateBackupStore
+	privateRaw     resource.PrivateRawStore
 }
 
-// Apply makes sure the error is saved in async operation condition.
-func (ac *APICallbacks) Apply(name string) terraform.CallbackFn {
+// Apply makes sure the error is saved in async operation condition. The given
+// generation, i.e. the one the resource had when the apply started, is
+// recorded as the generation of the apply.
+func (ac *APICallbacks) Apply(name string, generation int64) terraform.CallbackFn {
 	return func(err error, ctx context.Context) error {
 		nn := types.NamespacedName{Name: name}
 		tr := ac.newTerraformed()
 		if kErr := ac.kube.Get(ctx, nn, tr); kErr != nil {
 			return errors.Wrap(kErr, errGet)
 		}
+		// NOTE(muvaf): The status is updated and the reconciliation is
+		// requested even if the apply cannot be recorded so that the result
+		// of the operation is never lost.
+		var aErr error
+		if err == nil {
+			aErr = ac.recordApply(ctx, nn, tr, generation)
+			backupState(ctx, ac.backup, ac.recorder, tr, terraform.StateFromContext(ctx))
+		}
+		if res, ok := terraform.OperationResultFromContext(ctx); ok {
+			recordOperation(ac.recorder, tr, res, err)
+		}
 		tr.SetConditions(resource.LastAsyncOperationCondition(err))
 		tr.SetConditions(resource.AsyncOperationFinishedCondition())
-		return errors.Wrap(ac.kube.Status().Update(ctx, tr), errStatusUpdate)
+		uErr := ac.kube.Status().Update(ctx, tr)
+		ac.enqueue(name)
+		if aErr != nil {
+			return aErr
+		}
+		return errors.Wrap(uErr, errStatusUpdate)
 	}
 }
 
+// recordApply records the time of the successful apply on the given resource
+// along with the external name and the private attributes from the state
+// produced by the apply, if the configuration of the resource is known, so
+// that the external name is not lost if the resource cannot be observed
+// before the next apply. It removes the annotation that requested the
+// replacement of the resource, if any, since the apply has replaced it. The
+// resource is fetched again and the apply is
+// recorded on its latest version if the update conflicts with another one.
+func (ac *APICallbacks) recordApply(ctx context.Context, nn types.NamespacedName, tr resource.Terraformed, generation int64) error {
+	fetch := false
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if fetch {
+			if err := ac.kube.Get(ctx, nn, tr); err != nil {
+				return errors.Wrap(err, errGet)
+			}
+		}
+		fetch = true
+		if st := terraform.StateFromContext(ctx); st != nil && ac.config != nil {
+			attr, err := st.DecodeAttributes()
+			if err != nil {
+				return errors.Wrap(err, "cannot unmarshal state attributes")
+			}
+			if _, err := resource.SetCriticalState(ctx, ac.privateRaw, tr, ac.config, attr, st.GetPrivateRaw()); err != nil {
+				return errors.Wrap(err, "cannot set critical annotations")
+			}
+		}
+		tjmeta.SetLastApply(tr, time.Now(), generation)
+		xpmeta.RemoveAnnotations(tr, resource.AnnotationKeyReplace)
+		if ac.config != nil && ac.config.SkipUnchangedPlans {
+			resource.SetAppliedSpecHash(tr, terraform.ConfigurationHashFromContext(ctx))
+		}
+		return errors.Wrap(ac.kube.Update(ctx, tr), errUpdateManaged)
+	})
+}
+
 // Destroy makes sure the error is saved in async operation condition.
 func (ac *APICallbacks) Destroy(name string) terraform.CallbackFn {
 	return func(err error, ctx context.Context) error {
@@ -98,8 +215,21 @@ func (ac *APICallbacks) Destroy(name string) terraform.CallbackFn {
 		if kErr := ac.kube.Get(ctx, nn, tr); kErr != nil {
 			return errors.Wrap(kErr, errGet)
 		}
+		if res, ok := terraform.OperationResultFromContext(ctx); ok {
+			recordOperation(ac.recorder, tr, res, err)
+		}
 		tr.SetConditions(resource.LastAsyncOperationCondition(err))
 		tr.SetConditions(resource.AsyncOperationFinishedCondition())
-		return errors.Wrap(ac.kube.Status().Update(ctx, tr), errStatusUpdate)
+		if uErr := ac.kube.Status().Update(ctx, tr); uErr != nil {
+			return errors.Wrap(uErr, errStatusUpdate)
+		}
+		ac.enqueue(name)
+		return nil
+	}
+}
+
+func (ac *APICallbacks) enqueue(name string) {
+	if ac.enqueuer != nil {
+		ac.enqueuer.Enqueue(name)
 	}
 }
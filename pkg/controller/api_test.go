@@ -22,23 +22,31 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrl "sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 
+	"github.com/crossplane/terrajet/pkg/config"
+	tjmeta "github.com/crossplane/terrajet/pkg/meta"
 	"github.com/crossplane/terrajet/pkg/resource"
 	"github.com/crossplane/terrajet/pkg/resource/fake"
+	"github.com/crossplane/terrajet/pkg/terraform"
 	tjerrors "github.com/crossplane/terrajet/pkg/terraform/errors"
 )
 
 func TestAPICallbacks_Apply(t *testing.T) {
 	type args struct {
-		mgr ctrl.Manager
-		mg  xpresource.ManagedKind
-		err error
+		mgr  ctrl.Manager
+		mg   xpresource.ManagedKind
+		opts []APICallbacksOption
+		ctx  context.Context
+		err  error
 	}
 	type want struct {
 		err error
@@ -69,12 +77,21 @@ func TestAPICallbacks_Apply(t *testing.T) {
 			},
 		},
 		"ApplyOperationSucceeded": {
-			reason: "It should update the condition with success if the apply operation does not report error",
+			reason: "It should record the time and the starting generation of the apply and update the condition with success if the apply operation does not report error",
 			args: args{
 				mg: xpresource.ManagedKind(xpfake.GVK(&fake.Terraformed{})),
 				mgr: &xpfake.Manager{
 					Client: &test.MockClient{
 						MockGet: test.NewMockGetFn(nil),
+						MockUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+							if tjmeta.GetLastApplyTime(obj).IsZero() {
+								t.Errorf("\nApply(...): the time of the apply is not recorded")
+							}
+							if diff := cmp.Diff(int64(3), tjmeta.GetLastApplyGeneration(obj)); diff != "" {
+								t.Errorf("\nApply(...): -want generation, +got generation:\n%s", diff)
+							}
+							return nil
+						},
 						MockStatusUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
 							got := obj.(resource.Terraformed).GetCondition(resource.TypeLastAsyncOperation)
 							if diff := cmp.Diff(resource.LastAsyncOperationCondition(nil), got); diff != "" {
@@ -87,6 +104,122 @@ func TestAPICallbacks_Apply(t *testing.T) {
 				},
 			},
 		},
+		"ReplaceAnnotationRemoved": {
+			reason: "It should remove the annotation that requested the replacement once the apply operation succeeds",
+			args: args{
+				mg: xpresource.ManagedKind(xpfake.GVK(&fake.Terraformed{})),
+				mgr: &xpfake.Manager{
+					Client: &test.MockClient{
+						MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+							obj.SetAnnotations(map[string]string{resource.AnnotationKeyReplace: "true"})
+							return nil
+						},
+						MockUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+							if resource.ReplaceRequested(obj) {
+								t.Errorf("\nApply(...): the replace annotation is not removed")
+							}
+							return nil
+						},
+						MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+					},
+					Scheme: xpfake.SchemeWith(&fake.Terraformed{}),
+				},
+			},
+		},
+		"ApplyOperationSucceededWithState": {
+			reason: "It should set the external name from the state produced by the apply operation",
+			args: args{
+				mg: xpresource.ManagedKind(xpfake.GVK(&fake.Terraformed{})),
+				mgr: &xpfake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+							if diff := cmp.Diff("some-id", meta.GetExternalName(obj)); diff != "" {
+								t.Errorf("\nApply(...): -want external name, +got external name:\n%s", diff)
+							}
+							return nil
+						},
+						MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+					},
+					Scheme: xpfake.SchemeWith(&fake.Terraformed{}),
+				},
+				opts: []APICallbacksOption{WithCallbackResourceConfig(&config.Resource{
+					ExternalName: config.ExternalName{GetExternalNameFn: config.IDAsExternalName},
+				})},
+				ctx: terraform.ContextWithState(context.TODO(), exampleState),
+			},
+		},
+		"ApplyOperationSucceededWithConfigurationHash": {
+			reason: "It should record the hash of the configuration the apply operation ran with if unchanged plans are skipped",
+			args: args{
+				mg: xpresource.ManagedKind(xpfake.GVK(&fake.Terraformed{})),
+				mgr: &xpfake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+							if !resource.SpecApplied(obj, "very-cool-hash") {
+								t.Errorf("\nApply(...): the hash of the applied configuration is not recorded")
+							}
+							return nil
+						},
+						MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+					},
+					Scheme: xpfake.SchemeWith(&fake.Terraformed{}),
+				},
+				opts: []APICallbacksOption{WithCallbackResourceConfig(&config.Resource{SkipUnchangedPlans: true})},
+				ctx:  terraform.ContextWithConfigurationHash(context.TODO(), "very-cool-hash"),
+			},
+		},
+		"ConflictRetried": {
+			reason: "It should fetch the resource again and retry recording the apply if the update conflicts",
+			args: args{
+				mg: xpresource.ManagedKind(xpfake.GVK(&fake.Terraformed{})),
+				mgr: &xpfake.Manager{
+					Client: func() client.Client {
+						updates := 0
+						return &test.MockClient{
+							MockGet: test.NewMockGetFn(nil),
+							MockUpdate: func(_ context.Context, _ client.Object, _ ...client.UpdateOption) error {
+								updates++
+								if updates == 1 {
+									return kerrors.NewConflict(schema.GroupResource{}, "name", errBoom)
+								}
+								return nil
+							},
+							MockStatusUpdate: test.NewMockStatusUpdateFn(nil),
+						}
+					}(),
+					Scheme: xpfake.SchemeWith(&fake.Terraformed{}),
+				},
+			},
+		},
+		"CannotSetExternalName": {
+			reason: "It should return error if the external name cannot be saved but still update the status",
+			args: args{
+				mg: xpresource.ManagedKind(xpfake.GVK(&fake.Terraformed{})),
+				mgr: &xpfake.Manager{
+					Client: &test.MockClient{
+						MockGet:    test.NewMockGetFn(nil),
+						MockUpdate: test.NewMockUpdateFn(errBoom),
+						MockStatusUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+							got := obj.(resource.Terraformed).GetCondition(resource.TypeLastAsyncOperation)
+							if diff := cmp.Diff(resource.LastAsyncOperationCondition(nil), got); diff != "" {
+								t.Errorf("\nApply(...): -want condition, +got condition:\n%s", diff)
+							}
+							return nil
+						},
+					},
+					Scheme: xpfake.SchemeWith(&fake.Terraformed{}),
+				},
+				opts: []APICallbacksOption{WithCallbackResourceConfig(&config.Resource{
+					ExternalName: config.ExternalName{GetExternalNameFn: config.IDAsExternalName},
+				})},
+				ctx: terraform.ContextWithState(context.TODO(), exampleState),
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errUpdateManaged),
+			},
+		},
 		"CannotGet": {
 			reason: "It should return error if it cannot get the resource to update",
 			args: args{
@@ -107,8 +240,12 @@ func TestAPICallbacks_Apply(t *testing.T) {
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := NewAPICallbacks(tc.args.mgr, tc.args.mg)
-			err := e.Apply("name")(tc.args.err, context.TODO())
+			ctx := tc.args.ctx
+			if ctx == nil {
+				ctx = context.TODO()
+			}
+			e := NewAPICallbacks(tc.args.mgr, tc.args.mg, tc.args.opts...)
+			err := e.Apply("name", 3)(tc.args.err, ctx)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nApply(...): -want error, +got error:\n%s", tc.reason, diff)
 			}
@@ -0,0 +1,225 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+const (
+	// StateBackupKey is the key of the gzip compressed Terraform state in
+	// the state backup Secrets.
+	StateBackupKey = "tfstate.gz"
+	// PreviousStateBackupKey is the key of the state that was backed up
+	// before the latest one in the state backup Secrets.
+	PreviousStateBackupKey = "tfstate-backup.gz"
+	// StateBackupOwnerKey is the key of the UID of the resource whose state
+	// is kept in the state backup Secrets. The backups are restored only
+	// into the resource with the same UID.
+	StateBackupOwnerKey = "owner-uid"
+	// LabelKeyStateBackup is the label of the state backup Secrets whose
+	// value is the name of the resource whose state they keep.
+	LabelKeyStateBackup = "terrajet.crossplane.io/state-backup"
+
+	errBackupState        = "cannot back up terraform state"
+	errGetStateBackup     = "cannot get state backup secret"
+	errCompressState      = "cannot compress terraform state"
+	errCreateStateBackup  = "cannot create state backup secret"
+	errUpdateStateBackup  = "cannot update state backup secret"
+	errMarshalStateBackup = "cannot marshal terraform state"
+	errDecompressState    = "cannot decompress backed up terraform state"
+	errDeleteStateBackup  = "cannot delete state backup secret"
+	errBackupRemove       = "cannot remove terraform state backup"
+
+	reasonStateBackupFailed event.Reason = "StateBackupFailed"
+)
+
+// StateBackupStore persists copies of the Terraform states of the resources
+// outside of their workspaces so that the states survive the loss of the
+// workspaces.
+type StateBackupStore interface {
+	Backup(ctx context.Context, tr resource.Terraformed, st *json.StateV4) error
+	// Remove removes the backups of the given resource once it's deleted so
+	// that they're not restored into a new resource with the same name.
+	Remove(ctx context.Context, tr resource.Terraformed) error
+}
+
+// NewSecretStateBackup returns a SecretStateBackup that keeps the backups in
+// the Secrets in the given namespace.
+func NewSecretStateBackup(kube client.Client, namespace string) *SecretStateBackup {
+	return &SecretStateBackup{kube: kube, namespace: namespace}
+}
+
+// SecretStateBackup backs up the Terraform state of each resource in a
+// Secret of its own, keeping the previous state in the same Secret.
+type SecretStateBackup struct {
+	kube      client.Client
+	namespace string
+}
+
+// StateBackupSecretName returns the name of the Secret the state of the given
+// resource is backed up in. It's derived from the Terraform resource type
+// and the name of the resource so that the backups can be found by the name
+// of the resource. Since a new resource with the same name reuses the
+// Secret, the UID of the resource whose state is backed up is recorded in
+// the Secret, too.
+func StateBackupSecretName(tr resource.Terraformed) string {
+	return "tfstate-" + strings.ReplaceAll(tr.GetTerraformResourceType(), "_", "-") + "-" + tr.GetName()
+}
+
+// Backup stores the given state as the latest backup of the given resource
+// and rotates the former latest backup into the previous one. It's a no-op if
+// the state hasn't changed since the latest backup.
+func (b *SecretStateBackup) Backup(ctx context.Context, tr resource.Terraformed, st *json.StateV4) error {
+	raw, err := json.JSParser.Marshal(st)
+	if err != nil {
+		return errors.Wrap(err, errMarshalStateBackup)
+	}
+	compressed, err := compress(raw)
+	if err != nil {
+		return errors.Wrap(err, errCompressState)
+	}
+	s := &corev1.Secret{}
+	err = b.kube.Get(ctx, types.NamespacedName{Namespace: b.namespace, Name: StateBackupSecretName(tr)}, s)
+	if kerrors.IsNotFound(err) {
+		s = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: b.namespace,
+				Name:      StateBackupSecretName(tr),
+				Labels:    map[string]string{LabelKeyStateBackup: tr.GetName()},
+			},
+			Data: map[string][]byte{StateBackupKey: compressed, StateBackupOwnerKey: []byte(tr.GetUID())},
+		}
+		return errors.Wrap(b.kube.Create(ctx, s), errCreateStateBackup)
+	}
+	if err != nil {
+		return errors.Wrap(err, errGetStateBackup)
+	}
+	owned := string(s.Data[StateBackupOwnerKey]) == string(tr.GetUID())
+	if owned && bytes.Equal(s.Data[StateBackupKey], compressed) {
+		return nil
+	}
+	if s.Data == nil {
+		s.Data = map[string][]byte{}
+	}
+	// NOTE(muvaf): The backups of another resource with the same name, e.g.
+	// one that was deleted before its backups could be removed, are
+	// dropped rather than rotated.
+	delete(s.Data, PreviousStateBackupKey)
+	if latest, ok := s.Data[StateBackupKey]; ok && owned {
+		s.Data[PreviousStateBackupKey] = latest
+	}
+	s.Data[StateBackupKey] = compressed
+	s.Data[StateBackupOwnerKey] = []byte(tr.GetUID())
+	return errors.Wrap(b.kube.Update(ctx, s), errUpdateStateBackup)
+}
+
+// Remove deletes the backup Secret of the given resource.
+func (b *SecretStateBackup) Remove(ctx context.Context, tr resource.Terraformed) error {
+	s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: b.namespace, Name: StateBackupSecretName(tr)}}
+	return errors.Wrap(client.IgnoreNotFound(b.kube.Delete(ctx, s)), errDeleteStateBackup)
+}
+
+// NewSecretStateRestoreFn returns a function that reads the latest state
+// backup of a resource from the Secrets in the given namespace, so that the
+// workspaces that have lost their state can be hydrated from them with
+// terraform.WithStateRestore. The backups of another resource with the same
+// name are never returned.
+func NewSecretStateRestoreFn(namespace string) terraform.StateRestoreFn {
+	return func(ctx context.Context, c resource.SecretClient, tr resource.Terraformed) ([]byte, error) {
+		data, err := c.GetSecretData(ctx, &xpv1.SecretReference{Namespace: namespace, Name: StateBackupSecretName(tr)})
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, errGetStateBackup)
+		}
+		compressed, ok := data[StateBackupKey]
+		if !ok || string(data[StateBackupOwnerKey]) != string(tr.GetUID()) {
+			return nil, nil
+		}
+		raw, err := decompress(compressed)
+		return raw, errors.Wrap(err, errDecompressState)
+	}
+}
+
+// compress returns the gzip compressed form of the given content. Since the
+// header carries no modification time, equal content is compressed into
+// equal bytes.
+func compress(content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// backupState backs up the given state of the given resource, if a backup
+// store is configured. A failed backup doesn't fail the reconciliation but
+// is reported with an event since the workspace still has the state.
+func backupState(ctx context.Context, b StateBackupStore, r event.Recorder, mg xpresource.Managed, st *json.StateV4) {
+	tr, ok := mg.(resource.Terraformed)
+	if b == nil || st == nil || !ok {
+		return
+	}
+	if err := b.Backup(ctx, tr, st); err != nil {
+		r.Event(mg, event.Warning(reasonStateBackupFailed, errors.Wrap(err, errBackupState)))
+	}
+}
+
+// removeBackup removes the state backups of the given resource if it's being
+// deleted, which must be called only once the external resource is gone.
+func (e *external) removeBackup(ctx context.Context, mg xpresource.Managed) error {
+	tr, ok := mg.(resource.Terraformed)
+	if e.backup == nil || !ok || !xpmeta.WasDeleted(mg) {
+		return nil
+	}
+	return errors.Wrap(e.backup.Remove(ctx, tr), errBackupRemove)
+}
+
+// decompress returns the content of the given gzip compressed bytes.
+func decompress(compressed []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close() // nolint:errcheck
+	return io.ReadAll(zr)
+}
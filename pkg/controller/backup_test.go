@@ -0,0 +1,206 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+func TestSecretStateBackup(t *testing.T) {
+	state := &json.StateV4{Version: 4, Serial: 2}
+	raw, _ := json.JSParser.Marshal(state)
+	latest, _ := compress(raw)
+	older, _ := compress([]byte(`{"version":4,"serial":1}`))
+	uid := types.UID("very-cool-uid")
+	type want struct {
+		data map[string][]byte
+		err  error
+	}
+	cases := map[string]struct {
+		reason   string
+		existing map[string][]byte
+		getErr   error
+		want
+	}{
+		"Created": {
+			reason: "A Secret with the state should be created if there is no backup yet",
+			getErr: kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "tfstate-"),
+			want: want{
+				data: map[string][]byte{StateBackupKey: latest, StateBackupOwnerKey: []byte(uid)},
+			},
+		},
+		"Rotated": {
+			reason:   "The latest backup should be rotated into the previous one if the state has changed",
+			existing: map[string][]byte{StateBackupKey: older, StateBackupOwnerKey: []byte(uid)},
+			want: want{
+				data: map[string][]byte{StateBackupKey: latest, PreviousStateBackupKey: older, StateBackupOwnerKey: []byte(uid)},
+			},
+		},
+		"ForeignReplaced": {
+			reason:   "The backups of another resource with the same name should be replaced rather than rotated",
+			existing: map[string][]byte{StateBackupKey: older, PreviousStateBackupKey: older, StateBackupOwnerKey: []byte("another-uid")},
+			want: want{
+				data: map[string][]byte{StateBackupKey: latest, StateBackupOwnerKey: []byte(uid)},
+			},
+		},
+		"Unchanged": {
+			reason:   "The Secret should not be written if the state hasn't changed since the latest backup",
+			existing: map[string][]byte{StateBackupKey: latest, PreviousStateBackupKey: older, StateBackupOwnerKey: []byte(uid)},
+		},
+		"GetFailed": {
+			reason: "An error should be returned if the Secret cannot be read",
+			getErr: errBoom,
+			want: want{
+				err: errors.Wrap(errBoom, errGetStateBackup),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var written map[string][]byte
+			kube := &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+					obj.(*corev1.Secret).Data = tc.existing
+					return tc.getErr
+				},
+				MockCreate: func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+					written = obj.(*corev1.Secret).Data
+					return nil
+				},
+				MockUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+					written = obj.(*corev1.Secret).Data
+					return nil
+				},
+			}
+			err := NewSecretStateBackup(kube, "crossplane-system").Backup(context.TODO(), &fake.Terraformed{Managed: xpfake.Managed{ObjectMeta: metav1.ObjectMeta{UID: uid}}}, state)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nBackup(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.data, written); diff != "" {
+				t.Errorf("\n%s\nBackup(...): -want data, +got data:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretStateBackupRemove(t *testing.T) {
+	cases := map[string]struct {
+		reason    string
+		deleteErr error
+		want      error
+	}{
+		"Deleted": {
+			reason: "The backup Secret should be deleted",
+		},
+		"NotFound": {
+			reason:    "No error should be returned if there is no backup Secret",
+			deleteErr: kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "tfstate-"),
+		},
+		"DeleteFailed": {
+			reason:    "An error should be returned if the backup Secret cannot be deleted",
+			deleteErr: errBoom,
+			want:      errors.Wrap(errBoom, errDeleteStateBackup),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kube := &test.MockClient{MockDelete: test.NewMockDeleteFn(tc.deleteErr)}
+			err := NewSecretStateBackup(kube, "crossplane-system").Remove(context.TODO(), &fake.Terraformed{})
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nRemove(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+type secretClient struct {
+	data map[string][]byte
+	err  error
+}
+
+func (c secretClient) GetSecretData(_ context.Context, _ *xpv1.SecretReference) (map[string][]byte, error) {
+	return c.data, c.err
+}
+
+func (c secretClient) GetSecretValue(_ context.Context, _ xpv1.SecretKeySelector) ([]byte, error) {
+	return nil, c.err
+}
+
+func TestSecretStateRestoreFn(t *testing.T) {
+	raw := []byte(`{"version":4,"serial":2}`)
+	compressed, _ := compress(raw)
+	uid := types.UID("very-cool-uid")
+	type want struct {
+		state []byte
+		err   error
+	}
+	cases := map[string]struct {
+		reason string
+		c      secretClient
+		want
+	}{
+		"Restored": {
+			reason: "The latest backup should be returned decompressed",
+			c:      secretClient{data: map[string][]byte{StateBackupKey: compressed, StateBackupOwnerKey: []byte(uid)}},
+			want: want{
+				state: raw,
+			},
+		},
+		"ForeignBackup": {
+			reason: "No state should be returned if the backup belongs to another resource with the same name",
+			c:      secretClient{data: map[string][]byte{StateBackupKey: compressed, StateBackupOwnerKey: []byte("another-uid")}},
+		},
+		"NoBackup": {
+			reason: "No state should be returned if the backup Secret does not exist",
+			c:      secretClient{err: kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "tfstate-")},
+		},
+		"GetFailed": {
+			reason: "An error should be returned if the backup Secret cannot be read",
+			c:      secretClient{err: errBoom},
+			want: want{
+				err: errors.Wrap(errBoom, errGetStateBackup),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewSecretStateRestoreFn("crossplane-system")(context.TODO(), tc.c, &fake.Terraformed{Managed: xpfake.Managed{ObjectMeta: metav1.ObjectMeta{UID: uid}}})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nRestore(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.state, got); diff != "" {
+				t.Errorf("\n%s\nRestore(...): -want state, +got state:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
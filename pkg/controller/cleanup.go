@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+const (
+	// WorkspaceCleanupFinalizer is the finalizer that makes sure the
+	// Terraform workspace of a resource is removed before the resource
+	// disappears from the API server.
+	WorkspaceCleanupFinalizer = "terrajet.crossplane.io/workspace-cleanup"
+
+	errAddCleanupFinalizer    = "cannot add workspace cleanup finalizer"
+	errRemoveCleanupFinalizer = "cannot remove workspace cleanup finalizer"
+	errCleanupWorkspace       = "cannot clean up workspace"
+	errCleanupOrphaned        = "cannot clean up orphaned resource"
+)
+
+// WithWorkspaceCleanup configures the Connector to add a dedicated finalizer
+// to the resources that is removed only after the workspace of the resource
+// is removed from the given store. Unlike the managed reconciler's
+// finalizer, it is not removed while a Terraform operation, e.g. an async
+// destroy, is still running in the workspace.
+func WithWorkspaceCleanup(sc terraform.StoreCleaner) Option {
+	return func(c *Connector) {
+		c.cleaner = sc
+	}
+}
+
+// addCleanupFinalizer adds the workspace cleanup finalizer to the resource if
+// it's not being deleted and does not have the finalizer yet.
+func (e *external) addCleanupFinalizer(ctx context.Context, mg xpresource.Managed) error {
+	if e.cleaner == nil || meta.WasDeleted(mg) || meta.FinalizerExists(mg, WorkspaceCleanupFinalizer) {
+		return nil
+	}
+	meta.AddFinalizer(mg, WorkspaceCleanupFinalizer)
+	return errors.Wrap(e.kube.Update(ctx, mg), errAddCleanupFinalizer)
+}
+
+// cleanup removes the workspace of the resource from the store and only then
+// removes the workspace cleanup finalizer if the resource is being deleted.
+// It must be called only when no Terraform operation is running in the
+// workspace and the external resource is gone.
+func (e *external) cleanup(ctx context.Context, mg xpresource.Managed) error {
+	if e.cleaner == nil || !meta.WasDeleted(mg) || !meta.FinalizerExists(mg, WorkspaceCleanupFinalizer) {
+		return nil
+	}
+	if err := e.cleaner.Remove(mg); err != nil {
+		return errors.Wrap(err, errCleanupWorkspace)
+	}
+	meta.RemoveFinalizer(mg, WorkspaceCleanupFinalizer)
+	return errors.Wrap(e.kube.Update(ctx, mg), errRemoveCleanupFinalizer)
+}
+
+// NewOrphanCleanupFinalizer returns an OrphanCleanupFinalizer that wraps the
+// given finalizer and removes the state backups, the private attributes and
+// the module states of the orphaned resources from the given stores, if they
+// are not nil.
+func NewOrphanCleanupFinalizer(f xpresource.Finalizer, b StateBackupStore, pr resource.PrivateRawStore, ms ModuleStateStore) *OrphanCleanupFinalizer {
+	return &OrphanCleanupFinalizer{Finalizer: f, backup: b, privateRaw: pr, moduleState: ms}
+}
+
+// OrphanCleanupFinalizer wraps the finalizer of the managed reconciler to clean
+// up after the resources that are deleted with the Orphan deletion policy.
+// The managed reconciler removes its finalizer from such resources without
+// connecting to or observing them, so the workspace cleanup finalizer would
+// otherwise never be removed and the resources would never go away.
+type OrphanCleanupFinalizer struct {
+	xpresource.Finalizer
+	backup      StateBackupStore
+	privateRaw  resource.PrivateRawStore
+	moduleState ModuleStateStore
+}
+
+// RemoveFinalizer removes the workspace cleanup finalizer of the given
+// resource along with the finalizer of the managed reconciler if the resource
+// is orphaned.
+func (f *OrphanCleanupFinalizer) RemoveFinalizer(ctx context.Context, obj xpresource.Object) error {
+	mg, ok := obj.(xpresource.Managed)
+	if !ok || !meta.WasDeleted(mg) || mg.GetDeletionPolicy() != xpv1.DeletionOrphan {
+		return f.Finalizer.RemoveFinalizer(ctx, obj)
+	}
+	if tr, ok := mg.(resource.Terraformed); ok {
+		if f.backup != nil {
+			if err := f.backup.Remove(ctx, tr); err != nil {
+				return errors.Wrap(err, errCleanupOrphaned)
+			}
+		}
+		if f.privateRaw != nil {
+			if err := f.privateRaw.RemovePrivateRaw(ctx, tr); err != nil {
+				return errors.Wrap(err, errCleanupOrphaned)
+			}
+		}
+	}
+	if _, ok := mg.(resource.Module); ok && f.moduleState != nil {
+		if err := f.moduleState.RemoveModuleState(ctx, mg); err != nil {
+			return errors.Wrap(err, errCleanupOrphaned)
+		}
+	}
+	meta.RemoveFinalizer(mg, WorkspaceCleanupFinalizer)
+	return f.Finalizer.RemoveFinalizer(ctx, obj)
+}
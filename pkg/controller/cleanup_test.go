@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+type storeCleanerFn func(obj xpresource.Object) error
+
+func (fn storeCleanerFn) Remove(obj xpresource.Object) error {
+	return fn(obj)
+}
+
+func TestCleanupFinalizer(t *testing.T) {
+	now := metav1.Now()
+	type args struct {
+		obj     *fake.Terraformed
+		cleaner storeCleanerFn
+	}
+	type want struct {
+		err        error
+		finalizers []string
+		removed    bool
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"AddFinalizer": {
+			reason: "The cleanup finalizer should be added to a resource that is not being deleted",
+			args: args{
+				obj: &fake.Terraformed{},
+			},
+			want: want{
+				finalizers: []string{WorkspaceCleanupFinalizer},
+			},
+		},
+		"RemoveWorkspaceAndFinalizer": {
+			reason: "The workspace should be removed before the cleanup finalizer of a deleted resource",
+			args: args{
+				obj: func() *fake.Terraformed {
+					tr := &fake.Terraformed{}
+					tr.SetDeletionTimestamp(&now)
+					tr.SetFinalizers([]string{WorkspaceCleanupFinalizer})
+					return tr
+				}(),
+			},
+			want: want{
+				finalizers: []string{},
+				removed:    true,
+			},
+		},
+		"RemoveWorkspaceFailed": {
+			reason: "The cleanup finalizer should be kept if the workspace cannot be removed",
+			args: args{
+				obj: func() *fake.Terraformed {
+					tr := &fake.Terraformed{}
+					tr.SetDeletionTimestamp(&now)
+					tr.SetFinalizers([]string{WorkspaceCleanupFinalizer})
+					return tr
+				}(),
+				cleaner: func(_ xpresource.Object) error {
+					return errBoom
+				},
+			},
+			want: want{
+				err:        errors.Wrap(errBoom, errCleanupWorkspace),
+				finalizers: []string{WorkspaceCleanupFinalizer},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			removed := false
+			cleaner := tc.args.cleaner
+			if cleaner == nil {
+				cleaner = func(_ xpresource.Object) error {
+					removed = true
+					return nil
+				}
+			}
+			e := &external{
+				kube:    &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				cleaner: cleaner,
+			}
+			err := e.addCleanupFinalizer(context.TODO(), tc.args.obj)
+			if err == nil {
+				err = e.cleanup(context.TODO(), tc.args.obj)
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ncleanup(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.finalizers, tc.args.obj.GetFinalizers()); diff != "" {
+				t.Errorf("\n%s\ncleanup(...): -want finalizers, +got finalizers:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.removed, removed); diff != "" {
+				t.Errorf("\n%s\ncleanup(...): -want removed, +got removed:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+type backupStore struct {
+	removed bool
+	err     error
+}
+
+func (b *backupStore) Backup(_ context.Context, _ resource.Terraformed, _ *json.StateV4) error {
+	return nil
+}
+
+func (b *backupStore) Remove(_ context.Context, _ resource.Terraformed) error {
+	b.removed = true
+	return b.err
+}
+
+func TestOrphanCleanupFinalizer(t *testing.T) {
+	now := metav1.Now()
+	deleted := func(p xpv1.DeletionPolicy) *fake.Terraformed {
+		tr := &fake.Terraformed{}
+		tr.SetDeletionTimestamp(&now)
+		tr.SetDeletionPolicy(p)
+		tr.SetFinalizers([]string{managed.FinalizerName, WorkspaceCleanupFinalizer})
+		return tr
+	}
+	type want struct {
+		err           error
+		finalizers    []string
+		backupRemoved bool
+	}
+	cases := map[string]struct {
+		reason string
+		obj    *fake.Terraformed
+		backup *backupStore
+		want
+	}{
+		"Orphaned": {
+			reason: "The cleanup finalizer should be removed along with the managed finalizer of an orphaned resource, and its backup should be removed",
+			obj:    deleted(xpv1.DeletionOrphan),
+			backup: &backupStore{},
+			want: want{
+				finalizers:    []string{},
+				backupRemoved: true,
+			},
+		},
+		"Deleted": {
+			reason: "The cleanup finalizer of a resource whose external resource is deleted should be left to the external client",
+			obj:    deleted(xpv1.DeletionDelete),
+			backup: &backupStore{},
+			want: want{
+				finalizers: []string{WorkspaceCleanupFinalizer},
+			},
+		},
+		"RemoveBackupFailed": {
+			reason: "The finalizers of an orphaned resource should be kept if its backup cannot be removed",
+			obj:    deleted(xpv1.DeletionOrphan),
+			backup: &backupStore{err: errBoom},
+			want: want{
+				err:           errors.Wrap(errBoom, errCleanupOrphaned),
+				finalizers:    []string{managed.FinalizerName, WorkspaceCleanupFinalizer},
+				backupRemoved: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			af := xpresource.FinalizerFns{
+				RemoveFinalizerFn: func(_ context.Context, obj xpresource.Object) error {
+					meta.RemoveFinalizer(obj, managed.FinalizerName)
+					return nil
+				},
+			}
+			err := NewOrphanCleanupFinalizer(af, tc.backup, nil, nil).RemoveFinalizer(context.TODO(), tc.obj)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nRemoveFinalizer(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.finalizers, tc.obj.GetFinalizers()); diff != "" {
+				t.Errorf("\n%s\nRemoveFinalizer(...): -want finalizers, +got finalizers:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.backupRemoved, tc.backup.removed); diff != "" {
+				t.Errorf("\n%s\nRemoveFinalizer(...): -want backup removed, +got backup removed:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	listDependentsTimeout = 30 * time.Second
+)
+
+// EnqueueDependents returns an event handler that enqueues the resources of
+// the kind of the given list that reference the object of an event. The
+// reference paths are the dot-separated paths of the reference fields under
+// spec.forProvider, e.g. "vpcIdRef" or "subnetMapping.subnetIdRef", where
+// the lists on the way are traversed element by element. It's meant to be
+// used with ReadyOrExternalNameChanged so that the dependents are reconciled
+// as soon as the resources they reference become usable instead of waiting
+// for the next poll.
+func EnqueueDependents(kube client.Client, list client.ObjectList, l logging.Logger, refPaths ...string) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(dependentsMapFunc(kube, list, l, refPaths))
+}
+
+func dependentsMapFunc(kube client.Client, list client.ObjectList, l logging.Logger, refPaths []string) handler.MapFunc {
+	return func(o client.Object) []reconcile.Request {
+		ctx, cancel := context.WithTimeout(context.Background(), listDependentsTimeout)
+		defer cancel()
+		dl := list.DeepCopyObject().(client.ObjectList)
+		if err := kube.List(ctx, dl); err != nil {
+			l.Debug("cannot list dependents", "error", err.Error())
+			return nil
+		}
+		items, err := meta.ExtractList(dl)
+		if err != nil {
+			l.Debug("cannot extract dependents", "error", err.Error())
+			return nil
+		}
+		var reqs []reconcile.Request
+		for _, item := range items {
+			d, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			if referencesName(d, o.GetName(), refPaths) {
+				reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Name: d.GetName()}})
+			}
+		}
+		return reqs
+	}
+}
+
+// referencesName returns whether any of the reference fields at the given
+// paths of the given object refer to the given name.
+func referencesName(o runtime.Object, name string, refPaths []string) bool {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return false
+	}
+	spec, _ := u["spec"].(map[string]interface{})
+	for _, p := range refPaths {
+		if referencesAt(spec["forProvider"], strings.Split(p, "."), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func referencesAt(v interface{}, path []string, name string) bool {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, e := range x {
+			if referencesAt(e, path, name) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return x["name"] == name
+		}
+		return referencesAt(x[path[0]], path[1:], name)
+	}
+	return false
+}
+
+// ReadyOrExternalNameChanged returns a predicate that accepts only the
+// updates of managed resources that become ready or whose external names
+// change, i.e. the moments their dependents can make progress.
+func ReadyOrExternalNameChanged() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldMg, ok := e.ObjectOld.(xpresource.Managed)
+			if !ok {
+				return false
+			}
+			newMg, ok := e.ObjectNew.(xpresource.Managed)
+			if !ok {
+				return false
+			}
+			if xpmeta.GetExternalName(oldMg) != xpmeta.GetExternalName(newMg) {
+				return true
+			}
+			return !isReady(oldMg) && isReady(newMg)
+		},
+	}
+}
+
+func isReady(mg xpresource.Managed) bool {
+	return mg.GetCondition(xpv1.TypeReady).Status == "True"
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+)
+
+func dependent(name string, forProvider map[string]interface{}) unstructured.Unstructured {
+	u := unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"forProvider": forProvider,
+		},
+	}}
+	u.SetName(name)
+	return u
+}
+
+func TestDependentsMapFunc(t *testing.T) {
+	items := []unstructured.Unstructured{
+		dependent("direct", map[string]interface{}{
+			"vpcIdRef": map[string]interface{}{"name": "vpc"},
+		}),
+		dependent("other", map[string]interface{}{
+			"vpcIdRef": map[string]interface{}{"name": "another-vpc"},
+		}),
+		dependent("in-list", map[string]interface{}{
+			"securityGroupIdsRefs": []interface{}{
+				map[string]interface{}{"name": "sg"},
+				map[string]interface{}{"name": "vpc"},
+			},
+		}),
+		dependent("nested", map[string]interface{}{
+			"mapping": []interface{}{
+				map[string]interface{}{"vpcIdRef": map[string]interface{}{"name": "vpc"}},
+			},
+		}),
+	}
+	kube := &test.MockClient{
+		MockList: func(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+			list.(*unstructured.UnstructuredList).Items = items
+			return nil
+		},
+	}
+	cases := map[string]struct {
+		reason   string
+		refPaths []string
+		want     []reconcile.Request
+	}{
+		"Direct": {
+			reason:   "Only the resources whose reference field refers to the object should be enqueued",
+			refPaths: []string{"vpcIdRef"},
+			want: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "direct"}},
+			},
+		},
+		"ListsAndNested": {
+			reason:   "References in lists and in nested blocks should be matched",
+			refPaths: []string{"securityGroupIdsRefs", "mapping.vpcIdRef"},
+			want: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "in-list"}},
+				{NamespacedName: types.NamespacedName{Name: "nested"}},
+			},
+		},
+		"NoPaths": {
+			reason: "Nothing should be enqueued if there are no reference paths",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{}
+			obj.SetName("vpc")
+			got := dependentsMapFunc(kube, &unstructured.UnstructuredList{}, logging.NewNopLogger(), tc.refPaths)(obj)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ndependentsMapFunc(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReadyOrExternalNameChanged(t *testing.T) {
+	withExternalName := func(n string, c ...xpv1.Condition) *fake.Terraformed {
+		tr := &fake.Terraformed{}
+		xpmeta.SetExternalName(tr, n)
+		tr.SetConditions(c...)
+		return tr
+	}
+	cases := map[string]struct {
+		reason string
+		old    *fake.Terraformed
+		new    *fake.Terraformed
+		want   bool
+	}{
+		"BecameReady": {
+			reason: "An update in which the resource becomes ready should be accepted",
+			old:    withExternalName("a", xpv1.Creating()),
+			new:    withExternalName("a", xpv1.Available()),
+			want:   true,
+		},
+		"StillReady": {
+			reason: "An update of a resource that was already ready should be ignored",
+			old:    withExternalName("a", xpv1.Available()),
+			new:    withExternalName("a", xpv1.Available()),
+		},
+		"ExternalNameChanged": {
+			reason: "An update that changes the external name should be accepted",
+			old:    withExternalName("a", xpv1.Creating()),
+			new:    withExternalName("b", xpv1.Creating()),
+			want:   true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ReadyOrExternalNameChanged().Update(event.UpdateEvent{ObjectOld: tc.old, ObjectNew: tc.new})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nUpdate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
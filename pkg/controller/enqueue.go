@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"math/rand"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrlevent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// defaultEnqueueBufferSize is the number of the requested reconciliations
+	// that can be waiting to be added to the work queue.
+	defaultEnqueueBufferSize = 1024
+)
+
+// EnqueueSourceOption configures an EnqueueSource.
+type EnqueueSourceOption func(*EnqueueSource)
+
+// WithEnqueueJitter delays each requested reconciliation by a random duration
+// up to the given one before it's added to the work queue so that the
+// reconciliations requested at the same time, e.g. when thousands of async
+// applies complete together, are spread out.
+func WithEnqueueJitter(d time.Duration) EnqueueSourceOption {
+	return func(es *EnqueueSource) {
+		es.jitter = d
+	}
+}
+
+// WithEnqueueBufferSize sets the number of the requested reconciliations that
+// can be waiting to be added to the work queue. The requests made while the
+// buffer is full are dropped. Defaults to 1024.
+func WithEnqueueBufferSize(n int) EnqueueSourceOption {
+	return func(es *EnqueueSource) {
+		es.events = make(chan ctrlevent.GenericEvent, n)
+	}
+}
+
+// EnqueueSource is an Enqueuer that adds the requested reconciliations to the
+// work queue of a controller through its rate limiter so that they're subject
+// to the same backoff as the failed reconciliations. It must be watched by the
+// controller using its Source and EventHandler.
+type EnqueueSource struct {
+	events chan ctrlevent.GenericEvent
+	jitter time.Duration
+}
+
+// NewEnqueueSource returns a new EnqueueSource.
+func NewEnqueueSource(opts ...EnqueueSourceOption) *EnqueueSource {
+	es := &EnqueueSource{
+		events: make(chan ctrlevent.GenericEvent, defaultEnqueueBufferSize),
+	}
+	for _, o := range opts {
+		o(es)
+	}
+	return es
+}
+
+// Enqueue requests a reconciliation of the resource with the given name. The
+// request is dropped if the buffer is full, which is fine since a full buffer
+// means the resource is likely to be reconciled soon anyway.
+func (es *EnqueueSource) Enqueue(name string) {
+	select {
+	case es.events <- ctrlevent.GenericEvent{Object: &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: name}}}:
+	default:
+	}
+}
+
+// Source returns the source of the requested reconciliations to be watched by
+// the controller.
+func (es *EnqueueSource) Source() source.Source {
+	return &source.Channel{Source: es.events}
+}
+
+// EventHandler returns the handler that adds the requested reconciliations
+// to the work queue of the controller.
+func (es *EnqueueSource) EventHandler() handler.EventHandler {
+	return &rateLimitedEnqueue{jitter: es.jitter}
+}
+
+// rateLimitedEnqueue adds the objects of the generic events to the work queue
+// through its rate limiter.
+type rateLimitedEnqueue struct {
+	jitter time.Duration
+}
+
+// Create is a no-op since only generic events are fed to this handler.
+func (h *rateLimitedEnqueue) Create(ctrlevent.CreateEvent, workqueue.RateLimitingInterface) {}
+
+// Update is a no-op since only generic events are fed to this handler.
+func (h *rateLimitedEnqueue) Update(ctrlevent.UpdateEvent, workqueue.RateLimitingInterface) {}
+
+// Delete is a no-op since only generic events are fed to this handler.
+func (h *rateLimitedEnqueue) Delete(ctrlevent.DeleteEvent, workqueue.RateLimitingInterface) {}
+
+// Generic adds the object of the event to the given work queue through its
+// rate limiter after the jitter.
+func (h *rateLimitedEnqueue) Generic(e ctrlevent.GenericEvent, q workqueue.RateLimitingInterface) {
+	if e.Object == nil {
+		return
+	}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: e.Object.GetName(), Namespace: e.Object.GetNamespace()}}
+	if h.jitter <= 0 {
+		q.AddRateLimited(req)
+		return
+	}
+	time.AfterFunc(time.Duration(rand.Int63n(int64(h.jitter))), func() { // nolint:gosec
+		q.AddRateLimited(req)
+	})
+}
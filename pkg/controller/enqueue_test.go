@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestEnqueueSource(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		opts   []EnqueueSourceOption
+		names  []string
+		want   []reconcile.Request
+	}{
+		"Enqueued": {
+			reason: "The requested reconciliations should be added to the work queue through its rate limiter",
+			names:  []string{"a", "b"},
+			want: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "a"}},
+				{NamespacedName: types.NamespacedName{Name: "b"}},
+			},
+		},
+		"Jitter": {
+			reason: "The requested reconciliations should be added to the work queue after the jitter",
+			opts:   []EnqueueSourceOption{WithEnqueueJitter(10 * time.Millisecond)},
+			names:  []string{"a"},
+			want: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "a"}},
+			},
+		},
+		"BufferFull": {
+			reason: "The requests made while the buffer is full should be dropped",
+			opts:   []EnqueueSourceOption{WithEnqueueBufferSize(1)},
+			names:  []string{"a", "b"},
+			want: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "a"}},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			es := NewEnqueueSource(tc.opts...)
+			for _, n := range tc.names {
+				es.Enqueue(n)
+			}
+			q := workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(0, 0))
+			defer q.ShutDown()
+			h := es.EventHandler()
+			for len(es.events) > 0 {
+				h.Generic(<-es.events, q)
+			}
+			got := make([]reconcile.Request, 0, len(tc.want))
+			for range tc.want {
+				item, _ := q.Get()
+				req := item.(reconcile.Request)
+				if diff := cmp.Diff(1, q.NumRequeues(req)); diff != "" {
+					t.Errorf("\n%s\nNumRequeues(...): -want, +got:\n%s", tc.reason, diff)
+				}
+				got = append(got, req)
+				q.Done(item)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nEnqueue(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
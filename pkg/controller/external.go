@@ -18,30 +18,68 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"time"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/crossplane/terrajet/pkg/config"
+	tjmeta "github.com/crossplane/terrajet/pkg/meta"
 	"github.com/crossplane/terrajet/pkg/resource"
 	"github.com/crossplane/terrajet/pkg/resource/json"
 	"github.com/crossplane/terrajet/pkg/terraform"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
 )
 
 const (
 	errUnexpectedObject  = "the custom resource is not a Terraformed resource"
 	errGetTerraformSetup = "cannot get terraform setup"
+	errGetEnv            = "cannot get the environment of terraform"
+	errTrackUsage        = "cannot track ProviderConfig usage"
+	errExecutionMode     = "cannot get execution mode"
 	errGetWorkspace      = "cannot get a terraform workspace for resource"
 	errRefresh           = "cannot run refresh"
 	errPlan              = "cannot run plan"
+	errDrift             = "cannot explain drift"
+	errPlanDestroy       = "cannot plan destroy"
 	errStartAsyncApply   = "cannot start async apply"
 	errStartAsyncDestroy = "cannot start async destroy"
 	errApply             = "cannot apply"
+	errValidate          = "cannot validate configuration"
 	errDestroy           = "cannot destroy"
 	errStatusUpdate      = "cannot update status of custom resource"
+	errQuotaExceeded     = "quota check failed"
+
+	errFmtRemoveAnnotation = "cannot remove the %s annotation"
+	errFmtTraceKept        = "the debug output of the failed operation is kept in %s in the controller"
+	errRecordApply         = "cannot record the last apply"
+
+	errDisableDeletionProtection           = "cannot disable deletion protection"
+	errStartAsyncDisableDeletionProtection = "cannot start async apply to disable deletion protection"
+	errRecordDeletionProtectionDisabled    = "cannot record that the deletion protection is disabled"
+
+	fmtPendingState = "waiting for %s to reach a ready state, current state is %q"
+
+	reasonDriftDetected      event.Reason = "DriftDetected"
+	reasonDestroyPlanned     event.Reason = "DestroyPlanned"
+	reasonChangesPlanned     event.Reason = "ChangesPlanned"
+	reasonOperationSucceeded event.Reason = "OperationSucceeded"
+	reasonOperationFailed    event.Reason = "OperationFailed"
+	reasonOperationTrace     event.Reason = "OperationTrace"
+
+	// maxDriftMessageSize is the maximum size of the drift explanations in
+	// the conditions and the events of the resources in bytes.
+	maxDriftMessageSize = 1024
 )
 
 // Option allows you to configure Connector.
@@ -56,6 +94,75 @@ func WithCallbackProvider(ac CallbackProvider) Option {
 	}
 }
 
+// WithEventRecorder configures the controller to emit events summarizing the
+// Terraform operations run for the resources, and explaining the drift of
+// the resources whose configuration enables drift explanation.
+func WithEventRecorder(r event.Recorder) Option {
+	return func(c *Connector) {
+		c.recorder = r
+	}
+}
+
+// WithProviderConfigTracker configures the Connector to track the usage of the
+// ProviderConfig referenced by each resource before connecting so that the
+// ProviderConfig cannot be deleted while it's still in use.
+func WithProviderConfigTracker(t xpresource.Tracker) Option {
+	return func(c *Connector) {
+		c.usage = t
+	}
+}
+
+// WithExecutionModeFn configures the Connector to select whether the
+// Terraform operations of each resource are run synchronously or
+// asynchronously using the given function instead of the UseAsync
+// configuration of the resource. The asynchronous mode requires a
+// CallbackProvider to be configured.
+func WithExecutionModeFn(fn ExecutionModeFn) Option {
+	return func(c *Connector) {
+		c.executionMode = fn
+	}
+}
+
+// WithValidation configures the controller to run "terraform validate" on the
+// generated configuration before each apply so that configuration errors are
+// reported without waiting on a slow apply to fail.
+func WithValidation() Option {
+	return func(c *Connector) {
+		c.validate = true
+	}
+}
+
+// WithNamespacedSetupFn configures the Connector to resolve the Terraform setup
+// of the resources for which the given ProviderConfigNamespaceFn returns a
+// namespace from the namespaced objects they reference using the given
+// function, e.g. with resource.ClaimNamespace. The other resources keep using
+// the cluster-scoped ProviderConfig.
+func WithNamespacedSetupFn(ns resource.ProviderConfigNamespaceFn, fn terraform.NamespacedSetupFn) Option {
+	return func(c *Connector) {
+		c.providerConfigNamespace = ns
+		c.getNamespacedSetup = fn
+	}
+}
+
+// WithStateBackup configures the controller to back up the Terraform state of
+// the resources in the given store after every successful refresh and apply
+// so that the state can be restored if the workspace is lost.
+func WithStateBackup(b StateBackupStore) Option {
+	return func(c *Connector) {
+		c.backup = b
+	}
+}
+
+// WithPrivateRawStore configures the controller to store the private
+// attributes of the Terraform state of the resources in the given store
+// instead of an annotation on the resources. The WorkspaceStore reads them from
+// the same store when it reproduces the states.
+func WithPrivateRawStore(s resource.PrivateRawStore) Option {
+	return func(c *Connector) {
+		c.privateRaw = s
+	}
+}
+
 // NewConnector returns a new Connector object.
 func NewConnector(kube client.Client, ws Store, sf terraform.SetupFn, cfg *config.Resource, opts ...Option) *Connector {
 	c := &Connector{
@@ -63,6 +170,9 @@ func NewConnector(kube client.Client, ws Store, sf terraform.SetupFn, cfg *confi
 		getTerraformSetup: sf,
 		store:             ws,
 		config:            cfg,
+		recorder:          event.NewNopRecorder(),
+		usage:             xpresource.TrackerFn(func(_ context.Context, _ xpresource.Managed) error { return nil }),
+		privateRaw:        resource.AnnotationPrivateRawStore{},
 	}
 	for _, f := range opts {
 		f(c)
@@ -76,8 +186,22 @@ type Connector struct {
 	kube              client.Client
 	store             Store
 	getTerraformSetup terraform.SetupFn
-	config            *config.Resource
-	callback          CallbackProvider
+	// getNamespacedSetup resolves the Terraform setup from the namespaced
+	// objects in the namespaces providerConfigNamespace returns. Only the
+	// cluster-scoped ProviderConfig is used if it is nil.
+	getNamespacedSetup      terraform.NamespacedSetupFn
+	providerConfigNamespace resource.ProviderConfigNamespaceFn
+	config                  *config.Resource
+	callback                CallbackProvider
+	recorder                event.Recorder
+	usage                   xpresource.Tracker
+	cleaner                 terraform.StoreCleaner
+	executionMode           ExecutionModeFn
+	validate                bool
+	backup                  StateBackupStore
+	privateRaw              resource.PrivateRawStore
+	moduleState             ModuleStateStore
+	tracer                  trace.Tracer
 }
 
 // Connect makes sure the underlying client is ready to issue requests to the
@@ -88,27 +212,133 @@ func (c *Connector) Connect(ctx context.Context, mg xpresource.Managed) (managed
 		return nil, errors.New(errUnexpectedObject)
 	}
 
-	ts, err := c.getTerraformSetup(ctx, c.kube, mg)
+	ts, err := c.setup(ctx, mg)
 	if err != nil {
-		return nil, errors.Wrap(err, errGetTerraformSetup)
+		return nil, err
 	}
 
-	tf, err := c.store.Workspace(ctx, &APISecretClient{kube: c.kube}, tr, ts, c.config)
+	if c.config.Env != nil {
+		env, err := c.config.Env(ctx, c.kube, mg)
+		if err != nil {
+			return nil, errors.Wrap(err, errGetEnv)
+		}
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ts.SetEnv(k, env[k])
+		}
+	}
+
+	tf, err := c.store.Workspace(terraform.ContextWithPrivateRawStore(ctx, c.privateRaw), &APISecretClient{kube: c.kube}, tr, ts, c.config)
 	if err != nil {
 		return nil, errors.Wrap(err, errGetWorkspace)
 	}
 
-	return &external{
-		workspace: tf,
-		config:    c.config,
-		callback:  c.callback,
-	}, nil
+	async, err := c.useAsync(ctx, mg)
+	if err != nil {
+		return nil, errors.Wrap(err, errExecutionMode)
+	}
+
+	var ec managed.ExternalClient = &external{
+		kube:       c.kube,
+		workspace:  tf,
+		config:     c.config,
+		callback:   c.callback,
+		recorder:   c.recorder,
+		cleaner:    c.cleaner,
+		async:      async,
+		validate:   c.validate,
+		backup:     c.backup,
+		privateRaw: c.privateRaw,
+	}
+	if c.tracer != nil {
+		ec = &tracedExternal{ExternalClient: ec, tracer: c.tracer}
+	}
+	return ec, nil
+}
+
+// setup returns the Terraform setup of the given resource, which is resolved
+// from the namespaced object it references if namespaced setup is enabled and
+// selects a namespace for it, or from its cluster-scoped ProviderConfig whose
+// usage is tracked otherwise.
+func (c *Connector) setup(ctx context.Context, mg xpresource.Managed) (terraform.Setup, error) {
+	var ts terraform.Setup
+	var err error
+	if ns := c.namespace(mg); ns != "" {
+		ts, err = c.getNamespacedSetup(ctx, c.kube, mg, resource.NamespacedProviderConfigRef(mg, ns))
+	} else {
+		if err := c.usage.Track(ctx, mg); err != nil {
+			return terraform.Setup{}, errors.Wrap(err, errTrackUsage)
+		}
+		ts, err = c.getTerraformSetup(ctx, c.kube, mg)
+	}
+	if err != nil {
+		err = errors.Wrap(err, errGetTerraformSetup)
+		// NOTE(muvaf): The setup fails for many reasons other than the
+		// credentials, e.g. a missing ProviderConfig, so only the errors
+		// that are marked by the SetupFn or report that the credentials are
+		// rejected are classified as invalid credentials.
+		if !tferrors.IsCredentialsInvalid(err) && tferrors.IsAuthenticationFailure(err) {
+			err = tferrors.NewCredentialsInvalid(err)
+		}
+		mg.SetConditions(resource.LastOperationCondition(err))
+		return terraform.Setup{}, err
+	}
+	return ts, nil
+}
+
+// namespace returns the namespace the provider configuration of the given
+// resource is resolved from, or an empty string if its cluster-scoped
+// ProviderConfig is used.
+func (c *Connector) namespace(mg xpresource.Managed) string {
+	if c.getNamespacedSetup == nil || c.providerConfigNamespace == nil {
+		return ""
+	}
+	return c.providerConfigNamespace(mg)
+}
+
+// useAsync returns whether the Terraform operations of the given resource
+// should be run asynchronously.
+func (c *Connector) useAsync(ctx context.Context, mg xpresource.Managed) (bool, error) {
+	if c.executionMode == nil {
+		return c.config.UseAsync, nil
+	}
+	m, err := c.executionMode(ctx, c.kube, mg, c.config)
+	if err != nil {
+		return false, err
+	}
+	switch m {
+	case ExecutionModeSync:
+		return false, nil
+	case ExecutionModeAsync:
+		if c.callback == nil {
+			return false, errors.New(errNoCallbackProvider)
+		}
+		return true, nil
+	case "":
+		return c.config.UseAsync, nil
+	default:
+		return false, errors.Errorf(errFmtUnknownExecutionMode, m)
+	}
 }
 
 type external struct {
+	kube      client.Client
 	workspace Workspace
 	config    *config.Resource
 	callback  CallbackProvider
+	recorder  event.Recorder
+	cleaner   terraform.StoreCleaner
+	// async is whether the Terraform operations are run asynchronously.
+	async bool
+	// validate is whether the configuration is validated before apply.
+	validate bool
+	backup   StateBackupStore
+	// privateRaw stores the private attributes of the Terraform state.
+	privateRaw resource.PrivateRawStore
 }
 
 func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.ExternalObservation, error) { //nolint:gocyclo
@@ -120,6 +350,9 @@ func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.
 	if !ok {
 		return managed.ExternalObservation{}, errors.New(errUnexpectedObject)
 	}
+	if err := e.addCleanupFinalizer(ctx, mg); err != nil {
+		return managed.ExternalObservation{}, err
+	}
 	res, err := e.workspace.Refresh(ctx)
 	if err != nil {
 		return managed.ExternalObservation{}, errors.Wrap(err, errRefresh)
@@ -132,28 +365,40 @@ func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.
 			ResourceUpToDate: true,
 		}, nil
 	case !res.Exists:
+		if err := e.removePrivateRaw(ctx, mg); err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		if err := e.removeBackup(ctx, mg); err != nil {
+			return managed.ExternalObservation{}, err
+		}
 		return managed.ExternalObservation{
 			ResourceExists: false,
-		}, nil
+		}, e.cleanup(ctx, mg)
+	}
+	if !res.Cached {
+		backupState(ctx, e.backup, e.recorder, mg, res.State)
+	}
+	if resource.DestroyPlanRequested(mg) {
+		e.planDestroy(ctx, mg)
 	}
 	// There might be a case where async operation is finished and the status
 	// update marking it as finished didn't go through. At this point, we are
 	// sure that there is no ongoing operation.
-	if e.config.UseAsync {
+	if e.async {
 		tr.SetConditions(resource.AsyncOperationFinishedCondition())
 	}
 
 	// No operation was in progress, our observation completed successfully, and
 	// we have an observation to consume.
-	tfstate := map[string]interface{}{}
-	if err := json.JSParser.Unmarshal(res.State.GetAttributes(), &tfstate); err != nil {
+	tfstate, err := res.State.DecodeAttributes()
+	if err != nil {
 		return managed.ExternalObservation{}, errors.Wrap(err, "cannot unmarshal state attributes")
 	}
-	if err := tr.SetObservation(tfstate); err != nil {
+	if err := tr.SetObservation(resource.CapObservationLists(tfstate, e.config.ObservationListCaps)); err != nil {
 		return managed.ExternalObservation{}, errors.Wrap(err, "cannot set observation")
 	}
 
-	annotationsUpdated, err := resource.SetCriticalAnnotations(tr, e.config, tfstate, string(res.State.GetPrivateRaw()))
+	annotationsUpdated, err := resource.SetCriticalState(ctx, e.privateRaw, tr, e.config, tfstate, res.State.GetPrivateRaw())
 	if err != nil {
 		return managed.ExternalObservation{}, errors.Wrap(err, "cannot set critical annotations")
 	}
@@ -161,12 +406,19 @@ func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.
 	if err != nil {
 		return managed.ExternalObservation{}, errors.Wrap(err, "cannot get connection details")
 	}
+	if conn, err = withOutputs(conn, res.State); err != nil {
+		return managed.ExternalObservation{}, err
+	}
 
 	lateInitedParams, err := tr.LateInitialize(res.State.GetAttributes())
 	if err != nil {
 		return managed.ExternalObservation{}, errors.Wrap(err, "cannot late initialize parameters")
 	}
 	markedAvailable := tr.GetCondition(xpv1.TypeReady).Equal(xpv1.Available())
+	ready, state := true, ""
+	if ps := e.config.PendingStates; ps != nil {
+		ready, state = ps.IsReady(tfstate)
+	}
 	// In the following switch block, before running a relatively costly
 	// Terraform apply and that may fail before critical annotations are
 	// updated, or late-initialized configuration is written to main.tf.json,
@@ -188,6 +440,19 @@ func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.
 			ConnectionDetails:       conn,
 			ResourceLateInitialized: true,
 		}, nil
+	// the resource exists but it is still being provisioned by the cloud
+	// provider
+	case !ready:
+		c := xpv1.Creating()
+		if markedAvailable {
+			c = xpv1.Unavailable()
+		}
+		tr.SetConditions(c.WithMessage(fmt.Sprintf(fmtPendingState, e.config.PendingStates.FieldPath, state)))
+		return managed.ExternalObservation{
+			ResourceExists:    true,
+			ResourceUpToDate:  true,
+			ConnectionDetails: conn,
+		}, nil
 	// we prioritize status updates over late-init'ed spec updates
 	case !markedAvailable:
 		tr.SetConditions(xpv1.Available())
@@ -205,31 +470,167 @@ func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.
 			ConnectionDetails:       conn,
 			ResourceLateInitialized: true,
 		}, nil
+	// the spec was found applied before and nothing has changed since then
+	case e.config.SkipUnchangedPlans && !res.Changed && resource.SpecApplied(tr, e.workspace.ConfigurationHash()) && !resource.ReplaceRequested(mg):
+		return managed.ExternalObservation{
+			ResourceExists:    true,
+			ResourceUpToDate:  true,
+			ConnectionDetails: conn,
+		}, nil
 	// now we do a Workspace.Refresh
 	default:
 		plan, err := e.workspace.Plan(ctx)
+		setPlanCondition(tr, err)
+		if err == nil {
+			recordPlan(e.recorder, tr, plan)
+		}
+		if err == nil && e.config.ExplainDrift {
+			e.explainDrift(ctx, tr, plan.UpToDate)
+		}
+		if err == nil && plan.UpToDate && e.config.SkipUnchangedPlans && !resource.ReplaceRequested(mg) {
+			// NOTE(muvaf): The annotation is persisted with the update of
+			// the object that follows a late-initialization.
+			if resource.SetAppliedSpecHash(tr, e.workspace.ConfigurationHash()) {
+				return managed.ExternalObservation{
+					ResourceExists:          true,
+					ResourceUpToDate:        true,
+					ConnectionDetails:       conn,
+					ResourceLateInitialized: true,
+				}, nil
+			}
+		}
+		// NOTE(muvaf): A requested replacement is applied even if there is
+		// no change in the configuration.
 		return managed.ExternalObservation{
 			ResourceExists:    true,
-			ResourceUpToDate:  plan.UpToDate,
+			ResourceUpToDate:  plan.UpToDate && !resource.ReplaceRequested(mg),
 			ConnectionDetails: conn,
 		}, errors.Wrap(err, errPlan)
 	}
 }
 
+// withOutputs adds the root module outputs in the given state, if any, to
+// the given connection details.
+func withOutputs(conn managed.ConnectionDetails, st *json.StateV4) (managed.ConnectionDetails, error) {
+	outputs, err := st.DecodeOutputs()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode outputs")
+	}
+	oc, err := resource.GetOutputConnectionDetails(outputs)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get output connection details")
+	}
+	if len(oc) == 0 {
+		return conn, nil
+	}
+	if conn == nil {
+		conn = make(managed.ConnectionDetails, len(oc))
+	}
+	for k, v := range oc {
+		conn[k] = v
+	}
+	return conn, nil
+}
+
+// setPlanCondition reports a failed plan in the LastOperation condition and
+// clears the failure once planning succeeds again.
+func setPlanCondition(mg xpresource.Managed, err error) {
+	switch {
+	case err != nil:
+		mg.SetConditions(resource.LastOperationCondition(err))
+	case mg.GetCondition(resource.TypeLastOperation).Reason == resource.ReasonPlanFailed:
+		mg.SetConditions(resource.LastOperationCondition(nil))
+	}
+}
+
+// recordPlan emits an event summarizing the changes the plan would make so
+// that the impending changes are visible before they are applied. Plans
+// that would destroy a resource, including the replacements, are reported
+// as warnings. No event is emitted for plans without changes so that the
+// resources that are up-to-date are not flooded with events on every poll.
+func recordPlan(r event.Recorder, o runtime.Object, plan terraform.PlanResult) {
+	switch {
+	case plan.Changes.Destroy != 0:
+		r.Event(o, event.Warning(reasonChangesPlanned, errors.New(plan.Changes.String())))
+	case plan.Changes.HasChanges():
+		r.Event(o, event.Normal(reasonChangesPlanned, plan.Changes.String()))
+	}
+}
+
+// planDestroy emits an event listing the changes a destroy would make and
+// removes the annotation that requested it so that the plan is reported once
+// per request. Failing to plan does not block the reconciliation.
+func (e *external) planDestroy(ctx context.Context, mg xpresource.Managed) {
+	p, err := e.workspace.PlanDestroy(ctx)
+	if err != nil {
+		e.recorder.Event(mg, event.Warning(reasonDestroyPlanned, errors.Wrap(err, errPlanDestroy)))
+	} else {
+		e.recorder.Event(mg, event.Normal(reasonDestroyPlanned, p.String()))
+	}
+	xpmeta.RemoveAnnotations(mg, resource.AnnotationKeyPlanDestroy)
+	if err := e.kube.Update(ctx, mg); err != nil {
+		e.recorder.Event(mg, event.Warning(reasonDestroyPlanned, errors.Wrapf(err, errFmtRemoveAnnotation, resource.AnnotationKeyPlanDestroy)))
+	}
+}
+
+// explainDrift sets the Drift condition of the resource and emits an event
+// explaining the drift if the resource is not up-to-date. Failing to explain
+// the drift does not block the reconciliation.
+func (e *external) explainDrift(ctx context.Context, tr resource.Terraformed, upToDate bool) {
+	if upToDate {
+		tr.SetConditions(resource.NoDriftCondition())
+		return
+	}
+	report, err := e.workspace.Drift(ctx)
+	if err != nil {
+		tr.SetConditions(resource.DriftUnknownCondition(errors.Wrap(err, errDrift)))
+		return
+	}
+	msg := tferrors.Truncate(report.String(), maxDriftMessageSize, "")
+	tr.SetConditions(resource.DriftDetectedCondition(msg))
+	e.recorder.Event(tr, event.Normal(reasonDriftDetected, msg))
+}
+
+// recordOperation emits an event summarizing the given Terraform operation
+// with its exit code, duration and output size so that abnormally slow or
+// flapping resources can be spotted from their events alone.
+func recordOperation(r event.Recorder, o runtime.Object, res terraform.OperationResult, err error) {
+	if res.Type == "" {
+		return
+	}
+	if err != nil {
+		r.Event(o, event.Warning(reasonOperationFailed, errors.New(res.String())))
+		if res.TracePath != "" {
+			r.Event(o, event.Warning(reasonOperationTrace, errors.Errorf(errFmtTraceKept, res.TracePath)))
+		}
+		return
+	}
+	r.Event(o, event.Normal(reasonOperationSucceeded, res.String()))
+}
+
 func (e *external) Create(ctx context.Context, mg xpresource.Managed) (managed.ExternalCreation, error) {
-	if e.config.UseAsync {
-		return managed.ExternalCreation{}, errors.Wrap(e.workspace.ApplyAsync(e.callback.Apply(mg.GetName())), errStartAsyncApply)
+	if err := e.checkQuota(ctx, mg); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	if err := e.validateConfig(ctx, mg); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	if e.async {
+		return managed.ExternalCreation{}, errors.Wrap(e.workspace.ApplyAsync(e.callback.Apply(mg.GetName(), mg.GetGeneration())), errStartAsyncApply)
 	}
 	tr, ok := mg.(resource.Terraformed)
 	if !ok {
 		return managed.ExternalCreation{}, errors.New(errUnexpectedObject)
 	}
 	res, err := e.workspace.Apply(ctx)
+	recordOperation(e.recorder, mg, res.Operation, err)
+	mg.SetConditions(resource.LastOperationCondition(err))
 	if err != nil {
 		return managed.ExternalCreation{}, errors.Wrap(err, errApply)
 	}
-	tfstate := map[string]interface{}{}
-	if err := json.JSParser.Unmarshal(res.State.GetAttributes(), &tfstate); err != nil {
+	backupState(ctx, e.backup, e.recorder, mg, res.State)
+	tfstate, err := res.State.DecodeAttributes()
+	if err != nil {
 		return managed.ExternalCreation{}, errors.Wrap(err, "cannot unmarshal state attributes")
 	}
 
@@ -239,32 +640,164 @@ func (e *external) Create(ctx context.Context, mg xpresource.Managed) (managed.E
 	}
 
 	// NOTE(muvaf): Only spec and metadata changes are saved after Create call.
-	_, err = resource.SetCriticalAnnotations(tr, e.config, tfstate, string(res.State.GetPrivateRaw()))
+	tjmeta.SetLastApply(mg, time.Now(), mg.GetGeneration())
+	e.recordAppliedConfiguration(tr)
+	_, err = resource.SetCriticalState(ctx, e.privateRaw, tr, e.config, tfstate, res.State.GetPrivateRaw())
 	return managed.ExternalCreation{ConnectionDetails: conn}, errors.Wrap(err, "cannot set critical annotations")
 }
 
+// checkQuota runs the quota check of the resource, if any, and sets the
+// QuotaExceeded condition if the check fails.
+func (e *external) checkQuota(ctx context.Context, mg xpresource.Managed) error {
+	if e.config.QuotaCheck == nil {
+		return nil
+	}
+	if err := e.config.QuotaCheck(ctx, e.kube, mg); err != nil {
+		mg.SetConditions(resource.QuotaExceededCondition(err))
+		return errors.Wrap(err, errQuotaExceeded)
+	}
+	if mg.GetCondition(resource.TypeQuotaExceeded).Reason == resource.ReasonQuotaExceeded {
+		mg.SetConditions(resource.QuotaAvailableCondition())
+	}
+	return nil
+}
+
+// validateConfig validates the configuration of the resource if validation
+// is enabled, and sets the LastOperation condition with the diagnostics if
+// the configuration is invalid.
+func (e *external) validateConfig(ctx context.Context, mg xpresource.Managed) error {
+	if !e.validate {
+		return nil
+	}
+	if err := e.workspace.Validate(ctx); err != nil {
+		mg.SetConditions(resource.LastOperationCondition(err))
+		return errors.Wrap(err, errValidate)
+	}
+	return nil
+}
+
 func (e *external) Update(ctx context.Context, mg xpresource.Managed) (managed.ExternalUpdate, error) {
-	if e.config.UseAsync {
-		return managed.ExternalUpdate{}, errors.Wrap(e.workspace.ApplyAsync(e.callback.Apply(mg.GetName())), errStartAsyncApply)
+	if err := e.validateConfig(ctx, mg); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	if e.async {
+		return managed.ExternalUpdate{}, errors.Wrap(e.workspace.ApplyAsync(e.callback.Apply(mg.GetName(), mg.GetGeneration())), errStartAsyncApply)
 	}
 	tr, ok := mg.(resource.Terraformed)
 	if !ok {
 		return managed.ExternalUpdate{}, errors.New(errUnexpectedObject)
 	}
 	res, err := e.workspace.Apply(ctx)
+	recordOperation(e.recorder, mg, res.Operation, err)
 	if err != nil {
+		mg.SetConditions(resource.LastOperationCondition(err))
 		return managed.ExternalUpdate{}, errors.Wrap(err, errApply)
 	}
-	attr := map[string]interface{}{}
-	if err := json.JSParser.Unmarshal(res.State.GetAttributes(), &attr); err != nil {
+	backupState(ctx, e.backup, e.recorder, mg, res.State)
+	// NOTE(muvaf): The resource is updated before its conditions are set
+	// since the update overwrites its status with the one in the API server.
+	if err := e.applied(ctx, mg); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	mg.SetConditions(resource.LastOperationCondition(nil))
+	attr, err := res.State.DecodeAttributes()
+	if err != nil {
 		return managed.ExternalUpdate{}, errors.Wrap(err, "cannot unmarshal state attributes")
 	}
-	return managed.ExternalUpdate{}, errors.Wrap(tr.SetObservation(attr), "cannot set observation")
+	return managed.ExternalUpdate{}, errors.Wrap(tr.SetObservation(resource.CapObservationLists(attr, e.config.ObservationListCaps)), "cannot set observation")
+}
+
+// applied records the time of the successful apply of the resource and
+// removes the annotation that requested its replacement, if any, since the
+// apply has replaced it.
+func (e *external) applied(ctx context.Context, mg xpresource.Managed) error {
+	tjmeta.SetLastApply(mg, time.Now(), mg.GetGeneration())
+	e.recordAppliedConfiguration(mg)
+	xpmeta.RemoveAnnotations(mg, resource.AnnotationKeyReplace)
+	return errors.Wrap(e.kube.Update(ctx, mg), errRecordApply)
+}
+
+// recordAppliedConfiguration records the hash of the Terraform configuration a
+// successful apply ran with if unchanged plans are skipped, so that the plan
+// is skipped until the configuration changes.
+func (e *external) recordAppliedConfiguration(mg xpresource.Managed) {
+	if e.config.SkipUnchangedPlans {
+		resource.SetAppliedSpecHash(mg, e.workspace.ConfigurationHash())
+	}
 }
 
 func (e *external) Delete(ctx context.Context, mg xpresource.Managed) error {
-	if e.config.UseAsync {
+	if dp := e.config.DeletionProtection; dp != nil {
+		if destroy, err := e.handleDeletionProtection(ctx, mg, dp); err != nil || !destroy {
+			return err
+		}
+	}
+	if e.async {
 		return errors.Wrap(e.workspace.DestroyAsync(e.callback.Destroy(mg.GetName())), errStartAsyncDestroy)
 	}
-	return errors.Wrap(e.workspace.Destroy(ctx), errDestroy)
+	res, err := e.workspace.Destroy(ctx)
+	recordOperation(e.recorder, mg, res.Operation, err)
+	mg.SetConditions(resource.LastOperationCondition(err))
+	if dp := e.config.DeletionProtection; dp != nil && tferrors.IsDestroyFailed(err) && dp.Blocks(err.Error()) {
+		mg.SetConditions(resource.DeletionBlockedExternallyCondition(resource.DeletionProtectionHint(dp)))
+	}
+	return errors.Wrap(err, errDestroy)
+}
+
+// handleDeletionProtection disables the deletion protection of the external
+// resource if the resource is annotated to do so, and reports whether the
+// failure of the last async destroy operation was caused by the deletion
+// protection. It returns whether the resource can be destroyed now, which is
+// not the case in async mode until the apply that disables the deletion
+// protection completes.
+func (e *external) handleDeletionProtection(ctx context.Context, mg xpresource.Managed, dp *config.DeletionProtection) (bool, error) {
+	if resource.DeletionProtectionOverridden(mg) {
+		if mg.GetCondition(resource.TypeDeletionBlockedExternally).Reason == resource.ReasonDeletionProtectionDisabled {
+			return true, nil
+		}
+		if e.async {
+			// NOTE(muvaf): The apply that disables the deletion protection
+			// may still be running, in which case the callback requests
+			// another reconciliation once it completes.
+			if mg.GetCondition(resource.TypeAsyncOperation).Reason == resource.ReasonAsyncInProgress {
+				return false, nil
+			}
+			cb := e.deletionProtectionDisabled(mg, e.callback.Apply(mg.GetName(), mg.GetGeneration()))
+			return false, errors.Wrap(e.workspace.ApplyAsync(cb), errStartAsyncDisableDeletionProtection)
+		}
+		if _, err := e.workspace.Apply(ctx); err != nil {
+			return false, errors.Wrap(err, errDisableDeletionProtection)
+		}
+		mg.SetConditions(resource.DeletionProtectionDisabledCondition())
+		return true, nil
+	}
+	if c := mg.GetCondition(resource.TypeLastAsyncOperation); c.Reason == resource.ReasonDestroyFailed && dp.Blocks(c.Message) {
+		mg.SetConditions(resource.DeletionBlockedExternallyCondition(resource.DeletionProtectionHint(dp)))
+	}
+	return true, nil
+}
+
+// deletionProtectionDisabled returns a callback that records on the given
+// resource that its deletion protection is disabled if the async apply
+// succeeds, and then calls the given callback. The condition is recorded
+// first so that the reconciliation the given callback requests destroys the
+// resource.
+func (e *external) deletionProtectionDisabled(mg xpresource.Managed, cb terraform.CallbackFn) terraform.CallbackFn {
+	return func(err error, ctx context.Context) error {
+		var dErr error
+		if err == nil {
+			dErr = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				o := mg.DeepCopyObject().(xpresource.Managed)
+				if err := e.kube.Get(ctx, client.ObjectKeyFromObject(mg), o); err != nil {
+					return errors.Wrap(err, errGet)
+				}
+				o.SetConditions(resource.DeletionProtectionDisabledCondition())
+				return errors.Wrap(e.kube.Status().Update(ctx, o), errStatusUpdate)
+			})
+		}
+		if cErr := cb(err, ctx); cErr != nil {
+			return cErr
+		}
+		return errors.Wrap(dErr, errRecordDeletionProtectionDisabled)
+	}
 }
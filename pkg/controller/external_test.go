@@ -18,9 +18,13 @@ package controller
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
@@ -29,13 +33,17 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/crossplane/terrajet/pkg/config"
+	tjmeta "github.com/crossplane/terrajet/pkg/meta"
 	"github.com/crossplane/terrajet/pkg/resource"
 	"github.com/crossplane/terrajet/pkg/resource/fake"
 	"github.com/crossplane/terrajet/pkg/resource/json"
 	"github.com/crossplane/terrajet/pkg/terraform"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
 )
 
 var (
@@ -57,15 +65,27 @@ type WorkspaceFns struct {
 	ApplyAsyncFn   func(callback terraform.CallbackFn) error
 	ApplyFn        func(ctx context.Context) (terraform.ApplyResult, error)
 	DestroyAsyncFn func(callback terraform.CallbackFn) error
-	DestroyFn      func(ctx context.Context) error
+	DestroyFn      func(ctx context.Context) (terraform.DestroyResult, error)
 	RefreshFn      func(ctx context.Context) (terraform.RefreshResult, error)
 	PlanFn         func(ctx context.Context) (terraform.PlanResult, error)
+	ValidateFn     func(ctx context.Context) error
+	DriftFn        func(ctx context.Context) (terraform.DriftReport, error)
+	PlanDestroyFn  func(ctx context.Context) (terraform.DestroyPlan, error)
+	ImportFn       func(ctx context.Context, id string) (terraform.ImportResult, error)
+	DiffFn         func(ctx context.Context) (terraform.Diff, error)
+	OutputsFn      func(ctx context.Context) (map[string]terraform.Output, error)
+
+	ConfigurationHashFn func() string
 }
 
 func (c WorkspaceFns) ApplyAsync(callback terraform.CallbackFn) error {
 	return c.ApplyAsyncFn(callback)
 }
 
+func (c WorkspaceFns) ConfigurationHash() string {
+	return c.ConfigurationHashFn()
+}
+
 func (c WorkspaceFns) Apply(ctx context.Context) (terraform.ApplyResult, error) {
 	return c.ApplyFn(ctx)
 }
@@ -74,7 +94,7 @@ func (c WorkspaceFns) DestroyAsync(callback terraform.CallbackFn) error {
 	return c.DestroyAsyncFn(callback)
 }
 
-func (c WorkspaceFns) Destroy(ctx context.Context) error {
+func (c WorkspaceFns) Destroy(ctx context.Context) (terraform.DestroyResult, error) {
 	return c.DestroyFn(ctx)
 }
 
@@ -86,21 +106,57 @@ func (c WorkspaceFns) Plan(ctx context.Context) (terraform.PlanResult, error) {
 	return c.PlanFn(ctx)
 }
 
+func (c WorkspaceFns) Validate(ctx context.Context) error {
+	return c.ValidateFn(ctx)
+}
+
+func (c WorkspaceFns) Drift(ctx context.Context) (terraform.DriftReport, error) {
+	return c.DriftFn(ctx)
+}
+
+func (c WorkspaceFns) PlanDestroy(ctx context.Context) (terraform.DestroyPlan, error) {
+	return c.PlanDestroyFn(ctx)
+}
+
+func (c WorkspaceFns) Import(ctx context.Context, id string) (terraform.ImportResult, error) {
+	return c.ImportFn(ctx, id)
+}
+
+func (c WorkspaceFns) Diff(ctx context.Context) (terraform.Diff, error) {
+	return c.DiffFn(ctx)
+}
+
+func (c WorkspaceFns) Outputs(ctx context.Context) (map[string]terraform.Output, error) {
+	return c.OutputsFn(ctx)
+}
+
+type eventRecorder struct {
+	events []event.Event
+}
+
+func (r *eventRecorder) Event(_ runtime.Object, e event.Event) {
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) WithAnnotations(_ ...string) event.Recorder {
+	return r
+}
+
 type StoreFns struct {
-	WorkspaceFn func(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (*terraform.Workspace, error)
+	WorkspaceFn func(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error)
 }
 
-func (s StoreFns) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (*terraform.Workspace, error) {
+func (s StoreFns) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error) {
 	return s.WorkspaceFn(ctx, c, tr, ts, cfg)
 }
 
 type CallbackFns struct {
-	ApplyFn   func(string) terraform.CallbackFn
+	ApplyFn   func(string, int64) terraform.CallbackFn
 	DestroyFn func(string) terraform.CallbackFn
 }
 
-func (c CallbackFns) Apply(name string) terraform.CallbackFn {
-	return c.ApplyFn(name)
+func (c CallbackFns) Apply(name string, generation int64) terraform.CallbackFn {
+	return c.ApplyFn(name, generation)
 }
 
 func (c CallbackFns) Destroy(name string) terraform.CallbackFn {
@@ -112,6 +168,8 @@ func TestConnect(t *testing.T) {
 		setupFn terraform.SetupFn
 		store   Store
 		obj     xpresource.Managed
+		opts    []Option
+		cfg     *config.Resource
 	}
 	type want struct {
 		err error
@@ -129,6 +187,73 @@ func TestConnect(t *testing.T) {
 				err: errors.New(errUnexpectedObject),
 			},
 		},
+		"TrackUsageFailed": {
+			reason: "ProviderConfig usage should be tracked before connecting",
+			args: args{
+				obj: &fake.Terraformed{},
+				opts: []Option{WithProviderConfigTracker(xpresource.TrackerFn(func(_ context.Context, _ xpresource.Managed) error {
+					return errBoom
+				}))},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errTrackUsage),
+			},
+		},
+		"NamespacedNotEnabled": {
+			reason: "The cluster-scoped ProviderConfig should be used if namespaced setup is not enabled",
+			args: args{
+				obj: &fake.Terraformed{Managed: xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+					resource.LabelKeyClaimNamespace: "tenant",
+				}}}},
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{}, errBoom
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errGetTerraformSetup),
+			},
+		},
+		"NamespaceNotSelected": {
+			reason: "The cluster-scoped ProviderConfig should be used if no namespace is selected for the resource",
+			args: args{
+				obj: &fake.Terraformed{Managed: xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+					"terrajet.crossplane.io/provider-config-namespace": "tenant",
+				}}}},
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{}, errBoom
+				},
+				opts: []Option{
+					WithNamespacedSetupFn(resource.ClaimNamespace, func(_ context.Context, _ client.Client, _ xpresource.Managed, _ types.NamespacedName) (terraform.Setup, error) {
+						return terraform.Setup{}, errors.New("namespaced setup should not be used")
+					}),
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errGetTerraformSetup),
+			},
+		},
+		"NamespacedSetupFailed": {
+			reason: "The setup of a resource composed for a claim should be resolved from the namespaced object without tracking the ProviderConfig usage",
+			args: args{
+				obj: &fake.Terraformed{Managed: xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+					resource.LabelKeyClaimNamespace: "tenant",
+				}}}},
+				opts: []Option{
+					WithProviderConfigTracker(xpresource.TrackerFn(func(_ context.Context, _ xpresource.Managed) error {
+						return errors.New("usage should not be tracked")
+					})),
+					WithNamespacedSetupFn(resource.ClaimNamespace, func(_ context.Context, _ client.Client, _ xpresource.Managed, ref types.NamespacedName) (terraform.Setup, error) {
+						if diff := cmp.Diff(types.NamespacedName{Namespace: "tenant", Name: "default"}, ref); diff != "" {
+							t.Errorf("NamespacedSetupFn(...): -want ref, +got ref:\n%s", diff)
+						}
+						return terraform.Setup{}, errBoom
+					}),
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errGetTerraformSetup),
+			},
+		},
 		"SetupFailed": {
 			reason: "Terraform setup should succeed",
 			args: args{
@@ -141,6 +266,30 @@ func TestConnect(t *testing.T) {
 				err: errors.Wrap(errBoom, errGetTerraformSetup),
 			},
 		},
+		"SetupCredentialsRejected": {
+			reason: "A setup error that reports rejected credentials should be classified as invalid credentials",
+			args: args{
+				obj: &fake.Terraformed{},
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{}, errors.New("cannot assume role: ExpiredToken: the security token is expired")
+				},
+			},
+			want: want{
+				err: tferrors.NewCredentialsInvalid(errors.Wrap(errors.New("cannot assume role: ExpiredToken: the security token is expired"), errGetTerraformSetup)),
+			},
+		},
+		"SetupCredentialsInvalid": {
+			reason: "A setup error marked as invalid credentials by the SetupFn should be kept as is",
+			args: args{
+				obj: &fake.Terraformed{},
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{}, tferrors.NewCredentialsInvalid(errBoom)
+				},
+			},
+			want: want{
+				err: errors.Wrap(tferrors.NewCredentialsInvalid(errBoom), errGetTerraformSetup),
+			},
+		},
 		"WorkspaceFailed": {
 			reason: "We must get workspace successfully",
 			args: args{
@@ -149,7 +298,7 @@ func TestConnect(t *testing.T) {
 					return terraform.Setup{}, nil
 				},
 				store: StoreFns{
-					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (*terraform.Workspace, error) {
+					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (Workspace, error) {
 						return nil, errBoom
 					},
 				},
@@ -158,6 +307,81 @@ func TestConnect(t *testing.T) {
 				err: errors.Wrap(errBoom, errGetWorkspace),
 			},
 		},
+		"AsyncWithoutCallbackProvider": {
+			reason: "Async execution mode should not be selected without a callback provider",
+			args: args{
+				obj: &fake.Terraformed{},
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{}, nil
+				},
+				store: StoreFns{
+					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (Workspace, error) {
+						return nil, nil
+					},
+				},
+				opts: []Option{WithExecutionModeFn(ExecutionModesByKind(map[string]ExecutionMode{"": ExecutionModeAsync}))},
+			},
+			want: want{
+				err: errors.Wrap(errors.New(errNoCallbackProvider), errExecutionMode),
+			},
+		},
+		"UnknownExecutionMode": {
+			reason: "An unknown execution mode should be rejected",
+			args: args{
+				obj: &fake.Terraformed{},
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{}, nil
+				},
+				store: StoreFns{
+					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (Workspace, error) {
+						return nil, nil
+					},
+				},
+				opts: []Option{WithExecutionModeFn(ExecutionModesByKind(map[string]ExecutionMode{"": "Eventually"}))},
+			},
+			want: want{
+				err: errors.Wrap(errors.Errorf(errFmtUnknownExecutionMode, "Eventually"), errExecutionMode),
+			},
+		},
+		"EnvFailed": {
+			reason: "It should return error if it cannot get the environment of the resource",
+			args: args{
+				obj: &fake.Terraformed{},
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{}, nil
+				},
+				cfg: &config.Resource{
+					Env: func(_ context.Context, _ client.Client, _ xpresource.Managed) (map[string]string, error) {
+						return nil, errBoom
+					},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errGetEnv),
+			},
+		},
+		"Env": {
+			reason: "The environment of the resource should override the environment of the setup",
+			args: args{
+				obj: &fake.Terraformed{},
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{Env: []string{"AWS_REGION=us-east-1", "AWS_PROFILE=default"}}, nil
+				},
+				cfg: &config.Resource{
+					Env: func(_ context.Context, _ client.Client, _ xpresource.Managed) (map[string]string, error) {
+						return map[string]string{"AWS_PROFILE": "team-a", "AWS_CONFIG_FILE": "/creds/config"}, nil
+					},
+				},
+				store: StoreFns{
+					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, ts terraform.Setup, _ *config.Resource) (Workspace, error) {
+						if diff := cmp.Diff([]string{"AWS_REGION=us-east-1", "AWS_CONFIG_FILE=/creds/config", "AWS_PROFILE=team-a"}, ts.Env); diff != "" {
+							return nil, errors.New(diff)
+						}
+						return nil, nil
+					},
+				},
+			},
+		},
 		"Success": {
 			args: args{
 				obj: &fake.Terraformed{},
@@ -165,7 +389,7 @@ func TestConnect(t *testing.T) {
 					return terraform.Setup{}, nil
 				},
 				store: StoreFns{
-					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (*terraform.Workspace, error) {
+					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (Workspace, error) {
 						return nil, nil
 					},
 				},
@@ -174,7 +398,11 @@ func TestConnect(t *testing.T) {
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			c := NewConnector(nil, tc.args.store, tc.args.setupFn, &config.Resource{})
+			cfg := tc.args.cfg
+			if cfg == nil {
+				cfg = &config.Resource{}
+			}
+			c := NewConnector(nil, tc.args.store, tc.args.setupFn, cfg, tc.args.opts...)
 			_, err := c.Connect(context.TODO(), tc.args.obj)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nConnect(...): -want error, +got error:\n%s", tc.reason, diff)
@@ -183,14 +411,53 @@ func TestConnect(t *testing.T) {
 	}
 }
 
+func TestConnectFakeStore(t *testing.T) {
+	// A Store that is not backed by a terraform.WorkspaceStore should be
+	// able to provide the Workspace the external client runs Terraform
+	// operations with.
+	store := StoreFns{
+		WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (Workspace, error) {
+			return WorkspaceFns{
+				RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+					return terraform.RefreshResult{Exists: true, IsApplying: true}, nil
+				},
+			}, nil
+		},
+	}
+	setupFn := func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+		return terraform.Setup{}, nil
+	}
+	e, err := NewConnector(nil, store, setupFn, &config.Resource{}).Connect(context.TODO(), &fake.Terraformed{})
+	if err != nil {
+		t.Fatalf("Connect(...): %s", err)
+	}
+	obs, err := e.Observe(context.TODO(), &fake.Terraformed{})
+	if err != nil {
+		t.Fatalf("Observe(...): %s", err)
+	}
+	want := managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}
+	if diff := cmp.Diff(want, obs); diff != "" {
+		t.Errorf("Observe(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestNewWorkspaceProvider(t *testing.T) {
+	// The modules are run only if the WorkspaceProvider is a ModuleStore.
+	if _, ok := NewWorkspaceProvider(terraform.NewWorkspaceStore(logging.NewNopLogger())).(ModuleStore); !ok {
+		t.Errorf("NewWorkspaceProvider(...): the returned WorkspaceProvider is not a ModuleStore")
+	}
+}
+
 func TestObserve(t *testing.T) {
 	type args struct {
 		w   Workspace
+		cfg *config.Resource
 		obj xpresource.Managed
 	}
 	type want struct {
-		obs managed.ExternalObservation
-		err error
+		obs   managed.ExternalObservation
+		err   error
+		ready xpv1.ConditionReason
 	}
 	cases := map[string]struct {
 		reason string
@@ -271,6 +538,40 @@ func TestObserve(t *testing.T) {
 				},
 			},
 		},
+		"PendingState": {
+			reason: "We should not mark the resource as ready if its state attribute has not reached a ready value",
+			args: args{
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								xpmeta.AnnotationKeyExternalName: "some-id",
+							},
+						},
+					},
+				},
+				cfg: func() *config.Resource {
+					r := config.DefaultResource("terrajet_resource", nil)
+					r.PendingStates = &config.PendingStates{FieldPath: "obs", ReadyValues: []string{"ACTIVE"}}
+					return r
+				}(),
+				w: WorkspaceFns{
+					RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+						return terraform.RefreshResult{
+							Exists: true,
+							State:  exampleState,
+						}, nil
+					},
+				},
+			},
+			want: want{
+				obs: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: true,
+				},
+				ready: xpv1.ReasonCreating,
+			},
+		},
 		"PlanFailed": {
 			reason: "Failure of plan should be reported",
 			args: args{
@@ -302,6 +603,46 @@ func TestObserve(t *testing.T) {
 				err: errors.Wrap(errBoom, errPlan),
 			},
 		},
+		"SkipUnchangedPlan": {
+			reason: "Plan should be skipped if the spec was found applied before and the refresh has not changed the state",
+			args: args{
+				cfg: func() *config.Resource {
+					cfg := config.DefaultResource("terrajet_resource", nil)
+					cfg.SkipUnchangedPlans = true
+					return cfg
+				}(),
+				obj: func() *fake.Terraformed {
+					tr := &fake.Terraformed{
+						Managed: xpfake.Managed{
+							ObjectMeta: metav1.ObjectMeta{
+								Annotations: map[string]string{
+									xpmeta.AnnotationKeyExternalName: "some-id",
+								},
+							},
+							ConditionedStatus: xpv1.ConditionedStatus{
+								Conditions: []xpv1.Condition{xpv1.Available()},
+							},
+						},
+					}
+					resource.SetAppliedSpecHash(tr, "very-cool-hash")
+					return tr
+				}(),
+				w: WorkspaceFns{
+					RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+						return terraform.RefreshResult{
+							Exists: true,
+							State:  exampleState,
+						}, nil
+					},
+					PlanFn: func(_ context.Context) (terraform.PlanResult, error) {
+						return terraform.PlanResult{}, errBoom
+					},
+					ConfigurationHashFn: func() string {
+						return "very-cool-hash"
+					},
+				},
+			},
+		},
 		"Success": {
 			args: args{
 				obj: &fake.Terraformed{
@@ -334,11 +675,20 @@ func TestObserve(t *testing.T) {
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := &external{workspace: tc.w, config: config.DefaultResource("terrajet_resource", nil)}
+			cfg := tc.args.cfg
+			if cfg == nil {
+				cfg = config.DefaultResource("terrajet_resource", nil)
+			}
+			e := &external{workspace: tc.w, config: cfg}
 			_, err := e.Observe(context.TODO(), tc.args.obj)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nObserve(...): -want error, +got error:\n%s", tc.reason, diff)
 			}
+			if tc.want.ready != "" {
+				if diff := cmp.Diff(tc.want.ready, tc.args.obj.GetCondition(xpv1.TypeReady).Reason); diff != "" {
+					t.Errorf("\n%s\nObserve(...): -want ready reason, +got ready reason:\n%s", tc.reason, diff)
+				}
+			}
 		})
 	}
 }
@@ -374,7 +724,7 @@ func TestCreate(t *testing.T) {
 					UseAsync: true,
 				},
 				c: CallbackFns{
-					ApplyFn: func(s string) terraform.CallbackFn {
+					ApplyFn: func(_ string, _ int64) terraform.CallbackFn {
 						return nil
 					},
 				},
@@ -389,6 +739,20 @@ func TestCreate(t *testing.T) {
 				err: errors.Wrap(errBoom, errStartAsyncApply),
 			},
 		},
+		"QuotaExceeded": {
+			reason: "It should fail fast without applying if the quota check fails",
+			args: args{
+				cfg: &config.Resource{
+					QuotaCheck: func(_ context.Context, _ client.Client, _ xpresource.Managed) error {
+						return errBoom
+					},
+				},
+				obj: &fake.Terraformed{},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errQuotaExceeded),
+			},
+		},
 		"SyncApplyFailed": {
 			reason: "It should return error if it cannot apply in sync mode",
 			args: args{
@@ -407,7 +771,7 @@ func TestCreate(t *testing.T) {
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := &external{workspace: tc.w, callback: tc.c, config: tc.cfg}
+			e := &external{workspace: tc.w, callback: tc.c, config: tc.cfg, async: tc.cfg.UseAsync}
 			_, err := e.Create(context.TODO(), tc.args.obj)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nCreate(...): -want error, +got error:\n%s", tc.reason, diff)
@@ -418,10 +782,12 @@ func TestCreate(t *testing.T) {
 
 func TestUpdate(t *testing.T) {
 	type args struct {
-		w   Workspace
-		cfg *config.Resource
-		c   CallbackProvider
-		obj xpresource.Managed
+		w        Workspace
+		cfg      *config.Resource
+		c        CallbackProvider
+		obj      xpresource.Managed
+		validate bool
+		kube     client.Client
 	}
 	type want struct {
 		err error
@@ -447,7 +813,7 @@ func TestUpdate(t *testing.T) {
 					UseAsync: true,
 				},
 				c: CallbackFns{
-					ApplyFn: func(s string) terraform.CallbackFn {
+					ApplyFn: func(_ string, _ int64) terraform.CallbackFn {
 						return nil
 					},
 				},
@@ -477,10 +843,71 @@ func TestUpdate(t *testing.T) {
 				err: errors.Wrap(errBoom, errApply),
 			},
 		},
+		"ValidateFailed": {
+			reason: "It should return error without applying if the configuration is invalid",
+			args: args{
+				cfg:      &config.Resource{},
+				obj:      &fake.Terraformed{},
+				validate: true,
+				w: WorkspaceFns{
+					ValidateFn: func(_ context.Context) error {
+						return errBoom
+					},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errValidate),
+			},
+		},
+		"SyncApplied": {
+			reason: "It should record the time of the apply and remove the replace annotation once the resource is replaced in sync mode",
+			args: args{
+				cfg: &config.Resource{},
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								resource.AnnotationKeyReplace: "true",
+							},
+						},
+					},
+				},
+				kube: &test.MockClient{MockUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+					if tjmeta.GetLastApplyTime(obj).IsZero() {
+						t.Errorf("\nUpdate(...): the time of the apply is not recorded")
+					}
+					if resource.ReplaceRequested(obj) {
+						t.Errorf("\nUpdate(...): the replace annotation is not removed")
+					}
+					return nil
+				}},
+				w: WorkspaceFns{
+					ApplyFn: func(_ context.Context) (terraform.ApplyResult, error) {
+						return terraform.ApplyResult{State: exampleState}, nil
+					},
+				},
+			},
+		},
+		"CannotRecordApply": {
+			reason: "It should return error if it cannot record the time of the apply in sync mode",
+			args: args{
+				cfg:  &config.Resource{},
+				obj:  &fake.Terraformed{},
+				kube: &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				w: WorkspaceFns{
+					ApplyFn: func(_ context.Context) (terraform.ApplyResult, error) {
+						return terraform.ApplyResult{State: exampleState}, nil
+					},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errRecordApply),
+			},
+		},
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := &external{workspace: tc.w, callback: tc.c, config: tc.cfg}
+			e := &external{workspace: tc.w, callback: tc.c, config: tc.cfg, async: tc.cfg.UseAsync, validate: tc.args.validate, kube: tc.args.kube}
 			_, err := e.Update(context.TODO(), tc.args.obj)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nCreate(...): -want error, +got error:\n%s", tc.reason, diff)
@@ -491,13 +918,30 @@ func TestUpdate(t *testing.T) {
 
 func TestDelete(t *testing.T) {
 	type args struct {
-		w   Workspace
-		cfg *config.Resource
-		c   CallbackProvider
-		obj xpresource.Managed
+		w    Workspace
+		cfg  *config.Resource
+		c    CallbackProvider
+		kube client.Client
+		obj  xpresource.Managed
+	}
+	annotated := func(c ...xpv1.Condition) *fake.Terraformed {
+		tr := &fake.Terraformed{
+			Managed: xpfake.Managed{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "example",
+					DeletionTimestamp: &metav1.Time{Time: time.Now()},
+					Annotations: map[string]string{
+						resource.AnnotationKeyDisableDeletionProtection: "true",
+					},
+				},
+			},
+		}
+		tr.SetConditions(c...)
+		return tr
 	}
 	type want struct {
-		err error
+		err       error
+		condition xpv1.ConditionReason
 	}
 	cases := map[string]struct {
 		reason string
@@ -532,8 +976,8 @@ func TestDelete(t *testing.T) {
 				obj: &fake.Terraformed{},
 				cfg: &config.Resource{},
 				w: WorkspaceFns{
-					DestroyFn: func(_ context.Context) error {
-						return errBoom
+					DestroyFn: func(_ context.Context) (terraform.DestroyResult, error) {
+						return terraform.DestroyResult{}, errBoom
 					},
 				},
 			},
@@ -541,14 +985,357 @@ func TestDelete(t *testing.T) {
 				err: errors.Wrap(errBoom, errDestroy),
 			},
 		},
+		"DeletionBlockedExternally": {
+			reason: "It should report that the deletion is blocked if destroy fails due to deletion protection",
+			args: args{
+				obj: &fake.Terraformed{},
+				cfg: &config.Resource{
+					DeletionProtection: &config.DeletionProtection{FieldName: "deletion_protection"},
+				},
+				w: WorkspaceFns{
+					DestroyFn: func(_ context.Context) (terraform.DestroyResult, error) {
+						return terraform.DestroyResult{}, tferrors.NewDestroyFailed([]byte(`{"@level":"error","@message":"Error: cannot delete: DeletionProtection is enabled"}`))
+					},
+				},
+			},
+			want: want{
+				err:       errors.Wrap(tferrors.NewDestroyFailed([]byte(`{"@level":"error","@message":"Error: cannot delete: DeletionProtection is enabled"}`)), errDestroy),
+				condition: resource.ReasonDeletionProtected,
+			},
+		},
+		"DeletionProtectionOverridden": {
+			reason: "It should disable the deletion protection before destroying if the resource is annotated",
+			args: args{
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							DeletionTimestamp: &metav1.Time{Time: time.Now()},
+							Annotations: map[string]string{
+								resource.AnnotationKeyDisableDeletionProtection: "true",
+							},
+						},
+					},
+				},
+				cfg: &config.Resource{
+					DeletionProtection: &config.DeletionProtection{FieldName: "deletion_protection"},
+				},
+				w: WorkspaceFns{
+					ApplyFn: func(_ context.Context) (terraform.ApplyResult, error) {
+						return terraform.ApplyResult{}, nil
+					},
+					DestroyFn: func(_ context.Context) (terraform.DestroyResult, error) {
+						return terraform.DestroyResult{}, nil
+					},
+				},
+			},
+			want: want{
+				condition: resource.ReasonDeletionProtectionDisabled,
+			},
+		},
+		"DisableDeletionProtectionFailed": {
+			reason: "It should return error if it cannot disable the deletion protection",
+			args: args{
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							DeletionTimestamp: &metav1.Time{Time: time.Now()},
+							Annotations: map[string]string{
+								resource.AnnotationKeyDisableDeletionProtection: "true",
+							},
+						},
+					},
+				},
+				cfg: &config.Resource{
+					DeletionProtection: &config.DeletionProtection{FieldName: "deletion_protection"},
+				},
+				w: WorkspaceFns{
+					ApplyFn: func(_ context.Context) (terraform.ApplyResult, error) {
+						return terraform.ApplyResult{}, errBoom
+					},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errDisableDeletionProtection),
+			},
+		},
+		"AsyncDeletionProtectionOverridden": {
+			reason: "It should disable the deletion protection with an async apply and record it once the apply succeeds without destroying",
+			args: args{
+				obj: annotated(),
+				cfg: &config.Resource{
+					UseAsync:           true,
+					DeletionProtection: &config.DeletionProtection{FieldName: "deletion_protection"},
+				},
+				c: CallbackFns{
+					ApplyFn: func(_ string, _ int64) terraform.CallbackFn {
+						return func(_ error, _ context.Context) error {
+							return nil
+						}
+					},
+				},
+				kube: &test.MockClient{
+					MockGet: test.NewMockGetFn(nil),
+					MockStatusUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+						if r := obj.(*fake.Terraformed).GetCondition(resource.TypeDeletionBlockedExternally).Reason; r != resource.ReasonDeletionProtectionDisabled {
+							return errors.Errorf("unexpected condition reason %q", r)
+						}
+						return nil
+					},
+				},
+				w: WorkspaceFns{
+					ApplyAsyncFn: func(cb terraform.CallbackFn) error {
+						return cb(nil, context.TODO())
+					},
+				},
+			},
+		},
+		"AsyncDisableDeletionProtectionFailed": {
+			reason: "It should not record the deletion protection as disabled if the async apply fails",
+			args: args{
+				obj: annotated(),
+				cfg: &config.Resource{
+					UseAsync:           true,
+					DeletionProtection: &config.DeletionProtection{FieldName: "deletion_protection"},
+				},
+				c: CallbackFns{
+					ApplyFn: func(_ string, _ int64) terraform.CallbackFn {
+						return func(err error, _ context.Context) error {
+							return err
+						}
+					},
+				},
+				w: WorkspaceFns{
+					ApplyAsyncFn: func(cb terraform.CallbackFn) error {
+						return cb(errBoom, context.TODO())
+					},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errStartAsyncDisableDeletionProtection),
+			},
+		},
+		"AsyncDisablingDeletionProtection": {
+			reason: "It should neither start another apply nor destroy while the async apply that disables the deletion protection is running",
+			args: args{
+				obj: annotated(resource.AsyncOperationOngoingCondition()),
+				cfg: &config.Resource{
+					UseAsync:           true,
+					DeletionProtection: &config.DeletionProtection{FieldName: "deletion_protection"},
+				},
+				w: WorkspaceFns{},
+			},
+		},
+		"AsyncDeletionProtectionDisabled": {
+			reason: "It should destroy asynchronously once the deletion protection is disabled",
+			args: args{
+				obj: annotated(resource.DeletionProtectionDisabledCondition()),
+				cfg: &config.Resource{
+					UseAsync:           true,
+					DeletionProtection: &config.DeletionProtection{FieldName: "deletion_protection"},
+				},
+				c: CallbackFns{
+					DestroyFn: func(_ string) terraform.CallbackFn {
+						return nil
+					},
+				},
+				w: WorkspaceFns{
+					DestroyAsyncFn: func(_ terraform.CallbackFn) error {
+						return nil
+					},
+				},
+			},
+			want: want{
+				condition: resource.ReasonDeletionProtectionDisabled,
+			},
+		},
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := &external{workspace: tc.w, callback: tc.c, config: tc.cfg}
+			e := &external{workspace: tc.w, callback: tc.c, kube: tc.kube, config: tc.cfg, async: tc.cfg.UseAsync}
 			err := e.Delete(context.TODO(), tc.args.obj)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nCreate(...): -want error, +got error:\n%s", tc.reason, diff)
 			}
+			if tc.want.condition != "" {
+				if diff := cmp.Diff(tc.want.condition, tc.args.obj.GetCondition(resource.TypeDeletionBlockedExternally).Reason); diff != "" {
+					t.Errorf("\n%s\nDelete(...): -want condition reason, +got condition reason:\n%s", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordOperation(t *testing.T) {
+	res := terraform.OperationResult{Type: "apply", ExitCode: 1, Duration: 1500 * time.Millisecond, OutputBytes: 42}
+	type args struct {
+		res terraform.OperationResult
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want []event.Event
+	}{
+		"Succeeded": {
+			reason: "A normal event should be emitted for a successful operation",
+			args: args{
+				res: terraform.OperationResult{Type: "destroy", Duration: time.Second, OutputBytes: 10},
+			},
+			want: []event.Event{event.Normal(reasonOperationSucceeded, "terraform destroy exited with code 0 after 1s with 10 bytes of output")},
+		},
+		"Failed": {
+			reason: "A warning event should be emitted for a failed operation",
+			args: args{
+				res: res,
+				err: errBoom,
+			},
+			want: []event.Event{event.Warning(reasonOperationFailed, errors.New("terraform apply exited with code 1 after 1.5s with 42 bytes of output"))},
+		},
+		"FailedWithTrace": {
+			reason: "A separate event should point to the captured trace of a failed operation without including it",
+			args: args{
+				res: terraform.OperationResult{Type: "apply", ExitCode: 1, Duration: 1500 * time.Millisecond, OutputBytes: 42, TracePath: "/tf/uid/terraform-trace-failed.log"},
+				err: errBoom,
+			},
+			want: []event.Event{
+				event.Warning(reasonOperationFailed, errors.New("terraform apply exited with code 1 after 1.5s with 42 bytes of output")),
+				event.Warning(reasonOperationTrace, errors.Errorf(errFmtTraceKept, "/tf/uid/terraform-trace-failed.log")),
+			},
+		},
+		"NotRun": {
+			reason: "No event should be emitted if the command was not run",
+			args: args{
+				err: errBoom,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := &eventRecorder{}
+			recordOperation(r, &fake.Terraformed{}, tc.args.res, tc.args.err)
+			if diff := cmp.Diff(tc.want, r.events); diff != "" {
+				t.Errorf("\n%s\nrecordOperation(...): -want events, +got events:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRecordPlan(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		plan   terraform.PlanResult
+		want   []event.Event
+	}{
+		"NoChanges": {
+			reason: "No event should be emitted if the plan has no changes",
+			plan:   terraform.PlanResult{Exists: true, UpToDate: true},
+		},
+		"Changes": {
+			reason: "A normal event should be emitted if the plan has no destructive changes",
+			plan:   terraform.PlanResult{Exists: true, Changes: terraform.PlanChanges{Change: 2}},
+			want:   []event.Event{event.Normal(reasonChangesPlanned, "plan: 0 to add, 2 to change, 0 to destroy")},
+		},
+		"Destroy": {
+			reason: "A warning event should be emitted if the plan destroys a resource",
+			plan:   terraform.PlanResult{Exists: true, Changes: terraform.PlanChanges{Add: 1, Destroy: 1}},
+			want:   []event.Event{event.Warning(reasonChangesPlanned, errors.New("plan: 1 to add, 0 to change, 1 to destroy"))},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := &eventRecorder{}
+			recordPlan(r, &fake.Terraformed{}, tc.plan)
+			if diff := cmp.Diff(tc.want, r.events); diff != "" {
+				t.Errorf("\n%s\nrecordPlan(...): -want events, +got events:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPlanDestroy(t *testing.T) {
+	plan := terraform.DestroyPlan{Changes: []terraform.PlannedChange{{Address: "aws_vpc.example", Type: "aws_vpc", Name: "example", Actions: []string{"delete"}}}}
+	type args struct {
+		plan terraform.DestroyPlan
+		err  error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want []event.Event
+	}{
+		"Planned": {
+			reason: "A normal event should list the changes a destroy would make",
+			args: args{
+				plan: plan,
+			},
+			want: []event.Event{event.Normal(reasonDestroyPlanned, "destroy would make 1 change(s): delete aws_vpc.example")},
+		},
+		"PlanFailed": {
+			reason: "A warning event should be emitted if the destroy cannot be planned",
+			args: args{
+				err: errBoom,
+			},
+			want: []event.Event{event.Warning(reasonDestroyPlanned, errors.Wrap(errBoom, errPlanDestroy))},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := &eventRecorder{}
+			e := &external{
+				kube:     &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				recorder: r,
+				workspace: WorkspaceFns{PlanDestroyFn: func(_ context.Context) (terraform.DestroyPlan, error) {
+					return tc.args.plan, tc.args.err
+				}},
+			}
+			tr := &fake.Terraformed{}
+			tr.SetAnnotations(map[string]string{resource.AnnotationKeyPlanDestroy: "true"})
+			e.planDestroy(context.TODO(), tr)
+			if diff := cmp.Diff(tc.want, r.events, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nplanDestroy(...): -want events, +got events:\n%s", tc.reason, diff)
+			}
+			if resource.DestroyPlanRequested(tr) {
+				t.Errorf("\n%s\nplanDestroy(...): the annotation requesting the plan should be removed", tc.reason)
+			}
+		})
+	}
+}
+
+func TestExplainDrift(t *testing.T) {
+	long := terraform.DriftReport{Fields: []terraform.FieldDrift{{Path: "description", Expected: `"` + strings.Repeat("a", maxDriftMessageSize) + `"`, Actual: `""`, Source: terraform.DriftSourceExternal}}}
+	cases := map[string]struct {
+		reason string
+		report terraform.DriftReport
+		want   string
+	}{
+		"Drift": {
+			reason: "The drift should be explained in the condition and an event",
+			report: terraform.DriftReport{Fields: []terraform.FieldDrift{{Path: "name", Expected: `"new"`, Actual: `"old"`, Source: terraform.DriftSourceSpec}}},
+			want:   `name: expected "new", actual "old" (SpecChange)`,
+		},
+		"LongDrift": {
+			reason: "The explanation of a large drift should be truncated",
+			report: long,
+			want:   tferrors.Truncate(long.String(), maxDriftMessageSize, ""),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := &eventRecorder{}
+			e := &external{
+				recorder: r,
+				workspace: WorkspaceFns{DriftFn: func(_ context.Context) (terraform.DriftReport, error) {
+					return tc.report, nil
+				}},
+			}
+			tr := &fake.Terraformed{}
+			e.explainDrift(context.TODO(), tr, false)
+			if diff := cmp.Diff(tc.want, tr.GetCondition(resource.TypeDrift).Message); diff != "" {
+				t.Errorf("\n%s\nexplainDrift(...): -want message, +got message:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff([]event.Event{event.Normal(reasonDriftDetected, tc.want)}, r.events); diff != "" {
+				t.Errorf("\n%s\nexplainDrift(...): -want events, +got events:\n%s", tc.reason, diff)
+			}
 		})
 	}
 }
@@ -19,6 +19,8 @@ package controller
 import (
 	"context"
 
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+
 	"github.com/crossplane/terrajet/pkg/config"
 	"github.com/crossplane/terrajet/pkg/resource"
 	"github.com/crossplane/terrajet/pkg/terraform"
@@ -33,19 +35,80 @@ type Workspace interface {
 	ApplyAsync(terraform.CallbackFn) error
 	Apply(context.Context) (terraform.ApplyResult, error)
 	DestroyAsync(terraform.CallbackFn) error
-	Destroy(context.Context) error
+	Destroy(context.Context) (terraform.DestroyResult, error)
 	Refresh(context.Context) (terraform.RefreshResult, error)
 	Plan(context.Context) (terraform.PlanResult, error)
+	Validate(context.Context) error
+	Drift(context.Context) (terraform.DriftReport, error)
+	PlanDestroy(context.Context) (terraform.DestroyPlan, error)
+	Import(ctx context.Context, id string) (terraform.ImportResult, error)
+	Diff(context.Context) (terraform.Diff, error)
+	ConfigurationHash() string
 }
 
 // Store is where we can get access to the Terraform workspace of given resource.
 type Store interface {
-	Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (*terraform.Workspace, error)
+	Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error)
+}
+
+// WorkspaceProvider manages the lifecycle of the Terraform workspaces of the
+// resources. terraform.WorkspaceStore is the default implementation that
+// keeps the workspaces in the local filesystem, and it can be backed by an
+// in-memory filesystem in tests, see NewWorkspaceProvider. Alternative
+// implementations, e.g. ones that persist the workspaces in Secrets or in an
+// object storage, can be used without changing the controllers.
+type WorkspaceProvider interface {
+	Store
+	terraform.StoreCleaner
+}
+
+// NewWorkspaceProvider returns a WorkspaceProvider that is backed by the given
+// terraform.WorkspaceStore. It can run the Terraform modules, too.
+func NewWorkspaceProvider(ws *terraform.WorkspaceStore) WorkspaceProvider {
+	return workspaceStore{WorkspaceStore: ws}
+}
+
+type workspaceStore struct {
+	*terraform.WorkspaceStore
+}
+
+// Workspace returns the Terraform workspace of the given resource.
+func (s workspaceStore) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error) {
+	w, err := s.WorkspaceStore.Workspace(ctx, c, tr, ts, cfg)
+	if err != nil {
+		// NOTE(muvaf): A nil *terraform.Workspace would be a non-nil
+		// Workspace, so it must not be returned as is.
+		return nil, err
+	}
+	return w, nil
+}
+
+// ModuleWorkspace is the set of methods that are needed for the controller of
+// the Terraform modules to work.
+type ModuleWorkspace interface {
+	Apply(context.Context) (terraform.ApplyResult, error)
+	Destroy(context.Context) (terraform.DestroyResult, error)
+	Refresh(context.Context) (terraform.RefreshResult, error)
+	Plan(context.Context) (terraform.PlanResult, error)
+	Outputs(context.Context) (map[string]terraform.Output, error)
+}
+
+// ModuleStore is where we can get access to the Terraform workspace that runs
+// the module of a given resource.
+type ModuleStore interface {
+	ModuleWorkspace(ctx context.Context, obj xpresource.Object, ts terraform.Setup, m terraform.Module) (*terraform.Workspace, error)
 }
 
 // CallbackProvider provides functions that can be called with the result of
-// async operations.
+// async operations. The apply callback is given the generation the resource
+// had when the apply started.
 type CallbackProvider interface {
-	Apply(name string) terraform.CallbackFn
+	Apply(name string, generation int64) terraform.CallbackFn
 	Destroy(name string) terraform.CallbackFn
 }
+
+// Enqueuer requests reconciliations of the managed resources, e.g. once their
+// async operations complete.
+type Enqueuer interface {
+	Enqueue(name string)
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/config"
+)
+
+const (
+	errNoCallbackProvider      = "async execution mode requires a callback provider"
+	errFmtUnknownExecutionMode = "unknown execution mode %q"
+)
+
+// ExecutionMode is the mode the Terraform operations of a resource are run
+// in.
+type ExecutionMode string
+
+const (
+	// ExecutionModeSync runs the Terraform operations in the reconcile loop
+	// and is a good fit for the resources that are applied in seconds.
+	ExecutionModeSync ExecutionMode = "Sync"
+	// ExecutionModeAsync runs the Terraform operations in the background and
+	// reports their results via callbacks. It is a good fit for the
+	// resources whose creation or deletion takes minutes.
+	ExecutionModeAsync ExecutionMode = "Async"
+)
+
+// ExecutionModeFn returns the execution mode of the given resource, e.g. from
+// the ProviderConfig it references. If it returns an empty mode, the
+// UseAsync configuration of the resource is used.
+type ExecutionModeFn func(ctx context.Context, kube client.Client, mg xpresource.Managed, cfg *config.Resource) (ExecutionMode, error)
+
+// ExecutionModesByKind returns an ExecutionModeFn that selects the execution
+// modes of the resources by the names of their Terraform resources, e.g.
+// "aws_vpc".
+func ExecutionModesByKind(modes map[string]ExecutionMode) ExecutionModeFn {
+	return func(_ context.Context, _ client.Client, _ xpresource.Managed, cfg *config.Resource) (ExecutionMode, error) {
+		return modes[cfg.Name], nil
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+const (
+	errUnexpectedModule = "the custom resource is not a Terraform module"
+	errNoModuleStore    = "the workspace store cannot run Terraform modules"
+	errModulesDisabled  = "the module policy allows no modules, see Options.ModulePolicy"
+	errInlineDenied     = "inline modules are not allowed by the module policy"
+	errFmtSourceDenied  = "module source %q is not allowed by the module policy"
+	errOutputs          = "cannot get module outputs"
+	errFmtMarshalOutput = "cannot marshal module output %q"
+)
+
+// ModulePolicy restricts the Terraform modules the resources reconciled by
+// SetupModule are allowed to run. A module runs arbitrary Terraform
+// configuration with the credentials of the ProviderConfig of its resource,
+// and the remote modules are downloaded and run by the controller, so anyone
+// who can create such a resource can do anything those credentials and the
+// controller can. The zero value allows no modules.
+type ModulePolicy struct {
+	// AllowInline allows the resources to run inline modules.
+	AllowInline bool
+	// Sources are the prefixes of the addresses of the remote modules the
+	// resources are allowed to call, e.g.
+	// "git::https://github.com/example-org/". It should end with a
+	// separator so that a prefix doesn't match a sibling repository.
+	Sources []string
+}
+
+// Enabled returns whether the policy allows any module.
+func (p ModulePolicy) Enabled() bool {
+	return p.AllowInline || len(p.Sources) != 0
+}
+
+// Allows returns an error if the given module is not allowed by the policy.
+func (p ModulePolicy) Allows(m terraform.Module) error {
+	if m.Inline != "" && !p.AllowInline {
+		return errors.New(errInlineDenied)
+	}
+	if m.Source == "" {
+		return nil
+	}
+	for _, s := range p.Sources {
+		if strings.HasPrefix(m.Source, s) {
+			return nil
+		}
+	}
+	return errors.Errorf(errFmtSourceDenied, m.Source)
+}
+
+// NewModuleConnector returns a new ModuleConnector that runs only the modules
+// the given policy allows. The options configure how the Terraform setup of
+// the resources is resolved, e.g. with WithProviderConfigTracker and
+// WithNamespacedSetupFn.
+func NewModuleConnector(kube client.Client, ms ModuleStore, sf terraform.SetupFn, policy ModulePolicy, opts ...Option) *ModuleConnector {
+	return &ModuleConnector{
+		connector: NewConnector(kube, nil, sf, &config.Resource{}, opts...),
+		store:     ms,
+		policy:    policy,
+	}
+}
+
+// ModuleConnector initializes the external clients of the managed resources
+// that run a Terraform module as a whole.
+type ModuleConnector struct {
+	connector *Connector
+	store     ModuleStore
+	policy    ModulePolicy
+}
+
+// Connect makes sure the workspace of the module is ready to run Terraform
+// operations.
+func (c *ModuleConnector) Connect(ctx context.Context, mg xpresource.Managed) (managed.ExternalClient, error) {
+	m, ok := mg.(resource.Module)
+	if !ok {
+		return nil, errors.New(errUnexpectedModule)
+	}
+	tm := terraformModule(m.GetModuleParameters())
+	if err := c.policy.Allows(tm); err != nil {
+		return nil, err
+	}
+	ts, err := c.connector.setup(ctx, mg)
+	if err != nil {
+		return nil, err
+	}
+	if c.connector.moduleState != nil {
+		if tm.State, err = c.connector.moduleState.GetModuleState(ctx, mg); err != nil {
+			return nil, errors.Wrap(err, errLoadModuleState)
+		}
+	}
+	w, err := c.store.ModuleWorkspace(ctx, mg, ts, tm)
+	if err != nil {
+		return nil, errors.Wrap(err, errGetWorkspace)
+	}
+	var ec managed.ExternalClient = &moduleExternal{
+		kube:      c.connector.kube,
+		workspace: w,
+		recorder:  c.connector.recorder,
+		cleaner:   c.connector.cleaner,
+		state:     c.connector.moduleState,
+	}
+	if c.connector.tracer != nil {
+		ec = &tracedExternal{ExternalClient: ec, tracer: c.connector.tracer}
+	}
+	return ec, nil
+}
+
+// terraformModule converts the parameters of a module resource to the module
+// that is run in its workspace.
+func terraformModule(p resource.ModuleParameters) terraform.Module {
+	m := terraform.Module{
+		Inline:  p.Module,
+		JSON:    p.Format == resource.ModuleFormatJSON,
+		Source:  p.Source,
+		Outputs: p.Outputs,
+	}
+	if len(p.Vars) != 0 {
+		m.Variables = make(map[string]interface{}, len(p.Vars))
+		for _, v := range p.Vars {
+			m.Variables[v.Key] = v.Value
+		}
+	}
+	return m
+}
+
+type moduleExternal struct {
+	kube      client.Client
+	workspace ModuleWorkspace
+	recorder  event.Recorder
+	cleaner   terraform.StoreCleaner
+	state     ModuleStateStore
+}
+
+func (e *moduleExternal) external() *external {
+	return &external{kube: e.kube, cleaner: e.cleaner}
+}
+
+func (e *moduleExternal) Observe(ctx context.Context, mg xpresource.Managed) (managed.ExternalObservation, error) {
+	if err := e.external().addCleanupFinalizer(ctx, mg); err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	res, err := e.workspace.Refresh(ctx)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errRefresh)
+	}
+	if !moduleExists(mg, res) {
+		if err := e.removeState(ctx, mg); err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		return managed.ExternalObservation{
+			ResourceExists: false,
+		}, e.external().cleanup(ctx, mg)
+	}
+	if err := e.persistState(ctx, mg, res.State); err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	plan, err := e.workspace.Plan(ctx)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errPlan)
+	}
+	conn, err := e.connectionDetails(ctx)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	mg.SetConditions(xpv1.Available())
+	return managed.ExternalObservation{
+		ResourceExists:    true,
+		ResourceUpToDate:  !plan.Changes.HasChanges(),
+		ConnectionDetails: conn,
+	}, nil
+}
+
+// moduleExists returns whether the module of the given resource exists. A
+// module may manage no resources at all, e.g. one that only reads data
+// sources to compute its outputs, so it exists once it's created until it's
+// deleted even if its state has no managed resources.
+func moduleExists(mg xpresource.Managed, res terraform.RefreshResult) bool {
+	if res.Exists {
+		return true
+	}
+	if res.State != nil {
+		for _, r := range res.State.Resources {
+			if r.Mode == "managed" && len(r.Instances) != 0 {
+				return true
+			}
+		}
+	}
+	return !xpmeta.WasDeleted(mg) && !xpmeta.GetExternalCreateSucceeded(mg).IsZero()
+}
+
+func (e *moduleExternal) Create(ctx context.Context, mg xpresource.Managed) (managed.ExternalCreation, error) {
+	conn, err := e.apply(ctx, mg)
+	return managed.ExternalCreation{ConnectionDetails: conn}, err
+}
+
+func (e *moduleExternal) Update(ctx context.Context, mg xpresource.Managed) (managed.ExternalUpdate, error) {
+	conn, err := e.apply(ctx, mg)
+	return managed.ExternalUpdate{ConnectionDetails: conn}, err
+}
+
+func (e *moduleExternal) apply(ctx context.Context, mg xpresource.Managed) (managed.ConnectionDetails, error) {
+	res, err := e.workspace.Apply(ctx)
+	recordOperation(e.recorder, mg, res.Operation, err)
+	mg.SetConditions(resource.LastOperationCondition(err))
+	if err != nil {
+		return nil, errors.Wrap(err, errApply)
+	}
+	if err := e.persistState(ctx, mg, res.State); err != nil {
+		return nil, err
+	}
+	return e.connectionDetails(ctx)
+}
+
+func (e *moduleExternal) Delete(ctx context.Context, mg xpresource.Managed) error {
+	res, err := e.workspace.Destroy(ctx)
+	recordOperation(e.recorder, mg, res.Operation, err)
+	mg.SetConditions(resource.LastOperationCondition(err))
+	return errors.Wrap(err, errDestroy)
+}
+
+// connectionDetails returns the outputs of the module as connection details.
+// The outputs that are not strings are published in JSON.
+func (e *moduleExternal) connectionDetails(ctx context.Context) (managed.ConnectionDetails, error) {
+	outputs, err := e.workspace.Outputs(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, errOutputs)
+	}
+	conn := make(managed.ConnectionDetails, len(outputs))
+	for name, o := range outputs {
+		if s, ok := o.Value.(string); ok {
+			conn[name] = []byte(s)
+			continue
+		}
+		raw, err := json.JSParser.Marshal(o.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtMarshalOutput, name)
+		}
+		conn[name] = raw
+	}
+	return conn, nil
+}
+
+// SetupModule adds a controller that reconciles the managed resources of the
+// given kind, which run a Terraform module as a whole, e.g. a Workspace kind
+// whose forProvider embeds resource.ModuleParameters. It's an escape hatch
+// for the resources that no kind has been generated for. The WorkspaceStore
+// of the options needs to be able to run modules, as the one returned by
+// NewWorkspaceProvider is. Since a module runs arbitrary Terraform
+// configuration, the modules need to be allowed explicitly with
+// Options.ModulePolicy, see ModulePolicy.
+func SetupModule(mgr ctrl.Manager, o Options, gvk schema.GroupVersionKind, obj resource.Module) error {
+	if !o.ModulePolicy.Enabled() {
+		return errors.New(errModulesDisabled)
+	}
+	ms, ok := o.WorkspaceStore.(ModuleStore)
+	if !ok {
+		return errors.New(errNoModuleStore)
+	}
+	name := managed.ControllerName(gvk.String())
+	cps := []managed.ConnectionPublisher{managed.NewAPISecretPublisher(mgr.GetClient(), mgr.GetScheme())}
+	opts := []managed.ReconcilerOption{
+		managed.WithExternalConnecter(NewModuleConnector(mgr.GetClient(), ms, o.SetupFn, o.ModulePolicy, append(o.ConnectorOptions(mgr.GetClient()), WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))))...)),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithConnectionPublishers(cps...),
+	}
+	opts = append(opts, o.ManagedReconcilerOptions(mgr.GetClient(), gvk.Kind)...)
+	r := managed.NewReconciler(mgr, xpresource.ManagedKind(gvk), opts...)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(obj, builder.WithPredicates(o.EventPredicates()...)).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
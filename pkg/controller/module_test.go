@@ -0,0 +1,326 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+func TestModuleObserve(t *testing.T) {
+	st := &json.StateV4{Version: 4, Serial: 3}
+	withResources := &json.StateV4{Version: 4, Serial: 3, Resources: []json.ResourceStateV4{
+		{Mode: "data", Type: "null_data_source", Name: "a", Instances: []json.InstanceObjectStateV4{{}}},
+		{Mode: "managed", Type: "null_resource", Name: "b", Instances: []json.InstanceObjectStateV4{{}}},
+	}}
+	type want struct {
+		obs       managed.ExternalObservation
+		condition xpv1.Condition
+		persisted *json.StateV4
+		removed   bool
+		err       error
+	}
+	cases := map[string]struct {
+		reason  string
+		w       ModuleWorkspace
+		created bool
+		deleted bool
+		want
+	}{
+		"RefreshFailed": {
+			reason: "It should return error if the module cannot be refreshed",
+			w: WorkspaceFns{
+				RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+					return terraform.RefreshResult{}, errBoom
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errRefresh),
+			},
+		},
+		"NotExists": {
+			reason: "The module should be reported as non-existent if its state has no resources",
+			w: WorkspaceFns{
+				RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+					return terraform.RefreshResult{}, nil
+				},
+			},
+		},
+		"CreatedWithoutResources": {
+			reason: "A module that manages no resources should be reported as existent once it's created",
+			w: WorkspaceFns{
+				RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+					return terraform.RefreshResult{State: st}, nil
+				},
+				PlanFn: func(_ context.Context) (terraform.PlanResult, error) {
+					return terraform.PlanResult{UpToDate: true}, nil
+				},
+				OutputsFn: func(_ context.Context) (map[string]terraform.Output, error) {
+					return nil, nil
+				},
+			},
+			created: true,
+			want: want{
+				obs: managed.ExternalObservation{
+					ResourceExists:    true,
+					ResourceUpToDate:  true,
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+				condition: xpv1.Available(),
+				persisted: st,
+			},
+		},
+		"ManagedResourceInState": {
+			reason: "A module should be reported as existent if its state has a managed resource even if its first resource is not",
+			w: WorkspaceFns{
+				RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+					return terraform.RefreshResult{State: withResources}, nil
+				},
+				PlanFn: func(_ context.Context) (terraform.PlanResult, error) {
+					return terraform.PlanResult{UpToDate: true}, nil
+				},
+				OutputsFn: func(_ context.Context) (map[string]terraform.Output, error) {
+					return nil, nil
+				},
+			},
+			want: want{
+				obs: managed.ExternalObservation{
+					ResourceExists:    true,
+					ResourceUpToDate:  true,
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+				condition: xpv1.Available(),
+				persisted: withResources,
+			},
+		},
+		"DeletedWithoutResources": {
+			reason: "A module that manages no resources should be reported as non-existent once it's deleted",
+			w: WorkspaceFns{
+				RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+					return terraform.RefreshResult{State: st}, nil
+				},
+			},
+			created: true,
+			deleted: true,
+			want: want{
+				removed: true,
+			},
+		},
+		"DeletedStateRemoved": {
+			reason: "The persisted state of the module should be removed once it's deleted and its resources are gone",
+			w: WorkspaceFns{
+				RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+					return terraform.RefreshResult{}, nil
+				},
+			},
+			deleted: true,
+			want: want{
+				removed: true,
+			},
+		},
+		"StatePersisted": {
+			reason: "The refreshed state of the module should be persisted so that it survives the loss of the workspace",
+			w: WorkspaceFns{
+				RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+					return terraform.RefreshResult{Exists: true, State: st}, nil
+				},
+				PlanFn: func(_ context.Context) (terraform.PlanResult, error) {
+					return terraform.PlanResult{Exists: true, UpToDate: true}, nil
+				},
+				OutputsFn: func(_ context.Context) (map[string]terraform.Output, error) {
+					return nil, nil
+				},
+			},
+			want: want{
+				obs: managed.ExternalObservation{
+					ResourceExists:    true,
+					ResourceUpToDate:  true,
+					ConnectionDetails: managed.ConnectionDetails{},
+				},
+				condition: xpv1.Available(),
+				persisted: st,
+			},
+		},
+		"ChangesPlanned": {
+			reason: "The module should be reported as not up-to-date if the plan has changes and its outputs should be published",
+			w: WorkspaceFns{
+				RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+					return terraform.RefreshResult{Exists: true}, nil
+				},
+				PlanFn: func(_ context.Context) (terraform.PlanResult, error) {
+					return terraform.PlanResult{Exists: true, Changes: terraform.PlanChanges{Change: 1}}, nil
+				},
+				OutputsFn: func(_ context.Context) (map[string]terraform.Output, error) {
+					return map[string]terraform.Output{
+						"endpoint": {Value: "example.com"},
+						"ports":    {Value: []interface{}{float64(80), float64(443)}},
+					}, nil
+				},
+			},
+			want: want{
+				obs: managed.ExternalObservation{
+					ResourceExists: true,
+					ConnectionDetails: managed.ConnectionDetails{
+						"endpoint": []byte("example.com"),
+						"ports":    []byte("[80,443]"),
+					},
+				},
+				condition: xpv1.Available(),
+			},
+		},
+		"OutputsFailed": {
+			reason: "It should return error if the outputs of the module cannot be read",
+			w: WorkspaceFns{
+				RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
+					return terraform.RefreshResult{Exists: true}, nil
+				},
+				PlanFn: func(_ context.Context) (terraform.PlanResult, error) {
+					return terraform.PlanResult{Exists: true, UpToDate: true}, nil
+				},
+				OutputsFn: func(_ context.Context) (map[string]terraform.Output, error) {
+					return nil, errBoom
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errOutputs),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &xpfake.Managed{}
+			if tc.created {
+				xpmeta.SetExternalCreateSucceeded(mg, time.Now())
+			}
+			if tc.deleted {
+				mg.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+			}
+			s := &moduleStateStore{}
+			e := &moduleExternal{workspace: tc.w, state: s}
+			obs, err := e.Observe(context.TODO(), mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nObserve(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.persisted, s.persisted); diff != "" {
+				t.Errorf("\n%s\nObserve(...): -want persisted state, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.removed, s.removed); diff != "" {
+				t.Errorf("\n%s\nObserve(...): -want removed state, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.obs, obs); diff != "" {
+				t.Errorf("\n%s\nObserve(...): -want observation, +got observation:\n%s", tc.reason, diff)
+			}
+			if tc.want.condition.Type == "" {
+				return
+			}
+			if diff := cmp.Diff(tc.want.condition, mg.GetCondition(xpv1.TypeReady), test.EquateConditions()); diff != "" {
+				t.Errorf("\n%s\nObserve(...): -want condition, +got condition:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestModulePolicyAllows(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		policy ModulePolicy
+		module terraform.Module
+		want   error
+	}{
+		"InlineAllowed": {
+			reason: "An inline module should be allowed if the policy allows inline modules",
+			policy: ModulePolicy{AllowInline: true},
+			module: terraform.Module{Inline: "{}"},
+		},
+		"InlineDenied": {
+			reason: "An inline module should be denied unless the policy allows inline modules",
+			policy: ModulePolicy{Sources: []string{"git::https://example.com/"}},
+			module: terraform.Module{Inline: "{}"},
+			want:   errors.New(errInlineDenied),
+		},
+		"SourceAllowed": {
+			reason: "A remote module should be allowed if its source has an allowed prefix",
+			policy: ModulePolicy{Sources: []string{"git::https://example.com/"}},
+			module: terraform.Module{Source: "git::https://example.com/network.git?ref=v1.2.0"},
+		},
+		"SourceDenied": {
+			reason: "A remote module should be denied if its source has no allowed prefix",
+			policy: ModulePolicy{AllowInline: true, Sources: []string{"git::https://example.com/"}},
+			module: terraform.Module{Source: "git::https://example.org/network.git"},
+			want:   errors.Errorf(errFmtSourceDenied, "git::https://example.org/network.git"),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.policy.Allows(tc.module)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nAllows(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTerraformModule(t *testing.T) {
+	p := resource.ModuleParameters{
+		Module: `{"output":{"a":{"value":"${var.a}"}}}`,
+		Format: resource.ModuleFormatJSON,
+		Vars:   []resource.ModuleVar{{Key: "a", Value: "b"}},
+	}
+	want := terraform.Module{
+		Inline:    `{"output":{"a":{"value":"${var.a}"}}}`,
+		JSON:      true,
+		Variables: map[string]interface{}{"a": "b"},
+	}
+	if diff := cmp.Diff(want, terraformModule(p)); diff != "" {
+		t.Errorf("terraformModule(...): -want, +got:\n%s", diff)
+	}
+}
+
+// moduleStateStore is a ModuleStateStore that records the calls made to it.
+type moduleStateStore struct {
+	persisted *json.StateV4
+	removed   bool
+}
+
+func (s *moduleStateStore) GetModuleState(_ context.Context, _ xpresource.Managed) ([]byte, error) {
+	return nil, nil
+}
+
+func (s *moduleStateStore) SetModuleState(_ context.Context, _ xpresource.Managed, st *json.StateV4) error {
+	s.persisted = st
+	return nil
+}
+
+func (s *moduleStateStore) RemoveModuleState(_ context.Context, _ xpresource.Managed) error {
+	s.removed = true
+	return nil
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+const (
+	// LabelKeyModuleState is the label of the Secrets of the
+	// SecretModuleStateStore whose value is the name of the module resource
+	// whose state they keep.
+	LabelKeyModuleState = "terrajet.crossplane.io/module-state"
+
+	errGetModuleState        = "cannot get module state secret"
+	errCreateModuleState     = "cannot create module state secret"
+	errUpdateModuleState     = "cannot update module state secret"
+	errDeleteModuleState     = "cannot delete module state secret"
+	errMarshalModuleState    = "cannot marshal module state"
+	errCompressModuleState   = "cannot compress module state"
+	errLoadModuleState       = "cannot load module state"
+	errPersistModuleState    = "cannot persist module state"
+	errRemoveModuleState     = "cannot remove module state"
+	errDecompressModuleState = "cannot decompress module state"
+)
+
+// ModuleStateStore persists the Terraform states of the module resources
+// outside of their workspaces. Unlike the resources generated from the schema
+// of a provider, the state of a module cannot be reproduced from the
+// resource, so the workspace of a module that has lost its state is hydrated
+// from the store.
+type ModuleStateStore interface {
+	// GetModuleState returns the persisted state of the given module
+	// resource, or nil if it has none.
+	GetModuleState(ctx context.Context, mg xpresource.Managed) ([]byte, error)
+	// SetModuleState persists the given state of the given module resource.
+	SetModuleState(ctx context.Context, mg xpresource.Managed, st *json.StateV4) error
+	// RemoveModuleState removes the persisted state of the given module
+	// resource once it's deleted.
+	RemoveModuleState(ctx context.Context, mg xpresource.Managed) error
+}
+
+// WithModuleStateStore configures the store the states of the modules are
+// persisted in. The states of the modules are kept only in their workspaces
+// if it is not set.
+func WithModuleStateStore(s ModuleStateStore) Option {
+	return func(c *Connector) {
+		c.moduleState = s
+	}
+}
+
+// NewSecretModuleStateStore returns a SecretModuleStateStore that keeps the
+// states in the Secrets in the given namespace.
+func NewSecretModuleStateStore(kube client.Client, namespace string) *SecretModuleStateStore {
+	return &SecretModuleStateStore{kube: kube, namespace: namespace}
+}
+
+// SecretModuleStateStore keeps the gzip compressed Terraform state of each
+// module resource in a Secret of its own.
+type SecretModuleStateStore struct {
+	kube      client.Client
+	namespace string
+}
+
+// ModuleStateSecretName returns the name of the Secret the state of the given
+// module resource is kept in. It's derived from the UID of the resource so
+// that the state is never restored into another resource with the same name.
+func ModuleStateSecretName(mg xpresource.Managed) string {
+	return "tfmodule-" + string(mg.GetUID())
+}
+
+// GetModuleState returns the state of the given module resource from its
+// Secret.
+func (s *SecretModuleStateStore) GetModuleState(ctx context.Context, mg xpresource.Managed) ([]byte, error) {
+	sec := &corev1.Secret{}
+	err := s.kube.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: ModuleStateSecretName(mg)}, sec)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errGetModuleState)
+	}
+	compressed, ok := sec.Data[StateBackupKey]
+	if !ok {
+		return nil, nil
+	}
+	raw, err := decompress(compressed)
+	return raw, errors.Wrap(err, errDecompressModuleState)
+}
+
+// SetModuleState stores the given state of the given module resource in its
+// Secret. It's a no-op if the state hasn't changed.
+func (s *SecretModuleStateStore) SetModuleState(ctx context.Context, mg xpresource.Managed, st *json.StateV4) error {
+	raw, err := json.JSParser.Marshal(st)
+	if err != nil {
+		return errors.Wrap(err, errMarshalModuleState)
+	}
+	compressed, err := compress(raw)
+	if err != nil {
+		return errors.Wrap(err, errCompressModuleState)
+	}
+	sec := &corev1.Secret{}
+	err = s.kube.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: ModuleStateSecretName(mg)}, sec)
+	if kerrors.IsNotFound(err) {
+		sec = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.namespace,
+				Name:      ModuleStateSecretName(mg),
+				Labels:    map[string]string{LabelKeyModuleState: mg.GetName()},
+			},
+			Data: map[string][]byte{StateBackupKey: compressed},
+		}
+		return errors.Wrap(s.kube.Create(ctx, sec), errCreateModuleState)
+	}
+	if err != nil {
+		return errors.Wrap(err, errGetModuleState)
+	}
+	if bytes.Equal(sec.Data[StateBackupKey], compressed) {
+		return nil
+	}
+	if sec.Data == nil {
+		sec.Data = map[string][]byte{}
+	}
+	sec.Data[StateBackupKey] = compressed
+	return errors.Wrap(s.kube.Update(ctx, sec), errUpdateModuleState)
+}
+
+// RemoveModuleState deletes the Secret of the given module resource.
+func (s *SecretModuleStateStore) RemoveModuleState(ctx context.Context, mg xpresource.Managed) error {
+	sec := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: ModuleStateSecretName(mg)}}
+	return errors.Wrap(client.IgnoreNotFound(s.kube.Delete(ctx, sec)), errDeleteModuleState)
+}
+
+// persistState persists the given state of the module, if a module state
+// store is configured.
+func (e *moduleExternal) persistState(ctx context.Context, mg xpresource.Managed, st *json.StateV4) error {
+	if e.state == nil || st == nil {
+		return nil
+	}
+	return errors.Wrap(e.state.SetModuleState(ctx, mg, st), errPersistModuleState)
+}
+
+// removeState removes the persisted state of the module if it's being
+// deleted, which must be called only once the module's resources are gone.
+func (e *moduleExternal) removeState(ctx context.Context, mg xpresource.Managed) error {
+	if e.state == nil || !xpmeta.WasDeleted(mg) {
+		return nil
+	}
+	return errors.Wrap(e.state.RemoveModuleState(ctx, mg), errRemoveModuleState)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+func TestSecretModuleStateStore(t *testing.T) {
+	errNotFound := kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "tfmodule-")
+	st := &json.StateV4{Version: 4, Serial: 3}
+	raw, err := json.JSParser.Marshal(st)
+	if err != nil {
+		t.Fatalf("cannot marshal state: %s", err)
+	}
+	type want struct {
+		name  string
+		state []byte
+		err   error
+	}
+	cases := map[string]struct {
+		reason string
+		getErr error
+		want
+	}{
+		"RoundTrip": {
+			reason: "The persisted state should be read back from the Secret named after the UID of the resource",
+			want: want{
+				name:  "tfmodule-very-cool-uid",
+				state: raw,
+			},
+		},
+		"GetFailed": {
+			reason: "An error should be returned if the Secret cannot be read",
+			getErr: errBoom,
+			want: want{
+				err: errors.Wrap(errBoom, errGetModuleState),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var stored *corev1.Secret
+			kube := &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+					if tc.getErr != nil {
+						return tc.getErr
+					}
+					if stored == nil {
+						return errNotFound
+					}
+					stored.DeepCopyInto(obj.(*corev1.Secret))
+					return nil
+				},
+				MockCreate: func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+					stored = obj.(*corev1.Secret)
+					return nil
+				},
+			}
+			mg := &xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Name: "very-cool-name", UID: types.UID("very-cool-uid")}}
+			s := NewSecretModuleStateStore(kube, "crossplane-system")
+			if err := s.SetModuleState(context.TODO(), mg, st); err != nil {
+				if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+					t.Fatalf("\n%s\nSetModuleState(...): -want error, +got error:\n%s", tc.reason, diff)
+				}
+				return
+			}
+			if diff := cmp.Diff(tc.want.name, stored.GetName()); diff != "" {
+				t.Errorf("\n%s\nSetModuleState(...): -want secret name, +got:\n%s", tc.reason, diff)
+			}
+			got, err := s.GetModuleState(context.TODO(), mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nGetModuleState(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.state, got); diff != "" {
+				t.Errorf("\n%s\nGetModuleState(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
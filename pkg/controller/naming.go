@@ -0,0 +1,60 @@
+/*
+ Copyright 2021 The Crossplane Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/config"
+)
+
+const (
+	errNamingStrategy = "cannot produce external name"
+	errUpdateManaged  = "cannot update managed resource"
+)
+
+// NameInitializer is a managed.Initializer that sets the external name of a
+// resource using a naming strategy if it does not have one yet.
+type NameInitializer struct {
+	kube     client.Client
+	strategy config.NamingStrategy
+}
+
+// NewNameInitializer returns a new NameInitializer.
+func NewNameInitializer(kube client.Client, s config.NamingStrategy) *NameInitializer {
+	return &NameInitializer{kube: kube, strategy: s}
+}
+
+// Initialize sets the external name of the given resource to the name
+// produced by the naming strategy and stores it so that the same name is used
+// in all subsequent reconciliations.
+func (n *NameInitializer) Initialize(ctx context.Context, mg xpresource.Managed) error {
+	if meta.GetExternalName(mg) != "" {
+		return nil
+	}
+	name, err := n.strategy(mg)
+	if err != nil {
+		return errors.Wrap(err, errNamingStrategy)
+	}
+	meta.SetExternalName(mg, name)
+	return errors.Wrap(n.kube.Update(ctx, mg), errUpdateManaged)
+}
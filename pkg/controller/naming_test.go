@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/terrajet/pkg/config"
+)
+
+func TestNameInitializer(t *testing.T) {
+	type args struct {
+		strategy config.NamingStrategy
+		kube     *test.MockClient
+		mg       xpresource.Managed
+	}
+	type want struct {
+		externalName string
+		err          error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"AlreadySet": {
+			reason: "An existing external name should not be overridden",
+			args: args{
+				strategy: config.NameWithUIDSuffix(4),
+				mg: &xpfake.Managed{ObjectMeta: metav1.ObjectMeta{
+					Name:        "example",
+					UID:         "abcdef",
+					Annotations: map[string]string{meta.AnnotationKeyExternalName: "existing"},
+				}},
+			},
+			want: want{
+				externalName: "existing",
+			},
+		},
+		"Set": {
+			reason: "The name produced by the strategy should be set as external name",
+			args: args{
+				strategy: config.NameWithUIDSuffix(4),
+				kube:     &test.MockClient{MockUpdate: test.NewMockUpdateFn(nil)},
+				mg:       &xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Name: "example", UID: "abcdef"}},
+			},
+			want: want{
+				externalName: "example-abcd",
+			},
+		},
+		"UpdateFailed": {
+			reason: "An error should be returned if the resource cannot be updated",
+			args: args{
+				strategy: config.NameFromMetadata(),
+				kube:     &test.MockClient{MockUpdate: test.NewMockUpdateFn(errBoom)},
+				mg:       &xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Name: "example"}},
+			},
+			want: want{
+				externalName: "example",
+				err:          errors.Wrap(errBoom, errUpdateManaged),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := NewNameInitializer(tc.args.kube, tc.args.strategy).Initialize(context.TODO(), tc.args.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nInitialize(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.externalName, meta.GetExternalName(tc.args.mg)); diff != "" {
+				t.Errorf("\n%s\nInitialize(...): -want external name, +got external name:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
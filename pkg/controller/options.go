@@ -17,10 +17,20 @@ limitations under the License.
 package controller
 
 import (
+	"time"
+
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/resource"
 	"github.com/crossplane/terrajet/pkg/terraform"
 )
 
@@ -34,15 +44,250 @@ type Options struct {
 	Provider *config.Provider
 
 	// WorkspaceStore will be used to pick/initialize the workspace the specific CR
-	// instance should use.
-	WorkspaceStore *terraform.WorkspaceStore
+	// instance should use. It's usually a *terraform.WorkspaceStore wrapped
+	// with NewWorkspaceProvider.
+	WorkspaceStore WorkspaceProvider
 
 	// SetupFn contains the provider-specific initialization logic, such as
 	// preparing the auth token for Terraform CLI.
 	SetupFn terraform.SetupFn
 
+	// NamespacedSetupFn resolves the Terraform setup of the resources for
+	// which ProviderConfigNamespaceFn returns a namespace from the namespaced
+	// objects they reference, e.g. in multi-tenant clusters where each
+	// tenant brings its own credentials. All resources use the
+	// cluster-scoped ProviderConfig if it is not set.
+	NamespacedSetupFn terraform.NamespacedSetupFn
+
+	// ProviderConfigNamespaceFn selects the namespace the provider
+	// configuration of each resource is resolved from when NamespacedSetupFn
+	// is set. Defaults to resource.ClaimNamespace, i.e. the namespace of the
+	// claim the resource is composed for.
+	ProviderConfigNamespaceFn resource.ProviderConfigNamespaceFn
+
 	// SecretStoreConfigGVK is the GroupVersionKind for the Secret StoreConfig
 	// resource. Setting this enables External Secret Stores for the controller
 	// by adding connection.DetailsManager as a ConnectionPublisher.
 	SecretStoreConfigGVK *schema.GroupVersionKind
+
+	// ProviderConfigUsage is an empty instance of the ProviderConfigUsage
+	// type of the provider, e.g. &v1alpha1.ProviderConfigUsage{}. If set,
+	// every resource marks the usage of the ProviderConfig it references so
+	// that the ProviderConfig cannot be deleted while it's still in use.
+	ProviderConfigUsage xpresource.ProviderConfigUsage
+
+	// Initializers are run for the resources of all kinds before their first
+	// reconciliation, e.g. to set an external name following a provider-wide
+	// convention. They run after the initializers configured for the kind
+	// and the external name initializer of the kind, so they can rely on the
+	// external name being set.
+	Initializers []config.NewInitializerFn
+
+	// ModulePolicy restricts the Terraform modules the resources reconciled
+	// by SetupModule are allowed to run. SetupModule refuses to start if it
+	// allows no modules, which is the default.
+	ModulePolicy ModulePolicy
+
+	// ExecutionModeFn selects whether the Terraform operations of each
+	// resource are run synchronously or asynchronously, overriding the
+	// UseAsync configuration of its kind. See ExecutionModesByKind.
+	ExecutionModeFn ExecutionModeFn
+
+	// ReconcilerOptions are the options of the managed reconcilers of the
+	// kinds keyed by the names of their Terraform resources, e.g.
+	// "aws_vpc". They override the defaults that apply to all kinds.
+	ReconcilerOptions map[string][]ReconcilerOption
+
+	// Predicates filter the events of the managed resources that trigger a
+	// reconciliation. Defaults to IgnoreNoopUpdates so that status-only
+	// updates and resyncs don't cause Terraform to plan again. Set to an
+	// empty slice to react to all events.
+	Predicates []predicate.Predicate
+
+	// ValidateBeforeApply enables running "terraform validate" on the
+	// generated configuration before each apply. See WithValidation.
+	ValidateBeforeApply bool
+
+	// RateLimiter is the rate limiter of the work queue of each controller.
+	// Defaults to the per-item exponential rate limiter of crossplane-runtime.
+	RateLimiter workqueue.RateLimiter
+
+	// EnqueueJitter is the maximum random delay of the reconciliations
+	// requested once the async operations complete. They're added to the work
+	// queue through its rate limiter so that the completions of many async
+	// operations at once don't cause a reconciliation stampede.
+	EnqueueJitter time.Duration
+
+	// StateBackupNamespace is the namespace of the Secrets the Terraform
+	// states of the resources are backed up in after every successful
+	// refresh and apply. The states of the Terraform modules run by
+	// SetupModule are persisted in the Secrets in this namespace, too, so
+	// that they survive the loss of their workspaces. The states are not
+	// backed up if it is empty.
+	StateBackupNamespace string
+
+	// PrivateRawNamespace is the namespace of the Secrets the private
+	// attributes of the Terraform states of the resources are stored in
+	// instead of an annotation on the resources, which may exceed the size
+	// limits of the API server for some resources. The WorkspaceStore reads
+	// them from the same store, see PrivateRawStore. They are stored in the
+	// annotation if it is empty.
+	PrivateRawNamespace string
+
+	// TracerProvider produces the OpenTelemetry spans of the Observe, Create,
+	// Update and Delete calls of the resources if set. The spans of the
+	// Terraform CLI commands are enabled in the WorkspaceStore with
+	// terraform.WithTracing.
+	TracerProvider trace.TracerProvider
+}
+
+const (
+	defaultReconcileTimeout = 3 * time.Minute
+)
+
+// reconcilerConfig is the configuration of the managed reconciler of a kind.
+type reconcilerConfig struct {
+	pollInterval time.Duration
+	timeout      time.Duration
+	finalizer    xpresource.Finalizer
+}
+
+// ReconcilerOption configures the managed reconciler of a kind.
+type ReconcilerOption func(*reconcilerConfig)
+
+// WithPollInterval sets how often the managed resources of the kind are
+// observed after they are ready. Defaults to the PollInterval of the
+// controller Options.
+func WithPollInterval(d time.Duration) ReconcilerOption {
+	return func(c *reconcilerConfig) {
+		c.pollInterval = d
+	}
+}
+
+// WithTimeout sets the timeout of a single reconciliation of the managed
+// resources of the kind. Defaults to 3 minutes.
+func WithTimeout(d time.Duration) ReconcilerOption {
+	return func(c *reconcilerConfig) {
+		c.timeout = d
+	}
+}
+
+// WithFinalizer sets the finalizer of the managed resources of the kind.
+// Defaults to the finalizer that removes the Terraform workspace of the
+// resource along with the finalizer on the API object.
+func WithFinalizer(f xpresource.Finalizer) ReconcilerOption {
+	return func(c *reconcilerConfig) {
+		c.finalizer = f
+	}
+}
+
+func (o Options) reconcilerConfig(kube client.Client, resourceName string) reconcilerConfig {
+	c := reconcilerConfig{
+		pollInterval: o.PollInterval,
+		timeout:      defaultReconcileTimeout,
+	}
+	for _, f := range o.ReconcilerOptions[resourceName] {
+		f(&c)
+	}
+	if c.finalizer == nil {
+		c.finalizer = terraform.NewWorkspaceFinalizer(o.WorkspaceStore, xpresource.NewAPIFinalizer(kube, managed.FinalizerName))
+	}
+	// NOTE: The workspace cleanup finalizer is added to the resources if
+	// there is a WorkspaceStore, see ConnectorOptions.
+	if o.WorkspaceStore != nil {
+		c.finalizer = NewOrphanCleanupFinalizer(c.finalizer, o.StateBackup(kube), o.PrivateRawStore(kube), o.ModuleStateStore(kube))
+	}
+	return c
+}
+
+// ManagedReconcilerOptions returns the options of the managed reconciler of
+// the kind of the given Terraform resource that are configurable per kind.
+func (o Options) ManagedReconcilerOptions(kube client.Client, resourceName string) []managed.ReconcilerOption {
+	c := o.reconcilerConfig(kube, resourceName)
+	return []managed.ReconcilerOption{
+		managed.WithPollInterval(c.pollInterval),
+		managed.WithTimeout(c.timeout),
+		managed.WithFinalizer(c.finalizer),
+	}
+}
+
+// ForControllerRuntime returns the options of the controller-runtime
+// controller, using the configured RateLimiter if there is one.
+func (o Options) ForControllerRuntime() ctrlcontroller.Options {
+	co := o.Options.ForControllerRuntime()
+	if o.RateLimiter != nil {
+		co.RateLimiter = o.RateLimiter
+	}
+	return co
+}
+
+// EventPredicates returns the predicates the events of the managed resources
+// need to satisfy to trigger a reconciliation.
+func (o Options) EventPredicates() []predicate.Predicate {
+	if o.Predicates == nil {
+		return []predicate.Predicate{IgnoreNoopUpdates()}
+	}
+	return o.Predicates
+}
+
+// ConnectorOptions returns the options of the Connector that are shared by
+// the controllers of all kinds.
+func (o Options) ConnectorOptions(kube client.Client) []Option {
+	var opts []Option
+	if o.WorkspaceStore != nil {
+		opts = append(opts, WithWorkspaceCleanup(o.WorkspaceStore))
+	}
+	if o.ExecutionModeFn != nil {
+		opts = append(opts, WithExecutionModeFn(o.ExecutionModeFn))
+	}
+	if o.ValidateBeforeApply {
+		opts = append(opts, WithValidation())
+	}
+	if o.NamespacedSetupFn != nil {
+		ns := o.ProviderConfigNamespaceFn
+		if ns == nil {
+			ns = resource.ClaimNamespace
+		}
+		opts = append(opts, WithNamespacedSetupFn(ns, o.NamespacedSetupFn))
+	}
+	if o.ProviderConfigUsage != nil {
+		opts = append(opts, WithProviderConfigTracker(xpresource.NewProviderConfigUsageTracker(kube, o.ProviderConfigUsage)))
+	}
+	if o.TracerProvider != nil {
+		opts = append(opts, WithTracerProvider(o.TracerProvider))
+	}
+	if b := o.StateBackup(kube); b != nil {
+		opts = append(opts, WithStateBackup(b))
+	}
+	if s := o.ModuleStateStore(kube); s != nil {
+		opts = append(opts, WithModuleStateStore(s))
+	}
+	return append(opts, WithPrivateRawStore(o.PrivateRawStore(kube)))
+}
+
+// StateBackup returns the store the Terraform states of the resources are
+// backed up in, or nil if the states are not backed up.
+func (o Options) StateBackup(kube client.Client) StateBackupStore {
+	if o.StateBackupNamespace == "" {
+		return nil
+	}
+	return NewSecretStateBackup(kube, o.StateBackupNamespace)
+}
+
+// ModuleStateStore returns the store the Terraform states of the modules are
+// persisted in, or nil if they are not persisted.
+func (o Options) ModuleStateStore(kube client.Client) ModuleStateStore {
+	if o.StateBackupNamespace == "" {
+		return nil
+	}
+	return NewSecretModuleStateStore(kube, o.StateBackupNamespace)
+}
+
+// PrivateRawStore returns the store the private attributes of the Terraform
+// states of the resources are stored in.
+func (o Options) PrivateRawStore(kube client.Client) resource.PrivateRawStore {
+	if o.PrivateRawNamespace == "" {
+		return resource.AnnotationPrivateRawStore{}
+	}
+	return NewSecretPrivateRawStore(kube, o.PrivateRawNamespace)
 }
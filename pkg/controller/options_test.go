@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+func TestReconcilerConfig(t *testing.T) {
+	finalizer := xpresource.NewNopFinalizer()
+	type want struct {
+		pollInterval time.Duration
+		timeout      time.Duration
+		finalizer    xpresource.Finalizer
+	}
+	cases := map[string]struct {
+		reason string
+		opts   map[string][]ReconcilerOption
+		want
+	}{
+		"Defaults": {
+			reason: "The defaults should be used if no option is configured for the kind",
+			opts: map[string][]ReconcilerOption{
+				"aws_subnet": {WithPollInterval(time.Hour)},
+			},
+			want: want{
+				pollInterval: time.Minute,
+				timeout:      defaultReconcileTimeout,
+			},
+		},
+		"Overridden": {
+			reason: "The options configured for the kind should override the defaults",
+			opts: map[string][]ReconcilerOption{
+				"aws_vpc": {WithPollInterval(time.Hour), WithTimeout(10 * time.Minute), WithFinalizer(finalizer)},
+			},
+			want: want{
+				pollInterval: time.Hour,
+				timeout:      10 * time.Minute,
+				finalizer:    finalizer,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := Options{
+				Options:           controller.Options{PollInterval: time.Minute},
+				ReconcilerOptions: tc.opts,
+			}
+			got := o.reconcilerConfig(nil, "aws_vpc")
+			if diff := cmp.Diff(tc.want.pollInterval, got.pollInterval); diff != "" {
+				t.Errorf("\n%s\nreconcilerConfig(...): -want poll interval, +got poll interval:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.timeout, got.timeout); diff != "" {
+				t.Errorf("\n%s\nreconcilerConfig(...): -want timeout, +got timeout:\n%s", tc.reason, diff)
+			}
+			if tc.want.finalizer == nil {
+				if _, ok := got.finalizer.(*terraform.WorkspaceFinalizer); !ok {
+					t.Errorf("\n%s\nreconcilerConfig(...): expected the workspace finalizer, got %T", tc.reason, got.finalizer)
+				}
+				return
+			}
+			if got.finalizer != tc.want.finalizer {
+				t.Errorf("\n%s\nreconcilerConfig(...): expected the configured finalizer, got %T", tc.reason, got.finalizer)
+			}
+		})
+	}
+}
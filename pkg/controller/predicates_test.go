@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+)
+
+func TestIgnoreNoopUpdates(t *testing.T) {
+	now := metav1.Now()
+	obj := func(rv string, generation int64, mods ...func(*fake.Terraformed)) *fake.Terraformed {
+		tr := &fake.Terraformed{}
+		tr.SetResourceVersion(rv)
+		tr.SetGeneration(generation)
+		for _, m := range mods {
+			m(tr)
+		}
+		return tr
+	}
+	cases := map[string]struct {
+		reason string
+		old    *fake.Terraformed
+		new    *fake.Terraformed
+		want   bool
+	}{
+		"Resync": {
+			reason: "A resync of the same version of the object should be ignored",
+			old:    obj("1", 1),
+			new:    obj("1", 1),
+		},
+		"StatusOnly": {
+			reason: "An update that doesn't change the generation, annotations or deletion timestamp should be ignored",
+			old:    obj("1", 1),
+			new:    obj("2", 1),
+		},
+		"SpecChanged": {
+			reason: "An update that bumps the generation should be accepted",
+			old:    obj("1", 1),
+			new:    obj("2", 2),
+			want:   true,
+		},
+		"AnnotationsChanged": {
+			reason: "An update of the annotations should be accepted",
+			old:    obj("1", 1),
+			new: obj("2", 1, func(tr *fake.Terraformed) {
+				tr.SetAnnotations(map[string]string{"crossplane.io/external-name": "some"})
+			}),
+			want: true,
+		},
+		"Deleted": {
+			reason: "An update that marks the object for deletion should be accepted",
+			old:    obj("1", 1),
+			new: obj("2", 1, func(tr *fake.Terraformed) {
+				tr.SetDeletionTimestamp(&now)
+			}),
+			want: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := IgnoreNoopUpdates().Update(event.UpdateEvent{ObjectOld: tc.old, ObjectNew: tc.new})
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nUpdate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
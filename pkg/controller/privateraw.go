@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+)
+
+const (
+	// PrivateRawKey is the key of the private attributes of the Terraform
+	// state in the Secrets of the SecretPrivateRawStore.
+	PrivateRawKey = "private-raw"
+	// LabelKeyPrivateRaw is the label of the Secrets of the
+	// SecretPrivateRawStore whose value is the name of the resource whose
+	// private attributes they keep.
+	LabelKeyPrivateRaw = "terrajet.crossplane.io/private-raw"
+
+	errGetPrivateRawSecret    = "cannot get private attributes secret"
+	errCreatePrivateRawSecret = "cannot create private attributes secret"
+	errUpdatePrivateRawSecret = "cannot update private attributes secret"
+	errDeletePrivateRawSecret = "cannot delete private attributes secret"
+	errRemovePrivateRaw       = "cannot remove private attributes"
+)
+
+// NewSecretPrivateRawStore returns a SecretPrivateRawStore that keeps the
+// private attributes in the Secrets in the given namespace.
+func NewSecretPrivateRawStore(kube client.Client, namespace string) *SecretPrivateRawStore {
+	return &SecretPrivateRawStore{kube: kube, namespace: namespace}
+}
+
+// SecretPrivateRawStore stores the private attributes of the Terraform state
+// of each resource in a Secret of its own instead of an annotation on the
+// resource, so that they don't count towards the size limits of the
+// resource. The private attributes of the resources that still have the
+// annotation are read from it until they're stored again, at which point the
+// annotation is removed.
+type SecretPrivateRawStore struct {
+	kube      client.Client
+	namespace string
+}
+
+// PrivateRawSecretName returns the name of the Secret the private attributes
+// of the given resource are stored in. It's derived from the UID of the
+// resource so that it's always a valid name and the private attributes are
+// never read by another resource with the same name.
+func PrivateRawSecretName(tr resource.Terraformed) string {
+	return "tfprivate-" + string(tr.GetUID())
+}
+
+// GetPrivateRaw returns the private attributes of the given resource from its
+// Secret, or from its annotation if it has no Secret yet.
+func (s *SecretPrivateRawStore) GetPrivateRaw(ctx context.Context, tr resource.Terraformed) ([]byte, error) {
+	sec := &corev1.Secret{}
+	err := s.kube.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: PrivateRawSecretName(tr)}, sec)
+	if kerrors.IsNotFound(err) {
+		return resource.AnnotationPrivateRawStore{}.GetPrivateRaw(ctx, tr)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errGetPrivateRawSecret)
+	}
+	return sec.Data[PrivateRawKey], nil
+}
+
+// SetPrivateRaw stores the given private attributes of the given resource in
+// its Secret. It removes the annotation of the private attributes from the
+// resource, in which case it reports that the resource needs to be updated.
+func (s *SecretPrivateRawStore) SetPrivateRaw(ctx context.Context, tr resource.Terraformed, privateRaw []byte) (bool, error) {
+	sec := &corev1.Secret{}
+	err := s.kube.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: PrivateRawSecretName(tr)}, sec)
+	switch {
+	case kerrors.IsNotFound(err) && len(privateRaw) == 0:
+	case kerrors.IsNotFound(err):
+		sec = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.namespace,
+				Name:      PrivateRawSecretName(tr),
+				Labels:    map[string]string{LabelKeyPrivateRaw: tr.GetName()},
+			},
+			Data: map[string][]byte{PrivateRawKey: privateRaw},
+		}
+		if err := s.kube.Create(ctx, sec); err != nil {
+			return false, errors.Wrap(err, errCreatePrivateRawSecret)
+		}
+	case err != nil:
+		return false, errors.Wrap(err, errGetPrivateRawSecret)
+	case !bytes.Equal(sec.Data[PrivateRawKey], privateRaw):
+		if sec.Data == nil {
+			sec.Data = map[string][]byte{}
+		}
+		sec.Data[PrivateRawKey] = privateRaw
+		if err := s.kube.Update(ctx, sec); err != nil {
+			return false, errors.Wrap(err, errUpdatePrivateRawSecret)
+		}
+	}
+	if _, ok := tr.GetAnnotations()[resource.AnnotationKeyPrivateRawAttribute]; !ok {
+		return false, nil
+	}
+	xpmeta.RemoveAnnotations(tr, resource.AnnotationKeyPrivateRawAttribute)
+	return true, nil
+}
+
+// RemovePrivateRaw deletes the Secret of the given resource.
+func (s *SecretPrivateRawStore) RemovePrivateRaw(ctx context.Context, tr resource.Terraformed) error {
+	sec := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: PrivateRawSecretName(tr)}}
+	return errors.Wrap(client.IgnoreNotFound(s.kube.Delete(ctx, sec)), errDeletePrivateRawSecret)
+}
+
+// removePrivateRaw removes the stored private attributes of the given
+// resource if it's being deleted, which must be called only once the external
+// resource is gone.
+func (e *external) removePrivateRaw(ctx context.Context, mg xpresource.Managed) error {
+	tr, ok := mg.(resource.Terraformed)
+	if e.privateRaw == nil || !ok || !xpmeta.WasDeleted(mg) {
+		return nil
+	}
+	return errors.Wrap(e.privateRaw.RemovePrivateRaw(ctx, tr), errRemovePrivateRaw)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+)
+
+func TestSecretPrivateRawStoreGetPrivateRaw(t *testing.T) {
+	errNotFound := kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "tfprivate-")
+	type want struct {
+		privateRaw []byte
+		err        error
+	}
+	cases := map[string]struct {
+		reason      string
+		existing    map[string][]byte
+		getErr      error
+		annotations map[string]string
+		want
+	}{
+		"FromSecret": {
+			reason:   "The private attributes should be read from the Secret of the resource",
+			existing: map[string][]byte{PrivateRawKey: []byte("private")},
+			want: want{
+				privateRaw: []byte("private"),
+			},
+		},
+		"FromAnnotation": {
+			reason:      "The private attributes should be read from the annotation if the resource has no Secret yet",
+			getErr:      errNotFound,
+			annotations: map[string]string{resource.AnnotationKeyPrivateRawAttribute: "annotated"},
+			want: want{
+				privateRaw: []byte("annotated"),
+			},
+		},
+		"None": {
+			reason: "No private attributes should be returned if neither the Secret nor the annotation exists",
+			getErr: errNotFound,
+		},
+		"GetFailed": {
+			reason: "An error should be returned if the Secret cannot be read",
+			getErr: errBoom,
+			want: want{
+				err: errors.Wrap(errBoom, errGetPrivateRawSecret),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kube := &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+					obj.(*corev1.Secret).Data = tc.existing
+					return tc.getErr
+				},
+			}
+			tr := &fake.Terraformed{Managed: xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}}
+			got, err := NewSecretPrivateRawStore(kube, "crossplane-system").GetPrivateRaw(context.TODO(), tr)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nGetPrivateRaw(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.privateRaw, got); diff != "" {
+				t.Errorf("\n%s\nGetPrivateRaw(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretPrivateRawStoreSetPrivateRaw(t *testing.T) {
+	errNotFound := kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "tfprivate-")
+	type want struct {
+		changed     bool
+		data        map[string][]byte
+		annotations map[string]string
+		err         error
+	}
+	cases := map[string]struct {
+		reason      string
+		existing    map[string][]byte
+		getErr      error
+		annotations map[string]string
+		privateRaw  []byte
+		want
+	}{
+		"Created": {
+			reason:     "A Secret with the private attributes should be created if the resource has none yet",
+			getErr:     errNotFound,
+			privateRaw: []byte("private"),
+			want: want{
+				data: map[string][]byte{PrivateRawKey: []byte("private")},
+			},
+		},
+		"NothingToCreate": {
+			reason: "No Secret should be created if there are no private attributes",
+			getErr: errNotFound,
+		},
+		"Updated": {
+			reason:     "The Secret should be updated if the private attributes have changed",
+			existing:   map[string][]byte{PrivateRawKey: []byte("old")},
+			privateRaw: []byte("private"),
+			want: want{
+				data: map[string][]byte{PrivateRawKey: []byte("private")},
+			},
+		},
+		"Unchanged": {
+			reason:     "The Secret should not be written if the private attributes haven't changed",
+			existing:   map[string][]byte{PrivateRawKey: []byte("private")},
+			privateRaw: []byte("private"),
+		},
+		"AnnotationMigrated": {
+			reason:      "The annotation should be removed once the private attributes are stored in the Secret",
+			getErr:      errNotFound,
+			annotations: map[string]string{resource.AnnotationKeyPrivateRawAttribute: "private", "other": "value"},
+			privateRaw:  []byte("private"),
+			want: want{
+				changed:     true,
+				data:        map[string][]byte{PrivateRawKey: []byte("private")},
+				annotations: map[string]string{"other": "value"},
+			},
+		},
+		"GetFailed": {
+			reason:     "An error should be returned if the Secret cannot be read",
+			getErr:     errBoom,
+			privateRaw: []byte("private"),
+			want: want{
+				err: errors.Wrap(errBoom, errGetPrivateRawSecret),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var written map[string][]byte
+			kube := &test.MockClient{
+				MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+					if diff := cmp.Diff("tfprivate-uid", key.Name); diff != "" {
+						t.Errorf("\n%s\nSetPrivateRaw(...): -want name, +got name:\n%s", tc.reason, diff)
+					}
+					obj.(*corev1.Secret).Data = tc.existing
+					return tc.getErr
+				},
+				MockCreate: func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+					written = obj.(*corev1.Secret).Data
+					return nil
+				},
+				MockUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+					written = obj.(*corev1.Secret).Data
+					return nil
+				},
+			}
+			tr := &fake.Terraformed{Managed: xpfake.Managed{ObjectMeta: metav1.ObjectMeta{UID: "uid", Annotations: tc.annotations}}}
+			changed, err := NewSecretPrivateRawStore(kube, "crossplane-system").SetPrivateRaw(context.TODO(), tr, tc.privateRaw)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nSetPrivateRaw(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.changed, changed); diff != "" {
+				t.Errorf("\n%s\nSetPrivateRaw(...): -want changed, +got changed:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.data, written); diff != "" {
+				t.Errorf("\n%s\nSetPrivateRaw(...): -want data, +got data:\n%s", tc.reason, diff)
+			}
+			if tc.annotations == nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.annotations, tr.GetAnnotations()); diff != "" {
+				t.Errorf("\n%s\nSetPrivateRaw(...): -want annotations, +got annotations:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
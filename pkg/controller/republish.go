@@ -0,0 +1,134 @@
+/*
+ Copyright 2021 The Crossplane Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/resource"
+)
+
+const (
+	errListResources      = "cannot list resources"
+	errFmtRepublish       = "cannot republish connection details of %q"
+	errFmtNoConfig        = "cannot find configuration of Terraform resource %q"
+	errGetParameters      = "cannot get parameters"
+	errGetObservation     = "cannot get observation"
+	errGetSensitiveObs    = "cannot get sensitive observation"
+	errGetSensitiveParams = "cannot get sensitive parameters"
+	errPublishConn        = "cannot publish connection details"
+)
+
+// ConnectionRepublisher re-derives the connection details of existing
+// resources from their spec, status and the sensitive attributes stored in
+// their connection secrets, and publishes them again. It is meant to be run
+// after the connection details configuration of a resource changes, e.g. a
+// new sensitive field or additional connection detail is added, so that the
+// connection secrets are updated without touching the spec of every resource.
+type ConnectionRepublisher struct {
+	kube      client.Client
+	publisher managed.ConnectionPublisher
+	provider  *config.Provider
+}
+
+// NewConnectionRepublisher returns a new ConnectionRepublisher that looks up
+// the resource configurations in the given provider configuration and
+// publishes connection details using the given publisher.
+func NewConnectionRepublisher(kube client.Client, publisher managed.ConnectionPublisher, pc *config.Provider) *ConnectionRepublisher {
+	return &ConnectionRepublisher{
+		kube:      kube,
+		publisher: publisher,
+		provider:  pc,
+	}
+}
+
+// Republish lists the resources of the kind of the given list and
+// re-publishes their connection details. It returns the number of resources
+// whose connection details are published. An error for a resource does not
+// stop the others from being processed; all errors are aggregated.
+func (r *ConnectionRepublisher) Republish(ctx context.Context, l xpresource.ManagedList, opts ...client.ListOption) (int, error) {
+	if err := r.kube.List(ctx, l, opts...); err != nil {
+		return 0, errors.Wrap(err, errListResources)
+	}
+	return r.RepublishResources(ctx, l.GetItems())
+}
+
+// RepublishResources re-publishes the connection details of the given
+// resources. It returns the number of resources whose connection details are
+// published.
+func (r *ConnectionRepublisher) RepublishResources(ctx context.Context, mgs []xpresource.Managed) (int, error) {
+	n := 0
+	var errs []error
+	for _, mg := range mgs {
+		if err := r.republish(ctx, mg); err != nil {
+			errs = append(errs, errors.Wrapf(err, errFmtRepublish, mg.GetName()))
+			continue
+		}
+		n++
+	}
+	return n, kerrors.NewAggregate(errs)
+}
+
+func (r *ConnectionRepublisher) republish(ctx context.Context, mg xpresource.Managed) error {
+	tr, ok := mg.(resource.Terraformed)
+	if !ok {
+		return errors.New(errUnexpectedObject)
+	}
+	cfg, ok := r.provider.Resources[tr.GetTerraformResourceType()]
+	if !ok {
+		return errors.Errorf(errFmtNoConfig, tr.GetTerraformResourceType())
+	}
+	sc := &APISecretClient{kube: r.kube}
+	// NOTE(muvaf): The connection details are derived from the Terraform
+	// state in the controller. We rebuild the attributes of the state the
+	// same way the workspace does, i.e. the parameters overlaid with the
+	// observation, both including their sensitive fields.
+	params, err := tr.GetParameters()
+	if err != nil {
+		return errors.Wrap(err, errGetParameters)
+	}
+	if err := resource.GetSensitiveParameters(ctx, sc, tr, params, tr.GetConnectionDetailsMapping()); err != nil {
+		return errors.Wrap(err, errGetSensitiveParams)
+	}
+	obs, err := tr.GetObservation()
+	if err != nil {
+		return errors.Wrap(err, errGetObservation)
+	}
+	if err := resource.GetSensitiveObservation(ctx, sc, tr.GetWriteConnectionSecretToReference(), obs); err != nil {
+		return errors.Wrap(err, errGetSensitiveObs)
+	}
+	attr := make(map[string]interface{}, len(params)+len(obs))
+	for k, v := range params {
+		attr[k] = v
+	}
+	for k, v := range obs {
+		attr[k] = v
+	}
+	conn, err := resource.GetConnectionDetails(attr, tr, cfg)
+	if err != nil {
+		return errors.Wrap(err, "cannot get connection details")
+	}
+	_, err = r.publisher.PublishConnection(ctx, tr, conn)
+	return errors.Wrap(err, errPublishConn)
+}
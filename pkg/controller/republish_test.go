@@ -0,0 +1,141 @@
+/*
+ Copyright 2021 The Crossplane Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+)
+
+func TestRepublishResources(t *testing.T) {
+	pc := &config.Provider{
+		Resources: map[string]*config.Resource{
+			"aws_db_instance": {
+				Sensitive: config.Sensitive{
+					AdditionalConnectionDetailsFn: func(attr map[string]interface{}) (map[string][]byte, error) {
+						return map[string][]byte{
+							"endpoint": []byte(attr["endpoint"].(string)),
+							"username": []byte(attr["username"].(string)),
+						}, nil
+					},
+				},
+			},
+		},
+	}
+	tr := func(tfType string) *fake.Terraformed {
+		return &fake.Terraformed{
+			Managed: xpfake.Managed{
+				ObjectMeta: metav1.ObjectMeta{Name: "example"},
+			},
+			Parameterizable: fake.Parameterizable{
+				Parameters: map[string]interface{}{"username": "admin"},
+			},
+			Observable: fake.Observable{
+				Observation: map[string]interface{}{"endpoint": "db.example.com"},
+			},
+			MetadataProvider: fake.MetadataProvider{Type: tfType},
+		}
+	}
+	type args struct {
+		publisher managed.ConnectionPublisher
+		mgs       []xpresource.Managed
+	}
+	type want struct {
+		n   int
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Success": {
+			reason: "Connection details derived from both parameters and observation should be published",
+			args: args{
+				publisher: managed.ConnectionPublisherFns{
+					PublishConnectionFn: func(_ context.Context, _ xpresource.ConnectionSecretOwner, c managed.ConnectionDetails) (bool, error) {
+						want := managed.ConnectionDetails{
+							"endpoint": []byte("db.example.com"),
+							"username": []byte("admin"),
+						}
+						if diff := cmp.Diff(want, c); diff != "" {
+							t.Errorf("PublishConnection(...): -want details, +got details:\n%s", diff)
+						}
+						return true, nil
+					},
+				},
+				mgs: []xpresource.Managed{tr("aws_db_instance")},
+			},
+			want: want{
+				n: 1,
+			},
+		},
+		"NoConfig": {
+			reason: "Resources without a configuration should be reported without stopping the others",
+			args: args{
+				publisher: managed.ConnectionPublisherFns{
+					PublishConnectionFn: func(_ context.Context, _ xpresource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+						return true, nil
+					},
+				},
+				mgs: []xpresource.Managed{tr("aws_unknown"), tr("aws_db_instance")},
+			},
+			want: want{
+				n:   1,
+				err: kerrors.NewAggregate([]error{errors.Wrapf(errors.Errorf(errFmtNoConfig, "aws_unknown"), errFmtRepublish, "example")}),
+			},
+		},
+		"PublishFailed": {
+			reason: "Errors from the connection publisher should be returned",
+			args: args{
+				publisher: managed.ConnectionPublisherFns{
+					PublishConnectionFn: func(_ context.Context, _ xpresource.ConnectionSecretOwner, _ managed.ConnectionDetails) (bool, error) {
+						return false, errBoom
+					},
+				},
+				mgs: []xpresource.Managed{tr("aws_db_instance")},
+			},
+			want: want{
+				err: kerrors.NewAggregate([]error{errors.Wrapf(errors.Wrap(errBoom, errPublishConn), errFmtRepublish, "example")}),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := NewConnectionRepublisher(&test.MockClient{}, tc.args.publisher, pc)
+			n, err := r.RepublishResources(context.TODO(), tc.args.mgs)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nRepublishResources(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.n, n); diff != "" {
+				t.Errorf("\n%s\nRepublishResources(...): -want count, +got count:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
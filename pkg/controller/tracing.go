@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+const (
+	attrName = "crossplane.resource.name"
+	attrUID  = "crossplane.resource.uid"
+	attrGVK  = "crossplane.resource.gvk"
+)
+
+// WithTracerProvider configures the controller to produce an OpenTelemetry
+// span for every Observe, Create, Update and Delete call of the resources so
+// that slow reconciliations can be traced down to the Terraform CLI commands
+// whose spans, if enabled with terraform.WithTracing, are their children.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *Connector) {
+		c.tracer = tp.Tracer(terraform.TracerName)
+	}
+}
+
+// tracedExternal produces a span for every call of the ExternalClient it
+// wraps.
+type tracedExternal struct {
+	managed.ExternalClient
+	tracer trace.Tracer
+}
+
+func (t *tracedExternal) start(ctx context.Context, op string, mg xpresource.Managed) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String(attrName, mg.GetName()),
+		attribute.String(attrUID, string(mg.GetUID())),
+	}
+	if gvk := mg.GetObjectKind().GroupVersionKind(); !gvk.Empty() {
+		attrs = append(attrs, attribute.String(attrGVK, gvk.String()))
+	}
+	return t.tracer.Start(ctx, op, trace.WithAttributes(attrs...))
+}
+
+// end records the given error, if any, on the given span and ends it.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *tracedExternal) Observe(ctx context.Context, mg xpresource.Managed) (managed.ExternalObservation, error) {
+	ctx, span := t.start(ctx, "Observe", mg)
+	obs, err := t.ExternalClient.Observe(ctx, mg)
+	span.SetAttributes(attribute.Bool("crossplane.resource.exists", obs.ResourceExists), attribute.Bool("crossplane.resource.up_to_date", obs.ResourceUpToDate))
+	end(span, err)
+	return obs, err
+}
+
+func (t *tracedExternal) Create(ctx context.Context, mg xpresource.Managed) (managed.ExternalCreation, error) {
+	ctx, span := t.start(ctx, "Create", mg)
+	cr, err := t.ExternalClient.Create(ctx, mg)
+	end(span, err)
+	return cr, err
+}
+
+func (t *tracedExternal) Update(ctx context.Context, mg xpresource.Managed) (managed.ExternalUpdate, error) {
+	ctx, span := t.start(ctx, "Update", mg)
+	up, err := t.ExternalClient.Update(ctx, mg)
+	end(span, err)
+	return up, err
+}
+
+func (t *tracedExternal) Delete(ctx context.Context, mg xpresource.Managed) error {
+	ctx, span := t.start(ctx, "Delete", mg)
+	err := t.ExternalClient.Delete(ctx, mg)
+	end(span, err)
+	return err
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package docs parses the documentation of Terraform resources written in the
+// format of the Terraform Registry, i.e. the markdown files under the
+// website/docs or docs directory of Terraform providers, to enrich the
+// generated APIs with descriptions missing in the provider schema.
+package docs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+)
+
+var (
+	// e.g. * `instance_type` - (Optional) Instance type to use for the instance.
+	argumentRegex = regexp.MustCompile("^\\s*[*-]\\s+`([a-zA-Z0-9_]+)`\\s*-?\\s*(?:\\((?:Optional|Required)[^)]*\\)\\s*)?(.*)$")
+	// e.g. The `ebs_block_device` block supports the following:
+	// or   ### ebs_block_device
+	blockRegex   = regexp.MustCompile("^(?:#{3,}\\s+`?([a-zA-Z0-9_]+)`?\\s*$|.*`([a-zA-Z0-9_]+)`.*(?:supports|block|blocks)\\b.*:\\s*$)")
+	sectionRegex = regexp.MustCompile(`^##\s+(.+?)\s*$`)
+)
+
+const (
+	// defaultHTTPTimeout is the timeout of the requests of the HTTP source
+	// if no client is given.
+	defaultHTTPTimeout = 30 * time.Second
+
+	sectionExample    = "example"
+	sectionArguments  = "arguments"
+	sectionAttributes = "attributes"
+)
+
+// ResourceDoc is the documentation of a Terraform resource.
+type ResourceDoc struct {
+	// Description of the resource, i.e. the first paragraph after the title.
+	Description string
+	// Examples are the HCL snippets in the "Example Usage" section.
+	Examples []string
+	// Fields are the descriptions of the arguments and attributes keyed by
+	// their names for top level fields, or by "<block name>.<field name>"
+	// for the fields of nested blocks.
+	Fields map[string]string
+}
+
+// Source returns the documentation of Terraform resources.
+type Source interface {
+	// ResourceDoc returns the documentation of the given Terraform resource,
+	// or nil if the resource is not documented.
+	ResourceDoc(ctx context.Context, name string) (*ResourceDoc, error)
+}
+
+// NewLocalSource returns a Source that reads the documentation from the given
+// directory, e.g. website/docs/r of a provider repository. The files are
+// expected to be named after the resources without the provider prefix, e.g.
+// "instance.html.markdown" or "instance.md" for "aws_instance".
+func NewLocalSource(dir string) *LocalSource {
+	return &LocalSource{dir: dir}
+}
+
+// LocalSource reads the documentation of resources from a local directory.
+type LocalSource struct {
+	dir string
+}
+
+// ResourceDoc returns the documentation of the given Terraform resource.
+func (s *LocalSource) ResourceDoc(_ context.Context, name string) (*ResourceDoc, error) {
+	for _, f := range docFileNames(name) {
+		file, err := os.Open(filepath.Clean(filepath.Join(s.dir, f)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot open documentation of %s", name)
+		}
+		defer file.Close() // nolint:errcheck
+		return Parse(file)
+	}
+	return nil, nil
+}
+
+// NewHTTPSource returns a Source that fetches the documentation of resources
+// from the URLs produced by the given format where "%s" is replaced by the
+// resource name without the provider prefix, e.g.
+// https://raw.githubusercontent.com/hashicorp/terraform-provider-aws/v4.15.1/website/docs/r/%s.html.markdown
+// If no client is given, a client whose requests time out after 30 seconds
+// is used.
+func NewHTTPSource(urlFormat string, c *http.Client) *HTTPSource {
+	if c == nil {
+		c = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	return &HTTPSource{urlFormat: urlFormat, client: c}
+}
+
+// HTTPSource fetches the documentation of resources over HTTP.
+type HTTPSource struct {
+	urlFormat string
+	client    *http.Client
+}
+
+// ResourceDoc returns the documentation of the given Terraform resource.
+func (s *HTTPSource) ResourceDoc(ctx context.Context, name string) (*ResourceDoc, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(s.urlFormat, trimProviderPrefix(name)), nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot build request for documentation of %s", name)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot fetch documentation of %s", name)
+	}
+	defer resp.Body.Close() // nolint:errcheck
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("cannot fetch documentation of %s: unexpected status %s", name, resp.Status)
+	}
+	return Parse(resp.Body)
+}
+
+func trimProviderPrefix(name string) string {
+	if i := strings.Index(name, "_"); i != -1 {
+		return name[i+1:]
+	}
+	return name
+}
+
+func docFileNames(name string) []string {
+	n := trimProviderPrefix(name)
+	return []string{n + ".html.markdown", n + ".html.md", n + ".markdown", n + ".md"}
+}
+
+// Parse parses the given markdown documentation of a resource.
+func Parse(r io.Reader) (*ResourceDoc, error) { // nolint:gocyclo
+	d := &ResourceDoc{Fields: map[string]string{}}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var section, block, lastField string
+	var code *strings.Builder
+	inFrontMatter, seenTitle := false, false
+	for lineNo := 0; sc.Scan(); lineNo++ {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case lineNo == 0 && trimmed == "---":
+			inFrontMatter = true
+			continue
+		case inFrontMatter:
+			inFrontMatter = trimmed != "---"
+			continue
+		case strings.HasPrefix(trimmed, "```"):
+			if code == nil {
+				code = &strings.Builder{}
+				continue
+			}
+			if section == sectionExample {
+				d.Examples = append(d.Examples, strings.TrimSuffix(code.String(), "\n"))
+			}
+			code = nil
+			continue
+		case code != nil:
+			code.WriteString(line + "\n")
+			continue
+		case strings.HasPrefix(trimmed, "# "):
+			seenTitle = true
+			continue
+		}
+		if m := sectionRegex.FindStringSubmatch(trimmed); m != nil {
+			section, block, lastField = sectionType(m[1]), "", ""
+			continue
+		}
+		if section == "" {
+			if seenTitle && trimmed != "" {
+				d.Description = strings.TrimSpace(d.Description + " " + trimmed)
+			} else if trimmed == "" && d.Description != "" {
+				// Only the first paragraph is used as description.
+				seenTitle = false
+			}
+			continue
+		}
+		if section != sectionArguments && section != sectionAttributes {
+			continue
+		}
+		if m := argumentRegex.FindStringSubmatch(line); m != nil {
+			lastField = m[1]
+			if block != "" {
+				lastField = block + "." + m[1]
+			}
+			if _, ok := d.Fields[lastField]; !ok {
+				d.Fields[lastField] = strings.TrimSpace(m[2])
+			}
+			continue
+		}
+		if m := blockRegex.FindStringSubmatch(trimmed); m != nil {
+			block, lastField = m[1]+m[2], ""
+			continue
+		}
+		switch {
+		case trimmed == "":
+			lastField = ""
+		case lastField != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			// Continuation of the description of the previous field.
+			d.Fields[lastField] = strings.TrimSpace(d.Fields[lastField] + " " + trimmed)
+		}
+	}
+	return d, errors.Wrap(sc.Err(), "cannot read documentation")
+}
+
+func sectionType(title string) string {
+	t := strings.ToLower(title)
+	switch {
+	case strings.Contains(t, "example"):
+		return sectionExample
+	case strings.Contains(t, "argument"):
+		return sectionArguments
+	case strings.Contains(t, "attribute"):
+		return sectionAttributes
+	}
+	return "other"
+}
+
+// Apply sets the descriptions of the fields of the given schema that do not
+// have one using the documentation. The fields of nested blocks are looked up
+// by the name of their block.
+func (d *ResourceDoc) Apply(r *schema.Resource) {
+	if d == nil || r == nil {
+		return
+	}
+	d.apply("", r.Schema)
+}
+
+func (d *ResourceDoc) apply(block string, m map[string]*schema.Schema) {
+	for name, s := range m {
+		key := name
+		if block != "" {
+			key = block + "." + name
+		}
+		if s.Description == "" {
+			if desc, ok := d.Fields[key]; ok {
+				s.Description = desc
+			}
+		}
+		if e, ok := s.Elem.(*schema.Resource); ok {
+			d.apply(name, e.Schema)
+		}
+	}
+}
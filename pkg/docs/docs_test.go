@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const instanceDoc = "---\n" +
+	"subcategory: \"EC2 (Elastic Compute Cloud)\"\n" +
+	"layout: \"aws\"\n" +
+	"---\n" +
+	"\n" +
+	"# Resource: aws_instance\n" +
+	"\n" +
+	"Provides an EC2 instance resource. This allows instances to be created,\n" +
+	"updated, and deleted.\n" +
+	"\n" +
+	"Instances also support provisioning.\n" +
+	"\n" +
+	"## Example Usage\n" +
+	"\n" +
+	"```terraform\n" +
+	"resource \"aws_instance\" \"web\" {\n" +
+	"  instance_type = \"t3.micro\"\n" +
+	"}\n" +
+	"```\n" +
+	"\n" +
+	"## Argument Reference\n" +
+	"\n" +
+	"* `instance_type` - (Optional) Instance type to use for the instance.\n" +
+	"  Updates to this field will trigger a stop/start of the instance.\n" +
+	"* `ebs_block_device` - (Optional) One or more configuration blocks with additional EBS block devices.\n" +
+	"\n" +
+	"### ebs_block_device\n" +
+	"\n" +
+	"* `volume_size` - (Optional) Size of the volume in gibibytes (GiB).\n" +
+	"\n" +
+	"## Attributes Reference\n" +
+	"\n" +
+	"* `arn` - ARN of the instance.\n"
+
+func TestParse(t *testing.T) {
+	want := &ResourceDoc{
+		Description: "Provides an EC2 instance resource. This allows instances to be created, updated, and deleted.",
+		Examples:    []string{"resource \"aws_instance\" \"web\" {\n  instance_type = \"t3.micro\"\n}"},
+		Fields: map[string]string{
+			"instance_type":                "Instance type to use for the instance. Updates to this field will trigger a stop/start of the instance.",
+			"ebs_block_device":             "One or more configuration blocks with additional EBS block devices.",
+			"ebs_block_device.volume_size": "Size of the volume in gibibytes (GiB).",
+			"arn":                          "ARN of the instance.",
+		},
+	}
+	got, err := Parse(strings.NewReader(instanceDoc))
+	if err != nil {
+		t.Fatalf("Parse(...): unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Parse(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestApply(t *testing.T) {
+	d := &ResourceDoc{
+		Fields: map[string]string{
+			"instance_type":                "Instance type to use for the instance.",
+			"ami":                          "AMI to use for the instance.",
+			"ebs_block_device.volume_size": "Size of the volume in gibibytes (GiB).",
+		},
+	}
+	r := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"instance_type": {Type: schema.TypeString},
+			"ami":           {Type: schema.TypeString, Description: "From the schema."},
+			"ebs_block_device": {
+				Type: schema.TypeList,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"volume_size": {Type: schema.TypeInt},
+					},
+				},
+			},
+		},
+	}
+	d.Apply(r)
+	got := map[string]string{
+		"instance_type":                r.Schema["instance_type"].Description,
+		"ami":                          r.Schema["ami"].Description,
+		"ebs_block_device.volume_size": r.Schema["ebs_block_device"].Elem.(*schema.Resource).Schema["volume_size"].Description,
+	}
+	want := map[string]string{
+		"instance_type":                "Instance type to use for the instance.",
+		"ami":                          "From the schema.",
+		"ebs_block_device.volume_size": "Size of the volume in gibibytes (GiB).",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Apply(...): -want descriptions, +got descriptions:\n%s", diff)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package inventory contains the registry of the kinds a provider supports
+// that is generated along with the controllers, so that tooling can
+// introspect the capabilities of a running provider.
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigMapKey is the key of the inventory in the data of the ConfigMap
+	// it is published to.
+	ConfigMapKey = "inventory.json"
+
+	errMarshal = "cannot marshal inventory"
+	errPublish = "cannot publish inventory"
+)
+
+// Resource is a kind the provider supports.
+type Resource struct {
+	// Group is the API group of the kind.
+	Group string `json:"group"`
+	// Version is the API version of the kind.
+	Version string `json:"version"`
+	// Kind is the name of the kind.
+	Kind string `json:"kind"`
+	// TerraformResourceType is the type of the Terraform resource the kind
+	// is generated from, e.g. "aws_vpc".
+	TerraformResourceType string `json:"terraformResourceType"`
+	// SchemaVersion is the version of the schema of the Terraform resource.
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+// Inventory lists the kinds a provider supports.
+type Inventory struct {
+	// TerraformProviderVersion is the version of the Terraform provider whose
+	// schema the kinds are generated from. It is empty if the version was
+	// not recorded during generation.
+	TerraformProviderVersion string `json:"terraformProviderVersion,omitempty"`
+	// Resources are the supported kinds sorted by their groups, versions and
+	// kinds.
+	Resources []Resource `json:"resources"`
+}
+
+// Handler returns an http.Handler that serves the inventory in JSON format,
+// e.g. to be registered to the metrics server of the manager.
+func (i Inventory) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		raw, err := json.Marshal(i)
+		if err != nil {
+			http.Error(w, errors.Wrap(err, errMarshal).Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(raw)
+	})
+}
+
+// Publish creates or updates the ConfigMap with the given name in the given
+// namespace so that it contains the inventory in JSON format under
+// ConfigMapKey. It is meant to be called once at startup.
+func (i Inventory) Publish(ctx context.Context, kube client.Client, namespace, name string) error {
+	raw, err := json.Marshal(i)
+	if err != nil {
+		return errors.Wrap(err, errMarshal)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Data: map[string]string{
+			ConfigMapKey: string(raw),
+		},
+	}
+	return errors.Wrap(xpresource.NewAPIPatchingApplicator(kube).Apply(ctx, cm), errPublish)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	errBoom = errors.New("boom")
+
+	inv = Inventory{
+		TerraformProviderVersion: "4.15.1",
+		Resources: []Resource{
+			{Group: "ec2.aws.jet.crossplane.io", Version: "v1alpha2", Kind: "VPC", TerraformResourceType: "aws_vpc", SchemaVersion: 1},
+		},
+	}
+	invJSON = `{"terraformProviderVersion":"4.15.1","resources":[{"group":"ec2.aws.jet.crossplane.io","version":"v1alpha2","kind":"VPC","terraformResourceType":"aws_vpc","schemaVersion":1}]}`
+)
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	inv.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/inventory", nil))
+	if diff := cmp.Diff("application/json", rec.Header().Get("Content-Type")); diff != "" {
+		t.Errorf("Handler(...): -want content type, +got content type:\n%s", diff)
+	}
+	if diff := cmp.Diff(invJSON, rec.Body.String()); diff != "" {
+		t.Errorf("Handler(...): -want body, +got body:\n%s", diff)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	type want struct {
+		data map[string]string
+		err  error
+	}
+	cases := map[string]struct {
+		reason string
+		kube   client.Client
+		want
+	}{
+		"Created": {
+			reason: "The ConfigMap should be created with the inventory if it does not exist",
+			want: want{
+				data: map[string]string{ConfigMapKey: invJSON},
+			},
+		},
+		"CreateFailed": {
+			reason: "An error should be returned if the ConfigMap cannot be created",
+			kube: &test.MockClient{
+				MockGet:    test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "inventory")),
+				MockCreate: test.NewMockCreateFn(errBoom),
+			},
+			want: want{
+				err: errors.Wrap(errors.Wrap(errBoom, "cannot create object"), errPublish),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got map[string]string
+			kube := tc.kube
+			if kube == nil {
+				kube = &test.MockClient{
+					MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "inventory")),
+					MockCreate: func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+						got = obj.(*corev1.ConfigMap).Data
+						return nil
+					},
+				}
+			}
+			err := inv.Publish(context.TODO(), kube, "crossplane-system", "inventory")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPublish(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.data, got); diff != "" {
+				t.Errorf("\n%s\nPublish(...): -want data, +got data:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package meta contains helpers to read and write the metadata terrajet stamps
+// on the objects it generates and the managed resources it reconciles.
+package meta
+
+import (
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AnnotationKeyTerraformResourceType is the key of the annotation on the
+	// generated CRDs that records the type of the Terraform resource the CRD
+	// is generated from, e.g. "aws_vpc". The annotations are set by the
+	// +kubebuilder:metadata:annotations marker, which requires controller-gen
+	// v0.9.0 or later; older versions do not know the marker and generate the
+	// CRDs without the annotations.
+	AnnotationKeyTerraformResourceType = "terrajet.crossplane.io/terraform-resource-type"
+
+	// AnnotationKeyTerraformProviderVersion is the key of the annotation on
+	// the generated CRDs that records the version of the Terraform provider
+	// whose schema the CRD is generated from.
+	AnnotationKeyTerraformProviderVersion = "terrajet.crossplane.io/terraform-provider-version"
+
+	// AnnotationKeyLastApplyTime is the key of the annotation on the managed
+	// resources that records the time of the last successful apply in RFC
+	// 3339 format.
+	AnnotationKeyLastApplyTime = "terrajet.crossplane.io/last-apply-time"
+
+	// AnnotationKeyLastApplyGeneration is the key of the annotation on the
+	// managed resources that records the generation of the spec the last
+	// successful apply was run with.
+	AnnotationKeyLastApplyGeneration = "terrajet.crossplane.io/last-apply-generation"
+)
+
+// GetTerraformResourceType returns the type of the Terraform resource the
+// given CRD is generated from. It returns an empty string if the CRD is not
+// generated by terrajet.
+func GetTerraformResourceType(o metav1.Object) string {
+	return o.GetAnnotations()[AnnotationKeyTerraformResourceType]
+}
+
+// GetTerraformProviderVersion returns the version of the Terraform provider
+// whose schema the given CRD is generated from. It returns an empty string if
+// the version was not recorded during generation.
+func GetTerraformProviderVersion(o metav1.Object) string {
+	return o.GetAnnotations()[AnnotationKeyTerraformProviderVersion]
+}
+
+// SetLastApply records the given time and generation as those of the last
+// successful apply of the given managed resource. The generation is the one
+// the resource had when the apply started, which may be older than its
+// current generation if the apply ran asynchronously.
+func SetLastApply(o metav1.Object, t time.Time, generation int64) {
+	a := o.GetAnnotations()
+	if a == nil {
+		a = map[string]string{}
+	}
+	a[AnnotationKeyLastApplyTime] = t.UTC().Format(time.RFC3339)
+	a[AnnotationKeyLastApplyGeneration] = strconv.FormatInt(generation, 10)
+	o.SetAnnotations(a)
+}
+
+// GetLastApplyTime returns the time of the last successful apply of the given
+// managed resource. It returns the zero time if the time is not recorded or
+// cannot be parsed.
+func GetLastApplyTime(o metav1.Object) time.Time {
+	t, err := time.Parse(time.RFC3339, o.GetAnnotations()[AnnotationKeyLastApplyTime])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// GetLastApplyGeneration returns the generation of the spec the last
+// successful apply of the given managed resource was run with. It returns
+// zero if the generation is not recorded or cannot be parsed.
+func GetLastApplyGeneration(o metav1.Object) int64 {
+	g, err := strconv.ParseInt(o.GetAnnotations()[AnnotationKeyLastApplyGeneration], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return g
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetTerraformMetadata(t *testing.T) {
+	type want struct {
+		resourceType    string
+		providerVersion string
+	}
+	cases := map[string]struct {
+		reason      string
+		annotations map[string]string
+		want
+	}{
+		"Annotated": {
+			reason: "The recorded Terraform resource type and provider version should be returned",
+			annotations: map[string]string{
+				AnnotationKeyTerraformResourceType:    "aws_vpc",
+				AnnotationKeyTerraformProviderVersion: "4.15.1",
+			},
+			want: want{
+				resourceType:    "aws_vpc",
+				providerVersion: "4.15.1",
+			},
+		},
+		"NotAnnotated": {
+			reason: "Empty strings should be returned if the object is not annotated",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &metav1.ObjectMeta{Annotations: tc.annotations}
+			if diff := cmp.Diff(tc.want.resourceType, GetTerraformResourceType(o)); diff != "" {
+				t.Errorf("\n%s\nGetTerraformResourceType(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.providerVersion, GetTerraformProviderVersion(o)); diff != "" {
+				t.Errorf("\n%s\nGetTerraformProviderVersion(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestLastApply(t *testing.T) {
+	applied := time.Date(2022, 5, 4, 12, 30, 0, 0, time.UTC)
+	type want struct {
+		time       time.Time
+		generation int64
+	}
+	cases := map[string]struct {
+		reason string
+		obj    *metav1.ObjectMeta
+		set    bool
+		gen    int64
+		want   want
+	}{
+		"Recorded": {
+			reason: "The time and the generation of the last apply should be read as they are recorded",
+			obj:    &metav1.ObjectMeta{Generation: 3, Annotations: map[string]string{"other": "value"}},
+			set:    true,
+			gen:    3,
+			want: want{
+				time:       applied,
+				generation: 3,
+			},
+		},
+		"GenerationAtStart": {
+			reason: "The generation the apply started with should be recorded even if the resource has changed since then",
+			obj:    &metav1.ObjectMeta{Generation: 4},
+			set:    true,
+			gen:    3,
+			want: want{
+				time:       applied,
+				generation: 3,
+			},
+		},
+		"NotRecorded": {
+			reason: "Zero values should be returned if the last apply is not recorded",
+			obj:    &metav1.ObjectMeta{Generation: 3},
+		},
+		"Malformed": {
+			reason: "Zero values should be returned if the recorded values cannot be parsed",
+			obj: &metav1.ObjectMeta{Annotations: map[string]string{
+				AnnotationKeyLastApplyTime:       "yesterday",
+				AnnotationKeyLastApplyGeneration: "three",
+			}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if tc.set {
+				SetLastApply(tc.obj, applied.In(time.FixedZone("UTC+3", 3*60*60)), tc.gen)
+			}
+			if diff := cmp.Diff(tc.want.time, GetLastApplyTime(tc.obj)); diff != "" {
+				t.Errorf("\n%s\nGetLastApplyTime(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.generation, GetLastApplyGeneration(tc.obj)); diff != "" {
+				t.Errorf("\n%s\nGetLastApplyGeneration(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
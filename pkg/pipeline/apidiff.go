@@ -0,0 +1,284 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const markerOptional = "+kubebuilder:validation:Optional"
+
+// apiField is a field of a generated API type.
+type apiField struct {
+	typ      string
+	required bool
+}
+
+// apiSnapshot is the set of fields of the generated API types keyed by
+// "<package directory> <type name>.<json name>".
+type apiSnapshot map[string]apiField
+
+// takeAPISnapshot parses the generated types files under the given apis
+// directory. A missing directory results in an empty snapshot.
+func takeAPISnapshot(apisDir string) (apiSnapshot, error) {
+	s := apiSnapshot{}
+	err := filepath.Walk(apisDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasPrefix(info.Name(), "zz_") || !strings.HasSuffix(info.Name(), "_types.go") {
+			return nil
+		}
+		rel, err := filepath.Rel(apisDir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		return errors.Wrapf(s.addFile(filepath.Join("apis", rel), path), "cannot parse %s", path)
+	})
+	return s, errors.Wrap(err, "cannot walk the apis directory")
+}
+
+func (s apiSnapshot) addFile(pkgDir, path string) error {
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				name, omitEmpty := jsonName(field)
+				if name == "" {
+					continue
+				}
+				s[fmt.Sprintf("%s %s.%s", pkgDir, ts.Name.Name, name)] = apiField{
+					typ:      types.ExprString(field.Type),
+					required: !omitEmpty && !strings.Contains(field.Doc.Text(), markerOptional),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonName returns the JSON name of the given field and whether it is
+// omitted when empty. An empty name is returned for inlined and ignored
+// fields.
+func jsonName(f *ast.Field) (string, bool) {
+	if f.Tag == nil {
+		return "", false
+	}
+	tag := reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Get("json")
+	parts := strings.Split(tag, ",")
+	if parts[0] == "" || parts[0] == "-" {
+		return "", false
+	}
+	omitEmpty := false
+	for _, p := range parts[1:] {
+		if p == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty
+}
+
+// FieldChangeType is the type of change of a field of a generated API type.
+type FieldChangeType string
+
+// Types of field changes.
+const (
+	FieldAdded          FieldChangeType = "Added"
+	FieldRemoved        FieldChangeType = "Removed"
+	FieldRetyped        FieldChangeType = "Retyped"
+	FieldBecameRequired FieldChangeType = "BecameRequired"
+	FieldBecameOptional FieldChangeType = "BecameOptional"
+)
+
+// FieldChange is a change of a field of a generated API type.
+type FieldChange struct {
+	// Path of the field in the form of
+	// "<package directory> <type name>.<json name>".
+	Path string
+	// Type of the change.
+	Type FieldChangeType
+	// OldType is the Go type of the field before the change. It is empty for
+	// added fields.
+	OldType string
+	// NewType is the Go type of the field after the change. It is empty for
+	// removed fields.
+	NewType string
+	// Breaking is true if the change may break the users of the API, i.e.
+	// existing manifests may become invalid or lose data.
+	Breaking bool
+}
+
+// String returns a human-readable description of the change.
+func (c FieldChange) String() string {
+	switch c.Type {
+	case FieldAdded:
+		if c.Breaking {
+			return fmt.Sprintf("%s: new required field", c.Path)
+		}
+		return fmt.Sprintf("%s: field added", c.Path)
+	case FieldRemoved:
+		return fmt.Sprintf("%s: field removed", c.Path)
+	case FieldRetyped:
+		return fmt.Sprintf("%s: type changed from %s to %s", c.Path, c.OldType, c.NewType)
+	case FieldBecameRequired:
+		return fmt.Sprintf("%s: field became required", c.Path)
+	case FieldBecameOptional:
+		return fmt.Sprintf("%s: field became optional", c.Path)
+	}
+	return fmt.Sprintf("%s: %s", c.Path, c.Type)
+}
+
+// APIDiff is the set of changes between two sets of generated API types.
+type APIDiff struct {
+	// Changes are the field changes sorted by their paths.
+	Changes []FieldChange
+}
+
+// Breaking returns the breaking changes of the diff.
+func (d *APIDiff) Breaking() []FieldChange {
+	var result []FieldChange
+	for _, c := range d.Changes {
+		if c.Breaking {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Print writes a report of the changes to the given writer. Breaking changes
+// are marked so that provider maintainers can decide whether a new API
+// version and a conversion webhook are needed.
+func (d *APIDiff) Print(w io.Writer) {
+	if len(d.Changes) == 0 {
+		fmt.Fprintln(w, "No API changes found.")
+		return
+	}
+	fmt.Fprintf(w, "Found %d API change(s), %d of them breaking:\n", len(d.Changes), len(d.Breaking()))
+	for _, c := range d.Changes {
+		mark := " "
+		if c.Breaking {
+			mark = "!"
+		}
+		fmt.Fprintf(w, "%s %s\n", mark, c)
+	}
+}
+
+// CompareAPIs compares the generated API types in the given apis directories,
+// e.g. the apis directories of a provider generated from two versions of the
+// Terraform provider schema.
+func CompareAPIs(prevDir, nextDir string) (*APIDiff, error) {
+	prev, err := takeAPISnapshot(prevDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read the API types in %s", prevDir)
+	}
+	next, err := takeAPISnapshot(nextDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read the API types in %s", nextDir)
+	}
+	return &APIDiff{Changes: diffAPISnapshots(prev, next)}, nil
+}
+
+// diffAPISnapshots returns the changes between the given snapshots. Removed
+// fields, changed field types and fields that became required are breaking.
+// New required fields are breaking only if their type exists in the previous
+// snapshot, otherwise the type is new.
+func diffAPISnapshots(prev, next apiSnapshot) []FieldChange {
+	prevTypes := map[string]bool{}
+	for k := range prev {
+		prevTypes[k[:strings.LastIndex(k, ".")]] = true
+	}
+	var result []FieldChange
+	for k, p := range prev {
+		n, ok := next[k]
+		switch {
+		case !ok:
+			result = append(result, FieldChange{Path: k, Type: FieldRemoved, OldType: p.typ, Breaking: true})
+		case n.typ != p.typ:
+			result = append(result, FieldChange{Path: k, Type: FieldRetyped, OldType: p.typ, NewType: n.typ, Breaking: true})
+		case n.required && !p.required:
+			result = append(result, FieldChange{Path: k, Type: FieldBecameRequired, OldType: p.typ, NewType: n.typ, Breaking: true})
+		case !n.required && p.required:
+			result = append(result, FieldChange{Path: k, Type: FieldBecameOptional, OldType: p.typ, NewType: n.typ})
+		}
+	}
+	for k, n := range next {
+		if _, ok := prev[k]; ok {
+			continue
+		}
+		result = append(result, FieldChange{
+			Path:     k,
+			Type:     FieldAdded,
+			NewType:  n.typ,
+			Breaking: n.required && prevTypes[k[:strings.LastIndex(k, ".")]],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// breakingChanges returns the descriptions of the changes between the given
+// snapshots that may break the users of the API.
+func breakingChanges(prev, next apiSnapshot) []string {
+	var result []string
+	for _, c := range diffAPISnapshots(prev, next) {
+		if c.Breaking {
+			result = append(result, c.String())
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// printBreakingChanges writes a report of the given breaking changes.
+func printBreakingChanges(w io.Writer, changes []string) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nFound %d breaking API change(s) compared to the previous generation:\n", len(changes))
+	for _, c := range changes {
+		fmt.Fprintf(w, "  %s\n", c)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBreakingChanges(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		prev   apiSnapshot
+		next   apiSnapshot
+		want   []string
+	}{
+		"NoChanges": {
+			reason: "Nothing should be reported if the APIs are the same",
+			prev:   apiSnapshot{"apis/ec2/v1alpha1 InstanceParameters.ami": {typ: "*string"}},
+			next:   apiSnapshot{"apis/ec2/v1alpha1 InstanceParameters.ami": {typ: "*string"}},
+		},
+		"NonBreaking": {
+			reason: "Optional new fields, fields that became optional and required fields of new types should not be reported",
+			prev:   apiSnapshot{"apis/ec2/v1alpha1 InstanceParameters.ami": {typ: "string", required: true}},
+			next: apiSnapshot{
+				"apis/ec2/v1alpha1 InstanceParameters.ami":  {typ: "string"},
+				"apis/ec2/v1alpha1 InstanceParameters.tags": {typ: "map[string]*string"},
+				"apis/ec2/v1alpha1 VolumeParameters.size":   {typ: "float64", required: true},
+			},
+		},
+		"Breaking": {
+			reason: "Removed and retyped fields, fields that became required and new required fields of existing types should be reported",
+			prev: apiSnapshot{
+				"apis/ec2/v1alpha1 InstanceParameters.ami":   {typ: "*string"},
+				"apis/ec2/v1alpha1 InstanceParameters.count": {typ: "*int64"},
+				"apis/ec2/v1alpha1 InstanceParameters.name":  {typ: "*string"},
+			},
+			next: apiSnapshot{
+				"apis/ec2/v1alpha1 InstanceParameters.ami":   {typ: "*string", required: true},
+				"apis/ec2/v1alpha1 InstanceParameters.count": {typ: "*float64"},
+				"apis/ec2/v1alpha1 InstanceParameters.type":  {typ: "string", required: true},
+			},
+			want: []string{
+				"apis/ec2/v1alpha1 InstanceParameters.ami: field became required",
+				"apis/ec2/v1alpha1 InstanceParameters.count: type changed from *int64 to *float64",
+				"apis/ec2/v1alpha1 InstanceParameters.name: field removed",
+				"apis/ec2/v1alpha1 InstanceParameters.type: new required field",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, breakingChanges(tc.prev, tc.next)); diff != "" {
+				t.Errorf("\n%s\nbreakingChanges(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
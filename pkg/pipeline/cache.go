@@ -0,0 +1,246 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	tjtypes "github.com/crossplane/terrajet/pkg/types"
+)
+
+// cacheVersion is bumped whenever the output of the generators changes in a
+// way that cached resources need to be regenerated.
+const cacheVersion = "4"
+
+// generationCache keeps the hashes of the resources generated in the
+// previous run together with the information that later generation steps
+// need from the skipped ones.
+type generationCache struct {
+	// Key is the hash of everything that affects all resources, i.e. the
+	// templates, the license header and the provider-wide configuration.
+	Key       string                    `json:"key"`
+	Resources map[string]cachedResource `json:"resources"`
+}
+
+type cachedResource struct {
+	Hash                  string            `json:"hash"`
+	ParametersTypeName    string            `json:"parametersTypeName"`
+	ControllerPackagePath string            `json:"controllerPackagePath"`
+	SensitiveFieldPaths   map[string]string `json:"sensitiveFieldPaths,omitempty"`
+	LateInitIgnoredFields []string          `json:"lateInitIgnoredFields,omitempty"`
+	// Files are the hashes of the files generated for the resource keyed by
+	// their paths. The hashes are computed when the cache is written, i.e.
+	// after the generated files are formatted.
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// loadGenerationCache reads the cache in the given path. An empty cache with
+// the given key is returned if the file does not exist or its key does not
+// match.
+func loadGenerationCache(path, key string) (*generationCache, error) {
+	empty := &generationCache{Key: key, Resources: map[string]cachedResource{}}
+	raw, err := os.ReadFile(filepath.Clean(path))
+	if os.IsNotExist(err) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read generation cache")
+	}
+	c := &generationCache{}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal generation cache")
+	}
+	if c.Key != key || c.Resources == nil {
+		return empty, nil
+	}
+	return c, nil
+}
+
+// write computes the hashes of the files of the cached resources and writes
+// the cache to the given path. The resources whose files no longer exist,
+// e.g. the ones removed from the provider, are dropped from the cache.
+func (c *generationCache) write(path string) error {
+	for name, cr := range c.Resources {
+		for f := range cr.Files {
+			h, err := fileHash(f)
+			if os.IsNotExist(errors.Cause(err)) {
+				delete(c.Resources, name)
+				break
+			}
+			if err != nil {
+				return errors.Wrapf(err, "cannot compute hash of file %s of resource %s", f, name)
+			}
+			cr.Files[f] = h
+		}
+	}
+	raw, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal generation cache")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return errors.Wrap(err, "cannot create generation cache directory")
+	}
+	return errors.Wrap(os.WriteFile(path, raw, 0600), "cannot write generation cache")
+}
+
+// lookup returns the cached resource if its hash matches the given one and
+// none of its generated files is changed or removed since the cache was
+// written.
+func (c *generationCache) lookup(name, hash string) (cachedResource, bool) {
+	cr, ok := c.Resources[name]
+	if !ok || cr.Hash != hash || len(cr.Files) == 0 {
+		return cr, false
+	}
+	for f, want := range cr.Files {
+		if got, err := fileHash(f); err != nil || got != want {
+			return cr, false
+		}
+	}
+	return cr, true
+}
+
+// fileHash returns the hash of the content of the file in the given path.
+func fileHash(path string) (string, error) {
+	raw, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", errors.Wrap(err, "cannot read file")
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// restore populates the information collected during the generation of the
+// given resource from its cached version.
+func (cr cachedResource) restore(r *config.Resource) {
+	for tf, xp := range cr.SensitiveFieldPaths {
+		r.Sensitive.AddFieldPath(tf, xp)
+	}
+	for _, f := range cr.LateInitIgnoredFields {
+		r.LateInitializer.AddIgnoredCanonicalFields(f)
+	}
+}
+
+// cacheable returns whether the generation cache can be used with the given
+// provider configuration. A custom kind naming strategy cannot be hashed, so
+// its changes could not be detected.
+func cacheable(pc *config.Provider) bool {
+	switch pc.KindNaming.(type) {
+	case nil, config.DefaultKindNaming, *config.DefaultKindNaming:
+		return true
+	}
+	return false
+}
+
+// cacheKey returns the hash of the inputs that affect all resources.
+func cacheKey(pc *config.Provider, tmpls *templateSet, licenseHeaderPath string) (string, error) {
+	header, err := os.ReadFile(filepath.Clean(licenseHeaderPath))
+	if err != nil {
+		return "", errors.Wrap(err, "cannot read license header")
+	}
+	h := sha256.New()
+	// The kind naming strategy determines the resource names of the CRDs and
+	// the plurals in the controllers. fmt prints the maps sorted, so the
+	// hash is deterministic.
+	fmt.Fprintf(h, "%#v", pc.KindNaming)
+	for _, s := range []string{cacheVersion, pc.RootGroup, pc.ShortName, pc.ModulePath, pc.TerraformProviderVersion, string(header),
+		tmpls.crdTypes, tmpls.groupVersionInfo, tmpls.doc, tmpls.terraformed, tmpls.terraformedFuzz, tmpls.controller, tmpls.register, tmpls.groupRegister, tmpls.setup} {
+		fmt.Fprintf(h, "%d:%s", len(s), s)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// resourceHash returns the hash of the schema and the configuration of the
+// given resource that affect its generated files. It needs to be computed
+// before the resource is generated since the generation modifies the schema.
+func resourceHash(r *config.Resource) (string, error) {
+	cfg := struct {
+		Name                string
+		ShortGroup          string
+		Version             string
+		Kind                string
+		UseAsync            bool
+		ExplainDrift        bool
+		Initializers        int
+		OmittedFields       []string
+		DisableNameInit     bool
+		NamingStrategy      bool
+		References          config.References
+		IgnoredFields       []string
+		PrinterColumns      []config.PrinterColumn
+		ObservationListCaps map[string]int
+		Documentation       config.Documentation
+	}{
+		Name:                r.Name,
+		ShortGroup:          r.ShortGroup,
+		Version:             r.Version,
+		Kind:                r.Kind,
+		UseAsync:            r.UseAsync,
+		ExplainDrift:        r.ExplainDrift,
+		Initializers:        len(r.InitializerFns),
+		OmittedFields:       r.ExternalName.OmittedFields,
+		DisableNameInit:     r.ExternalName.DisableNameInitializer,
+		NamingStrategy:      r.ExternalName.NamingStrategy != nil,
+		References:          r.References,
+		IgnoredFields:       r.LateInitializer.IgnoredFields,
+		PrinterColumns:      r.PrinterColumns,
+		ObservationListCaps: r.ObservationListCaps,
+		Documentation:       r.Documentation,
+	}
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot marshal resource configuration")
+	}
+	h := sha256.New()
+	h.Write(raw) // nolint:errcheck
+	if r.TerraformResource != nil {
+		hashSchemaMap(h, r.TerraformResource.Schema)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashSchemaMap(h hash.Hash, m map[string]*schema.Schema) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := m[k]
+		// NOTE(muvaf): The validation functions are opaque, so only the enum
+		// values derived from them are hashed.
+		fmt.Fprintf(h, "{%q:%d,%t,%t,%t,%t,%t,%d,%d,%q,%q,%#v,%q", k, s.Type, s.Optional, s.Required, s.Computed,
+			s.Sensitive, s.ForceNew, s.MinItems, s.MaxItems, s.Description, s.Deprecated, s.Default, tjtypes.EnumValues(s))
+		switch e := s.Elem.(type) {
+		case *schema.Schema:
+			hashSchemaMap(h, map[string]*schema.Schema{"": e})
+		case *schema.Resource:
+			hashSchemaMap(h, e.Schema)
+		}
+		fmt.Fprint(h, "}")
+	}
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	"github.com/crossplane/terrajet/pkg/config"
+)
+
+func TestResourceHash(t *testing.T) {
+	base := func(s *schema.Schema) *config.Resource {
+		return &config.Resource{
+			Name: "aws_instance",
+			Kind: "Instance",
+			TerraformResource: &schema.Resource{
+				Schema: map[string]*schema.Schema{"field": s},
+			},
+		}
+	}
+	cases := map[string]struct {
+		reason string
+		prev   *config.Resource
+		next   *config.Resource
+		want   bool
+	}{
+		"Unchanged": {
+			reason: "The hash should be the same for the same schema and configuration",
+			prev:   base(&schema.Schema{Type: schema.TypeString, Optional: true}),
+			next:   base(&schema.Schema{Type: schema.TypeString, Optional: true}),
+			want:   true,
+		},
+		"DefaultChanged": {
+			reason: "The hash should change if the default value of a field changes",
+			prev:   base(&schema.Schema{Type: schema.TypeString, Optional: true, Default: "a"}),
+			next:   base(&schema.Schema{Type: schema.TypeString, Optional: true, Default: "b"}),
+		},
+		"EnumChanged": {
+			reason: "The hash should change if the enum values derived from the validation of a field change",
+			prev:   base(&schema.Schema{Type: schema.TypeString, Optional: true, ValidateFunc: validation.StringInSlice([]string{"a"}, false)}),
+			next:   base(&schema.Schema{Type: schema.TypeString, Optional: true, ValidateFunc: validation.StringInSlice([]string{"a", "b"}, false)}),
+		},
+		"ConfigurationChanged": {
+			reason: "The hash should change if the configuration of the resource changes",
+			prev:   base(&schema.Schema{Type: schema.TypeString, Optional: true}),
+			next: func() *config.Resource {
+				r := base(&schema.Schema{Type: schema.TypeString, Optional: true})
+				r.UseAsync = true
+				return r
+			}(),
+		},
+		"NestedChanged": {
+			reason: "The hash should change if a field of a nested block changes",
+			prev: base(&schema.Schema{Type: schema.TypeList, Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+				"nested": {Type: schema.TypeString, Optional: true},
+			}}}),
+			next: base(&schema.Schema{Type: schema.TypeList, Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+				"nested": {Type: schema.TypeString, Required: true},
+			}}}),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			prev, err := resourceHash(tc.prev)
+			if err != nil {
+				t.Fatalf("\n%s\nresourceHash(...): %s", tc.reason, err)
+			}
+			next, err := resourceHash(tc.next)
+			if err != nil {
+				t.Fatalf("\n%s\nresourceHash(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, prev == next); diff != "" {
+				t.Errorf("\n%s\nresourceHash(...): -want equal hashes, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestLoadGenerationCache(t *testing.T) {
+	cached := `{"key":"key","resources":{"aws_instance":{"hash":"hash","parametersTypeName":"InstanceParameters","controllerPackagePath":"ec2/instance"}}}`
+	type want struct {
+		cache *generationCache
+		err   bool
+	}
+	cases := map[string]struct {
+		reason  string
+		content string
+		want
+	}{
+		"NoFile": {
+			reason: "An empty cache with the given key should be returned if the file does not exist",
+			want: want{
+				cache: &generationCache{Key: "key", Resources: map[string]cachedResource{}},
+			},
+		},
+		"Loaded": {
+			reason:  "The cached resources should be returned if the key matches",
+			content: cached,
+			want: want{
+				cache: &generationCache{Key: "key", Resources: map[string]cachedResource{
+					"aws_instance": {Hash: "hash", ParametersTypeName: "InstanceParameters", ControllerPackagePath: "ec2/instance"},
+				}},
+			},
+		},
+		"KeyMismatch": {
+			reason:  "An empty cache should be returned if the key of the cache does not match",
+			content: `{"key":"another","resources":{"aws_instance":{"hash":"hash"}}}`,
+			want: want{
+				cache: &generationCache{Key: "key", Resources: map[string]cachedResource{}},
+			},
+		},
+		"Malformed": {
+			reason:  "An error should be returned if the cache cannot be unmarshaled",
+			content: "{",
+			want: want{
+				err: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.json")
+			if tc.content != "" {
+				if err := os.WriteFile(path, []byte(tc.content), 0600); err != nil {
+					t.Fatalf("cannot write cache: %s", err)
+				}
+			}
+			got, err := loadGenerationCache(path, "key")
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Fatalf("\n%s\nloadGenerationCache(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.cache, got); diff != "" {
+				t.Errorf("\n%s\nloadGenerationCache(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+type kindNaming struct {
+	config.DefaultKindNaming
+}
+
+func TestCacheKey(t *testing.T) {
+	header := filepath.Join(t.TempDir(), "header.txt")
+	if err := os.WriteFile(header, []byte("header"), 0600); err != nil {
+		t.Fatalf("cannot write license header: %s", err)
+	}
+	cases := map[string]struct {
+		reason    string
+		prev      *config.Provider
+		next      *config.Provider
+		cacheable bool
+		want      bool
+	}{
+		"Unchanged": {
+			reason:    "The key should be the same for the same kind naming strategy",
+			prev:      &config.Provider{KindNaming: config.DefaultKindNaming{Plurals: map[string]string{"index": "indices", "mouse": "mice"}}},
+			next:      &config.Provider{KindNaming: config.DefaultKindNaming{Plurals: map[string]string{"mouse": "mice", "index": "indices"}}},
+			cacheable: true,
+			want:      true,
+		},
+		"KindNamingChanged": {
+			reason:    "The key should change if the kind naming strategy changes",
+			prev:      &config.Provider{KindNaming: config.DefaultKindNaming{Plurals: map[string]string{"index": "indices"}}},
+			next:      &config.Provider{KindNaming: config.DefaultKindNaming{Plurals: map[string]string{"index": "indexes"}}},
+			cacheable: true,
+		},
+		"KindNamingSet": {
+			reason:    "The key should change if the kind naming strategy is set",
+			prev:      &config.Provider{},
+			next:      &config.Provider{KindNaming: &config.DefaultKindNaming{}},
+			cacheable: true,
+		},
+		"CustomKindNaming": {
+			reason: "The cache should not be used with a custom kind naming strategy",
+			prev:   &config.Provider{KindNaming: kindNaming{}},
+			next:   &config.Provider{KindNaming: kindNaming{}},
+			want:   true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			prev, err := cacheKey(tc.prev, &templateSet{}, header)
+			if err != nil {
+				t.Fatalf("\n%s\ncacheKey(...): %s", tc.reason, err)
+			}
+			next, err := cacheKey(tc.next, &templateSet{}, header)
+			if err != nil {
+				t.Fatalf("\n%s\ncacheKey(...): %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, prev == next); diff != "" {
+				t.Errorf("\n%s\ncacheKey(...): -want equal keys, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.cacheable, cacheable(tc.next)); diff != "" {
+				t.Errorf("\n%s\ncacheable(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGenerationCacheLookup(t *testing.T) {
+	type args struct {
+		hash    string
+		content *string
+	}
+	generated := "package v1alpha1\n"
+	edited := "package v1alpha1\n\n// edited\n"
+	cases := map[string]struct {
+		reason string
+		args
+		want bool
+	}{
+		"Unchanged": {
+			reason: "The cached resource should be used if its hash and its generated files are unchanged",
+			args:   args{hash: "hash", content: &generated},
+			want:   true,
+		},
+		"HashChanged": {
+			reason: "The cached resource should not be used if its hash changed",
+			args:   args{hash: "another", content: &generated},
+		},
+		"FileChanged": {
+			reason: "The cached resource should not be used if one of its generated files changed",
+			args:   args{hash: "hash", content: &edited},
+		},
+		"FileRemoved": {
+			reason: "The cached resource should not be used if one of its generated files is removed",
+			args:   args{hash: "hash"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "zz_instance_types.go")
+			if err := os.WriteFile(file, []byte(generated), 0600); err != nil {
+				t.Fatalf("cannot write generated file: %s", err)
+			}
+			c := &generationCache{Key: "key", Resources: map[string]cachedResource{
+				"aws_instance": {Hash: "hash", Files: map[string]string{file: ""}},
+			}}
+			path := filepath.Join(dir, "cache.json")
+			if err := c.write(path); err != nil {
+				t.Fatalf("cannot write cache: %s", err)
+			}
+			c, err := loadGenerationCache(path, "key")
+			if err != nil {
+				t.Fatalf("cannot load cache: %s", err)
+			}
+			if tc.args.content == nil {
+				if err := os.Remove(file); err != nil {
+					t.Fatalf("cannot remove generated file: %s", err)
+				}
+			} else if err := os.WriteFile(file, []byte(*tc.args.content), 0600); err != nil {
+				t.Fatalf("cannot write generated file: %s", err)
+			}
+			_, got := c.lookup("aws_instance", tc.args.hash)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nlookup(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
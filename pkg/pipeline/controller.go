@@ -19,13 +19,16 @@ package pipeline
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/muvaf/typewriter/pkg/wrapper"
 	"github.com/pkg/errors"
 
 	"github.com/crossplane/terrajet/pkg/config"
 	"github.com/crossplane/terrajet/pkg/pipeline/templates"
+	"github.com/crossplane/terrajet/pkg/types/name"
 )
 
 // NewControllerGenerator returns a new ControllerGenerator.
@@ -35,6 +38,7 @@ func NewControllerGenerator(rootDir, modulePath, group string) *ControllerGenera
 		ControllerGroupDir: filepath.Join(rootDir, "internal", "controller", strings.Split(group, ".")[0]),
 		ModulePath:         modulePath,
 		LicenseHeaderPath:  filepath.Join(rootDir, "hack", "boilerplate.go.txt"),
+		Template:           templates.ControllerTemplate,
 	}
 }
 
@@ -44,12 +48,18 @@ type ControllerGenerator struct {
 	ControllerGroupDir string
 	ModulePath         string
 	LicenseHeaderPath  string
+	// Template is the template the controller setup functions are generated
+	// from.
+	Template string
+	// KindNaming is the strategy the plurals of the kinds are derived with if
+	// set. Defaults to config.DefaultKindNaming.
+	KindNaming config.KindNamingStrategy
 }
 
 // Generate writes controller setup functions.
 func (cg *ControllerGenerator) Generate(cfg *config.Resource, typesPkgPath string) (pkgPath string, err error) {
 	controllerPkgPath := filepath.Join(cg.ModulePath, "internal", "controller", strings.ToLower(strings.Split(cg.Group, ".")[0]), strings.ToLower(cfg.Kind))
-	ctrlFile := wrapper.NewFile(controllerPkgPath, strings.ToLower(cfg.Kind), templates.ControllerTemplate,
+	ctrlFile := wrapper.NewFile(controllerPkgPath, strings.ToLower(cfg.Kind), cg.Template,
 		wrapper.WithGenStatement(GenStatement),
 		wrapper.WithHeaderPath(cg.LicenseHeaderPath),
 	)
@@ -57,13 +67,16 @@ func (cg *ControllerGenerator) Generate(cfg *config.Resource, typesPkgPath strin
 	vars := map[string]interface{}{
 		"Package": strings.ToLower(cfg.Kind),
 		"CRD": map[string]string{
-			"Kind": cfg.Kind,
+			"Kind":   cfg.Kind,
+			"Group":  cg.Group,
+			"Plural": plural(cg.KindNaming, cfg.Kind),
 		},
 		"DisableNameInitializer": cfg.ExternalName.DisableNameInitializer,
+		"NamingStrategy":         cfg.ExternalName.NamingStrategy != nil,
 		"TypePackageAlias":       ctrlFile.Imports.UsePackage(typesPkgPath),
-		"UseAsync":               cfg.UseAsync,
 		"ResourceType":           cfg.Name,
 		"Initializers":           cfg.InitializerFns,
+		"Dependencies":           dependencies(cfg, typesPkgPath, ctrlFile.Imports.UsePackage),
 	}
 
 	filePath := filepath.Join(cg.ControllerGroupDir, strings.ToLower(cfg.Kind), "zz_controller.go")
@@ -72,3 +85,77 @@ func (cg *ControllerGenerator) Generate(cfg *config.Resource, typesPkgPath strin
 		"cannot write controller file",
 	)
 }
+
+// dependency is a kind referenced by the generated resource together with
+// the JSON paths of the reference fields under spec.forProvider that refer to
+// it.
+type dependency struct {
+	TypePackageAlias string
+	Kind             string
+	RefPaths         []string
+}
+
+// dependencies returns the kinds referenced by the given resource so that the
+// generated controller can watch them and enqueue the referencing resources
+// when the referenced ones become ready.
+func dependencies(cfg *config.Resource, typesPkgPath string, usePackage func(string) string) []dependency {
+	byType := map[string]*dependency{}
+	for field, ref := range cfg.References {
+		pkgPath, kind := typesPkgPath, ref.Type
+		if i := strings.LastIndex(ref.Type, "."); i != -1 {
+			pkgPath, kind = ref.Type[:i], ref.Type[i+1:]
+		}
+		d, ok := byType[ref.Type]
+		if !ok {
+			d = &dependency{TypePackageAlias: usePackage(pkgPath), Kind: kind}
+			byType[ref.Type] = d
+		}
+		d.RefPaths = append(d.RefPaths, refJSONPath(cfg.TerraformResource.Schema, field, ref))
+	}
+	result := make([]dependency, 0, len(byType))
+	for _, d := range byType {
+		sort.Strings(d.RefPaths)
+		result = append(result, *d)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TypePackageAlias != result[j].TypePackageAlias {
+			return result[i].TypePackageAlias < result[j].TypePackageAlias
+		}
+		return result[i].Kind < result[j].Kind
+	})
+	return result
+}
+
+// refJSONPath returns the dot-separated JSON path of the reference field
+// generated for the given Terraform field path the same way the types
+// builder names it.
+func refJSONPath(s map[string]*schema.Schema, field string, ref config.Reference) string {
+	parts := strings.Split(field, ".")
+	path := make([]string, 0, len(parts))
+	for _, p := range parts[:len(parts)-1] {
+		path = append(path, name.NewFromSnake(p).LowerCamelComputed)
+		if sch, ok := s[p]; ok {
+			if r, ok := sch.Elem.(*schema.Resource); ok {
+				s = r.Schema
+			}
+		}
+	}
+	last := parts[len(parts)-1]
+	rfn := ref.RefFieldName
+	if rfn == "" {
+		rfn = name.NewFromSnake(last).Camel + "Ref"
+		if sch, ok := s[last]; ok && (sch.Type == schema.TypeList || sch.Type == schema.TypeSet) {
+			rfn += "s"
+		}
+	}
+	return strings.Join(append(path, name.NewFromCamel(rfn).LowerCamelComputed), ".")
+}
+
+// plural returns the plural of the given kind in lower case with the given
+// strategy, falling back to config.DefaultKindNaming.
+func plural(n config.KindNamingStrategy, kind string) string {
+	if n == nil {
+		n = config.DefaultKindNaming{}
+	}
+	return n.Plural(kind)
+}
@@ -0,0 +1,158 @@
+/*
+ Copyright 2021 The Crossplane Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/config"
+)
+
+// referenceCandidateSuffixes are the suffixes of the names of the arguments
+// that usually refer to other resources.
+var referenceCandidateSuffixes = []string{"_id", "_ids", "_arn", "_arns"}
+
+// coverageReport is the machine-readable report of a generation run that
+// shows how much of the Terraform provider is covered by the generated
+// provider.
+type coverageReport struct {
+	Summary   coverageSummary    `json:"summary"`
+	Generated []resourceCoverage `json:"generated"`
+	Skipped   map[string]string  `json:"skipped"`
+}
+
+type coverageSummary struct {
+	Total     int `json:"total"`
+	Generated int `json:"generated"`
+	Skipped   int `json:"skipped"`
+	// References is the number of configured references.
+	References int `json:"references"`
+	// ReferenceCandidates is the number of arguments that look like they
+	// refer to other resources but do not have a reference configured.
+	ReferenceCandidates int `json:"referenceCandidates"`
+}
+
+type resourceCoverage struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	// DroppedFields are the top level arguments that are removed from the
+	// generated API.
+	DroppedFields []string `json:"droppedFields,omitempty"`
+	// References are the field paths of the configured references.
+	References []string `json:"references,omitempty"`
+	// ReferenceCandidates are the field paths of the arguments that look
+	// like they refer to other resources but do not have a reference
+	// configured.
+	ReferenceCandidates []string `json:"referenceCandidates,omitempty"`
+}
+
+// newCoverageReport returns the coverage report of the given provider. It
+// needs to be called before the code generation since the generation removes
+// the dropped fields from the schema.
+func newCoverageReport(pc *config.Provider) *coverageReport {
+	cr := &coverageReport{
+		Skipped: map[string]string{},
+	}
+	for name, reason := range pc.SkippedResources {
+		cr.Skipped[name] = reason
+	}
+	for _, name := range sortedResources(pc.Resources) {
+		r := pc.Resources[name]
+		rc := resourceCoverage{
+			Name:    name,
+			Kind:    r.Kind,
+			Group:   resourceGroup(pc, r),
+			Version: r.Version,
+		}
+		if r.TerraformResource != nil {
+			for _, f := range r.ExternalName.OmittedFields {
+				if _, ok := r.TerraformResource.Schema[f]; ok {
+					rc.DroppedFields = append(rc.DroppedFields, f)
+				}
+			}
+			rc.ReferenceCandidates = referenceCandidates("", r.TerraformResource.Schema, r.References)
+		}
+		for f := range r.References {
+			rc.References = append(rc.References, f)
+		}
+		sort.Strings(rc.DroppedFields)
+		sort.Strings(rc.References)
+		cr.Summary.References += len(rc.References)
+		cr.Summary.ReferenceCandidates += len(rc.ReferenceCandidates)
+		cr.Generated = append(cr.Generated, rc)
+	}
+	cr.Summary.Generated = len(cr.Generated)
+	cr.Summary.Skipped = len(cr.Skipped)
+	cr.Summary.Total = cr.Summary.Generated + cr.Summary.Skipped
+	return cr
+}
+
+// referenceCandidates returns the paths of the arguments in the given schema
+// that look like references to other resources and are not configured as
+// references.
+func referenceCandidates(prefix string, m map[string]*schema.Schema, refs config.References) []string {
+	var result []string
+	for name, s := range m {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		if e, ok := s.Elem.(*schema.Resource); ok {
+			result = append(result, referenceCandidates(path, e.Schema, refs)...)
+			continue
+		}
+		if s.Computed && !s.Optional {
+			continue
+		}
+		if _, ok := refs[path]; ok {
+			continue
+		}
+		for _, suffix := range referenceCandidateSuffixes {
+			if strings.HasSuffix(name, suffix) {
+				result = append(result, path)
+				break
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// write writes the report to the given path in JSON format.
+func (cr *coverageReport) write(path string) error {
+	raw, err := json.MarshalIndent(cr, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal coverage report")
+	}
+	return errors.Wrap(os.WriteFile(path, append(raw, '\n'), 0600), "cannot write coverage report")
+}
+
+// resourceGroup returns the API group of the given resource.
+func resourceGroup(pc *config.Provider, r *config.Resource) string {
+	if r.ShortGroup == "" {
+		return pc.RootGroup
+	}
+	return strings.ToLower(r.ShortGroup) + "." + pc.RootGroup
+}
@@ -29,8 +29,10 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/meta"
 	"github.com/crossplane/terrajet/pkg/pipeline/templates"
 	tjtypes "github.com/crossplane/terrajet/pkg/types"
+	tjname "github.com/crossplane/terrajet/pkg/types/name"
 )
 
 // GenStatement is printed on every generated file.
@@ -41,6 +43,7 @@ func NewCRDGenerator(pkg *types.Package, rootDir, providerShortName, group, vers
 	return &CRDGenerator{
 		LocalDirectoryPath: filepath.Join(rootDir, "apis", strings.ToLower(strings.Split(group, ".")[0]), version),
 		LicenseHeaderPath:  filepath.Join(rootDir, "hack", "boilerplate.go.txt"),
+		Template:           templates.CRDTypesTemplate,
 		Group:              group,
 		ProviderShortName:  providerShortName,
 		pkg:                pkg,
@@ -54,13 +57,24 @@ type CRDGenerator struct {
 	Group              string
 	ProviderShortName  string
 	LicenseHeaderPath  string
+	// TerraformProviderVersion is the version of the Terraform provider that
+	// is recorded on the generated CRDs if set.
+	TerraformProviderVersion string
+	// Template is the template the types file is generated from.
+	Template string
+	// KindNaming is the strategy the resource names of the CRDs are derived
+	// with. Defaults to config.DefaultKindNaming.
+	KindNaming config.KindNamingStrategy
+	// TypeHooks are called for every type generated for a resource before
+	// the types are printed.
+	TypeHooks []config.TypeHook
 
 	pkg *types.Package
 }
 
 // Generate builds and writes a new CRD out of Terraform resource definition.
 func (cg *CRDGenerator) Generate(cfg *config.Resource) (string, error) {
-	file := wrapper.NewFile(cg.pkg.Path(), cg.pkg.Name(), templates.CRDTypesTemplate,
+	file := wrapper.NewFile(cg.pkg.Path(), cg.pkg.Name(), cg.Template,
 		wrapper.WithGenStatement(GenStatement),
 		wrapper.WithHeaderPath(cg.LicenseHeaderPath),
 	)
@@ -77,6 +91,10 @@ func (cg *CRDGenerator) Generate(cfg *config.Resource) (string, error) {
 	if err != nil {
 		return "", errors.Wrapf(err, "cannot build types for %s", cfg.Kind)
 	}
+	extra, err := runTypeHooks(cg.TypeHooks, gen, cfg.TerraformResource)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot run type hooks for %s", cfg.Kind)
+	}
 	// TODO(muvaf): TypePrinter uses the given scope to see if the type exists
 	// before printing. We should ideally load the package in file system but
 	// loading the local package will result in error if there is
@@ -88,20 +106,101 @@ func (cg *CRDGenerator) Generate(cfg *config.Resource) (string, error) {
 	if err != nil {
 		return "", errors.Wrap(err, "cannot print the type list")
 	}
+	// NOTE(muvaf): The plural is always given to the CRD generator so that
+	// the resource name of the CRD is the one the RBAC markers of the
+	// controller are generated with.
+	p := plural(cg.KindNaming, cfg.Kind)
 	vars := map[string]interface{}{
-		"Types": typesStr,
+		"Types": typesStr + extra,
 		"CRD": map[string]string{
 			"APIVersion":      cfg.Version,
 			"Group":           cg.Group,
 			"Kind":            cfg.Kind,
 			"ForProviderType": gen.ForProviderType.Obj().Name(),
 			"AtProviderType":  gen.AtProviderType.Obj().Name(),
+			"TerraformType":   cfg.Name,
+			"Description":     strings.ReplaceAll(cfg.Documentation.Description, "\n", " "),
+			"Plural":          p,
 		},
+		"Examples":        cfg.Documentation.Examples,
+		"ExampleComments": exampleComments(cfg.Documentation.Examples),
 		"Provider": map[string]string{
-			"ShortName": cg.ProviderShortName,
+			"ShortName":        cg.ProviderShortName,
+			"TerraformVersion": cg.TerraformProviderVersion,
 		},
+		"Annotations": map[string]string{
+			"ResourceType":    meta.AnnotationKeyTerraformResourceType,
+			"ProviderVersion": meta.AnnotationKeyTerraformProviderVersion,
+		},
+		"PrinterColumns":           printerColumns(cfg.PrinterColumns),
 		"XPCommonAPIsPackageAlias": file.Imports.UsePackage(tjtypes.PackagePathXPCommonAPIs),
 	}
 	filePath := filepath.Join(cg.LocalDirectoryPath, fmt.Sprintf("zz_%s_types.go", strings.ToLower(cfg.Kind)))
 	return gen.ForProviderType.Obj().Name(), errors.Wrap(file.Write(filePath, vars, os.ModePerm), "cannot write crd file")
 }
+
+func printerColumns(cols []config.PrinterColumn) []map[string]string {
+	result := make([]map[string]string, len(cols))
+	for i, c := range cols {
+		t := c.Type
+		if t == "" {
+			t = "string"
+		}
+		parts := strings.Split(c.FieldPath, ".")
+		for j, p := range parts {
+			parts[j] = tjname.NewFromSnake(p).LowerCamelComputed
+		}
+		result[i] = map[string]string{
+			"Name":     c.Name,
+			"Type":     t,
+			"JSONPath": ".spec.forProvider." + strings.Join(parts, "."),
+		}
+	}
+	return result
+}
+
+// runTypeHooks calls the given hooks for every generated type, adds the
+// markers they return to the comments of the types and returns the extra
+// source code they produce.
+// exampleComments returns the given HCL examples as a Go comment block in
+// which the examples are separated by an empty comment line.
+func exampleComments(examples []string) string {
+	blocks := make([]string, 0, len(examples))
+	for _, e := range examples {
+		lines := strings.Split(strings.TrimRight(e, "\n"), "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("//\t"+l, " \t")
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	return strings.Join(blocks, "\n//\n")
+}
+
+func runTypeHooks(hooks []config.TypeHook, gen tjtypes.Generated, r *schema.Resource) (string, error) {
+	if len(hooks) == 0 {
+		return "", nil
+	}
+	extra := ""
+	for _, n := range gen.Types {
+		t := &config.GeneratedType{Named: n}
+		for _, h := range hooks {
+			if err := h(t, r); err != nil {
+				return "", errors.Wrapf(err, "type hook failed for %s", n.Obj().Name())
+			}
+		}
+		if len(t.Markers) != 0 {
+			lines := make([]string, 0, len(t.Markers)+1)
+			if c := gen.Comments[twtypes.QualifiedTypePath(n.Obj())]; c != "" {
+				lines = append(lines, strings.TrimSuffix(c, "\n"))
+			}
+			for _, m := range t.Markers {
+				lines = append(lines, "// "+m)
+			}
+			gen.Comments.AddTypeComment(n.Obj(), strings.Join(lines, "\n"))
+		}
+		if t.Extra != "" {
+			extra += "\n" + t.Extra + "\n"
+		}
+	}
+	return extra, nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExampleComments(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		examples []string
+		want     string
+	}{
+		"None": {
+			reason: "No comment should be produced if there are no examples",
+		},
+		"Multiple": {
+			reason: "Every line of the examples should be commented and the examples should be separated by an empty comment line",
+			examples: []string{
+				"resource \"aws_vpc\" \"main\" {\n  cidr_block = \"10.0.0.0/16\"\n\n}\n",
+				"resource \"aws_vpc\" \"other\" {}",
+			},
+			want: "//\tresource \"aws_vpc\" \"main\" {\n//\t  cidr_block = \"10.0.0.0/16\"\n//\n//\t}\n//\n//\tresource \"aws_vpc\" \"other\" {}",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, exampleComments(tc.examples)); diff != "" {
+				t.Errorf("\n%s\nexampleComments(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
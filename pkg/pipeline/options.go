@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/docs"
+	"github.com/crossplane/terrajet/pkg/pipeline/templates"
+)
+
+// RunOption configures the code generation pipeline.
+type RunOption func(*runOptions)
+
+// WithTemplateDir configures a directory whose templates override the
+// built-in ones. A template is overridden only if the directory contains a
+// file with the same name as the built-in template, e.g. crd_types.go.tmpl or
+// controller.go.tmpl, so that providers can replace only the templates they
+// need to customize.
+func WithTemplateDir(dir string) RunOption {
+	return func(o *runOptions) {
+		o.templateDir = dir
+	}
+}
+
+// WithLicenseHeaderPath configures the path of the license header printed on
+// every generated file. Defaults to hack/boilerplate.go.txt under the root
+// directory of the provider.
+func WithLicenseHeaderPath(path string) RunOption {
+	return func(o *runOptions) {
+		o.licenseHeaderPath = path
+	}
+}
+
+// WithConfigFile configures a YAML or JSON provider configuration file that
+// is applied on top of the configuration of the provider before the code
+// generation. See config.FileConfig for its format.
+func WithConfigFile(path string) RunOption {
+	return func(o *runOptions) {
+		o.configFile = path
+	}
+}
+
+// WithGenerationCache enables incremental generation using the cache file in
+// the given path. The hashes of the schema and the configuration of every
+// generated resource are stored in the cache together with the hashes of
+// the files generated for it, and the resources whose hashes and files did
+// not change since the previous run are not generated again. Changes to
+// the Go code of the generator, such as type hooks or a new terrajet
+// version, are not detected; the cache file should be deleted in that case.
+// The cache is not used if the provider has a custom KindNamingStrategy since
+// its changes cannot be detected either.
+func WithGenerationCache(path string) RunOption {
+	return func(o *runOptions) {
+		o.cachePath = path
+	}
+}
+
+// WithParallelism configures the number of API group versions whose resources
+// are generated concurrently. Defaults to the number of CPUs. Type hooks of the
+// provider are called concurrently for resources of different group versions
+// unless the parallelism is set to 1.
+func WithParallelism(n int) RunOption {
+	return func(o *runOptions) {
+		o.parallelism = n
+	}
+}
+
+// WithIncludeList configures a list of regular expressions matching the names
+// of the Terraform resources to be generated. The resources of the provider
+// configuration that do not match any of them are not generated. This allows
+// generating a curated subset of the resources without changing the provider
+// configuration.
+func WithIncludeList(l []string) RunOption {
+	return func(o *runOptions) {
+		o.includeList = l
+	}
+}
+
+// WithSkipList configures a list of regular expressions matching the names of
+// the Terraform resources that are not generated.
+func WithSkipList(l []string) RunOption {
+	return func(o *runOptions) {
+		o.skipList = l
+	}
+}
+
+// WithDocsSource configures a source of the documentation of the Terraform
+// resources. The descriptions of the fields missing in the Terraform schema,
+// the description of the resources and their usage examples are populated
+// from the documentation before the code generation.
+func WithDocsSource(s docs.Source) RunOption {
+	return func(o *runOptions) {
+		o.docsSource = s
+	}
+}
+
+// WithCoverageReport configures the path of a JSON report written after the
+// generation that lists the generated and skipped resources, the fields
+// dropped from the generated APIs and the configured references together with
+// the arguments that look like references but are not configured as such.
+func WithCoverageReport(path string) RunOption {
+	return func(o *runOptions) {
+		o.coverageReportPath = path
+	}
+}
+
+type runOptions struct {
+	templateDir        string
+	licenseHeaderPath  string
+	configFile         string
+	cachePath          string
+	parallelism        int
+	includeList        []string
+	skipList           []string
+	docsSource         docs.Source
+	coverageReportPath string
+}
+
+// templateSet is the set of templates used by the generators.
+type templateSet struct {
+	crdTypes         string
+	groupVersionInfo string
+	doc              string
+	terraformed      string
+	terraformedFuzz  string
+	controller       string
+	register         string
+	groupRegister    string
+	setup            string
+}
+
+// loadTemplates returns the built-in templates overridden with the ones found
+// in the given directory. The built-in templates are returned if dir is empty.
+func loadTemplates(dir string) (*templateSet, error) {
+	ts := &templateSet{
+		crdTypes:         templates.CRDTypesTemplate,
+		groupVersionInfo: templates.GroupVersionInfoTemplate,
+		doc:              templates.DocTemplate,
+		terraformed:      templates.TerraformedTemplate,
+		terraformedFuzz:  templates.TerraformedFuzzTemplate,
+		controller:       templates.ControllerTemplate,
+		register:         templates.RegisterTemplate,
+		groupRegister:    templates.GroupRegisterTemplate,
+		setup:            templates.SetupTemplate,
+	}
+	if dir == "" {
+		return ts, nil
+	}
+	files := map[string]*string{
+		"crd_types.go.tmpl":             &ts.crdTypes,
+		"groupversion_info.go.tmpl":     &ts.groupVersionInfo,
+		"doc.go.tmpl":                   &ts.doc,
+		"terraformed.go.tmpl":           &ts.terraformed,
+		"terraformed_fuzz_test.go.tmpl": &ts.terraformedFuzz,
+		"controller.go.tmpl":            &ts.controller,
+		"register.go.tmpl":              &ts.register,
+		"group_register.go.tmpl":        &ts.groupRegister,
+		"setup.go.tmpl":                 &ts.setup,
+	}
+	for name, tmpl := range files {
+		raw, err := os.ReadFile(filepath.Clean(filepath.Join(dir, name)))
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return nil, errors.Wrapf(err, "cannot read template %s", name)
+		}
+		*tmpl = string(raw)
+	}
+	return ts, nil
+}
@@ -0,0 +1,193 @@
+/*
+ Copyright 2021 The Crossplane Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/config"
+)
+
+// versionJob is the generation of the resources of a single API group
+// version. The jobs are independent of each other, i.e. they write to
+// different files, so they can run concurrently.
+type versionJob struct {
+	group     string
+	version   string
+	resources map[string]*config.Resource
+}
+
+// versionResult is the outcome of a versionJob to be merged into the inputs
+// of the generators that run once per provider.
+type versionResult struct {
+	group          string
+	apiVersionPkg  string
+	controllerPkgs []string
+	cached         map[string]cachedResource
+	generated      int
+	skipped        int
+}
+
+// versionJobs returns a job for every group version in the given tree sorted
+// by group and version.
+func versionJobs(groups map[string]map[string]map[string]*config.Resource) []versionJob {
+	var jobs []versionJob
+	for group, versions := range groups {
+		for version, resources := range versions {
+			jobs = append(jobs, versionJob{group: group, version: version, resources: resources})
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].group != jobs[j].group {
+			return jobs[i].group < jobs[j].group
+		}
+		return jobs[i].version < jobs[j].version
+	})
+	return jobs
+}
+
+// runVersionJobs runs the given jobs using the given number of workers and
+// returns their results in the order of the jobs. If any of the jobs fails,
+// the error of the first failed job in that order is returned.
+func runVersionJobs(jobs []versionJob, parallelism int, fn func(versionJob) (*versionResult, error)) ([]*versionResult, error) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	results := make([]*versionResult, len(jobs))
+	errs := make([]error, len(jobs))
+	queue := make(chan int)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i], errs[i] = fn(jobs[i])
+			}
+		}()
+	}
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// versionGenerator generates the types, terraformed and controller files of
+// the resources of a group version.
+type versionGenerator struct {
+	rootDir string
+	pc      *config.Provider
+	opts    *runOptions
+	tmpls   *templateSet
+	// cache is only read by the jobs. The resources generated by a job are
+	// returned in its result to be stored in the cache.
+	cache *generationCache
+}
+
+func (vg *versionGenerator) generate(job versionJob) (*versionResult, error) {
+	res := &versionResult{group: job.group, cached: map[string]cachedResource{}}
+	var tfResources []*terraformedInput
+	versionGen := NewVersionGenerator(vg.rootDir, vg.pc.ModulePath, job.group, job.version)
+	versionGen.LicenseHeaderPath = vg.opts.licenseHeaderPath
+	versionGen.Template = vg.tmpls.groupVersionInfo
+	versionGen.DocTemplate = vg.tmpls.doc
+	versionGen.TerraformProviderVersion = vg.pc.TerraformProviderVersion
+	crdGen := NewCRDGenerator(versionGen.Package(), vg.rootDir, vg.pc.ShortName, job.group, job.version)
+	crdGen.LicenseHeaderPath = vg.opts.licenseHeaderPath
+	crdGen.Template = vg.tmpls.crdTypes
+	crdGen.TypeHooks = vg.pc.TypeHooks
+	crdGen.TerraformProviderVersion = vg.pc.TerraformProviderVersion
+	crdGen.KindNaming = vg.pc.KindNaming
+	tfGen := NewTerraformedGenerator(versionGen.Package(), vg.rootDir, job.group, job.version)
+	tfGen.LicenseHeaderPath = vg.opts.licenseHeaderPath
+	tfGen.Template = vg.tmpls.terraformed
+	tfGen.FuzzTemplate = vg.tmpls.terraformedFuzz
+	ctrlGen := NewControllerGenerator(vg.rootDir, vg.pc.ModulePath, job.group)
+	ctrlGen.LicenseHeaderPath = vg.opts.licenseHeaderPath
+	ctrlGen.Template = vg.tmpls.controller
+	ctrlGen.KindNaming = vg.pc.KindNaming
+
+	for _, name := range sortedResources(job.resources) {
+		r := job.resources[name]
+		var hash string
+		if vg.cache != nil {
+			var err error
+			if hash, err = resourceHash(r); err != nil {
+				return nil, errors.Wrapf(err, "cannot compute hash of resource %s", name)
+			}
+			if cr, ok := vg.cache.lookup(name, hash); ok {
+				cr.restore(r)
+				tfResources = append(tfResources, &terraformedInput{
+					Resource:           r,
+					ParametersTypeName: cr.ParametersTypeName,
+				})
+				res.controllerPkgs = append(res.controllerPkgs, cr.ControllerPackagePath)
+				res.skipped++
+				continue
+			}
+		}
+		paramTypeName, err := crdGen.Generate(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot generate crd for resource %s", name)
+		}
+		tfResources = append(tfResources, &terraformedInput{
+			Resource:           r,
+			ParametersTypeName: paramTypeName,
+		})
+		ctrlPkgPath, err := ctrlGen.Generate(r, versionGen.Package().Path())
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot generate controller for resource %s", name)
+		}
+		res.controllerPkgs = append(res.controllerPkgs, ctrlPkgPath)
+		if vg.cache != nil {
+			res.cached[name] = cachedResource{
+				Hash:                  hash,
+				ParametersTypeName:    paramTypeName,
+				ControllerPackagePath: ctrlPkgPath,
+				SensitiveFieldPaths:   r.Sensitive.GetFieldPaths(),
+				LateInitIgnoredFields: r.LateInitializer.GetIgnoredCanonicalFields(),
+				Files: map[string]string{
+					filepath.Join(crdGen.LocalDirectoryPath, fmt.Sprintf("zz_%s_types.go", strings.ToLower(r.Kind))): "",
+					filepath.Join(ctrlGen.ControllerGroupDir, strings.ToLower(r.Kind), "zz_controller.go"):           "",
+				},
+			}
+		}
+		res.generated++
+	}
+
+	if err := tfGen.Generate(tfResources, job.version); err != nil {
+		return nil, errors.Wrapf(err, "cannot generate terraformed for resource %s", job.group)
+	}
+	if err := versionGen.Generate(); err != nil {
+		return nil, errors.Wrap(err, "cannot generate version files")
+	}
+	res.apiVersionPkg = versionGen.Package().Path()
+	return res, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+func TestRunVersionJobs(t *testing.T) {
+	errBoom := errors.New("boom")
+	jobs := []versionJob{{group: "a", version: "v1"}, {group: "b", version: "v1"}, {group: "c", version: "v1"}}
+	type want struct {
+		groups []string
+		err    error
+	}
+	cases := map[string]struct {
+		reason      string
+		parallelism int
+		fn          func(versionJob) (*versionResult, error)
+		want
+	}{
+		"Ordered": {
+			reason:      "The results should be returned in the order of the jobs regardless of the parallelism",
+			parallelism: 3,
+			fn: func(j versionJob) (*versionResult, error) {
+				return &versionResult{group: j.group}, nil
+			},
+			want: want{
+				groups: []string{"a", "b", "c"},
+			},
+		},
+		"NoParallelism": {
+			reason:      "The jobs should be run by a single worker if the parallelism is not positive",
+			parallelism: 0,
+			fn: func(j versionJob) (*versionResult, error) {
+				return &versionResult{group: j.group}, nil
+			},
+			want: want{
+				groups: []string{"a", "b", "c"},
+			},
+		},
+		"FirstError": {
+			reason:      "The error of the first failed job in the order of the jobs should be returned",
+			parallelism: 2,
+			fn: func(j versionJob) (*versionResult, error) {
+				if j.group == "a" {
+					return &versionResult{group: j.group}, nil
+				}
+				return nil, errors.Wrap(errBoom, j.group)
+			},
+			want: want{
+				err: errors.Wrap(errBoom, "b"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			results, err := runVersionJobs(jobs, tc.parallelism, tc.fn)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nrunVersionJobs(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			var groups []string
+			for _, r := range results {
+				groups = append(groups, r.group)
+			}
+			if diff := cmp.Diff(tc.want.groups, groups); diff != "" {
+				t.Errorf("\n%s\nrunVersionJobs(...): -want groups, +got groups:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
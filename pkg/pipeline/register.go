@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/muvaf/typewriter/pkg/wrapper"
 	"github.com/pkg/errors"
@@ -32,6 +33,7 @@ func NewRegisterGenerator(rootDir, modulePath string) *RegisterGenerator {
 	return &RegisterGenerator{
 		LocalDirectoryPath: filepath.Join(rootDir, "apis"),
 		LicenseHeaderPath:  filepath.Join(rootDir, "hack", "boilerplate.go.txt"),
+		Template:           templates.RegisterTemplate,
 		ModulePath:         modulePath,
 	}
 }
@@ -41,23 +43,96 @@ type RegisterGenerator struct {
 	LocalDirectoryPath string
 	ModulePath         string
 	LicenseHeaderPath  string
+	// Template is the template the register file is generated from.
+	Template string
 }
 
 // Generate writes the register file with the content produced using given
-// list of version packages.
-func (rg *RegisterGenerator) Generate(versionPkgList []string) error {
-	registerFile := wrapper.NewFile(filepath.Join(rg.ModulePath, "apis"), "apis", templates.RegisterTemplate,
+// list of version packages and group packages. The group packages are the
+// ones generated by GroupRegisterGenerator that register all their versions.
+func (rg *RegisterGenerator) Generate(versionPkgList, groupPkgList []string) error {
+	registerFile := wrapper.NewFile(filepath.Join(rg.ModulePath, "apis"), "apis", rg.Template,
 		wrapper.WithGenStatement(GenStatement),
 		wrapper.WithHeaderPath(rg.LicenseHeaderPath),
 	)
-	sort.Strings(versionPkgList)
+	versionPkgList = uniquePackages(versionPkgList)
 	aliases := make([]string, len(versionPkgList))
 	for i, pkgPath := range versionPkgList {
 		aliases[i] = registerFile.Imports.UsePackage(pkgPath)
 	}
+	groupPkgList = uniquePackages(groupPkgList)
+	groupAliases := make([]string, len(groupPkgList))
+	for i, pkgPath := range groupPkgList {
+		groupAliases[i] = registerFile.Imports.UsePackage(pkgPath)
+	}
 	vars := map[string]interface{}{
-		"Aliases": aliases,
+		"Aliases":      aliases,
+		"GroupAliases": groupAliases,
 	}
 	filePath := filepath.Join(rg.LocalDirectoryPath, "zz_register.go")
 	return errors.Wrap(registerFile.Write(filePath, vars, os.ModePerm), "cannot write register file")
 }
+
+// NewGroupRegisterGenerator returns a new GroupRegisterGenerator.
+func NewGroupRegisterGenerator(rootDir, modulePath, group string) *GroupRegisterGenerator {
+	short := strings.ToLower(strings.Split(group, ".")[0])
+	return &GroupRegisterGenerator{
+		LocalDirectoryPath: filepath.Join(rootDir, "apis", short),
+		LicenseHeaderPath:  filepath.Join(rootDir, "hack", "boilerplate.go.txt"),
+		Template:           templates.GroupRegisterTemplate,
+		Group:              group,
+		pkgPath:            filepath.Join(modulePath, "apis", short),
+		pkgName:            short,
+	}
+}
+
+// GroupRegisterGenerator generates the scheme registration file of an API
+// group that registers all versions of the group.
+type GroupRegisterGenerator struct {
+	LocalDirectoryPath string
+	LicenseHeaderPath  string
+	Group              string
+	// Template is the template the register file is generated from.
+	Template string
+
+	pkgPath string
+	pkgName string
+}
+
+// Generate writes the register file of the group that registers the given
+// version packages of the group and returns the path of the group package.
+func (gg *GroupRegisterGenerator) Generate(versionPkgList []string) (string, error) {
+	file := wrapper.NewFile(gg.pkgPath, gg.pkgName, gg.Template,
+		wrapper.WithGenStatement(GenStatement),
+		wrapper.WithHeaderPath(gg.LicenseHeaderPath),
+	)
+	versionPkgList = uniquePackages(versionPkgList)
+	aliases := make([]string, len(versionPkgList))
+	for i, pkgPath := range versionPkgList {
+		aliases[i] = file.Imports.UsePackage(pkgPath)
+	}
+	vars := map[string]interface{}{
+		"Package": gg.pkgName,
+		"Group":   gg.Group,
+		"Aliases": aliases,
+	}
+	filePath := filepath.Join(gg.LocalDirectoryPath, "zz_register.go")
+	return gg.pkgPath, errors.Wrap(file.Write(filePath, vars, os.ModePerm), "cannot write group register file")
+}
+
+// uniquePackages returns the given package paths sorted and without
+// duplicates. Groups whose names differ only after the first label share the
+// same package, so the same version package may be listed more than once.
+func uniquePackages(pkgs []string) []string {
+	result := make([]string, 0, len(pkgs))
+	seen := make(map[string]bool, len(pkgs))
+	for _, p := range pkgs {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		result = append(result, p)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUniquePackages(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		pkgs   []string
+		want   []string
+	}{
+		"Empty": {
+			reason: "No package should be returned if none is given",
+			want:   []string{},
+		},
+		"Duplicates": {
+			reason: "A version package listed by more than one group sharing the same package should be returned only once",
+			pkgs: []string{
+				"github.com/example/provider/apis/ec2/v1alpha2",
+				"github.com/example/provider/apis/ec2/v1alpha1",
+				"github.com/example/provider/apis/ec2/v1alpha2",
+			},
+			want: []string{
+				"github.com/example/provider/apis/ec2/v1alpha1",
+				"github.com/example/provider/apis/ec2/v1alpha2",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, uniquePackages(tc.pkgs)); diff != "" {
+				t.Errorf("\n%s\nuniquePackages(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -17,37 +17,87 @@
 package pipeline
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"sort"
-	"strings"
 
 	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/docs"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 )
 
+const (
+	goimportsCmd = "goimports -w $(find . -iname 'zz_*')"
+	// goimportsNewerCmd runs goimports only on the files that are written
+	// after the generation stamp file is created.
+	goimportsNewerCmd = `files=$(find . -iname 'zz_*' -newer "$GENERATION_STAMP"); [ -z "$files" ] || goimports -w $files`
+)
+
 type terraformedInput struct {
 	*config.Resource
 	ParametersTypeName string
 }
 
 // Run runs the Terrajet code generation pipelines.
-func Run(pc *config.Provider, rootDir string) { // nolint:gocyclo
+func Run(pc *config.Provider, rootDir string, opts ...RunOption) { // nolint:gocyclo
 	// Note(turkenh): nolint reasoning - this is the main function of the code
 	// generation pipeline. We didn't want to split it into multiple functions
 	// for better readability considering the straightforward logic here.
 
+	o := &runOptions{
+		licenseHeaderPath: filepath.Join(rootDir, "hack", "boilerplate.go.txt"),
+		parallelism:       runtime.NumCPU(),
+	}
+	for _, f := range opts {
+		f(o)
+	}
+	tmpls, err := loadTemplates(o.templateDir)
+	if err != nil {
+		panic(errors.Wrap(err, "cannot load templates"))
+	}
+	if o.configFile != "" {
+		fc, err := config.LoadFileConfig(o.configFile)
+		if err != nil {
+			panic(errors.Wrap(err, "cannot load provider configuration file"))
+		}
+		if err := fc.Configure(pc); err != nil {
+			panic(errors.Wrap(err, "cannot apply provider configuration file"))
+		}
+	}
+
+	if err := pc.FilterResources(o.includeList, o.skipList); err != nil {
+		panic(errors.Wrap(err, "cannot filter resources"))
+	}
+
+	if o.docsSource != nil {
+		if err := applyDocs(context.Background(), o.docsSource, pc); err != nil {
+			panic(errors.Wrap(err, "cannot apply documentation"))
+		}
+	}
+
+	var coverage *coverageReport
+	if o.coverageReportPath != "" {
+		coverage = newCoverageReport(pc)
+	}
+
+	// Take a snapshot of the previously generated API types to report the
+	// breaking changes introduced by this run.
+	prevAPI, err := takeAPISnapshot(filepath.Join(rootDir, "apis"))
+	if err != nil {
+		panic(errors.Wrap(err, "cannot take a snapshot of the previously generated types"))
+	}
+
 	// Group resources based on their Group and API Versions.
 	// An example entry in the tree would be:
 	// ec2.awsjet.crossplane.io -> v1alpha1 -> aws_vpc
 	resourcesGroups := map[string]map[string]map[string]*config.Resource{}
 	for name, resource := range pc.Resources {
-		group := pc.RootGroup
-		if resource.ShortGroup != "" {
-			group = strings.ToLower(resource.ShortGroup) + "." + pc.RootGroup
-		}
+		group := resourceGroup(pc, resource)
 		if len(resourcesGroups[group]) == 0 {
 			resourcesGroups[group] = map[string]map[string]*config.Resource{}
 		}
@@ -67,68 +117,157 @@ func Run(pc *config.Provider, rootDir string) { // nolint:gocyclo
 	for _, p := range pc.BasePackages.Controller {
 		controllerPkgList = append(controllerPkgList, filepath.Join(pc.ModulePath, p))
 	}
-	count := 0
-	for group, versions := range resourcesGroups {
-		for version, resources := range versions {
-			var tfResources []*terraformedInput
-			versionGen := NewVersionGenerator(rootDir, pc.ModulePath, group, version)
-			crdGen := NewCRDGenerator(versionGen.Package(), rootDir, pc.ShortName, group, version)
-			tfGen := NewTerraformedGenerator(versionGen.Package(), rootDir, group, version)
-			ctrlGen := NewControllerGenerator(rootDir, pc.ModulePath, group)
-
-			for _, name := range sortedResources(resources) {
-				paramTypeName, err := crdGen.Generate(resources[name])
-				if err != nil {
-					panic(errors.Wrapf(err, "cannot generate crd for resource %s", name))
-				}
-				tfResources = append(tfResources, &terraformedInput{
-					Resource:           resources[name],
-					ParametersTypeName: paramTypeName,
-				})
-				ctrlPkgPath, err := ctrlGen.Generate(resources[name], versionGen.Package().Path())
-				if err != nil {
-					panic(errors.Wrapf(err, "cannot generate controller for resource %s", name))
-				}
-				controllerPkgList = append(controllerPkgList, ctrlPkgPath)
-				count++
-			}
-
-			if err := tfGen.Generate(tfResources, version); err != nil {
-				panic(errors.Wrapf(err, "cannot generate terraformed for resource %s", group))
-			}
-
-			if err := versionGen.Generate(); err != nil {
-				panic(errors.Wrap(err, "cannot generate version files"))
+	// Load the cache of the previous run to skip generating the resources
+	// whose schema and configuration did not change.
+	var cache *generationCache
+	var stampPath string
+	if o.cachePath != "" && !cacheable(pc) {
+		fmt.Printf("\nGeneration cache is disabled because of the custom kind naming strategy.")
+	}
+	if o.cachePath != "" && cacheable(pc) {
+		key, err := cacheKey(pc, tmpls, o.licenseHeaderPath)
+		if err != nil {
+			panic(errors.Wrap(err, "cannot compute generation cache key"))
+		}
+		if cache, err = loadGenerationCache(o.cachePath, key); err != nil {
+			panic(errors.Wrap(err, "cannot load generation cache"))
+		}
+		stampPath = o.cachePath + ".stamp"
+		if err := os.WriteFile(stampPath, nil, 0600); err != nil {
+			panic(errors.Wrap(err, "cannot write generation stamp file"))
+		}
+	}
+	// Generate the resources of every group version in parallel. The results
+	// are merged in the order of the jobs so that the files generated from
+	// them are deterministic.
+	jobs := versionJobs(resourcesGroups)
+	vg := &versionGenerator{
+		rootDir: rootDir,
+		pc:      pc,
+		opts:    o,
+		tmpls:   tmpls,
+		cache:   cache,
+	}
+	results, err := runVersionJobs(jobs, o.parallelism, vg.generate)
+	if err != nil {
+		panic(err)
+	}
+	count, skipped := 0, 0
+	// The version packages are collected per group package rather than per
+	// group because groups that differ only after the first label share
+	// the same package and its register file has to list all their versions.
+	groupVersionPkgs := map[string][]string{}
+	groupNames := map[string]string{}
+	for _, r := range results {
+		groupPkg := filepath.Dir(r.apiVersionPkg)
+		if _, ok := groupNames[groupPkg]; !ok {
+			groupNames[groupPkg] = r.group
+		}
+		groupVersionPkgs[groupPkg] = append(groupVersionPkgs[groupPkg], r.apiVersionPkg)
+		controllerPkgList = append(controllerPkgList, r.controllerPkgs...)
+		if cache != nil {
+			for name, cr := range r.cached {
+				cache.Resources[name] = cr
 			}
-			apiVersionPkgList = append(apiVersionPkgList, versionGen.Package().Path())
 		}
+		count += r.generated
+		skipped += r.skipped
 	}
 
-	if err := NewRegisterGenerator(rootDir, pc.ModulePath).Generate(apiVersionPkgList); err != nil {
+	groupPkgList := make([]string, 0, len(groupVersionPkgs))
+	for pkg, versionPkgs := range groupVersionPkgs {
+		group := groupNames[pkg]
+		groupRegisterGen := NewGroupRegisterGenerator(rootDir, pc.ModulePath, group)
+		groupRegisterGen.LicenseHeaderPath = o.licenseHeaderPath
+		groupRegisterGen.Template = tmpls.groupRegister
+		groupPkg, err := groupRegisterGen.Generate(versionPkgs)
+		if err != nil {
+			panic(errors.Wrapf(err, "cannot generate register file of group %s", group))
+		}
+		groupPkgList = append(groupPkgList, groupPkg)
+	}
+	registerGen := NewRegisterGenerator(rootDir, pc.ModulePath)
+	registerGen.LicenseHeaderPath = o.licenseHeaderPath
+	registerGen.Template = tmpls.register
+	if err := registerGen.Generate(apiVersionPkgList, groupPkgList); err != nil {
 		panic(errors.Wrap(err, "cannot generate register file"))
 	}
-	if err := NewSetupGenerator(rootDir, pc.ModulePath).Generate(controllerPkgList); err != nil {
+	setupGen := NewSetupGenerator(rootDir, pc.ModulePath)
+	setupGen.LicenseHeaderPath = o.licenseHeaderPath
+	setupGen.Template = tmpls.setup
+	if err := setupGen.Generate(controllerPkgList, pc); err != nil {
 		panic(errors.Wrap(err, "cannot generate setup file"))
 	}
 
 	// NOTE(muvaf): gosec linter requires that the whole command is hard-coded.
 	// So, we set the directory of the command instead of passing in the directory
 	// as an argument to "find".
-	apisCmd := exec.Command("bash", "-c", "goimports -w $(find . -iname 'zz_*')")
+	// If the generation cache is used, only the files written in this run
+	// are processed.
+	importsCmd := goimportsCmd
+	if cache != nil {
+		importsCmd = goimportsNewerCmd
+	}
+	apisCmd := exec.Command("bash", "-c", importsCmd)
 	apisCmd.Dir = filepath.Clean(filepath.Join(rootDir, "apis"))
+	apisCmd.Env = append(os.Environ(), "GENERATION_STAMP="+stampPath)
 	if out, err := apisCmd.CombinedOutput(); err != nil {
 		panic(errors.Wrap(err, "cannot run goimports for apis folder: "+string(out)))
 	}
 
-	internalCmd := exec.Command("bash", "-c", "goimports -w $(find . -iname 'zz_*')")
+	internalCmd := exec.Command("bash", "-c", importsCmd)
 	internalCmd.Dir = filepath.Clean(filepath.Join(rootDir, "internal"))
+	internalCmd.Env = append(os.Environ(), "GENERATION_STAMP="+stampPath)
 	if out, err := internalCmd.CombinedOutput(); err != nil {
 		panic(errors.Wrap(err, "cannot run goimports for internal folder: "+string(out)))
 	}
 
+	nextAPI, err := takeAPISnapshot(filepath.Join(rootDir, "apis"))
+	if err != nil {
+		panic(errors.Wrap(err, "cannot take a snapshot of the generated types"))
+	}
+	printBreakingChanges(os.Stdout, breakingChanges(prevAPI, nextAPI))
+
+	if cache != nil {
+		if err := cache.write(o.cachePath); err != nil {
+			panic(errors.Wrap(err, "cannot write generation cache"))
+		}
+		if err := os.Remove(stampPath); err != nil {
+			panic(errors.Wrap(err, "cannot remove generation stamp file"))
+		}
+		fmt.Printf("\nSkipped %d unchanged resources.", skipped)
+	}
+	if coverage != nil {
+		if err := coverage.write(o.coverageReportPath); err != nil {
+			panic(errors.Wrap(err, "cannot write coverage report"))
+		}
+	}
 	fmt.Printf("\nGenerated %d resources!\n", count)
 }
 
+// applyDocs populates the missing descriptions of the resources from the
+// given documentation source.
+func applyDocs(ctx context.Context, s docs.Source, pc *config.Provider) error {
+	for _, name := range sortedResources(pc.Resources) {
+		r := pc.Resources[name]
+		d, err := s.ResourceDoc(ctx, name)
+		if err != nil {
+			return errors.Wrapf(err, "cannot get documentation of resource %s", name)
+		}
+		if d == nil {
+			continue
+		}
+		d.Apply(r.TerraformResource)
+		if r.Documentation.Description == "" {
+			r.Documentation.Description = d.Description
+		}
+		if len(r.Documentation.Examples) == 0 {
+			r.Documentation.Examples = d.Examples
+		}
+	}
+	return nil
+}
+
 func sortedResources(m map[string]*config.Resource) []string {
 	result := make([]string, len(m))
 	i := 0
@@ -24,6 +24,8 @@ import (
 	"github.com/muvaf/typewriter/pkg/wrapper"
 	"github.com/pkg/errors"
 
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/inventory"
 	"github.com/crossplane/terrajet/pkg/pipeline/templates"
 )
 
@@ -32,6 +34,7 @@ func NewSetupGenerator(rootDir, modulePath string) *SetupGenerator {
 	return &SetupGenerator{
 		LocalDirectoryPath: filepath.Join(rootDir, "internal", "controller"),
 		LicenseHeaderPath:  filepath.Join(rootDir, "hack", "boilerplate.go.txt"),
+		Template:           templates.SetupTemplate,
 		ModulePath:         modulePath,
 	}
 }
@@ -41,12 +44,15 @@ type SetupGenerator struct {
 	LocalDirectoryPath string
 	LicenseHeaderPath  string
 	ModulePath         string
+	// Template is the template the setup file is generated from.
+	Template string
 }
 
 // Generate writes the setup file with the content produced using given
-// list of version packages.
-func (sg *SetupGenerator) Generate(versionPkgList []string) error {
-	setupFile := wrapper.NewFile(filepath.Join(sg.ModulePath, "apis"), "apis", templates.SetupTemplate,
+// list of version packages and the inventory of the kinds of the given
+// provider.
+func (sg *SetupGenerator) Generate(versionPkgList []string, pc *config.Provider) error {
+	setupFile := wrapper.NewFile(filepath.Join(sg.ModulePath, "apis"), "apis", sg.Template,
 		wrapper.WithGenStatement(GenStatement),
 		wrapper.WithHeaderPath(sg.LicenseHeaderPath),
 	)
@@ -56,8 +62,39 @@ func (sg *SetupGenerator) Generate(versionPkgList []string) error {
 		aliases[i] = setupFile.Imports.UsePackage(pkgPath)
 	}
 	vars := map[string]interface{}{
-		"Aliases": aliases,
+		"Aliases":   aliases,
+		"Inventory": newInventory(pc),
 	}
 	filePath := filepath.Join(sg.LocalDirectoryPath, "zz_setup.go")
 	return errors.Wrap(setupFile.Write(filePath, vars, os.ModePerm), "cannot write setup file")
 }
+
+// newInventory returns the inventory of the kinds of the given provider.
+func newInventory(pc *config.Provider) inventory.Inventory {
+	inv := inventory.Inventory{
+		TerraformProviderVersion: pc.TerraformProviderVersion,
+	}
+	for name, r := range pc.Resources {
+		res := inventory.Resource{
+			Group:                 resourceGroup(pc, r),
+			Version:               r.Version,
+			Kind:                  r.Kind,
+			TerraformResourceType: name,
+		}
+		if r.TerraformResource != nil {
+			res.SchemaVersion = r.TerraformResource.SchemaVersion
+		}
+		inv.Resources = append(inv.Resources, res)
+	}
+	sort.Slice(inv.Resources, func(i, j int) bool {
+		a, b := inv.Resources[i], inv.Resources[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Kind < b.Kind
+	})
+	return inv
+}
@@ -26,11 +26,21 @@ var CRDTypesTemplate string
 //go:embed groupversion_info.go.tmpl
 var GroupVersionInfoTemplate string
 
+// DocTemplate is populated with the package documentation and the group and
+// version markers of a version package.
+//go:embed doc.go.tmpl
+var DocTemplate string
+
 // TerraformedTemplate is populated with conversion methods implementing
 // Terraformed interface on CRD structs.
 //go:embed terraformed.go.tmpl
 var TerraformedTemplate string
 
+// TerraformedFuzzTemplate is populated with fuzz tests of the conversion
+// methods implementing Terraformed interface on CRD structs.
+//go:embed terraformed_fuzz_test.go.tmpl
+var TerraformedFuzzTemplate string
+
 // ControllerTemplate is populated with controller setup functions.
 //go:embed controller.go.tmpl
 var ControllerTemplate string
@@ -39,6 +49,11 @@ var ControllerTemplate string
 //go:embed register.go.tmpl
 var RegisterTemplate string
 
+// GroupRegisterTemplate is populated with scheme registration calls of the
+// versions of an API group.
+//go:embed group_register.go.tmpl
+var GroupRegisterTemplate string
+
 // SetupTemplate is populated with controller setup calls.
 //go:embed setup.go.tmpl
 var SetupTemplate string
@@ -33,6 +33,8 @@ func NewTerraformedGenerator(pkg *types.Package, rootDir, group, version string)
 	return &TerraformedGenerator{
 		LocalDirectoryPath: filepath.Join(rootDir, "apis", strings.ToLower(strings.Split(group, ".")[0]), version),
 		LicenseHeaderPath:  filepath.Join(rootDir, "hack", "boilerplate.go.txt"),
+		Template:           templates.TerraformedTemplate,
+		FuzzTemplate:       templates.TerraformedFuzzTemplate,
 		pkg:                pkg,
 	}
 }
@@ -42,13 +44,18 @@ func NewTerraformedGenerator(pkg *types.Package, rootDir, group, version string)
 type TerraformedGenerator struct {
 	LocalDirectoryPath string
 	LicenseHeaderPath  string
+	// Template is the template the conversion methods are generated from.
+	Template string
+	// FuzzTemplate is the template the fuzz tests of the conversion methods
+	// are generated from.
+	FuzzTemplate string
 
 	pkg *types.Package
 }
 
 // Generate writes generated Terraformed interface functions
 func (tg *TerraformedGenerator) Generate(cfgs []*terraformedInput, apiVersion string) error {
-	trFile := wrapper.NewFile(tg.pkg.Path(), tg.pkg.Name(), templates.TerraformedTemplate,
+	trFile := wrapper.NewFile(tg.pkg.Path(), tg.pkg.Name(), tg.Template,
 		wrapper.WithGenStatement(GenStatement),
 		wrapper.WithHeaderPath(tg.LicenseHeaderPath),
 	)
@@ -72,14 +79,23 @@ func (tg *TerraformedGenerator) Generate(cfgs []*terraformedInput, apiVersion st
 				"Fields": cfg.Sensitive.GetFieldPaths(),
 			},
 			"LateInitializer": map[string]interface{}{
-				"IgnoredFields": cfg.LateInitializer.GetIgnoredCanonicalFields(),
+				"IgnoredFields":          cfg.LateInitializer.GetIgnoredCanonicalFields(),
+				"TerraformIgnoredFields": cfg.LateInitializer.IgnoredFields,
+				"ListMergeKeys":          cfg.LateInitializer.ListMergeKeys,
 			},
 		}
 		index++
 	}
 	vars["Resources"] = resources
+	if err := trFile.Write(filePath, vars, os.ModePerm); err != nil {
+		return errors.Wrap(err, "cannot write terraformed conversion methods file")
+	}
+	fuzzFile := wrapper.NewFile(tg.pkg.Path(), tg.pkg.Name(), tg.FuzzTemplate,
+		wrapper.WithGenStatement(GenStatement),
+		wrapper.WithHeaderPath(tg.LicenseHeaderPath),
+	)
 	return errors.Wrap(
-		trFile.Write(filePath, vars, os.ModePerm),
-		"cannot write terraformed conversion methods file",
+		fuzzFile.Write(filepath.Join(tg.LocalDirectoryPath, "zz_generated_terraformed_fuzz_test.go"), vars, os.ModePerm),
+		"cannot write terraformed conversion fuzz tests file",
 	)
 }
@@ -36,6 +36,8 @@ func NewVersionGenerator(rootDir, modulePath, group, version string) *VersionGen
 		Version:           version,
 		DirectoryPath:     filepath.Join(rootDir, "apis", strings.ToLower(strings.Split(group, ".")[0]), version),
 		LicenseHeaderPath: filepath.Join(rootDir, "hack", "boilerplate.go.txt"),
+		Template:          templates.GroupVersionInfoTemplate,
+		DocTemplate:       templates.DocTemplate,
 		pkg:               types.NewPackage(pkgPath, version),
 	}
 }
@@ -46,6 +48,16 @@ type VersionGenerator struct {
 	Version           string
 	DirectoryPath     string
 	LicenseHeaderPath string
+	// Template is the template the group version info file is generated
+	// from.
+	Template string
+	// DocTemplate is the template the package documentation file carrying
+	// the group and version markers is generated from.
+	DocTemplate string
+	// TerraformProviderVersion is the version of the Terraform provider that
+	// the types are generated from. If set, it is recorded in the package
+	// documentation.
+	TerraformProviderVersion string
 
 	pkg *types.Package
 }
@@ -57,8 +69,28 @@ func (vg *VersionGenerator) Generate() error {
 			"Version": vg.Version,
 			"Group":   vg.Group,
 		},
+		"Provider": map[string]string{
+			"Version": vg.TerraformProviderVersion,
+		},
+	}
+	// NOTE(muvaf): The custom group version info templates of the providers
+	// written before the doc file was generated carry the markers
+	// themselves, so they are omitted from the doc file in that case not to
+	// declare them twice in the package.
+	vars["Markers"] = !strings.Contains(vg.Template, "+groupName=")
+	docFile := wrapper.NewFile(vg.pkg.Path(), vg.Version, vg.DocTemplate,
+		wrapper.WithGenStatement(GenStatement),
+		wrapper.WithHeaderPath(vg.LicenseHeaderPath),
+	)
+	if err := docFile.Write(filepath.Join(vg.DirectoryPath, "doc.go"), vars, os.ModePerm); err != nil {
+		return errors.Wrap(err, "cannot write doc file")
+	}
+	// The doc file used to be generated as zz_doc.go, which would now
+	// declare the package documentation twice.
+	if err := os.Remove(filepath.Join(vg.DirectoryPath, "zz_doc.go")); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "cannot remove the previously generated doc file")
 	}
-	gviFile := wrapper.NewFile(vg.pkg.Path(), vg.Version, templates.GroupVersionInfoTemplate,
+	gviFile := wrapper.NewFile(vg.pkg.Path(), vg.Version, vg.Template,
 		wrapper.WithGenStatement(GenStatement),
 		wrapper.WithHeaderPath(vg.LicenseHeaderPath),
 	)
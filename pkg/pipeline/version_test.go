@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVersionGeneratorGenerate(t *testing.T) {
+	const header = "// Copyright 2021 The Crossplane Authors.\n"
+	const legacyTemplate = "{{ .Header }}\n\n{{ .GenStatement }}\n\n// +kubebuilder:object:generate=true\n// +groupName={{ .CRD.Group }}\n// +versionName={{ .CRD.Version }}\npackage {{ .CRD.Version }}\n"
+	type args struct {
+		template        string
+		providerVersion string
+		staleDoc        bool
+	}
+	type want struct {
+		doc string
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Markers": {
+			reason: "The doc file should carry the group and version markers along with the provider version",
+			args: args{
+				providerVersion: "3.63.0",
+			},
+			want: want{
+				doc: header + "\n\n" + GenStatement + "\n\n// Package v1alpha1 contains the v1alpha1 version of the\n// Kubernetes API of the ec2.aws.jet.crossplane.io group.\n//\n// The types in this package are generated by terrajet from the schema of the\n// Terraform provider at version 3.63.0.\n//\n// +kubebuilder:object:generate=true\n// +groupName=ec2.aws.jet.crossplane.io\n// +versionName=v1alpha1\npackage v1alpha1\n",
+			},
+		},
+		"MarkersInGroupVersionInfo": {
+			reason: "The markers should not be repeated in the doc file if the group version info template already declares them",
+			args: args{
+				template: legacyTemplate,
+			},
+			want: want{
+				doc: header + "\n\n" + GenStatement + "\n\n// Package v1alpha1 contains the v1alpha1 version of the\n// Kubernetes API of the ec2.aws.jet.crossplane.io group.\n//\n// The types in this package are generated by terrajet from the schema of the\n// Terraform provider.\npackage v1alpha1\n",
+			},
+		},
+		"StaleDoc": {
+			reason: "A doc file generated by a previous version of the generator should be removed",
+			args: args{
+				staleDoc: true,
+			},
+			want: want{
+				doc: header + "\n\n" + GenStatement + "\n\n// Package v1alpha1 contains the v1alpha1 version of the\n// Kubernetes API of the ec2.aws.jet.crossplane.io group.\n//\n// The types in this package are generated by terrajet from the schema of the\n// Terraform provider.\n//\n// +kubebuilder:object:generate=true\n// +groupName=ec2.aws.jet.crossplane.io\n// +versionName=v1alpha1\npackage v1alpha1\n",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			headerPath := filepath.Join(root, "boilerplate.go.txt")
+			if err := os.WriteFile(headerPath, []byte(header), 0600); err != nil {
+				t.Fatal(err)
+			}
+			vg := NewVersionGenerator(root, "github.com/crossplane-contrib/provider-jet-aws", "ec2.aws.jet.crossplane.io", "v1alpha1")
+			vg.LicenseHeaderPath = headerPath
+			vg.TerraformProviderVersion = tc.args.providerVersion
+			if tc.args.template != "" {
+				vg.Template = tc.args.template
+			}
+			stalePath := filepath.Join(vg.DirectoryPath, "zz_doc.go")
+			if tc.args.staleDoc {
+				if err := os.MkdirAll(vg.DirectoryPath, os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(stalePath, []byte("package v1alpha1\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := vg.Generate(); err != nil {
+				t.Fatalf("\n%s\nGenerate(...): unexpected error: %v", tc.reason, err)
+			}
+			doc, err := os.ReadFile(filepath.Join(vg.DirectoryPath, "doc.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want.doc, string(doc)); diff != "" {
+				t.Errorf("\n%s\nGenerate(...): -want doc, +got doc:\n%s", tc.reason, diff)
+			}
+			if _, err := os.Stat(stalePath); !os.IsNotExist(err) {
+				t.Errorf("\n%s\nGenerate(...): zz_doc.go should not exist, stat error: %v", tc.reason, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+)
+
+const (
+	errFmtCoerce     = "cannot coerce value of %s"
+	errFmtUnexpected = "unexpected type %T"
+)
+
+// CoerceToSchema returns a copy of the given Terraform attributes whose
+// primitive values are converted to the types declared in the given schema.
+// Values that went through a JSON round-trip via untyped maps may have lost
+// their type, e.g. integers become float64 and numbers or booleans may be
+// represented as strings, which causes Terraform to report spurious diffs.
+// Attributes that do not exist in the schema are kept as is.
+func CoerceToSchema(attr map[string]interface{}, sch map[string]*schema.Schema) (map[string]interface{}, error) {
+	return coerceObject("", attr, sch)
+}
+
+func coerceObject(path string, attr map[string]interface{}, sch map[string]*schema.Schema) (map[string]interface{}, error) {
+	if attr == nil {
+		return nil, nil
+	}
+	result := make(map[string]interface{}, len(attr))
+	for k, v := range attr {
+		s, ok := sch[k]
+		if !ok {
+			result[k] = v
+			continue
+		}
+		c, err := coerceValue(joinPath(path, k), v, s)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = c
+	}
+	return result, nil
+}
+
+func coerceValue(path string, v interface{}, s *schema.Schema) (interface{}, error) { // nolint:gocyclo
+	if v == nil {
+		return nil, nil
+	}
+	switch s.Type { // nolint:exhaustive
+	case schema.TypeList, schema.TypeSet:
+		l, ok := v.([]interface{})
+		if !ok {
+			return v, nil
+		}
+		result := make([]interface{}, len(l))
+		for i, e := range l {
+			c, err := coerceElem(path+"["+strconv.Itoa(i)+"]", e, s.Elem)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = c
+		}
+		return result, nil
+	case schema.TypeMap:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v, nil
+		}
+		elem := s.Elem
+		if elem == nil {
+			elem = &schema.Schema{Type: schema.TypeString}
+		}
+		result := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			c, err := coerceElem(joinPath(path, k), e, elem)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = c
+		}
+		return result, nil
+	}
+	c, err := coercePrimitive(v, s.Type)
+	return c, errors.Wrapf(err, errFmtCoerce, path)
+}
+
+func coerceElem(path string, v interface{}, elem interface{}) (interface{}, error) {
+	switch e := elem.(type) {
+	case *schema.Resource:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v, nil
+		}
+		return coerceObject(path, m, e.Schema)
+	case *schema.Schema:
+		return coerceValue(path, v, e)
+	}
+	return v, nil
+}
+
+func coercePrimitive(v interface{}, t schema.ValueType) (interface{}, error) { // nolint:gocyclo
+	switch t { // nolint:exhaustive
+	case schema.TypeString:
+		switch x := v.(type) {
+		case float64:
+			return strconv.FormatFloat(x, 'f', -1, 64), nil
+		case int64:
+			return strconv.FormatInt(x, 10), nil
+		case int:
+			return strconv.Itoa(x), nil
+		case bool:
+			return strconv.FormatBool(x), nil
+		case json.Number:
+			return x.String(), nil
+		}
+	case schema.TypeInt:
+		switch x := v.(type) {
+		case float64:
+			// NOTE(muvaf): A fractional number cannot be represented as an
+			// integer without losing information, so it's kept as is and
+			// left to Terraform to report it for the field.
+			if x != math.Trunc(x) {
+				return x, nil
+			}
+			return int64(x), nil
+		case int:
+			return int64(x), nil
+		case string:
+			return strconv.ParseInt(x, 10, 64)
+		}
+	case schema.TypeFloat:
+		switch x := v.(type) {
+		case int64:
+			return float64(x), nil
+		case int:
+			return float64(x), nil
+		case string:
+			return strconv.ParseFloat(x, 64)
+		}
+	case schema.TypeBool:
+		if x, ok := v.(string); ok {
+			return strconv.ParseBool(x)
+		}
+	}
+	if !isPrimitive(v) {
+		return nil, errors.Errorf(errFmtUnexpected, v)
+	}
+	return v, nil
+}
+
+func isPrimitive(v interface{}) bool {
+	switch v.(type) {
+	case string, bool, float64, int64, int, json.Number:
+		return true
+	}
+	return false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
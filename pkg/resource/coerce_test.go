@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestCoerceToSchema(t *testing.T) {
+	sch := map[string]*schema.Schema{
+		"name":    {Type: schema.TypeString},
+		"port":    {Type: schema.TypeInt},
+		"ratio":   {Type: schema.TypeFloat},
+		"enabled": {Type: schema.TypeBool},
+		"tags":    {Type: schema.TypeMap},
+		"ports":   {Type: schema.TypeList, Elem: &schema.Schema{Type: schema.TypeInt}},
+		"rule": {Type: schema.TypeList, Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"priority": {Type: schema.TypeInt},
+			},
+		}},
+	}
+	type want struct {
+		attr map[string]interface{}
+		err  error
+	}
+	cases := map[string]struct {
+		reason string
+		attr   map[string]interface{}
+		want
+	}{
+		"Primitives": {
+			reason: "Primitive values should be converted to the types declared in the schema",
+			attr: map[string]interface{}{
+				"name":    float64(12),
+				"port":    float64(8080),
+				"ratio":   "0.5",
+				"enabled": "true",
+			},
+			want: want{
+				attr: map[string]interface{}{
+					"name":    "12",
+					"port":    int64(8080),
+					"ratio":   0.5,
+					"enabled": true,
+				},
+			},
+		},
+		"Nested": {
+			reason: "Values in lists, maps and nested blocks should be converted",
+			attr: map[string]interface{}{
+				"tags":  map[string]interface{}{"count": float64(3)},
+				"ports": []interface{}{float64(80), "443"},
+				"rule":  []interface{}{map[string]interface{}{"priority": float64(1)}},
+			},
+			want: want{
+				attr: map[string]interface{}{
+					"tags":  map[string]interface{}{"count": "3"},
+					"ports": []interface{}{int64(80), int64(443)},
+					"rule":  []interface{}{map[string]interface{}{"priority": int64(1)}},
+				},
+			},
+		},
+		"Unknown": {
+			reason: "Attributes that do not exist in the schema should be kept as is",
+			attr: map[string]interface{}{
+				"id":  "some-id",
+				"nil": nil,
+			},
+			want: want{
+				attr: map[string]interface{}{
+					"id":  "some-id",
+					"nil": nil,
+				},
+			},
+		},
+		"NotIntegral": {
+			reason: "A fractional number given for an integer should be kept as is so that the other attributes are still coerced",
+			attr: map[string]interface{}{
+				"port": "3",
+				"rule": []interface{}{map[string]interface{}{"priority": 1.5}},
+			},
+			want: want{
+				attr: map[string]interface{}{
+					"port": int64(3),
+					"rule": []interface{}{map[string]interface{}{"priority": 1.5}},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := CoerceToSchema(tc.attr, sch)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nCoerceToSchema(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.attr, got); diff != "" {
+				t.Errorf("\n%s\nCoerceToSchema(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -26,16 +26,115 @@ import (
 
 // Condition constants.
 const (
+	TypeLastOperation      = "LastOperation"
 	TypeLastAsyncOperation = "LastAsyncOperation"
 	TypeAsyncOperation     = "AsyncOperation"
+	TypeDrift              = "Drift"
 
-	ReasonApplyFailure   xpv1.ConditionReason = "ApplyFailure"
+	TypeDeletionBlockedExternally = "DeletionBlockedExternally"
+	TypeQuotaExceeded             = "QuotaExceeded"
+
+	ReasonSuccess      xpv1.ConditionReason = "Success"
+	ReasonFinished     xpv1.ConditionReason = "Finished"
+	ReasonNoDrift      xpv1.ConditionReason = "NoDrift"
+	ReasonDriftUnknown xpv1.ConditionReason = "DriftUnknown"
+	ReasonUnknown      xpv1.ConditionReason = "Unknown"
+
+	ReasonDeletionProtected          xpv1.ConditionReason = "DeletionProtected"
+	ReasonDeletionProtectionDisabled xpv1.ConditionReason = "DeletionProtectionDisabled"
+
+	ReasonQuotaExceeded  xpv1.ConditionReason = "QuotaExceeded"
+	ReasonQuotaAvailable xpv1.ConditionReason = "QuotaAvailable"
+)
+
+// Canonical condition reasons. They are the same in all providers generated
+// by terrajet so that dashboards and automations can rely on them instead of
+// parsing condition messages. Use ReasonFor to get the one for an error.
+const (
+	ReasonApplyFailed        xpv1.ConditionReason = "ApplyFailed"
+	ReasonDestroyFailed      xpv1.ConditionReason = "DestroyFailed"
+	ReasonPlanFailed         xpv1.ConditionReason = "PlanFailed"
+	ReasonValidateFailed     xpv1.ConditionReason = "ValidateFailed"
+	ReasonAsyncInProgress    xpv1.ConditionReason = "AsyncInProgress"
+	ReasonDependencyMissing  xpv1.ConditionReason = "DependencyMissing"
+	ReasonCredentialsInvalid xpv1.ConditionReason = "CredentialsInvalid"
+	ReasonDriftDetected      xpv1.ConditionReason = "DriftDetected"
+	ReasonConflict           xpv1.ConditionReason = "Conflict"
+	ReasonInvalidParameter   xpv1.ConditionReason = "InvalidParameter"
+	ReasonThrottled          xpv1.ConditionReason = "Throttled"
+)
+
+// Deprecated condition reasons kept for compatibility. They keep their
+// original values so that the conditions that have been set with them can
+// still be matched.
+const (
+	// Deprecated: Use ReasonApplyFailed.
+	ReasonApplyFailure xpv1.ConditionReason = "ApplyFailure"
+	// Deprecated: Use ReasonDestroyFailed.
 	ReasonDestroyFailure xpv1.ConditionReason = "DestroyFailure"
-	ReasonSuccess        xpv1.ConditionReason = "Success"
-	ReasonOngoing        xpv1.ConditionReason = "Ongoing"
-	ReasonFinished       xpv1.ConditionReason = "Finished"
+	// Deprecated: Use ReasonAsyncInProgress.
+	ReasonOngoing xpv1.ConditionReason = "Ongoing"
 )
 
+// classReasons are the canonical condition reasons of the classes of the
+// failures reported by the Terraform diagnostics.
+var classReasons = map[tferrors.Class]xpv1.ConditionReason{
+	tferrors.ClassAuthFailure:      ReasonCredentialsInvalid,
+	tferrors.ClassQuotaExceeded:    ReasonQuotaExceeded,
+	tferrors.ClassConflict:         ReasonConflict,
+	tferrors.ClassInvalidParameter: ReasonInvalidParameter,
+	tferrors.ClassThrottled:        ReasonThrottled,
+}
+
+// ReasonFor returns the canonical condition reason of the given error of a
+// failed operation, or ReasonUnknown if the error cannot be classified. The
+// class of the failure reported by the Terraform diagnostics takes
+// precedence over the type of the operation that failed.
+func ReasonFor(err error) xpv1.ConditionReason {
+	switch {
+	case tferrors.IsCredentialsInvalid(err):
+		return ReasonCredentialsInvalid
+	case tferrors.IsDependencyMissing(err):
+		return ReasonDependencyMissing
+	}
+	if r, ok := classReasons[tferrors.Classify(err)]; ok {
+		return r
+	}
+	switch {
+	case tferrors.IsApplyFailed(err):
+		return ReasonApplyFailed
+	case tferrors.IsDestroyFailed(err):
+		return ReasonDestroyFailed
+	case tferrors.IsPlanFailed(err):
+		return ReasonPlanFailed
+	case tferrors.IsValidateFailed(err):
+		return ReasonValidateFailed
+	default:
+		return ReasonUnknown
+	}
+}
+
+// LastOperationCondition returns the condition TypeLastOperation that
+// reports the outcome of the last synchronous operation on the resource
+// with its canonical reason.
+func LastOperationCondition(err error) xpv1.Condition {
+	if err == nil {
+		return xpv1.Condition{
+			Type:               TypeLastOperation,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             ReasonSuccess,
+		}
+	}
+	return xpv1.Condition{
+		Type:               TypeLastOperation,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonFor(err),
+		Message:            err.Error(),
+	}
+}
+
 // LastAsyncOperationCondition returns the condition depending on the content
 // of the error.
 func LastAsyncOperationCondition(err error) xpv1.Condition {
@@ -47,20 +146,12 @@ func LastAsyncOperationCondition(err error) xpv1.Condition {
 			LastTransitionTime: metav1.Now(),
 			Reason:             ReasonSuccess,
 		}
-	case tferrors.IsApplyFailed(err):
-		return xpv1.Condition{
-			Type:               TypeLastAsyncOperation,
-			Status:             corev1.ConditionFalse,
-			LastTransitionTime: metav1.Now(),
-			Reason:             ReasonApplyFailure,
-			Message:            err.Error(),
-		}
-	case tferrors.IsDestroyFailed(err):
+	case ReasonFor(err) != ReasonUnknown:
 		return xpv1.Condition{
 			Type:               TypeLastAsyncOperation,
 			Status:             corev1.ConditionFalse,
 			LastTransitionTime: metav1.Now(),
-			Reason:             ReasonDestroyFailure,
+			Reason:             ReasonFor(err),
 			Message:            err.Error(),
 		}
 	default:
@@ -68,7 +159,7 @@ func LastAsyncOperationCondition(err error) xpv1.Condition {
 			Type:               "Unknown",
 			Status:             corev1.ConditionFalse,
 			LastTransitionTime: metav1.Now(),
-			Reason:             "Unknown",
+			Reason:             ReasonUnknown,
 			Message:            err.Error(),
 		}
 	}
@@ -92,6 +183,90 @@ func AsyncOperationOngoingCondition() xpv1.Condition {
 		Type:               TypeAsyncOperation,
 		Status:             corev1.ConditionFalse,
 		LastTransitionTime: metav1.Now(),
-		Reason:             ReasonOngoing,
+		Reason:             ReasonAsyncInProgress,
+	}
+}
+
+// DriftDetectedCondition returns the condition TypeDrift DriftDetected with
+// the given explanation of the drift.
+func DriftDetectedCondition(explanation string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDrift,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDriftDetected,
+		Message:            explanation,
+	}
+}
+
+// NoDriftCondition returns the condition TypeDrift NoDrift if the resource is
+// up-to-date.
+func NoDriftCondition() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDrift,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoDrift,
+	}
+}
+
+// DriftUnknownCondition returns the condition TypeDrift DriftUnknown if the
+// drift could not be explained.
+func DriftUnknownCondition(err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDrift,
+		Status:             corev1.ConditionUnknown,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDriftUnknown,
+		Message:            err.Error(),
+	}
+}
+
+// DeletionBlockedExternallyCondition returns the condition
+// TypeDeletionBlockedExternally DeletionProtected with the given remediation
+// hint.
+func DeletionBlockedExternallyCondition(hint string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDeletionBlockedExternally,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDeletionProtected,
+		Message:            hint,
+	}
+}
+
+// DeletionProtectionDisabledCondition returns the condition
+// TypeDeletionBlockedExternally DeletionProtectionDisabled once the deletion
+// protection is disabled by the provider.
+func DeletionProtectionDisabledCondition() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDeletionBlockedExternally,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDeletionProtectionDisabled,
+	}
+}
+
+// QuotaExceededCondition returns the condition TypeQuotaExceeded
+// QuotaExceeded with the message of the given error of the quota check.
+func QuotaExceededCondition(err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeQuotaExceeded,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonQuotaExceeded,
+		Message:            err.Error(),
+	}
+}
+
+// QuotaAvailableCondition returns the condition TypeQuotaExceeded
+// QuotaAvailable once the quota check of a resource that exceeded its quota
+// passes.
+func QuotaAvailableCondition() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeQuotaExceeded,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonQuotaAvailable,
 	}
 }
@@ -0,0 +1,248 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+const (
+	errFmtCtyConvert       = "cannot convert value of %s"
+	errFmtCtyUnsupported   = "unsupported attribute %s"
+	errFmtCtyUnexpectedVal = "unexpected value of type %T for %s"
+)
+
+// SchemaType returns the cty type of the values of the given Terraform
+// schema.
+func SchemaType(s *schema.Schema) cty.Type { // nolint:gocyclo
+	switch s.Type { // nolint:exhaustive
+	case schema.TypeString:
+		return cty.String
+	case schema.TypeInt, schema.TypeFloat:
+		return cty.Number
+	case schema.TypeBool:
+		return cty.Bool
+	case schema.TypeList:
+		return cty.List(elemType(s.Elem))
+	case schema.TypeSet:
+		return cty.Set(elemType(s.Elem))
+	case schema.TypeMap:
+		return cty.Map(elemType(s.Elem))
+	}
+	return cty.DynamicPseudoType
+}
+
+// ObjectType returns the cty object type of a block with the given Terraform
+// schema.
+func ObjectType(sch map[string]*schema.Schema) cty.Type {
+	attrs := make(map[string]cty.Type, len(sch))
+	for k, s := range sch {
+		attrs[k] = SchemaType(s)
+	}
+	return cty.Object(attrs)
+}
+
+func elemType(elem interface{}) cty.Type {
+	switch e := elem.(type) {
+	case *schema.Resource:
+		return ObjectType(e.Schema)
+	case *schema.Schema:
+		return SchemaType(e)
+	}
+	// NOTE(muvaf): Terraform assumes string elements if no element type is
+	// given, e.g. for maps.
+	return cty.String
+}
+
+// ToCtyValue converts the given value that went through a JSON round-trip
+// into a cty value of the given type. Primitive values are converted to the
+// type, e.g. a string holding a number becomes a number, nil becomes a null
+// value and empty collections stay empty rather than null.
+func ToCtyValue(v interface{}, t cty.Type) (cty.Value, error) {
+	return toCtyValue("", v, t)
+}
+
+func toCtyValue(path string, v interface{}, t cty.Type) (cty.Value, error) { // nolint:gocyclo
+	if v == nil {
+		return cty.NullVal(t), nil
+	}
+	switch {
+	case t.IsObjectType():
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return cty.NilVal, errors.Errorf(errFmtCtyUnexpectedVal, v, path)
+		}
+		for k := range m {
+			if !t.HasAttribute(k) {
+				return cty.NilVal, errors.Errorf(errFmtCtyUnsupported, joinPath(path, k))
+			}
+		}
+		attrs := make(map[string]cty.Value, len(t.AttributeTypes()))
+		for k, at := range t.AttributeTypes() {
+			av, err := toCtyValue(joinPath(path, k), m[k], at)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			attrs[k] = av
+		}
+		return cty.ObjectVal(attrs), nil
+	case t.IsListType(), t.IsSetType():
+		l, ok := v.([]interface{})
+		if !ok {
+			return cty.NilVal, errors.Errorf(errFmtCtyUnexpectedVal, v, path)
+		}
+		et := t.ElementType()
+		if len(l) == 0 {
+			if t.IsSetType() {
+				return cty.SetValEmpty(et), nil
+			}
+			return cty.ListValEmpty(et), nil
+		}
+		elems := make([]cty.Value, len(l))
+		for i, e := range l {
+			ev, err := toCtyValue(path+"["+strconv.Itoa(i)+"]", e, et)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elems[i] = ev
+		}
+		if t.IsSetType() {
+			return cty.SetVal(elems), nil
+		}
+		return cty.ListVal(elems), nil
+	case t.IsMapType():
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return cty.NilVal, errors.Errorf(errFmtCtyUnexpectedVal, v, path)
+		}
+		et := t.ElementType()
+		if len(m) == 0 {
+			return cty.MapValEmpty(et), nil
+		}
+		elems := make(map[string]cty.Value, len(m))
+		for k, e := range m {
+			ev, err := toCtyValue(joinPath(path, k), e, et)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elems[k] = ev
+		}
+		return cty.MapVal(elems), nil
+	}
+	pv, err := primitiveCtyValue(v)
+	if err != nil {
+		return cty.NilVal, errors.Wrapf(err, errFmtCtyConvert, path)
+	}
+	if t == cty.DynamicPseudoType {
+		return pv, nil
+	}
+	cv, err := convert.Convert(pv, t)
+	return cv, errors.Wrapf(err, errFmtCtyConvert, path)
+}
+
+func primitiveCtyValue(v interface{}) (cty.Value, error) {
+	switch x := v.(type) {
+	case string:
+		return cty.StringVal(x), nil
+	case bool:
+		return cty.BoolVal(x), nil
+	case float64:
+		return cty.NumberFloatVal(x), nil
+	case int64:
+		return cty.NumberIntVal(x), nil
+	case int:
+		return cty.NumberIntVal(int64(x)), nil
+	case json.Number:
+		return cty.ParseNumberVal(x.String())
+	}
+	return cty.NilVal, errors.Errorf(errFmtUnexpected, v)
+}
+
+// FromCtyValue converts the given cty value into its JSON representation in
+// the form of untyped maps and slices. Numbers are represented as
+// json.Number so that their precision is preserved. The null attributes of
+// objects are omitted, which is how unset arguments are represented in
+// Terraform JSON configuration.
+func FromCtyValue(v cty.Value) interface{} { // nolint:gocyclo
+	if v.IsNull() || !v.IsKnown() {
+		return nil
+	}
+	t := v.Type()
+	switch {
+	case t == cty.String:
+		return v.AsString()
+	case t == cty.Number:
+		return json.Number(v.AsBigFloat().Text('f', -1))
+	case t == cty.Bool:
+		return v.True()
+	case t.IsObjectType():
+		m := map[string]interface{}{}
+		for k, av := range v.AsValueMap() {
+			if av.IsNull() {
+				continue
+			}
+			m[k] = FromCtyValue(av)
+		}
+		return m
+	case t.IsMapType():
+		m := map[string]interface{}{}
+		for k, ev := range v.AsValueMap() {
+			m[k] = FromCtyValue(ev)
+		}
+		return m
+	case t.IsListType(), t.IsSetType(), t.IsTupleType():
+		l := make([]interface{}, 0, v.LengthInt())
+		for _, ev := range v.AsValueSlice() {
+			l = append(l, FromCtyValue(ev))
+		}
+		return l
+	}
+	return nil
+}
+
+// TypedParameters returns a copy of the given Terraform parameters whose
+// arguments are converted to cty values typed by the given schema and back,
+// so that numbers, sets and the difference between null and empty values
+// are represented the way Terraform expects them. The parameters that are
+// not in the schema, e.g. meta-arguments like "lifecycle", are kept as is.
+// The primitive values are coerced with CoerceToSchema first.
+func TypedParameters(params map[string]interface{}, sch map[string]*schema.Schema) (map[string]interface{}, error) {
+	coerced, err := CoerceToSchema(params, sch)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{}, len(coerced))
+	for k, v := range coerced {
+		s, ok := sch[k]
+		if !ok {
+			result[k] = v
+			continue
+		}
+		cv, err := toCtyValue(k, v, SchemaType(s))
+		if err != nil {
+			return nil, err
+		}
+		result[k] = FromCtyValue(cv)
+	}
+	return result, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+
+	tjjson "github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+func TestTypedParameters(t *testing.T) {
+	sch := map[string]*schema.Schema{
+		"name":  {Type: schema.TypeString},
+		"count": {Type: schema.TypeInt},
+		"ratio": {Type: schema.TypeFloat},
+		"tags":  {Type: schema.TypeMap, Elem: &schema.Schema{Type: schema.TypeString}},
+		"ports": {Type: schema.TypeSet, Elem: &schema.Schema{Type: schema.TypeInt}},
+		"rule": {Type: schema.TypeList, Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"cidr":    {Type: schema.TypeString},
+			"enabled": {Type: schema.TypeBool},
+		}}},
+	}
+	type want struct {
+		out string
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		params map[string]interface{}
+		want
+	}{
+		"Typed": {
+			reason: "Arguments should be converted to the types declared in the schema",
+			params: map[string]interface{}{
+				"name":  float64(12),
+				"count": "3",
+				"ratio": float64(0.5),
+				"ports": []interface{}{float64(80), float64(443), float64(80)},
+				"rule": []interface{}{
+					map[string]interface{}{"cidr": "10.0.0.0/16", "enabled": "true"},
+				},
+			},
+			want: want{
+				out: `{"count":3,"name":"12","ports":[80,443],"ratio":0.5,"rule":[{"cidr":"10.0.0.0/16","enabled":true}]}`,
+			},
+		},
+		"JSONNumber": {
+			reason: "Numbers that are already represented as json.Number should be accepted",
+			params: map[string]interface{}{
+				"name":  json.Number("12"),
+				"count": json.Number("3"),
+			},
+			want: want{
+				out: `{"count":3,"name":"12"}`,
+			},
+		},
+		"NullVersusEmpty": {
+			reason: "Empty collections should be kept empty and nulls should be kept null",
+			params: map[string]interface{}{
+				"name":  nil,
+				"tags":  map[string]interface{}{},
+				"ports": []interface{}{},
+			},
+			want: want{
+				out: `{"name":null,"ports":[],"tags":{}}`,
+			},
+		},
+		"MetaArguments": {
+			reason: "Arguments that are not in the schema should be kept as is",
+			params: map[string]interface{}{
+				"lifecycle": map[string]bool{"prevent_destroy": true},
+			},
+			want: want{
+				out: `{"lifecycle":{"prevent_destroy":true}}`,
+			},
+		},
+		"NotConvertible": {
+			reason: "An error should be returned if a value cannot be converted to its type",
+			params: map[string]interface{}{
+				"count": "three",
+			},
+			want: want{
+				err: errors.Wrapf(&strconv.NumError{Func: "ParseInt", Num: "three", Err: strconv.ErrSyntax}, errFmtCoerce, "count"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := TypedParameters(tc.params, sch)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nTypedParameters(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			out, err := tjjson.JSParser.Marshal(got)
+			if err != nil {
+				t.Fatalf("cannot marshal typed parameters: %s", err)
+			}
+			if diff := cmp.Diff(tc.want.out, string(out)); diff != "" {
+				t.Errorf("\n%s\nTypedParameters(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFromCtyValueNumberPrecision(t *testing.T) {
+	v, err := ToCtyValue(json.Number("12345678901234567890"), SchemaType(&schema.Schema{Type: schema.TypeInt}))
+	if err != nil {
+		t.Fatalf("ToCtyValue(...): unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(json.Number("12345678901234567890"), FromCtyValue(v)); diff != "" {
+		t.Errorf("FromCtyValue(...): -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/types/name"
+)
+
+// AnnotationKeyDisableDeletionProtection is the annotation that lets the
+// provider disable the deletion protection of the external resource before
+// deleting it when its value is "true".
+const AnnotationKeyDisableDeletionProtection = "terrajet.crossplane.io/disable-deletion-protection"
+
+// AnnotationKeyPlanDestroy is the debug annotation that makes the provider
+// plan the destruction of the external resource without destroying it and
+// report the resources and actions the destroy would involve in an event.
+// The annotation is removed once the plan is reported.
+const AnnotationKeyPlanDestroy = "terrajet.crossplane.io/plan-destroy"
+
+// DestroyPlanRequested returns whether the given resource is annotated to
+// have the destruction of its external resource planned.
+func DestroyPlanRequested(mg xpresource.Managed) bool {
+	return mg.GetAnnotations()[AnnotationKeyPlanDestroy] == "true"
+}
+
+// AnnotationKeyReplace is the annotation that makes the provider replace the
+// external resource in the next apply when its value is "true", like
+// "terraform apply -replace" does, so that a degraded resource can be
+// recreated. The annotation is removed once the replacing apply is run.
+const AnnotationKeyReplace = "terrajet.crossplane.io/replace"
+
+// ReplaceRequested returns whether the given resource is annotated to have
+// its external resource replaced.
+func ReplaceRequested(mg xpresource.Object) bool {
+	return mg.GetAnnotations()[AnnotationKeyReplace] == "true"
+}
+
+// DeletionProtectionOverridden returns whether the given resource is being
+// deleted and it is annotated to have its deletion protection disabled.
+func DeletionProtectionOverridden(mg xpresource.Managed) bool {
+	return meta.WasDeleted(mg) && mg.GetAnnotations()[AnnotationKeyDisableDeletionProtection] == "true"
+}
+
+// DeletionProtectionHint returns the remediation hint for a deletion that is
+// blocked by the given deletion protection configuration.
+func DeletionProtectionHint(dp *config.DeletionProtection) string {
+	return fmt.Sprintf("deletion is blocked by the deletion protection of the external resource: set spec.forProvider.%s to false, or add the %s annotation with value \"true\" to let the provider disable it before deletion",
+		name.NewFromSnake(dp.FieldName).LowerCamelComputed, AnnotationKeyDisableDeletionProtection)
+}
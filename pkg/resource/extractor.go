@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/pkg/reference"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+)
+
+// ExtractResourceID extracts the value of the "id" attribute of the
+// Terraform state of the referenced resource.
+func ExtractResourceID() reference.ExtractValueFn {
+	return ExtractParamPath("id", true)
+}
+
+// ExtractParamPath extracts the value of the given Terraform field path of the
+// referenced resource, e.g. "arn" or "network_interface[0].id". The value is
+// read from the observation if isObservation is true and from the
+// parameters otherwise. It returns an empty string if the referenced
+// resource is not a Terraformed resource or the field is not a string.
+func ExtractParamPath(path string, isObservation bool) reference.ExtractValueFn {
+	return func(mg xpresource.Managed) string {
+		tr, ok := mg.(Terraformed)
+		if !ok {
+			return ""
+		}
+		get := tr.GetParameters
+		if isObservation {
+			get = tr.GetObservation
+		}
+		m, err := get()
+		if err != nil {
+			return ""
+		}
+		v, err := fieldpath.Pave(m).GetString(path)
+		if err != nil {
+			return ""
+		}
+		return v
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"testing"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+)
+
+func TestExtractParamPath(t *testing.T) {
+	tr := &fake.Terraformed{
+		Parameterizable: fake.Parameterizable{Parameters: map[string]interface{}{
+			"name": "example",
+		}},
+		Observable: fake.Observable{Observation: map[string]interface{}{
+			"id":  "vpc-123",
+			"arn": "arn:aws:ec2:us-east-1:123456789012:vpc/vpc-123",
+			"network_interface": []interface{}{
+				map[string]interface{}{"id": "eni-123"},
+			},
+		}},
+	}
+	type args struct {
+		mg            xpresource.Managed
+		path          string
+		isObservation bool
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want string
+	}{
+		"Parameter": {
+			reason: "The value should be read from the parameters if the field is not an observation",
+			args:   args{mg: tr, path: "name"},
+			want:   "example",
+		},
+		"Observation": {
+			reason: "The value should be read from the observation if the field is an observation",
+			args:   args{mg: tr, path: "arn", isObservation: true},
+			want:   "arn:aws:ec2:us-east-1:123456789012:vpc/vpc-123",
+		},
+		"Nested": {
+			reason: "The value of a nested field should be extracted",
+			args:   args{mg: tr, path: "network_interface[0].id", isObservation: true},
+			want:   "eni-123",
+		},
+		"Missing": {
+			reason: "An empty string should be returned if the field does not exist",
+			args:   args{mg: tr, path: "arn"},
+		},
+		"NotTerraformed": {
+			reason: "An empty string should be returned if the resource is not Terraformed",
+			args:   args{mg: &xpfake.Managed{}, path: "arn", isObservation: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ExtractParamPath(tc.args.path, tc.args.isObservation)(tc.args.mg)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nExtractParamPath(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
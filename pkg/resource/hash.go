@@ -0,0 +1,46 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AnnotationKeyAppliedSpecHash is the annotation that holds the hash of the
+// Terraform configuration of the resource that was last confirmed to be
+// applied, i.e. the configuration an apply succeeded with or a plan found no
+// changes for. The configuration covers the resolved sensitive parameters
+// and the provider configuration along with the parameters of the resource.
+const AnnotationKeyAppliedSpecHash = "terrajet.crossplane.io/applied-spec-hash"
+
+// SpecApplied returns whether the Terraform configuration with the given hash
+// is the one that was last confirmed to be applied for the given resource.
+func SpecApplied(o metav1.Object, hash string) bool {
+	return hash != "" && hash == o.GetAnnotations()[AnnotationKeyAppliedSpecHash]
+}
+
+// SetAppliedSpecHash records the given hash of the Terraform configuration of
+// the given resource as applied. It returns whether the annotation has
+// changed.
+func SetAppliedSpecHash(o metav1.Object, hash string) bool {
+	if hash == "" || o.GetAnnotations()[AnnotationKeyAppliedSpecHash] == hash {
+		return false
+	}
+	meta.AddAnnotations(o, map[string]string{AnnotationKeyAppliedSpecHash: hash})
+	return true
+}
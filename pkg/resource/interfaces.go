@@ -60,3 +60,11 @@ type Terraformed interface {
 	Parameterizable
 	LateInitializer
 }
+
+// Module is a Kubernetes object representing a Terraform module that is run
+// as a whole instead of a resource generated from the schema of a provider.
+type Module interface {
+	resource.Managed
+
+	GetModuleParameters() ModuleParameters
+}
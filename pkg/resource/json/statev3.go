@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package json
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// UnsupportedStateVersionError is returned for the Terraform states whose
+// format version can neither be read nor upgraded to version 4.
+type UnsupportedStateVersionError struct {
+	Version uint64
+}
+
+func (e *UnsupportedStateVersionError) Error() string {
+	return fmt.Sprintf("unsupported terraform state version %d: only versions 3 and 4 are supported", e.Version)
+}
+
+// IsUnsupportedStateVersion returns whether the given error is due to a
+// Terraform state whose format version is not supported.
+func IsUnsupportedStateVersion(err error) bool {
+	e := &UnsupportedStateVersionError{}
+	return errors.As(err, &e)
+}
+
+// versionedState is decoded from a state of any format version so that the
+// version 3 states can be upgraded without reading the state file twice.
+type versionedState struct {
+	StateV4
+	Modules []moduleStateV3 `json:"modules,omitempty"`
+}
+
+type moduleStateV3 struct {
+	Path      []string                   `json:"path"`
+	Resources map[string]resourceStateV3 `json:"resources"`
+}
+
+type resourceStateV3 struct {
+	Type     string            `json:"type"`
+	Provider string            `json:"provider"`
+	Primary  *instanceStateV3  `json:"primary"`
+	Deposed  []instanceStateV3 `json:"deposed"`
+}
+
+type instanceStateV3 struct {
+	ID         string                 `json:"id"`
+	Attributes map[string]string      `json:"attributes"`
+	Meta       map[string]interface{} `json:"meta"`
+	Tainted    bool                   `json:"tainted"`
+}
+
+// upgradeStateV3 upgrades the given version 3 state to version 4. Only the
+// managed resources of the root module are kept since terrajet workspaces
+// contain nothing else. The legacy flat attributes are kept for Terraform
+// to upgrade with the provider schema and their best-effort unflattened
+// form is used as the attributes until then, where all primitive values are
+// strings.
+func upgradeStateV3(v3 *versionedState) (*StateV4, error) {
+	st := &StateV4{
+		Version:          4,
+		TerraformVersion: v3.TerraformVersion,
+		Serial:           v3.Serial,
+		Lineage:          v3.Lineage,
+		RootOutputs:      map[string]OutputStateV4{},
+	}
+	for _, m := range v3.Modules {
+		if len(m.Path) != 1 || m.Path[0] != "root" {
+			continue
+		}
+		keys := make([]string, 0, len(m.Resources))
+		for k := range m.Resources {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			r := m.Resources[k]
+			// NOTE(muvaf): Data sources are prefixed with "data." and
+			// counted resources are suffixed with their index.
+			parts := strings.Split(k, ".")
+			if len(parts) < 2 || parts[0] == "data" || r.Primary == nil {
+				continue
+			}
+			attr, err := JSParser.Marshal(unflatten(r.Primary.Attributes))
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot marshal attributes of %s", k)
+			}
+			is := InstanceObjectStateV4{
+				AttributesRaw:  jsoniter.RawMessage(attr),
+				AttributesFlat: r.Primary.Attributes,
+			}
+			if v, ok := r.Primary.Meta["schema_version"].(string); ok {
+				if sv, err := strconv.ParseUint(v, 10, 64); err == nil {
+					is.SchemaVersion = sv
+				}
+			}
+			if r.Primary.Tainted {
+				is.Status = "tainted"
+			}
+			st.Resources = append(st.Resources, ResourceStateV4{
+				Mode:           "managed",
+				Type:           parts[0],
+				Name:           parts[1],
+				ProviderConfig: r.Provider,
+				Instances:      []InstanceObjectStateV4{is},
+			})
+		}
+	}
+	return st, nil
+}
+
+// unflatten converts the given attributes in the legacy flatmap format, e.g.
+// {"tags.%": "1", "tags.env": "dev", "ports.#": "1", "ports.0": "80"}, to
+// nested maps and lists.
+func unflatten(flat map[string]string) map[string]interface{} {
+	lists := map[string]bool{}
+	root := map[string]interface{}{}
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch {
+		case strings.HasSuffix(k, ".#"):
+			lists[strings.TrimSuffix(k, ".#")] = true
+			continue
+		case strings.HasSuffix(k, ".%"):
+			continue
+		}
+		m := root
+		parts := strings.Split(k, ".")
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := m[p].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				m[p] = next
+			}
+			m = next
+		}
+		m[parts[len(parts)-1]] = flat[k]
+	}
+	return toLists(root, "", lists).(map[string]interface{})
+}
+
+// toLists converts the maps at the given list paths to lists ordered by
+// their indices.
+func toLists(v interface{}, path string, lists map[string]bool) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for k, e := range m {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		m[k] = toLists(e, p, lists)
+	}
+	if !lists[path] {
+		return m
+	}
+	indices := make([]int, 0, len(m))
+	byIndex := make(map[int]interface{}, len(m))
+	for k, e := range m {
+		i, err := strconv.Atoi(k)
+		if err != nil {
+			// NOTE(muvaf): Sets are keyed by hashes of their elements
+			// rather than indices.
+			i = len(indices) + len(m)
+		}
+		indices = append(indices, i)
+		byIndex[i] = e
+	}
+	sort.Ints(indices)
+	l := make([]interface{}, len(indices))
+	for j, i := range indices {
+		l[j] = byIndex[i]
+	}
+	return l
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package json
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadLegacyState(t *testing.T) {
+	type want struct {
+		attributes  string
+		unsupported bool
+	}
+	cases := map[string]struct {
+		reason string
+		raw    string
+		want
+	}{
+		"Version3": {
+			reason: "The flat attributes of a version 3 state should be upgraded to nested attributes",
+			raw:    `{"version":3,"terraform_version":"0.11.14","serial":1,"lineage":"l","modules":[{"path":["root"],"outputs":{},"resources":{"aws_vpc.example":{"type":"aws_vpc","depends_on":[],"primary":{"id":"vpc-1","attributes":{"id":"vpc-1","cidr_block":"10.0.0.0/16","tags.%":"1","tags.env":"dev","ports.#":"2","ports.0":"80","ports.1":"443"},"meta":{"schema_version":"1"},"tainted":false},"deposed":[],"provider":"provider.aws"}},"depends_on":[]}]}`,
+			want: want{
+				attributes: `{"cidr_block":"10.0.0.0/16","id":"vpc-1","ports":["80","443"],"tags":{"env":"dev"}}`,
+			},
+		},
+		"Version2": {
+			reason: "An UnsupportedStateVersionError should be returned for states older than version 3",
+			raw:    `{"version":2,"serial":1,"modules":[]}`,
+			want: want{
+				unsupported: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			st, err := ReadStateV4(strings.NewReader(tc.raw))
+			if diff := cmp.Diff(tc.want.unsupported, IsUnsupportedStateVersion(err)); diff != "" {
+				t.Fatalf("\n%s\nReadStateV4(...): -want unsupported, +got unsupported:\n%s\nerror: %v", tc.reason, diff, err)
+			}
+			if tc.want.unsupported {
+				return
+			}
+			if err != nil {
+				t.Fatalf("\n%s\nReadStateV4(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(uint64(4), st.Version); diff != "" {
+				t.Errorf("\n%s\nReadStateV4(...): -want version, +got version:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.attributes, string(st.GetAttributes())); diff != "" {
+				t.Errorf("\n%s\nReadStateV4(...): -want attributes, +got attributes:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -17,7 +17,12 @@ limitations under the License.
 package json
 
 import (
+	"fmt"
+	"io"
+	"strings"
+
 	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
 )
 
 // NewStateV4 returns a new base StateV4 object.
@@ -28,6 +33,27 @@ func NewStateV4() *StateV4 {
 	}
 }
 
+// ReadStateV4 decodes the state read from the given reader. The state is
+// parsed while it's being read so that the whole state file, which can be
+// large for resources with huge attributes, is not buffered before parsing.
+// The attributes are kept as raw JSON to be decoded only when needed. Version
+// 3 states written by Terraform versions older than 0.12 are upgraded to
+// version 4 and an UnsupportedStateVersionError is returned for the others.
+func ReadStateV4(r io.Reader) (*StateV4, error) {
+	st := &versionedState{}
+	if err := JSParser.NewDecoder(r).Decode(st); err != nil {
+		return nil, err
+	}
+	switch st.Version {
+	case 4:
+		return &st.StateV4, nil
+	case 3:
+		return upgradeStateV3(st)
+	default:
+		return nil, &UnsupportedStateVersionError{Version: st.Version}
+	}
+}
+
 // State file schema from https://github.com/hashicorp/terraform/blob/d9dfd451ea572219871bb9c5503a471418258e40/internal/states/statefile/version4.go
 
 // StateV4 represents a version 4 terraform state
@@ -76,27 +102,153 @@ type InstanceObjectStateV4 struct {
 	CreateBeforeDestroy bool `json:"create_before_destroy,omitempty"`
 }
 
-// GetAttributes returns attributes of the Terraform managed resource (i.e. first instance of first resource)
-func (st *StateV4) GetAttributes() jsoniter.RawMessage {
+// GetInstance returns the instance of the Terraform managed resource (i.e.
+// first instance of first resource), or nil if there is none.
+func (st *StateV4) GetInstance() *InstanceObjectStateV4 {
 	if st == nil || len(st.Resources) == 0 || len(st.Resources[0].Instances) == 0 {
 		return nil
 	}
-	return st.Resources[0].Instances[0].AttributesRaw
+	return &st.Resources[0].Instances[0]
+}
+
+// GetAttributes returns attributes of the Terraform managed resource (i.e. first instance of first resource)
+func (st *StateV4) GetAttributes() jsoniter.RawMessage {
+	if i := st.GetInstance(); i != nil {
+		return i.AttributesRaw
+	}
+	return nil
 }
 
 // GetSensitiveAttributes returns sensitive attributes of the Terraform managed resource (i.e. first instance of first resource)
 func (st *StateV4) GetSensitiveAttributes() jsoniter.RawMessage {
-	if st == nil || len(st.Resources) == 0 || len(st.Resources[0].Instances) == 0 {
-		return nil
+	if i := st.GetInstance(); i != nil {
+		return i.AttributeSensitivePaths
 	}
-	return st.Resources[0].Instances[0].AttributeSensitivePaths
+	return nil
 }
 
 // GetPrivateRaw returns private attribute of the Terraform managed resource
 // that is used as metadata by the Terraform provider
 func (st *StateV4) GetPrivateRaw() []byte {
-	if st == nil || len(st.Resources) == 0 || len(st.Resources[0].Instances) == 0 {
-		return nil
+	if i := st.GetInstance(); i != nil {
+		return i.PrivateRaw
+	}
+	return nil
+}
+
+// DecodeAttributes returns the decoded attributes of the Terraform managed
+// resource (i.e. first instance of first resource).
+func (st *StateV4) DecodeAttributes() (map[string]interface{}, error) {
+	attr := map[string]interface{}{}
+	err := JSParser.Unmarshal(st.GetAttributes(), &attr)
+	return attr, err
+}
+
+// DecodeOutputs returns the decoded values of the root module outputs.
+func (st *StateV4) DecodeOutputs() (map[string]interface{}, error) {
+	if st == nil || len(st.RootOutputs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, len(st.RootOutputs))
+	for name, o := range st.RootOutputs {
+		var v interface{}
+		if err := JSParser.Unmarshal(o.ValueRaw, &v); err != nil {
+			return nil, errors.Wrapf(err, "cannot decode output %s", name)
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
+// DecodeAttributes returns the decoded attributes of the instance.
+func (i *InstanceObjectStateV4) DecodeAttributes() (map[string]interface{}, error) {
+	attr := map[string]interface{}{}
+	err := JSParser.Unmarshal(i.AttributesRaw, &attr)
+	return attr, err
+}
+
+// GetAttribute returns the decoded value of the top-level attribute of the
+// instance with the given key, or nil if there is no such attribute. The
+// raw attributes are iterated without decoding the other attributes, which
+// is cheaper than decoding all of them to read a single one, e.g. "id".
+func (i *InstanceObjectStateV4) GetAttribute(key string) (interface{}, error) {
+	if len(i.AttributesRaw) == 0 {
+		return nil, nil
+	}
+	iter := JSParser.BorrowIterator(i.AttributesRaw)
+	defer JSParser.ReturnIterator(iter)
+	var v interface{}
+	iter.ReadObjectCB(func(iter *jsoniter.Iterator, k string) bool {
+		if k != key {
+			iter.Skip()
+			return true
+		}
+		v = iter.Read()
+		return false
+	})
+	if iter.Error != nil && iter.Error != io.EOF {
+		return nil, errors.Wrapf(iter.Error, "cannot read attribute %s", key)
+	}
+	return v, nil
+}
+
+// DecodePrivate returns the decoded private metadata the Terraform provider
+// stores in the instance, e.g. the operation timeouts. It returns nil if
+// the instance has no private metadata.
+func (i *InstanceObjectStateV4) DecodePrivate() (map[string]interface{}, error) {
+	if len(i.PrivateRaw) == 0 {
+		return nil, nil
+	}
+	p := map[string]interface{}{}
+	err := JSParser.Unmarshal(i.PrivateRaw, &p)
+	return p, err
+}
+
+// sensitivePathStep is a step of a path in the sensitive attributes of an
+// instance, e.g. {"type":"get_attr","value":"password"} or
+// {"type":"index","value":{"value":0,"type":"number"}}.
+type sensitivePathStep struct {
+	Type  string              `json:"type"`
+	Value jsoniter.RawMessage `json:"value"`
+}
+
+// DecodeSensitivePaths returns the paths of the sensitive attributes of the
+// instance as field paths, e.g. "password" or "user[0].password".
+func (i *InstanceObjectStateV4) DecodeSensitivePaths() ([]string, error) {
+	if len(i.AttributeSensitivePaths) == 0 {
+		return nil, nil
+	}
+	var raw [][]sensitivePathStep
+	if err := JSParser.Unmarshal(i.AttributeSensitivePaths, &raw); err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(raw))
+	for _, steps := range raw {
+		var sb strings.Builder
+		for _, s := range steps {
+			switch s.Type {
+			case "get_attr":
+				var name string
+				if err := JSParser.Unmarshal(s.Value, &name); err != nil {
+					return nil, errors.Wrap(err, "cannot decode attribute step")
+				}
+				if sb.Len() > 0 {
+					sb.WriteString(".")
+				}
+				sb.WriteString(name)
+			case "index":
+				key := struct {
+					Value interface{} `json:"value"`
+				}{}
+				if err := JSParser.Unmarshal(s.Value, &key); err != nil {
+					return nil, errors.Wrap(err, "cannot decode index step")
+				}
+				sb.WriteString(fmt.Sprintf("[%v]", key.Value))
+			default:
+				return nil, errors.Errorf("unknown sensitive path step type %q", s.Type)
+			}
+		}
+		paths = append(paths, sb.String())
 	}
-	return st.Resources[0].Instances[0].PrivateRaw
+	return paths, nil
 }
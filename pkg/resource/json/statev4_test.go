@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package json
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecodeSensitivePaths(t *testing.T) {
+	type want struct {
+		paths []string
+		err   bool
+	}
+	cases := map[string]struct {
+		reason string
+		raw    string
+		want
+	}{
+		"Empty": {
+			reason: "No paths should be returned if there are no sensitive attributes",
+		},
+		"Paths": {
+			reason: "Attribute and index steps should be converted to field paths",
+			raw:    `[[{"type":"get_attr","value":"password"}],[{"type":"get_attr","value":"user"},{"type":"index","value":{"value":0,"type":"number"}},{"type":"get_attr","value":"secret"}],[{"type":"get_attr","value":"tags"},{"type":"index","value":{"value":"token","type":"string"}}]]`,
+			want: want{
+				paths: []string{"password", "user[0].secret", "tags[token]"},
+			},
+		},
+		"UnknownStep": {
+			reason: "An error should be returned for an unknown step type",
+			raw:    `[[{"type":"splat","value":"x"}]]`,
+			want: want{
+				err: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			i := &InstanceObjectStateV4{AttributeSensitivePaths: []byte(tc.raw)}
+			got, err := i.DecodeSensitivePaths()
+			if (err != nil) != tc.want.err {
+				t.Fatalf("\n%s\nDecodeSensitivePaths(): unexpected error: %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.paths, got); diff != "" {
+				t.Errorf("\n%s\nDecodeSensitivePaths(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestStateV4DecodeAttributes(t *testing.T) {
+	st := &StateV4{Resources: []ResourceStateV4{{Instances: []InstanceObjectStateV4{{
+		AttributesRaw: []byte(`{"id":"vpc-1","cidr_block":"10.0.0.0/16"}`),
+		PrivateRaw:    []byte(`{"schema_version":"1"}`),
+	}}}}}
+	attr, err := st.DecodeAttributes()
+	if err != nil {
+		t.Fatalf("DecodeAttributes(): unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"id": "vpc-1", "cidr_block": "10.0.0.0/16"}, attr); diff != "" {
+		t.Errorf("DecodeAttributes(): -want, +got:\n%s", diff)
+	}
+	p, err := st.GetInstance().DecodePrivate()
+	if err != nil {
+		t.Fatalf("DecodePrivate(): unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"schema_version": "1"}, p); diff != "" {
+		t.Errorf("DecodePrivate(): -want, +got:\n%s", diff)
+	}
+}
+
+func TestReadStateV4(t *testing.T) {
+	raw := `{"version":4,"terraform_version":"1.2.1","serial":3,"lineage":"l","outputs":{},"resources":[{"mode":"managed","type":"aws_vpc","name":"example","provider":"provider","instances":[{"schema_version":1,"attributes":{"id":"vpc-1","tags":{"a":"b"}},"private":"eyJzY2hlbWFfdmVyc2lvbiI6IjEifQ=="}]}]}`
+	st, err := ReadStateV4(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ReadStateV4(...): unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(`{"id":"vpc-1","tags":{"a":"b"}}`, string(st.GetAttributes())); diff != "" {
+		t.Errorf("ReadStateV4(...): -want attributes, +got attributes:\n%s", diff)
+	}
+	if diff := cmp.Diff(`{"schema_version":"1"}`, string(st.GetPrivateRaw())); diff != "" {
+		t.Errorf("ReadStateV4(...): -want private, +got private:\n%s", diff)
+	}
+}
+
+func TestGetAttribute(t *testing.T) {
+	i := &InstanceObjectStateV4{AttributesRaw: []byte(`{"policy":{"Statement":[{"Effect":"Allow"}]},"id":"role-1","arn":"arn:aws:iam::123456789012:role/role-1"}`)}
+	cases := map[string]struct {
+		reason string
+		key    string
+		want   interface{}
+	}{
+		"Found": {
+			reason: "The value of the attribute should be returned skipping the ones before it",
+			key:    "id",
+			want:   "role-1",
+		},
+		"Object": {
+			reason: "Object attributes should be decoded",
+			key:    "policy",
+			want:   map[string]interface{}{"Statement": []interface{}{map[string]interface{}{"Effect": "Allow"}}},
+		},
+		"NotFound": {
+			reason: "Nil should be returned if there is no such attribute",
+			key:    "name",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := i.GetAttribute(tc.key)
+			if err != nil {
+				t.Fatalf("\n%s\nGetAttribute(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nGetAttribute(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -56,23 +56,39 @@ type GenericLateInitializer struct {
 }
 
 // SetCriticalAnnotations sets the critical annotations of the resource and reports
-// whether there has been a change.
+// whether there has been a change. The external name is set to the one
+// extracted from the given state unless it's disabled in the configuration of
+// the resource.
 func SetCriticalAnnotations(tr metav1.Object, cfg *config.Resource, tfstate map[string]interface{}, privateRaw string) (bool, error) {
-	name, err := cfg.ExternalName.GetExternalNameFn(tfstate)
+	name, err := externalNameFromState(tr, cfg, tfstate)
 	if err != nil {
-		return false, errors.Wrap(err, "cannot get external name")
+		return false, err
 	}
 	if tr.GetAnnotations()[AnnotationKeyPrivateRawAttribute] == privateRaw &&
 		tr.GetAnnotations()[xpmeta.AnnotationKeyExternalName] == name {
 		return false, nil
 	}
-	xpmeta.AddAnnotations(tr, map[string]string{
+	annotations := map[string]string{
 		AnnotationKeyPrivateRawAttribute: privateRaw,
-		xpmeta.AnnotationKeyExternalName: name,
-	})
+	}
+	if name != "" {
+		annotations[xpmeta.AnnotationKeyExternalName] = name
+	}
+	xpmeta.AddAnnotations(tr, annotations)
 	return true, nil
 }
 
+// externalNameFromState returns the external name of the resource extracted
+// from the given state, or its current external name if writing it from the
+// state is disabled in the configuration of the resource.
+func externalNameFromState(tr metav1.Object, cfg *config.Resource, tfstate map[string]interface{}) (string, error) {
+	if cfg.ExternalName.DisableNameFromState {
+		return xpmeta.GetExternalName(tr), nil
+	}
+	name, err := cfg.ExternalName.GetExternalNameFn(tfstate)
+	return name, errors.Wrap(err, "cannot get external name")
+}
+
 // GenericLateInitializerOption are options that control the late-initialization
 // behavior of a Terraformed resource.
 type GenericLateInitializerOption func(l *GenericLateInitializer)
@@ -271,6 +287,13 @@ func (li *GenericLateInitializer) handleStruct(parentName string, desiredObject
 		if err != nil {
 			return false, err
 		}
+		// NOTE(muvaf): A pointer, slice or map is allocated before its
+		// elements are late-initialized. If none of them is, we reset it so
+		// that the desired object is left untouched when no change is
+		// reported, e.g. an empty block is not added to the configuration.
+		if !desiredKeepField && !desiredFieldValue.IsZero() {
+			desiredFieldValue.Set(reflect.Zero(desiredStructField.Type))
+		}
 
 		fieldAssigned = fieldAssigned || desiredKeepField
 	}
@@ -318,6 +341,13 @@ func (li *GenericLateInitializer) handleSlice(cName string, desiredFieldValue, o
 	v := desiredFieldValue.Interface()
 	desiredFieldValue.Set(reflect.MakeSlice(reflect.ValueOf(&v).Elem().Elem().Type(), 0, observedFieldValue.Len()))
 
+	// NOTE(muvaf): The items are matched by their positions, so an item
+	// none of whose fields is late-initialized is still appended to keep the
+	// positions of the others. The slice is kept only if it's empty or at
+	// least one of its items is late-initialized, otherwise handleStruct
+	// resets it.
+	itemAssigned := observedFieldValue.Len() == 0
+
 	// then cr object's field is not set but response object contains a value, carry it
 	// copy slice items from response field
 	for i := 0; i < observedFieldValue.Len(); i++ {
@@ -325,19 +355,21 @@ func (li *GenericLateInitializer) handleSlice(cName string, desiredFieldValue, o
 		item := reflect.New(desiredFieldValue.Type().Elem())
 		// error from processing the next element of the slice
 		var err error
+		assigned := false
 		// check slice item's kind (not slice type)
 		switch item.Elem().Kind() { // nolint:exhaustive
 		// if dealing with a slice of pointers
 		case reflect.Ptr:
-			_, err = li.handlePtr(cName, item.Elem(), observedFieldValue.Index(i))
+			assigned, err = li.handlePtr(cName, item.Elem(), observedFieldValue.Index(i))
 		case reflect.Struct:
-			_, err = li.handleStruct(cName, item.Interface(), observedFieldValue.Index(i).Addr().Interface())
+			assigned, err = li.handleStruct(cName, item.Interface(), observedFieldValue.Index(i).Addr().Interface())
 		case reflect.String, reflect.Bool, reflect.Int, reflect.Uint,
 			reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 			reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
 			reflect.Float32, reflect.Float64:
 			// set primitive type
 			item.Elem().Set(observedFieldValue.Index(i))
+			assigned = true
 		// other slice item types are not supported
 		default:
 			return false, errors.Errorf("slice items of kind %q is not supported for canonical name: %s",
@@ -349,8 +381,9 @@ func (li *GenericLateInitializer) handleSlice(cName string, desiredFieldValue, o
 		}
 		// a new item has been allocated, expand the slice with it
 		desiredFieldValue.Set(reflect.Append(desiredFieldValue, item.Elem()))
+		itemAssigned = itemAssigned || assigned
 	}
-	return true, nil
+	return itemAssigned, nil
 }
 
 func (li *GenericLateInitializer) handleMap(cName string, desiredFieldValue, observedFieldValue reflect.Value) (bool, error) {
@@ -361,6 +394,11 @@ func (li *GenericLateInitializer) handleMap(cName string, desiredFieldValue, obs
 	v := desiredFieldValue.Interface()
 	desiredFieldValue.Set(reflect.MakeMap(reflect.ValueOf(&v).Elem().Elem().Type()))
 
+	// The map is kept only if at least one of its values is late-initialized,
+	// or it's empty. The values none of whose fields is late-initialized are
+	// left out.
+	valueAssigned := observedFieldValue.Len() == 0
+
 	// then cr object's field is not set but response object contains a value, carry it
 	// copy map items from response field
 	for _, k := range observedFieldValue.MapKeys() {
@@ -368,20 +406,22 @@ func (li *GenericLateInitializer) handleMap(cName string, desiredFieldValue, obs
 		item := reflect.New(desiredFieldValue.Type().Elem())
 		// error from processing the next element of the map
 		var err error
+		assigned := false
 		// check map item's kind (not map type)
 		switch item.Elem().Kind() { // nolint:exhaustive
 		// if dealing with a slice of pointers
 		case reflect.Ptr:
-			_, err = li.handlePtr(cName, item.Elem(), observedFieldValue.MapIndex(k))
+			assigned, err = li.handlePtr(cName, item.Elem(), observedFieldValue.MapIndex(k))
 		// else if dealing with a slice of slices
 		case reflect.Slice:
-			_, err = li.handleSlice(cName, item.Elem(), observedFieldValue.MapIndex(k))
+			assigned, err = li.handleSlice(cName, item.Elem(), observedFieldValue.MapIndex(k))
 		case reflect.String, reflect.Bool, reflect.Int, reflect.Uint,
 			reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 			reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
 			reflect.Float32, reflect.Float64:
 			// set primitive type
 			item.Elem().Set(observedFieldValue.MapIndex(k))
+			assigned = true
 		// other slice item types are not supported
 		default:
 			return false, errors.Errorf(errFmtMapElemNotSupported, item.Elem().Kind().String(), cName)
@@ -389,11 +429,15 @@ func (li *GenericLateInitializer) handleMap(cName string, desiredFieldValue, obs
 		if err != nil {
 			return false, err
 		}
+		if !assigned {
+			continue
+		}
 		// set value at current key
 		desiredFieldValue.SetMapIndex(k, item.Elem())
+		valueAssigned = true
 	}
 
-	return true, nil
+	return valueAssigned, nil
 }
 
 func getCanonicalName(parent, child string) string {
@@ -19,7 +19,13 @@ package resource
 import (
 	"testing"
 
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/terrajet/pkg/config"
 )
 
 func TestLateInitialize(t *testing.T) {
@@ -257,6 +263,94 @@ func TestLateInitialize(t *testing.T) {
 				},
 			},
 		},
+		"TestFilteredObservedNestedFields": {
+			args: args{
+				desiredObject: &struct {
+					C1 *nestedStruct10
+				}{},
+				observedObject: &struct {
+					C1 *nestedStruct10
+				}{
+					C1: &nestedStruct10{
+						F1: &testStringObservedField,
+					},
+				},
+				opts: []GenericLateInitializerOption{WithNameFilter("C1.F1")},
+			},
+			wantModified: false,
+			wantCRObject: &struct {
+				C1 *nestedStruct10
+			}{},
+		},
+		"TestFilteredObservedSliceItemFields": {
+			args: args{
+				desiredObject: &struct {
+					C1 []*nestedStruct10
+				}{},
+				observedObject: &struct {
+					C1 []*nestedStruct10
+				}{
+					C1: []*nestedStruct10{
+						{F1: &testStringObservedField},
+						{F1: &testStringObservedField},
+					},
+				},
+				opts: []GenericLateInitializerOption{WithNameFilter("C1.F1")},
+			},
+			wantModified: false,
+			wantCRObject: &struct {
+				C1 []*nestedStruct10
+			}{},
+		},
+		"TestPartiallyFilteredObservedSliceItemFields": {
+			args: args{
+				desiredObject: &struct {
+					C1 []nestedStruct3
+				}{},
+				observedObject: &struct {
+					C1 []nestedStruct3
+				}{
+					C1: []nestedStruct3{
+						{F1: &testStringObservedField},
+						{F2: &testStringObservedField},
+					},
+				},
+				opts: []GenericLateInitializerOption{WithNameFilter("C1.F1")},
+			},
+			wantModified: true,
+			wantCRObject: &struct {
+				C1 []nestedStruct3
+			}{
+				C1: []nestedStruct3{
+					{},
+					{F2: &testStringObservedField},
+				},
+			},
+		},
+		"TestFilteredObservedMapValueFields": {
+			args: args{
+				desiredObject: &struct {
+					C1 map[string]*nestedStruct3
+				}{},
+				observedObject: &struct {
+					C1 map[string]*nestedStruct3
+				}{
+					C1: map[string]*nestedStruct3{
+						"a": {F1: &testStringObservedField},
+						"b": {F2: &testStringObservedField},
+					},
+				},
+				opts: []GenericLateInitializerOption{WithNameFilter("C1.F1")},
+			},
+			wantModified: true,
+			wantCRObject: &struct {
+				C1 map[string]*nestedStruct3
+			}{
+				C1: map[string]*nestedStruct3{
+					"b": {F2: &testStringObservedField},
+				},
+			},
+		},
 		"TestFieldKindMismatch": {
 			args: args{
 				desiredObject: &nestedStruct1{
@@ -550,3 +644,118 @@ func TestLateInitialize(t *testing.T) {
 		})
 	}
 }
+
+func TestSetCriticalAnnotations(t *testing.T) {
+	type args struct {
+		annotations map[string]string
+		cfg         *config.Resource
+		tfstate     map[string]interface{}
+		privateRaw  string
+	}
+	type want struct {
+		updated     bool
+		annotations map[string]string
+		err         error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NameFromID": {
+			reason: "The external name should be set to the id in the state after the first apply.",
+			args: args{
+				cfg:        &config.Resource{ExternalName: config.IdentifierFromProvider},
+				tfstate:    map[string]interface{}{"id": "vpc-123"},
+				privateRaw: "raw",
+			},
+			want: want{
+				updated: true,
+				annotations: map[string]string{
+					xpmeta.AnnotationKeyExternalName: "vpc-123",
+					AnnotationKeyPrivateRawAttribute: "raw",
+				},
+			},
+		},
+		"NoChange": {
+			reason: "No change should be reported if the annotations are up to date.",
+			args: args{
+				annotations: map[string]string{
+					xpmeta.AnnotationKeyExternalName: "vpc-123",
+					AnnotationKeyPrivateRawAttribute: "raw",
+				},
+				cfg:        &config.Resource{ExternalName: config.IdentifierFromProvider},
+				tfstate:    map[string]interface{}{"id": "vpc-123"},
+				privateRaw: "raw",
+			},
+			want: want{
+				annotations: map[string]string{
+					xpmeta.AnnotationKeyExternalName: "vpc-123",
+					AnnotationKeyPrivateRawAttribute: "raw",
+				},
+			},
+		},
+		"NoID": {
+			reason: "An error should be returned if the external name cannot be extracted from the state.",
+			args: args{
+				cfg:     &config.Resource{ExternalName: config.IdentifierFromProvider},
+				tfstate: map[string]interface{}{},
+			},
+			want: want{
+				err: errors.Wrap(errors.New("cannot find id in tfstate"), "cannot get external name"),
+			},
+		},
+		"NameFromStateDisabled": {
+			reason: "The external name should be left as it is if writing it from the state is disabled.",
+			args: args{
+				annotations: map[string]string{
+					xpmeta.AnnotationKeyExternalName: "my-name",
+				},
+				cfg: &config.Resource{ExternalName: config.ExternalName{
+					GetExternalNameFn:    config.IDAsExternalName,
+					DisableNameFromState: true,
+				}},
+				tfstate:    map[string]interface{}{},
+				privateRaw: "raw",
+			},
+			want: want{
+				updated: true,
+				annotations: map[string]string{
+					xpmeta.AnnotationKeyExternalName: "my-name",
+					AnnotationKeyPrivateRawAttribute: "raw",
+				},
+			},
+		},
+		"NameFromStateDisabledWithoutName": {
+			reason: "No empty external name should be set if writing it from the state is disabled.",
+			args: args{
+				cfg:        &config.Resource{ExternalName: config.ExternalName{DisableNameFromState: true}},
+				privateRaw: "raw",
+			},
+			want: want{
+				updated: true,
+				annotations: map[string]string{
+					AnnotationKeyPrivateRawAttribute: "raw",
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &metav1.ObjectMeta{Annotations: tc.args.annotations}
+			updated, err := SetCriticalAnnotations(o, tc.args.cfg, tc.args.tfstate, tc.args.privateRaw)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nSetCriticalAnnotations(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if tc.want.err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.updated, updated); diff != "" {
+				t.Errorf("\n%s\nSetCriticalAnnotations(...): -want updated, +got updated:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.annotations, o.GetAnnotations()); diff != "" {
+				t.Errorf("\n%s\nSetCriticalAnnotations(...): -want annotations, +got annotations:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
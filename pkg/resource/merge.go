@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+// MergeOption configures how the observed values are merged into the desired
+// ones.
+type MergeOption func(*merger)
+
+// WithMergeIgnoredField makes the merge skip the field with the given
+// Terraform field path, e.g. "block_device_mappings.ebs".
+func WithMergeIgnoredField(path string) MergeOption {
+	return func(m *merger) {
+		m.ignored[path] = true
+	}
+}
+
+// WithListMergeKey makes the merge match the elements of the list with the
+// given Terraform field path by the value of their key field instead of
+// leaving a desired list as is. The matching elements are merged recursively
+// while the observed elements that do not have a match are never added, since
+// they may have been removed from the desired list by the user.
+func WithListMergeKey(path, key string) MergeOption {
+	return func(m *merger) {
+		m.listKeys[path] = key
+	}
+}
+
+type merger struct {
+	ignored  map[string]bool
+	listKeys map[string]string
+}
+
+// Merge deep-merges the observed values into the desired ones in the form of
+// Terraform JSON and reports whether the desired values have changed. A
+// field is set to its observed value only if it is absent or null in the
+// desired values, maps are merged key by key and lists are merged by the
+// keys configured with WithListMergeKey. Desired values, including explicit
+// zero values like false, 0 or "", are never overwritten. The merged values
+// may be shared between desired and observed.
+func Merge(desired, observed map[string]interface{}, opts ...MergeOption) bool {
+	m := &merger{
+		ignored:  map[string]bool{},
+		listKeys: map[string]string{},
+	}
+	for _, f := range opts {
+		f(m)
+	}
+	return m.mergeMap("", desired, observed)
+}
+
+func (m *merger) mergeMap(parent string, desired, observed map[string]interface{}) bool {
+	changed := false
+	for k, o := range observed {
+		path := getFieldPath(parent, k)
+		if m.ignored[path] || o == nil {
+			continue
+		}
+		d, ok := desired[k]
+		if !ok || d == nil {
+			desired[k] = o
+			changed = true
+			continue
+		}
+		switch dv := d.(type) {
+		case map[string]interface{}:
+			if ov, ok := o.(map[string]interface{}); ok {
+				changed = m.mergeMap(path, dv, ov) || changed
+			}
+		case []interface{}:
+			if ov, ok := o.([]interface{}); ok {
+				var listChanged bool
+				desired[k], listChanged = m.mergeList(path, dv, ov)
+				changed = listChanged || changed
+			}
+		}
+	}
+	return changed
+}
+
+func (m *merger) mergeList(path string, desired, observed []interface{}) ([]interface{}, bool) {
+	key, ok := m.listKeys[path]
+	if !ok {
+		return desired, false
+	}
+	changed := false
+	for _, o := range observed {
+		ov, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, d := range desired {
+			dv, ok := d.(map[string]interface{})
+			if !ok || !reflect.DeepEqual(dv[key], ov[key]) {
+				continue
+			}
+			changed = m.mergeMap(path, dv, ov) || changed
+			break
+		}
+	}
+	return desired, changed
+}
+
+func getFieldPath(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
+
+// MergeParameters merges the given observed parameters into the parameters
+// of the given resource and reports whether they have changed. The observed
+// parameters are usually the observed Terraform state unmarshalled into the
+// parameters type of the resource, so that only the parameter fields are
+// merged.
+func MergeParameters(p Parameterizable, observed interface{}, opts ...MergeOption) (bool, error) {
+	desired, err := p.GetParameters()
+	if err != nil {
+		return false, errors.Wrap(err, "cannot get parameters")
+	}
+	raw, err := json.TFParser.Marshal(observed)
+	if err != nil {
+		return false, errors.Wrap(err, "cannot marshal observed parameters")
+	}
+	o := map[string]interface{}{}
+	if err := json.TFParser.Unmarshal(raw, &o); err != nil {
+		return false, errors.Wrap(err, "cannot unmarshal observed parameters")
+	}
+	if !Merge(desired, o, opts...) {
+		return false, nil
+	}
+	return true, errors.Wrap(p.SetParameters(desired), "cannot set parameters")
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+)
+
+func TestMerge(t *testing.T) {
+	type args struct {
+		desired  map[string]interface{}
+		observed map[string]interface{}
+		opts     []MergeOption
+	}
+	type want struct {
+		desired map[string]interface{}
+		changed bool
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"ScalarsIfUnset": {
+			reason: "Absent and null scalars should be initialized while the desired ones, including the zero values, are kept",
+			args: args{
+				desired:  map[string]interface{}{"name": "desired", "size": 0.0, "public": false, "zone": nil},
+				observed: map[string]interface{}{"name": "observed", "size": 10.0, "public": true, "zone": "a", "enabled": false},
+			},
+			want: want{
+				desired: map[string]interface{}{"name": "desired", "size": 0.0, "public": false, "zone": "a", "enabled": false},
+				changed: true,
+			},
+		},
+		"Maps": {
+			reason: "Maps should be merged key by key",
+			args: args{
+				desired:  map[string]interface{}{"tags": map[string]interface{}{"a": "desired"}},
+				observed: map[string]interface{}{"tags": map[string]interface{}{"a": "observed", "b": "observed"}},
+			},
+			want: want{
+				desired: map[string]interface{}{"tags": map[string]interface{}{"a": "desired", "b": "observed"}},
+				changed: true,
+			},
+		},
+		"ListWithoutKey": {
+			reason: "Desired lists without a key should be kept as is",
+			args: args{
+				desired:  map[string]interface{}{"rule": []interface{}{map[string]interface{}{"name": "a"}}},
+				observed: map[string]interface{}{"rule": []interface{}{map[string]interface{}{"name": "a", "port": 80.0}}},
+			},
+			want: want{
+				desired: map[string]interface{}{"rule": []interface{}{map[string]interface{}{"name": "a"}}},
+			},
+		},
+		"ListByKey": {
+			reason: "Elements of lists with a key should be merged by the key while the observed ones without a match are not added",
+			args: args{
+				desired: map[string]interface{}{"rule": []interface{}{map[string]interface{}{"name": "a"}}},
+				observed: map[string]interface{}{"rule": []interface{}{
+					map[string]interface{}{"name": "b", "port": 443.0},
+					map[string]interface{}{"name": "a", "port": 80.0},
+				}},
+				opts: []MergeOption{WithListMergeKey("rule", "name")},
+			},
+			want: want{
+				desired: map[string]interface{}{"rule": []interface{}{
+					map[string]interface{}{"name": "a", "port": 80.0},
+				}},
+				changed: true,
+			},
+		},
+		"IgnoredField": {
+			reason: "Ignored fields should not be merged",
+			args: args{
+				desired:  map[string]interface{}{"block": map[string]interface{}{}},
+				observed: map[string]interface{}{"block": map[string]interface{}{"a": "observed"}, "b": "observed"},
+				opts:     []MergeOption{WithMergeIgnoredField("block")},
+			},
+			want: want{
+				desired: map[string]interface{}{"block": map[string]interface{}{}, "b": "observed"},
+				changed: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			changed := Merge(tc.args.desired, tc.args.observed, tc.args.opts...)
+			if diff := cmp.Diff(tc.want.changed, changed); diff != "" {
+				t.Errorf("\n%s\nMerge(...): -want changed, +got changed:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.desired, tc.args.desired); diff != "" {
+				t.Errorf("\n%s\nMerge(...): -want desired, +got desired:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestMergeParameters(t *testing.T) {
+	type observed struct {
+		Name *string `tf:"name,omitempty"`
+		Size *int    `tf:"size,omitempty"`
+	}
+	name, size := "observed", 10
+	p := &fake.Parameterizable{Parameters: map[string]interface{}{"name": "desired"}}
+	changed, err := MergeParameters(p, &observed{Name: &name, Size: &size})
+	if err != nil {
+		t.Fatalf("MergeParameters(...): unexpected error: %s", err)
+	}
+	if !changed {
+		t.Errorf("MergeParameters(...): expected parameters to be changed")
+	}
+	if diff := cmp.Diff(map[string]interface{}{"name": "desired", "size": 10.0}, p.Parameters); diff != "" {
+		t.Errorf("MergeParameters(...): -want parameters, +got parameters:\n%s", diff)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+// ModuleFormat is the syntax of the content of an inline module.
+type ModuleFormat string
+
+// The syntaxes of the inline modules.
+const (
+	ModuleFormatHCL  ModuleFormat = "HCL"
+	ModuleFormatJSON ModuleFormat = "JSON"
+)
+
+// ModuleParameters are the parameters of a managed resource that runs a
+// Terraform module as a whole. They are meant to be embedded in the
+// forProvider field of such a kind, so that the resources terrajet hasn't
+// generated a kind for can still be managed. The modules they can run are restricted
+// by the controller.ModulePolicy of their controller.
+type ModuleParameters struct {
+	// Module is the content of the root module, in the syntax given in
+	// Format. The provider is configured from the ProviderConfig of the
+	// resource, so the module should not configure it. Exactly one of Module
+	// and Source needs to be set.
+	// +optional
+	Module string `json:"module,omitempty"`
+
+	// Format is the syntax of the inline module.
+	// +kubebuilder:validation:Enum=HCL;JSON
+	// +kubebuilder:default=HCL
+	// +optional
+	Format ModuleFormat `json:"format,omitempty"`
+
+	// Source is the address of a remote module, e.g.
+	// "git::https://example.com/network.git?ref=v1.2.0", that is called
+	// with the variables.
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// Vars are the values of the input variables of the module.
+	// +optional
+	Vars []ModuleVar `json:"vars,omitempty"`
+
+	// Outputs are the names of the outputs of the remote module that are
+	// published as connection details. All outputs of an inline module are
+	// published.
+	// +optional
+	Outputs []string `json:"outputs,omitempty"`
+}
+
+// ModuleVar is the value of an input variable of a module.
+type ModuleVar struct {
+	// Key is the name of the variable.
+	Key string `json:"key"`
+	// Value of the variable. Terraform converts it to the type of the
+	// variable.
+	Value string `json:"value"`
+}
+
+// DeepCopyInto copies the receiver into out. Since this package is not
+// processed by controller-gen, it's written by hand so that the kinds
+// embedding ModuleParameters can have their deep copy functions generated.
+func (in *ModuleParameters) DeepCopyInto(out *ModuleParameters) {
+	*out = *in
+	if in.Vars != nil {
+		out.Vars = make([]ModuleVar, len(in.Vars))
+		copy(out.Vars, in.Vars)
+	}
+	if in.Outputs != nil {
+		out.Outputs = make([]string, len(in.Outputs))
+		copy(out.Outputs, in.Outputs)
+	}
+}
+
+// DeepCopy returns a deep copy of the receiver.
+func (in *ModuleParameters) DeepCopy() *ModuleParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(ModuleParameters)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+// CapObservationLists returns a shallow copy of the given Terraform state
+// attributes in which the list attributes with a cap keep only their first
+// elements up to the cap. The total number of elements of each capped list
+// is reported in the "<attribute>_count" key. The given attributes are not
+// modified.
+func CapObservationLists(attr map[string]interface{}, caps map[string]int) map[string]interface{} {
+	if len(caps) == 0 {
+		return attr
+	}
+	result := make(map[string]interface{}, len(attr))
+	for k, v := range attr {
+		result[k] = v
+	}
+	for k, c := range caps {
+		l, ok := attr[k].([]interface{})
+		if !ok {
+			continue
+		}
+		result[k+"_count"] = int64(len(l))
+		if len(l) > c {
+			result[k] = l[:c]
+		}
+	}
+	return result
+}
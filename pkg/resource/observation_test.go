@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCapObservationLists(t *testing.T) {
+	type args struct {
+		attr map[string]interface{}
+		caps map[string]int
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want map[string]interface{}
+	}{
+		"NoCaps": {
+			reason: "Attributes should be returned as is if there are no caps",
+			args: args{
+				attr: map[string]interface{}{"rules": []interface{}{"a", "b"}},
+			},
+			want: map[string]interface{}{"rules": []interface{}{"a", "b"}},
+		},
+		"Capped": {
+			reason: "Lists longer than their cap should be truncated and their total counts reported",
+			args: args{
+				attr: map[string]interface{}{"rules": []interface{}{"a", "b", "c"}, "name": "n"},
+				caps: map[string]int{"rules": 2},
+			},
+			want: map[string]interface{}{"rules": []interface{}{"a", "b"}, "rules_count": int64(3), "name": "n"},
+		},
+		"UnderCap": {
+			reason: "Lists shorter than their cap should be kept as is with their counts reported",
+			args: args{
+				attr: map[string]interface{}{"rules": []interface{}{"a"}},
+				caps: map[string]int{"rules": 2},
+			},
+			want: map[string]interface{}{"rules": []interface{}{"a"}, "rules_count": int64(1)},
+		},
+		"Missing": {
+			reason: "Caps of attributes that do not exist should be ignored",
+			args: args{
+				attr: map[string]interface{}{"name": "n"},
+				caps: map[string]int{"rules": 2},
+			},
+			want: map[string]interface{}{"name": "n"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := CapObservationLists(tc.args.attr, tc.args.caps)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nCapObservationLists(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
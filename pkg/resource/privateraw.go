@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/config"
+)
+
+const (
+	errSetPrivateRaw = "cannot store private attributes"
+)
+
+// PrivateRawStore stores the private attributes of the Terraform states of
+// the resources, which the Terraform providers use to keep metadata such as
+// the schema version and the timeouts of the resources. They're needed to
+// reproduce the state of a resource whose workspace is lost.
+type PrivateRawStore interface {
+	// GetPrivateRaw returns the private attributes of the given resource,
+	// or nil if none are stored.
+	GetPrivateRaw(ctx context.Context, tr Terraformed) ([]byte, error)
+	// SetPrivateRaw stores the given private attributes of the given
+	// resource and reports whether the resource object is changed and needs
+	// to be updated.
+	SetPrivateRaw(ctx context.Context, tr Terraformed, privateRaw []byte) (bool, error)
+	// RemovePrivateRaw removes the private attributes of the given resource
+	// once it's deleted.
+	RemovePrivateRaw(ctx context.Context, tr Terraformed) error
+}
+
+// AnnotationPrivateRawStore stores the private attributes in the
+// AnnotationKeyPrivateRawAttribute annotation of the resources. It's the
+// default store since it needs no other objects, but the annotation may
+// exceed the size limits of the API server for the resources whose providers
+// keep large private attributes.
+type AnnotationPrivateRawStore struct{}
+
+// GetPrivateRaw returns the value of the private attributes annotation.
+func (AnnotationPrivateRawStore) GetPrivateRaw(_ context.Context, tr Terraformed) ([]byte, error) {
+	pr, ok := tr.GetAnnotations()[AnnotationKeyPrivateRawAttribute]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(pr), nil
+}
+
+// SetPrivateRaw sets the private attributes annotation to the given private
+// attributes.
+func (AnnotationPrivateRawStore) SetPrivateRaw(_ context.Context, tr Terraformed, privateRaw []byte) (bool, error) {
+	if tr.GetAnnotations()[AnnotationKeyPrivateRawAttribute] == string(privateRaw) {
+		return false, nil
+	}
+	xpmeta.AddAnnotations(tr, map[string]string{AnnotationKeyPrivateRawAttribute: string(privateRaw)})
+	return true, nil
+}
+
+// RemovePrivateRaw is a no-op since the annotation is removed along with the
+// resource.
+func (AnnotationPrivateRawStore) RemovePrivateRaw(_ context.Context, _ Terraformed) error {
+	return nil
+}
+
+// SetCriticalState sets the external name of the resource from the given
+// state as SetCriticalAnnotations does and stores the given private
+// attributes in the given store, or in the annotation if the store is nil. It
+// reports whether the resource object is changed and needs to be updated.
+func SetCriticalState(ctx context.Context, s PrivateRawStore, tr Terraformed, cfg *config.Resource, tfstate map[string]interface{}, privateRaw []byte) (bool, error) {
+	if s == nil {
+		s = AnnotationPrivateRawStore{}
+	}
+	name, err := externalNameFromState(tr, cfg, tfstate)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	if name != "" && name != xpmeta.GetExternalName(tr) {
+		xpmeta.SetExternalName(tr, name)
+		changed = true
+	}
+	stored, err := s.SetPrivateRaw(ctx, tr, privateRaw)
+	if err != nil {
+		return false, errors.Wrap(err, errSetPrivateRaw)
+	}
+	return changed || stored, nil
+}
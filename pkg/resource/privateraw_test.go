@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"context"
+	"testing"
+
+	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/config"
+)
+
+// privateRawStore is a PrivateRawStore that records the private attributes
+// it's given.
+type privateRawStore struct {
+	stored  []byte
+	changed bool
+	err     error
+}
+
+func (s *privateRawStore) GetPrivateRaw(_ context.Context, _ Terraformed) ([]byte, error) {
+	return s.stored, s.err
+}
+
+func (s *privateRawStore) SetPrivateRaw(_ context.Context, _ Terraformed, privateRaw []byte) (bool, error) {
+	s.stored = privateRaw
+	return s.changed, s.err
+}
+
+func (s *privateRawStore) RemovePrivateRaw(_ context.Context, _ Terraformed) error {
+	return s.err
+}
+
+// terraformed is a minimal Terraformed for the tests of the stores.
+type terraformed struct {
+	xpfake.Managed
+	MetadataProvider
+	Observable
+	Parameterizable
+	LateInitializer
+}
+
+func TestSetCriticalState(t *testing.T) {
+	type args struct {
+		externalName string
+		store        *privateRawStore
+		tfstate      map[string]interface{}
+		privateRaw   []byte
+	}
+	type want struct {
+		changed      bool
+		externalName string
+		stored       []byte
+		err          error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NameAndPrivateRawStored": {
+			reason: "The external name should be set from the state and the private attributes should be stored.",
+			args: args{
+				store:      &privateRawStore{},
+				tfstate:    map[string]interface{}{"id": "vpc-123"},
+				privateRaw: []byte("raw"),
+			},
+			want: want{
+				changed:      true,
+				externalName: "vpc-123",
+				stored:       []byte("raw"),
+			},
+		},
+		"StoreChangedResource": {
+			reason: "A change should be reported if the store changes the resource object.",
+			args: args{
+				externalName: "vpc-123",
+				store:        &privateRawStore{changed: true},
+				tfstate:      map[string]interface{}{"id": "vpc-123"},
+				privateRaw:   []byte("raw"),
+			},
+			want: want{
+				changed:      true,
+				externalName: "vpc-123",
+				stored:       []byte("raw"),
+			},
+		},
+		"Unchanged": {
+			reason: "No change should be reported if the external name is up to date and the store doesn't change the resource object.",
+			args: args{
+				externalName: "vpc-123",
+				store:        &privateRawStore{},
+				tfstate:      map[string]interface{}{"id": "vpc-123"},
+				privateRaw:   []byte("raw"),
+			},
+			want: want{
+				externalName: "vpc-123",
+				stored:       []byte("raw"),
+			},
+		},
+		"StoreFailed": {
+			reason: "An error should be returned if the private attributes cannot be stored.",
+			args: args{
+				store:      &privateRawStore{err: errBoom},
+				tfstate:    map[string]interface{}{"id": "vpc-123"},
+				privateRaw: []byte("raw"),
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errSetPrivateRaw),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tr := &terraformed{}
+			if tc.args.externalName != "" {
+				xpmeta.SetExternalName(tr, tc.args.externalName)
+			}
+			cfg := &config.Resource{ExternalName: config.IdentifierFromProvider}
+			changed, err := SetCriticalState(context.TODO(), tc.args.store, tr, cfg, tc.args.tfstate, tc.args.privateRaw)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nSetCriticalState(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if tc.want.err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.changed, changed); diff != "" {
+				t.Errorf("\n%s\nSetCriticalState(...): -want changed, +got changed:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.externalName, xpmeta.GetExternalName(tr)); diff != "" {
+				t.Errorf("\n%s\nSetCriticalState(...): -want external name, +got external name:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.stored, tc.args.store.stored); diff != "" {
+				t.Errorf("\n%s\nSetCriticalState(...): -want stored, +got stored:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
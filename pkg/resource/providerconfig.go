@@ -0,0 +1,56 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// LabelKeyClaimNamespace is the label Crossplane sets on the managed
+// resources composed for a claim with the namespace of the claim as value.
+const LabelKeyClaimNamespace = "crossplane.io/claim-namespace"
+
+// defaultProviderConfigName is the name of the ProviderConfig used by the
+// resources that do not reference one.
+const defaultProviderConfigName = "default"
+
+// ProviderConfigNamespaceFn returns the namespace of the namespaced object the
+// configuration of the given resource is resolved from, or an empty string
+// if the cluster-scoped ProviderConfig of the resource is used.
+type ProviderConfigNamespaceFn func(mg xpresource.Managed) string
+
+// ClaimNamespace is a ProviderConfigNamespaceFn that resolves the
+// configuration of the resources composed for claims from the namespace of
+// their claims, so that the tenants of a multi-tenant cluster who can only
+// create claims in their own namespaces bring their own credentials. The
+// tenants must not be allowed to create managed resources directly since
+// they could set the label themselves.
+func ClaimNamespace(mg xpresource.Managed) string {
+	return mg.GetLabels()[LabelKeyClaimNamespace]
+}
+
+// NamespacedProviderConfigRef returns the key of the object in the given
+// namespace that has the name of the ProviderConfig reference of the given
+// resource.
+func NamespacedProviderConfigRef(mg xpresource.Managed, namespace string) types.NamespacedName {
+	name := defaultProviderConfigName
+	if ref := mg.GetProviderConfigReference(); ref != nil && ref.Name != "" {
+		name = ref.Name
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}
+}
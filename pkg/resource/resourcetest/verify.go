@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resourcetest contains the helpers to test the implementations of
+// the interfaces of the resource package. It imports the testing package, so
+// it should only be imported by tests.
+package resourcetest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+)
+
+// VerifyTerraformed asserts the semantics of the Terraformed interface that
+// terrajet relies on for the given object so that handwritten and generated
+// implementations can verify themselves in their tests:
+//   - GetTerraformResourceType returns the same non-empty value on every call.
+//   - GetTerraformSchemaVersion is not negative.
+//   - GetParameters and GetObservation return non-nil maps.
+//   - SetParameters and SetObservation accept the maps returned by their
+//     getters and the getters return the same maps afterwards.
+//
+// The given object is not modified.
+func VerifyTerraformed(t testing.TB, obj resource.Terraformed) {
+	t.Helper()
+	rt := obj.GetTerraformResourceType()
+	if rt == "" {
+		t.Errorf("GetTerraformResourceType(): returned empty resource type")
+	}
+	if again := obj.GetTerraformResourceType(); again != rt {
+		t.Errorf("GetTerraformResourceType(): returned %q and then %q", rt, again)
+	}
+	if v := obj.GetTerraformSchemaVersion(); v < 0 {
+		t.Errorf("GetTerraformSchemaVersion(): returned negative version %d", v)
+	}
+	cp, ok := obj.DeepCopyObject().(resource.Terraformed)
+	if !ok {
+		t.Fatalf("DeepCopyObject(): returned an object that is not Terraformed")
+		return
+	}
+
+	params, err := obj.GetParameters()
+	if err != nil {
+		t.Fatalf("GetParameters(): %s", err)
+		return
+	}
+	if params == nil {
+		t.Errorf("GetParameters(): returned nil map")
+	}
+	if err := cp.SetParameters(params); err != nil {
+		t.Fatalf("SetParameters(...): %s", err)
+		return
+	}
+	got, err := cp.GetParameters()
+	if err != nil {
+		t.Fatalf("GetParameters(): %s", err)
+		return
+	}
+	if !reflect.DeepEqual(params, got) {
+		t.Errorf("GetParameters(): returned %v after SetParameters(%v)", got, params)
+	}
+
+	obs, err := obj.GetObservation()
+	if err != nil {
+		t.Fatalf("GetObservation(): %s", err)
+		return
+	}
+	if obs == nil {
+		t.Errorf("GetObservation(): returned nil map")
+	}
+	if err := cp.SetObservation(obs); err != nil {
+		t.Fatalf("SetObservation(...): %s", err)
+		return
+	}
+	got, err = cp.GetObservation()
+	if err != nil {
+		t.Fatalf("GetObservation(): %s", err)
+		return
+	}
+	if !reflect.DeepEqual(obs, got) {
+		t.Errorf("GetObservation(): returned %v after SetObservation(%v)", got, obs)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcetest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+)
+
+// recorder records the failures instead of failing the test.
+type recorder struct {
+	*testing.T
+	failures []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestVerifyTerraformed(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		obj    resource.Terraformed
+		want   []string
+	}{
+		"Valid": {
+			reason: "No failure should be reported for a valid implementation",
+			obj: &fake.Terraformed{
+				MetadataProvider: fake.MetadataProvider{Type: "aws_vpc"},
+				Parameterizable:  fake.Parameterizable{Parameters: map[string]interface{}{"cidr_block": "10.0.0.0/16"}},
+				Observable:       fake.Observable{Observation: map[string]interface{}{"id": "vpc-1"}},
+			},
+		},
+		"Invalid": {
+			reason: "Empty resource type and nil maps should be reported",
+			obj:    &fake.Terraformed{},
+			want: []string{
+				"GetTerraformResourceType(): returned empty resource type",
+				"GetParameters(): returned nil map",
+				"GetObservation(): returned nil map",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := &recorder{T: t}
+			VerifyTerraformed(r, tc.obj)
+			if diff := cmp.Diff(tc.want, r.failures); diff != "" {
+				t.Errorf("\n%s\nVerifyTerraformed(...): -want failures, +got failures:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/resource/json"
 )
 
 const (
@@ -49,6 +50,9 @@ const (
 	// overridden by any custom connection key configured which would break
 	// our ability to build tfstate back.
 	prefixAttribute = "attribute."
+	// prefixOutput used to prefix connection detail keys for the outputs of
+	// the root module.
+	prefixOutput = "output."
 
 	pluralSuffix = "s"
 
@@ -106,6 +110,28 @@ func GetConnectionDetails(attr map[string]interface{}, tr Terraformed, cfg *conf
 	return conn, nil
 }
 
+// GetOutputConnectionDetails returns the connection details of the given
+// root module outputs keyed by their names prefixed with "output.". String
+// values are published as they are and the others are JSON encoded.
+func GetOutputConnectionDetails(outputs map[string]interface{}) (managed.ConnectionDetails, error) {
+	if len(outputs) == 0 {
+		return nil, nil
+	}
+	conn := make(managed.ConnectionDetails, len(outputs))
+	for name, v := range outputs {
+		if s, ok := v.(string); ok {
+			conn[prefixOutput+name] = []byte(s)
+			continue
+		}
+		raw, err := json.JSParser.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot marshal output %s", name)
+		}
+		conn[prefixOutput+name] = raw
+	}
+	return conn, nil
+}
+
 // GetSensitiveAttributes returns strings matching provided field paths in the
 // input data.
 // See the unit tests for examples.
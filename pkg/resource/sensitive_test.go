@@ -275,6 +275,40 @@ func TestGetConnectionDetails(t *testing.T) {
 	}
 }
 
+func TestGetOutputConnectionDetails(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		outputs map[string]interface{}
+		want    managed.ConnectionDetails
+	}{
+		"NoOutputs": {
+			reason: "No connection details should be returned if there are no outputs",
+		},
+		"Outputs": {
+			reason: "String outputs should be published as they are and the others JSON encoded",
+			outputs: map[string]interface{}{
+				"endpoint": "https://example.com",
+				"ports":    []interface{}{float64(80), float64(443)},
+			},
+			want: managed.ConnectionDetails{
+				"output.endpoint": []byte("https://example.com"),
+				"output.ports":    []byte("[80,443]"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := GetOutputConnectionDetails(tc.outputs)
+			if err != nil {
+				t.Fatalf("\n%s\nGetOutputConnectionDetails(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nGetOutputConnectionDetails(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestGetSensitiveAttributes(t *testing.T) {
 	testInput := map[string]interface{}{}
 	if err := json.JSParser.Unmarshal(testData, &testInput); err != nil {
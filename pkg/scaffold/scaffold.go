@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package scaffold contains the templates of the repository layout of a new
+// terrajet-based provider and the logic to render them.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"go/format"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/crossplane/terrajet/pkg/version"
+)
+
+const (
+	templateRoot   = "templates/provider"
+	templateSuffix = ".tmpl"
+	// nameToken in the paths of the templates is replaced with the name of
+	// the provider.
+	nameToken = "PROVIDER"
+
+	defaultCopyright        = "The Crossplane Authors"
+	defaultTerraformVersion = "1.2.1"
+
+	errFmtFileExists   = "%s already exists"
+	errFmtRender       = "cannot render %s"
+	errFmtFormat       = "cannot format %s"
+	errFmtWrite        = "cannot write %s"
+	errMissingName     = "name of the provider is required"
+	errMissingModule   = "module path of the provider is required"
+	errMissingProvider = "source and version of the Terraform provider are required"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// Provider describes the provider repository to scaffold.
+type Provider struct {
+	// Name of the provider in lower case, e.g. "github". It is used as the
+	// prefix of the Terraform resource names.
+	Name string
+	// ModulePath is the Go module path of the provider repository, e.g.
+	// "github.com/crossplane-contrib/provider-jet-github".
+	ModulePath string
+	// RootGroup is the root API group of the provider. Defaults to
+	// "<name>.jet.crossplane.io".
+	RootGroup string
+	// ShortName is the short name of the provider used as a category of its
+	// resources. Defaults to "<name>jet".
+	ShortName string
+	// TerraformVersion is the version of the Terraform CLI the provider
+	// runs.
+	TerraformVersion string
+	// TerraformProviderSource is the source address of the Terraform
+	// provider, e.g. "integrations/github".
+	TerraformProviderSource string
+	// TerraformProviderVersion is the version of the Terraform provider.
+	TerraformProviderVersion string
+	// TerrajetVersion is the version of terrajet the provider requires.
+	// Defaults to the version of terrajet the scaffolding is built from, if
+	// it is a released one.
+	TerrajetVersion string
+	// Copyright is the holder of the copyright in the license header of the
+	// Go files. Defaults to "The Crossplane Authors".
+	Copyright string
+	// Year in the license header of the Go files. Defaults to the current
+	// year.
+	Year int
+}
+
+func (p *Provider) setDefaults() {
+	if p.RootGroup == "" {
+		p.RootGroup = p.Name + ".jet.crossplane.io"
+	}
+	if p.ShortName == "" {
+		p.ShortName = p.Name + "jet"
+	}
+	if p.TerraformVersion == "" {
+		p.TerraformVersion = defaultTerraformVersion
+	}
+	if p.TerrajetVersion == "" && version.Version != "0.0.0" {
+		p.TerrajetVersion = "v" + strings.TrimPrefix(version.Version, "v")
+	}
+	if p.Copyright == "" {
+		p.Copyright = defaultCopyright
+	}
+	if p.Year == 0 {
+		p.Year = time.Now().Year()
+	}
+}
+
+func (p *Provider) validate() error {
+	switch {
+	case p.Name == "":
+		return errors.New(errMissingName)
+	case p.ModulePath == "":
+		return errors.New(errMissingModule)
+	case p.TerraformProviderSource == "" || p.TerraformProviderVersion == "":
+		return errors.New(errMissingProvider)
+	}
+	return nil
+}
+
+// Write renders the repository layout of the provider into the given
+// directory: the ProviderConfig API types and controller, the Terraform setup
+// of the credentials, the provider configuration the code generation pipeline
+// runs with, the generator and provider binaries and the Makefile targets
+// that run the pipeline. It never overwrites an existing file.
+func (p Provider) Write(fsys afero.Fs, dir string) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	p.setDefaults()
+	vars := map[string]interface{}{
+		"Name":                     p.Name,
+		"ModulePath":               p.ModulePath,
+		"RootGroup":                p.RootGroup,
+		"ShortName":                p.ShortName,
+		"TerraformVersion":         p.TerraformVersion,
+		"TerraformProviderSource":  p.TerraformProviderSource,
+		"TerraformProviderVersion": p.TerraformProviderVersion,
+		"TerrajetVersion":          p.TerrajetVersion,
+		"Copyright":                p.Copyright,
+		"Year":                     p.Year,
+	}
+	header, err := render(path.Join(templateRoot, "hack", "boilerplate.go.txt"+templateSuffix), vars)
+	if err != nil {
+		return err
+	}
+	vars["Header"] = strings.TrimSpace(string(header))
+	var paths []string
+	files := map[string][]byte{}
+	err = fs.WalkDir(templates, templateRoot, func(tmplPath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := strings.TrimSuffix(strings.TrimPrefix(tmplPath, templateRoot+"/"), templateSuffix)
+		rel = strings.ReplaceAll(rel, nameToken, p.Name)
+		out, err := render(tmplPath, vars)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(rel, ".go") {
+			if out, err = format.Source(out); err != nil {
+				return errors.Wrapf(err, errFmtFormat, rel)
+			}
+		}
+		paths = append(paths, filepath.FromSlash(rel))
+		files[filepath.FromSlash(rel)] = out
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, rel := range paths {
+		if ok, _ := afero.Exists(fsys, filepath.Join(dir, rel)); ok {
+			return errors.Errorf(errFmtFileExists, filepath.Join(dir, rel))
+		}
+	}
+	for _, rel := range paths {
+		target := filepath.Join(dir, rel)
+		if err := fsys.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return errors.Wrapf(err, errFmtWrite, target)
+		}
+		if err := afero.WriteFile(fsys, target, files[rel], 0644); err != nil {
+			return errors.Wrapf(err, errFmtWrite, target)
+		}
+	}
+	return nil
+}
+
+// render executes the template in the given path of the embedded templates.
+func render(tmplPath string, vars map[string]interface{}) ([]byte, error) {
+	raw, err := templates.ReadFile(tmplPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtRender, tmplPath)
+	}
+	t, err := template.New(path.Base(tmplPath)).Parse(string(raw))
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtRender, tmplPath)
+	}
+	buff := &bytes.Buffer{}
+	if err := t.Execute(buff, vars); err != nil {
+		return nil, errors.Wrapf(err, errFmtRender, tmplPath)
+	}
+	return buff.Bytes(), nil
+}
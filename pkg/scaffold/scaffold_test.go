@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+func TestProviderWrite(t *testing.T) {
+	p := Provider{
+		Name:                     "github",
+		ModulePath:               "github.com/crossplane-contrib/provider-jet-github",
+		TerraformProviderSource:  "integrations/github",
+		TerraformProviderVersion: "4.19.2",
+		TerrajetVersion:          "v0.4.2",
+		Year:                     2022,
+	}
+	type args struct {
+		p     Provider
+		files map[string]string
+	}
+	type want struct {
+		files map[string]string
+		err   error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Success": {
+			reason: "The repository layout of the provider should be written with the name of the provider in the paths",
+			args: args{
+				p: p,
+			},
+			want: want{
+				files: map[string]string{
+					"go.mod": "module github.com/crossplane-contrib/provider-jet-github\n\ngo 1.17\n\n// The generated CRDs carry the +kubebuilder:metadata:annotations marker,\n// which controller-gen supports as of controller-tools v0.9.0.\nrequire sigs.k8s.io/controller-tools v0.9.0\n\nrequire github.com/crossplane/terrajet v0.4.2\n",
+					"examples/providerconfig/providerconfig.yaml": `apiVersion: github.jet.crossplane.io/v1alpha1
+kind: ProviderConfig
+metadata:
+  name: default
+spec:
+  credentials:
+    source: Secret
+    secretRef:
+      name: example-creds
+      namespace: crossplane-system
+      key: credentials
+`,
+				},
+			},
+		},
+		"MissingName": {
+			reason: "An error should be returned if the name of the provider is not given",
+			args: args{
+				p: Provider{ModulePath: p.ModulePath},
+			},
+			want: want{
+				err: errors.New(errMissingName),
+			},
+		},
+		"FileExists": {
+			reason: "Existing files should never be overwritten",
+			args: args{
+				p: p,
+				files: map[string]string{
+					"config/provider.go": "package config",
+				},
+			},
+			want: want{
+				err: errors.Errorf(errFmtFileExists, filepath.Join("/repo", "config", "provider.go")),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for f, c := range tc.args.files {
+				if err := afero.WriteFile(fs, filepath.Join("/repo", f), []byte(c), 0600); err != nil {
+					t.Fatalf("cannot write %s: %s", f, err)
+				}
+			}
+			err := tc.args.p.Write(fs, "/repo")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nWrite(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			for f, c := range tc.want.files {
+				got, err := afero.ReadFile(fs, filepath.Join("/repo", f))
+				if err != nil {
+					t.Fatalf("\n%s\nWrite(...): cannot read %s: %s", tc.reason, f, err)
+				}
+				if diff := cmp.Diff(c, string(got)); diff != "" {
+					t.Errorf("\n%s\nWrite(...): -want %s, +got %s:\n%s", tc.reason, f, f, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestProviderWriteLayout(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	p := Provider{
+		Name:                     "github",
+		ModulePath:               "github.com/crossplane-contrib/provider-jet-github",
+		TerraformProviderSource:  "integrations/github",
+		TerraformProviderVersion: "4.19.2",
+	}
+	if err := p.Write(fs, "/repo"); err != nil {
+		t.Fatalf("Write(...): unexpected error: %s", err)
+	}
+	var got []string
+	err := afero.Walk(fs, "/repo", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel("/repo", path)
+		got = append(got, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("cannot walk the repository: %s", err)
+	}
+	sort.Strings(got)
+	want := []string{
+		"Makefile",
+		"README.md",
+		"apis/generate.go",
+		"apis/v1alpha1/doc.go",
+		"apis/v1alpha1/register.go",
+		"apis/v1alpha1/types.go",
+		"cmd/generator/main.go",
+		"cmd/provider/main.go",
+		"config/provider.go",
+		"examples/providerconfig/providerconfig.yaml",
+		"examples/providerconfig/secret.yaml.tmpl",
+		"go.mod",
+		"hack/boilerplate.go.txt",
+		"internal/clients/github.go",
+		"internal/controller/providerconfig/config.go",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Write(...): -want files, +got files:\n%s", diff)
+	}
+}
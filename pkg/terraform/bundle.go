@@ -0,0 +1,212 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+const (
+	// DefaultBundleManifestPath is the well-known path of the manifest that
+	// describes the Terraform CLI and provider binaries baked into a provider
+	// image.
+	DefaultBundleManifestPath = "/terraform/bundle.json"
+
+	// defaultProviderRegistry is the hostname Terraform assumes for provider
+	// source addresses that do not specify one.
+	defaultProviderRegistry = "registry.terraform.io"
+
+	errFmtChecksumMismatch = "checksum of %s does not match: expected %s, got %s"
+	errFmtNoPlatform       = "bundle does not contain a provider binary for platform %s"
+)
+
+// BundledBinary is a binary baked into the provider image.
+type BundledBinary struct {
+	// Version of the binary.
+	Version string `json:"version"`
+	// Path is the absolute path of the binary in the image.
+	Path string `json:"path"`
+	// SHA256 is the hex encoded SHA256 checksum of the binary.
+	SHA256 string `json:"sha256"`
+}
+
+// BundledProvider is a Terraform provider binary baked into the provider
+// image.
+type BundledProvider struct {
+	BundledBinary `json:",inline"`
+	// Source is the source address of the provider, e.g. "hashicorp/aws".
+	Source string `json:"source"`
+	// Platforms are the provider binaries for each platform in the form of
+	// <os>_<arch>, e.g. "linux_arm64", for images built for multiple
+	// architectures. If given, the binary for the runtime platform is
+	// selected and the path and checksum of the provider are populated from
+	// it.
+	Platforms map[string]BundledBinary `json:"platforms,omitempty"`
+}
+
+// Bundle describes the Terraform CLI and provider binaries baked into a
+// provider image so that no binary needs to be downloaded at runtime.
+type Bundle struct {
+	// Terraform is the Terraform CLI binary.
+	Terraform BundledBinary `json:"terraform"`
+	// Provider is the Terraform provider binary.
+	Provider BundledProvider `json:"provider"`
+	// PluginDir is the directory laid out as a Terraform provider filesystem
+	// mirror that contains the provider binary. It is passed to
+	// "terraform init" so that the provider is never downloaded. If the
+	// provider is bundled for multiple platforms, the binary of the runtime
+	// platform is placed in this directory during workspace initialization.
+	PluginDir string `json:"pluginDir"`
+}
+
+// CurrentPlatform returns the platform the provider is running on in the form
+// Terraform uses, i.e. <os>_<arch>.
+func CurrentPlatform() string {
+	return runtime.GOOS + "_" + runtime.GOARCH
+}
+
+// LoadBundle reads the bundle manifest in the given path, selects the
+// provider binary for the runtime platform and verifies the checksums of the
+// binaries listed in it.
+func LoadBundle(fs afero.Fs, path string) (*Bundle, error) {
+	return loadBundle(fs, path, CurrentPlatform())
+}
+
+func loadBundle(fs afero.Fs, path, platform string) (*Bundle, error) {
+	raw, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read bundle manifest")
+	}
+	b := &Bundle{}
+	if err := json.JSParser.Unmarshal(raw, b); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal bundle manifest")
+	}
+	if len(b.Provider.Platforms) != 0 {
+		p, ok := b.Provider.Platforms[platform]
+		if !ok {
+			return nil, errors.Errorf(errFmtNoPlatform, platform)
+		}
+		b.Provider.Path = p.Path
+		b.Provider.SHA256 = p.SHA256
+	}
+	if err := verifyChecksum(fs, b.Terraform); err != nil {
+		return nil, errors.Wrap(err, "cannot verify terraform binary")
+	}
+	if err := verifyChecksum(fs, b.Provider.BundledBinary); err != nil {
+		return nil, errors.Wrap(err, "cannot verify provider binary")
+	}
+	return b, nil
+}
+
+func verifyChecksum(fs afero.Fs, b BundledBinary) error {
+	f, err := fs.Open(b.Path)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open %s", b.Path)
+	}
+	defer f.Close() // nolint:errcheck
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return errors.Wrapf(err, "cannot read %s", b.Path)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != b.SHA256 {
+		return errors.Errorf(errFmtChecksumMismatch, b.Path, b.SHA256, got)
+	}
+	return nil
+}
+
+// PopulatePluginDir places the provider binary selected for the given
+// platform into the plugin directory in the layout of a Terraform filesystem
+// mirror, i.e. <hostname>/<namespace>/<type>/<version>/<os>_<arch>. It is a
+// no-op if the provider is not bundled for multiple platforms, in which case
+// the plugin directory is expected to be laid out in the image already.
+func (b *Bundle) PopulatePluginDir(fs afero.Fs, platform string) error {
+	if len(b.Provider.Platforms) == 0 {
+		return nil
+	}
+	addr := strings.Split(b.Provider.Source, "/")
+	if len(addr) == 2 {
+		addr = append([]string{defaultProviderRegistry}, addr...)
+	}
+	if len(addr) != 3 {
+		return errors.Errorf("invalid provider source address %q", b.Provider.Source)
+	}
+	dir := filepath.Join(append([]string{b.PluginDir}, append(addr, b.Provider.Version, platform)...)...)
+	if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "cannot create plugin directory")
+	}
+	target := filepath.Join(dir, "terraform-provider-"+addr[2]+"_v"+b.Provider.Version)
+	if _, err := fs.Stat(target); err == nil {
+		return nil
+	}
+	if l, ok := fs.(afero.Linker); ok {
+		return errors.Wrap(l.SymlinkIfPossible(b.Provider.Path, target), "cannot link provider binary into plugin directory")
+	}
+	return errors.Wrap(copyFile(fs, b.Provider.Path, target), "cannot copy provider binary into plugin directory")
+}
+
+func copyFile(fs afero.Fs, src, dst string) error {
+	in, err := fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() // nolint:errcheck
+	out, err := fs.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// NewBundleSetupFn returns a SetupFn that populates the Terraform version and
+// the provider requirement of the Setup returned by the given SetupFn from
+// the bundle if they are not set.
+func NewBundleSetupFn(b *Bundle, sf SetupFn) SetupFn {
+	return func(ctx context.Context, client client.Client, mg xpresource.Managed) (Setup, error) {
+		ts, err := sf(ctx, client, mg)
+		if err != nil {
+			return ts, err
+		}
+		if ts.Version == "" {
+			ts.Version = b.Terraform.Version
+		}
+		if ts.Requirement.Source == "" {
+			ts.Requirement.Source = b.Provider.Source
+		}
+		if ts.Requirement.Version == "" {
+			ts.Requirement.Version = b.Provider.Version
+		}
+		return ts, nil
+	}
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func checksum(content string) string {
+	h := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(h[:])
+}
+
+func TestLoadBundle(t *testing.T) {
+	manifest := `{
+  "terraform": {"version": "1.2.1", "path": "/usr/bin/terraform", "sha256": "%s"},
+  "provider": {"source": "hashicorp/aws", "version": "4.15.1", "path": "/terraform/plugins/terraform-provider-aws", "sha256": "%s"},
+  "pluginDir": "/terraform/plugins"
+}`
+	type args struct {
+		terraformSum string
+		providerSum  string
+	}
+	type want struct {
+		bundle *Bundle
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Success": {
+			reason: "A bundle whose binaries match their checksums should be loaded",
+			args: args{
+				terraformSum: checksum("terraform"),
+				providerSum:  checksum("provider"),
+			},
+			want: want{
+				bundle: &Bundle{
+					Terraform: BundledBinary{Version: "1.2.1", Path: "/usr/bin/terraform", SHA256: checksum("terraform")},
+					Provider: BundledProvider{
+						BundledBinary: BundledBinary{Version: "4.15.1", Path: "/terraform/plugins/terraform-provider-aws", SHA256: checksum("provider")},
+						Source:        "hashicorp/aws",
+					},
+					PluginDir: "/terraform/plugins",
+				},
+			},
+		},
+		"ProviderChecksumMismatch": {
+			reason: "An error should be returned if the provider binary does not match its checksum",
+			args: args{
+				terraformSum: checksum("terraform"),
+				providerSum:  checksum("tampered"),
+			},
+			want: want{
+				err: errors.Wrap(errors.Errorf(errFmtChecksumMismatch, "/terraform/plugins/terraform-provider-aws", checksum("tampered"), checksum("provider")), "cannot verify provider binary"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			files := map[string]string{
+				DefaultBundleManifestPath:                   fmt.Sprintf(manifest, tc.args.terraformSum, tc.args.providerSum),
+				"/usr/bin/terraform":                        "terraform",
+				"/terraform/plugins/terraform-provider-aws": "provider",
+			}
+			for p, c := range files {
+				if err := afero.WriteFile(fs, p, []byte(c), 0600); err != nil {
+					t.Fatalf("cannot write %s: %s", p, err)
+				}
+			}
+			b, err := loadBundle(fs, DefaultBundleManifestPath, "linux_amd64")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nLoadBundle(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.bundle, b); diff != "" {
+				t.Errorf("\n%s\nLoadBundle(...): -want bundle, +got bundle:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestLoadBundleMultiPlatform(t *testing.T) {
+	manifest := fmt.Sprintf(`{
+  "terraform": {"version": "1.2.1", "path": "/usr/bin/terraform", "sha256": "%s"},
+  "provider": {
+    "source": "hashicorp/aws",
+    "version": "4.15.1",
+    "platforms": {
+      "linux_amd64": {"path": "/terraform/bin/amd64/terraform-provider-aws", "sha256": "%s"},
+      "linux_arm64": {"path": "/terraform/bin/arm64/terraform-provider-aws", "sha256": "%s"}
+    }
+  },
+  "pluginDir": "/terraform/plugins"
+}`, checksum("terraform"), checksum("amd64"), checksum("arm64"))
+	type want struct {
+		path string
+		err  error
+	}
+	cases := map[string]struct {
+		reason   string
+		platform string
+		want
+	}{
+		"Arm64": {
+			reason:   "The provider binary of the runtime platform should be selected",
+			platform: "linux_arm64",
+			want: want{
+				path: "/terraform/bin/arm64/terraform-provider-aws",
+			},
+		},
+		"MissingPlatform": {
+			reason:   "An error should be returned if the provider is not bundled for the runtime platform",
+			platform: "darwin_arm64",
+			want: want{
+				err: errors.Errorf(errFmtNoPlatform, "darwin_arm64"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			files := map[string]string{
+				DefaultBundleManifestPath:                     manifest,
+				"/usr/bin/terraform":                          "terraform",
+				"/terraform/bin/amd64/terraform-provider-aws": "amd64",
+				"/terraform/bin/arm64/terraform-provider-aws": "arm64",
+			}
+			for p, c := range files {
+				if err := afero.WriteFile(fs, p, []byte(c), 0600); err != nil {
+					t.Fatalf("cannot write %s: %s", p, err)
+				}
+			}
+			b, err := loadBundle(fs, DefaultBundleManifestPath, tc.platform)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nloadBundle(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.path, b.Provider.Path); diff != "" {
+				t.Errorf("\n%s\nloadBundle(...): -want path, +got path:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPopulatePluginDir(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "/terraform/bin/arm64/terraform-provider-aws", []byte("arm64"), 0755); err != nil {
+		t.Fatalf("cannot write provider binary: %s", err)
+	}
+	b := &Bundle{
+		Provider: BundledProvider{
+			BundledBinary: BundledBinary{Version: "4.15.1", Path: "/terraform/bin/arm64/terraform-provider-aws"},
+			Source:        "hashicorp/aws",
+			Platforms: map[string]BundledBinary{
+				"linux_arm64": {Path: "/terraform/bin/arm64/terraform-provider-aws"},
+			},
+		},
+		PluginDir: "/terraform/plugins",
+	}
+	if err := b.PopulatePluginDir(fs, "linux_arm64"); err != nil {
+		t.Fatalf("PopulatePluginDir(...): unexpected error: %s", err)
+	}
+	got, err := afero.ReadFile(fs, "/terraform/plugins/registry.terraform.io/hashicorp/aws/4.15.1/linux_arm64/terraform-provider-aws_v4.15.1")
+	if err != nil {
+		t.Fatalf("PopulatePluginDir(...): cannot read provider binary in mirror layout: %s", err)
+	}
+	if diff := cmp.Diff("arm64", string(got)); diff != "" {
+		t.Errorf("PopulatePluginDir(...): -want content, +got content:\n%s", diff)
+	}
+}
+
+func TestNewBundleSetupFn(t *testing.T) {
+	b := &Bundle{
+		Terraform: BundledBinary{Version: "1.2.1"},
+		Provider: BundledProvider{
+			BundledBinary: BundledBinary{Version: "4.15.1"},
+			Source:        "hashicorp/aws",
+		},
+	}
+	cases := map[string]struct {
+		reason string
+		setup  Setup
+		want   Setup
+	}{
+		"Empty": {
+			reason: "Versions and source should be populated from the bundle if they are not set",
+			want: Setup{
+				Version:     "1.2.1",
+				Requirement: ProviderRequirement{Source: "hashicorp/aws", Version: "4.15.1"},
+			},
+		},
+		"Set": {
+			reason: "Values returned by the wrapped SetupFn should take precedence",
+			setup: Setup{
+				Version:     "1.1.0",
+				Requirement: ProviderRequirement{Source: "hashicorp/aws", Version: "4.0.0"},
+			},
+			want: Setup{
+				Version:     "1.1.0",
+				Requirement: ProviderRequirement{Source: "hashicorp/aws", Version: "4.0.0"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			sf := NewBundleSetupFn(b, func(_ context.Context, _ client.Client, _ xpresource.Managed) (Setup, error) {
+				return tc.setup, nil
+			})
+			got, err := sf(context.TODO(), nil, nil)
+			if err != nil {
+				t.Fatalf("\n%s\nSetupFn(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nSetupFn(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
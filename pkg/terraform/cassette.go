@@ -0,0 +1,451 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	k8sExec "k8s.io/utils/exec"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+const (
+	// cassetteFiles are the workspace files whose contents, together with the
+	// command arguments, identify a Terraform interaction. The environment is
+	// not used since it contains the provider reattach configuration that is
+	// different in every run.
+	cassetteMainFile  = "main.tf.json"
+	cassetteStateFile = "terraform.tfstate"
+
+	// cassetteRedacted replaces the secrets in the interactions.
+	cassetteRedacted = "REDACTED"
+
+	errFmtNoInteraction = "no recorded interaction for %q with key %s"
+)
+
+// sensitiveEnvRegex matches the names of the environment variables whose
+// values are scrubbed from the interactions, e.g. AWS_SECRET_ACCESS_KEY or
+// GITHUB_TOKEN.
+var sensitiveEnvRegex = regexp.MustCompile(`(?i)(SECRET|TOKEN|PASSWORD|PASSWD|CREDENTIAL|PRIVATE|_KEY$|^KEY$|_KEY_)`)
+
+// Interaction is a recorded Terraform CLI invocation.
+type Interaction struct {
+	// Args are the arguments of the command.
+	Args []string `json:"args"`
+	// Output is the output of the command. It contains the standard error
+	// for the interactions recorded with CombinedOutput.
+	Output string `json:"output"`
+	// ExitCode of the command. Zero if the command succeeded.
+	ExitCode int `json:"exitCode"`
+	// Error is the message of the error returned by the command, if any.
+	Error string `json:"error,omitempty"`
+	// State is the content of the Terraform state file after the command
+	// if the command changed it.
+	State *string `json:"state,omitempty"`
+}
+
+// CassetteExecutor is a k8sExec.Interface that records the Terraform CLI
+// invocations of workspaces into a cassette directory, or replays them from
+// the cassette without running Terraform. The interactions are keyed by the
+// hash of the command arguments and the configuration and state files in the
+// workspace, so a replayed run is deterministic as long as the managed
+// resources, e.g. their UIDs, are the same as the recorded run. The secrets,
+// i.e. the values given with WithCassetteSecrets and the values of the
+// environment variables whose names look sensitive, are scrubbed from the
+// recorded interactions and from the contents the keys are computed with.
+// It is meant to be used in tests via WithCommandExecutor.
+type CassetteExecutor struct {
+	inner   k8sExec.Interface
+	fs      afero.Afero
+	dir     string
+	replay  bool
+	secrets []string
+}
+
+// CassetteOption configures a CassetteExecutor.
+type CassetteOption func(*CassetteExecutor)
+
+// WithCassetteSecrets scrubs the given values from the interactions, e.g. the
+// credentials in the provider configuration. They should be given in both the
+// record and the replay mode so that the keys of the interactions match.
+func WithCassetteSecrets(values ...string) CassetteOption {
+	return func(c *CassetteExecutor) {
+		c.secrets = append(c.secrets, values...)
+	}
+}
+
+// NewRecordingExecutor returns a CassetteExecutor that runs the commands using
+// the given executor and records them in the given cassette directory.
+func NewRecordingExecutor(e k8sExec.Interface, fs afero.Fs, dir string, opts ...CassetteOption) *CassetteExecutor {
+	c := &CassetteExecutor{inner: e, fs: afero.Afero{Fs: fs}, dir: dir}
+	for _, f := range opts {
+		f(c)
+	}
+	return c
+}
+
+// NewReplayingExecutor returns a CassetteExecutor that replays the commands
+// recorded in the given cassette directory. A command that is not recorded
+// results in an error.
+func NewReplayingExecutor(fs afero.Fs, dir string, opts ...CassetteOption) *CassetteExecutor {
+	c := &CassetteExecutor{fs: afero.Afero{Fs: fs}, dir: dir, replay: true}
+	for _, f := range opts {
+		f(c)
+	}
+	return c
+}
+
+// Command returns a Cmd that is recorded or replayed.
+func (c *CassetteExecutor) Command(cmd string, args ...string) k8sExec.Cmd {
+	return c.CommandContext(context.Background(), cmd, args...)
+}
+
+// CommandContext returns a Cmd that is recorded or replayed.
+func (c *CassetteExecutor) CommandContext(ctx context.Context, cmd string, args ...string) k8sExec.Cmd {
+	cc := &cassetteCmd{executor: c, args: args}
+	if !c.replay {
+		cc.Cmd = c.inner.CommandContext(ctx, cmd, args...)
+	}
+	return cc
+}
+
+// LookPath returns the given file as is in replay mode.
+func (c *CassetteExecutor) LookPath(file string) (string, error) {
+	if c.replay {
+		return file, nil
+	}
+	return c.inner.LookPath(file)
+}
+
+// key returns the key of the interaction with the given arguments in the
+// given workspace directory. The arguments and the files are scrubbed with
+// the given function before they're hashed.
+func (c *CassetteExecutor) key(args []string, dir string, scrub func(string) string) (string, error) {
+	h := sha256.New()
+	for _, a := range args {
+		a = scrub(a)
+		fmt.Fprintf(h, "%d:%s", len(a), a)
+	}
+	if dir != "" {
+		for _, f := range []string{cassetteMainFile, cassetteStateFile} {
+			raw, err := c.fs.ReadFile(filepath.Join(dir, f))
+			if err != nil && !os.IsNotExist(err) {
+				return "", errors.Wrapf(err, "cannot read %s", f)
+			}
+			content := scrub(string(raw))
+			fmt.Fprintf(h, "%s:%d:%s", f, len(content), content)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *CassetteExecutor) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// lockedBuffer is a bytes.Buffer that is safe to be written by the goroutine
+// that reads the output of a command and read once it finishes.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
+
+// teeReadCloser reads the output of a command through a reader that copies
+// it to a buffer and closes the pipe the output is read from.
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type cassetteCmd struct {
+	k8sExec.Cmd
+	executor *CassetteExecutor
+	args     []string
+	dir      string
+	env      []string
+
+	// The following are used by the commands that are started
+	// asynchronously. In record mode, out collects the standard output of
+	// the command and in replay mode, the recorded output is written to
+	// stdout or to the pipe returned by StdoutPipe.
+	key         string
+	before      *string
+	out         lockedBuffer
+	stdout      io.Writer
+	stdoutPipe  *io.PipeWriter
+	interaction *Interaction
+}
+
+func (cc *cassetteCmd) SetDir(dir string) {
+	cc.dir = dir
+	if cc.Cmd != nil {
+		cc.Cmd.SetDir(dir)
+	}
+}
+
+func (cc *cassetteCmd) SetEnv(env []string) {
+	cc.env = env
+	if cc.Cmd != nil {
+		cc.Cmd.SetEnv(env)
+	}
+}
+
+func (cc *cassetteCmd) Run() error {
+	_, err := cc.run(func() ([]byte, error) { return nil, cc.Cmd.Run() })
+	return err
+}
+
+func (cc *cassetteCmd) CombinedOutput() ([]byte, error) {
+	return cc.run(func() ([]byte, error) { return cc.Cmd.CombinedOutput() })
+}
+
+func (cc *cassetteCmd) Output() ([]byte, error) {
+	return cc.run(func() ([]byte, error) { return cc.Cmd.Output() })
+}
+
+func (cc *cassetteCmd) Start() error {
+	key, err := cc.executor.key(cc.args, cc.dir, cc.scrub)
+	if err != nil {
+		return errors.Wrap(err, "cannot compute interaction key")
+	}
+	cc.key = key
+	if !cc.executor.replay {
+		cc.before = cc.readState()
+		return cc.Cmd.Start()
+	}
+	in, err := cc.load(key)
+	if err != nil {
+		return err
+	}
+	cc.interaction = in
+	if cc.stdout != nil {
+		if _, err := io.WriteString(cc.stdout, in.Output); err != nil {
+			return errors.Wrap(err, "cannot write replayed output")
+		}
+	}
+	if cc.stdoutPipe != nil {
+		go func(w *io.PipeWriter) {
+			_, err := io.WriteString(w, in.Output)
+			_ = w.CloseWithError(err)
+		}(cc.stdoutPipe)
+	}
+	return nil
+}
+
+func (cc *cassetteCmd) Wait() error {
+	if !cc.executor.replay {
+		err := cc.Cmd.Wait()
+		if rErr := cc.record(cc.key, cc.before, cc.out.Bytes(), err); rErr != nil {
+			return rErr
+		}
+		return err
+	}
+	if cc.interaction == nil {
+		return errors.New("cannot wait for a command that is not started")
+	}
+	_, err := cc.apply(cc.interaction)
+	return err
+}
+
+func (cc *cassetteCmd) StdoutPipe() (io.ReadCloser, error) {
+	if cc.executor.replay {
+		r, w := io.Pipe()
+		cc.stdoutPipe = w
+		return r, nil
+	}
+	r, err := cc.Cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	return teeReadCloser{Reader: io.TeeReader(r, &cc.out), Closer: r}, nil
+}
+
+func (cc *cassetteCmd) StderrPipe() (io.ReadCloser, error) {
+	// NOTE(muvaf): Only the standard output of the asynchronous commands is
+	// recorded, so nothing is replayed on the standard error.
+	if cc.executor.replay {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	return cc.Cmd.StderrPipe()
+}
+
+func (cc *cassetteCmd) SetStdin(in io.Reader) {
+	if cc.Cmd != nil {
+		cc.Cmd.SetStdin(in)
+	}
+}
+
+func (cc *cassetteCmd) SetStdout(out io.Writer) {
+	cc.stdout = out
+	if cc.Cmd != nil {
+		cc.Cmd.SetStdout(io.MultiWriter(out, &cc.out))
+	}
+}
+
+func (cc *cassetteCmd) SetStderr(out io.Writer) {
+	if cc.Cmd != nil {
+		cc.Cmd.SetStderr(out)
+	}
+}
+
+func (cc *cassetteCmd) Stop() {
+	if cc.Cmd != nil {
+		cc.Cmd.Stop()
+	}
+}
+
+func (cc *cassetteCmd) run(fn func() ([]byte, error)) ([]byte, error) {
+	e := cc.executor
+	key, err := e.key(cc.args, cc.dir, cc.scrub)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot compute interaction key")
+	}
+	if e.replay {
+		in, err := cc.load(key)
+		if err != nil {
+			return nil, err
+		}
+		return cc.apply(in)
+	}
+	before := cc.readState()
+	out, runErr := fn()
+	if err := cc.record(key, before, out, runErr); err != nil {
+		return nil, err
+	}
+	return out, runErr
+}
+
+// record writes the interaction with the given result into the cassette
+// after scrubbing the secrets from it.
+func (cc *cassetteCmd) record(key string, before *string, out []byte, runErr error) error {
+	e := cc.executor
+	in := Interaction{Args: make([]string, len(cc.args)), Output: cc.scrub(string(out))}
+	for i, a := range cc.args {
+		in.Args[i] = cc.scrub(a)
+	}
+	if runErr != nil {
+		in.Error = cc.scrub(runErr.Error())
+		in.ExitCode = 1
+		if ee, ok := runErr.(k8sExec.ExitError); ok {
+			in.ExitCode = ee.ExitStatus()
+		}
+	}
+	if after := cc.readState(); after != nil && (before == nil || *before != *after) {
+		scrubbed := cc.scrub(*after)
+		in.State = &scrubbed
+	}
+	raw, err := json.JSParser.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal interaction")
+	}
+	if err := e.fs.MkdirAll(e.dir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "cannot create cassette directory")
+	}
+	return errors.Wrap(e.fs.WriteFile(e.path(key), raw, 0600), "cannot write interaction")
+}
+
+// load reads the interaction with the given key from the cassette.
+func (cc *cassetteCmd) load(key string) (*Interaction, error) {
+	e := cc.executor
+	raw, err := e.fs.ReadFile(e.path(key))
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf(errFmtNoInteraction, strings.Join(cc.args, " "), key)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read interaction")
+	}
+	in := &Interaction{}
+	if err := json.JSParser.Unmarshal(raw, in); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal interaction")
+	}
+	return in, nil
+}
+
+// apply writes the state of the given replayed interaction into the
+// workspace and returns its result.
+func (cc *cassetteCmd) apply(in *Interaction) ([]byte, error) {
+	if in.State != nil && cc.dir != "" {
+		if err := cc.executor.fs.WriteFile(filepath.Join(cc.dir, cassetteStateFile), []byte(*in.State), 0600); err != nil {
+			return nil, errors.Wrap(err, "cannot write replayed state")
+		}
+	}
+	if in.Error != "" {
+		return []byte(in.Output), k8sExec.CodeExitError{Err: errors.New(in.Error), Code: in.ExitCode}
+	}
+	return []byte(in.Output), nil
+}
+
+// scrub replaces the secrets in the given string, i.e. the values given to
+// the executor and the values of the sensitive environment variables of the
+// command, with a placeholder. The longer secrets are replaced first so that
+// a secret that contains another one is not partially replaced.
+func (cc *cassetteCmd) scrub(s string) string {
+	secrets := append([]string{}, cc.executor.secrets...)
+	for _, kv := range cc.env {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && sensitiveEnvRegex.MatchString(parts[0]) {
+			secrets = append(secrets, parts[1])
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+	for _, v := range secrets {
+		if v == "" {
+			continue
+		}
+		s = strings.ReplaceAll(s, v, cassetteRedacted)
+	}
+	return s
+}
+func (cc *cassetteCmd) readState() *string {
+	if cc.dir == "" {
+		return nil
+	}
+	raw, err := cc.executor.fs.ReadFile(filepath.Join(cc.dir, cassetteStateFile))
+	if err != nil {
+		return nil
+	}
+	s := string(raw)
+	return &s
+}
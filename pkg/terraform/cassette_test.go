@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	k8sExec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+func TestCassetteExecutor(t *testing.T) {
+	const (
+		ws       = "/ws"
+		cassette = "/cassette"
+		mainTF   = `{"resource":{"null_resource":{"test":{}}}}`
+		state    = `{"version":4,"lineage":"uid"}`
+	)
+	newFs := func() afero.Fs {
+		fs := afero.NewMemMapFs()
+		if err := afero.WriteFile(fs, ws+"/main.tf.json", []byte(mainTF), 0600); err != nil {
+			t.Fatalf("cannot write main.tf.json: %s", err)
+		}
+		return fs
+	}
+
+	// Record an apply that writes the state file.
+	recordFs := newFs()
+	inner := &testingexec.FakeExec{
+		CommandScript: []testingexec.FakeCommandAction{
+			func(_ string, _ ...string) k8sExec.Cmd {
+				return &testingexec.FakeCmd{
+					CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) {
+							if err := afero.WriteFile(recordFs, ws+"/terraform.tfstate", []byte(state), 0600); err != nil {
+								t.Fatalf("cannot write state: %s", err)
+							}
+							return []byte("applied"), nil, nil
+						},
+					},
+				}
+			},
+		},
+	}
+	cmd := NewRecordingExecutor(inner, recordFs, cassette).CommandContext(context.TODO(), "terraform", "apply", "-auto-approve")
+	cmd.SetDir(ws)
+	if _, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("CombinedOutput(...): unexpected error in record mode: %s", err)
+	}
+
+	// Replay it in a fresh workspace using the same cassette.
+	replayFs := newFs()
+	files, err := afero.ReadDir(recordFs, cassette)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected a single recorded interaction, got %d: %v", len(files), err)
+	}
+	raw, _ := afero.ReadFile(recordFs, cassette+"/"+files[0].Name())
+	if err := afero.WriteFile(replayFs, cassette+"/"+files[0].Name(), raw, 0600); err != nil {
+		t.Fatalf("cannot copy cassette: %s", err)
+	}
+	replay := NewReplayingExecutor(replayFs, cassette)
+	cmd = replay.CommandContext(context.TODO(), "terraform", "apply", "-auto-approve")
+	cmd.SetDir(ws)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CombinedOutput(...): unexpected error in replay mode: %s", err)
+	}
+	if diff := cmp.Diff("applied", string(out)); diff != "" {
+		t.Errorf("CombinedOutput(...): -want output, +got output:\n%s", diff)
+	}
+	got, err := afero.ReadFile(replayFs, ws+"/terraform.tfstate")
+	if err != nil {
+		t.Fatalf("cannot read replayed state: %s", err)
+	}
+	if diff := cmp.Diff(state, string(got)); diff != "" {
+		t.Errorf("CombinedOutput(...): -want state, +got state:\n%s", diff)
+	}
+
+	// The state has changed, so a second apply is not recorded.
+	cmd = replay.CommandContext(context.TODO(), "terraform", "apply", "-auto-approve")
+	cmd.SetDir(ws)
+	_, err = cmd.CombinedOutput()
+	key, _ := replay.key([]string{"apply", "-auto-approve"}, ws, func(s string) string { return s })
+	want := errors.Errorf(errFmtNoInteraction, "apply -auto-approve", key)
+	if diff := cmp.Diff(want, err, test.EquateErrors()); diff != "" {
+		t.Errorf("CombinedOutput(...): -want error, +got error:\n%s", diff)
+	}
+}
+
+func TestCassetteExecutorAsync(t *testing.T) {
+	const cassette = "/cassette"
+	fs := afero.NewMemMapFs()
+	inner := &testingexec.FakeExec{
+		CommandScript: []testingexec.FakeCommandAction{
+			func(_ string, _ ...string) k8sExec.Cmd {
+				return &testingexec.FakeCmd{
+					StdoutPipeResponse: testingexec.FakeStdIOPipeResponse{
+						ReadCloser: io.NopCloser(strings.NewReader("serving")),
+					},
+				}
+			},
+		},
+	}
+	run := func(e k8sExec.Interface) string {
+		cmd := e.Command("terraform-provider-null")
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			t.Fatalf("StdoutPipe(): unexpected error: %s", err)
+		}
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Start(): unexpected error: %s", err)
+		}
+		out, err := io.ReadAll(stdout)
+		if err != nil {
+			t.Fatalf("cannot read the output: %s", err)
+		}
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("Wait(): unexpected error: %s", err)
+		}
+		return string(out)
+	}
+	if diff := cmp.Diff("serving", run(NewRecordingExecutor(inner, fs, cassette))); diff != "" {
+		t.Errorf("StdoutPipe(): -want output in record mode, +got output:\n%s", diff)
+	}
+	if diff := cmp.Diff("serving", run(NewReplayingExecutor(fs, cassette))); diff != "" {
+		t.Errorf("StdoutPipe(): -want output in replay mode, +got output:\n%s", diff)
+	}
+}
+
+func TestCassetteExecutorScrub(t *testing.T) {
+	const cassette = "/cassette"
+	fs := afero.NewMemMapFs()
+	inner := &testingexec.FakeExec{
+		CommandScript: []testingexec.FakeCommandAction{
+			func(_ string, _ ...string) k8sExec.Cmd {
+				return &testingexec.FakeCmd{
+					CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) {
+							return []byte("token gh-token, password s3cr3t"), nil, nil
+						},
+					},
+				}
+			},
+		},
+	}
+	cmd := NewRecordingExecutor(inner, fs, cassette, WithCassetteSecrets("s3cr3t")).Command("terraform", "apply", "-var=password=s3cr3t")
+	cmd.SetEnv([]string{"GITHUB_TOKEN=gh-token", "TF_LOG=debug"})
+	if _, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("CombinedOutput(...): unexpected error: %s", err)
+	}
+	files, err := afero.ReadDir(fs, cassette)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected a single recorded interaction, got %d: %v", len(files), err)
+	}
+	raw, _ := afero.ReadFile(fs, cassette+"/"+files[0].Name())
+	in := Interaction{}
+	if err := json.Unmarshal(raw, &in); err != nil {
+		t.Fatalf("cannot unmarshal interaction: %s", err)
+	}
+	want := Interaction{
+		Args:   []string{"apply", "-var=password=" + cassetteRedacted},
+		Output: "token " + cassetteRedacted + ", password " + cassetteRedacted,
+	}
+	if diff := cmp.Diff(want, in); diff != "" {
+		t.Errorf("CombinedOutput(...): -want interaction, +got interaction:\n%s", diff)
+	}
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	k8sExec "k8s.io/utils/exec"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+const (
+	// supportedCLIVersions is the range of Terraform CLI versions whose flags
+	// and machine-readable JSON output terrajet knows how to work with. The
+	// lower bound is the first version that supports "apply -refresh-only"
+	// and the upper bound guards against a future major version changing
+	// the JSON UI stream.
+	supportedCLIVersions = ">= 0.15.4, < 2.0.0"
+
+	errFmtUnsupportedCLIVersion = "unsupported terraform CLI version %s: supported versions are %q"
+	errFmtParseCLIVersion       = "cannot parse terraform CLI version %q"
+)
+
+// cliFlag is a Terraform CLI flag that is available only in a range of
+// Terraform versions. A nil since or until means the range is open on that
+// side.
+type cliFlag struct {
+	flag  string
+	since *goversion.Version
+	until *goversion.Version
+}
+
+// NOTE(muvaf): When a flag is deprecated or its behavior changes in a newer
+// Terraform version, add an entry with an until bound for the old variant and
+// one with a since bound for the new one instead of branching in Workspace.
+var (
+	initFlags = []cliFlag{
+		{flag: "-input=false"},
+	}
+	applyFlags = []cliFlag{
+		{flag: "-auto-approve"},
+		{flag: "-input=false"},
+		{flag: "-lock=false"},
+		{flag: "-json"},
+	}
+	destroyFlags = []cliFlag{
+		{flag: "-auto-approve"},
+		{flag: "-input=false"},
+		{flag: "-lock=false"},
+		{flag: "-json"},
+	}
+	refreshFlags = []cliFlag{
+		{flag: "-refresh-only"},
+		{flag: "-auto-approve"},
+		{flag: "-input=false"},
+		{flag: "-lock=false"},
+		{flag: "-json"},
+	}
+	planFlags = []cliFlag{
+		{flag: "-refresh=false"},
+		{flag: "-input=false"},
+		{flag: "-lock=false"},
+		{flag: "-json"},
+	}
+	driftPlanFlags = []cliFlag{
+		{flag: "-refresh=false"},
+		{flag: "-input=false"},
+		{flag: "-lock=false"},
+	}
+	destroyPlanFlags = []cliFlag{
+		{flag: "-destroy"},
+		{flag: "-refresh=false"},
+		{flag: "-input=false"},
+		{flag: "-lock=false"},
+	}
+	validateFlags = []cliFlag{
+		{flag: "-json"},
+		// The workspaces never contain test files, so loading the tests
+		// directory that "terraform validate" looks into as of 1.6 is
+		// skipped.
+		{flag: "-no-tests", since: goversion.Must(goversion.NewVersion("1.6.0"))},
+	}
+	showFlags = []cliFlag{
+		{flag: "-json"},
+	}
+	outputFlags = []cliFlag{
+		{flag: "-json"},
+	}
+	importFlags = []cliFlag{
+		{flag: "-input=false"},
+		{flag: "-lock=false"},
+	}
+)
+
+// CommandBuilder builds the arguments of Terraform CLI commands with the
+// flags that are supported by a specific Terraform CLI version.
+type CommandBuilder struct {
+	version *goversion.Version
+}
+
+// NewCommandBuilder returns a CommandBuilder for the given Terraform CLI
+// version. It returns an error if the version is not supported by terrajet.
+func NewCommandBuilder(v string) (*CommandBuilder, error) {
+	ver, err := goversion.NewVersion(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtParseCLIVersion, v)
+	}
+	c, err := goversion.NewConstraint(supportedCLIVersions)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse supported terraform CLI versions")
+	}
+	if !c.Check(ver) {
+		return nil, errors.Errorf(errFmtUnsupportedCLIVersion, ver.String(), supportedCLIVersions)
+	}
+	return &CommandBuilder{version: ver}, nil
+}
+
+// Version returns the Terraform CLI version the commands are built for. It
+// returns an empty string if the version was not detected.
+func (cb *CommandBuilder) Version() string {
+	if cb == nil || cb.version == nil {
+		return ""
+	}
+	return cb.version.String()
+}
+
+// Init returns the arguments of the "terraform init" command. If pluginDir
+// is given, providers are installed only from that directory.
+func (cb *CommandBuilder) Init(pluginDir string) []string {
+	args := cb.build([]string{"init"}, initFlags)
+	if pluginDir != "" {
+		args = append(args, "-plugin-dir="+pluginDir)
+	}
+	return args
+}
+
+// Apply returns the arguments of the "terraform apply" command. The
+// resources with the given addresses are replaced even if they have no
+// changes.
+func (cb *CommandBuilder) Apply(replace ...string) []string {
+	args := cb.build([]string{"apply"}, applyFlags)
+	for _, addr := range replace {
+		args = append(args, "-replace="+addr)
+	}
+	return args
+}
+
+// Destroy returns the arguments of the "terraform destroy" command.
+func (cb *CommandBuilder) Destroy() []string {
+	return cb.build([]string{"destroy"}, destroyFlags)
+}
+
+// Refresh returns the arguments of the "terraform apply -refresh-only"
+// command.
+func (cb *CommandBuilder) Refresh() []string {
+	return cb.build([]string{"apply"}, refreshFlags)
+}
+
+// Plan returns the arguments of the "terraform plan" command.
+func (cb *CommandBuilder) Plan() []string {
+	return cb.build([]string{"plan"}, planFlags)
+}
+
+// SavedPlan returns the arguments of the "terraform plan" command that saves
+// the plan to the given file so that exactly the planned changes can be
+// applied later.
+func (cb *CommandBuilder) SavedPlan(planFile string) []string {
+	return append(cb.Plan(), "-out="+planFile)
+}
+
+// DriftPlan returns the arguments of the "terraform plan" command that saves
+// the plan to the given file so that its details can be inspected.
+func (cb *CommandBuilder) DriftPlan(planFile string) []string {
+	return append(cb.build([]string{"plan"}, driftPlanFlags), "-out="+planFile)
+}
+
+// DestroyPlan returns the arguments of the "terraform plan -destroy" command
+// that saves the plan to the given file so that the planned actions can be
+// inspected without destroying anything.
+func (cb *CommandBuilder) DestroyPlan(planFile string) []string {
+	return append(cb.build([]string{"plan"}, destroyPlanFlags), "-out="+planFile)
+}
+
+// Validate returns the arguments of the "terraform validate" command that
+// reports the diagnostics in machine-readable form.
+func (cb *CommandBuilder) Validate() []string {
+	return cb.build([]string{"validate"}, validateFlags)
+}
+
+// Show returns the arguments of the "terraform show" command that prints the
+// given saved plan in machine-readable form.
+func (cb *CommandBuilder) Show(planFile string) []string {
+	return append(cb.build([]string{"show"}, showFlags), planFile)
+}
+
+// Output returns the arguments of the "terraform output" command that prints
+// the root module outputs in machine-readable form.
+func (cb *CommandBuilder) Output() []string {
+	return cb.build([]string{"output"}, outputFlags)
+}
+
+// Import returns the arguments of the "terraform import" command that brings
+// the existing resource with the given ID under the management of the
+// resource with the given address.
+func (cb *CommandBuilder) Import(address, id string) []string {
+	return append(cb.build([]string{"import"}, importFlags), address, id)
+}
+
+func (cb *CommandBuilder) build(cmd []string, flags []cliFlag) []string {
+	args := make([]string, 0, len(cmd)+len(flags))
+	args = append(args, cmd...)
+	for _, f := range flags {
+		if cb.supports(f) {
+			args = append(args, f.flag)
+		}
+	}
+	return args
+}
+
+// supports returns whether the given flag is available in the Terraform CLI
+// version of this CommandBuilder. If the version is not known, we assume the
+// latest supported version is in use.
+func (cb *CommandBuilder) supports(f cliFlag) bool {
+	if cb == nil || cb.version == nil {
+		return f.until == nil
+	}
+	if f.since != nil && cb.version.LessThan(f.since) {
+		return false
+	}
+	if f.until != nil && !cb.version.LessThan(f.until) {
+		return false
+	}
+	return true
+}
+
+// DetectCLIVersion runs "terraform version -json" using the given executor
+// and returns the reported Terraform CLI version.
+func DetectCLIVersion(ctx context.Context, e k8sExec.Interface, binary string) (string, error) {
+	cmd := e.CommandContext(ctx, binary, "version", "-json")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot run terraform version: %s", string(out))
+	}
+	v := struct {
+		Version string `json:"terraform_version"`
+	}{}
+	if err := json.JSParser.Unmarshal(out, &v); err != nil {
+		return "", errors.Wrap(err, "cannot unmarshal terraform version output")
+	}
+	if v.Version == "" {
+		return "", errors.Errorf("cannot find terraform version in output: %s", string(out))
+	}
+	return v.Version, nil
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	goversion "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	k8sExec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+func TestNewCommandBuilder(t *testing.T) {
+	type want struct {
+		version string
+		err     error
+	}
+	cases := map[string]struct {
+		reason  string
+		version string
+		want
+	}{
+		"Supported": {
+			reason:  "A version in the supported range should be accepted",
+			version: "1.2.3",
+			want: want{
+				version: "1.2.3",
+			},
+		},
+		"TooOld": {
+			reason:  "A version older than the minimum supported version should be rejected",
+			version: "0.14.11",
+			want: want{
+				err: errors.Errorf(errFmtUnsupportedCLIVersion, "0.14.11", supportedCLIVersions),
+			},
+		},
+		"NextMajor": {
+			reason:  "A future major version should be rejected",
+			version: "2.0.0",
+			want: want{
+				err: errors.Errorf(errFmtUnsupportedCLIVersion, "2.0.0", supportedCLIVersions),
+			},
+		},
+		"Malformed": {
+			reason:  "An unparsable version should be rejected",
+			version: "not-a-version",
+			want: want{
+				err: errors.Wrapf(errors.New(`Malformed version: not-a-version`), errFmtParseCLIVersion, "not-a-version"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cb, err := NewCommandBuilder(tc.version)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nNewCommandBuilder(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.version, cb.Version()); diff != "" {
+				t.Errorf("\n%s\nNewCommandBuilder(...): -want version, +got version:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCommandBuilderFlags(t *testing.T) {
+	type args struct {
+		cb    *CommandBuilder
+		flags []cliFlag
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want []string
+	}{
+		"UnknownVersion": {
+			reason: "Flags that are deprecated at some version should be skipped if the version is not known",
+			args: args{
+				flags: []cliFlag{
+					{flag: "-always"},
+					{flag: "-old", until: goversion.Must(goversion.NewVersion("1.1.0"))},
+					{flag: "-new", since: goversion.Must(goversion.NewVersion("1.1.0"))},
+				},
+			},
+			want: []string{"cmd", "-always", "-new"},
+		},
+		"OldVersion": {
+			reason: "Only the flags available in an older version should be used",
+			args: args{
+				cb: &CommandBuilder{version: goversion.Must(goversion.NewVersion("1.0.11"))},
+				flags: []cliFlag{
+					{flag: "-always"},
+					{flag: "-old", until: goversion.Must(goversion.NewVersion("1.1.0"))},
+					{flag: "-new", since: goversion.Must(goversion.NewVersion("1.1.0"))},
+				},
+			},
+			want: []string{"cmd", "-always", "-old"},
+		},
+		"NewVersion": {
+			reason: "Only the flags available in a newer version should be used",
+			args: args{
+				cb: &CommandBuilder{version: goversion.Must(goversion.NewVersion("1.1.0"))},
+				flags: []cliFlag{
+					{flag: "-always"},
+					{flag: "-old", until: goversion.Must(goversion.NewVersion("1.1.0"))},
+					{flag: "-new", since: goversion.Must(goversion.NewVersion("1.1.0"))},
+				},
+			},
+			want: []string{"cmd", "-always", "-new"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.args.cb.build([]string{"cmd"}, tc.args.flags)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nbuild(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDetectCLIVersion(t *testing.T) {
+	type want struct {
+		version string
+		err     error
+	}
+	cases := map[string]struct {
+		reason string
+		out    string
+		err    error
+		want
+	}{
+		"Success": {
+			reason: "The version reported by the CLI should be returned",
+			out:    `{"terraform_version":"1.2.3","platform":"linux_amd64","provider_selections":{},"terraform_outdated":false}`,
+			want: want{
+				version: "1.2.3",
+			},
+		},
+		"NoVersion": {
+			reason: "An error should be returned if the output does not contain a version",
+			out:    `{}`,
+			want: want{
+				err: errors.Errorf("cannot find terraform version in output: %s", "{}"),
+			},
+		},
+		"CommandFailed": {
+			reason: "An error should be returned if the CLI cannot be run",
+			out:    "boom",
+			err:    errBoom,
+			want: want{
+				err: errors.Wrapf(errBoom, "cannot run terraform version: %s", "boom"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := &testingexec.FakeExec{
+				CommandScript: []testingexec.FakeCommandAction{
+					func(_ string, _ ...string) k8sExec.Cmd {
+						return &testingexec.FakeCmd{
+							OutputScript: []testingexec.FakeAction{
+								func() ([]byte, []byte, error) {
+									return []byte(tc.out), nil, tc.err
+								},
+							},
+						}
+					},
+				},
+			}
+			v, err := DetectCLIVersion(context.TODO(), e, "terraform")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nDetectCLIVersion(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.version, v); diff != "" {
+				t.Errorf("\n%s\nDetectCLIVersion(...): -want version, +got version:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCommandBuilderApply(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		replace []string
+		want    []string
+	}{
+		"NoReplace": {
+			reason: "Only the apply flags should be used if no resource is replaced",
+			want:   []string{"apply", "-auto-approve", "-input=false", "-lock=false", "-json"},
+		},
+		"Replace": {
+			reason:  "The replaced resources should be passed with the -replace flag",
+			replace: []string{"aws_vpc.example"},
+			want:    []string{"apply", "-auto-approve", "-input=false", "-lock=false", "-json", "-replace=aws_vpc.example"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := (&CommandBuilder{}).Apply(tc.replace...)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nApply(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCommandBuilderValidate(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		version string
+		want    []string
+	}{
+		"UnknownVersion": {
+			reason: "The flags of the latest supported version should be used if the version is not known",
+			want:   []string{"validate", "-json", "-no-tests"},
+		},
+		"BeforeTests": {
+			reason:  "The -no-tests flag should not be used before Terraform 1.6",
+			version: "1.5.7",
+			want:    []string{"validate", "-json"},
+		},
+		"WithTests": {
+			reason:  "The -no-tests flag should be used as of Terraform 1.6",
+			version: "1.6.0",
+			want:    []string{"validate", "-json", "-no-tests"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cb := &CommandBuilder{}
+			if tc.version != "" {
+				cb.version = goversion.Must(goversion.NewVersion(tc.version))
+			}
+			if diff := cmp.Diff(tc.want, cb.Validate()); diff != "" {
+				t.Errorf("\n%s\nValidate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
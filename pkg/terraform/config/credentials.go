@@ -0,0 +1,271 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config contains composable credential sources and middlewares that
+// populate the Terraform provider configuration, so that the providers do not
+// need to re-implement the credential extraction in their SetupFns.
+package config
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+const (
+	errFmtMissingEnv   = "environment variable %s is not set"
+	errFmtReadFile     = "cannot read credentials file %s"
+	errFmtMissingFile  = "credentials file %s does not exist"
+	errFmtSource       = "cannot configure credentials from source %d"
+	errFmtMissingKey   = "configuration key %s is not set"
+	errNoSourceSucceed = "none of the credential sources could configure the credentials"
+)
+
+// CredentialSource populates the configuration of the Terraform provider in
+// the given Setup with credentials.
+type CredentialSource interface {
+	Configure(ctx context.Context, kube client.Client, mg xpresource.Managed, ts *terraform.Setup) error
+}
+
+// A CredentialSourceFn is a function that satisfies the CredentialSource
+// interface.
+type CredentialSourceFn func(ctx context.Context, kube client.Client, mg xpresource.Managed, ts *terraform.Setup) error
+
+// Configure the credentials using the CredentialSourceFn.
+func (fn CredentialSourceFn) Configure(ctx context.Context, kube client.Client, mg xpresource.Managed, ts *terraform.Setup) error {
+	return fn(ctx, kube, mg, ts)
+}
+
+// NewSetupFn returns a SetupFn that configures the credentials of the Setup
+// returned by the given SetupFn using the given sources in order.
+func NewSetupFn(sf terraform.SetupFn, sources ...CredentialSource) terraform.SetupFn {
+	return func(ctx context.Context, kube client.Client, mg xpresource.Managed) (terraform.Setup, error) {
+		ts, err := sf(ctx, kube, mg)
+		if err != nil {
+			return ts, err
+		}
+		for i, s := range sources {
+			if err := s.Configure(ctx, kube, mg, &ts); err != nil {
+				return ts, errors.Wrapf(err, errFmtSource, i)
+			}
+		}
+		return ts, nil
+	}
+}
+
+// FirstOf returns a CredentialSource that uses the first of the given sources
+// that succeeds, e.g. to fall back to static credentials if the pod is not
+// configured with a workload identity. The configuration changes made by the
+// failing sources are discarded.
+func FirstOf(sources ...CredentialSource) CredentialSource {
+	return CredentialSourceFn(func(ctx context.Context, kube client.Client, mg xpresource.Managed, ts *terraform.Setup) error {
+		errs := make([]string, 0, len(sources))
+		for _, s := range sources {
+			c := copySetup(*ts)
+			err := s.Configure(ctx, kube, mg, &c)
+			if err == nil {
+				*ts = c
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return errors.Errorf("%s: %s", errNoSourceSucceed, strings.Join(errs, "; "))
+	})
+}
+
+// FromEnv returns a CredentialSource that sets the given configuration keys
+// to the values of the environment variables they are mapped to. Keys may
+// be dot-separated paths to configure nested blocks, e.g.
+// "assume_role.role_arn". It fails if any of the variables is not set.
+func FromEnv(keys map[string]string) CredentialSource {
+	return CredentialSourceFn(func(_ context.Context, _ client.Client, _ xpresource.Managed, ts *terraform.Setup) error {
+		for k, env := range keys {
+			v, ok := os.LookupEnv(env)
+			if !ok {
+				return errors.Errorf(errFmtMissingEnv, env)
+			}
+			setValue(ts, k, v)
+		}
+		return nil
+	})
+}
+
+// FromFiles returns a CredentialSource that sets the given configuration keys
+// to the contents of the files they are mapped to, e.g. credentials mounted
+// from a Secret volume. The leading and trailing white space of the contents
+// is trimmed. It fails if any of the files cannot be read.
+func FromFiles(keys map[string]string) CredentialSource {
+	return CredentialSourceFn(func(_ context.Context, _ client.Client, _ xpresource.Managed, ts *terraform.Setup) error {
+		for k, path := range keys {
+			raw, err := os.ReadFile(path) // nolint:gosec
+			if err != nil {
+				return errors.Wrapf(err, errFmtReadFile, path)
+			}
+			setValue(ts, k, strings.TrimSpace(string(raw)))
+		}
+		return nil
+	})
+}
+
+// FromEnvFilePaths returns a CredentialSource that sets the given
+// configuration keys to the paths of the files in the environment variables
+// they are mapped to, e.g. the projected service account token that a
+// workload identity webhook mounts into the pod. It fails if any of the
+// variables is not set or the file it points to does not exist.
+func FromEnvFilePaths(keys map[string]string) CredentialSource {
+	return CredentialSourceFn(func(_ context.Context, _ client.Client, _ xpresource.Managed, ts *terraform.Setup) error {
+		for k, env := range keys {
+			path, ok := os.LookupEnv(env)
+			if !ok {
+				return errors.Errorf(errFmtMissingEnv, env)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return errors.Wrapf(err, errFmtMissingFile, path)
+			}
+			setValue(ts, k, path)
+		}
+		return nil
+	})
+}
+
+// AsFile returns a CredentialSource that moves the string value of the given
+// configuration key, e.g. the contents of a kubeconfig configured by a
+// previous source, into the setup file with the given name and sets the key
+// to the path of that file, for the providers that accept the credentials
+// only as a file on disk. It fails if the key is not set.
+func AsFile(key, name string) CredentialSource {
+	return CredentialSourceFn(func(_ context.Context, _ client.Client, _ xpresource.Managed, ts *terraform.Setup) error {
+		v, ok := getValue(ts, key).(string)
+		if !ok {
+			return errors.Errorf(errFmtMissingKey, key)
+		}
+		files := make(map[string][]byte, len(ts.Files)+1)
+		for n, c := range ts.Files {
+			files[n] = c
+		}
+		files[name] = []byte(v)
+		ts.Files = files
+		setValue(ts, key, terraform.CredentialFilePath(name))
+		return nil
+	})
+}
+
+// AWSWebIdentity returns a CredentialSource that configures the given block
+// of the AWS provider, e.g. "assume_role_with_web_identity", with the role
+// and the web identity token the EKS pod identity webhook injects for IAM
+// Roles for Service Accounts (IRSA).
+func AWSWebIdentity(block string) CredentialSource {
+	return chain(
+		FromEnv(map[string]string{block + ".role_arn": "AWS_ROLE_ARN"}),
+		FromEnvFilePaths(map[string]string{block + ".web_identity_token_file": "AWS_WEB_IDENTITY_TOKEN_FILE"}),
+	)
+}
+
+// AzureWorkloadIdentity returns a CredentialSource that configures the
+// AzureRM provider with the client, the tenant and the federated token the
+// Azure Workload Identity webhook injects.
+func AzureWorkloadIdentity() CredentialSource {
+	return chain(
+		FromEnv(map[string]string{
+			"client_id": "AZURE_CLIENT_ID",
+			"tenant_id": "AZURE_TENANT_ID",
+		}),
+		FromEnvFilePaths(map[string]string{"oidc_token_file_path": "AZURE_FEDERATED_TOKEN_FILE"}),
+		CredentialSourceFn(func(_ context.Context, _ client.Client, _ xpresource.Managed, ts *terraform.Setup) error {
+			setValue(ts, "use_oidc", true)
+			return nil
+		}),
+	)
+}
+
+func chain(sources ...CredentialSource) CredentialSource {
+	return CredentialSourceFn(func(ctx context.Context, kube client.Client, mg xpresource.Managed, ts *terraform.Setup) error {
+		for _, s := range sources {
+			if err := s.Configure(ctx, kube, mg, ts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// setValue sets the value at the given dot-separated path of the provider
+// configuration, creating the intermediate blocks if necessary.
+func setValue(ts *terraform.Setup, path string, v interface{}) {
+	if ts.Configuration == nil {
+		ts.Configuration = terraform.ProviderConfiguration{}
+	}
+	m := map[string]interface{}(ts.Configuration)
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[k] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = v
+}
+
+// getValue returns the value at the given dot-separated path of the provider
+// configuration, or nil if it does not exist.
+func getValue(ts *terraform.Setup, path string) interface{} {
+	var v interface{} = map[string]interface{}(ts.Configuration)
+	for _, k := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+// copySetup returns a copy of the given Setup whose configuration can be
+// modified without affecting the original one.
+func copySetup(ts terraform.Setup) terraform.Setup {
+	ts.Configuration = terraform.ProviderConfiguration(copyMap(ts.Configuration))
+	ts.Env = append([]string(nil), ts.Env...)
+	if ts.Files != nil {
+		files := make(map[string][]byte, len(ts.Files))
+		for n, c := range ts.Files {
+			files[n] = c
+		}
+		ts.Files = files
+	}
+	return ts
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			v = copyMap(nested)
+		}
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+func TestNewSetupFn(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(tokenFile, []byte("token"), 0600); err != nil {
+		t.Fatalf("cannot write token file: %s", err)
+	}
+	if err := os.WriteFile(keyFile, []byte("secret-key\n"), 0600); err != nil {
+		t.Fatalf("cannot write key file: %s", err)
+	}
+	t.Setenv("TEST_REGION", "us-east-1")
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/provider")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+
+	type want struct {
+		setup terraform.Setup
+		err   error
+	}
+	cases := map[string]struct {
+		reason  string
+		sources []CredentialSource
+		want
+	}{
+		"EnvAndFiles": {
+			reason: "Configuration keys should be populated from environment variables and files",
+			sources: []CredentialSource{
+				FromEnv(map[string]string{"region": "TEST_REGION"}),
+				FromFiles(map[string]string{"secret_key": keyFile}),
+			},
+			want: want{
+				setup: terraform.Setup{Configuration: terraform.ProviderConfiguration{
+					"region":     "us-east-1",
+					"secret_key": "secret-key",
+				}},
+			},
+		},
+		"AWSWebIdentity": {
+			reason: "The web identity block should be populated from the variables injected for IRSA",
+			sources: []CredentialSource{
+				AWSWebIdentity("assume_role_with_web_identity"),
+			},
+			want: want{
+				setup: terraform.Setup{Configuration: terraform.ProviderConfiguration{
+					"assume_role_with_web_identity": map[string]interface{}{
+						"role_arn":                "arn:aws:iam::123456789012:role/provider",
+						"web_identity_token_file": tokenFile,
+					},
+				}},
+			},
+		},
+		"FirstOfFallback": {
+			reason: "The first source that succeeds should be used and the changes of the failing ones discarded",
+			sources: []CredentialSource{
+				FirstOf(
+					chain(FromEnv(map[string]string{"region": "TEST_REGION"}), FromEnv(map[string]string{"token": "TEST_MISSING"})),
+					FromFiles(map[string]string{"secret_key": keyFile}),
+				),
+			},
+			want: want{
+				setup: terraform.Setup{Configuration: terraform.ProviderConfiguration{
+					"secret_key": "secret-key",
+				}},
+			},
+		},
+		"AsFile": {
+			reason: "The value of the key should be moved into a setup file referenced from the configuration",
+			sources: []CredentialSource{
+				FromFiles(map[string]string{"credentials": keyFile}),
+				AsFile("credentials", "credentials.json"),
+			},
+			want: want{
+				setup: terraform.Setup{
+					Configuration: terraform.ProviderConfiguration{
+						"credentials": "${abspath(path.root)}/credentials.json",
+					},
+					Files: map[string][]byte{"credentials.json": []byte("secret-key")},
+				},
+			},
+		},
+		"AsFileMissingKey": {
+			reason: "An error should be returned if the key to move into a file is not set",
+			sources: []CredentialSource{
+				AsFile("credentials", "credentials.json"),
+			},
+			want: want{
+				err: errors.Wrapf(errors.Errorf(errFmtMissingKey, "credentials"), errFmtSource, 0),
+			},
+		},
+		"MissingEnv": {
+			reason: "An error should be returned if a required environment variable is not set",
+			sources: []CredentialSource{
+				FromEnv(map[string]string{"token": "TEST_MISSING"}),
+			},
+			want: want{
+				err: errors.Wrapf(errors.Errorf(errFmtMissingEnv, "TEST_MISSING"), errFmtSource, 0),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			sf := NewSetupFn(func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+				return terraform.Setup{}, nil
+			}, tc.sources...)
+			got, err := sf(context.TODO(), nil, nil)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nSetupFn(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.setup, got); diff != "" {
+				t.Errorf("\n%s\nSetupFn(...): -want setup, +got setup:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+// Middleware wraps a SetupFn to build upon the Setup it returns, e.g. to add
+// an assumed role to the base credentials.
+type Middleware func(terraform.SetupFn) terraform.SetupFn
+
+// Chain returns a SetupFn that assembles the provider configuration by
+// running the given middlewares in order on the Setup returned by the given
+// base SetupFn, e.g. base credentials, then an assumed role, then a default
+// region and then custom endpoints. Each middleware works on a copy of the
+// configuration so that a Setup cached by the base SetupFn is not modified.
+func Chain(base terraform.SetupFn, mws ...Middleware) terraform.SetupFn {
+	sf := base
+	for _, mw := range mws {
+		sf = mw(sf)
+	}
+	return sf
+}
+
+// Configure returns a Middleware that configures a copy of the Setup using the
+// given sources in order. The original Setup is returned along with the error
+// if any of the sources fails.
+func Configure(sources ...CredentialSource) Middleware {
+	return func(sf terraform.SetupFn) terraform.SetupFn {
+		return func(ctx context.Context, kube client.Client, mg xpresource.Managed) (terraform.Setup, error) {
+			ts, err := sf(ctx, kube, mg)
+			if err != nil {
+				return ts, err
+			}
+			c := copySetup(ts)
+			for _, s := range sources {
+				if err := s.Configure(ctx, kube, mg, &c); err != nil {
+					return ts, err
+				}
+			}
+			return c, nil
+		}
+	}
+}
+
+// AssumeRole returns a Middleware that makes the AWS provider assume the role
+// with the given ARN using the credentials configured so far. The session
+// name is set if it is not empty.
+func AssumeRole(roleARN, sessionName string) Middleware {
+	return Configure(CredentialSourceFn(func(_ context.Context, _ client.Client, _ xpresource.Managed, ts *terraform.Setup) error {
+		setValue(ts, "assume_role.role_arn", roleARN)
+		if sessionName != "" {
+			setValue(ts, "assume_role.session_name", sessionName)
+		}
+		return nil
+	}))
+}
+
+// Default returns a Middleware that sets the given configuration key, e.g.
+// "region", to the given value if it is not configured yet.
+func Default(key string, v interface{}) Middleware {
+	return Configure(CredentialSourceFn(func(_ context.Context, _ client.Client, _ xpresource.Managed, ts *terraform.Setup) error {
+		if getValue(ts, key) == nil {
+			setValue(ts, key, v)
+		}
+		return nil
+	}))
+}
+
+// Endpoints returns a Middleware that points the services of the provider to
+// the given custom endpoints in the given block, e.g. "endpoints" for the AWS
+// provider, keyed by the service names, e.g. {"s3": "http://localhost:4566"}.
+func Endpoints(block string, endpoints map[string]string) Middleware {
+	return Configure(CredentialSourceFn(func(_ context.Context, _ client.Client, _ xpresource.Managed, ts *terraform.Setup) error {
+		for svc, url := range endpoints {
+			setValue(ts, block+"."+svc, url)
+		}
+		return nil
+	}))
+}
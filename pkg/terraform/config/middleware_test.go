@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+func TestChain(t *testing.T) {
+	errBoom := errors.New("boom")
+	type want struct {
+		config terraform.ProviderConfiguration
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		base   terraform.ProviderConfiguration
+		mws    []Middleware
+		want
+	}{
+		"Assembled": {
+			reason: "The middlewares should build upon the base configuration in order",
+			base:   terraform.ProviderConfiguration{"access_key": "key"},
+			mws: []Middleware{
+				AssumeRole("arn:aws:iam::123456789012:role/crossplane", "terrajet"),
+				Default("region", "us-east-1"),
+				Endpoints("endpoints", map[string]string{"s3": "http://localhost:4566"}),
+			},
+			want: want{
+				config: terraform.ProviderConfiguration{
+					"access_key": "key",
+					"assume_role": map[string]interface{}{
+						"role_arn":     "arn:aws:iam::123456789012:role/crossplane",
+						"session_name": "terrajet",
+					},
+					"region": "us-east-1",
+					"endpoints": map[string]interface{}{
+						"s3": "http://localhost:4566",
+					},
+				},
+			},
+		},
+		"DefaultNotOverridden": {
+			reason: "A default should not override the value configured before",
+			base:   terraform.ProviderConfiguration{"region": "eu-west-1"},
+			mws:    []Middleware{Default("region", "us-east-1")},
+			want: want{
+				config: terraform.ProviderConfiguration{"region": "eu-west-1"},
+			},
+		},
+		"SourceFailed": {
+			reason: "The error of a failing source should be returned",
+			base:   terraform.ProviderConfiguration{},
+			mws: []Middleware{Configure(CredentialSourceFn(func(_ context.Context, _ client.Client, _ xpresource.Managed, _ *terraform.Setup) error {
+				return errBoom
+			}))},
+			want: want{
+				config: terraform.ProviderConfiguration{},
+				err:    errBoom,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			base := terraform.Setup{Configuration: tc.base}
+			sf := Chain(func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+				return base, nil
+			}, tc.mws...)
+			ts, err := sf(context.TODO(), nil, nil)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nChain(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.config, ts.Configuration); diff != "" {
+				t.Errorf("\n%s\nChain(...): -want configuration, +got configuration:\n%s", tc.reason, diff)
+			}
+			if _, ok := base.Configuration["assume_role"]; ok {
+				t.Errorf("\n%s\nChain(...): the configuration returned by the base SetupFn is modified", tc.reason)
+			}
+		})
+	}
+}
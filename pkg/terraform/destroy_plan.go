@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	destroyPlanFile = "destroy.tfplan"
+)
+
+// PlannedChange is a change a Terraform operation would make to a resource
+// managed by the provider.
+type PlannedChange struct {
+	// Address of the resource, e.g. "aws_vpc.example".
+	Address string
+	// Type of the resource, e.g. "aws_vpc".
+	Type string
+	// Name of the resource in the configuration.
+	Name string
+	// Actions that would be performed on the resource, e.g. ["delete"].
+	Actions []string
+}
+
+// DestroyPlan lists the changes a destroy operation would make.
+type DestroyPlan struct {
+	Changes []PlannedChange
+}
+
+// String returns a human-readable summary of the planned changes.
+func (p DestroyPlan) String() string {
+	if len(p.Changes) == 0 {
+		return "destroy would not change any resource"
+	}
+	changes := make([]string, len(p.Changes))
+	for i, c := range p.Changes {
+		changes[i] = fmt.Sprintf("%s %s", strings.Join(c.Actions, ","), c.Address)
+	}
+	return fmt.Sprintf("destroy would make %d change(s): %s", len(p.Changes), strings.Join(changes, "; "))
+}
+
+// PlanDestroy makes a blocking terraform plan -destroy call and reports the
+// resources and the actions a destroy operation would perform on them,
+// without changing anything, so that the impact of a deletion can be
+// assessed beforehand.
+func (w *Workspace) PlanDestroy(ctx context.Context) (DestroyPlan, error) {
+	if err := w.awaitOperation(ctx, "plan-destroy"); err != nil {
+		return DestroyPlan{}, err
+	}
+	p, err := w.savedPlan(ctx, w.cli.DestroyPlan(destroyPlanFile), destroyPlanFile)
+	if err != nil {
+		return DestroyPlan{}, err
+	}
+	result := DestroyPlan{}
+	for _, rc := range p.ResourceChanges {
+		if rc.Mode != "managed" || isNoOp(rc.Change.Actions) {
+			continue
+		}
+		result.Changes = append(result.Changes, PlannedChange{
+			Address: rc.Address,
+			Type:    rc.Type,
+			Name:    rc.Name,
+			Actions: rc.Change.Actions,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+func TestWorkspacePlanDestroy(t *testing.T) {
+	type want struct {
+		plan DestroyPlan
+		err  error
+	}
+	cases := map[string]struct {
+		reason  string
+		planErr error
+		show    string
+		want
+	}{
+		"Success": {
+			reason: "The resources a destroy would change should be reported with their actions",
+			show:   `{"resource_changes":[{"address":"aws_vpc.example","mode":"managed","type":"aws_vpc","name":"example","change":{"actions":["delete"]}},{"address":"data.aws_ami.x","mode":"data","type":"aws_ami","name":"x","change":{"actions":["read"]}}]}`,
+			want: want{
+				plan: DestroyPlan{Changes: []PlannedChange{
+					{Address: "aws_vpc.example", Type: "aws_vpc", Name: "example", Actions: []string{"delete"}},
+				}},
+			},
+		},
+		"PlanFailed": {
+			reason:  "Failure of plan should be reported",
+			planErr: errBoom,
+			want: want{
+				err: tferrors.NewPlanFailed(nil),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := NewWorkspace(directory, WithExecutor(newFakeDriftExec(tc.planErr, tc.show)), WithAferoFs(afero.NewMemMapFs()))
+			p, err := w.PlanDestroy(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nPlanDestroy(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.plan, p); diff != "" {
+				t.Errorf("\n%s\nPlanDestroy(...): -want plan, +got plan:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+)
+
+// workspaceUIDFile is the file in the workspace directories that holds the
+// UID of the resource the directory is created for.
+const workspaceUIDFile = ".uid"
+
+// WorkspaceDirFn returns the path of the workspace directory of the given
+// resource relative to the base directory of the workspaces.
+type WorkspaceDirFn func(tr resource.Terraformed) string
+
+// UIDWorkspaceDir keys the workspace directories with the UIDs of the
+// resources. It is the default convention.
+func UIDWorkspaceDir(tr resource.Terraformed) string {
+	return string(tr.GetUID())
+}
+
+// TypeNameWorkspaceDir keys the workspace directories with the Terraform
+// resource types and the names of the resources, which identify a resource
+// as its kind does since every kind is generated from a single Terraform
+// resource type. Unlike UIDs, they make the workspace of a resource easy to
+// find. A resource re-created with the same name, e.g. during a backup
+// restore, gets a new UID, so its workspace directory is cleared when it's
+// first used and its state is reproduced from the resource, see
+// workspaceUIDFile.
+func TypeNameWorkspaceDir(tr resource.Terraformed) string {
+	return filepath.Join(tr.GetTerraformResourceType(), tr.GetName())
+}
+
+// migrateWorkspaceDir relocates the workspace directory of the given resource
+// to the given directory if it does not exist yet but a directory following
+// one of the legacy conventions does, so that the state of the resources
+// is not stranded when the convention changes. The workspace kept in the
+// store for the resource, if any, is updated to work in the new directory.
+func (ws *WorkspaceStore) migrateWorkspaceDir(tr resource.Terraformed, base, dir string) error {
+	_, err := ws.fs.Stat(dir)
+	if xpresource.Ignore(os.IsNotExist, err) != nil {
+		return errors.Wrap(err, "cannot stat workspace directory")
+	}
+	if err == nil {
+		return nil
+	}
+	for _, fn := range ws.legacyDirFns {
+		old := filepath.Join(base, fn(tr))
+		if old == dir {
+			continue
+		}
+		_, err := ws.fs.Stat(old)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "cannot stat legacy workspace directory %s", old)
+		}
+		if err := ws.fs.MkdirAll(filepath.Dir(dir), os.ModePerm); err != nil {
+			return errors.Wrap(err, "cannot create parent of workspace directory")
+		}
+		if err := ws.fs.Rename(old, dir); err != nil {
+			return errors.Wrapf(err, "cannot move legacy workspace directory %s", old)
+		}
+		ws.mu.Lock()
+		if w, ok := ws.store[tr.GetUID()]; ok && w.dir == old {
+			w.dir = dir
+		}
+		ws.mu.Unlock()
+		ws.logger.Debug("Moved legacy workspace directory", "from", old, "to", dir)
+		return nil
+	}
+	return nil
+}
+
+// claimWorkspaceDir makes sure the given workspace directory belongs to the
+// given resource, unless its workspace is already in the store. The directory
+// keeps the UID of the resource it's created for and it's cleared if it's
+// been created for another resource, e.g. a deleted resource of the same name
+// whose directory has not been removed, so that the state of that resource
+// is not used. The directories without a UID, e.g. the ones created by
+// earlier versions, are claimed by the given resource.
+func (ws *WorkspaceStore) claimWorkspaceDir(tr resource.Terraformed, dir string) error {
+	ws.mu.Lock()
+	_, ok := ws.store[tr.GetUID()]
+	ws.mu.Unlock()
+	if ok {
+		return nil
+	}
+	f := filepath.Join(dir, workspaceUIDFile)
+	uid, err := ws.fs.ReadFile(f)
+	switch {
+	case err == nil && string(uid) == string(tr.GetUID()):
+		return nil
+	case err == nil:
+		ws.logger.Debug("Clearing the workspace directory of another resource", logKeyWorkspace, dir, logKeyUID, string(uid))
+		if err := ws.fs.RemoveAll(dir); err != nil {
+			return errors.Wrap(err, "cannot clear the workspace directory of another resource")
+		}
+	case !os.IsNotExist(err):
+		return errors.Wrap(err, "cannot read the UID of the workspace directory")
+	}
+	if err := ws.fs.MkdirAll(dir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "cannot create directory for workspace")
+	}
+	return errors.Wrap(writeFileAtomic(ws.fs, f, []byte(tr.GetUID()), 0600), "cannot write the UID of the workspace directory")
+}
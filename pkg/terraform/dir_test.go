@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+)
+
+func TestMigrateWorkspaceDir(t *testing.T) {
+	tr := &fake.Terraformed{
+		Managed:          xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Name: "example", UID: "some-uid"}},
+		MetadataProvider: fake.MetadataProvider{Type: "aws_vpc"},
+	}
+	type want struct {
+		state     string
+		legacyDir bool
+	}
+	cases := map[string]struct {
+		reason   string
+		existing []string
+		legacy   []WorkspaceDirFn
+		want
+	}{
+		"Migrated": {
+			reason:   "A workspace in a legacy directory should be moved to the new directory",
+			existing: []string{"some-uid"},
+			legacy:   []WorkspaceDirFn{UIDWorkspaceDir},
+			want: want{
+				state: "some-uid",
+			},
+		},
+		"AlreadyMigrated": {
+			reason:   "A workspace in the new directory should be kept even if a legacy one exists",
+			existing: []string{"some-uid", filepath.Join("aws_vpc", "example")},
+			legacy:   []WorkspaceDirFn{UIDWorkspaceDir},
+			want: want{
+				state:     filepath.Join("aws_vpc", "example"),
+				legacyDir: true,
+			},
+		},
+		"NoLegacyConvention": {
+			reason:   "Workspaces should not be moved if no legacy convention is configured",
+			existing: []string{"some-uid"},
+			want: want{
+				legacyDir: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			base := t.TempDir()
+			for _, d := range tc.existing {
+				if err := os.MkdirAll(filepath.Join(base, d), os.ModePerm); err != nil {
+					t.Fatalf("cannot create workspace directory: %s", err)
+				}
+				if err := os.WriteFile(filepath.Join(base, d, "terraform.tfstate"), []byte(d), 0600); err != nil {
+					t.Fatalf("cannot write state file: %s", err)
+				}
+			}
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(afero.NewOsFs()), WithWorkspaceDir(TypeNameWorkspaceDir, tc.legacy...))
+			dir := filepath.Join(base, TypeNameWorkspaceDir(tr))
+			if err := ws.migrateWorkspaceDir(tr, base, dir); err != nil {
+				t.Fatalf("\n%s\nmigrateWorkspaceDir(...): unexpected error: %s", tc.reason, err)
+			}
+			state, _ := os.ReadFile(filepath.Join(dir, "terraform.tfstate"))
+			if diff := cmp.Diff(tc.want.state, string(state)); diff != "" {
+				t.Errorf("\n%s\nmigrateWorkspaceDir(...): -want state, +got state:\n%s", tc.reason, diff)
+			}
+			_, err := os.Stat(filepath.Join(base, "some-uid"))
+			if diff := cmp.Diff(tc.want.legacyDir, err == nil); diff != "" {
+				t.Errorf("\n%s\nmigrateWorkspaceDir(...): -want legacy directory, +got legacy directory:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestClaimWorkspaceDir(t *testing.T) {
+	tr := &fake.Terraformed{
+		Managed:          xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Name: "example", UID: "some-uid"}},
+		MetadataProvider: fake.MetadataProvider{Type: "aws_vpc"},
+	}
+	type want struct {
+		state string
+		uid   string
+	}
+	cases := map[string]struct {
+		reason string
+		uid    string
+		want
+	}{
+		"SameResource": {
+			reason: "The workspace directory of the same resource should be kept",
+			uid:    "some-uid",
+			want: want{
+				state: "state",
+				uid:   "some-uid",
+			},
+		},
+		"AnotherResource": {
+			reason: "The workspace directory of another resource of the same name should be cleared",
+			uid:    "another-uid",
+			want: want{
+				uid: "some-uid",
+			},
+		},
+		"NoUID": {
+			reason: "A workspace directory without a UID should be claimed by the resource",
+			want: want{
+				state: "state",
+				uid:   "some-uid",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			dir := filepath.Join("/ws", TypeNameWorkspaceDir(tr))
+			_ = afero.WriteFile(fs, filepath.Join(dir, "terraform.tfstate"), []byte("state"), 0600)
+			if tc.uid != "" {
+				_ = afero.WriteFile(fs, filepath.Join(dir, workspaceUIDFile), []byte(tc.uid), 0600)
+			}
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(fs), WithWorkspaceDir(TypeNameWorkspaceDir))
+			if err := ws.claimWorkspaceDir(tr, dir); err != nil {
+				t.Fatalf("\n%s\nclaimWorkspaceDir(...): unexpected error: %s", tc.reason, err)
+			}
+			state, _ := afero.ReadFile(fs, filepath.Join(dir, "terraform.tfstate"))
+			if diff := cmp.Diff(tc.want.state, string(state)); diff != "" {
+				t.Errorf("\n%s\nclaimWorkspaceDir(...): -want state, +got state:\n%s", tc.reason, diff)
+			}
+			uid, _ := afero.ReadFile(fs, filepath.Join(dir, workspaceUIDFile))
+			if diff := cmp.Diff(tc.want.uid, string(uid)); diff != "" {
+				t.Errorf("\n%s\nclaimWorkspaceDir(...): -want UID, +got UID:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
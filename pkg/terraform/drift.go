@@ -0,0 +1,351 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+const (
+	driftPlanFile = "drift.tfplan"
+
+	sensitiveValue = "(sensitive)"
+)
+
+// DriftSource is the origin of a drift.
+type DriftSource string
+
+// Drift sources.
+const (
+	// DriftSourceSpec means that the desired value has changed while the
+	// actual value stayed the same since the latest successful apply.
+	DriftSourceSpec DriftSource = "SpecChange"
+	// DriftSourceExternal means that the actual value has been changed
+	// outside of the provider since the latest successful apply.
+	DriftSourceExternal DriftSource = "External"
+)
+
+// FieldDrift is the drift of a single Terraform attribute.
+type FieldDrift struct {
+	// Path of the attribute, e.g. "tags.env" or "ingress[0].from_port".
+	Path string
+	// Expected is the JSON representation of the desired value.
+	Expected string
+	// Actual is the JSON representation of the observed value.
+	Actual string
+	// Source tells whether the drift is caused by a change in the spec or by
+	// an external change.
+	Source DriftSource
+}
+
+// DriftReport lists the attributes whose observed values differ from the
+// desired ones.
+type DriftReport struct {
+	Fields []FieldDrift
+}
+
+// HasDrift returns whether any drift is reported.
+func (r DriftReport) HasDrift() bool {
+	return len(r.Fields) != 0
+}
+
+// String returns a human-readable summary of the drift.
+func (r DriftReport) String() string {
+	if !r.HasDrift() {
+		return "no drift detected"
+	}
+	fields := make([]string, len(r.Fields))
+	for i, f := range r.Fields {
+		fields[i] = fmt.Sprintf("%s: expected %s, actual %s (%s)", f.Path, f.Expected, f.Actual, f.Source)
+	}
+	return strings.Join(fields, "; ")
+}
+
+type planChange struct {
+	Actions         []string    `json:"actions"`
+	Before          interface{} `json:"before"`
+	After           interface{} `json:"after"`
+	AfterUnknown    interface{} `json:"after_unknown"`
+	BeforeSensitive interface{} `json:"before_sensitive"`
+	AfterSensitive  interface{} `json:"after_sensitive"`
+}
+
+type planRepresentation struct {
+	ResourceChanges []struct {
+		Address string     `json:"address"`
+		Mode    string     `json:"mode"`
+		Type    string     `json:"type"`
+		Name    string     `json:"name"`
+		Change  planChange `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// Drift makes a blocking terraform plan call and reports the attributes whose
+// desired values differ from the observed ones. The source of a drift is
+// determined by comparing the observed value to the one the latest successful
+// apply left, or to the first one observed if the workspace hasn't applied
+// yet. It is reported as a spec change if there is no such value.
+func (w *Workspace) Drift(ctx context.Context) (DriftReport, error) {
+	if err := w.awaitOperation(ctx, "drift"); err != nil {
+		return DriftReport{}, err
+	}
+	p, err := w.savedPlan(ctx, w.cli.DriftPlan(driftPlanFile), driftPlanFile)
+	if err != nil {
+		return DriftReport{}, err
+	}
+	previous, err := w.appliedAttributes()
+	if err != nil {
+		return DriftReport{}, err
+	}
+	report := DriftReport{}
+	for _, rc := range p.ResourceChanges {
+		if rc.Mode != "managed" || isNoOp(rc.Change.Actions) {
+			continue
+		}
+		report.Fields = append(report.Fields, fieldDrifts(rc.Change, previous)...)
+	}
+	sort.Slice(report.Fields, func(i, j int) bool {
+		return report.Fields[i].Path < report.Fields[j].Path
+	})
+	return report, nil
+}
+
+// Diff is the difference between the desired and the observed state of the
+// resource.
+type Diff struct {
+	// Actions Terraform would perform on the resource to reconcile the
+	// difference, e.g. ["update"] or ["delete", "create"] if it would be
+	// replaced. It is empty if there is no difference.
+	Actions []string
+	// Fields are the attributes whose desired values differ from the
+	// observed ones.
+	Fields []FieldDrift
+}
+
+// HasDiff returns whether the desired and the observed state differ.
+func (d Diff) HasDiff() bool {
+	return len(d.Actions) != 0
+}
+
+// Diff makes a blocking terraform plan call and reports the actions that would
+// be performed on the resource of the Workspace along with the attributes
+// whose desired values differ from the observed ones, without changing
+// anything.
+func (w *Workspace) Diff(ctx context.Context) (Diff, error) {
+	if err := w.awaitOperation(ctx, "diff"); err != nil {
+		return Diff{}, err
+	}
+	p, err := w.savedPlan(ctx, w.cli.DriftPlan(driftPlanFile), driftPlanFile)
+	if err != nil {
+		return Diff{}, err
+	}
+	previous, err := w.appliedAttributes()
+	if err != nil {
+		return Diff{}, err
+	}
+	d := Diff{}
+	for _, rc := range p.ResourceChanges {
+		if rc.Mode != "managed" || isNoOp(rc.Change.Actions) || (w.address != "" && rc.Address != w.address) {
+			continue
+		}
+		d.Actions = append(d.Actions, rc.Change.Actions...)
+		d.Fields = append(d.Fields, fieldDrifts(rc.Change, previous)...)
+	}
+	sort.Slice(d.Fields, func(i, j int) bool {
+		return d.Fields[i].Path < d.Fields[j].Path
+	})
+	return d, nil
+}
+
+// appliedAttributes returns the flattened attributes the latest successful
+// apply left, if any.
+func (w *Workspace) appliedAttributes() (map[string]interface{}, error) {
+	w.lockExec()
+	applied := w.applied
+	w.unlockExec()
+	if len(applied) == 0 {
+		return nil, nil
+	}
+	var attr interface{}
+	if err := json.JSParser.Unmarshal(applied, &attr); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal applied state attributes")
+	}
+	return flatten(attr), nil
+}
+
+// savedPlan runs the given plan command that saves the plan to the given
+// file and returns the machine-readable representation of the plan.
+func (w *Workspace) savedPlan(ctx context.Context, args []string, planFile string) (*planRepresentation, error) {
+	w.lockExec()
+	defer w.unlockExec()
+	ctx, cancel := w.withCommandTimeout(ctx)
+	defer cancel()
+	defer w.fs.Remove(filepath.Join(w.dir, planFile)) // nolint:errcheck
+	cmd := w.executor.CommandContext(ctx, w.terraformPath, args...)
+	cmd.SetEnv(w.environ(w.env))
+	cmd.SetDir(w.dir)
+	out, err := w.combinedOutput(ctx, "plan", cmd)
+	w.log("plan").Debug(msgCommandEnded, "file", planFile, "out", string(out))
+	if err != nil {
+		return nil, w.operationFailed("plan", tferrors.NewPlanFailed(out, w.errorOptions("plan", out)...))
+	}
+	cmd = w.executor.CommandContext(ctx, w.terraformPath, w.cli.Show(planFile)...)
+	cmd.SetEnv(w.environ(w.env))
+	cmd.SetDir(w.dir)
+	out, err = cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot show the saved plan: %s", string(out))
+	}
+	p := &planRepresentation{}
+	if err := json.JSParser.Unmarshal(out, p); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal the saved plan")
+	}
+	return p, nil
+}
+
+func fieldDrifts(c planChange, previous map[string]interface{}) []FieldDrift {
+	actual, expected := flatten(c.Before), flatten(c.After)
+	unknown := trueLeaves(c.AfterUnknown)
+	sensitive := append(trueLeaves(c.BeforeSensitive), trueLeaves(c.AfterSensitive)...)
+	paths := map[string]struct{}{}
+	for p := range actual {
+		paths[p] = struct{}{}
+	}
+	for p := range expected {
+		paths[p] = struct{}{}
+	}
+	var result []FieldDrift
+	for p := range paths {
+		a, e := actual[p], expected[p]
+		if equalValues(a, e) || underAny(p, unknown) {
+			continue
+		}
+		f := FieldDrift{
+			Path:     p,
+			Expected: formatValue(e),
+			Actual:   formatValue(a),
+			Source:   DriftSourceSpec,
+		}
+		if previous != nil && !equalValues(previous[p], a) {
+			f.Source = DriftSourceExternal
+		}
+		if underAny(p, sensitive) {
+			f.Expected, f.Actual = sensitiveValue, sensitiveValue
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+func isNoOp(actions []string) bool {
+	return len(actions) == 1 && (actions[0] == "no-op" || actions[0] == "read")
+}
+
+// flatten returns the leaf values of the given JSON value keyed by their
+// paths. Empty collections are treated as leaves.
+func flatten(v interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	flattenInto("", v, result)
+	return result
+}
+
+func flattenInto(path string, v interface{}, out map[string]interface{}) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if len(x) == 0 && path != "" {
+			out[path] = x
+			return
+		}
+		for k, e := range x {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			flattenInto(p, e, out)
+		}
+	case []interface{}:
+		if len(x) == 0 {
+			out[path] = x
+			return
+		}
+		for i, e := range x {
+			flattenInto(fmt.Sprintf("%s[%d]", path, i), e, out)
+		}
+	default:
+		if path != "" {
+			out[path] = x
+		}
+	}
+}
+
+// trueLeaves returns the paths whose values are true in the given JSON value
+// in which Terraform marks unknown or sensitive attributes.
+func trueLeaves(v interface{}) []string {
+	var result []string
+	for p, e := range flatten(v) {
+		if b, ok := e.(bool); ok && b {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func underAny(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if path == p || strings.HasPrefix(path, p+".") || strings.HasPrefix(path, p+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func equalValues(a, b interface{}) bool {
+	if isEmpty(a) && isEmpty(b) {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func isEmpty(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(x) == 0
+	case []interface{}:
+		return len(x) == 0
+	}
+	return false
+}
+
+func formatValue(v interface{}) string {
+	raw, err := json.JSParser.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(raw)
+}
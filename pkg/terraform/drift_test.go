@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	k8sExec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+const driftPlan = `{"resource_changes":[{"mode":"managed","change":{
+"actions":["update"],
+"before":{"id":"some-id","name":"old","tags":{"env":"dev","team":"a"},"password":"p1","arn":"arn"},
+"after":{"id":"some-id","name":"new","tags":{"env":"prod","team":"a"},"password":"p2"},
+"after_unknown":{"arn":true},
+"before_sensitive":{"password":true},
+"after_sensitive":{"password":true}}}]}`
+
+func newFakeDriftExec(planErr error, show string) *testingexec.FakeExec {
+	return &testingexec.FakeExec{
+		CommandScript: []testingexec.FakeCommandAction{
+			func(_ string, _ ...string) k8sExec.Cmd {
+				return &testingexec.FakeCmd{
+					CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) {
+							return nil, nil, planErr
+						},
+					},
+				}
+			},
+			func(_ string, _ ...string) k8sExec.Cmd {
+				return &testingexec.FakeCmd{
+					OutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) {
+							return []byte(show), nil, nil
+						},
+					},
+				}
+			},
+		},
+	}
+}
+
+func TestWorkspaceDrift(t *testing.T) {
+	type args struct {
+		w *Workspace
+	}
+	type want struct {
+		report DriftReport
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NoAppliedState": {
+			reason: "Differing attributes should be reported as spec changes if there is no applied state",
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeDriftExec(nil, driftPlan)), WithAferoFs(afero.NewMemMapFs())),
+			},
+			want: want{
+				report: DriftReport{Fields: []FieldDrift{
+					{Path: "name", Expected: `"new"`, Actual: `"old"`, Source: DriftSourceSpec},
+					{Path: "password", Expected: sensitiveValue, Actual: sensitiveValue, Source: DriftSourceSpec},
+					{Path: "tags.env", Expected: `"prod"`, Actual: `"dev"`, Source: DriftSourceSpec},
+				}},
+			},
+		},
+		"ExternalChange": {
+			reason: "Attributes whose observed values changed since the latest apply should be reported as external changes",
+			args: args{
+				w: func() *Workspace {
+					w := NewWorkspace(directory, WithExecutor(newFakeDriftExec(nil, driftPlan)), WithAferoFs(afero.NewMemMapFs()))
+					w.applied = []byte(`{"id":"some-id","name":"old","tags":{"env":"prod","team":"a"},"password":"p1"}`)
+					return w
+				}(),
+			},
+			want: want{
+				report: DriftReport{Fields: []FieldDrift{
+					{Path: "name", Expected: `"new"`, Actual: `"old"`, Source: DriftSourceSpec},
+					{Path: "password", Expected: sensitiveValue, Actual: sensitiveValue, Source: DriftSourceSpec},
+					{Path: "tags.env", Expected: `"prod"`, Actual: `"dev"`, Source: DriftSourceExternal},
+				}},
+			},
+		},
+		"NoDrift": {
+			reason: "No drift should be reported if the plan has no changes",
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeDriftExec(nil, `{"resource_changes":[{"mode":"managed","change":{"actions":["no-op"]}}]}`)), WithAferoFs(afero.NewMemMapFs())),
+			},
+		},
+		"PlanFailed": {
+			reason: "Failure of plan should be reported",
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeDriftExec(errBoom, "")), WithAferoFs(afero.NewMemMapFs())),
+			},
+			want: want{
+				err: tferrors.NewPlanFailed(nil),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r, err := tc.args.w.Drift(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nDrift(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.report, r); diff != "" {
+				t.Errorf("\n%s\nDrift(...): -want report, +got report:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaceDiff(t *testing.T) {
+	plan := `{"resource_changes":[
+{"address":"aws_vpc.example","mode":"managed","change":{"actions":["delete","create"],"before":{"id":"some-id","cidr":"10.0.0.0/16"},"after":{"id":"some-id","cidr":"10.1.0.0/16"}}},
+{"address":"aws_vpc.other","mode":"managed","change":{"actions":["update"],"before":{"name":"old"},"after":{"name":"new"}}}]}`
+	type args struct {
+		w *Workspace
+	}
+	type want struct {
+		diff Diff
+		err  error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Replace": {
+			reason: "The actions and the differing attributes of the resource of the workspace should be reported",
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeDriftExec(nil, plan)), WithAferoFs(afero.NewMemMapFs()), WithResourceAddress("aws_vpc.example")),
+			},
+			want: want{
+				diff: Diff{
+					Actions: []string{"delete", "create"},
+					Fields: []FieldDrift{
+						{Path: "cidr", Expected: `"10.1.0.0/16"`, Actual: `"10.0.0.0/16"`, Source: DriftSourceSpec},
+					},
+				},
+			},
+		},
+		"NoDiff": {
+			reason: "No difference should be reported if the plan has no changes",
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeDriftExec(nil, `{"resource_changes":[{"mode":"managed","change":{"actions":["no-op"]}}]}`)), WithAferoFs(afero.NewMemMapFs())),
+			},
+		},
+		"PlanFailed": {
+			reason: "Failure of plan should be reported",
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeDriftExec(errBoom, "")), WithAferoFs(afero.NewMemMapFs())),
+			},
+			want: want{
+				err: tferrors.NewPlanFailed(nil),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := tc.args.w.Diff(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nDiff(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.diff, d); diff != "" {
+				t.Errorf("\n%s\nDiff(...): -want diff, +got diff:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Class is the class of the failure reported by the diagnostics of a
+// Terraform operation.
+type Class string
+
+// Failure classes.
+const (
+	// ClassUnknown is the class of the failures that couldn't be classified.
+	ClassUnknown Class = "Unknown"
+	// ClassAuthFailure is the class of the failures caused by credentials
+	// rejected by the provider.
+	ClassAuthFailure Class = "AuthFailure"
+	// ClassQuotaExceeded is the class of the failures caused by a quota or a
+	// limit of the account being exceeded.
+	ClassQuotaExceeded Class = "QuotaExceeded"
+	// ClassConflict is the class of the failures caused by the external
+	// resource conflicting with another one or with a concurrent change.
+	ClassConflict Class = "Conflict"
+	// ClassInvalidParameter is the class of the failures caused by the API
+	// rejecting a parameter of the resource.
+	ClassInvalidParameter Class = "InvalidParameter"
+	// ClassThrottled is the class of the failures caused by the API rate
+	// limiting the requests.
+	ClassThrottled Class = "Throttled"
+)
+
+// codePattern matches the tokens of the diagnostics that look like the error
+// codes of the APIs, e.g. "InvalidParameterValue", "alreadyExists" and
+// "RESOURCE_EXHAUSTED".
+var codePattern = regexp.MustCompile(`\b(?:[A-Za-z][a-z0-9]+(?:[A-Z][a-z0-9]*)+|[A-Z]{2}[A-Z0-9]*(?:_[A-Z0-9]+)*)\b`)
+
+// classRule tells the diagnostics of a class of failure by the error codes of
+// the APIs they report and by the phrases of their summaries.
+type classRule struct {
+	class Class
+	// codes are the error codes of the class. An error code whose suffix is
+	// one of them, e.g. "VpcLimitExceeded" for "LimitExceeded", is of the
+	// class, too.
+	codes []string
+	// phrases match the whole words of the summaries, ignoring case.
+	phrases *regexp.Regexp
+}
+
+func (r classRule) matchesCode(code string) bool {
+	code = strings.ToUpper(code[:1]) + code[1:]
+	for _, c := range r.codes {
+		if code == c || (strings.ToUpper(c) != c && strings.HasSuffix(code, c)) {
+			return true
+		}
+	}
+	return false
+}
+
+func phrases(p ...string) *regexp.Regexp {
+	for i := range p {
+		p[i] = regexp.QuoteMeta(p[i])
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(p, "|") + `)\b`)
+}
+
+// classRules are checked in order, so that a throttling error like
+// "RequestLimitExceeded" isn't classified as a quota failure.
+var classRules = []classRule{
+	{
+		class:   ClassAuthFailure,
+		codes:   []string{"AuthFailure", "UnrecognizedClientException", "InvalidClientTokenId", "SignatureDoesNotMatch", "ExpiredToken", "ExpiredTokenException", "UNAUTHENTICATED"},
+		phrases: phrases("invalid_grant", "no valid credential sources", "invalid credentials", "authentication failed", "unauthorized"),
+	},
+	{
+		class:   ClassThrottled,
+		codes:   []string{"Throttling", "ThrottlingException", "RequestLimitExceeded", "RateLimitExceeded", "TooManyRequestsException", "RequestThrottled"},
+		phrases: phrases("throttling", "throttled", "rate exceeded", "rate limit exceeded", "too many requests"),
+	},
+	{
+		class:   ClassQuotaExceeded,
+		codes:   []string{"QuotaExceeded", "QuotaExceededException", "LimitExceeded", "LimitExceededException", "RESOURCE_EXHAUSTED"},
+		phrases: phrases("quota exceeded", "exceeded quota", "exceeds quota", "limit exceeded"),
+	},
+	{
+		class:   ClassConflict,
+		codes:   []string{"ConflictException", "AlreadyExists", "AlreadyExistsException", "ResourceInUse", "ResourceInUseException", "DependencyViolation", "ConcurrentModification", "ConcurrentModificationException", "ALREADY_EXISTS"},
+		phrases: phrases("conflict", "already exists", "concurrent modification"),
+	},
+	{
+		class:   ClassInvalidParameter,
+		codes:   []string{"InvalidParameter", "InvalidParameterValue", "InvalidParameterCombination", "InvalidParameterException", "InvalidParameterValueException", "InvalidArgument", "InvalidInput", "ValidationException", "ValidationError", "MalformedPolicyDocument", "BadRequest", "INVALID_ARGUMENT"},
+		phrases: phrases("invalid parameter", "invalid argument", "invalid value", "bad request"),
+	},
+}
+
+// diagnostic is the part of a Terraform diagnostic its failure is classified
+// by. The error codes are looked for in both its summary and detail, while
+// the phrases are looked for only in its summary, since the detail may quote
+// the configuration.
+type diagnostic struct {
+	summary string
+	detail  string
+}
+
+// classify returns the class of the failure reported by the given
+// diagnostics, which is the class of the first one that can be classified.
+// The error codes a diagnostic reports take precedence over the phrases of
+// its summary.
+func classify(diags ...diagnostic) Class {
+	for _, d := range diags {
+		codes := codePattern.FindAllString(d.summary+"\n"+d.detail, -1)
+		for _, r := range classRules {
+			for _, c := range codes {
+				if r.matchesCode(c) {
+					return r.class
+				}
+			}
+		}
+		for _, r := range classRules {
+			if r.phrases.MatchString(d.summary) {
+				return r.class
+			}
+		}
+	}
+	return ClassUnknown
+}
+
+type classified interface {
+	Class() Class
+}
+
+// Class returns the class of the failure.
+func (t *tfError) Class() Class {
+	if t.class == "" {
+		return ClassUnknown
+	}
+	return t.class
+}
+
+// Class returns ClassAuthFailure.
+func (c *credentialsInvalid) Class() Class {
+	return ClassAuthFailure
+}
+
+// Classify returns the class of the given error of a Terraform operation
+// according to its diagnostics, or ClassUnknown if it cannot be classified,
+// so that a bad credential can be told apart from a quota problem without
+// parsing the error message.
+func Classify(err error) Class {
+	var c classified
+	if !errors.As(err, &c) {
+		return ClassUnknown
+	}
+	return c.Class()
+}
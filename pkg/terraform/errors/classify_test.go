@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestClassify(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want Class
+	}{
+		"NilError": {
+			want: ClassUnknown,
+		},
+		"NotAnOperation": {
+			err:  errors.New("QuotaExceeded"),
+			want: ClassUnknown,
+		},
+		"Unclassified": {
+			err:  NewApplyFailed(errorLog),
+			want: ClassUnknown,
+		},
+		"MarkedCredentialsInvalid": {
+			err:  errors.Wrap(NewCredentialsInvalid(errorBoom), "cannot connect"),
+			want: ClassAuthFailure,
+		},
+		"AuthFailure": {
+			err:  NewApplyFailed([]byte(`{"@level":"error","@message":"Error: creating VPC","diagnostic":{"severity":"error","summary":"creating VPC","detail":"AuthFailure: AWS was not able to validate the provided access credentials"}}`)),
+			want: ClassAuthFailure,
+		},
+		"QuotaExceeded": {
+			err:  NewApplyFailed([]byte(`{"@level":"error","@message":"Error: creating VPC","diagnostic":{"severity":"error","summary":"creating VPC","detail":"VpcLimitExceeded: The maximum number of VPCs has been reached."}}`)),
+			want: ClassQuotaExceeded,
+		},
+		"Throttled": {
+			err:  NewRefreshFailed([]byte(`{"@level":"error","@message":"Error: reading VPC","diagnostic":{"severity":"error","summary":"reading VPC","detail":"RequestLimitExceeded: Request limit exceeded."}}`)),
+			want: ClassThrottled,
+		},
+		"Conflict": {
+			err:  NewApplyFailed([]byte(`{"@level":"error","@message":"Error: creating bucket","diagnostic":{"severity":"error","summary":"creating bucket","detail":"BucketAlreadyExists: The requested bucket name is not available."}}`)),
+			want: ClassConflict,
+		},
+		"InvalidParameter": {
+			err:  NewPlanFailed([]byte(`{"@level":"error","@message":"Error: creating subnet","diagnostic":{"severity":"error","summary":"creating subnet","detail":"InvalidParameterValue: Value (10.0.0.0/33) for parameter cidrBlock is invalid."}}`)),
+			want: ClassInvalidParameter,
+		},
+		"LowerCamelCode": {
+			err:  NewApplyFailed([]byte(`{"@level":"error","@message":"Error: creating Network","diagnostic":{"severity":"error","summary":"Error creating Network: googleapi: Error 409: The resource is in use, alreadyExists","detail":""}}`)),
+			want: ClassConflict,
+		},
+		"UpperSnakeCode": {
+			err:  NewApplyFailed([]byte(`{"@level":"error","@message":"Error: creating Instance","diagnostic":{"severity":"error","summary":"creating Instance","detail":"rpc error: code = RESOURCE_EXHAUSTED desc = out of capacity"}}`)),
+			want: ClassQuotaExceeded,
+		},
+		"PartialWord": {
+			err:  NewApplyFailed([]byte(`{"@level":"error","@message":"Error: creating rule","diagnostic":{"severity":"error","summary":"creating rule: the rule has conflicting priorities","detail":""}}`)),
+			want: ClassUnknown,
+		},
+		"PhraseInDetail": {
+			err:  NewApplyFailed([]byte(`{"@level":"error","@message":"Error: creating policy","diagnostic":{"severity":"error","summary":"creating policy","detail":"the description \"resolve the conflict\" is too long"}}`)),
+			want: ClassUnknown,
+		},
+		"FirstClassifiedDiagnostic": {
+			err: NewApplyFailed([]byte(`{"@level":"error","@message":"Error: something went wrong"}
+{"@level":"error","@message":"Error: Throttling: Rate exceeded"}
+{"@level":"error","@message":"Error: ServiceQuotaExceeded"}`)),
+			want: ClassThrottled,
+		},
+		"WarningsIgnored": {
+			err: NewApplyFailed([]byte(`{"@level":"warn","@message":"Warning: Throttling: Rate exceeded"}
+{"@level":"error","@message":"Error: something went wrong"}`)),
+			want: ClassUnknown,
+		},
+		"Init": {
+			err:  NewInitFailed([]byte("Error: Failed to query available provider packages: 429 Too Many Requests")),
+			want: ClassThrottled,
+		},
+		"Validate": {
+			err:  NewValidateFailed([]ValidationDiagnostic{{Summary: "Invalid value", Detail: "conflict"}}),
+			want: ClassUnknown,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package errors
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
@@ -26,10 +27,92 @@ import (
 
 const (
 	levelError = "error"
+
+	fmtTruncated        = "\n... [%d bytes truncated] ...\n"
+	fmtTruncatedWithLog = "\n... [%d bytes truncated, full output is in %s] ...\n"
 )
 
 type tfError struct {
 	message string
+	// class is the class of the failure according to the diagnostics.
+	class Class
+
+	maxMessageSize int
+	logPath        string
+}
+
+// ErrorOption configures the errors of the Terraform operations.
+type ErrorOption func(*tfError)
+
+// WithMaxMessageSize truncates the messages of the errors that are longer
+// than the given number of bytes by keeping their heads and tails, since
+// provider errors may span multiple pages and end up in status conditions
+// and events. Messages are not truncated if it is not positive.
+func WithMaxMessageSize(n int) ErrorOption {
+	return func(e *tfError) {
+		e.maxMessageSize = n
+	}
+}
+
+// WithLogPath records the path of the file the full output of the failed
+// operation is stored in, so that it is referenced in the truncation marker.
+func WithLogPath(p string) ErrorOption {
+	return func(e *tfError) {
+		e.logPath = p
+	}
+}
+
+// Truncate returns the given message as is if it is not longer than max
+// bytes. Otherwise, it keeps the head and the tail of the message and puts a
+// marker in between that tells how many bytes are truncated and, if given,
+// where the full output is stored. The room for the marker is reserved, so
+// the result is never longer than max bytes, and the message is cut on rune
+// boundaries so that no multi-byte character is split. If max is too small
+// to fit the marker, only the head of the message is kept. The message is
+// not truncated if max is not positive.
+func Truncate(msg string, max int, logPath string) string {
+	if max <= 0 || len(msg) <= max {
+		return msg
+	}
+	marker := func(n int) string {
+		if logPath != "" {
+			return fmt.Sprintf(fmtTruncatedWithLog, n, logPath)
+		}
+		return fmt.Sprintf(fmtTruncated, n)
+	}
+	// NOTE(muvaf): The length of the marker depends on the number of
+	// truncated bytes, which depends on the room left for the message, so
+	// the number is recomputed until the marker fits.
+	truncated := len(msg) - max
+	for {
+		m := marker(truncated)
+		keep := max - len(m)
+		if keep <= 0 {
+			return msg[:runeStart(msg, max)]
+		}
+		head := runeStart(msg, keep/2)
+		t := len(msg) - (keep - keep/2)
+		tail := runeStart(msg, t)
+		if tail < t {
+			// The tail would start in the middle of a rune, so it starts at
+			// the next one instead.
+			_, size := utf8.DecodeRuneInString(msg[tail:])
+			tail += size
+		}
+		if n := tail - head; len(marker(n)) <= len(m) {
+			return msg[:head] + marker(n) + msg[tail:]
+		}
+		truncated = tail - head
+	}
+}
+
+// runeStart returns the largest index that is not greater than the given one
+// and is at the start of a rune in the given string.
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
 }
 
 type applyFailed struct {
@@ -61,10 +144,13 @@ func (t *tfError) Error() string {
 	return t.message
 }
 
-func newTFError(message string, logs []byte) (string, *tfError) {
+func newTFError(message string, logs []byte, opts ...ErrorOption) (string, *tfError) {
 	tfError := &tfError{
 		message: message,
 	}
+	for _, f := range opts {
+		f(tfError)
+	}
 
 	tfLogs, err := parseTerraformLogs(logs)
 	if err != nil {
@@ -72,21 +158,26 @@ func newTFError(message string, logs []byte) (string, *tfError) {
 	}
 
 	messages := make([]string, 0, len(tfLogs))
+	diags := make([]diagnostic, 0, len(tfLogs))
 	for _, l := range tfLogs {
 		// only use error logs
 		if l == nil || l.Level != levelError {
 			continue
 		}
 		m := l.Message
+		d := diagnostic{summary: l.Message}
 		if l.Diagnostic.Severity == levelError && l.Diagnostic.Summary != "" {
 			m = fmt.Sprintf("%s: %s", l.Diagnostic.Summary, l.Diagnostic.Detail)
 			if len(l.Diagnostic.Range.FileName) != 0 {
 				m = m + ": File name: " + l.Diagnostic.Range.FileName
 			}
+			d = diagnostic{summary: l.Diagnostic.Summary, detail: l.Diagnostic.Detail}
 		}
 		messages = append(messages, m)
+		diags = append(diags, d)
 	}
-	tfError.message = fmt.Sprintf("%s: %s", message, strings.Join(messages, "\n"))
+	tfError.class = classify(diags...)
+	tfError.message = Truncate(fmt.Sprintf("%s: %s", message, strings.Join(messages, "\n")), tfError.maxMessageSize, tfError.logPath)
 	return "", tfError
 }
 
@@ -108,13 +199,13 @@ func parseTerraformLogs(logs []byte) ([]*TerraformLog, error) {
 }
 
 // NewApplyFailed returns a new apply failure error with given logs.
-func NewApplyFailed(logs []byte) error {
-	parseError, tfError := newTFError("apply failed", logs)
+func NewApplyFailed(logs []byte, opts ...ErrorOption) error {
+	parseError, tfError := newTFError("apply failed", logs, opts...)
 	result := &applyFailed{tfError: tfError}
 	if parseError == "" {
 		return result
 	}
-	return errors.WithMessage(result, parseError)
+	return errors.WithMessage(result, Truncate(parseError, tfError.maxMessageSize, tfError.logPath))
 }
 
 // IsApplyFailed returns whether error is due to failure of an apply operation.
@@ -128,13 +219,13 @@ type destroyFailed struct {
 }
 
 // NewDestroyFailed returns a new destroy failure error with given logs.
-func NewDestroyFailed(logs []byte) error {
-	parseError, tfError := newTFError("destroy failed", logs)
+func NewDestroyFailed(logs []byte, opts ...ErrorOption) error {
+	parseError, tfError := newTFError("destroy failed", logs, opts...)
 	result := &destroyFailed{tfError: tfError}
 	if parseError == "" {
 		return result
 	}
-	return errors.WithMessage(result, parseError)
+	return errors.WithMessage(result, Truncate(parseError, tfError.maxMessageSize, tfError.logPath))
 }
 
 // IsDestroyFailed returns whether error is due to failure of a destroy operation.
@@ -148,13 +239,13 @@ type refreshFailed struct {
 }
 
 // NewRefreshFailed returns a new destroy failure error with given logs.
-func NewRefreshFailed(logs []byte) error {
-	parseError, tfError := newTFError("refresh failed", logs)
+func NewRefreshFailed(logs []byte, opts ...ErrorOption) error {
+	parseError, tfError := newTFError("refresh failed", logs, opts...)
 	result := &refreshFailed{tfError: tfError}
 	if parseError == "" {
 		return result
 	}
-	return errors.WithMessage(result, parseError)
+	return errors.WithMessage(result, Truncate(parseError, tfError.maxMessageSize, tfError.logPath))
 }
 
 // IsRefreshFailed returns whether error is due to failure of a destroy operation.
@@ -168,13 +259,13 @@ type planFailed struct {
 }
 
 // NewPlanFailed returns a new destroy failure error with given logs.
-func NewPlanFailed(logs []byte) error {
-	parseError, tfError := newTFError("plan failed", logs)
+func NewPlanFailed(logs []byte, opts ...ErrorOption) error {
+	parseError, tfError := newTFError("plan failed", logs, opts...)
 	result := &planFailed{tfError: tfError}
 	if parseError == "" {
 		return result
 	}
-	return errors.WithMessage(result, parseError)
+	return errors.WithMessage(result, Truncate(parseError, tfError.maxMessageSize, tfError.logPath))
 }
 
 // IsPlanFailed returns whether error is due to failure of a destroy operation.
@@ -182,3 +273,210 @@ func IsPlanFailed(err error) bool {
 	r := &planFailed{}
 	return errors.As(err, &r)
 }
+
+type importFailed struct {
+	*tfError
+}
+
+// NewImportFailed returns a new import failure error with the given output of
+// the "terraform import" command, which does not support machine-readable
+// output.
+func NewImportFailed(out []byte, opts ...ErrorOption) error {
+	tfError := &tfError{}
+	for _, f := range opts {
+		f(tfError)
+	}
+	tfError.message = Truncate(fmt.Sprintf("import failed: %s", strings.TrimSpace(string(out))), tfError.maxMessageSize, tfError.logPath)
+	tfError.class = classify(diagnostic{summary: string(out)})
+	return &importFailed{tfError: tfError}
+}
+
+// IsImportFailed returns whether error is due to failure of an import
+// operation.
+func IsImportFailed(err error) bool {
+	r := &importFailed{}
+	return errors.As(err, &r)
+}
+
+// transientInitFailures are the fragments of the "terraform init" output
+// that indicate a failure which may go away on a retry, such as a registry
+// timeout, as opposed to a configuration error.
+var transientInitFailures = []string{
+	"timeout",
+	"timed out",
+	"could not connect to",
+	"connection reset by peer",
+	"connection refused",
+	"no such host",
+	"temporary failure in name resolution",
+	"unexpected eof",
+	"429 too many requests",
+	"502 bad gateway",
+	"503 service unavailable",
+	"504 gateway timeout",
+}
+
+type initFailed struct {
+	*tfError
+	transient bool
+}
+
+// NewInitFailed returns a new init failure error with the given output of
+// the "terraform init" command. The failure is classified as transient if the
+// output indicates a registry or network failure.
+func NewInitFailed(out []byte, opts ...ErrorOption) error {
+	tfError := &tfError{}
+	for _, f := range opts {
+		f(tfError)
+	}
+	tfError.message = Truncate(fmt.Sprintf("init failed: %s", strings.TrimSpace(string(out))), tfError.maxMessageSize, tfError.logPath)
+	tfError.class = classify(diagnostic{summary: string(out)})
+	lower := strings.ToLower(string(out))
+	transient := false
+	for _, f := range transientInitFailures {
+		if strings.Contains(lower, f) {
+			transient = true
+			break
+		}
+	}
+	return &initFailed{tfError: tfError, transient: transient}
+}
+
+// IsInitFailed returns whether error is due to failure of an init operation.
+func IsInitFailed(err error) bool {
+	r := &initFailed{}
+	return errors.As(err, &r)
+}
+
+// IsTransientInitFailed returns whether error is due to failure of an init
+// operation that may succeed if it is retried.
+func IsTransientInitFailed(err error) bool {
+	r := &initFailed{}
+	return errors.As(err, &r) && r.transient
+}
+
+// credentialsFailures are the fragments of Terraform diagnostics that
+// indicate the provider rejected the credentials it was configured with.
+var credentialsFailures = []string{
+	"no valid credential",
+	"invalid credentials",
+	"invalidclienttokenid",
+	"signaturedoesnotmatch",
+	"expiredtoken",
+	"authentication failed",
+	"unauthorized",
+}
+
+// dependencyMissingFailures are the fragments of Terraform diagnostics that
+// indicate an external resource referred to by the configuration does not
+// exist (yet).
+var dependencyMissingFailures = []string{
+	"notfound",
+	"not found",
+	"does not exist",
+}
+
+func containsAny(msg string, fragments []string) bool {
+	lower := strings.ToLower(msg)
+	for _, f := range fragments {
+		if strings.Contains(lower, f) {
+			return true
+		}
+	}
+	return false
+}
+
+type credentialsInvalid struct {
+	error
+}
+
+// NewCredentialsInvalid returns a new error that marks the given error as a
+// failure to prepare valid credentials for the Terraform provider.
+func NewCredentialsInvalid(err error) error {
+	return &credentialsInvalid{error: err}
+}
+
+// Unwrap returns the error marked as a credentials failure.
+func (c *credentialsInvalid) Unwrap() error {
+	return c.error
+}
+
+// IsCredentialsInvalid returns whether error is due to invalid credentials,
+// either because they couldn't be prepared or because the provider rejected
+// them during a Terraform operation.
+func IsCredentialsInvalid(err error) bool {
+	r := &credentialsInvalid{}
+	if errors.As(err, &r) {
+		return true
+	}
+	return isOperationFailed(err) && containsAny(err.Error(), credentialsFailures)
+}
+
+// IsAuthenticationFailure returns whether the given error, e.g. one returned
+// while preparing the credentials of the Terraform provider, reports that the
+// credentials are rejected.
+func IsAuthenticationFailure(err error) bool {
+	return err != nil && containsAny(err.Error(), credentialsFailures)
+}
+
+// IsDependencyMissing returns whether error is due to a Terraform operation
+// failing because an external resource it refers to does not exist.
+func IsDependencyMissing(err error) bool {
+	return (IsApplyFailed(err) || IsPlanFailed(err)) && containsAny(err.Error(), dependencyMissingFailures)
+}
+
+func isOperationFailed(err error) bool {
+	return IsApplyFailed(err) || IsDestroyFailed(err) || IsRefreshFailed(err) || IsPlanFailed(err)
+}
+
+// ValidationDiagnostic is an error reported by "terraform validate" about
+// the generated configuration.
+type ValidationDiagnostic struct {
+	Summary string
+	Detail  string
+	// Path is the field path of the attribute the diagnostic is about in the
+	// managed resource, e.g. "spec.forProvider.cidrBlock", if it could be
+	// determined.
+	Path string
+}
+
+type validateFailed struct {
+	*tfError
+	diagnostics []ValidationDiagnostic
+}
+
+// NewValidateFailed returns a new validation failure error with the given
+// diagnostics.
+func NewValidateFailed(diags []ValidationDiagnostic, opts ...ErrorOption) error {
+	tfError := &tfError{}
+	for _, f := range opts {
+		f(tfError)
+	}
+	messages := make([]string, 0, len(diags))
+	for _, d := range diags {
+		m := fmt.Sprintf("%s: %s", d.Summary, d.Detail)
+		if d.Path != "" {
+			m = fmt.Sprintf("%s: %s", d.Path, m)
+		}
+		messages = append(messages, m)
+	}
+	tfError.message = Truncate(fmt.Sprintf("validation failed: %s", strings.Join(messages, "\n")), tfError.maxMessageSize, tfError.logPath)
+	return &validateFailed{tfError: tfError, diagnostics: diags}
+}
+
+// IsValidateFailed returns whether error is due to the generated
+// configuration being invalid.
+func IsValidateFailed(err error) bool {
+	r := &validateFailed{}
+	return errors.As(err, &r)
+}
+
+// ValidationDiagnostics returns the diagnostics of the given validation
+// failure error, or nil if it's not a validation failure.
+func ValidationDiagnostics(err error) []ValidationDiagnostic {
+	r := &validateFailed{}
+	if !errors.As(err, &r) {
+		return nil
+	}
+	return r.diagnostics
+}
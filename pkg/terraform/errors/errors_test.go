@@ -224,6 +224,7 @@ func TestNewApplyFailed(t *testing.T) {
 	}
 	tests := map[string]struct {
 		args           args
+		opts           []ErrorOption
 		wantErrMessage string
 	}{
 		"ApplyError": {
@@ -232,10 +233,17 @@ func TestNewApplyFailed(t *testing.T) {
 			},
 			wantErrMessage: "apply failed: Missing required argument: The argument \"location\" is required, but no definition was found.: File name: main.tf.json\nMissing required argument: The argument \"name\" is required, but no definition was found.: File name: main.tf.json",
 		},
+		"TruncatedApplyError": {
+			args: args{
+				logs: errorLog,
+			},
+			opts:           []ErrorOption{WithMaxMessageSize(120), WithLogPath("/tmp/ws/apply.log")},
+			wantErrMessage: "apply failed: Missing requ\n... [193 bytes truncated, full output is in /tmp/ws/apply.log] ...\n.: File name: main.tf.json",
+		},
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			err := NewApplyFailed(tt.args.logs)
+			err := NewApplyFailed(tt.args.logs, tt.opts...)
 			got := ""
 			if err != nil {
 				got = err.Error()
@@ -333,3 +341,205 @@ func TestNewPlanFailed(t *testing.T) {
 		})
 	}
 }
+
+func TestTruncate(t *testing.T) {
+	type args struct {
+		msg     string
+		max     int
+		logPath string
+	}
+	tests := map[string]struct {
+		args args
+		want string
+	}{
+		"Short": {
+			args: args{msg: "short message", max: 20},
+			want: "short message",
+		},
+		"NoLimit": {
+			args: args{msg: "short message"},
+			want: "short message",
+		},
+		"Truncated": {
+			args: args{msg: "0123456789abcdefghij0123456789abcdefghij0123456789", max: 40},
+			want: "01234\n... [40 bytes truncated] ...\n56789",
+		},
+		"TruncatedWithLog": {
+			args: args{msg: "0123456789abcdefghij0123456789abcdefghij0123456789abcdefghij0123456789abcdefghij", max: 77, logPath: "/tmp/ws/apply.log"},
+			want: "01234\n... [70 bytes truncated, full output is in /tmp/ws/apply.log] ...\nfghij",
+		},
+		"NoRoomForMarker": {
+			args: args{msg: "0123456789abcdefghij", max: 10},
+			want: "0123456789",
+		},
+		"MultiByte": {
+			args: args{msg: "éééééééééééééééééééééééééééééé", max: 40},
+			want: "éé\n... [52 bytes truncated] ...\néé",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := Truncate(tt.args.msg, tt.args.max, tt.args.logPath)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("\nTruncate(...): -want message, +got message:\n%s", diff)
+			}
+			if tt.args.max > 0 && len(got) > tt.args.max {
+				t.Errorf("\nTruncate(...): got %d bytes, want at most %d", len(got), tt.args.max)
+			}
+		})
+	}
+}
+
+func TestNewInitFailed(t *testing.T) {
+	type want struct {
+		message   string
+		transient bool
+	}
+	tests := map[string]struct {
+		reason string
+		out    []byte
+		want   want
+	}{
+		"RegistryTimeout": {
+			reason: "A registry timeout should be classified as transient",
+			out:    []byte("Error: Failed to query available provider packages\n\nCould not retrieve the list of available versions for provider hashicorp/aws: could not connect to registry.terraform.io: Failed to request discovery document: Get \"https://registry.terraform.io/.well-known/terraform.json\": net/http: request canceled (Client.Timeout exceeded while awaiting headers)\n"),
+			want: want{
+				message:   "init failed: Error: Failed to query available provider packages\n\nCould not retrieve the list of available versions for provider hashicorp/aws: could not connect to registry.terraform.io: Failed to request discovery document: Get \"https://registry.terraform.io/.well-known/terraform.json\": net/http: request canceled (Client.Timeout exceeded while awaiting headers)",
+				transient: true,
+			},
+		},
+		"ConfigurationError": {
+			reason: "A configuration error should not be classified as transient",
+			out:    []byte("Error: Invalid provider version constraint\n"),
+			want: want{
+				message: "init failed: Error: Invalid provider version constraint",
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := NewInitFailed(tt.out)
+			if diff := cmp.Diff(tt.want.message, err.Error()); diff != "" {
+				t.Errorf("\n%s\nNewInitFailed(...): -want message, +got message:\n%s", tt.reason, diff)
+			}
+			if !IsInitFailed(errors.Wrap(err, "cannot init workspace")) {
+				t.Errorf("\n%s\nIsInitFailed(...): wrapped init failure is not recognized", tt.reason)
+			}
+			if diff := cmp.Diff(tt.want.transient, IsTransientInitFailed(errors.Wrap(err, "cannot init workspace"))); diff != "" {
+				t.Errorf("\n%s\nIsTransientInitFailed(...): -want, +got:\n%s", tt.reason, diff)
+			}
+		})
+	}
+}
+
+func TestIsCredentialsInvalid(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"NilError": {},
+		"Marked": {
+			err:  NewCredentialsInvalid(errorBoom),
+			want: true,
+		},
+		"WrappedMarked": {
+			err:  errors.Wrap(NewCredentialsInvalid(errorBoom), "cannot connect"),
+			want: true,
+		},
+		"RejectedByProvider": {
+			err:  NewApplyFailed([]byte(`{"@level":"error","@message":"Error: error creating VPC: UnrecognizedClientException: InvalidClientTokenId","@module":"terraform.ui"}`)),
+			want: true,
+		},
+		"OtherApplyError": {
+			err: NewApplyFailed(errorLog),
+		},
+		"NotAnOperation": {
+			err: errors.New("unauthorized"),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := IsCredentialsInvalid(tt.err); got != tt.want {
+				t.Errorf("IsCredentialsInvalid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAuthenticationFailure(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"NilError": {},
+		"Rejected": {
+			err:  errors.Wrap(errors.New("ExpiredToken: the security token is expired"), "cannot assume role"),
+			want: true,
+		},
+		"Other": {
+			err: errors.New("cannot get referenced ProviderConfig"),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := IsAuthenticationFailure(tt.err); got != tt.want {
+				t.Errorf("IsAuthenticationFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDependencyMissing(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"NilError": {},
+		"ApplyReferenceNotFound": {
+			err:  NewApplyFailed([]byte(`{"@level":"error","@message":"Error: error creating subnet: InvalidVpcID.NotFound: The vpc ID 'vpc-1' does not exist","@module":"terraform.ui"}`)),
+			want: true,
+		},
+		"DestroyNotFound": {
+			err: NewDestroyFailed([]byte(`{"@level":"error","@message":"Error: not found","@module":"terraform.ui"}`)),
+		},
+		"OtherApplyError": {
+			err: NewApplyFailed(errorLog),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := IsDependencyMissing(tt.err); got != tt.want {
+				t.Errorf("IsDependencyMissing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewValidateFailed(t *testing.T) {
+	tests := map[string]struct {
+		diags       []ValidationDiagnostic
+		wantMessage string
+	}{
+		"WithPath": {
+			diags: []ValidationDiagnostic{
+				{Summary: "Missing required argument", Detail: `The argument "region" is required.`, Path: "spec.forProvider.region"},
+				{Summary: "Invalid reference", Detail: "A reference must start with a name."},
+			},
+			wantMessage: "validation failed: spec.forProvider.region: Missing required argument: The argument \"region\" is required.\nInvalid reference: A reference must start with a name.",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := NewValidateFailed(tt.diags)
+			if got := err.Error(); got != tt.wantMessage {
+				t.Errorf("NewValidateFailed() error message = %v, want %v", got, tt.wantMessage)
+			}
+			if !IsValidateFailed(errors.Wrap(err, "wrapped")) {
+				t.Errorf("IsValidateFailed() = false, want true")
+			}
+			if diff := cmp.Diff(tt.diags, ValidationDiagnostics(err)); diff != "" {
+				t.Errorf("ValidationDiagnostics(): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -17,9 +17,14 @@ limitations under the License.
 package terraform
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
@@ -31,6 +36,11 @@ import (
 	"github.com/crossplane/terrajet/pkg/resource/json"
 )
 
+const (
+	errFmtInvalidFileName = "invalid setup file name %q: %s"
+	errGetPrivateRaw      = "cannot get private attributes"
+)
+
 // FileProducerOption allows you to configure FileProducer
 type FileProducerOption func(*FileProducer)
 
@@ -41,6 +51,15 @@ func WithFileSystem(fs afero.Fs) FileProducerOption {
 	}
 }
 
+// WithPrivateRawStore configures the store the private attributes of the
+// Terraform state are read from. Defaults to
+// resource.AnnotationPrivateRawStore.
+func WithPrivateRawStore(s resource.PrivateRawStore) FileProducerOption {
+	return func(fp *FileProducer) {
+		fp.privateRaw = s
+	}
+}
+
 // NewFileProducer returns a new FileProducer.
 func NewFileProducer(ctx context.Context, client resource.SecretClient, dir string, tr resource.Terraformed, ts Setup, cfg *config.Resource, opts ...FileProducerOption) (*FileProducer, error) {
 	fp := &FileProducer{
@@ -49,6 +68,8 @@ func NewFileProducer(ctx context.Context, client resource.SecretClient, dir stri
 		Dir:      dir,
 		Config:   cfg,
 		fs:       afero.Afero{Fs: afero.NewOsFs()},
+
+		privateRaw: resource.AnnotationPrivateRawStore{},
 	}
 	for _, f := range opts {
 		f(fp)
@@ -62,6 +83,7 @@ func NewFileProducer(ctx context.Context, client resource.SecretClient, dir stri
 	}
 	fp.Config.ExternalName.SetIdentifierArgumentFn(params, meta.GetExternalName(tr))
 	fp.parameters = params
+	fp.providerConfig = fp.liftProviderFields()
 
 	obs, err := tr.GetObservation()
 	if err != nil {
@@ -83,9 +105,46 @@ type FileProducer struct {
 	Dir      string
 	Config   *config.Resource
 
-	parameters  map[string]interface{}
-	observation map[string]interface{}
-	fs          afero.Afero
+	parameters     map[string]interface{}
+	observation    map[string]interface{}
+	providerConfig ProviderConfiguration
+	fs             afero.Afero
+	privateRaw     resource.PrivateRawStore
+	mainTFHash     string
+}
+
+// liftProviderFields removes the parameters that are configured to be set in
+// the provider configuration from the resource arguments and returns the
+// provider configuration of the resource with them.
+func (fp *FileProducer) liftProviderFields() ProviderConfiguration {
+	po := fp.Config.ProviderOverrides
+	if po == nil || len(po.Fields) == 0 {
+		return fp.Setup.Configuration
+	}
+	conf := make(ProviderConfiguration, len(fp.Setup.Configuration)+len(po.Fields)+1)
+	for k, v := range fp.Setup.Configuration {
+		conf[k] = v
+	}
+	for param, key := range po.Fields {
+		v, ok := fp.parameters[param]
+		delete(fp.parameters, param)
+		if ok && v != nil {
+			conf[key] = v
+		}
+	}
+	if po.Alias != "" {
+		conf["alias"] = po.Alias
+	}
+	return conf
+}
+
+// providerAlias returns the alias of the provider block the resource uses, or
+// an empty string if it uses the default provider configuration.
+func (fp *FileProducer) providerAlias() string {
+	if po := fp.Config.ProviderOverrides; po != nil && len(po.Fields) != 0 {
+		return po.Alias
+	}
+	return ""
 }
 
 // WriteTFState writes the Terraform state that should exist in the filesystem to
@@ -100,33 +159,42 @@ func (fp *FileProducer) WriteTFState(ctx context.Context) error {
 	for k, v := range fp.observation {
 		base[k] = v
 	}
-	id, err := fp.Config.ExternalName.GetIDFn(ctx, meta.GetExternalName(fp.Resource), fp.parameters, fp.Setup.Configuration)
+	id, err := fp.Config.ExternalName.GetIDFn(ctx, meta.GetExternalName(fp.Resource), fp.parameters, fp.providerConfig)
 	if err != nil {
 		return errors.Wrap(err, "cannot get id")
 	}
 	base["id"] = id
+	if fp.Config.TerraformResource != nil {
+		if base, err = resource.CoerceToSchema(base, fp.Config.TerraformResource.Schema); err != nil {
+			return errors.Wrap(err, "cannot coerce produced state attributes to the schema")
+		}
+	}
 	attr, err := json.JSParser.Marshal(base)
 	if err != nil {
 		return errors.Wrap(err, "cannot marshal produced state attributes")
 	}
-	var privateRaw []byte
-	if pr, ok := fp.Resource.GetAnnotations()[resource.AnnotationKeyPrivateRawAttribute]; ok {
-		privateRaw = []byte(pr)
+	privateRaw, err := fp.privateRaw.GetPrivateRaw(ctx, fp.Resource)
+	if err != nil {
+		return errors.Wrap(err, errGetPrivateRaw)
 	}
 	if privateRaw, err = insertTimeoutsMeta(privateRaw, timeouts(fp.Config.OperationTimeouts)); err != nil {
 		return errors.Wrap(err, "cannot insert timeouts metadata to private raw")
 	}
+	// TODO(muvaf): we should get the full URL from Dockerfile since
+	// providers don't have to be hosted in registry.terraform.io
+	providerConfig := fmt.Sprintf(`provider["registry.terraform.io/%s"]`, fp.Setup.Requirement.Source)
+	if alias := fp.providerAlias(); alias != "" {
+		providerConfig += "." + alias
+	}
 	s := json.NewStateV4()
 	s.TerraformVersion = fp.Setup.Version
 	s.Lineage = string(fp.Resource.GetUID())
 	s.Resources = []json.ResourceStateV4{
 		{
-			Mode: "managed",
-			Type: fp.Resource.GetTerraformResourceType(),
-			Name: fp.Resource.GetName(),
-			// TODO(muvaf): we should get the full URL from Dockerfile since
-			// providers don't have to be hosted in registry.terraform.io
-			ProviderConfig: fmt.Sprintf(`provider["registry.terraform.io/%s"]`, fp.Setup.Requirement.Source),
+			Mode:           "managed",
+			Type:           fp.Resource.GetTerraformResourceType(),
+			Name:           fp.Resource.GetName(),
+			ProviderConfig: providerConfig,
 			Instances: []json.InstanceObjectStateV4{
 				{
 					SchemaVersion: uint64(fp.Resource.GetTerraformSchemaVersion()),
@@ -141,7 +209,7 @@ func (fp *FileProducer) WriteTFState(ctx context.Context) error {
 	if err != nil {
 		return errors.Wrap(err, "cannot marshal state object")
 	}
-	return errors.Wrap(fp.fs.WriteFile(filepath.Join(fp.Dir, "terraform.tfstate"), rawState, 0600), "cannot write tfstate file")
+	return errors.Wrap(fp.writeIfChanged(filepath.Join(fp.Dir, "terraform.tfstate"), rawState), "cannot write tfstate file")
 }
 
 // WriteMainTF writes the content main configuration file that has the desired
@@ -153,29 +221,60 @@ func (fp *FileProducer) WriteMainTF() error {
 		"prevent_destroy": !meta.WasDeleted(fp.Resource),
 	}
 
+	// If the deletion protection of the external resource is to be disabled
+	// before the deletion, we need to configure it as disabled.
+	if dp := fp.Config.DeletionProtection; dp != nil && resource.DeletionProtectionOverridden(fp.Resource) {
+		fp.parameters[dp.FieldName] = false
+	}
+
 	// Add operation timeouts if any timeout configured for the resource
 	if tp := timeouts(fp.Config.OperationTimeouts).asParameter(); len(tp) != 0 {
 		fp.parameters["timeouts"] = tp
 	}
 
+	// NOTE(muvaf): The parameters went through an untyped JSON round-trip,
+	// so we type them by the schema to make sure numbers, sets and the
+	// difference between null and empty values reach Terraform intact.
+	params := fp.parameters
+	if fp.Config.TerraformResource != nil {
+		var err error
+		if params, err = resource.TypedParameters(fp.parameters, fp.Config.TerraformResource.Schema); err != nil {
+			return errors.Wrap(err, "cannot convert parameters to the types in the schema")
+		}
+	}
+
 	// Note(turkenh): To use third party providers, we need to configure
 	// provider name in required_providers.
 	providerSource := strings.Split(fp.Setup.Requirement.Source, "/")
+	providerName := providerSource[len(providerSource)-1]
+
+	// NOTE(muvaf): If the parameters moved into the provider configuration
+	// are configured in an aliased provider block, the default provider
+	// block stays as is and the resource refers to the aliased one.
+	var provider interface{} = fp.providerConfig
+	if alias := fp.providerAlias(); alias != "" {
+		def := fp.Setup.Configuration
+		if def == nil {
+			def = ProviderConfiguration{}
+		}
+		provider = []interface{}{def, fp.providerConfig}
+		params["provider"] = providerName + "." + alias
+	}
 	m := map[string]interface{}{
 		"terraform": map[string]interface{}{
 			"required_providers": map[string]interface{}{
-				providerSource[len(providerSource)-1]: map[string]string{
+				providerName: map[string]string{
 					"source":  fp.Setup.Requirement.Source,
 					"version": fp.Setup.Requirement.Version,
 				},
 			},
 		},
 		"provider": map[string]interface{}{
-			providerSource[len(providerSource)-1]: fp.Setup.Configuration,
+			providerName: provider,
 		},
 		"resource": map[string]interface{}{
 			fp.Resource.GetTerraformResourceType(): map[string]interface{}{
-				fp.Resource.GetName(): fp.parameters,
+				fp.Resource.GetName(): params,
 			},
 		},
 	}
@@ -183,5 +282,94 @@ func (fp *FileProducer) WriteMainTF() error {
 	if err != nil {
 		return errors.Wrap(err, "cannot marshal main hcl object")
 	}
-	return errors.Wrap(fp.fs.WriteFile(filepath.Join(fp.Dir, "main.tf.json"), rawMainTF, 0600), "cannot write maintf file")
+	h := sha256.Sum256(rawMainTF)
+	fp.mainTFHash = hex.EncodeToString(h[:])
+	return errors.Wrap(fp.writeIfChanged(filepath.Join(fp.Dir, "main.tf.json"), rawMainTF), "cannot write maintf file")
+}
+
+// CredentialFilePath returns the Terraform expression that evaluates to the
+// absolute path of the file with the given name in Setup.Files, so that it
+// can be referenced from the provider configuration, e.g. as the value of
+// "credentials" for GCP. The path is absolute since the provider process
+// may not run in the workspace directory.
+func CredentialFilePath(name string) string {
+	return "${abspath(path.root)}/" + name
+}
+
+// WriteFiles writes the files in the Setup into the workspace directory with
+// permissions allowing only the owner to read them.
+func (fp *FileProducer) WriteFiles() error {
+	names := make([]string, 0, len(fp.Setup.Files))
+	for n := range fp.Setup.Files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if err := validateFileName(n); err != nil {
+			return err
+		}
+		if err := fp.writeIfChanged(filepath.Join(fp.Dir, n), fp.Setup.Files[n]); err != nil {
+			return errors.Wrapf(err, "cannot write file %s", n)
+		}
+	}
+	return nil
+}
+
+// validateFileName returns an error if a setup file with the given name
+// would be written outside of the workspace directory, overwrite a file
+// managed by terrajet or be loaded by Terraform as configuration.
+func validateFileName(name string) error {
+	switch {
+	case name == "" || name == "." || name == ".." || filepath.Base(name) != name:
+		return errors.Errorf(errFmtInvalidFileName, name, "it must be the name of a file in the workspace directory")
+	case strings.HasPrefix(name, "terraform.tfstate") || strings.HasPrefix(name, ".terraform"):
+		return errors.Errorf(errFmtInvalidFileName, name, "it is reserved for Terraform")
+	case strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".tf.json") || strings.HasSuffix(name, ".tfvars") || strings.HasSuffix(name, ".tfvars.json"):
+		return errors.Errorf(errFmtInvalidFileName, name, "Terraform would load it as configuration")
+	}
+	return nil
+}
+
+// writeIfChanged writes the given content to the file in the given path only
+// if the file doesn't already have the same content. This saves the disk
+// churn of rewriting the workspace files on every reconciliation and avoids
+// touching them while a Terraform process works in the same directory.
+func (fp *FileProducer) writeIfChanged(path string, content []byte) error {
+	// NOTE(muvaf): The marshaled content is deterministic since JSON object
+	// keys are sorted, so equal content means nothing has changed.
+	if existing, err := fp.fs.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+	return writeFileAtomic(fp.fs, path, content, 0600)
+}
+
+// writeFileAtomic writes the given content to a temporary file in the
+// directory of the given path, flushes it to the disk and renames it to the
+// given path. Since the rename is atomic, a crash in the middle of the write
+// can never leave a truncated file, like a state file Terraform refuses to
+// load, behind.
+func writeFileAtomic(fs afero.Fs, path string, content []byte, perm os.FileMode) error {
+	f, err := afero.TempFile(fs, filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "cannot create temporary file")
+	}
+	tmp := f.Name()
+	// NOTE(muvaf): The temporary file is removed if anything goes wrong and
+	// it's a no-op after a successful rename.
+	defer fs.Remove(tmp) // nolint:errcheck
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return errors.Wrap(err, "cannot write temporary file")
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return errors.Wrap(err, "cannot sync temporary file")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "cannot close temporary file")
+	}
+	if err := fs.Chmod(tmp, perm); err != nil {
+		return errors.Wrap(err, "cannot set permissions of temporary file")
+	}
+	return errors.Wrap(fs.Rename(tmp, path), "cannot rename temporary file")
 }
@@ -18,7 +18,11 @@ package terraform
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 	"time"
 
@@ -26,6 +30,7 @@ import (
 	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
 	"github.com/spf13/afero"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -212,6 +217,68 @@ func TestWriteMainTF(t *testing.T) {
 				maintf: `{"provider":{"provider-test":null},"resource":{"":{"":{"lifecycle":{"prevent_destroy":true},"name":"some-id","param":"paramval"}}},"terraform":{"required_providers":{"provider-test":{"source":"hashicorp/provider-test","version":"1.2.3"}}}}`,
 			},
 		},
+		"ProviderOverrides": {
+			reason: "Parameters configured to be set in the provider configuration should be moved into the provider block",
+			args: args{
+				tr: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								meta.AnnotationKeyExternalName: "some-id",
+							},
+						},
+					},
+					Parameterizable: fake.Parameterizable{Parameters: map[string]interface{}{
+						"param":  "paramval",
+						"region": "us-west-1",
+					}},
+				},
+				cfg: config.DefaultResource("terrajet_resource", nil, func(r *config.Resource) {
+					r.ProviderOverrides = &config.ProviderOverrides{Fields: map[string]string{"region": "region"}}
+				}),
+				s: Setup{
+					Requirement: ProviderRequirement{
+						Source:  "hashicorp/provider-test",
+						Version: "1.2.3",
+					},
+					Configuration: ProviderConfiguration{"region": "us-east-1", "profile": "p"},
+				},
+			},
+			want: want{
+				maintf: `{"provider":{"provider-test":{"profile":"p","region":"us-west-1"}},"resource":{"":{"":{"lifecycle":{"prevent_destroy":true},"name":"some-id","param":"paramval"}}},"terraform":{"required_providers":{"provider-test":{"source":"hashicorp/provider-test","version":"1.2.3"}}}}`,
+			},
+		},
+		"ProviderOverridesWithAlias": {
+			reason: "Parameters configured to be set in an aliased provider block should be moved into that block and the resource should refer to it",
+			args: args{
+				tr: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								meta.AnnotationKeyExternalName: "some-id",
+							},
+						},
+					},
+					Parameterizable: fake.Parameterizable{Parameters: map[string]interface{}{
+						"param":  "paramval",
+						"region": "us-west-1",
+					}},
+				},
+				cfg: config.DefaultResource("terrajet_resource", nil, func(r *config.Resource) {
+					r.ProviderOverrides = &config.ProviderOverrides{Fields: map[string]string{"region": "region"}, Alias: "resource"}
+				}),
+				s: Setup{
+					Requirement: ProviderRequirement{
+						Source:  "hashicorp/provider-test",
+						Version: "1.2.3",
+					},
+					Configuration: ProviderConfiguration{"region": "us-east-1"},
+				},
+			},
+			want: want{
+				maintf: `{"provider":{"provider-test":[{"region":"us-east-1"},{"alias":"resource","region":"us-west-1"}]},"resource":{"":{"":{"lifecycle":{"prevent_destroy":true},"name":"some-id","param":"paramval","provider":"provider-test.resource"}}},"terraform":{"required_providers":{"provider-test":{"source":"hashicorp/provider-test","version":"1.2.3"}}}}`,
+			},
+		},
 		"Custom Source": {
 			reason: "Custom source like my-company/namespace/provider-test resources should be able to write everything it has into maintf file",
 			args: args{
@@ -261,6 +328,162 @@ func TestWriteMainTF(t *testing.T) {
 			if diff := cmp.Diff(tc.want.maintf, string(s)); diff != "" {
 				t.Errorf("\n%s\nWriteMainTF(...): -want error, +got error:\n%s", tc.reason, diff)
 			}
+			h := sha256.Sum256(s)
+			if diff := cmp.Diff(hex.EncodeToString(h[:]), fp.mainTFHash); diff != "" {
+				t.Errorf("\n%s\nWriteMainTF(...): -want hash of main.tf.json, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWriteIfChanged(t *testing.T) {
+	path := filepath.Join(dir, "main.tf.json")
+	type want struct {
+		err     error
+		content string
+	}
+	cases := map[string]struct {
+		reason   string
+		existing string
+		content  string
+		want
+	}{
+		"Unchanged": {
+			reason:   "A file that already has the same content should not be written",
+			existing: `{"a":"b"}`,
+			content:  `{"a":"b"}`,
+			want: want{
+				content: `{"a":"b"}`,
+			},
+		},
+		"Changed": {
+			reason:   "A file whose content differs should be written",
+			existing: `{"a":"b"}`,
+			content:  `{"a":"c"}`,
+			want: want{
+				err:     errors.Wrap(syscall.EPERM, "cannot create temporary file"),
+				content: `{"a":"b"}`,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			base := afero.NewMemMapFs()
+			if err := afero.WriteFile(base, path, []byte(tc.existing), 0600); err != nil {
+				t.Fatalf("cannot write existing file: %s", err)
+			}
+			// Writes fail on a read-only filesystem, so a nil error means
+			// nothing has been written.
+			fp := &FileProducer{fs: afero.Afero{Fs: afero.NewReadOnlyFs(base)}}
+			err := fp.writeIfChanged(path, []byte(tc.content))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nwriteIfChanged(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			got, _ := afero.ReadFile(base, path)
+			if diff := cmp.Diff(tc.want.content, string(got)); diff != "" {
+				t.Errorf("\n%s\nwriteIfChanged(...): -want content, +got content:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(dir, "terraform.tfstate")
+	cases := map[string]struct {
+		reason   string
+		existing string
+		content  string
+	}{
+		"NewFile": {
+			reason:  "A file that doesn't exist should be created with the given content",
+			content: `{"version":4}`,
+		},
+		"ExistingFile": {
+			reason:   "An existing file should be replaced with the given content as a whole",
+			existing: `{"version":4,"serial":1,"resources":[{"mode":"managed"}]}`,
+			content:  `{"version":4}`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if tc.existing != "" {
+				if err := afero.WriteFile(fs, path, []byte(tc.existing), 0600); err != nil {
+					t.Fatalf("cannot write existing file: %s", err)
+				}
+			}
+			if err := writeFileAtomic(fs, path, []byte(tc.content), 0600); err != nil {
+				t.Fatalf("\n%s\nwriteFileAtomic(...): unexpected error: %s", tc.reason, err)
+			}
+			got, _ := afero.ReadFile(fs, path)
+			if diff := cmp.Diff(tc.content, string(got)); diff != "" {
+				t.Errorf("\n%s\nwriteFileAtomic(...): -want content, +got content:\n%s", tc.reason, diff)
+			}
+			files, _ := afero.ReadDir(fs, dir)
+			if len(files) != 1 {
+				t.Errorf("\n%s\nwriteFileAtomic(...): temporary files should not be left behind, got %d files", tc.reason, len(files))
+			}
+		})
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	type want struct {
+		err   error
+		files map[string]string
+	}
+	cases := map[string]struct {
+		reason string
+		files  map[string][]byte
+		want
+	}{
+		"Written": {
+			reason: "The setup files should be written into the workspace directory",
+			files:  map[string][]byte{"credentials.json": []byte(`{"type":"service_account"}`)},
+			want: want{
+				files: map[string]string{"credentials.json": `{"type":"service_account"}`},
+			},
+		},
+		"OutsideWorkspace": {
+			reason: "A file that would be written outside of the workspace directory should be rejected",
+			files:  map[string][]byte{"../kubeconfig": []byte("config")},
+			want: want{
+				err: errors.Errorf(errFmtInvalidFileName, "../kubeconfig", "it must be the name of a file in the workspace directory"),
+			},
+		},
+		"Configuration": {
+			reason: "A file that Terraform would load as configuration should be rejected",
+			files:  map[string][]byte{"override.tf.json": []byte("{}")},
+			want: want{
+				err: errors.Errorf(errFmtInvalidFileName, "override.tf.json", "Terraform would load it as configuration"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			fp := &FileProducer{Dir: dir, Setup: Setup{Files: tc.files}, fs: afero.Afero{Fs: fs}}
+			err := fp.WriteFiles()
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nWriteFiles(): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			for n, want := range tc.want.files {
+				p := filepath.Join(dir, n)
+				got, err := afero.ReadFile(fs, p)
+				if err != nil {
+					t.Fatalf("\n%s\nWriteFiles(): cannot read %s: %s", tc.reason, n, err)
+				}
+				if diff := cmp.Diff(want, string(got)); diff != "" {
+					t.Errorf("\n%s\nWriteFiles(): -want content, +got content:\n%s", tc.reason, diff)
+				}
+				fi, err := fs.Stat(p)
+				if err != nil {
+					t.Fatalf("\n%s\nWriteFiles(): cannot stat %s: %s", tc.reason, n, err)
+				}
+				if diff := cmp.Diff(os.FileMode(0600), fi.Mode().Perm()); diff != "" {
+					t.Errorf("\n%s\nWriteFiles(): -want permissions, +got permissions:\n%s", tc.reason, diff)
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,148 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+const (
+	// DefaultStuckOperationFactor is the default multiple of the timeout of
+	// the async operations after which a running operation is considered
+	// stuck.
+	DefaultStuckOperationFactor = 2
+
+	errFmtStuckOperations  = "operations running longer than %g times their timeout: %s"
+	errFmtFailedWorkspaces = "workspaces with %d or more failed terraform commands in a row: %s"
+	errUnwritableRoot      = "workspace root directory is not writable"
+)
+
+// recordResult counts the consecutive failures of the Terraform CLI commands
+// in the workspace.
+func (w *Workspace) recordResult(err error) {
+	if err != nil {
+		atomic.AddInt32(&w.failures, 1)
+		return
+	}
+	atomic.StoreInt32(&w.failures, 0)
+}
+
+// HealthCheckOption configures the health check of a WorkspaceStore.
+type HealthCheckOption func(*healthCheck)
+
+// WithStuckOperationFactor sets the multiple of the timeout of the async
+// operations after which a running operation is considered stuck. The
+// operations that are still queued are never considered stuck. Defaults to
+// DefaultStuckOperationFactor.
+func WithStuckOperationFactor(f float64) HealthCheckOption {
+	return func(h *healthCheck) {
+		h.stuckFactor = f
+	}
+}
+
+// WithMaxConsecutiveFailures sets the number of the Terraform CLI commands
+// that can fail in a row in a workspace before the workspace is considered
+// unhealthy. Failing workspaces are not checked by default since the commands
+// of a resource also keep failing because of user errors, e.g. invalid
+// credentials or parameters, which a restart of the provider does not fix.
+// It's meant to be enabled for readiness checks only; the failures are
+// exported as the terrajet_operation_failures_total metric, too.
+func WithMaxConsecutiveFailures(n int) HealthCheckOption {
+	return func(h *healthCheck) {
+		h.maxFailures = n
+	}
+}
+
+type healthCheck struct {
+	stuckFactor float64
+	maxFailures int
+}
+
+// HealthCheck returns a checker that fails if an async operation has been
+// running for longer than a multiple of its timeout, the root directory of the
+// workspaces is not writable or, if configured, the Terraform commands keep
+// failing in a workspace. It's meant to be added to the health or readiness
+// checks of the controller manager, e.g. with mgr.AddHealthzCheck, so that
+// the provider is restarted or alerted on before the users notice.
+func (ws *WorkspaceStore) HealthCheck(opts ...HealthCheckOption) healthz.Checker {
+	h := &healthCheck{
+		stuckFactor: DefaultStuckOperationFactor,
+	}
+	for _, f := range opts {
+		f(h)
+	}
+	return func(_ *http.Request) error {
+		if err := ws.checkRoot(); err != nil {
+			return err
+		}
+		return ws.checkWorkspaces(h)
+	}
+}
+
+// checkWorkspaces returns an error listing the workspaces with stuck
+// operations or too many consecutive failures, if any.
+func (ws *WorkspaceStore) checkWorkspaces(h *healthCheck) error {
+	ws.mu.Lock()
+	now := ws.now()
+	var stuck, failing []string
+	for _, w := range ws.store {
+		limit := time.Duration(h.stuckFactor * float64(w.asyncTimeout()))
+		if st := w.LastOperation.StartTime(); st != nil && w.LastOperation.IsRunning() && !w.LastOperation.IsQueued() && now.Sub(*st) > limit {
+			stuck = append(stuck, w.LastOperation.Type+" in "+w.dir)
+		}
+		if h.maxFailures > 0 && int(atomic.LoadInt32(&w.failures)) >= h.maxFailures {
+			failing = append(failing, w.dir)
+		}
+	}
+	ws.mu.Unlock()
+	sort.Strings(stuck)
+	sort.Strings(failing)
+	switch {
+	case len(stuck) != 0:
+		return errors.Errorf(errFmtStuckOperations, h.stuckFactor, strings.Join(stuck, ", "))
+	case len(failing) != 0:
+		return errors.Errorf(errFmtFailedWorkspaces, h.maxFailures, strings.Join(failing, ", "))
+	}
+	return nil
+}
+
+// checkRoot returns an error if a file cannot be written into the root
+// directory of the workspaces.
+func (ws *WorkspaceStore) checkRoot() error {
+	root := ws.workdir
+	if root == "" {
+		root = ws.fs.GetTempDir("")
+	}
+	if err := ws.fs.MkdirAll(root, os.ModePerm); err != nil {
+		return errors.Wrap(err, errUnwritableRoot)
+	}
+	f, err := afero.TempFile(ws.fs, root, ".healthz-")
+	if err != nil {
+		return errors.Wrap(err, errUnwritableRoot)
+	}
+	_ = f.Close()
+	return errors.Wrap(ws.fs.Remove(f.Name()), errUnwritableRoot)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestWorkspaceStoreHealthCheck(t *testing.T) {
+	running := &Operation{}
+	running.MarkStart("apply")
+	queued := &Operation{}
+	queued.MarkQueued("apply")
+	type args struct {
+		fs         afero.Fs
+		opts       []HealthCheckOption
+		elapsed    time.Duration
+		workspaces map[types.UID]*Workspace
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want error
+	}{
+		"Healthy": {
+			reason: "No error should be returned if the operations are within their timeout and the commands succeed",
+			args: args{
+				fs:      afero.NewMemMapFs(),
+				elapsed: time.Minute,
+				workspaces: map[types.UID]*Workspace{
+					"running": {LastOperation: running, dir: "/ws/running", asyncOperationTimeout: time.Hour},
+					"failing": {LastOperation: &Operation{}, dir: "/ws/failing", failures: 2},
+				},
+			},
+		},
+		"StuckOperation": {
+			reason: "An error should be returned if an operation has been running longer than the multiple of its timeout",
+			args: args{
+				fs:      afero.NewMemMapFs(),
+				opts:    []HealthCheckOption{WithStuckOperationFactor(3)},
+				elapsed: 4 * time.Hour,
+				workspaces: map[types.UID]*Workspace{
+					"running": {LastOperation: running, dir: "/ws/running", asyncOperationTimeout: time.Hour},
+				},
+			},
+			want: errors.Errorf(errFmtStuckOperations, 3.0, "apply in /ws/running"),
+		},
+		"QueuedOperation": {
+			reason: "An operation that waits for a worker to run it should not be reported as stuck",
+			args: args{
+				fs:      afero.NewMemMapFs(),
+				opts:    []HealthCheckOption{WithStuckOperationFactor(3)},
+				elapsed: 4 * time.Hour,
+				workspaces: map[types.UID]*Workspace{
+					"queued": {LastOperation: queued, dir: "/ws/queued", asyncOperationTimeout: time.Hour},
+				},
+			},
+		},
+		"FailingWorkspace": {
+			reason: "An error should be returned if the commands keep failing in a workspace",
+			args: args{
+				fs:   afero.NewMemMapFs(),
+				opts: []HealthCheckOption{WithMaxConsecutiveFailures(2)},
+				workspaces: map[types.UID]*Workspace{
+					"failing": {LastOperation: &Operation{}, dir: "/ws/failing", failures: 2},
+				},
+			},
+			want: errors.Errorf(errFmtFailedWorkspaces, 2, "/ws/failing"),
+		},
+		"FailuresNotChecked": {
+			reason: "Failing workspaces should not be reported unless the check is enabled",
+			args: args{
+				fs: afero.NewMemMapFs(),
+				workspaces: map[types.UID]*Workspace{
+					"failing": {LastOperation: &Operation{}, dir: "/ws/failing", failures: 100},
+				},
+			},
+		},
+		"UnwritableRoot": {
+			reason: "An error should be returned if the workspace root is not writable",
+			args: args{
+				fs: afero.NewReadOnlyFs(afero.NewMemMapFs()),
+			},
+			want: errors.Wrap(errors.New("operation not permitted"), errUnwritableRoot),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(tc.args.fs), WithWorkdir("/ws"))
+			ws.now = func() time.Time { return time.Now().Add(tc.args.elapsed) }
+			for uid, w := range tc.args.workspaces {
+				ws.store[uid] = w
+			}
+			err := ws.HealthCheck(tc.args.opts...)(nil)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nHealthCheck(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
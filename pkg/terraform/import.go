@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+const (
+	errNoResourceAddress = "cannot import without the address of the resource"
+	errRestoreState      = "cannot restore the state that was in the workspace before the failed import"
+)
+
+// ImportResult contains the state of the imported resource.
+type ImportResult struct {
+	State *json.StateV4
+}
+
+// Import makes a blocking terraform import call that brings the existing
+// resource with the given ID under the management of the Workspace so that
+// resources created outside of the provider can be adopted. The resource in
+// the state of the Workspace, e.g. the one produced from the external name,
+// is replaced by the imported one.
+func (w *Workspace) Import(ctx context.Context, id string) (ImportResult, error) {
+	if err := w.awaitOperation(ctx, "import"); err != nil {
+		return ImportResult{}, err
+	}
+	var res ImportResult
+	var err error
+	if rErr := w.runSync(ctx, PriorityApply, func() { res, err = w.importResource(ctx, id) }); rErr != nil {
+		return ImportResult{}, rErr
+	}
+	return res, err
+}
+
+func (w *Workspace) importResource(ctx context.Context, id string) (ImportResult, error) {
+	if w.address == "" {
+		return ImportResult{}, errors.New(errNoResourceAddress)
+	}
+	w.lockExec()
+	defer w.unlockExec()
+	restore, err := w.dropStateResources()
+	if err != nil {
+		return ImportResult{}, err
+	}
+	ctx, cancel := w.withCommandTimeout(ctx)
+	defer cancel()
+	cmd := w.executor.CommandContext(ctx, w.terraformPath, w.cli.Import(w.address, id)...)
+	cmd.SetEnv(w.environ(w.env))
+	cmd.SetDir(w.dir)
+	out, err := w.combinedOutput(ctx, "import", cmd)
+	w.log("import").Debug(msgCommandEnded, "out", string(out))
+	if err != nil {
+		if rErr := restore(); rErr != nil {
+			w.log("import").Info(errRestoreState, "error", rErr)
+		}
+		return ImportResult{}, w.operationFailed("import", tferrors.NewImportFailed(out, w.errorOptions("import", out)...))
+	}
+	s, err := w.readState()
+	if err != nil {
+		return ImportResult{}, err
+	}
+	w.observed = s.GetAttributes()
+	return ImportResult{State: s}, nil
+}
+
+// dropStateResources removes the resources from the state file, if any, since
+// Terraform refuses to import into an address that's already in the state. It
+// returns a function that puts the original state file back so that the state
+// isn't lost if the import fails.
+func (w *Workspace) dropStateResources() (func() error, error) {
+	p := filepath.Join(w.dir, "terraform.tfstate")
+	original, err := afero.ReadFile(w.fs, p)
+	if err != nil {
+		return func() error { return nil }, nil
+	}
+	s, err := w.readState()
+	if err != nil {
+		return nil, err
+	}
+	s.Resources = nil
+	raw, err := json.JSParser.Marshal(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal state object")
+	}
+	if err := writeFileAtomic(w.fs, p, raw, 0600); err != nil {
+		return nil, errors.Wrap(err, "cannot write terraform state file")
+	}
+	return func() error {
+		return errors.Wrap(writeFileAtomic(w.fs, p, original, 0600), "cannot write terraform state file")
+	}, nil
+}
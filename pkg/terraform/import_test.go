@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	k8sExec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+func TestWorkspaceImport(t *testing.T) {
+	resources, err := stateResources(strings.NewReader(tfstateWithResource))
+	if err != nil {
+		t.Fatalf("cannot read state: %s", err)
+	}
+	type args struct {
+		address string
+		err     error
+	}
+	type want struct {
+		args     []string
+		stateIn  string
+		stateOut string
+		attrs    string
+		err      error
+		executed bool
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Success": {
+			reason: "The resource should be imported into an empty state and the imported state should be returned",
+			args: args{
+				address: "very-cool-type.very-cool-name",
+			},
+			want: want{
+				args:     []string{"import", "-input=false", "-lock=false", "very-cool-type.very-cool-name", "very-cool-id"},
+				stateIn:  "null",
+				stateOut: resources,
+				attrs:    `{"id": "very-cool-id"}`,
+				executed: true,
+			},
+		},
+		"NoAddress": {
+			reason: "An error should be returned if the address of the resource is not known",
+			want: want{
+				err: errors.New(errNoResourceAddress),
+			},
+		},
+		"ImportFailed": {
+			reason: "Failure of import should be reported and the original state should be kept",
+			args: args{
+				address: "very-cool-type.very-cool-name",
+				err:     errBoom,
+			},
+			want: want{
+				args:     []string{"import", "-input=false", "-lock=false", "very-cool-type.very-cool-name", "very-cool-id"},
+				stateIn:  "null",
+				stateOut: resources,
+				err:      tferrors.NewImportFailed([]byte("boom")),
+				executed: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			p := filepath.Join(directory, "terraform.tfstate")
+			if err := afero.WriteFile(fs, p, []byte(tfstateWithResource), 0600); err != nil {
+				t.Fatalf("cannot write state: %s", err)
+			}
+			var gotArgs []string
+			var stateIn string
+			executed := false
+			e := &testingexec.FakeExec{
+				CommandScript: []testingexec.FakeCommandAction{
+					func(_ string, args ...string) k8sExec.Cmd {
+						gotArgs = args
+						return &testingexec.FakeCmd{
+							CombinedOutputScript: []testingexec.FakeAction{
+								func() ([]byte, []byte, error) {
+									executed = true
+									s, err := readStateFile(fs, p)
+									if err != nil {
+										t.Fatalf("cannot read state: %s", err)
+									}
+									stateIn = s
+									if tc.args.err != nil {
+										return []byte("boom"), nil, tc.args.err
+									}
+									return nil, nil, afero.WriteFile(fs, p, []byte(tfstateWithResource), 0600)
+								},
+							},
+						}
+					},
+				},
+			}
+			w := NewWorkspace(directory, WithExecutor(e), WithAferoFs(fs), WithResourceAddress(tc.args.address))
+			res, err := w.Import(context.TODO(), "very-cool-id")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nImport(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.executed, executed); diff != "" {
+				t.Fatalf("\n%s\nImport(...): -want executed, +got executed:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.args, gotArgs); diff != "" {
+				t.Errorf("\n%s\nImport(...): -want args, +got args:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.stateIn, stateIn); diff != "" {
+				t.Errorf("\n%s\nImport(...): -want resources before import, +got:\n%s", tc.reason, diff)
+			}
+			if tc.want.stateOut != "" {
+				stateOut, err := readStateFile(fs, p)
+				if err != nil {
+					t.Fatalf("cannot read state: %s", err)
+				}
+				if diff := cmp.Diff(tc.want.stateOut, stateOut); diff != "" {
+					t.Errorf("\n%s\nImport(...): -want resources after import, +got:\n%s", tc.reason, diff)
+				}
+			}
+			if tc.want.attrs == "" {
+				return
+			}
+			if diff := cmp.Diff(tc.want.attrs, string(res.State.GetAttributes())); diff != "" {
+				t.Errorf("\n%s\nImport(...): -want attributes, +got attributes:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// readStateFile returns the JSON representation of the resources in the
+// state file in the given path.
+func readStateFile(fs afero.Fs, p string) (string, error) {
+	f, err := fs.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close() // nolint:errcheck
+	return stateResources(f)
+}
+
+// stateResources returns the JSON representation of the resources in the
+// given state.
+func stateResources(r io.Reader) (string, error) {
+	s, err := json.ReadStateV4(r)
+	if err != nil {
+		return "", err
+	}
+	raw, err := json.JSParser.Marshal(s.Resources)
+	return string(raw), err
+}
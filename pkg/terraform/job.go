@@ -0,0 +1,347 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	k8sExec "k8s.io/utils/exec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultJobPollInterval is the default interval the status of the Jobs
+	// is checked with.
+	DefaultJobPollInterval = 2 * time.Second
+
+	jobNamePrefix    = "terrajet-"
+	jobContainerName = "terraform"
+	jobVolumeName    = "workspaces"
+	jobOutputPrefix  = ".terrajet-job-"
+	jobExitSuffix    = ".exit"
+	// jobScript runs the command and saves its output and exit code in the
+	// workspace directory so that the controller can read them from the
+	// shared volume. The Job itself always succeeds unless the pod fails.
+	jobScript = `out="$1"; combined="$2"; shift 2
+if [ "$combined" = true ]; then "$@" >"$out" 2>&1; else "$@" >"$out"; fi
+echo $? >"$out` + jobExitSuffix + `"`
+
+	errJobNoAsync    = "cannot run a command asynchronously in job mode"
+	errFmtJobFailed  = "job %s failed: %s"
+	errFmtJobNoExit  = "cannot read the exit code of job %s"
+	errFmtJobOutside = "workspace directory %s is not in the shared volume mounted at %s"
+)
+
+// JobExecutor is a k8sExec.Interface that runs each Terraform CLI command as
+// a Kubernetes Job so that the operations of very large providers scale out
+// of the controller pod with their own resource limits. The workspaces need
+// to be in a volume that is mounted to the controller and the Jobs at the
+// same path, e.g. a ReadWriteMany PersistentVolumeClaim, see WithWorkdir.
+// The image of the Jobs needs to contain the Terraform CLI and the provider
+// binary since the provider server of the controller is not reachable from
+// the Jobs, hence the provider reattach configuration is not passed to them.
+// The environment of the commands is passed through a Secret owned by the
+// Job, so consider using WithIsolatedEnv to not pass the whole environment
+// of the controller.
+type JobExecutor struct {
+	kube      client.Client
+	namespace string
+	image     string
+	volume    corev1.VolumeSource
+	mountPath string
+
+	serviceAccount string
+	resources      corev1.ResourceRequirements
+	interval       time.Duration
+	fs             afero.Afero
+}
+
+// JobExecutorOption configures the JobExecutor.
+type JobExecutorOption func(*JobExecutor)
+
+// WithJobResources sets the resource requirements of the Terraform container
+// of the Jobs.
+func WithJobResources(r corev1.ResourceRequirements) JobExecutorOption {
+	return func(e *JobExecutor) {
+		e.resources = r
+	}
+}
+
+// WithJobServiceAccount sets the service account the Jobs run with.
+func WithJobServiceAccount(name string) JobExecutorOption {
+	return func(e *JobExecutor) {
+		e.serviceAccount = name
+	}
+}
+
+// WithJobPollInterval sets the interval the status of the Jobs is checked
+// with.
+func WithJobPollInterval(d time.Duration) JobExecutorOption {
+	return func(e *JobExecutor) {
+		e.interval = d
+	}
+}
+
+// WithJobFs sets the filesystem the shared volume is read from. Used mostly
+// for testing.
+func WithJobFs(fs afero.Fs) JobExecutorOption {
+	return func(e *JobExecutor) {
+		e.fs = afero.Afero{Fs: fs}
+	}
+}
+
+// NewJobExecutor returns a JobExecutor that runs the commands as Jobs in the
+// given namespace with the given image. The given volume that contains the
+// workspaces is mounted to the Jobs at the given path.
+func NewJobExecutor(kube client.Client, namespace, image string, volume corev1.VolumeSource, mountPath string, opts ...JobExecutorOption) *JobExecutor {
+	e := &JobExecutor{
+		kube:      kube,
+		namespace: namespace,
+		image:     image,
+		volume:    volume,
+		mountPath: mountPath,
+		interval:  DefaultJobPollInterval,
+		fs:        afero.Afero{Fs: afero.NewOsFs()},
+	}
+	for _, f := range opts {
+		f(e)
+	}
+	return e
+}
+
+// Command returns a Cmd that is run as a Job.
+func (e *JobExecutor) Command(cmd string, args ...string) k8sExec.Cmd {
+	return e.CommandContext(context.Background(), cmd, args...)
+}
+
+// CommandContext returns a Cmd that is run as a Job. The Job is deleted if
+// the given context is done before the command finishes.
+func (e *JobExecutor) CommandContext(ctx context.Context, cmd string, args ...string) k8sExec.Cmd {
+	return &jobCmd{executor: e, ctx: ctx, binary: cmd, args: args}
+}
+
+// LookPath returns the given file as is since it is looked up in the image
+// of the Jobs.
+func (e *JobExecutor) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+type jobCmd struct {
+	executor *JobExecutor
+	ctx      context.Context
+	binary   string
+	args     []string
+	dir      string
+	env      []string
+}
+
+func (jc *jobCmd) SetDir(dir string) {
+	jc.dir = dir
+}
+
+func (jc *jobCmd) SetEnv(env []string) {
+	jc.env = env
+}
+
+func (jc *jobCmd) Run() error {
+	_, err := jc.run(false)
+	return err
+}
+
+func (jc *jobCmd) CombinedOutput() ([]byte, error) {
+	return jc.run(true)
+}
+
+func (jc *jobCmd) Output() ([]byte, error) {
+	return jc.run(false)
+}
+
+func (jc *jobCmd) Start() error {
+	return errors.New(errJobNoAsync)
+}
+
+func (jc *jobCmd) Wait() error {
+	return errors.New(errJobNoAsync)
+}
+
+func (jc *jobCmd) StdoutPipe() (io.ReadCloser, error) {
+	return nil, errors.New(errJobNoAsync)
+}
+
+func (jc *jobCmd) StderrPipe() (io.ReadCloser, error) {
+	return nil, errors.New(errJobNoAsync)
+}
+
+func (jc *jobCmd) SetStdin(_ io.Reader) {}
+
+func (jc *jobCmd) SetStdout(_ io.Writer) {}
+
+func (jc *jobCmd) SetStderr(_ io.Writer) {}
+
+func (jc *jobCmd) Stop() {}
+
+// run creates the Job of the command, waits for it to finish and returns the
+// output and the exit code the Job saved in the workspace directory.
+func (jc *jobCmd) run(combined bool) ([]byte, error) { // nolint:gocyclo
+	e := jc.executor
+	name := jobNamePrefix + string(uuid.NewUUID())
+	// NOTE(muvaf): Commands like "terraform version" don't run in a
+	// workspace, so their output is saved in the root of the volume.
+	dir := jc.dir
+	if dir == "" {
+		dir = e.mountPath
+	}
+	if rel, err := filepath.Rel(e.mountPath, dir); err != nil || strings.HasPrefix(rel, "..") {
+		return nil, errors.Errorf(errFmtJobOutside, dir, e.mountPath)
+	}
+	out := filepath.Join(dir, jobOutputPrefix+name)
+	defer e.fs.Remove(out)                 // nolint:errcheck
+	defer e.fs.Remove(out + jobExitSuffix) // nolint:errcheck
+
+	job := e.job(name, out, dir, combined, jc.binary, jc.args)
+	if err := e.kube.Create(jc.ctx, job); err != nil {
+		return nil, errors.Wrap(err, "cannot create job")
+	}
+	// The job is deleted in the background, which deletes its pod and the
+	// environment secret it owns.
+	defer e.kube.Delete(context.Background(), job, client.PropagationPolicy(metav1.DeletePropagationBackground)) // nolint:errcheck
+	// NOTE(muvaf): The secret is created after the job so that it's owned by
+	// the job from the start and garbage collected with it no matter how the
+	// command ends. The pod of the job waits for the secret to be created
+	// since it's not an optional source of its environment.
+	sec := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: e.namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: batchv1.SchemeGroupVersion.String(),
+				Kind:       "Job",
+				Name:       job.GetName(),
+				UID:        job.GetUID(),
+			}},
+		},
+		StringData: jobEnv(jc.env),
+	}
+	if err := e.kube.Create(jc.ctx, sec); err != nil {
+		return nil, errors.Wrap(err, "cannot create the environment secret of the job")
+	}
+	if err := jc.wait(job); err != nil {
+		return nil, err
+	}
+	raw, err := e.fs.ReadFile(out + jobExitSuffix)
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtJobNoExit, name)
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtJobNoExit, name)
+	}
+	output, err := e.fs.ReadFile(out)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read the output of job %s", name)
+	}
+	if code != 0 {
+		return output, k8sExec.CodeExitError{Err: errors.Errorf("exit status %d", code), Code: code}
+	}
+	return output, nil
+}
+
+// wait blocks until the given Job completes or fails.
+func (jc *jobCmd) wait(job *batchv1.Job) error {
+	e := jc.executor
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+	nn := types.NamespacedName{Namespace: job.GetNamespace(), Name: job.GetName()}
+	for {
+		if err := e.kube.Get(jc.ctx, nn, job); err != nil {
+			return errors.Wrapf(err, "cannot get job %s", nn.Name)
+		}
+		for _, c := range job.Status.Conditions {
+			if c.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch c.Type {
+			case batchv1.JobComplete:
+				return nil
+			case batchv1.JobFailed:
+				return errors.Errorf(errFmtJobFailed, nn.Name, c.Message)
+			}
+		}
+		select {
+		case <-jc.ctx.Done():
+			return jc.ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (e *JobExecutor) job(name, out, dir string, combined bool, binary string, args []string) *batchv1.Job {
+	backoff := int32(0)
+	ttl := int32(time.Hour.Seconds())
+	command := append([]string{"/bin/sh", "-c", jobScript, "--", out, strconv.FormatBool(combined), binary}, args...)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: e.namespace},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoff,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: e.serviceAccount,
+					Containers: []corev1.Container{{
+						Name:       jobContainerName,
+						Image:      e.image,
+						Command:    command,
+						WorkingDir: dir,
+						EnvFrom: []corev1.EnvFromSource{{
+							SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+						}},
+						Resources:    e.resources,
+						VolumeMounts: []corev1.VolumeMount{{Name: jobVolumeName, MountPath: e.mountPath}},
+					}},
+					Volumes: []corev1.Volume{{Name: jobVolumeName, VolumeSource: e.volume}},
+				},
+			},
+		},
+	}
+}
+
+// jobEnv converts the given environment in the form of KEY=VALUE to a map
+// leaving out the provider reattach configuration.
+func jobEnv(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, kv := range env {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == envReattachConfig {
+			continue
+		}
+		m[parts[0]] = parts[1]
+	}
+	return m
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sExec "k8s.io/utils/exec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestJobExecutor(t *testing.T) {
+	type args struct {
+		exit      string
+		condition batchv1.JobConditionType
+	}
+	type want struct {
+		out string
+		err error
+		env map[string]string
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Success": {
+			reason: "The output saved by a completed job should be returned",
+			args: args{
+				exit:      "0\n",
+				condition: batchv1.JobComplete,
+			},
+			want: want{
+				out: "applied",
+				env: map[string]string{"TF_LOG": "debug"},
+			},
+		},
+		"ExitError": {
+			reason: "The exit code saved by a completed job should be returned",
+			args: args{
+				exit:      "1\n",
+				condition: batchv1.JobComplete,
+			},
+			want: want{
+				out: "applied",
+				err: k8sExec.CodeExitError{Err: errors.New("exit status 1"), Code: 1},
+				env: map[string]string{"TF_LOG": "debug"},
+			},
+		},
+		"JobFailed": {
+			reason: "An error should be returned if the job fails",
+			args: args{
+				condition: batchv1.JobFailed,
+			},
+			want: want{
+				err: errors.Errorf(errFmtJobFailed, "job", "BackoffLimitExceeded"),
+				env: map[string]string{"TF_LOG": "debug"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			var env map[string]string
+			var job *batchv1.Job
+			var owners []string
+			kube := &test.MockClient{
+				MockCreate: func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+					switch o := obj.(type) {
+					case *corev1.Secret:
+						env = o.StringData
+						for _, ref := range o.GetOwnerReferences() {
+							owners = append(owners, ref.Kind+"/"+ref.Name)
+						}
+					case *batchv1.Job:
+						job = o
+						// The arguments of the job script are the output
+						// file and whether the output is combined.
+						out := o.Spec.Template.Spec.Containers[0].Command[4]
+						if tc.args.exit != "" {
+							_ = afero.WriteFile(fs, out, []byte("applied"), 0600)
+							_ = afero.WriteFile(fs, out+jobExitSuffix, []byte(tc.args.exit), 0600)
+						}
+					}
+					return nil
+				},
+				MockDelete: test.NewMockDeleteFn(nil),
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+					j := obj.(*batchv1.Job)
+					j.Status.Conditions = []batchv1.JobCondition{{Type: tc.args.condition, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}}
+					return nil
+				},
+			}
+			e := NewJobExecutor(kube, "crossplane-system", "terraform:1.2.1", corev1.VolumeSource{}, "/workspaces", WithJobFs(fs), WithJobPollInterval(time.Millisecond))
+			cmd := e.CommandContext(context.TODO(), "terraform", "apply", "-auto-approve")
+			cmd.SetDir("/workspaces/ws")
+			cmd.SetEnv([]string{"TF_LOG=debug", envReattachConfig + "=config"})
+			out, err := cmd.CombinedOutput()
+			if tc.args.condition == batchv1.JobFailed && job != nil {
+				// The name of the job is generated.
+				tc.want.err = errors.Errorf(errFmtJobFailed, job.GetName(), "BackoffLimitExceeded")
+			}
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nCombinedOutput(): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.out, string(out)); diff != "" {
+				t.Errorf("\n%s\nCombinedOutput(): -want output, +got output:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.env, env); diff != "" {
+				t.Errorf("\n%s\nCombinedOutput(): -want job environment, +got job environment:\n%s", tc.reason, diff)
+			}
+			// The environment secret should be owned by the job from the
+			// start, so the job has to be created first.
+			if diff := cmp.Diff([]string{"Job/" + job.GetName()}, owners); diff != "" {
+				t.Errorf("\n%s\nCombinedOutput(): -want secret owners, +got secret owners:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/afero"
+
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+var (
+	workspacesDesc = prometheus.NewDesc("terrajet_workspaces",
+		"Number of Terraform workspaces in the workspace store.", nil, nil)
+	workspaceDiskDesc = prometheus.NewDesc("terrajet_workspace_disk_bytes",
+		"Total size of the files in the directories of the workspaces in the store in bytes.", nil, nil)
+	initCacheDesc = prometheus.NewDesc("terrajet_workspace_init_cache_bytes",
+		"Total size of the providers and modules installed by terraform init, including the bundled plugin directory, in bytes.", nil, nil)
+)
+
+// newFailureCounter returns the counter of the failed Terraform operations by
+// the operation and the class of the failure.
+func newFailureCounter() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "terrajet_operation_failures_total",
+		Help: "Number of failed Terraform operations by the operation and the class of the failure reported by the diagnostics.",
+	}, []string{"operation", "class"})
+}
+
+// operationFailed counts the given error of the given operation by its class
+// if the workspace is configured with a failure counter, and returns the
+// error.
+func (w *Workspace) operationFailed(op string, err error) error {
+	if w.failureCounter != nil {
+		w.failureCounter.WithLabelValues(op, string(tferrors.Classify(err))).Inc()
+	}
+	return err
+}
+
+// Describe implements prometheus.Collector.
+func (ws *WorkspaceStore) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workspacesDesc
+	ch <- workspaceDiskDesc
+	ch <- initCacheDesc
+	ws.failures.Describe(ch)
+}
+
+// Collect implements prometheus.Collector so that the WorkspaceStore can be
+// registered to a Prometheus registry, e.g. the metrics registry of
+// controller-runtime, to help right-size the ephemeral storage of the
+// provider. The disk usage is calculated at the time of collection.
+func (ws *WorkspaceStore) Collect(ch chan<- prometheus.Metric) {
+	ws.mu.Lock()
+	dirs := make([]string, 0, len(ws.store))
+	for _, w := range ws.store {
+		dirs = append(dirs, w.dir)
+	}
+	pluginDir := ""
+	if ws.pluginDir != nil {
+		pluginDir = *ws.pluginDir
+	}
+	ws.mu.Unlock()
+
+	var disk, initCache int64
+	for _, d := range dirs {
+		disk += dirSize(ws.fs, d)
+		initCache += dirSize(ws.fs, filepath.Join(d, ".terraform"))
+	}
+	if pluginDir != "" {
+		initCache += dirSize(ws.fs, pluginDir)
+	}
+	ch <- prometheus.MustNewConstMetric(workspacesDesc, prometheus.GaugeValue, float64(len(dirs)))
+	ch <- prometheus.MustNewConstMetric(workspaceDiskDesc, prometheus.GaugeValue, float64(disk))
+	ch <- prometheus.MustNewConstMetric(initCacheDesc, prometheus.GaugeValue, float64(initCache))
+	ws.failures.Collect(ch)
+}
+
+// dirSize returns the total size of the regular files in the given directory.
+// The files that cannot be read, e.g. because they're removed during the
+// walk, are skipped.
+func dirSize(fs afero.Fs, dir string) int64 {
+	var size int64
+	_ = afero.Walk(fs, dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
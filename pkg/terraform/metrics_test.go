@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/afero"
+
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+func TestWorkspaceStoreCollect(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/ws/a/main.tf.json":                      "12345",
+		"/ws/a/.terraform/providers/provider-aws": "1234567890",
+		"/ws/b/terraform.tfstate":                 "123",
+		"/plugins/provider-aws":                   "12345678901234567890",
+	}
+	for p, c := range files {
+		if err := afero.WriteFile(fs, p, []byte(c), 0600); err != nil {
+			t.Fatalf("cannot write %s: %s", p, err)
+		}
+	}
+	ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(fs))
+	ws.store["a"] = &Workspace{dir: "/ws/a"}
+	ws.store["b"] = &Workspace{dir: "/ws/b"}
+	pluginDir := "/plugins"
+	ws.pluginDir = &pluginDir
+
+	want := `
+# HELP terrajet_workspace_disk_bytes Total size of the files in the directories of the workspaces in the store in bytes.
+# TYPE terrajet_workspace_disk_bytes gauge
+terrajet_workspace_disk_bytes 18
+# HELP terrajet_workspace_init_cache_bytes Total size of the providers and modules installed by terraform init, including the bundled plugin directory, in bytes.
+# TYPE terrajet_workspace_init_cache_bytes gauge
+terrajet_workspace_init_cache_bytes 30
+# HELP terrajet_workspaces Number of Terraform workspaces in the workspace store.
+# TYPE terrajet_workspaces gauge
+terrajet_workspaces 2
+`
+	if err := testutil.CollectAndCompare(ws, strings.NewReader(want)); err != nil {
+		t.Errorf("Collect(...): %s", err)
+	}
+}
+
+func TestWorkspaceOperationFailed(t *testing.T) {
+	ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(afero.NewMemMapFs()))
+	w := &Workspace{failureCounter: ws.failures}
+	_ = w.operationFailed("apply", tferrors.NewApplyFailed([]byte(`{"@level":"error","@message":"Error: RequestLimitExceeded: Request limit exceeded."}`)))
+	_ = w.operationFailed("apply", tferrors.NewApplyFailed([]byte(`{"@level":"error","@message":"Error: something went wrong"}`)))
+	_ = w.operationFailed("refresh", tferrors.NewRefreshFailed([]byte(`{"@level":"error","@message":"Error: RequestLimitExceeded: Request limit exceeded."}`)))
+	_ = (&Workspace{}).operationFailed("apply", tferrors.NewApplyFailed(nil))
+
+	want := `
+# HELP terrajet_operation_failures_total Number of failed Terraform operations by the operation and the class of the failure reported by the diagnostics.
+# TYPE terrajet_operation_failures_total counter
+terrajet_operation_failures_total{class="Throttled",operation="apply"} 1
+terrajet_operation_failures_total{class="Throttled",operation="refresh"} 1
+terrajet_operation_failures_total{class="Unknown",operation="apply"} 1
+`
+	if err := testutil.CollectAndCompare(ws, strings.NewReader(want), "terrajet_operation_failures_total"); err != nil {
+		t.Errorf("operationFailed(...): %s", err)
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+const (
+	// moduleName is the name the remote modules are called with from the
+	// root module of the workspace.
+	moduleName = "workspace"
+
+	errModuleSource = "exactly one of the inline content and the source of the module must be set"
+)
+
+// Module is a Terraform module that is run as a whole in a workspace, unlike
+// the resources that are generated from the schema of a provider.
+type Module struct {
+	// Inline is the content of the root module, in HCL or, if JSON is true,
+	// in the JSON syntax of Terraform. Exactly one of Inline and Source
+	// needs to be set.
+	Inline string
+	// JSON is whether the inline content is in the JSON syntax.
+	JSON bool
+	// Source is the address of a remote module that is called from the root
+	// module, e.g. "git::https://example.com/network.git?ref=v1.2.0".
+	Source string
+	// Variables are the values of the input variables of the module.
+	Variables map[string]interface{}
+	// Outputs are the outputs of the remote module that are exported as the
+	// outputs of the root module. All outputs of an inline module are
+	// outputs of the root module already.
+	Outputs []string
+	// State is the Terraform state the workspace is hydrated with if it has
+	// no state yet, e.g. the persisted state of a module whose workspace has
+	// been lost. Unlike the state of a resource, the state of a module
+	// cannot be reproduced from its resource.
+	State []byte
+}
+
+// ModuleWorkspace makes sure the Terraform workspace that runs the given
+// module for the given object is ready to be used and returns the Workspace
+// object configured to work in that workspace folder in the filesystem. The
+// provider of the Setup is configured in the root module, so the module
+// should not configure it. The returned errors carry the key-value pairs the
+// log lines of the workspace are tagged with, see LogValues.
+func (ws *WorkspaceStore) ModuleWorkspace(ctx context.Context, obj xpresource.Object, ts Setup, m Module) (*Workspace, error) {
+	base := ws.workdir
+	if base == "" {
+		base = ws.fs.GetTempDir("")
+	}
+	dir := filepath.Join(base, string(obj.GetUID()))
+	w, err := ws.moduleWorkspace(ctx, obj, ts, m, dir)
+	return w, withLogValues(err, ws.logValues(obj, dir))
+}
+
+func (ws *WorkspaceStore) moduleWorkspace(ctx context.Context, obj xpresource.Object, ts Setup, m Module, dir string) (*Workspace, error) {
+	if err := ws.fs.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "cannot create directory for workspace")
+	}
+	changed, err := ws.writeModuleFiles(dir, ts, m)
+	if err != nil {
+		return nil, err
+	}
+	if err := ws.writeModuleState(dir, m); err != nil {
+		return nil, err
+	}
+	w, initialized, err := ws.workspace(ctx, obj, dir, ts)
+	if err != nil {
+		return nil, err
+	}
+	// NOTE(muvaf): Terraform installs the remote modules during the
+	// initialization, so the workspace needs to be initialized again once
+	// the module is changed.
+	return ws.initialize(ctx, w, initialized && !changed)
+}
+
+// writeModuleFiles writes the configuration of the given module into the
+// given workspace directory and reports whether the remote module it calls
+// has changed.
+func (ws *WorkspaceStore) writeModuleFiles(dir string, ts Setup, m Module) (bool, error) { // nolint:gocyclo
+	if (m.Inline == "") == (m.Source == "") {
+		return false, errors.New(errModuleSource)
+	}
+	fp := &FileProducer{Setup: ts, Dir: dir, fs: ws.fs}
+	if ts.Requirement.Source != "" {
+		providerSource := strings.Split(ts.Requirement.Source, "/")
+		providerName := providerSource[len(providerSource)-1]
+		provider, err := json.JSParser.Marshal(map[string]interface{}{
+			"terraform": map[string]interface{}{
+				"required_providers": map[string]interface{}{
+					providerName: map[string]string{
+						"source":  ts.Requirement.Source,
+						"version": ts.Requirement.Version,
+					},
+				},
+			},
+			"provider": map[string]interface{}{
+				providerName: ts.Configuration,
+			},
+		})
+		if err != nil {
+			return false, errors.Wrap(err, "cannot marshal provider configuration")
+		}
+		if err := fp.writeIfChanged(filepath.Join(dir, "provider.tf.json"), provider); err != nil {
+			return false, errors.Wrap(err, "cannot write provider configuration file")
+		}
+	}
+	vars := m.Variables
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+	mainFile, content, stale := "main.tf.json", []byte(m.Inline), "main.tf"
+	changed := false
+	switch {
+	case m.Source != "":
+		block := make(map[string]interface{}, len(vars)+1)
+		for k, v := range vars {
+			block[k] = v
+		}
+		block["source"] = m.Source
+		outputs := make(map[string]interface{}, len(m.Outputs))
+		for _, o := range m.Outputs {
+			outputs[o] = map[string]interface{}{
+				"value": "${module." + moduleName + "." + o + "}",
+				// NOTE(muvaf): Terraform requires the outputs that are
+				// derived from the sensitive outputs of the module to be
+				// marked as sensitive, and they are published as
+				// connection details regardless.
+				"sensitive": true,
+			}
+		}
+		main := map[string]interface{}{
+			"module": map[string]interface{}{moduleName: block},
+		}
+		if len(outputs) != 0 {
+			main["output"] = outputs
+		}
+		var err error
+		if content, err = json.JSParser.Marshal(main); err != nil {
+			return false, errors.Wrap(err, "cannot marshal module configuration")
+		}
+		existing, err := ws.fs.ReadFile(filepath.Join(dir, mainFile))
+		changed = err != nil || string(existing) != string(content)
+		// Variables are passed as the arguments of the module block.
+		vars = map[string]interface{}{}
+	case !m.JSON:
+		mainFile, stale = "main.tf", "main.tf.json"
+	}
+	if err := ws.fs.Remove(filepath.Join(dir, stale)); xpresource.Ignore(os.IsNotExist, err) != nil {
+		return false, errors.Wrapf(err, "cannot remove stale module file %s", stale)
+	}
+	if err := fp.writeIfChanged(filepath.Join(dir, mainFile), content); err != nil {
+		return false, errors.Wrap(err, "cannot write module file")
+	}
+	rawVars, err := json.JSParser.Marshal(vars)
+	if err != nil {
+		return false, errors.Wrap(err, "cannot marshal module variables")
+	}
+	if err := fp.writeIfChanged(filepath.Join(dir, "terraform.tfvars.json"), rawVars); err != nil {
+		return false, errors.Wrap(err, "cannot write module variables file")
+	}
+	return changed, errors.Wrap(fp.WriteFiles(), "cannot write setup files")
+}
+
+// writeModuleState writes the state of the given module into the given
+// workspace directory if the module has a state and the workspace doesn't.
+func (ws *WorkspaceStore) writeModuleState(dir string, m Module) error {
+	if len(m.State) == 0 {
+		return nil
+	}
+	p := filepath.Join(dir, "terraform.tfstate")
+	_, err := ws.fs.Stat(p)
+	if !os.IsNotExist(err) {
+		return errors.Wrap(err, "cannot stat terraform.tfstate file")
+	}
+	if err := json.JSParser.Unmarshal(m.State, &json.StateV4{}); err != nil {
+		return errors.Wrap(err, "cannot unmarshal module state")
+	}
+	return errors.Wrap(writeFileAtomic(ws.fs, p, m.State, 0600), "cannot write tfstate file")
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+func TestWriteModuleFiles(t *testing.T) {
+	ts := Setup{
+		Requirement:   ProviderRequirement{Source: "hashicorp/aws", Version: "4.15.1"},
+		Configuration: ProviderConfiguration{"region": "us-east-1"},
+	}
+	type want struct {
+		files   map[string]string
+		changed bool
+		err     error
+	}
+	cases := map[string]struct {
+		reason   string
+		existing map[string]string
+		module   Module
+		want
+	}{
+		"NoSource": {
+			reason: "An error should be returned if neither the inline content nor the source of the module is set",
+			want: want{
+				err: errors.New(errModuleSource),
+			},
+		},
+		"Inline": {
+			reason: "An inline module should be written as the root module with its variables in a variable definitions file",
+			existing: map[string]string{
+				"main.tf.json": `{}`,
+			},
+			module: Module{
+				Inline:    `output "a" { value = var.a }`,
+				Variables: map[string]interface{}{"a": "b"},
+			},
+			want: want{
+				files: map[string]string{
+					"provider.tf.json":      `{"provider":{"aws":{"region":"us-east-1"}},"terraform":{"required_providers":{"aws":{"source":"hashicorp/aws","version":"4.15.1"}}}}`,
+					"main.tf":               `output "a" { value = var.a }`,
+					"terraform.tfvars.json": `{"a":"b"}`,
+				},
+			},
+		},
+		"Source": {
+			reason: "A remote module should be called from the root module with its variables and its outputs should be exported",
+			module: Module{
+				Source:    "git::https://example.com/network.git",
+				Variables: map[string]interface{}{"a": "b"},
+				Outputs:   []string{"id"},
+			},
+			want: want{
+				files: map[string]string{
+					"provider.tf.json":      `{"provider":{"aws":{"region":"us-east-1"}},"terraform":{"required_providers":{"aws":{"source":"hashicorp/aws","version":"4.15.1"}}}}`,
+					"main.tf.json":          `{"module":{"workspace":{"a":"b","source":"git::https://example.com/network.git"}},"output":{"id":{"sensitive":true,"value":"${module.workspace.id}"}}}`,
+					"terraform.tfvars.json": `{}`,
+				},
+				changed: true,
+			},
+		},
+		"SourceUnchanged": {
+			reason: "A remote module that is already called with the same configuration should not be reported as changed",
+			existing: map[string]string{
+				"main.tf.json": `{"module":{"workspace":{"source":"git::https://example.com/network.git"}}}`,
+			},
+			module: Module{
+				Source: "git::https://example.com/network.git",
+			},
+			want: want{
+				files: map[string]string{
+					"main.tf.json": `{"module":{"workspace":{"source":"git::https://example.com/network.git"}}}`,
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for n, c := range tc.existing {
+				if err := afero.WriteFile(fs, filepath.Join(dir, n), []byte(c), 0600); err != nil {
+					t.Fatalf("cannot write %s: %s", n, err)
+				}
+			}
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(fs))
+			changed, err := ws.writeModuleFiles(dir, ts, tc.module)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nwriteModuleFiles(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.changed, changed); diff != "" {
+				t.Errorf("\n%s\nwriteModuleFiles(...): -want changed, +got changed:\n%s", tc.reason, diff)
+			}
+			for n, c := range tc.want.files {
+				got, _ := afero.ReadFile(fs, filepath.Join(dir, n))
+				if diff := cmp.Diff(c, string(got)); diff != "" {
+					t.Errorf("\n%s\nwriteModuleFiles(...): -want %s, +got %s:\n%s", tc.reason, n, n, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteModuleState(t *testing.T) {
+	state := `{"version":4,"serial":3}`
+	cases := map[string]struct {
+		reason   string
+		existing string
+		module   Module
+		want     string
+	}{
+		"Restored": {
+			reason: "The state of the module should be written if the workspace has no state",
+			module: Module{State: []byte(state)},
+			want:   state,
+		},
+		"Existing": {
+			reason:   "The state of the workspace should not be overwritten by the state of the module",
+			existing: `{"version":4,"serial":5}`,
+			module:   Module{State: []byte(state)},
+			want:     `{"version":4,"serial":5}`,
+		},
+		"NoState": {
+			reason: "No state should be written if the module has none",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			p := filepath.Join(dir, "terraform.tfstate")
+			if tc.existing != "" {
+				if err := afero.WriteFile(fs, p, []byte(tc.existing), 0600); err != nil {
+					t.Fatalf("cannot write state: %s", err)
+				}
+			}
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(fs))
+			if err := ws.writeModuleState(dir, tc.module); err != nil {
+				t.Fatalf("\n%s\nwriteModuleState(...): %s", tc.reason, err)
+			}
+			got, _ := afero.ReadFile(fs, p)
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("\n%s\nwriteModuleState(...): -want state, +got state:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
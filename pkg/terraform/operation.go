@@ -17,8 +17,12 @@ limitations under the License.
 package terraform
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"time"
+
+	k8sExec "k8s.io/utils/exec"
 )
 
 // Operation is the representation of a single Terraform CLI operation.
@@ -27,7 +31,11 @@ type Operation struct {
 
 	startTime *time.Time
 	endTime   *time.Time
-	mu        sync.RWMutex
+	// queued is true while the operation waits for a worker to run it.
+	queued bool
+	// done is closed when the running operation ends.
+	done chan struct{}
+	mu   sync.RWMutex
 }
 
 // MarkStart marks the operation as started.
@@ -38,6 +46,33 @@ func (o *Operation) MarkStart(t string) {
 	o.Type = t
 	o.startTime = &now
 	o.endTime = nil
+	o.queued = false
+	o.done = make(chan struct{})
+}
+
+// MarkQueued marks the operation as queued to run once a worker picks it up.
+// A queued operation counts as running so that no other operation starts
+// before it, and its start time is the time it's queued until MarkRunning
+// is called.
+func (o *Operation) MarkQueued(t string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	o.Type = t
+	o.startTime = &now
+	o.endTime = nil
+	o.queued = true
+	o.done = make(chan struct{})
+}
+
+// MarkRunning marks the queued operation as picked up by a worker and resets
+// its start time to now.
+func (o *Operation) MarkRunning() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	o.startTime = &now
+	o.queued = false
 }
 
 // MarkEnd marks the operation as ended.
@@ -46,6 +81,8 @@ func (o *Operation) MarkEnd() {
 	defer o.mu.Unlock()
 	now := time.Now()
 	o.endTime = &now
+	o.queued = false
+	o.closeDone()
 }
 
 // Flush cleans the operation information.
@@ -55,6 +92,34 @@ func (o *Operation) Flush() {
 	o.Type = ""
 	o.startTime = nil
 	o.endTime = nil
+	o.queued = false
+	o.closeDone()
+}
+
+// closeDone releases the waiters of the running operation. It must be called
+// with the lock held.
+func (o *Operation) closeDone() {
+	if o.done != nil {
+		close(o.done)
+		o.done = nil
+	}
+}
+
+// Wait blocks until the running operation, if any, ends. It returns the error
+// of the given context if the context is done first.
+func (o *Operation) Wait(ctx context.Context) error {
+	o.mu.RLock()
+	done := o.done
+	o.mu.RUnlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // IsEnded returns whether the operation has ended, regardless of its result.
@@ -71,6 +136,14 @@ func (o *Operation) IsRunning() bool {
 	return o.startTime != nil && o.endTime == nil
 }
 
+// IsQueued returns whether there is an ongoing operation that waits for a
+// worker to run it.
+func (o *Operation) IsQueued() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.startTime != nil && o.endTime == nil && o.queued
+}
+
 // StartTime returns the start time of the current operation.
 func (o *Operation) StartTime() *time.Time {
 	o.mu.RLock()
@@ -84,3 +157,56 @@ func (o *Operation) EndTime() *time.Time {
 	defer o.mu.RUnlock()
 	return o.endTime
 }
+
+// OperationResult summarizes the run of a Terraform CLI command.
+type OperationResult struct {
+	// Type is the type of the operation, e.g. "apply".
+	Type string
+	// ExitCode is the exit code of the command. It is -1 if the command
+	// could not be run or was terminated before it exited.
+	ExitCode int
+	// Duration is the wall-clock duration of the command.
+	Duration time.Duration
+	// OutputBytes is the size of the combined output of the command.
+	OutputBytes int
+	// TracePath is the path of the file in the workspace directory that
+	// keeps the tail of the TF_LOG=debug output of a failed operation if
+	// trace capturing is enabled for the workspace. See WithTraceCapture.
+	TracePath string
+}
+
+// String returns a human-readable summary of the operation.
+func (r OperationResult) String() string {
+	return fmt.Sprintf("terraform %s exited with code %d after %s with %d bytes of output", r.Type, r.ExitCode, r.Duration.Round(time.Millisecond), r.OutputBytes)
+}
+
+func newOperationResult(t string, start time.Time, out []byte, err error) OperationResult {
+	r := OperationResult{
+		Type:        t,
+		Duration:    time.Since(start),
+		OutputBytes: len(out),
+	}
+	if err != nil {
+		r.ExitCode = -1
+		if ee, ok := err.(k8sExec.ExitError); ok {
+			r.ExitCode = ee.ExitStatus()
+		}
+	}
+	return r
+}
+
+type operationResultKey struct{}
+
+// ContextWithOperationResult returns a copy of the given context that carries
+// the given result of an async operation.
+func ContextWithOperationResult(ctx context.Context, r OperationResult) context.Context {
+	return context.WithValue(ctx, operationResultKey{}, r)
+}
+
+// OperationResultFromContext returns the result of the async operation
+// carried by the given context passed to a CallbackFn. It returns false if
+// the context does not carry a result.
+func OperationResultFromContext(ctx context.Context) (OperationResult, bool) {
+	r, ok := ctx.Value(operationResultKey{}).(OperationResult)
+	return r, ok
+}
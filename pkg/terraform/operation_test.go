@@ -17,9 +17,13 @@ limitations under the License.
 package terraform
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
 )
 
 func TestOperation(t *testing.T) {
@@ -48,6 +52,33 @@ func TestOperation(t *testing.T) {
 				result: true,
 			},
 		},
+		"Queued": {
+			args: args{
+				calls: func(o *Operation) {
+					o.MarkQueued("type")
+				},
+			},
+			want: want{
+				checks: func(o *Operation) bool {
+					return o.IsRunning() && o.IsQueued() && !o.IsEnded()
+				},
+				result: true,
+			},
+		},
+		"PickedUp": {
+			args: args{
+				calls: func(o *Operation) {
+					o.MarkQueued("type")
+					o.MarkRunning()
+				},
+			},
+			want: want{
+				checks: func(o *Operation) bool {
+					return o.IsRunning() && !o.IsQueued() && !o.IsEnded()
+				},
+				result: true,
+			},
+		},
 		"Ended": {
 			args: args{
 				calls: func(o *Operation) {
@@ -88,3 +119,89 @@ func TestOperation(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkspaceAwaitOperation(t *testing.T) {
+	type args struct {
+		wait    time.Duration
+		running bool
+		end     bool
+	}
+	type want struct {
+		running bool
+		err     bool
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NotRunning": {
+			reason: "No error should be returned if no operation is running",
+			args: args{
+				wait: time.Minute,
+			},
+		},
+		"NoWait": {
+			reason: "An error should be returned right away if the operations are not queued",
+			args: args{
+				running: true,
+			},
+			want: want{
+				running: true,
+				err:     true,
+			},
+		},
+		"Ended": {
+			reason: "The queued operation should proceed once the running operation ends",
+			args: args{
+				wait:    time.Minute,
+				running: true,
+				end:     true,
+			},
+		},
+		"TimedOut": {
+			reason: "An error should be returned if the running operation does not end in time",
+			args: args{
+				wait:    10 * time.Millisecond,
+				running: true,
+			},
+			want: want{
+				running: true,
+				err:     true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := NewWorkspace(directory, WithOperationWait(tc.args.wait))
+			if tc.args.running {
+				w.LastOperation.MarkStart("apply")
+			}
+			var wantErr error
+			if tc.want.err {
+				wantErr = errors.Errorf("%s operation that started at %s is still running", "apply", w.LastOperation.StartTime().String())
+			}
+			if tc.args.end {
+				go func() {
+					for len(w.Pending()) == 0 {
+						time.Sleep(time.Millisecond)
+					}
+					if diff := cmp.Diff([]string{"plan"}, w.Pending()); diff != "" {
+						t.Errorf("\n%s\nPending(): -want, +got:\n%s", tc.reason, diff)
+					}
+					w.LastOperation.MarkEnd()
+				}()
+			}
+			err := w.awaitOperation(context.TODO(), "plan")
+			if diff := cmp.Diff(wantErr, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nawaitOperation(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.running, w.LastOperation.IsRunning()); diff != "" {
+				t.Errorf("\n%s\nawaitOperation(...): -want running, +got running:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(0, len(w.Pending())); diff != "" {
+				t.Errorf("\n%s\nPending(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
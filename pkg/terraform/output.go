@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+// Output is an output of the root module.
+type Output struct {
+	// Value of the output.
+	Value interface{} `json:"value"`
+	// Sensitive is whether the output is marked as sensitive.
+	Sensitive bool `json:"sensitive"`
+}
+
+// Outputs makes a blocking terraform output call and returns the outputs of
+// the root module keyed by their names, e.g. to publish the outputs of a
+// module as connection details.
+func (w *Workspace) Outputs(ctx context.Context) (map[string]Output, error) {
+	if err := w.awaitOperation(ctx, "output"); err != nil {
+		return nil, err
+	}
+	w.lockExec()
+	defer w.unlockExec()
+	ctx, cancel := w.withCommandTimeout(ctx)
+	defer cancel()
+	cmd := w.executor.CommandContext(ctx, w.terraformPath, w.cli.Output()...)
+	cmd.SetEnv(w.environ(w.env))
+	cmd.SetDir(w.dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot run terraform output: %s", string(out))
+	}
+	outputs := map[string]Output{}
+	if err := json.JSParser.Unmarshal(out, &outputs); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal terraform output")
+	}
+	return outputs, nil
+}
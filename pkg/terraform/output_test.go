@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	k8sExec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+func TestWorkspaceOutputs(t *testing.T) {
+	type args struct {
+		out string
+		err error
+	}
+	type want struct {
+		outputs map[string]Output
+		err     error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Outputs": {
+			reason: "The outputs of the root module should be returned",
+			args: args{
+				out: `{"endpoint":{"sensitive":false,"type":"string","value":"https://example.com"},"password":{"sensitive":true,"type":"string","value":"s3cr3t"}}`,
+			},
+			want: want{
+				outputs: map[string]Output{
+					"endpoint": {Value: "https://example.com"},
+					"password": {Value: "s3cr3t", Sensitive: true},
+				},
+			},
+		},
+		"CommandFailed": {
+			reason: "An error should be returned if terraform output cannot be run",
+			args: args{
+				out: "boom",
+				err: errBoom,
+			},
+			want: want{
+				err: errors.Wrapf(errBoom, "cannot run terraform output: %s", "boom"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := &testingexec.FakeExec{
+				CommandScript: []testingexec.FakeCommandAction{
+					func(_ string, _ ...string) k8sExec.Cmd {
+						return &testingexec.FakeCmd{
+							OutputScript: []testingexec.FakeAction{
+								func() ([]byte, []byte, error) {
+									return []byte(tc.args.out), nil, tc.args.err
+								},
+							},
+						}
+					},
+				},
+			}
+			got, err := NewWorkspace(directory, WithExecutor(e)).Outputs(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nOutputs(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.outputs, got); diff != "" {
+				t.Errorf("\n%s\nOutputs(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
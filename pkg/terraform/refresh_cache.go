@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"time"
+)
+
+// refreshCache is the result of the latest refresh of a Workspace that is
+// reused within the drift detection interval.
+type refreshCache struct {
+	// key identifies the configuration and the state the refresh resulted
+	// in. The result is reused only if neither has changed since then.
+	key    string
+	time   time.Time
+	result RefreshResult
+}
+
+// WithDriftDetectionInterval makes the Workspace reuse the result of its
+// latest refresh for the given duration as long as the configuration and the
+// state of the Workspace stay the same, so that the resource is not read
+// from the provider API in every reconciliation. The refreshes are never
+// reused if it is not positive.
+func WithDriftDetectionInterval(d time.Duration) WorkspaceOption {
+	return func(w *Workspace) {
+		w.driftInterval = d
+	}
+}
+
+// cachedRefresh returns the result of the latest refresh if it's within the
+// drift detection interval and the configuration and the state of the
+// Workspace haven't changed since then.
+func (w *Workspace) cachedRefresh() (RefreshResult, bool) {
+	c := w.refreshCache
+	if w.driftInterval <= 0 || c == nil || time.Since(c.time) >= w.driftInterval {
+		return RefreshResult{}, false
+	}
+	key, err := w.refreshKey()
+	if err != nil || key != c.key {
+		return RefreshResult{}, false
+	}
+	res := c.result
+	res.Cached = true
+	res.Changed = false
+	return res, true
+}
+
+// cacheRefresh stores the result of a successful refresh to be reused within
+// the drift detection interval.
+func (w *Workspace) cacheRefresh(res RefreshResult) {
+	if w.driftInterval <= 0 {
+		return
+	}
+	key, err := w.refreshKey()
+	if err != nil {
+		w.log("refresh").Debug("cannot cache the refresh result", "error", err.Error())
+		w.refreshCache = nil
+		return
+	}
+	w.refreshCache = &refreshCache{key: key, time: time.Now(), result: res}
+}
+
+// refreshKey returns the hash of the configuration and the state files of the
+// Workspace.
+func (w *Workspace) refreshKey() (string, error) {
+	h := sha256.New()
+	for _, f := range []string{"main.tf.json", "terraform.tfstate"} {
+		raw, err := w.fs.ReadFile(filepath.Join(w.dir, f))
+		if err != nil {
+			return "", err
+		}
+		_, _ = h.Write(raw)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+func TestWorkspaceRefreshCache(t *testing.T) {
+	type args struct {
+		interval time.Duration
+		// mainTF is the configuration the second refresh is run with.
+		mainTF string
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want bool
+	}{
+		"Cached": {
+			reason: "The latest refresh should be reused within the drift detection interval",
+			args: args{
+				interval: time.Hour,
+				mainTF:   `{"resource":{}}`,
+			},
+			want: true,
+		},
+		"SpecChanged": {
+			reason: "The resource should be refreshed if its configuration has changed",
+			args: args{
+				interval: time.Hour,
+				mainTF:   `{"resource":{"changed":{}}}`,
+			},
+		},
+		"NoInterval": {
+			reason: "The resource should be refreshed every time if there is no drift detection interval",
+			args: args{
+				mainTF: `{"resource":{}}`,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			w := NewWorkspace(directory, WithAferoFs(fs), WithDriftDetectionInterval(tc.args.interval))
+			write := func(mainTF string) {
+				if err := afero.WriteFile(fs, directory+"main.tf.json", []byte(mainTF), 0600); err != nil {
+					t.Fatalf("cannot write main.tf.json: %s", err)
+				}
+				if err := afero.WriteFile(fs, directory+"terraform.tfstate", []byte(tfstate), 0600); err != nil {
+					t.Fatalf("cannot write terraform.tfstate: %s", err)
+				}
+			}
+			write(`{"resource":{}}`)
+			w.executor = newFakeExec("", nil)
+			if _, err := w.Refresh(context.TODO()); err != nil {
+				t.Fatalf("\n%s\nRefresh(...): unexpected error: %s", tc.reason, err)
+			}
+			write(tc.args.mainTF)
+			w.executor = newFakeExec("", nil)
+			r, err := w.Refresh(context.TODO())
+			if err != nil {
+				t.Fatalf("\n%s\nRefresh(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, r.Cached); diff != "" {
+				t.Errorf("\n%s\nRefresh(...): -want cached, +got cached:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
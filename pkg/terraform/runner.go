@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Priority is the priority of a Terraform operation in a Runner. Operations
+// with higher priorities run first.
+type Priority int
+
+const (
+	// PriorityPlan is the priority of the operations that only read, i.e.
+	// plans and refreshes.
+	PriorityPlan Priority = iota
+	// PriorityApply is the priority of the apply operations.
+	PriorityApply
+	// PriorityDestroy is the priority of the destroy operations so that the
+	// resources are released before new ones are created.
+	PriorityDestroy
+
+	priorityCount = iota
+)
+
+// Runner runs the Terraform operations of the workspaces.
+type Runner interface {
+	// Run runs the given function with the given priority without blocking.
+	Run(p Priority, fn func())
+}
+
+// WorkerPool is a Runner that queues the operations and runs them on a
+// bounded number of workers so that the number of concurrent Terraform
+// processes, and the memory and CPU they consume, do not grow with the
+// number of resources. Queued operations run in the order of their
+// priorities and the ones with the same priority run in the order they are
+// queued.
+type WorkerPool struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queues [priorityCount][]func()
+}
+
+// NewWorkerPool returns a WorkerPool that runs at most the given number of
+// operations at a time. At least one worker is started.
+func NewWorkerPool(workers int) *WorkerPool {
+	p := &WorkerPool{}
+	p.cond = sync.NewCond(&p.mu)
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Run queues the given function to be run by a worker.
+func (p *WorkerPool) Run(pr Priority, fn func()) {
+	switch {
+	case pr < PriorityPlan:
+		pr = PriorityPlan
+	case pr > PriorityDestroy:
+		pr = PriorityDestroy
+	}
+	p.mu.Lock()
+	p.queues[pr] = append(p.queues[pr], fn)
+	p.mu.Unlock()
+	p.cond.Signal()
+}
+
+// Pending returns the number of queued operations that haven't started yet.
+func (p *WorkerPool) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, q := range p.queues {
+		n += len(q)
+	}
+	return n
+}
+
+func (p *WorkerPool) work() {
+	for {
+		p.next()()
+	}
+}
+
+// next blocks until an operation is queued and returns the one with the
+// highest priority.
+func (p *WorkerPool) next() func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		for i := len(p.queues) - 1; i >= 0; i-- {
+			if len(p.queues[i]) == 0 {
+				continue
+			}
+			fn := p.queues[i][0]
+			p.queues[i][0] = nil
+			p.queues[i] = p.queues[i][1:]
+			return fn
+		}
+		p.cond.Wait()
+	}
+}
+
+// runAsync runs the given function with the runner of the Workspace, or in a
+// new goroutine if the Workspace has no runner.
+func (w *Workspace) runAsync(p Priority, fn func()) {
+	if w.runner == nil {
+		go fn()
+		return
+	}
+	w.runner.Run(p, fn)
+}
+
+// runSync runs the given function with the runner of the Workspace and
+// waits for it to finish. It returns the error of the given context without
+// running the function if the context is done before the function starts.
+// The function is run in the calling goroutine if the Workspace has no
+// runner.
+func (w *Workspace) runSync(ctx context.Context, p Priority, fn func()) error {
+	if w.runner == nil {
+		fn()
+		return nil
+	}
+	// state is 0 while the function is queued, 1 once it starts and 2 if
+	// it's abandoned.
+	var state int32
+	done := make(chan struct{})
+	w.runner.Run(p, func() {
+		if !atomic.CompareAndSwapInt32(&state, 0, 1) {
+			return
+		}
+		defer close(done)
+		fn()
+	})
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if atomic.CompareAndSwapInt32(&state, 0, 2) {
+			return ctx.Err()
+		}
+		<-done
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWorkerPoolPriority(t *testing.T) {
+	p := NewWorkerPool(1)
+	// Block the only worker so that the operations below are queued.
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p.Run(PriorityPlan, func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var got []string
+	queue := func(pr Priority, name string) {
+		wg.Add(1)
+		p.Run(pr, func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, name)
+		})
+	}
+	queue(PriorityPlan, "plan")
+	queue(PriorityApply, "apply-1")
+	queue(PriorityDestroy, "destroy")
+	queue(PriorityApply, "apply-2")
+	if diff := cmp.Diff(4, p.Pending()); diff != "" {
+		t.Errorf("Pending(): -want, +got:\n%s", diff)
+	}
+	close(release)
+	wg.Wait()
+
+	want := []string{"destroy", "apply-1", "apply-2", "plan"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Run(...): -want order, +got order:\n%s", diff)
+	}
+}
+
+func TestWorkspaceRunSync(t *testing.T) {
+	p := NewWorkerPool(1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p.Run(PriorityPlan, func() {
+		close(started)
+		<-release
+	})
+	<-started
+	defer close(release)
+
+	w := NewWorkspace(directory, WithRunner(p))
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	ran := false
+	err := w.runSync(ctx, PriorityPlan, func() { ran = true })
+	if diff := cmp.Diff(context.Canceled, err, test.EquateErrors()); diff != "" {
+		t.Errorf("runSync(...): -want error, +got error:\n%s", diff)
+	}
+	if ran {
+		t.Errorf("runSync(...): an operation whose context is done before it starts should not run")
+	}
+}
@@ -0,0 +1,352 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"k8s.io/utils/exec"
+
+	// The server packages register the messages of the plugin protocols.
+	_ "github.com/hashicorp/terraform-plugin-go/tfprotov5/tf5server"
+	_ "github.com/hashicorp/terraform-plugin-go/tfprotov6/tf6server"
+)
+
+const (
+	// envPluginProtocolVersions lists the plugin protocol versions the
+	// client supports so that the plugin serves the newest one it supports.
+	envPluginProtocolVersions = "PLUGIN_PROTOCOL_VERSIONS"
+	valPluginProtocolVersions = "5,6"
+	// schemaFormatVersion is the format version of the JSON output of
+	// "terraform providers schema -json" the schemas are converted to.
+	schemaFormatVersion = "1.0"
+
+	errFmtHandshake       = "cannot parse the handshake line of the provider plugin: %q"
+	errFmtProtocolVersion = "unsupported plugin protocol version %d"
+	errFmtDiagnostic      = "provider plugin returned an error diagnostic: %s: %s"
+	errNoHandshake        = "provider plugin exited before writing its handshake line"
+	errStartPlugin        = "cannot start the provider plugin"
+	errDialPlugin         = "cannot connect to the provider plugin"
+	errGetSchema          = "cannot get the schema of the provider plugin"
+	errDecodeSchema       = "cannot decode the schema of the provider plugin"
+	errFmtFindMessage     = "cannot find the plugin protocol message %s"
+
+	descriptionKindMarkdown = "MARKDOWN"
+	diagnosticSeverityError = "ERROR"
+)
+
+// schemaMethod is the gRPC method that returns the schema of the provider in
+// a plugin protocol version.
+type schemaMethod struct {
+	// name is the full name of the method.
+	name string
+	// pkg is the protobuf package of the messages of the protocol version.
+	pkg string
+}
+
+// schemaMethods are the methods that return the schema of the provider in
+// each plugin protocol version.
+var schemaMethods = map[int]schemaMethod{
+	5: {name: "/tfplugin5.Provider/GetSchema", pkg: "tfplugin5"},
+	6: {name: "/tfplugin6.Provider/GetProviderSchema", pkg: "tfplugin6"},
+}
+
+// nestingModes maps the names of the values of the NestedBlock.NestingMode
+// enum to the nesting modes in the JSON schema.
+var nestingModes = map[string]tfjson.SchemaNestingMode{
+	"SINGLE": tfjson.SchemaNestingModeSingle,
+	"LIST":   tfjson.SchemaNestingModeList,
+	"SET":    tfjson.SchemaNestingModeSet,
+	"MAP":    tfjson.SchemaNestingModeMap,
+	"GROUP":  tfjson.SchemaNestingModeGroup,
+}
+
+// GetProviderSchema starts the Terraform provider plugin in the given path,
+// fetches its schema over gRPC and returns it in the JSON format of
+// "terraform providers schema -json" with the given provider source address,
+// e.g. "registry.terraform.io/hashicorp/aws". This way, the code generation
+// pipeline can run on the provider binary without a Terraform CLI or a Go
+// import of the provider code.
+func GetProviderSchema(ctx context.Context, e exec.Interface, path, source string) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	//#nosec G204 the plugin path is given by the user generating the provider
+	cmd := e.CommandContext(ctx, path)
+	cmd.SetEnv(append(os.Environ(),
+		fmt.Sprintf(fmtSetEnv, envMagicCookie, valMagicCookie),
+		fmt.Sprintf(fmtSetEnv, envPluginProtocolVersions, valPluginProtocolVersions)))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, errStartPlugin)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, errStartPlugin)
+	}
+	// NOTE(muvaf): The plugin doesn't exit on its own, so the context is
+	// cancelled to kill it before the process is reaped. Otherwise, Wait
+	// would block forever.
+	defer func() {
+		cancel()
+		_ = cmd.Wait()
+	}()
+	scanner := bufio.NewScanner(stdout)
+	if !scanner.Scan() {
+		return nil, errors.New(errNoHandshake)
+	}
+	network, addr, version, err := parseHandshake(scanner.Text())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.DialContext(ctx, addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}),
+		grpc.WithBlock())
+	if err != nil {
+		return nil, errors.Wrap(err, errDialPlugin)
+	}
+	defer conn.Close() // nolint:errcheck
+	return getSchema(ctx, conn, version, source)
+}
+
+// parseHandshake parses the handshake line the plugins write to their
+// stdout, i.e. <core version>|<protocol version>|<network>|<address>|grpc,
+// optionally followed by the server certificate.
+func parseHandshake(line string) (network, addr string, version int, err error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) < 5 || parts[4] != "grpc" {
+		return "", "", 0, errors.Errorf(errFmtHandshake, line)
+	}
+	version, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return "", "", 0, errors.Errorf(errFmtHandshake, line)
+	}
+	if _, ok := schemaMethods[version]; !ok {
+		return "", "", 0, errors.Errorf(errFmtProtocolVersion, version)
+	}
+	return parts[2], parts[3], version, nil
+}
+
+// getSchema calls the schema method of the given plugin protocol version and
+// converts the response to the JSON schema format.
+func getSchema(ctx context.Context, conn grpc.ClientConnInterface, version int, source string) ([]byte, error) {
+	m, ok := schemaMethods[version]
+	if !ok {
+		return nil, errors.Errorf(errFmtProtocolVersion, version)
+	}
+	req, err := newMessage(m.pkg + ".GetProviderSchema.Request")
+	if err != nil {
+		return nil, errors.Wrap(err, errGetSchema)
+	}
+	resp, err := newMessage(m.pkg + ".GetProviderSchema.Response")
+	if err != nil {
+		return nil, errors.Wrap(err, errGetSchema)
+	}
+	if err := conn.Invoke(ctx, m.name, req, resp); err != nil {
+		return nil, errors.Wrap(err, errGetSchema)
+	}
+	ps, err := decodeProviderSchema(resp)
+	if err != nil {
+		return nil, errors.Wrap(err, errDecodeSchema)
+	}
+	out, err := json.Marshal(&tfjson.ProviderSchemas{
+		FormatVersion: schemaFormatVersion,
+		Schemas:       map[string]*tfjson.ProviderSchema{source: ps},
+	})
+	return out, errors.Wrap(err, "cannot marshal the schema of the provider plugin")
+}
+
+// newMessage returns a new message of the given full name. The generated
+// code of the plugin protocols is internal to terraform-plugin-go, so its
+// messages are looked up in the registry they're added to once the server
+// packages are imported.
+func newMessage(name string) (proto.Message, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, errors.Wrapf(err, errFmtFindMessage, name)
+	}
+	return mt.New().Interface(), nil
+}
+
+// The following types mirror the messages of the GetProviderSchema response
+// in their JSON form, which are the same in the tfplugin5 and tfplugin6
+// protocols. The 64-bit integers are encoded as strings in JSON.
+type providerSchemaResponse struct {
+	Provider          *schemaMessage            `json:"provider"`
+	ResourceSchemas   map[string]*schemaMessage `json:"resource_schemas"`
+	DataSourceSchemas map[string]*schemaMessage `json:"data_source_schemas"`
+	Diagnostics       []diagnosticMessage       `json:"diagnostics"`
+}
+
+type schemaMessage struct {
+	Version uint64       `json:"version,string"`
+	Block   blockMessage `json:"block"`
+}
+
+type blockMessage struct {
+	Attributes      []attributeMessage   `json:"attributes"`
+	BlockTypes      []nestedBlockMessage `json:"block_types"`
+	Description     string               `json:"description"`
+	DescriptionKind string               `json:"description_kind"`
+	Deprecated      bool                 `json:"deprecated"`
+}
+
+type attributeMessage struct {
+	Name            string `json:"name"`
+	Type            []byte `json:"type"`
+	Description     string `json:"description"`
+	Required        bool   `json:"required"`
+	Optional        bool   `json:"optional"`
+	Computed        bool   `json:"computed"`
+	Sensitive       bool   `json:"sensitive"`
+	DescriptionKind string `json:"description_kind"`
+	Deprecated      bool   `json:"deprecated"`
+}
+
+type nestedBlockMessage struct {
+	TypeName string       `json:"type_name"`
+	Block    blockMessage `json:"block"`
+	Nesting  string       `json:"nesting"`
+	MinItems uint64       `json:"min_items,string"`
+	MaxItems uint64       `json:"max_items,string"`
+}
+
+type diagnosticMessage struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+}
+
+func decodeProviderSchema(msg proto.Message) (*tfjson.ProviderSchema, error) {
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	resp := &providerSchemaResponse{}
+	if err := json.Unmarshal(b, resp); err != nil {
+		return nil, err
+	}
+	for _, d := range resp.Diagnostics {
+		if d.Severity == diagnosticSeverityError {
+			return nil, errors.Errorf(errFmtDiagnostic, d.Summary, d.Detail)
+		}
+	}
+	ps := &tfjson.ProviderSchema{
+		ResourceSchemas:   make(map[string]*tfjson.Schema, len(resp.ResourceSchemas)),
+		DataSourceSchemas: make(map[string]*tfjson.Schema, len(resp.DataSourceSchemas)),
+	}
+	if resp.Provider != nil {
+		if ps.ConfigSchema, err = resp.Provider.schema(); err != nil {
+			return nil, errors.Wrap(err, "cannot decode provider configuration schema")
+		}
+	}
+	for name, s := range resp.ResourceSchemas {
+		if ps.ResourceSchemas[name], err = s.schema(); err != nil {
+			return nil, errors.Wrapf(err, "cannot decode schema of %s", name)
+		}
+	}
+	for name, s := range resp.DataSourceSchemas {
+		if ps.DataSourceSchemas[name], err = s.schema(); err != nil {
+			return nil, errors.Wrapf(err, "cannot decode schema of %s", name)
+		}
+	}
+	return ps, nil
+}
+
+func (s *schemaMessage) schema() (*tfjson.Schema, error) {
+	b, err := s.Block.block()
+	if err != nil {
+		return nil, err
+	}
+	return &tfjson.Schema{Version: s.Version, Block: b}, nil
+}
+
+func (m *blockMessage) block() (*tfjson.SchemaBlock, error) {
+	b := &tfjson.SchemaBlock{
+		Description:     m.Description,
+		DescriptionKind: descriptionKind(m.DescriptionKind),
+		Deprecated:      m.Deprecated,
+	}
+	for _, am := range m.Attributes {
+		a, err := am.attribute()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot decode attribute %s", am.Name)
+		}
+		if b.Attributes == nil {
+			b.Attributes = map[string]*tfjson.SchemaAttribute{}
+		}
+		b.Attributes[am.Name] = a
+	}
+	for _, nm := range m.BlockTypes {
+		nb, err := nm.Block.block()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot decode block %s", nm.TypeName)
+		}
+		if b.NestedBlocks == nil {
+			b.NestedBlocks = map[string]*tfjson.SchemaBlockType{}
+		}
+		b.NestedBlocks[nm.TypeName] = &tfjson.SchemaBlockType{
+			NestingMode: nestingModes[nm.Nesting],
+			Block:       nb,
+			MinItems:    nm.MinItems,
+			MaxItems:    nm.MaxItems,
+		}
+	}
+	return b, nil
+}
+
+func (m *attributeMessage) attribute() (*tfjson.SchemaAttribute, error) {
+	a := &tfjson.SchemaAttribute{
+		Description:     m.Description,
+		DescriptionKind: descriptionKind(m.DescriptionKind),
+		Deprecated:      m.Deprecated,
+		Required:        m.Required,
+		Optional:        m.Optional,
+		Computed:        m.Computed,
+		Sensitive:       m.Sensitive,
+	}
+	// The type is encoded in the JSON form of cty types.
+	if len(m.Type) > 0 {
+		if err := a.AttributeType.UnmarshalJSON(m.Type); err != nil {
+			return nil, errors.Wrap(err, "cannot decode attribute type")
+		}
+	}
+	return a, nil
+}
+
+func descriptionKind(v string) tfjson.SchemaDescriptionKind {
+	if v == descriptionKindMarkdown {
+		return tfjson.SchemaDescriptionKindMarkdown
+	}
+	return tfjson.SchemaDescriptionKindPlain
+}
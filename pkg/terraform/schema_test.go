@@ -0,0 +1,230 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+// schemaResponse returns the GetProviderSchema response of the given plugin
+// protocol package for a provider with a single resource.
+func schemaResponse(t *testing.T, pkg string, diagnostics ...map[string]interface{}) proto.Message {
+	page := map[string]interface{}{"name": "branch", "type": []byte(`"string"`), "computed": true}
+	block := map[string]interface{}{
+		"attributes": []interface{}{
+			map[string]interface{}{"name": "name", "type": []byte(`"string"`), "description": "Name of the repository.", "required": true},
+			map[string]interface{}{"name": "topics", "type": []byte(`["set","string"]`), "optional": true},
+		},
+		"block_types": []interface{}{
+			map[string]interface{}{
+				"type_name": "pages",
+				"block":     map[string]interface{}{"attributes": []interface{}{page}},
+				"nesting":   "LIST",
+				"max_items": 1,
+			},
+		},
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"provider":         map[string]interface{}{"block": map[string]interface{}{}},
+		"resource_schemas": map[string]interface{}{"github_repository": map[string]interface{}{"version": 1, "block": block}},
+		"diagnostics":      diagnostics,
+	})
+	if err != nil {
+		t.Fatalf("cannot marshal the schema response: %s", err)
+	}
+	resp, err := newMessage(pkg + ".GetProviderSchema.Response")
+	if err != nil {
+		t.Fatalf("cannot create the schema response: %s", err)
+	}
+	if err := protojson.Unmarshal(b, resp); err != nil {
+		t.Fatalf("cannot unmarshal the schema response: %s", err)
+	}
+	return resp
+}
+
+// servePlugin serves the given response to the calls of the schema method on
+// a Unix socket and returns the handshake line of the plugin.
+func servePlugin(t *testing.T, version string, method schemaMethod, resp proto.Message) string {
+	addr := filepath.Join(t.TempDir(), "plugin.sock")
+	l, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatalf("cannot listen on %s: %s", addr, err)
+	}
+	s := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		if m, _ := grpc.MethodFromServerStream(stream); m != method.name {
+			return errors.Errorf("unexpected method %s", m)
+		}
+		req, err := newMessage(method.pkg + ".GetProviderSchema.Request")
+		if err != nil {
+			return err
+		}
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		return stream.SendMsg(resp)
+	}))
+	go s.Serve(l) // nolint:errcheck
+	t.Cleanup(s.Stop)
+	return "1|" + version + "|unix|" + addr + "|grpc|\n"
+}
+
+func TestGetProviderSchema(t *testing.T) {
+	type args struct {
+		version string
+		method  schemaMethod
+		resp    proto.Message
+	}
+	type want struct {
+		schema string
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Protocol5": {
+			reason: "The schema served by a plugin with protocol version 5 should be returned in the JSON schema format",
+			args: args{
+				version: "5",
+				method:  schemaMethods[5],
+				resp:    schemaResponse(t, "tfplugin5"),
+			},
+			want: want{
+				schema: `{"format_version":"1.0","provider_schemas":{"registry.terraform.io/integrations/github":{"provider":{"version":0,"block":{"description_kind":"plain"}},"resource_schemas":{"github_repository":{"version":1,"block":{"attributes":{"name":{"type":"string","description":"Name of the repository.","description_kind":"plain","required":true},"topics":{"type":["set","string"],"description_kind":"plain","optional":true}},"block_types":{"pages":{"nesting_mode":"list","block":{"attributes":{"branch":{"type":"string","description_kind":"plain","computed":true}},"description_kind":"plain"},"max_items":1}},"description_kind":"plain"}}}}}}`,
+			},
+		},
+		"Protocol6": {
+			reason: "The schema of a plugin with protocol version 6 should be fetched with the method of that version",
+			args: args{
+				version: "6",
+				method:  schemaMethods[6],
+				resp:    schemaResponse(t, "tfplugin6"),
+			},
+			want: want{
+				schema: `{"format_version":"1.0","provider_schemas":{"registry.terraform.io/integrations/github":{"provider":{"version":0,"block":{"description_kind":"plain"}},"resource_schemas":{"github_repository":{"version":1,"block":{"attributes":{"name":{"type":"string","description":"Name of the repository.","description_kind":"plain","required":true},"topics":{"type":["set","string"],"description_kind":"plain","optional":true}},"block_types":{"pages":{"nesting_mode":"list","block":{"attributes":{"branch":{"type":"string","description_kind":"plain","computed":true}},"description_kind":"plain"},"max_items":1}},"description_kind":"plain"}}}}}}`,
+			},
+		},
+		"ErrorDiagnostic": {
+			reason: "An error should be returned if the plugin returns an error diagnostic",
+			args: args{
+				version: "5",
+				method:  schemaMethods[5],
+				resp:    schemaResponse(t, "tfplugin5", map[string]interface{}{"severity": "ERROR", "summary": "invalid schema", "detail": "boom"}),
+			},
+			want: want{
+				err: errors.Wrap(errors.Errorf(errFmtDiagnostic, "invalid schema", "boom"), errDecodeSchema),
+			},
+		},
+		"UnsupportedProtocol": {
+			reason: "An error should be returned if the plugin serves an unsupported protocol version",
+			args: args{
+				version: "4",
+			},
+			want: want{
+				err: errors.Errorf(errFmtProtocolVersion, 4),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			handshake := servePlugin(t, tc.args.version, tc.args.method, tc.args.resp)
+			e := &testingexec.FakeExec{
+				CommandScript: []testingexec.FakeCommandAction{
+					func(_ string, _ ...string) exec.Cmd {
+						return &testingexec.FakeCmd{
+							StdoutPipeResponse: testingexec.FakeStdIOPipeResponse{
+								ReadCloser: io.NopCloser(strings.NewReader(handshake)),
+							},
+						}
+					},
+				},
+			}
+			got, err := GetProviderSchema(context.TODO(), e, "terraform-provider-github", "registry.terraform.io/integrations/github")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nGetProviderSchema(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.schema, string(got)); diff != "" {
+				t.Errorf("\n%s\nGetProviderSchema(...): -want schema, +got schema:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestParseHandshake(t *testing.T) {
+	type want struct {
+		network string
+		addr    string
+		version int
+		err     error
+	}
+	cases := map[string]struct {
+		reason string
+		line   string
+		want
+	}{
+		"Unix": {
+			reason: "The network, address and protocol version of the plugin should be parsed",
+			line:   "1|5|unix|/tmp/plugin123|grpc|",
+			want: want{
+				network: "unix",
+				addr:    "/tmp/plugin123",
+				version: 5,
+			},
+		},
+		"NetRPC": {
+			reason: "Plugins that do not serve gRPC should be rejected",
+			line:   "1|5|unix|/tmp/plugin123|netrpc",
+			want: want{
+				err: errors.Errorf(errFmtHandshake, "1|5|unix|/tmp/plugin123|netrpc"),
+			},
+		},
+		"Malformed": {
+			reason: "An error should be returned if the line is not a handshake line",
+			line:   "starting the provider",
+			want: want{
+				err: errors.Errorf(errFmtHandshake, "starting the provider"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			network, addr, version, err := parseHandshake(tc.line)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nparseHandshake(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff([]interface{}{tc.want.network, tc.want.addr, tc.want.version}, []interface{}{network, addr, version}); diff != "" {
+				t.Errorf("\n%s\nparseHandshake(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
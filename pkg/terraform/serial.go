@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"sync"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SerialGroups hands out a lock for each serialization group so that the
+// operations of the workspaces in the same group run one at a time while
+// the workspaces in different groups are not blocked by each other.
+type SerialGroups struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewSerialGroups returns a new SerialGroups.
+func NewSerialGroups() *SerialGroups {
+	return &SerialGroups{locks: map[string]*sync.Mutex{}}
+}
+
+// Locker returns the lock of the given group. It returns nil for the empty
+// group, i.e. the operations that do not belong to a group are never
+// serialized.
+func (s *SerialGroups) Locker(group string) sync.Locker {
+	if group == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.locks[group]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[group] = l
+	}
+	return l
+}
+
+// NewSerialDestroySetupFn returns a SetupFn that puts the destroy operations
+// of all resources using the same ProviderConfig into the same serialization
+// group if the given SetupFn does not assign a group itself. Providers whose
+// ProviderConfigs may point to the same account can assign a group derived
+// from the account in their SetupFn instead.
+func NewSerialDestroySetupFn(sf SetupFn) SetupFn {
+	return func(ctx context.Context, client client.Client, mg xpresource.Managed) (Setup, error) {
+		ts, err := sf(ctx, client, mg)
+		if err != nil {
+			return ts, err
+		}
+		if ref := mg.GetProviderConfigReference(); ts.DestroyGroup == "" && ref != nil {
+			ts.DestroyGroup = ref.Name
+		}
+		return ts, nil
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSerialGroupsLocker(t *testing.T) {
+	s := NewSerialGroups()
+	if l := s.Locker(""); l != nil {
+		t.Errorf("Locker(\"\"): expected no lock for the empty group, got %v", l)
+	}
+	if s.Locker("a") != s.Locker("a") {
+		t.Errorf("Locker(\"a\"): expected the same lock for the same group")
+	}
+	if s.Locker("a") == s.Locker("b") {
+		t.Errorf("Locker(\"b\"): expected different locks for different groups")
+	}
+}
+
+func TestNewSerialDestroySetupFn(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		setup  Setup
+		ref    *xpv1.Reference
+		want   string
+	}{
+		"ProviderConfig": {
+			reason: "The name of the ProviderConfig should be used as the group if none is set",
+			ref:    &xpv1.Reference{Name: "account-a"},
+			want:   "account-a",
+		},
+		"Set": {
+			reason: "The group set by the wrapped SetupFn should take precedence",
+			setup:  Setup{DestroyGroup: "123456789012"},
+			ref:    &xpv1.Reference{Name: "account-a"},
+			want:   "123456789012",
+		},
+		"NoProviderConfig": {
+			reason: "No group should be assigned if the resource does not reference a ProviderConfig",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			sf := NewSerialDestroySetupFn(func(_ context.Context, _ client.Client, _ xpresource.Managed) (Setup, error) {
+				return tc.setup, nil
+			})
+			mg := &xpfake.Managed{ProviderConfigReferencer: xpfake.ProviderConfigReferencer{Ref: tc.ref}}
+			got, err := sf(context.TODO(), nil, mg)
+			if err != nil {
+				t.Fatalf("\n%s\nSetupFn(...): unexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got.DestroyGroup); diff != "" {
+				t.Errorf("\n%s\nSetupFn(...): -want group, +got group:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
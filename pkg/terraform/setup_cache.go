@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type setupEntry struct {
+	setup   Setup
+	expires time.Time
+}
+
+// SetupCache caches the Setup resolved by a SetupFn per ProviderConfig so that
+// the credentials of a ProviderConfig are not read for every reconciliation
+// of every resource using it. Resources that use different ProviderConfigs
+// never share a Setup and every caller gets its own copy of the cached Setup,
+// so the workspaces cannot leak the configuration of one ProviderConfig into
+// another.
+type SetupCache struct {
+	fn      SetupFn
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]setupEntry
+	now     func() time.Time
+}
+
+// NewSetupCache returns a new SetupCache that resolves the Setup of a
+// ProviderConfig using the given SetupFn and keeps it for the given duration.
+// Changes to a ProviderConfig or its credentials are picked up once the
+// cached Setup expires or is invalidated.
+func NewSetupCache(fn SetupFn, ttl time.Duration) *SetupCache {
+	return &SetupCache{
+		fn:      fn,
+		ttl:     ttl,
+		entries: map[string]setupEntry{},
+		now:     time.Now,
+	}
+}
+
+// Setup returns the Setup of the ProviderConfig of the given resource. It has
+// the signature of SetupFn so that it can be used in its place. Resources
+// that do not refer to a ProviderConfig are not cached.
+func (c *SetupCache) Setup(ctx context.Context, kube client.Client, mg xpresource.Managed) (Setup, error) {
+	ref := mg.GetProviderConfigReference()
+	if ref == nil || ref.Name == "" {
+		return c.fn(ctx, kube, mg)
+	}
+	c.mu.Lock()
+	e, ok := c.entries[ref.Name]
+	c.mu.Unlock()
+	if ok && c.now().Before(e.expires) {
+		return e.setup.DeepCopy(), nil
+	}
+	s, err := c.fn(ctx, kube, mg)
+	if err != nil {
+		return Setup{}, err
+	}
+	c.mu.Lock()
+	c.entries[ref.Name] = setupEntry{setup: s.DeepCopy(), expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return s, nil
+}
+
+// Invalidate removes the cached Setup of the given ProviderConfig, e.g. when
+// the ProviderConfig or its credentials change.
+func (c *SetupCache) Invalidate(providerConfigName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, providerConfigName)
+}
+
+// DeepCopy returns a copy of the Setup that does not share any memory with
+// the original.
+func (s Setup) DeepCopy() Setup {
+	out := s
+	if s.Env != nil {
+		out.Env = make([]string, len(s.Env))
+		copy(out.Env, s.Env)
+	}
+	if s.Files != nil {
+		out.Files = make(map[string][]byte, len(s.Files))
+		for k, v := range s.Files {
+			out.Files[k] = append([]byte(nil), v...)
+		}
+	}
+	if s.Configuration != nil {
+		out.Configuration = copyValue(map[string]interface{}(s.Configuration)).(map[string]interface{})
+	}
+	return out
+}
+
+func copyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, e := range t {
+			l[i] = copyValue(e)
+		}
+		return l
+	default:
+		return v
+	}
+}
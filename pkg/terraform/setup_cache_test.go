@@ -0,0 +1,97 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSetupCache(t *testing.T) {
+	withPC := func(name string) xpresource.Managed {
+		return &xpfake.Managed{ProviderConfigReferencer: xpfake.ProviderConfigReferencer{Ref: &xpv1.Reference{Name: name}}}
+	}
+	type call struct {
+		mg      xpresource.Managed
+		advance time.Duration
+	}
+	cases := map[string]struct {
+		reason    string
+		calls     []call
+		wantCalls int
+	}{
+		"SameProviderConfig": {
+			reason:    "The setup of a ProviderConfig should be resolved only once",
+			calls:     []call{{mg: withPC("a")}, {mg: withPC("a")}},
+			wantCalls: 1,
+		},
+		"DifferentProviderConfigs": {
+			reason:    "Resources with different ProviderConfigs should not share a setup",
+			calls:     []call{{mg: withPC("a")}, {mg: withPC("b")}, {mg: withPC("a")}},
+			wantCalls: 2,
+		},
+		"Expired": {
+			reason:    "The setup should be resolved again once it expires",
+			calls:     []call{{mg: withPC("a")}, {mg: withPC("a"), advance: 2 * time.Minute}},
+			wantCalls: 2,
+		},
+		"NoProviderConfig": {
+			reason:    "The setup of resources without a ProviderConfig should not be cached",
+			calls:     []call{{mg: &xpfake.Managed{}}, {mg: &xpfake.Managed{}}},
+			wantCalls: 2,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			sc := NewSetupCache(func(_ context.Context, _ client.Client, mg xpresource.Managed) (Setup, error) {
+				calls++
+				s := Setup{Env: []string{"CALL=" + mg.GetName()}}
+				if ref := mg.GetProviderConfigReference(); ref != nil {
+					s.Configuration = ProviderConfiguration{"profile": ref.Name}
+				}
+				return s, nil
+			}, time.Minute)
+			now := time.Now()
+			sc.now = func() time.Time { return now }
+			for _, c := range tc.calls {
+				now = now.Add(c.advance)
+				s, err := sc.Setup(context.TODO(), nil, c.mg)
+				if err != nil {
+					t.Fatalf("\n%s\nSetup(...): unexpected error: %s", tc.reason, err)
+				}
+				if ref := c.mg.GetProviderConfigReference(); ref != nil {
+					if diff := cmp.Diff(ref.Name, s.Configuration["profile"]); diff != "" {
+						t.Errorf("\n%s\nSetup(...): -want profile, +got profile:\n%s", tc.reason, diff)
+					}
+					// Modifying the returned setup must not affect the cache.
+					s.Configuration["profile"] = "modified"
+				}
+			}
+			if diff := cmp.Diff(tc.wantCalls, calls); diff != "" {
+				t.Errorf("\n%s\nSetup(...): -want calls, +got calls:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	k8sExec "k8s.io/utils/exec"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+const (
+	// DefaultSidecarPollInterval is the default interval the sidecar
+	// executor and server check the exchange directory with.
+	DefaultSidecarPollInterval = time.Second
+
+	sidecarRequestSuffix  = ".request.json"
+	sidecarResponseSuffix = ".response.json"
+	sidecarCancelSuffix   = ".cancel"
+
+	errSidecarNoAsync         = "cannot run a command asynchronously in sidecar mode"
+	errFmtSidecarBinaryDenied = "cannot run %q in the sidecar: only %q is allowed"
+)
+
+// SidecarRequest is a Terraform CLI invocation requested from the sidecar.
+type SidecarRequest struct {
+	// Binary is the path of the Terraform CLI binary in the sidecar.
+	Binary string `json:"binary"`
+	// Args are the arguments of the command.
+	Args []string `json:"args"`
+	// Dir is the workspace directory the command is run in. It must be in
+	// the volume shared with the sidecar.
+	Dir string `json:"dir,omitempty"`
+	// Env is the whole environment of the command.
+	Env []string `json:"env,omitempty"`
+	// Combined requests the standard error to be included in the output.
+	Combined bool `json:"combined,omitempty"`
+}
+
+// SidecarResponse is the result of a SidecarRequest.
+type SidecarResponse struct {
+	// Output of the command.
+	Output string `json:"output"`
+	// ExitCode of the command. Zero if the command succeeded.
+	ExitCode int `json:"exitCode"`
+	// Error is the message of the error returned by the command, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// SidecarExecutor is a k8sExec.Interface that runs the Terraform CLI in a
+// sidecar container instead of the controller. The commands are exchanged as
+// files in a directory on a volume shared with the sidecar, e.g. an
+// emptyDir, where the sidecar runs a SidecarServer. The workspaces need to
+// be in the shared volume, too, see WithWorkdir. Since the containers of a
+// pod share the network namespace, the provider reattach configuration the
+// commands are run with stays valid in the sidecar. The controller image
+// doesn't need the Terraform CLI and a crashing CLI cannot take the
+// controller down.
+type SidecarExecutor struct {
+	sidecarConfig
+}
+
+// sidecarConfig is the configuration shared by the SidecarExecutor and the
+// SidecarServer.
+type sidecarConfig struct {
+	fs       afero.Afero
+	dir      string
+	interval time.Duration
+}
+
+func newSidecarConfig(dir string, opts []SidecarOption) sidecarConfig {
+	c := sidecarConfig{
+		fs:       afero.Afero{Fs: afero.NewOsFs()},
+		dir:      dir,
+		interval: DefaultSidecarPollInterval,
+	}
+	for _, f := range opts {
+		f(&c)
+	}
+	return c
+}
+
+func (c sidecarConfig) path(id, suffix string) string {
+	return filepath.Join(c.dir, id+suffix)
+}
+
+// SidecarOption configures the SidecarExecutor and the SidecarServer.
+type SidecarOption func(*sidecarConfig)
+
+// WithSidecarFs sets the filesystem the exchange directory is in. Used mostly
+// for testing.
+func WithSidecarFs(fs afero.Fs) SidecarOption {
+	return func(c *sidecarConfig) {
+		c.fs = afero.Afero{Fs: fs}
+	}
+}
+
+// WithSidecarPollInterval sets the interval the exchange directory is checked
+// with.
+func WithSidecarPollInterval(d time.Duration) SidecarOption {
+	return func(c *sidecarConfig) {
+		c.interval = d
+	}
+}
+
+// NewSidecarExecutor returns a SidecarExecutor that exchanges the commands in
+// the given directory.
+func NewSidecarExecutor(dir string, opts ...SidecarOption) *SidecarExecutor {
+	return &SidecarExecutor{sidecarConfig: newSidecarConfig(dir, opts)}
+}
+
+// Command returns a Cmd that is run in the sidecar.
+func (e *SidecarExecutor) Command(cmd string, args ...string) k8sExec.Cmd {
+	return e.CommandContext(context.Background(), cmd, args...)
+}
+
+// CommandContext returns a Cmd that is run in the sidecar. If the given
+// context is done before the command finishes, the sidecar is asked to stop
+// it.
+func (e *SidecarExecutor) CommandContext(ctx context.Context, cmd string, args ...string) k8sExec.Cmd {
+	return &sidecarCmd{executor: e, ctx: ctx, req: SidecarRequest{Binary: cmd, Args: args}}
+}
+
+// LookPath returns the given file as is since it is looked up in the sidecar.
+func (e *SidecarExecutor) LookPath(file string) (string, error) {
+	return file, nil
+}
+
+type sidecarCmd struct {
+	executor *SidecarExecutor
+	ctx      context.Context
+	req      SidecarRequest
+}
+
+func (sc *sidecarCmd) SetDir(dir string) {
+	sc.req.Dir = dir
+}
+
+func (sc *sidecarCmd) SetEnv(env []string) {
+	sc.req.Env = env
+}
+
+func (sc *sidecarCmd) Run() error {
+	_, err := sc.run(false)
+	return err
+}
+
+func (sc *sidecarCmd) CombinedOutput() ([]byte, error) {
+	return sc.run(true)
+}
+
+func (sc *sidecarCmd) Output() ([]byte, error) {
+	return sc.run(false)
+}
+
+func (sc *sidecarCmd) Start() error {
+	return errors.New(errSidecarNoAsync)
+}
+
+func (sc *sidecarCmd) Wait() error {
+	return errors.New(errSidecarNoAsync)
+}
+
+func (sc *sidecarCmd) StdoutPipe() (io.ReadCloser, error) {
+	return nil, errors.New(errSidecarNoAsync)
+}
+
+func (sc *sidecarCmd) StderrPipe() (io.ReadCloser, error) {
+	return nil, errors.New(errSidecarNoAsync)
+}
+
+func (sc *sidecarCmd) SetStdin(_ io.Reader) {}
+
+func (sc *sidecarCmd) SetStdout(_ io.Writer) {}
+
+func (sc *sidecarCmd) SetStderr(_ io.Writer) {}
+
+func (sc *sidecarCmd) Stop() {}
+
+// run requests the command from the sidecar and waits for its response. If
+// the context of the command is done first, a cancellation is requested and
+// the sidecar cleans the exchange files up once the command stops.
+func (sc *sidecarCmd) run(combined bool) ([]byte, error) {
+	e := sc.executor
+	sc.req.Combined = combined
+	raw, err := json.JSParser.Marshal(sc.req)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal sidecar request")
+	}
+	id := string(uuid.NewUUID())
+	if err := writeFileAtomic(e.fs.Fs, e.path(id, sidecarRequestSuffix), raw, 0600); err != nil {
+		return nil, errors.Wrap(err, "cannot write sidecar request")
+	}
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+	for {
+		raw, err := e.fs.ReadFile(e.path(id, sidecarResponseSuffix))
+		switch {
+		case err == nil:
+			return readSidecarResponse(raw, e.fs, e.path(id, sidecarRequestSuffix), e.path(id, sidecarResponseSuffix))
+		case !os.IsNotExist(err):
+			return nil, errors.Wrap(err, "cannot read sidecar response")
+		}
+		select {
+		case <-sc.ctx.Done():
+			if err := e.fs.WriteFile(e.path(id, sidecarCancelSuffix), nil, 0600); err != nil {
+				return nil, errors.Wrap(err, "cannot request the cancellation of the sidecar command")
+			}
+			return nil, sc.ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func readSidecarResponse(raw []byte, fs afero.Afero, files ...string) ([]byte, error) {
+	for _, f := range files {
+		_ = fs.Remove(f)
+	}
+	res := SidecarResponse{}
+	if err := json.JSParser.Unmarshal(raw, &res); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal sidecar response")
+	}
+	if res.Error != "" {
+		return []byte(res.Output), k8sExec.CodeExitError{Err: errors.New(res.Error), Code: res.ExitCode}
+	}
+	return []byte(res.Output), nil
+}
+
+// SidecarServer runs the Terraform CLI commands requested by the
+// SidecarExecutor of the controller in the sidecar container. Only the
+// configured Terraform CLI binary is run; the requests for any other binary
+// are rejected so that whoever can write into the exchange directory cannot
+// run arbitrary programs in the sidecar.
+type SidecarServer struct {
+	sidecarConfig
+	binary   string
+	executor k8sExec.Interface
+	logger   logging.Logger
+
+	mu sync.Mutex
+	// running are the cancel functions of the running commands keyed by
+	// their request IDs.
+	running map[string]context.CancelFunc
+	// done are the IDs of the requests that are responded but whose
+	// request files haven't been removed by the controller yet.
+	done map[string]struct{}
+}
+
+// NewSidecarServer returns a SidecarServer that serves the commands requested
+// in the given directory with the given Terraform CLI binary, which needs to
+// be the same path the controller is configured with, see WithTerraformPath.
+func NewSidecarServer(l logging.Logger, dir, binary string, opts ...SidecarOption) *SidecarServer {
+	return &SidecarServer{
+		sidecarConfig: newSidecarConfig(dir, opts),
+		binary:        binary,
+		executor:      k8sExec.New(),
+		logger:        l,
+		running:       map[string]context.CancelFunc{},
+		done:          map[string]struct{}{},
+	}
+}
+
+// Serve runs the requested commands until the given context is done. The
+// running commands are stopped when it returns.
+func (s *SidecarServer) Serve(ctx context.Context) error {
+	if err := s.fs.MkdirAll(s.dir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "cannot create sidecar exchange directory")
+	}
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		if err := s.poll(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// poll starts the new requests and cancels the running ones whose
+// cancellation is requested.
+func (s *SidecarServer) poll(ctx context.Context) error {
+	files, err := s.fs.ReadDir(s.dir)
+	if err != nil {
+		return errors.Wrap(err, "cannot read sidecar exchange directory")
+	}
+	requests := map[string]struct{}{}
+	cancels := map[string]struct{}{}
+	for _, f := range files {
+		switch name := f.Name(); {
+		case strings.HasSuffix(name, sidecarRequestSuffix):
+			requests[strings.TrimSuffix(name, sidecarRequestSuffix)] = struct{}{}
+		case strings.HasSuffix(name, sidecarCancelSuffix):
+			cancels[strings.TrimSuffix(name, sidecarCancelSuffix)] = struct{}{}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.done {
+		if _, ok := requests[id]; !ok {
+			delete(s.done, id)
+		}
+	}
+	for id := range cancels {
+		if cancel, ok := s.running[id]; ok {
+			cancel()
+			continue
+		}
+		// NOTE(muvaf): The command has either finished or not started
+		// before its cancellation was noticed, so nobody will read its
+		// response.
+		s.cleanup(id)
+		delete(s.done, id)
+	}
+	for id := range requests {
+		_, running := s.running[id]
+		_, done := s.done[id]
+		_, cancelled := cancels[id]
+		if running || done || cancelled {
+			continue
+		}
+		cctx, cancel := context.WithCancel(ctx)
+		s.running[id] = cancel
+		go s.serve(cctx, id)
+	}
+	return nil
+}
+
+func (s *SidecarServer) serve(ctx context.Context, id string) {
+	res := s.run(ctx, id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stopped := ctx.Err() != nil
+	s.running[id]()
+	delete(s.running, id)
+	if stopped {
+		// NOTE(muvaf): If the server is stopping rather than the command
+		// being cancelled, the request is kept to be served again once the
+		// sidecar restarts.
+		if _, err := s.fs.Stat(s.path(id, sidecarCancelSuffix)); err == nil {
+			s.cleanup(id)
+		}
+		return
+	}
+	s.done[id] = struct{}{}
+	raw, err := json.JSParser.Marshal(res)
+	if err == nil {
+		err = writeFileAtomic(s.fs.Fs, s.path(id, sidecarResponseSuffix), raw, 0600)
+	}
+	if err != nil {
+		s.logger.Info("cannot write sidecar response", "id", id, "error", err.Error())
+	}
+}
+
+func (s *SidecarServer) run(ctx context.Context, id string) SidecarResponse {
+	raw, err := s.fs.ReadFile(s.path(id, sidecarRequestSuffix))
+	if err != nil {
+		return SidecarResponse{ExitCode: 1, Error: errors.Wrap(err, "cannot read sidecar request").Error()}
+	}
+	req := SidecarRequest{}
+	if err := json.JSParser.Unmarshal(raw, &req); err != nil {
+		return SidecarResponse{ExitCode: 1, Error: errors.Wrap(err, "cannot unmarshal sidecar request").Error()}
+	}
+	if req.Binary != s.binary {
+		s.logger.Info("rejected sidecar request", "id", id, "binary", req.Binary)
+		return SidecarResponse{ExitCode: 1, Error: errors.Errorf(errFmtSidecarBinaryDenied, req.Binary, s.binary).Error()}
+	}
+	cmd := s.executor.CommandContext(ctx, req.Binary, req.Args...)
+	cmd.SetEnv(req.Env)
+	if req.Dir != "" {
+		cmd.SetDir(req.Dir)
+	}
+	var out []byte
+	if req.Combined {
+		out, err = cmd.CombinedOutput()
+	} else {
+		out, err = cmd.Output()
+	}
+	s.logger.Debug("sidecar command ended", "id", id, "args", req.Args)
+	res := SidecarResponse{Output: string(out)}
+	if err != nil {
+		res.Error = err.Error()
+		res.ExitCode = 1
+		if ee, ok := err.(k8sExec.ExitError); ok {
+			res.ExitCode = ee.ExitStatus()
+		}
+	}
+	return res
+}
+
+// cleanup removes the exchange files of the given request. It must be called
+// with the lock held.
+func (s *SidecarServer) cleanup(id string) {
+	for _, suffix := range []string{sidecarRequestSuffix, sidecarResponseSuffix, sidecarCancelSuffix} {
+		_ = s.fs.Remove(s.path(id, suffix))
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	k8sExec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+func TestSidecarExecutor(t *testing.T) {
+	type want struct {
+		out  string
+		err  error
+		argv []string
+		dirs []string
+		env  []string
+	}
+	cases := map[string]struct {
+		reason string
+		binary string
+		out    string
+		err    error
+		want
+	}{
+		"Success": {
+			reason: "The command should be run in the sidecar with the requested arguments, directory and environment",
+			binary: "/usr/bin/terraform",
+			out:    "applied",
+			want: want{
+				out:  "applied",
+				argv: []string{"/usr/bin/terraform", "apply", "-auto-approve"},
+				dirs: []string{"/shared/ws"},
+				env:  []string{"TF_REATTACH_PROVIDERS=config"},
+			},
+		},
+		"ExitError": {
+			reason: "The exit code of a failed command should be returned",
+			binary: "/usr/bin/terraform",
+			out:    "boom",
+			err:    k8sExec.CodeExitError{Err: errors.New("exit status 2"), Code: 2},
+			want: want{
+				out:  "boom",
+				err:  k8sExec.CodeExitError{Err: errors.New("exit status 2"), Code: 2},
+				argv: []string{"/usr/bin/terraform", "apply", "-auto-approve"},
+				dirs: []string{"/shared/ws"},
+				env:  []string{"TF_REATTACH_PROVIDERS=config"},
+			},
+		},
+		"BinaryDenied": {
+			reason: "A binary other than the configured Terraform CLI should not be run",
+			binary: "/bin/sh",
+			want: want{
+				err: k8sExec.CodeExitError{Err: errors.Errorf(errFmtSidecarBinaryDenied, "/bin/sh", "/usr/bin/terraform"), Code: 1},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			fc := &testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte(tc.out), nil, tc.err
+					},
+				},
+			}
+			s := NewSidecarServer(logging.NewNopLogger(), "/exchange", "/usr/bin/terraform", WithSidecarFs(fs), WithSidecarPollInterval(time.Millisecond))
+			s.executor = &testingexec.FakeExec{
+				CommandScript: []testingexec.FakeCommandAction{
+					func(cmd string, args ...string) k8sExec.Cmd {
+						return testingexec.InitFakeCmd(fc, cmd, args...)
+					},
+				},
+			}
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			go s.Serve(ctx) // nolint:errcheck
+
+			e := NewSidecarExecutor("/exchange", WithSidecarFs(fs), WithSidecarPollInterval(time.Millisecond))
+			cmd := e.CommandContext(context.TODO(), tc.binary, "apply", "-auto-approve")
+			cmd.SetDir("/shared/ws")
+			cmd.SetEnv([]string{"TF_REATTACH_PROVIDERS=config"})
+			out, err := cmd.CombinedOutput()
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nCombinedOutput(): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.out, string(out)); diff != "" {
+				t.Errorf("\n%s\nCombinedOutput(): -want output, +got output:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.argv, fc.Argv); diff != "" {
+				t.Errorf("\n%s\nCombinedOutput(): -want arguments, +got arguments:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.dirs, fc.Dirs); diff != "" {
+				t.Errorf("\n%s\nCombinedOutput(): -want directory, +got directory:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.env, fc.Env); diff != "" {
+				t.Errorf("\n%s\nCombinedOutput(): -want environment, +got environment:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSidecarExecutorCancelled(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	e := NewSidecarExecutor("/exchange", WithSidecarFs(fs), WithSidecarPollInterval(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err := e.CommandContext(ctx, "terraform", "plan").CombinedOutput()
+	if diff := cmp.Diff(context.Canceled, err, test.EquateErrors()); diff != "" {
+		t.Errorf("CombinedOutput(): -want error, +got error:\n%s", diff)
+	}
+
+	// The server should drop the request without running it since its
+	// cancellation is requested before it starts.
+	s := NewSidecarServer(logging.NewNopLogger(), "/exchange", "terraform", WithSidecarFs(fs))
+	s.executor = &testingexec.FakeExec{}
+	if err := s.poll(context.TODO()); err != nil {
+		t.Fatalf("poll(...): unexpected error: %s", err)
+	}
+	files, err := afero.ReadDir(fs, "/exchange")
+	if err != nil {
+		t.Fatalf("cannot read exchange directory: %s", err)
+	}
+	if diff := cmp.Diff(0, len(files)); diff != "" {
+		t.Errorf("poll(...): -want exchange files, +got exchange files:\n%s", diff)
+	}
+}
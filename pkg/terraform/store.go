@@ -21,18 +21,27 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/afero"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/utils/exec"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 
 	"github.com/crossplane/terrajet/pkg/config"
 	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/json"
 )
 
 const (
@@ -43,6 +52,11 @@ const (
 // provider requirement, configuration and Terraform version.
 type SetupFn func(ctx context.Context, client client.Client, mg xpresource.Managed) (Setup, error)
 
+// NamespacedSetupFn is a function that returns the Terraform setup of the given
+// managed resource from the namespaced object with the given key instead of
+// the cluster-scoped ProviderConfig.
+type NamespacedSetupFn func(ctx context.Context, client client.Client, mg xpresource.Managed, ref types.NamespacedName) (Setup, error)
+
 // ProviderRequirement holds values for the Terraform HCL setup requirements
 type ProviderRequirement struct {
 	Source  string
@@ -59,8 +73,44 @@ type Setup struct {
 	Requirement   ProviderRequirement
 	Configuration ProviderConfiguration
 	Env           []string
+	// DestroyGroup is the serialization group of the destroy operations.
+	// The destroy operations of the workspaces in the same group run one at
+	// a time while creations and updates still run in parallel. Destroy
+	// operations are not serialized if it is empty.
+	DestroyGroup string
+	// Parallelism is the number of concurrent operations Terraform walks the
+	// resource graph with in apply and destroy operations, i.e. the
+	// -parallelism flag. It can be lowered to reduce the pressure on the
+	// provider API. Terraform's default is used if it is zero. The
+	// Parallelism configuration of a resource takes precedence.
+	Parallelism int
+	// Files are the files written into the workspace with permissions
+	// allowing only the owner to read them, keyed by their names, e.g. the
+	// JSON key of a GCP service account or a kubeconfig for the providers
+	// that need credentials on disk. They are removed along with the
+	// workspace. Use CredentialFilePath to reference them from the provider
+	// configuration.
+	Files map[string][]byte
+}
+
+// SetEnv sets the environment variable with the given key to the given value
+// in the environment of the Terraform processes, replacing its previous
+// value if any. The Env slice is never modified in place so that Setups
+// sharing it are not affected.
+func (s *Setup) SetEnv(key, value string) {
+	env := make([]string, 0, len(s.Env)+1)
+	for _, e := range s.Env {
+		if !strings.HasPrefix(e, key+"=") {
+			env = append(env, e)
+		}
+	}
+	s.Env = append(env, key+"="+value)
 }
 
+// StateRestoreFn returns the backed up Terraform state of the given resource,
+// or nil if it has no backup.
+type StateRestoreFn func(ctx context.Context, c resource.SecretClient, tr resource.Terraformed) ([]byte, error)
+
 // WorkspaceStoreOption lets you configure the workspace store.
 type WorkspaceStoreOption func(*WorkspaceStore)
 
@@ -78,6 +128,195 @@ func WithProviderRunner(pr ProviderRunner) WorkspaceStoreOption {
 	}
 }
 
+// WithBundle makes the workspaces use the Terraform CLI and provider binaries
+// in the given bundle instead of downloading them.
+func WithBundle(b *Bundle) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.terraformPath = b.Terraform.Path
+		ws.bundle = b
+	}
+}
+
+// WithStateRestore makes the store hydrate the workspaces that have no state
+// from the backups returned by the given function if the resources don't
+// have their private attributes stored, instead of reproducing the state from
+// the parameters of the resources, which would end up creating new external
+// resources if their identifiers cannot be derived from the parameters. The
+// function must return only the backups of the given resource, not the ones
+// of a former resource with the same name.
+func WithStateRestore(fn StateRestoreFn) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.restoreState = fn
+	}
+}
+
+type privateRawStoreKey struct{}
+
+// ContextWithPrivateRawStore returns a copy of the given context that makes
+// the WorkspaceStore read the private attributes of the Terraform state of a
+// resource from the given store when it reproduces the state. It needs to be
+// the same store the controller writes the private attributes to.
+func ContextWithPrivateRawStore(ctx context.Context, s resource.PrivateRawStore) context.Context {
+	return context.WithValue(ctx, privateRawStoreKey{}, s)
+}
+
+// PrivateRawStoreFromContext returns the store of the private attributes of
+// the Terraform states carried by the given context. It defaults to
+// resource.AnnotationPrivateRawStore.
+func PrivateRawStoreFromContext(ctx context.Context) resource.PrivateRawStore {
+	if s, ok := ctx.Value(privateRawStoreKey{}).(resource.PrivateRawStore); ok && s != nil {
+		return s
+	}
+	return resource.AnnotationPrivateRawStore{}
+}
+
+// WithCommandExecutor sets the executor the workspaces run the Terraform CLI
+// with, e.g. a CassetteExecutor to record or replay Terraform interactions in
+// tests.
+func WithCommandExecutor(e exec.Interface) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.executor = e
+	}
+}
+
+// WithWorkspaceDir sets the convention the workspace directories are named
+// with. Workspaces found in the directories named with any of the given
+// legacy conventions are moved to the directories following the new one the
+// first time they are requested, so that changing the convention on an
+// upgrade does not strand the state of existing resources.
+func WithWorkspaceDir(fn WorkspaceDirFn, legacy ...WorkspaceDirFn) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.dirFn = fn
+		ws.legacyDirFns = legacy
+	}
+}
+
+// WithErrorMessageLimit sets the maximum size of the messages of the errors
+// returned by the Terraform operations in bytes. See WithMaxErrorMessageSize.
+func WithErrorMessageLimit(n int) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.maxErrorMessageSize = n
+	}
+}
+
+// WithApplyTraces makes the workspaces keep the last n bytes of the
+// TF_LOG=debug output of the failed apply operations in their directories.
+// See the workspace option WithTraceCapture.
+func WithApplyTraces(n int) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.traceLimit = n
+	}
+}
+
+// WithIsolatedEnv makes the Terraform processes of the workspaces inherit
+// only the given variables of the environment of the controller, e.g. PATH
+// and HOME. See the workspace option WithInheritedEnv.
+func WithIsolatedEnv(keys ...string) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.inheritedEnv = keys
+		ws.isolateEnv = true
+	}
+}
+
+// WithInitRetry sets the backoff "terraform init" is retried with when it
+// fails due to a transient registry or network failure. See WithInitBackoff.
+func WithInitRetry(b wait.Backoff) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.initBackoff = b
+	}
+}
+
+// WithIdleWorkspaceTTL makes the store tear down the workspaces of the
+// resources that haven't been reconciled for the given duration. The state
+// of a resource is kept in its critical annotations and status, so its
+// workspace is reproduced from them the next time it's requested.
+func WithIdleWorkspaceTTL(d time.Duration) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.idleTTL = d
+	}
+}
+
+// WithMaxWorkspaces bounds the number of workspaces kept in the store. When
+// the limit is exceeded, the least recently used idle workspaces are torn
+// down the same way WithIdleWorkspaceTTL does.
+func WithMaxWorkspaces(n int) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.maxWorkspaces = n
+	}
+}
+
+// WithEnv adds the given variables, in the form of KEY=VALUE, to the
+// environment of the Terraform processes of all workspaces. The environment
+// returned in the Setup of a resource takes precedence.
+func WithEnv(env ...string) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.env = env
+	}
+}
+
+// WithWorkdir sets the directory the workspace directories are created in.
+// The temporary directory of the filesystem of the store is used by default.
+func WithWorkdir(dir string) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.workdir = dir
+	}
+}
+
+// WithTerraformBinary sets the path of the Terraform CLI binary the
+// workspaces run. The binary is looked up in PATH if it is not an absolute
+// path. It takes precedence over the binary of a bundle only if given after
+// WithBundle.
+func WithTerraformBinary(path string) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.terraformPath = path
+	}
+}
+
+// WithExecTimeout bounds the duration of the blocking Terraform operations of
+// the workspaces. See the workspace option WithCommandTimeout.
+func WithExecTimeout(d time.Duration) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.execTimeout = d
+	}
+}
+
+// WithAsyncExecTimeout sets the duration after which the async Terraform
+// operations of the workspaces are cancelled. See the workspace option
+// WithAsyncTimeout.
+func WithAsyncExecTimeout(d time.Duration) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.asyncExecTimeout = d
+	}
+}
+
+// WithWorkerPool makes the workspaces run their Terraform operations on a
+// WorkerPool with the given number of workers shared by all of them, which
+// bounds the number of concurrent Terraform processes. Destroy operations
+// run before applies and applies run before plans and refreshes.
+func WithWorkerPool(workers int) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.runner = NewWorkerPool(workers)
+	}
+}
+
+// WithQueuedOperations makes the blocking operations of the workspaces wait
+// for a running async operation for up to the given duration instead of
+// failing right away. See the workspace option WithOperationWait.
+func WithQueuedOperations(d time.Duration) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.operationWait = d
+	}
+}
+
+// WithScheme sets the scheme the GroupVersionKind of the resources the log
+// lines of their workspaces are tagged with is derived from if the resources
+// don't carry it.
+func WithScheme(s *runtime.Scheme) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.scheme = s
+	}
+}
+
 // NewWorkspaceStore returns a new WorkspaceStore.
 func NewWorkspaceStore(l logging.Logger, opts ...WorkspaceStoreOption) *WorkspaceStore {
 	ws := &WorkspaceStore{
@@ -87,6 +326,14 @@ func NewWorkspaceStore(l logging.Logger, opts ...WorkspaceStoreOption) *Workspac
 		fs:             afero.Afero{Fs: afero.NewOsFs()},
 		executor:       exec.New(),
 		providerRunner: NewNoOpProviderRunner(),
+		terraformPath:  defaultTerraformPath,
+		destroyGroups:  NewSerialGroups(),
+		dirFn:          UIDWorkspaceDir,
+		now:            time.Now,
+		failures:       newFailureCounter(),
+
+		maxErrorMessageSize: DefaultMaxErrorMessageSize,
+		initBackoff:         DefaultInitBackoff,
 	}
 	for _, f := range opts {
 		f(ws)
@@ -105,19 +352,78 @@ type WorkspaceStore struct {
 	providerRunner ProviderRunner
 	mu             sync.Mutex
 
-	fs       afero.Afero
-	executor exec.Interface
+	fs               afero.Afero
+	executor         exec.Interface
+	terraformPath    string
+	workdir          string
+	env              []string
+	execTimeout      time.Duration
+	asyncExecTimeout time.Duration
+	runner           Runner
+	operationWait    time.Duration
+	bundle           *Bundle
+	destroyGroups    *SerialGroups
+	dirFn            WorkspaceDirFn
+	legacyDirFns     []WorkspaceDirFn
+	restoreState     StateRestoreFn
+	// tracerProvider produces the spans of the Terraform CLI commands if
+	// it's set.
+	tracerProvider trace.TracerProvider
+	// failures counts the failed operations of the workspaces by their
+	// class.
+	failures *prometheus.CounterVec
+
+	maxErrorMessageSize int
+	initBackoff         wait.Backoff
+	traceLimit          int
+	isolateEnv          bool
+	inheritedEnv        []string
+	// scheme is used to find the GroupVersionKind of the resources in the
+	// log lines if they don't carry it.
+	scheme *runtime.Scheme
+	// pluginDir is the provider filesystem mirror that is populated from the
+	// bundle the first time a workspace is initialized. It is guarded by
+	// pluginMu instead of mu so that copying the plugins does not block the
+	// store.
+	pluginMu  sync.Mutex
+	pluginDir *string
+	// cli is lazily initialized with the detected Terraform CLI version the
+	// first time a workspace is requested. It is guarded by cliMu instead of
+	// mu so that running the CLI does not block the store.
+	cliMu sync.Mutex
+	cli   *CommandBuilder
+
+	idleTTL       time.Duration
+	maxWorkspaces int
+	lastSweep     time.Time
+	now           func() time.Time
 }
 
 // Workspace makes sure the Terraform workspace for the given resource is ready
 // to be used and returns the Workspace object configured to work in that
-// workspace folder in the filesystem.
-func (ws *WorkspaceStore) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts Setup, cfg *config.Resource) (*Workspace, error) { //nolint:gocyclo
-	dir := filepath.Join(ws.fs.GetTempDir(""), string(tr.GetUID()))
-	if err := ws.fs.MkdirAll(dir, os.ModePerm); err != nil {
-		return nil, errors.Wrap(err, "cannot create directory for workspace")
+// workspace folder in the filesystem. The private attributes of the Terraform
+// state of the resource are read from the store carried by the context, see
+// ContextWithPrivateRawStore.
+// The returned errors carry the key-value pairs the log lines of the
+// workspace are tagged with, see LogValues.
+func (ws *WorkspaceStore) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts Setup, cfg *config.Resource) (*Workspace, error) {
+	base := ws.workdir
+	if base == "" {
+		base = ws.fs.GetTempDir("")
 	}
-	fp, err := NewFileProducer(ctx, c, dir, tr, ts, cfg)
+	dir := filepath.Join(base, ws.dirFn(tr))
+	w, err := ws.resourceWorkspace(ctx, c, tr, ts, cfg, base, dir)
+	return w, withLogValues(err, ws.logValues(tr, dir))
+}
+
+func (ws *WorkspaceStore) resourceWorkspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts Setup, cfg *config.Resource, base, dir string) (*Workspace, error) { //nolint:gocyclo
+	if err := ws.migrateWorkspaceDir(tr, base, dir); err != nil {
+		return nil, errors.Wrap(err, "cannot migrate workspace directory")
+	}
+	if err := ws.claimWorkspaceDir(tr, dir); err != nil {
+		return nil, err
+	}
+	fp, err := NewFileProducer(ctx, c, dir, tr, ts, cfg, WithPrivateRawStore(PrivateRawStoreFromContext(ctx)))
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot create a new file producer")
 	}
@@ -126,40 +432,222 @@ func (ws *WorkspaceStore) Workspace(ctx context.Context, c resource.SecretClient
 		return nil, errors.Wrap(err, "cannot stat terraform.tfstate file")
 	}
 	if os.IsNotExist(err) {
-		if err := fp.WriteTFState(ctx); err != nil {
-			return nil, errors.Wrap(err, "cannot reproduce tfstate file")
+		restored, err := ws.restore(ctx, c, tr, fp.Dir)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot restore tfstate file from backup")
+		}
+		if !restored {
+			if err := fp.WriteTFState(ctx); err != nil {
+				return nil, errors.Wrap(err, "cannot reproduce tfstate file")
+			}
 		}
 	}
 	if err := fp.WriteMainTF(); err != nil {
 		return nil, errors.Wrap(err, "cannot write main tf file")
 	}
-	l := ws.logger.WithValues("workspace", dir)
-	attachmentConfig, err := ws.providerRunner.Start()
+	if err := fp.WriteFiles(); err != nil {
+		return nil, errors.Wrap(err, "cannot write setup files")
+	}
+	w, initialized, err := ws.workspace(ctx, tr, dir, ts, WithDestroyVerification(cfg.VerifyDeletion))
 	if err != nil {
 		return nil, err
 	}
+	if cfg.Parallelism > 0 {
+		w.parallelism = cfg.Parallelism
+	}
+	w.driftInterval = cfg.DriftDetectionInterval
+	w.configHash = fp.mainTFHash
+	w.address = tr.GetTerraformResourceType() + "." + tr.GetName()
+	w.replace = ""
+	if resource.ReplaceRequested(tr) {
+		w.replace = w.address
+	}
+	return ws.initialize(ctx, w, initialized)
+}
+
+// restore writes the backed up state of the given resource into the given
+// workspace directory if state restoration is enabled, the resource doesn't
+// have its private attributes stored and a backup exists. It reports whether
+// the state is restored. The restored state is refreshed by the next
+// observation of the resource like any other state.
+func (ws *WorkspaceStore) restore(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, dir string) (bool, error) {
+	if ws.restoreState == nil {
+		return false, nil
+	}
+	pr, err := PrivateRawStoreFromContext(ctx).GetPrivateRaw(ctx, tr)
+	if err != nil {
+		return false, errors.Wrap(err, errGetPrivateRaw)
+	}
+	if len(pr) != 0 {
+		return false, nil
+	}
+	raw, err := ws.restoreState(ctx, c, tr)
+	if err != nil || raw == nil {
+		return false, err
+	}
+	st := &json.StateV4{}
+	if err := json.JSParser.Unmarshal(raw, st); err != nil {
+		return false, errors.Wrap(err, "cannot unmarshal backed up state")
+	}
+	if err := writeFileAtomic(ws.fs, filepath.Join(dir, "terraform.tfstate"), raw, 0600); err != nil {
+		return false, errors.Wrap(err, "cannot write tfstate file")
+	}
+	ws.logger.Info("Restored terraform state from backup", ws.logValues(tr, dir)...)
+	return true, nil
+}
+
+// workspace returns the Workspace of the given object that works in the given
+// directory, creating it with the given options if it's not in the store yet,
+// and configures it with the given Setup. It also reports whether the
+// workspace directory has already been initialized.
+func (ws *WorkspaceStore) workspace(ctx context.Context, obj xpresource.Object, dir string, ts Setup, opts ...WorkspaceOption) (*Workspace, bool, error) {
+	uid := obj.GetUID()
+	l := ws.logger.WithValues(ws.logValues(obj, dir)...)
+	attachmentConfig, err := ws.providerRunner.Start()
+	if err != nil {
+		return nil, false, err
+	}
+	cli, err := ws.commandBuilder(ctx)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "cannot get terraform CLI command builder")
+	}
 	ws.mu.Lock()
-	w, ok := ws.store[tr.GetUID()]
+	w, ok := ws.store[uid]
 	if !ok {
-		ws.store[tr.GetUID()] = NewWorkspace(dir, WithLogger(l), WithExecutor(ws.executor))
-		w = ws.store[tr.GetUID()]
+		opts = append([]WorkspaceOption{WithLogger(l), WithExecutor(ws.executor), WithCommandBuilder(cli), WithTerraformPath(ws.terraformPath), WithMaxErrorMessageSize(ws.maxErrorMessageSize), WithInitBackoff(ws.initBackoff), WithTraceCapture(ws.traceLimit), WithCommandTimeout(ws.execTimeout), WithAsyncTimeout(ws.asyncExecTimeout), WithRunner(ws.runner), WithOperationWait(ws.operationWait)}, opts...)
+		if ws.isolateEnv {
+			opts = append(opts, WithInheritedEnv(ws.inheritedEnv...))
+		}
+		if ws.tracerProvider != nil {
+			opts = append(opts, WithTracerProvider(ws.tracerProvider))
+		}
+		ws.store[uid] = NewWorkspace(dir, opts...)
+		w = ws.store[uid]
+		w.failureCounter = ws.failures
 	}
+	w.lastUsed = ws.now()
+	ws.evict(uid)
 	ws.mu.Unlock()
 	_, err = ws.fs.Stat(filepath.Join(dir, ".terraform.lock.hcl"))
 	if xpresource.Ignore(os.IsNotExist, err) != nil {
-		return nil, errors.Wrap(err, "cannot stat init lock file")
+		return nil, false, errors.Wrap(err, "cannot stat init lock file")
+	}
+	// NOTE(muvaf): The environment is copied so that appending to it never
+	// writes into the backing array of a Setup shared with other workspaces.
+	env := make([]string, 0, len(ws.env)+len(ts.Env)+1)
+	env = append(append(env, ws.env...), ts.Env...)
+	w.env = append(env, fmt.Sprintf(fmtEnv, envReattachConfig, attachmentConfig))
+	w.destroyLock = ws.destroyGroups.Locker(ts.DestroyGroup)
+	w.parallelism = ts.Parallelism
+	return w, !os.IsNotExist(err), nil
+}
+
+// logValues returns the key-value pairs that identify the given object and its
+// workspace directory in the log lines of its workspace. The GroupVersionKind
+// is derived from the scheme of the store if the object doesn't carry it,
+// which is the case for the typed objects read from the API server, and it's
+// included only if it's known.
+func (ws *WorkspaceStore) logValues(obj xpresource.Object, dir string) []interface{} {
+	kv := []interface{}{logKeyName, obj.GetName(), logKeyUID, string(obj.GetUID()), logKeyWorkspace, dir}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() && ws.scheme != nil {
+		if sgvk, err := apiutil.GVKForObject(obj, ws.scheme); err == nil {
+			gvk = sgvk
+		}
+	}
+	if !gvk.Empty() {
+		kv = append(kv, logKeyGVK, gvk.String())
+	}
+	return kv
+}
+
+// logValuesError is an error that carries the key-value pairs that identify
+// the resource and the workspace it's returned for.
+type logValuesError struct {
+	error
+	kv []interface{}
+}
+
+func (e *logValuesError) Unwrap() error {
+	return e.error
+}
+
+// withLogValues attaches the given key-value pairs to the given error. It
+// returns nil if the error is nil.
+func withLogValues(err error, kv []interface{}) error {
+	if err == nil {
+		return nil
 	}
-	w.env = ts.Env
-	w.env = append(w.env, fmt.Sprintf(fmtEnv, envReattachConfig, attachmentConfig))
-	// We need to initialize only if the workspace hasn't been initialized yet.
-	if !os.IsNotExist(err) {
+	return &logValuesError{error: err, kv: kv}
+}
+
+// LogValues returns the key-value pairs that identify the resource and the
+// workspace the given error is returned for by WorkspaceStore, so that the
+// callers can tag the log lines of the error with the same values the log
+// lines of the workspace are tagged with. It returns nil if the error doesn't
+// carry them.
+func LogValues(err error) []interface{} {
+	var e *logValuesError
+	if !errors.As(err, &e) {
+		return nil
+	}
+	return e.kv
+}
+
+// initialize initializes the given workspace unless it has already been
+// initialized.
+func (ws *WorkspaceStore) initialize(ctx context.Context, w *Workspace, initialized bool) (*Workspace, error) {
+	if initialized {
 		return w, nil
 	}
-	cmd := w.executor.CommandContext(ctx, "terraform", "init", "-input=false")
-	cmd.SetDir(w.dir)
-	out, err := cmd.CombinedOutput()
-	l.Debug("init ended", "out", string(out))
-	return w, errors.Wrapf(err, "cannot init workspace: %s", string(out))
+	pluginDir, err := ws.pluginDirectory()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot prepare provider plugin directory")
+	}
+	return w, errors.Wrap(w.Init(ctx, pluginDir), "cannot init workspace")
+}
+
+// commandBuilder returns the CommandBuilder for the Terraform CLI in use. The
+// CLI version is detected once it succeeds and an unsupported version results
+// in an error for every workspace request. Concurrent requests wait for the
+// detection in progress without holding the store lock.
+func (ws *WorkspaceStore) commandBuilder(ctx context.Context) (*CommandBuilder, error) {
+	ws.cliMu.Lock()
+	defer ws.cliMu.Unlock()
+	if ws.cli != nil {
+		return ws.cli, nil
+	}
+	v, err := DetectCLIVersion(ctx, ws.executor, ws.terraformPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot detect terraform CLI version")
+	}
+	cli, err := NewCommandBuilder(v)
+	if err != nil {
+		return nil, err
+	}
+	ws.logger.Debug("Detected terraform CLI version", "version", cli.Version())
+	ws.cli = cli
+	return ws.cli, nil
+}
+
+// pluginDirectory returns the directory "terraform init" installs providers
+// from. It is empty if no bundle is used. The directory is populated once it
+// succeeds and concurrent requests wait for the population in progress.
+func (ws *WorkspaceStore) pluginDirectory() (string, error) {
+	ws.pluginMu.Lock()
+	defer ws.pluginMu.Unlock()
+	if ws.pluginDir != nil {
+		return *ws.pluginDir, nil
+	}
+	dir := ""
+	if ws.bundle != nil {
+		if err := ws.bundle.PopulatePluginDir(ws.fs.Fs, CurrentPlatform()); err != nil {
+			return "", err
+		}
+		dir = ws.bundle.PluginDir
+	}
+	ws.pluginDir = &dir
+	return dir, nil
 }
 
 // Remove deletes the workspace directory from the filesystem and erases its
@@ -177,3 +665,69 @@ func (ws *WorkspaceStore) Remove(obj xpresource.Object) error {
 	delete(ws.store, obj.GetUID())
 	return nil
 }
+
+const (
+	// evictionSweepPeriod is how often the store looks for idle workspaces
+	// when it's not over capacity.
+	evictionSweepPeriod = time.Minute
+	// evictionGracePeriod is how long a workspace is not evicted after it's
+	// requested from the store. It's longer than the default timeout of a
+	// reconciliation so that a workspace isn't removed between the time it's
+	// handed to a reconciliation and the time its operations start, or
+	// before the results of its operations are recorded on the resource.
+	evictionGracePeriod = 5 * time.Minute
+)
+
+// evict tears down the workspaces that have been idle longer than the idle
+// TTL and, if the store is over capacity, the least recently used ones. The
+// workspace of the given UID, the workspaces that have been requested within
+// the grace period, the ones with a running or waiting Terraform process and
+// the ones with an async operation whose result hasn't been consumed yet are
+// never evicted, so the store may stay over capacity while they are busy. It
+// must be called with the store lock held.
+func (ws *WorkspaceStore) evict(keep types.UID) {
+	overCapacity := ws.maxWorkspaces > 0 && len(ws.store) > ws.maxWorkspaces
+	now := ws.now()
+	if !overCapacity && (ws.idleTTL <= 0 || now.Sub(ws.lastSweep) < evictionSweepPeriod) {
+		return
+	}
+	ws.lastSweep = now
+	idle := make([]types.UID, 0, len(ws.store))
+	for uid, w := range ws.store {
+		if uid == keep || w.busy() || now.Sub(w.lastUsed) < evictionGracePeriod {
+			continue
+		}
+		if ws.idleTTL > 0 && now.Sub(w.lastUsed) > ws.idleTTL {
+			ws.evictWorkspace(uid, w)
+			continue
+		}
+		idle = append(idle, uid)
+	}
+	if ws.maxWorkspaces <= 0 || len(ws.store) <= ws.maxWorkspaces {
+		return
+	}
+	sort.Slice(idle, func(i, j int) bool {
+		return ws.store[idle[i]].lastUsed.Before(ws.store[idle[j]].lastUsed)
+	})
+	for _, uid := range idle {
+		if len(ws.store) <= ws.maxWorkspaces {
+			return
+		}
+		ws.evictWorkspace(uid, ws.store[uid])
+	}
+}
+
+// evictWorkspace removes the directory of the given idle workspace without
+// persisting its state first. Since only the workspaces whose operations have
+// ended and whose results have been recorded on their resources are evicted,
+// the state is reproduced from the critical annotations, the spec and the
+// status of the resource, or restored from its backup if a StateRestoreFn is
+// configured, the next time the workspace is requested.
+func (ws *WorkspaceStore) evictWorkspace(uid types.UID, w *Workspace) {
+	if err := ws.fs.RemoveAll(w.dir); err != nil {
+		ws.logger.Debug("cannot remove idle workspace folder", logKeyWorkspace, w.dir, "error", err.Error())
+		return
+	}
+	delete(ws.store, uid)
+	ws.logger.Debug("Evicted idle workspace", logKeyWorkspace, w.dir)
+}
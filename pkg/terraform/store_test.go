@@ -0,0 +1,347 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8sExec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+)
+
+func TestWorkspaceStoreEvict(t *testing.T) {
+	now := time.Now()
+	running := &Operation{}
+	running.MarkStart("apply")
+	type args struct {
+		opts       []WorkspaceStoreOption
+		workspaces map[types.UID]*Workspace
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want []types.UID
+	}{
+		"IdleTTL": {
+			reason: "Workspaces that have been idle longer than the TTL should be evicted",
+			args: args{
+				opts: []WorkspaceStoreOption{WithIdleWorkspaceTTL(time.Hour)},
+				workspaces: map[types.UID]*Workspace{
+					"current": {LastOperation: &Operation{}, dir: "/ws/current", lastUsed: now},
+					"recent":  {LastOperation: &Operation{}, dir: "/ws/recent", lastUsed: now.Add(-time.Minute)},
+					"idle":    {LastOperation: &Operation{}, dir: "/ws/idle", lastUsed: now.Add(-2 * time.Hour)},
+				},
+			},
+			want: []types.UID{"current", "recent"},
+		},
+		"Running": {
+			reason: "Workspaces with an ongoing async operation should never be evicted",
+			args: args{
+				opts: []WorkspaceStoreOption{WithIdleWorkspaceTTL(time.Hour)},
+				workspaces: map[types.UID]*Workspace{
+					"current": {LastOperation: &Operation{}, dir: "/ws/current", lastUsed: now},
+					"running": {LastOperation: running, dir: "/ws/running", lastUsed: now.Add(-2 * time.Hour)},
+				},
+			},
+			want: []types.UID{"current", "running"},
+		},
+		"Busy": {
+			reason: "Workspaces with a Terraform process that runs or waits for the execution lock should not be evicted",
+			args: args{
+				opts: []WorkspaceStoreOption{WithIdleWorkspaceTTL(time.Hour)},
+				workspaces: map[types.UID]*Workspace{
+					"current": {LastOperation: &Operation{}, dir: "/ws/current", lastUsed: now},
+					"busy":    {LastOperation: &Operation{}, dir: "/ws/busy", lastUsed: now.Add(-2 * time.Hour), execWaiters: 1},
+				},
+			},
+			want: []types.UID{"busy", "current"},
+		},
+		"RecentlyUsed": {
+			reason: "Workspaces requested within the grace period should not be evicted even if the store is over capacity",
+			args: args{
+				opts: []WorkspaceStoreOption{WithMaxWorkspaces(1)},
+				workspaces: map[types.UID]*Workspace{
+					"current": {LastOperation: &Operation{}, dir: "/ws/current", lastUsed: now},
+					"recent":  {LastOperation: &Operation{}, dir: "/ws/recent", lastUsed: now.Add(-time.Minute)},
+				},
+			},
+			want: []types.UID{"current", "recent"},
+		},
+		"LeastRecentlyUsed": {
+			reason: "The least recently used workspaces should be evicted when the store is over capacity",
+			args: args{
+				opts: []WorkspaceStoreOption{WithMaxWorkspaces(2)},
+				workspaces: map[types.UID]*Workspace{
+					"current": {LastOperation: &Operation{}, dir: "/ws/current", lastUsed: now.Add(-3 * time.Hour)},
+					"newer":   {LastOperation: &Operation{}, dir: "/ws/newer", lastUsed: now.Add(-time.Minute)},
+					"older":   {LastOperation: &Operation{}, dir: "/ws/older", lastUsed: now.Add(-time.Hour)},
+				},
+			},
+			want: []types.UID{"current", "newer"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			ws := NewWorkspaceStore(logging.NewNopLogger(), append(tc.args.opts, WithFs(fs))...)
+			ws.now = func() time.Time { return now }
+			for uid, w := range tc.args.workspaces {
+				if err := fs.MkdirAll(w.dir, 0700); err != nil {
+					t.Fatalf("cannot create workspace directory: %s", err)
+				}
+				ws.store[uid] = w
+			}
+			ws.evict("current")
+			got := make([]types.UID, 0, len(ws.store))
+			for uid, w := range ws.store {
+				got = append(got, uid)
+				if ok, _ := afero.DirExists(fs, w.dir); !ok {
+					t.Errorf("\n%s\nevict(...): directory of kept workspace %s should exist", tc.reason, uid)
+				}
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nevict(...): -want kept workspaces, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// privateRawStore returns the given private attributes for every resource.
+type privateRawStore struct {
+	resource.PrivateRawStore
+	privateRaw []byte
+}
+
+func (s privateRawStore) GetPrivateRaw(_ context.Context, _ resource.Terraformed) ([]byte, error) {
+	return s.privateRaw, nil
+}
+
+func TestWorkspaceStoreRestore(t *testing.T) {
+	backup := []byte(`{"version":4,"serial":3}`)
+	type args struct {
+		fn          StateRestoreFn
+		annotations map[string]string
+		privateRaw  resource.PrivateRawStore
+	}
+	type want struct {
+		restored bool
+		state    string
+		err      error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Disabled": {
+			reason: "The state should not be restored if state restoration is not enabled",
+		},
+		"Restored": {
+			reason: "The backed up state should be written into the workspace if the resource has lost its private attributes",
+			args: args{
+				fn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed) ([]byte, error) {
+					return backup, nil
+				},
+			},
+			want: want{
+				restored: true,
+				state:    string(backup),
+			},
+		},
+		"Annotated": {
+			reason: "The state should be reproduced from the resource instead of its backup if the resource has its private attributes",
+			args: args{
+				fn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed) ([]byte, error) {
+					return backup, nil
+				},
+				annotations: map[string]string{resource.AnnotationKeyPrivateRawAttribute: "privateraw"},
+			},
+		},
+		"StoredInContext": {
+			reason: "The private attributes should be read from the store carried by the context",
+			args: args{
+				fn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed) ([]byte, error) {
+					return backup, nil
+				},
+				privateRaw: privateRawStore{privateRaw: []byte("privateraw")},
+			},
+		},
+		"NoBackup": {
+			reason: "The state should not be restored if the resource has no backup",
+			args: args{
+				fn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed) ([]byte, error) {
+					return nil, nil
+				},
+			},
+		},
+		"Failed": {
+			reason: "An error should be returned if the backup cannot be read",
+			args: args{
+				fn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed) ([]byte, error) {
+					return nil, errBoom
+				},
+			},
+			want: want{
+				err: errBoom,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(fs), WithStateRestore(tc.args.fn))
+			tr := &fake.Terraformed{Managed: xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Annotations: tc.args.annotations}}}
+			ctx := context.TODO()
+			if tc.args.privateRaw != nil {
+				ctx = ContextWithPrivateRawStore(ctx, tc.args.privateRaw)
+			}
+			restored, err := ws.restore(ctx, nil, tr, dir)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Fatalf("\n%s\nrestore(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.restored, restored); diff != "" {
+				t.Errorf("\n%s\nrestore(...): -want restored, +got restored:\n%s", tc.reason, diff)
+			}
+			got, _ := afero.ReadFile(fs, filepath.Join(dir, "terraform.tfstate"))
+			if diff := cmp.Diff(tc.want.state, string(got)); diff != "" {
+				t.Errorf("\n%s\nrestore(...): -want state, +got state:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaceStoreCommandBuilder(t *testing.T) {
+	ws := NewWorkspaceStore(logging.NewNopLogger())
+	// The store should not be locked while the CLI version is detected so
+	// that the requests of the other workspaces are not blocked.
+	version := func() k8sExec.Cmd {
+		return &testingexec.FakeCmd{
+			OutputScript: []testingexec.FakeAction{
+				func() ([]byte, []byte, error) {
+					locked := make(chan struct{})
+					go func() {
+						ws.mu.Lock()
+						defer ws.mu.Unlock()
+						close(locked)
+					}()
+					select {
+					case <-locked:
+					case <-time.After(wait.ForeverTestTimeout):
+						t.Error("store is locked while the terraform CLI version is detected")
+					}
+					return []byte(`{"terraform_version":"1.2.3"}`), nil, nil
+				},
+			},
+		}
+	}
+	ws.executor = &testingexec.FakeExec{
+		CommandScript: []testingexec.FakeCommandAction{
+			func(_ string, _ ...string) k8sExec.Cmd { return version() },
+		},
+	}
+	for i := 0; i < 2; i++ {
+		cli, err := ws.commandBuilder(context.TODO())
+		if err != nil {
+			t.Fatalf("commandBuilder(...): %s", err)
+		}
+		if diff := cmp.Diff("1.2.3", cli.Version()); diff != "" {
+			t.Errorf("commandBuilder(...): -want version, +got version:\n%s", diff)
+		}
+	}
+	if diff := cmp.Diff(1, ws.executor.(*testingexec.FakeExec).CommandCalls); diff != "" {
+		t.Errorf("commandBuilder(...): -want CLI version detections, +got:\n%s", diff)
+	}
+}
+
+func TestLogValues(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "ec2.aws.jet.crossplane.io", Version: "v1alpha2", Kind: "VPC"}
+	sch := runtime.NewScheme()
+	sch.AddKnownTypeWithName(gvk, &fake.Terraformed{})
+	u := &unstructured.Unstructured{}
+	u.SetName("example")
+	u.SetUID("some-uid")
+	u.SetGroupVersionKind(gvk)
+	typed := &fake.Terraformed{}
+	typed.SetName("example")
+	typed.SetUID("some-uid")
+	cases := map[string]struct {
+		reason string
+		obj    xpresource.Object
+		opts   []WorkspaceStoreOption
+		want   []interface{}
+	}{
+		"ObjectKind": {
+			reason: "The GroupVersionKind carried by the object should be included",
+			obj:    u,
+			want:   []interface{}{logKeyName, "example", logKeyUID, "some-uid", logKeyWorkspace, "/ws/some-uid", logKeyGVK, "ec2.aws.jet.crossplane.io/v1alpha2, Kind=VPC"},
+		},
+		"Scheme": {
+			reason: "The GroupVersionKind should be derived from the scheme if the object does not carry it",
+			obj:    typed,
+			opts:   []WorkspaceStoreOption{WithScheme(sch)},
+			want:   []interface{}{logKeyName, "example", logKeyUID, "some-uid", logKeyWorkspace, "/ws/some-uid", logKeyGVK, "ec2.aws.jet.crossplane.io/v1alpha2, Kind=VPC"},
+		},
+		"Unknown": {
+			reason: "The GroupVersionKind should be omitted if it is not known",
+			obj:    typed,
+			want:   []interface{}{logKeyName, "example", logKeyUID, "some-uid", logKeyWorkspace, "/ws/some-uid"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ws := NewWorkspaceStore(logging.NewNopLogger(), tc.opts...)
+			if diff := cmp.Diff(tc.want, ws.logValues(tc.obj, "/ws/some-uid")); diff != "" {
+				t.Errorf("\n%s\nlogValues(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestErrorLogValues(t *testing.T) {
+	kv := []interface{}{logKeyName, "example"}
+	err := errors.Wrap(withLogValues(errors.New("boom"), kv), "cannot get workspace")
+	if diff := cmp.Diff(kv, LogValues(err)); diff != "" {
+		t.Errorf("LogValues(...): -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff("cannot get workspace: boom", err.Error()); diff != "" {
+		t.Errorf("Error(): -want, +got:\n%s", diff)
+	}
+	if withLogValues(nil, kv) != nil {
+		t.Errorf("withLogValues(nil, ...): should return nil")
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	k8sExec "k8s.io/utils/exec"
+)
+
+const (
+	// TracerName is the name of the OpenTelemetry tracer that produces the
+	// spans of terrajet.
+	TracerName = "github.com/crossplane/terrajet"
+
+	attrOperation = "terraform.operation"
+	attrWorkspace = "terraform.workspace"
+	attrExitCode  = "terraform.exit_code"
+)
+
+// WithTracerProvider makes the Workspace produce an OpenTelemetry span for
+// every Terraform CLI command it runs, with the operation, the workspace
+// directory and the exit code of the command as attributes. The spans are
+// children of the spans in the contexts of the synchronous operations, e.g.
+// the spans of the reconciliations. No spans are produced by default.
+func WithTracerProvider(tp trace.TracerProvider) WorkspaceOption {
+	return func(w *Workspace) {
+		w.tracer = tp.Tracer(TracerName)
+	}
+}
+
+// WithTracing makes the workspaces in the store produce OpenTelemetry spans
+// using the given TracerProvider. See WithTracerProvider.
+func WithTracing(tp trace.TracerProvider) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.tracerProvider = tp
+	}
+}
+
+// combinedOutput runs the given command of the given operation in a span and
+// returns its combined standard output and standard error.
+func (w *Workspace) combinedOutput(ctx context.Context, op string, cmd k8sExec.Cmd) ([]byte, error) {
+	_, span := w.tracer.Start(ctx, "terraform "+op, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(
+		attribute.String(attrOperation, op),
+		attribute.String(attrWorkspace, w.dir),
+	))
+	defer span.End()
+	out, err := cmd.CombinedOutput()
+	w.recordResult(err)
+	code := 0
+	if err != nil {
+		code = -1
+		if ee, ok := err.(k8sExec.ExitError); ok {
+			code = ee.ExitStatus()
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.Int(attrExitCode, code))
+	return out, err
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+// recordingSpan records the attributes and the status set on it.
+type recordingSpan struct {
+	trace.Span
+	attrs  map[attribute.Key]attribute.Value
+	status codes.Code
+	ended  bool
+}
+
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, a := range kv {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) {
+	s.status = code
+}
+
+func (s *recordingSpan) RecordError(_ error, _ ...trace.EventOption) {}
+
+func (s *recordingSpan) End(_ ...trace.SpanEndOption) {
+	s.ended = true
+}
+
+type recordingTracer struct {
+	trace.Tracer
+	name string
+	span *recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.name = name
+	t.span = &recordingSpan{Span: trace.SpanFromContext(ctx), attrs: map[attribute.Key]attribute.Value{}}
+	cfg := trace.NewSpanStartConfig(opts...)
+	t.span.SetAttributes(cfg.Attributes()...)
+	return ctx, t.span
+}
+
+func TestCombinedOutput(t *testing.T) {
+	type want struct {
+		exitCode int64
+		status   codes.Code
+	}
+	cases := map[string]struct {
+		reason string
+		err    error
+		want
+	}{
+		"Succeeded": {
+			reason: "The span of a successful command should have a zero exit code",
+		},
+		"Failed": {
+			reason: "The span of a failed command should have its exit code and an error status",
+			err:    testingexec.FakeExitError{Status: 1},
+			want: want{
+				exitCode: 1,
+				status:   codes.Error,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tr := &recordingTracer{}
+			w := NewWorkspace(dir)
+			w.tracer = tr
+			cmd := &testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{
+					func() ([]byte, []byte, error) { return nil, nil, tc.err },
+				},
+			}
+			if _, err := w.combinedOutput(context.TODO(), "plan", cmd); err != tc.err {
+				t.Fatalf("\n%s\ncombinedOutput(...): unexpected error: %v", tc.reason, err)
+			}
+			if diff := cmp.Diff("terraform plan", tr.name); diff != "" {
+				t.Errorf("\n%s\ncombinedOutput(...): -want span name, +got:\n%s", tc.reason, diff)
+			}
+			got := want{exitCode: tr.span.attrs[attrExitCode].AsInt64(), status: tr.span.status}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ncombinedOutput(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff("plan", tr.span.attrs[attrOperation].AsString()); diff != "" {
+				t.Errorf("\n%s\ncombinedOutput(...): -want operation, +got:\n%s", tc.reason, diff)
+			}
+			if !tr.span.ended {
+				t.Errorf("\n%s\ncombinedOutput(...): span should be ended", tc.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+	"github.com/crossplane/terrajet/pkg/types/name"
+)
+
+var (
+	// reAttributeInDetail matches the attribute named in the details of the
+	// diagnostics, e.g. `The argument "location" is required` or
+	// `An argument named "foo" is not expected here`.
+	reAttributeInDetail = regexp.MustCompile(`(?:argument|attribute)(?: named)? "([^"]+)"`)
+	// reAttributeInCode matches the key of the JSON configuration line that
+	// the diagnostic points to, e.g. `"cidr_block": 123,`.
+	reAttributeInCode = regexp.MustCompile(`^\s*"([^"]+)"\s*:`)
+)
+
+// validateOutput is the machine-readable output of "terraform validate".
+type validateOutput struct {
+	Valid       bool                 `json:"valid"`
+	Diagnostics []validateDiagnostic `json:"diagnostics"`
+}
+
+type validateDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Snippet  struct {
+		Code string `json:"code"`
+	} `json:"snippet"`
+}
+
+// Validate makes a blocking terraform validate call that checks the
+// generated configuration without calling the provider API, so that
+// configuration errors surface before a slow apply. The returned error
+// carries the diagnostics with the field paths of the attributes they are
+// about in the managed resource.
+func (w *Workspace) Validate(ctx context.Context) error {
+	if err := w.awaitOperation(ctx, "validate"); err != nil {
+		return err
+	}
+	w.lockExec()
+	defer w.unlockExec()
+	ctx, cancel := w.withCommandTimeout(ctx)
+	defer cancel()
+	cmd := w.executor.CommandContext(ctx, w.terraformPath, w.cli.Validate()...)
+	cmd.SetEnv(w.environ(w.env))
+	cmd.SetDir(w.dir)
+	// NOTE(muvaf): validate exits with a non-zero code if the configuration
+	// is invalid, so the output is parsed before the error is checked.
+	out, err := cmd.Output()
+	w.log("validate").Debug(msgCommandEnded, "out", string(out))
+	res := validateOutput{}
+	if pErr := json.JSParser.Unmarshal(out, &res); pErr != nil {
+		if err != nil {
+			return errors.Wrapf(err, "cannot run terraform validate: %s", string(out))
+		}
+		return errors.Wrap(pErr, "cannot unmarshal terraform validate output")
+	}
+	if res.Valid {
+		return nil
+	}
+	diags := make([]tferrors.ValidationDiagnostic, 0, len(res.Diagnostics))
+	for _, d := range res.Diagnostics {
+		if d.Severity != "error" {
+			continue
+		}
+		diags = append(diags, tferrors.ValidationDiagnostic{
+			Summary: d.Summary,
+			Detail:  d.Detail,
+			Path:    diagnosticPath(d),
+		})
+	}
+	return tferrors.NewValidateFailed(diags, tferrors.WithMaxMessageSize(w.maxErrorMessageSize))
+}
+
+// diagnosticPath returns the field path in the managed resource of the
+// attribute the given diagnostic is about, or an empty string if the
+// attribute cannot be determined.
+func diagnosticPath(d validateDiagnostic) string {
+	attr := ""
+	if m := reAttributeInDetail.FindStringSubmatch(d.Detail); m != nil {
+		attr = m[1]
+	} else if m := reAttributeInCode.FindStringSubmatch(d.Snippet.Code); m != nil {
+		attr = m[1]
+	}
+	if attr == "" {
+		return ""
+	}
+	parts := strings.Split(attr, ".")
+	path := make([]string, 0, len(parts)+2)
+	path = append(path, "spec", "forProvider")
+	for _, p := range parts {
+		path = append(path, name.NewFromSnake(p).LowerCamelComputed)
+	}
+	return strings.Join(path, ".")
+}
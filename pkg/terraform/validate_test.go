@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	k8sExec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+func TestWorkspaceValidate(t *testing.T) {
+	type args struct {
+		out string
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want error
+	}{
+		"Valid": {
+			reason: "No error should be returned if the configuration is valid",
+			args: args{
+				out: `{"format_version":"1.0","valid":true,"error_count":0,"warning_count":0,"diagnostics":[]}`,
+			},
+		},
+		"InvalidArgument": {
+			reason: "The path of the attribute named in the diagnostic details should be reported",
+			args: args{
+				out: `{"valid":false,"error_count":1,"diagnostics":[{"severity":"error","summary":"Unsupported argument","detail":"An argument named \"cidr_blocks\" is not expected here.","snippet":{"context":"resource.aws_vpc.example","code":"        \"cidr_blocks\": \"10.0.0.0/16\","}}]}`,
+				err: errBoom,
+			},
+			want: tferrors.NewValidateFailed([]tferrors.ValidationDiagnostic{
+				{Summary: "Unsupported argument", Detail: `An argument named "cidr_blocks" is not expected here.`, Path: "spec.forProvider.cidrBlocks"},
+			}),
+		},
+		"InvalidValue": {
+			reason: "The path of the attribute in the configuration snippet should be reported and warnings should be skipped",
+			args: args{
+				out: `{"valid":false,"diagnostics":[{"severity":"warning","summary":"Deprecated","detail":"Deprecated attribute"},{"severity":"error","summary":"Incorrect attribute value type","detail":"Inappropriate value for attribute: a number is required.","snippet":{"code":"  \"max_size\": \"many\","}}]}`,
+				err: errBoom,
+			},
+			want: tferrors.NewValidateFailed([]tferrors.ValidationDiagnostic{
+				{Summary: "Incorrect attribute value type", Detail: "Inappropriate value for attribute: a number is required.", Path: "spec.forProvider.maxSize"},
+			}),
+		},
+		"CommandFailed": {
+			reason: "An error should be returned if terraform validate cannot be run",
+			args: args{
+				out: "boom",
+				err: errBoom,
+			},
+			want: errors.Wrapf(errBoom, "cannot run terraform validate: %s", "boom"),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := &testingexec.FakeExec{
+				CommandScript: []testingexec.FakeCommandAction{
+					func(_ string, _ ...string) k8sExec.Cmd {
+						return &testingexec.FakeCmd{
+							OutputScript: []testingexec.FakeAction{
+								func() ([]byte, []byte, error) {
+									return []byte(tc.args.out), nil, tc.args.err
+								},
+							},
+						}
+					},
+				},
+			}
+			err := NewWorkspace(directory, WithExecutor(e)).Validate(context.TODO())
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nValidate(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
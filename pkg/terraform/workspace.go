@@ -17,14 +17,21 @@ limitations under the License.
 package terraform
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/afero"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/util/wait"
 	k8sExec "k8s.io/utils/exec"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
@@ -34,13 +41,54 @@ import (
 )
 
 const (
-	defaultAsyncTimeout = 1 * time.Hour
+	defaultAsyncTimeout  = 1 * time.Hour
+	defaultTerraformPath = "terraform"
+
+	envTFLog     = "TF_LOG"
+	envTFLogPath = "TF_LOG_PATH"
+	traceFile    = "terraform-trace.log"
+	// failedTraceFile keeps the tail of the debug output of the latest
+	// failed apply operation of the workspace.
+	failedTraceFile = "terraform-trace-failed.log"
+
+	// DefaultMaxErrorMessageSize is the default maximum size of the messages
+	// of the errors of the Terraform operations in bytes.
+	DefaultMaxErrorMessageSize = 4096
+
+	// savedPlanFile is the file the plans are saved to so that the next
+	// apply operation applies exactly the planned changes.
+	savedPlanFile = "terrajet.tfplan"
+
+	errResourceStillExists = "resource still exists after destroy operation reported success"
+
+	// The keys the log lines of the workspaces are tagged with.
+	logKeyOperation = "operation"
+	logKeyWorkspace = "workspace"
+	logKeyName      = "name"
+	logKeyUID       = "uid"
+	logKeyGVK       = "gvk"
+
+	msgCommandEnded = "terraform command ended"
 )
 
+// DefaultInitBackoff is the default backoff "terraform init" is retried with
+// when it fails due to a transient registry or network failure. It allows
+// three retries in about 14 seconds in total.
+var DefaultInitBackoff = wait.Backoff{
+	Duration: 2 * time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    3,
+	Cap:      10 * time.Second,
+}
+
 // WorkspaceOption allows you to configure Workspace objects.
 type WorkspaceOption func(*Workspace)
 
-// WithLogger sets the logger of Workspace.
+// WithLogger sets the logger of Workspace. Every log line of the Workspace is
+// tagged with the operation it belongs to in addition to the values of the
+// given logger, which WorkspaceStore tags with the identity of the resource
+// and the workspace directory.
 func WithLogger(l logging.Logger) WorkspaceOption {
 	return func(w *Workspace) {
 		w.logger = l
@@ -61,6 +109,159 @@ func WithLastOperation(lo *Operation) WorkspaceOption {
 	}
 }
 
+// WithCommandBuilder sets the CommandBuilder that is used to build the
+// arguments of Terraform CLI commands run in the Workspace.
+func WithCommandBuilder(cb *CommandBuilder) WorkspaceOption {
+	return func(w *Workspace) {
+		w.cli = cb
+	}
+}
+
+// WithTerraformPath sets the path of the Terraform CLI binary that is run in
+// the Workspace.
+func WithTerraformPath(path string) WorkspaceOption {
+	return func(w *Workspace) {
+		w.terraformPath = path
+	}
+}
+
+// WithDestroyVerification makes the Workspace refresh the state it had before
+// a successful destroy operation to verify that the resource is really gone.
+// The destroy operation fails and the resource stays in the state if it is
+// still found.
+func WithDestroyVerification(verify bool) WorkspaceOption {
+	return func(w *Workspace) {
+		w.verifyDestroy = verify
+	}
+}
+
+// WithDestroyLock makes the Workspace hold the given lock while running
+// destroy operations so that they are serialized with the destroy operations
+// of the other workspaces sharing the same lock.
+func WithDestroyLock(l sync.Locker) WorkspaceOption {
+	return func(w *Workspace) {
+		w.destroyLock = l
+	}
+}
+
+// WithReplace makes the next apply operations of the Workspace replace the
+// resource with the given address, e.g. "aws_vpc.example", even if it has
+// no changes. An empty address disables the replacement.
+func WithReplace(address string) WorkspaceOption {
+	return func(w *Workspace) {
+		w.replace = address
+	}
+}
+
+// WithResourceAddress sets the address of the resource in the configuration of
+// the Workspace, e.g. "aws_vpc.example", which the import operations import
+// the existing resources into.
+func WithResourceAddress(address string) WorkspaceOption {
+	return func(w *Workspace) {
+		w.address = address
+	}
+}
+
+// WithParallelism sets the number of concurrent operations Terraform walks
+// the resource graph with in apply and destroy operations, i.e. the
+// -parallelism flag. Terraform's default is used if it is not positive.
+func WithParallelism(n int) WorkspaceOption {
+	return func(w *Workspace) {
+		w.parallelism = n
+	}
+}
+
+// WithMaxErrorMessageSize sets the maximum size of the messages of the errors
+// returned by the Terraform operations in bytes. Longer messages are
+// truncated and the full output of the operation is stored in the workspace
+// directory. Messages are not truncated if it is not positive.
+func WithMaxErrorMessageSize(n int) WorkspaceOption {
+	return func(w *Workspace) {
+		w.maxErrorMessageSize = n
+	}
+}
+
+// WithInitBackoff sets the backoff "terraform init" is retried with when it
+// fails due to a transient registry or network failure. The number of steps
+// of the backoff bounds the number of retries and init is not retried if it
+// is zero. Failures due to configuration errors are never retried.
+func WithInitBackoff(b wait.Backoff) WorkspaceOption {
+	return func(w *Workspace) {
+		w.initBackoff = b
+	}
+}
+
+// WithTraceCapture makes the Workspace run apply operations with TF_LOG=debug
+// and keep the last n bytes of the debug output of the failed ones in a file
+// in the workspace directory, which gives provider-level diagnostics, like
+// the HTTP requests and the retries, for errors that are hard to reproduce.
+// The debug output may contain credentials and sensitive attributes, so it
+// never leaves the file system of the controller; the OperationResult only
+// points to the file. Traces are not captured if n is not positive.
+func WithTraceCapture(n int) WorkspaceOption {
+	return func(w *Workspace) {
+		w.traceLimit = n
+	}
+}
+
+// WithInheritedEnv makes the Terraform processes of the Workspace inherit only
+// the given variables of the environment of the controller, e.g. PATH and
+// HOME, instead of all of them so that the credentials the provider needs
+// are passed explicitly through the Setup and the credentials of the
+// controller itself are never used by accident.
+func WithInheritedEnv(keys ...string) WorkspaceOption {
+	return func(w *Workspace) {
+		allowed := make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			allowed[k] = struct{}{}
+		}
+		w.inheritEnv = func(key string) bool {
+			_, ok := allowed[key]
+			return ok
+		}
+	}
+}
+
+// WithCommandTimeout bounds the duration of the blocking Terraform operations
+// run in the Workspace. They are cancelled when their context is cancelled or
+// the timeout passes, whichever comes first. They are not bounded by the
+// Workspace if it is not positive. See WithAsyncTimeout for the async
+// operations.
+func WithCommandTimeout(d time.Duration) WorkspaceOption {
+	return func(w *Workspace) {
+		w.commandTimeout = d
+	}
+}
+
+// WithAsyncTimeout sets the duration after which the async Terraform
+// operations run in the Workspace are cancelled. Defaults to an hour if it is
+// not positive.
+func WithAsyncTimeout(d time.Duration) WorkspaceOption {
+	return func(w *Workspace) {
+		w.asyncOperationTimeout = d
+	}
+}
+
+// WithRunner makes the Workspace run its Terraform operations with the given
+// Runner, e.g. a WorkerPool shared by all workspaces, instead of running
+// them right away. Blocking operations wait for the Runner to run them.
+func WithRunner(r Runner) WorkspaceOption {
+	return func(w *Workspace) {
+		w.runner = r
+	}
+}
+
+// WithOperationWait makes the blocking operations of the Workspace that are
+// requested while an async operation is running wait for it to end for up to
+// the given duration instead of failing right away. The waiting operations
+// are reported by Pending and run one at a time once the async operation
+// ends. They fail as before if it does not end in time.
+func WithOperationWait(d time.Duration) WorkspaceOption {
+	return func(w *Workspace) {
+		w.operationWait = d
+	}
+}
+
 // WithAferoFs lets you set the fs of WorkspaceStore.
 func WithAferoFs(fs afero.Fs) WorkspaceOption {
 	return func(ws *Workspace) {
@@ -72,10 +273,14 @@ func WithAferoFs(fs afero.Fs) WorkspaceOption {
 // directory.
 func NewWorkspace(dir string, opts ...WorkspaceOption) *Workspace {
 	w := &Workspace{
-		LastOperation: &Operation{},
-		dir:           dir,
-		logger:        logging.NewNopLogger(),
-		fs:            afero.Afero{Fs: afero.NewOsFs()},
+		LastOperation:       &Operation{},
+		dir:                 dir,
+		terraformPath:       defaultTerraformPath,
+		maxErrorMessageSize: DefaultMaxErrorMessageSize,
+		initBackoff:         DefaultInitBackoff,
+		logger:              logging.NewNopLogger(),
+		tracer:              trace.NewNoopTracerProvider().Tracer(TracerName),
+		fs:                  afero.Afero{Fs: afero.NewOsFs()},
 	}
 	for _, f := range opts {
 		f(w)
@@ -95,10 +300,112 @@ type Workspace struct {
 
 	dir string
 	env []string
+	// inheritEnv selects the variables of the environment of the controller
+	// that are passed to the Terraform processes. All of them are passed if
+	// it is nil.
+	inheritEnv    func(key string) bool
+	terraformPath string
+	verifyDestroy bool
+	// execLock is held while a Terraform process runs in the workspace
+	// directory so that overlapping reconciliations, or a plan racing an
+	// async apply, never run two processes in the same directory at once.
+	execLock sync.Mutex
+	// execWaiters is the number of the operations that hold or wait for
+	// execLock. The workspaces that have any are never evicted.
+	execWaiters int32
+	// configHash is the hash of the Terraform configuration of the resource
+	// the workspace was last prepared with.
+	configHash string
+	// destroyLock is held during destroy operations if it is set.
+	destroyLock         sync.Locker
+	maxErrorMessageSize int
+	initBackoff         wait.Backoff
+	traceLimit          int
+	// address is the address of the resource in the configuration.
+	address string
+	// replace is the address of the resource the apply operations replace.
+	replace string
+	// parallelism is the number of concurrent operations Terraform walks the
+	// graph with in apply and destroy operations. Terraform's default is
+	// used if it is zero.
+	parallelism int
+	// commandTimeout bounds the duration of the blocking operations if it
+	// is positive.
+	commandTimeout time.Duration
+	// asyncOperationTimeout bounds the duration of the async operations. The
+	// default is used if it is not positive.
+	asyncOperationTimeout time.Duration
+	// runner runs the operations. They are run right away if it is nil.
+	runner Runner
+	// driftInterval is how long the result of a refresh is reused.
+	driftInterval time.Duration
+	refreshCache  *refreshCache
+	// operationWait is how long the blocking operations wait for a running
+	// async operation to end.
+	operationWait time.Duration
+	// pending are the types of the blocking operations waiting for the
+	// running async operation to end, in the order they were requested.
+	pending   []string
+	pendingMu sync.Mutex
+	// savedPlanKey identifies the configuration and the state the saved plan
+	// was made for. It's empty if there is no saved plan to apply.
+	savedPlanKey string
+
+	// observed is the state attributes read after the latest refresh or
+	// apply.
+	observed []byte
+	// applied is the state attributes read after the latest successful apply,
+	// or after the first refresh if the workspace hasn't applied yet. They
+	// are used to tell the external changes apart from the spec changes when
+	// explaining a drift. It's guarded by execLock.
+	applied []byte
+	// lastUsed is the last time the workspace was requested from the store.
+	lastUsed time.Time
+	// failures is the number of the Terraform CLI commands that have failed
+	// in a row in the workspace.
+	failures int32
+	// failureCounter counts the failed operations by their class if it is
+	// set.
+	failureCounter *prometheus.CounterVec
 
 	logger   logging.Logger
 	executor k8sExec.Interface
+	cli      *CommandBuilder
 	fs       afero.Afero
+	tracer   trace.Tracer
+}
+
+// Init runs "terraform init" in the Workspace. If pluginDir is given,
+// providers are installed only from that directory. Failures due to transient
+// registry or network errors are retried with the init backoff of the
+// Workspace.
+func (w *Workspace) Init(ctx context.Context, pluginDir string) error {
+	b := w.initBackoff
+	w.lockExec()
+	defer w.unlockExec()
+	ctx, cancel := w.withCommandTimeout(ctx)
+	defer cancel()
+	for {
+		cmd := w.executor.CommandContext(ctx, w.terraformPath, w.cli.Init(pluginDir)...)
+		cmd.SetEnv(w.environ(w.env))
+		cmd.SetDir(w.dir)
+		out, err := w.combinedOutput(ctx, "init", cmd)
+		w.log("init").Debug(msgCommandEnded, "out", string(out))
+		if err == nil {
+			return nil
+		}
+		err = w.operationFailed("init", tferrors.NewInitFailed(out, w.errorOptions("init", out)...))
+		if !tferrors.IsTransientInitFailed(err) || b.Steps < 1 {
+			return err
+		}
+		d := b.Step()
+		w.log("init").Debug("retrying init after a transient failure", "backoff", d.String())
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(d):
+		}
+	}
 }
 
 // ApplyAsync makes a terraform apply call without blocking and calls the given
@@ -107,55 +414,309 @@ func (w *Workspace) ApplyAsync(callback CallbackFn) error {
 	if w.LastOperation.IsRunning() {
 		return errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
 	}
-	w.LastOperation.MarkStart("apply")
-	ctx, cancel := context.WithDeadline(context.TODO(), w.LastOperation.StartTime().Add(defaultAsyncTimeout))
-	go func() {
+	w.LastOperation.MarkQueued("apply")
+	// NOTE(muvaf): The arguments are built before the goroutine starts since
+	// the store may change them for the next reconciliation.
+	args, hash := w.applyArgs(), w.configHash
+	w.runAsync(PriorityApply, func() {
+		w.lockExec()
+		defer w.unlockExec()
+		// NOTE(muvaf): The operation may wait in the queue of the runner
+		// and for the running operations of the workspace, so it's marked
+		// as running and its deadline starts only now.
+		w.LastOperation.MarkRunning()
+		ctx, cancel := context.WithDeadline(context.TODO(), w.LastOperation.StartTime().Add(w.asyncTimeout()))
 		defer cancel()
-		cmd := w.executor.CommandContext(ctx, "terraform", "apply", "-auto-approve", "-input=false", "-lock=false", "-json")
-		cmd.SetEnv(append(os.Environ(), w.env...))
+		start := time.Now()
+		cmd := w.executor.CommandContext(ctx, w.terraformPath, args...)
+		cmd.SetEnv(w.environ(w.applyEnv()))
 		cmd.SetDir(w.dir)
-		out, err := cmd.CombinedOutput()
+		out, err := w.combinedOutput(ctx, "apply", cmd)
 		w.LastOperation.MarkEnd()
-		w.logger.Debug("apply async ended", "out", string(out))
+		w.log("apply").Debug(msgCommandEnded, "async", true, "out", string(out))
+		opRes := newOperationResult("apply", start, out, err)
+		opRes.TracePath = w.trace(err)
+		cbCtx := ContextWithConfigurationHash(ContextWithOperationResult(ctx, opRes), hash)
 		defer func() {
-			if cErr := callback(err, ctx); cErr != nil {
-				w.logger.Info("callback failed", "error", cErr.Error())
+			if cErr := callback(err, cbCtx); cErr != nil {
+				w.log("apply").Info("callback failed", "error", cErr.Error())
 			}
 		}()
 		if err != nil {
-			err = tferrors.NewApplyFailed(out)
+			err = w.operationFailed("apply", tferrors.NewApplyFailed(out, w.errorOptions("apply", out)...))
+			return
 		}
-	}()
+		st, sErr := w.readState()
+		if sErr != nil {
+			w.log("apply").Info("cannot read state after async apply", "error", sErr.Error())
+			return
+		}
+		w.applied = st.GetAttributes()
+		cbCtx = ContextWithState(cbCtx, st)
+	})
 	return nil
 }
 
 // ApplyResult contains the state after the apply operation.
 type ApplyResult struct {
 	State *json.StateV4
+	// Operation is the summary of the Terraform CLI command run. It is
+	// populated even if the operation fails.
+	Operation OperationResult
 }
 
 // Apply makes a blocking terraform apply call.
 func (w *Workspace) Apply(ctx context.Context) (ApplyResult, error) {
+	if err := w.awaitOperation(ctx, "apply"); err != nil {
+		return ApplyResult{}, err
+	}
+	var res ApplyResult
+	var err error
+	if rErr := w.runSync(ctx, PriorityApply, func() { res, err = w.apply(ctx) }); rErr != nil {
+		return ApplyResult{}, rErr
+	}
+	return res, err
+}
+
+func (w *Workspace) apply(ctx context.Context) (ApplyResult, error) {
 	if w.LastOperation.IsRunning() {
 		return ApplyResult{}, errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
 	}
-	cmd := w.executor.CommandContext(ctx, "terraform", "apply", "-auto-approve", "-input=false", "-lock=false", "-json")
-	cmd.SetEnv(append(os.Environ(), w.env...))
+	w.lockExec()
+	defer w.unlockExec()
+	ctx, cancel := w.withCommandTimeout(ctx)
+	defer cancel()
+	start := time.Now()
+	cmd := w.executor.CommandContext(ctx, w.terraformPath, w.applyArgs()...)
+	cmd.SetEnv(w.environ(w.applyEnv()))
 	cmd.SetDir(w.dir)
-	out, err := cmd.CombinedOutput()
-	w.logger.Debug("apply ended", "out", string(out))
+	out, err := w.combinedOutput(ctx, "apply", cmd)
+	w.log("apply").Debug(msgCommandEnded, "out", string(out))
+	res := ApplyResult{Operation: newOperationResult("apply", start, out, err)}
+	res.Operation.TracePath = w.trace(err)
 	if err != nil {
-		return ApplyResult{}, tferrors.NewApplyFailed(out)
+		return res, w.operationFailed("apply", tferrors.NewApplyFailed(out, w.errorOptions("apply", out)...))
 	}
-	raw, err := w.fs.ReadFile(filepath.Join(w.dir, "terraform.tfstate"))
+	s, err := w.readState()
 	if err != nil {
-		return ApplyResult{}, errors.Wrap(err, "cannot read terraform state file")
+		return res, err
+	}
+	w.observed = s.GetAttributes()
+	w.applied = w.observed
+	res.State = s
+	return res, nil
+}
+
+// environ returns the environment of the Terraform processes, which is the
+// inherited environment of the controller followed by the given variables
+// so that the latter take precedence.
+func (w *Workspace) environ(env []string) []string {
+	base := os.Environ()
+	if w.inheritEnv != nil {
+		inherited := make([]string, 0, len(base))
+		for _, e := range base {
+			if w.inheritEnv(strings.SplitN(e, "=", 2)[0]) {
+				inherited = append(inherited, e)
+			}
+		}
+		base = inherited
+	}
+	return append(base, env...)
+}
+
+// withCommandTimeout returns a copy of the given context that is cancelled
+// when the command timeout of the Workspace passes, if it is set.
+func (w *Workspace) withCommandTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if w.commandTimeout <= 0 {
+		return ctx, func() {}
 	}
-	s := &json.StateV4{}
-	if err := json.JSParser.Unmarshal(raw, s); err != nil {
-		return ApplyResult{}, errors.Wrap(err, "cannot unmarshal tfstate file")
+	return context.WithTimeout(ctx, w.commandTimeout)
+}
+
+// asyncTimeout returns the duration after which the async operations are
+// cancelled.
+func (w *Workspace) asyncTimeout() time.Duration {
+	if w.asyncOperationTimeout <= 0 {
+		return defaultAsyncTimeout
 	}
-	return ApplyResult{State: s}, nil
+	return w.asyncOperationTimeout
+}
+
+// Pending returns the types of the operations that are waiting for the running
+// async operation, whose type is reported by the LastOperation, to end, in
+// the order they were requested.
+func (w *Workspace) Pending() []string {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+	return append([]string(nil), w.pending...)
+}
+
+// awaitOperation waits for the running async operation, if any, to end
+// within the operation wait of the Workspace. The operation of the given
+// type is reported as pending in the meantime. An error is returned if the
+// async operation is still running after that.
+func (w *Workspace) awaitOperation(ctx context.Context, op string) error {
+	if !w.LastOperation.IsRunning() {
+		return nil
+	}
+	err := errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
+	if w.operationWait <= 0 {
+		return err
+	}
+	w.pendingMu.Lock()
+	w.pending = append(w.pending, op)
+	w.pendingMu.Unlock()
+	defer func() {
+		w.pendingMu.Lock()
+		defer w.pendingMu.Unlock()
+		for i, p := range w.pending {
+			if p == op {
+				w.pending = append(w.pending[:i], w.pending[i+1:]...)
+				break
+			}
+		}
+	}()
+	ctx, cancel := context.WithTimeout(ctx, w.operationWait)
+	defer cancel()
+	if w.LastOperation.Wait(ctx) != nil {
+		return err
+	}
+	return nil
+}
+
+// applyArgs returns the arguments of the apply operations. The saved plan is
+// applied if the configuration and the state haven't changed since it was
+// made, which saves a plan and guarantees that the applied changes are the
+// planned ones. A saved plan is applied at most once.
+func (w *Workspace) applyArgs() []string {
+	if w.replace != "" {
+		w.savedPlanKey = ""
+		return w.withParallelism(w.cli.Apply(w.replace))
+	}
+	if w.consumeSavedPlan() {
+		return append(w.withParallelism(w.cli.Apply()), savedPlanFile)
+	}
+	return w.withParallelism(w.cli.Apply())
+}
+
+// consumeSavedPlan returns whether the saved plan is still valid and clears
+// it.
+func (w *Workspace) consumeSavedPlan() bool {
+	saved := w.savedPlanKey
+	w.savedPlanKey = ""
+	if saved == "" {
+		return false
+	}
+	key, err := w.refreshKey()
+	return err == nil && key == saved
+}
+
+// destroyArgs returns the arguments of the destroy operations.
+func (w *Workspace) destroyArgs() []string {
+	return w.withParallelism(w.cli.Destroy())
+}
+
+func (w *Workspace) withParallelism(args []string) []string {
+	if w.parallelism <= 0 {
+		return args
+	}
+	return append(args, fmt.Sprintf("-parallelism=%d", w.parallelism))
+}
+
+// applyEnv returns the environment of the apply operations, which makes
+// Terraform write its debug output to the trace file if traces are captured.
+func (w *Workspace) applyEnv() []string {
+	if w.traceLimit <= 0 {
+		return w.env
+	}
+	env := make([]string, 0, len(w.env)+2)
+	env = append(env, w.env...)
+	return append(env, fmt.Sprintf(fmtEnv, envTFLog, "debug"), fmt.Sprintf(fmtEnv, envTFLogPath, filepath.Join(w.dir, traceFile)))
+}
+
+// log returns the logger of the Workspace tagged with the given operation.
+func (w *Workspace) log(op string) logging.Logger {
+	return w.logger.WithValues(logKeyOperation, op)
+}
+
+// trace keeps the last bytes of the debug output of a failed apply operation,
+// up to the trace limit, in the failed trace file and returns its path. It
+// removes the trace file so that the output of the next operation isn't
+// appended to it.
+func (w *Workspace) trace(err error) string {
+	if w.traceLimit <= 0 {
+		return ""
+	}
+	p := filepath.Join(w.dir, traceFile)
+	defer w.fs.Remove(p) // nolint:errcheck
+	if err == nil {
+		return ""
+	}
+	raw, rErr := w.fs.ReadFile(p)
+	if rErr != nil {
+		w.log("apply").Debug("cannot read the trace of the failed operation", "error", rErr.Error())
+		return ""
+	}
+	if len(raw) > w.traceLimit {
+		raw = raw[len(raw)-w.traceLimit:]
+	}
+	fp := filepath.Join(w.dir, failedTraceFile)
+	if wErr := writeFileAtomic(w.fs, fp, raw, 0600); wErr != nil {
+		w.log("apply").Debug("cannot keep the trace of the failed operation", "error", wErr.Error())
+		return ""
+	}
+	return fp
+}
+
+func (w *Workspace) readState() (*json.StateV4, error) {
+	f, err := w.fs.Open(filepath.Join(w.dir, "terraform.tfstate"))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read terraform state file")
+	}
+	defer f.Close() // nolint:errcheck
+	s, err := json.ReadStateV4(f)
+	return s, errors.Wrap(err, "cannot unmarshal tfstate file")
+}
+
+type stateKey struct{}
+
+// ContextWithState returns a copy of the given context that carries the given
+// state to be read by StateFromContext.
+func ContextWithState(ctx context.Context, s *json.StateV4) context.Context {
+	return context.WithValue(ctx, stateKey{}, s)
+}
+
+// StateFromContext returns the Terraform state produced by a successful async
+// apply operation from the context passed to its callback. It returns nil if
+// the operation failed or the state could not be read.
+func StateFromContext(ctx context.Context) *json.StateV4 {
+	s, _ := ctx.Value(stateKey{}).(*json.StateV4)
+	return s
+}
+
+type configHashKey struct{}
+
+// ContextWithConfigurationHash returns a copy of the given context that
+// carries the given configuration hash to be read by
+// ConfigurationHashFromContext.
+func ContextWithConfigurationHash(ctx context.Context, hash string) context.Context {
+	return context.WithValue(ctx, configHashKey{}, hash)
+}
+
+// ConfigurationHashFromContext returns the hash of the Terraform configuration
+// an async apply operation ran with from the context passed to its callback.
+// It returns an empty string if the hash is not known.
+func ConfigurationHashFromContext(ctx context.Context) string {
+	h, _ := ctx.Value(configHashKey{}).(string)
+	return h
+}
+
+// ConfigurationHash returns the hash of the Terraform configuration of the
+// resource, including its resolved sensitive parameters and the provider
+// configuration, that the workspace was last prepared with. It's empty if
+// the workspace doesn't run a resource.
+func (w *Workspace) ConfigurationHash() string {
+	return w.configHash
 }
 
 // DestroyAsync makes a non-blocking terraform destroy call. It doesn't accept
@@ -172,40 +733,157 @@ func (w *Workspace) DestroyAsync(callback CallbackFn) error {
 	case w.LastOperation.IsRunning():
 		return errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
 	}
-	w.LastOperation.MarkStart("destroy")
-	ctx, cancel := context.WithDeadline(context.TODO(), w.LastOperation.StartTime().Add(defaultAsyncTimeout))
-	go func() {
+	preDestroy, err := w.preDestroyState()
+	if err != nil {
+		return err
+	}
+	w.LastOperation.MarkQueued("destroy")
+	l := w.destroyLock
+	args := w.destroyArgs()
+	w.runAsync(PriorityDestroy, func() {
+		w.lockExec()
+		unlock := lock(l)
+		w.LastOperation.MarkRunning()
+		ctx, cancel := context.WithDeadline(context.TODO(), w.LastOperation.StartTime().Add(w.asyncTimeout()))
 		defer cancel()
-		cmd := w.executor.CommandContext(ctx, "terraform", "destroy", "-auto-approve", "-input=false", "-lock=false", "-json")
-		cmd.SetEnv(append(os.Environ(), w.env...))
+		start := time.Now()
+		cmd := w.executor.CommandContext(ctx, w.terraformPath, args...)
+		cmd.SetEnv(w.environ(w.env))
 		cmd.SetDir(w.dir)
-		out, err := cmd.CombinedOutput()
+		out, err := w.combinedOutput(ctx, "destroy", cmd)
+		w.log("destroy").Debug(msgCommandEnded, "async", true, "out", string(out))
+		cbCtx := ContextWithOperationResult(ctx, newOperationResult("destroy", start, out, err))
+		var vErr error
+		if err == nil && w.verifyDestroy {
+			vErr = w.verifyDestroyed(ctx, preDestroy)
+		}
+		unlock()
+		w.unlockExec()
 		w.LastOperation.MarkEnd()
-		w.logger.Debug("destroy async ended", "out", string(out))
 		defer func() {
-			if cErr := callback(err, ctx); cErr != nil {
-				w.logger.Info("callback failed", "error", cErr.Error())
+			if cErr := callback(err, cbCtx); cErr != nil {
+				w.log("destroy").Info("callback failed", "error", cErr.Error())
 			}
 		}()
-		if err != nil {
-			err = tferrors.NewDestroyFailed(out)
+		switch {
+		case err != nil:
+			err = w.operationFailed("destroy", tferrors.NewDestroyFailed(out, w.errorOptions("destroy", out)...))
+		case vErr != nil:
+			err = vErr
 		}
-	}()
+	})
 	return nil
 }
 
+// DestroyResult contains the summary of the destroy operation.
+type DestroyResult struct {
+	// Operation is the summary of the Terraform CLI command run. It is
+	// populated even if the operation fails.
+	Operation OperationResult
+}
+
 // Destroy makes a blocking terraform destroy call.
-func (w *Workspace) Destroy(ctx context.Context) error {
+func (w *Workspace) Destroy(ctx context.Context) (DestroyResult, error) {
+	if err := w.awaitOperation(ctx, "destroy"); err != nil {
+		return DestroyResult{}, err
+	}
+	var res DestroyResult
+	var err error
+	if rErr := w.runSync(ctx, PriorityDestroy, func() { res, err = w.destroy(ctx) }); rErr != nil {
+		return DestroyResult{}, rErr
+	}
+	return res, err
+}
+
+func (w *Workspace) destroy(ctx context.Context) (DestroyResult, error) {
 	if w.LastOperation.IsRunning() {
-		return errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
+		return DestroyResult{}, errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
+	}
+	w.lockExec()
+	defer w.unlockExec()
+	ctx, cancel := w.withCommandTimeout(ctx)
+	defer cancel()
+	preDestroy, err := w.preDestroyState()
+	if err != nil {
+		return DestroyResult{}, err
+	}
+	defer lock(w.destroyLock)()
+	start := time.Now()
+	cmd := w.executor.CommandContext(ctx, w.terraformPath, w.destroyArgs()...)
+	cmd.SetEnv(w.environ(w.env))
+	cmd.SetDir(w.dir)
+	out, err := w.combinedOutput(ctx, "destroy", cmd)
+	w.log("destroy").Debug(msgCommandEnded, "out", string(out))
+	res := DestroyResult{Operation: newOperationResult("destroy", start, out, err)}
+	if err != nil {
+		return res, w.operationFailed("destroy", tferrors.NewDestroyFailed(out, w.errorOptions("destroy", out)...))
+	}
+	if w.verifyDestroy {
+		return res, w.verifyDestroyed(ctx, preDestroy)
+	}
+	return res, nil
+}
+
+// errorOptions returns the options of the error of the given failed
+// operation. If the output of the operation is large enough to be
+// truncated, it is stored in the workspace directory so that the truncated
+// error message can point to it.
+func (w *Workspace) errorOptions(op string, out []byte) []tferrors.ErrorOption {
+	opts := []tferrors.ErrorOption{tferrors.WithMaxMessageSize(w.maxErrorMessageSize)}
+	if w.maxErrorMessageSize <= 0 || len(out) <= w.maxErrorMessageSize {
+		return opts
+	}
+	p := filepath.Join(w.dir, op+".log")
+	if err := w.fs.WriteFile(p, out, 0600); err != nil {
+		w.log(op).Debug("cannot store the output of the failed operation", "error", err.Error())
+		return opts
 	}
-	cmd := w.executor.CommandContext(ctx, "terraform", "destroy", "-auto-approve", "-input=false", "-lock=false", "-json")
-	cmd.SetEnv(append(os.Environ(), w.env...))
+	return append(opts, tferrors.WithLogPath(p))
+}
+
+// lock acquires the given lock if it is set and returns the function that
+// releases it.
+func lock(l sync.Locker) func() {
+	if l == nil {
+		return func() {}
+	}
+	l.Lock()
+	return l.Unlock
+}
+
+// preDestroyState returns the content of the state file if the destroy
+// operations need to be verified.
+func (w *Workspace) preDestroyState() ([]byte, error) {
+	if !w.verifyDestroy {
+		return nil, nil
+	}
+	raw, err := w.fs.ReadFile(filepath.Join(w.dir, "terraform.tfstate"))
+	return raw, errors.Wrap(err, "cannot read terraform state file")
+}
+
+// verifyDestroyed restores the given state that the workspace had before the
+// destroy operation and refreshes it. Terraform drops the resource from the
+// state during refresh if it does not exist anymore. Otherwise, the restored
+// state is kept so that the resource is not orphaned.
+func (w *Workspace) verifyDestroyed(ctx context.Context, preDestroy []byte) error {
+	p := filepath.Join(w.dir, "terraform.tfstate")
+	if err := writeFileAtomic(w.fs, p, preDestroy, 0600); err != nil {
+		return errors.Wrap(err, "cannot restore terraform state file")
+	}
+	cmd := w.executor.CommandContext(ctx, w.terraformPath, w.cli.Refresh()...)
+	cmd.SetEnv(w.environ(w.env))
 	cmd.SetDir(w.dir)
-	out, err := cmd.CombinedOutput()
-	w.logger.Debug("destroy ended", "out", string(out))
+	out, err := w.combinedOutput(ctx, "refresh", cmd)
+	w.log("destroy").Debug(msgCommandEnded, "verification", true, "out", string(out))
 	if err != nil {
-		return tferrors.NewDestroyFailed(out)
+		return w.operationFailed("refresh", tferrors.NewRefreshFailed(out, w.errorOptions("refresh", out)...))
+	}
+	s, err := w.readState()
+	if err != nil {
+		return err
+	}
+	if s.GetAttributes() != nil {
+		return errors.New(errResourceStillExists)
 	}
 	return nil
 }
@@ -216,6 +894,12 @@ type RefreshResult struct {
 	IsApplying   bool
 	IsDestroying bool
 	State        *json.StateV4
+	// Cached is true if the result of an earlier refresh within the drift
+	// detection interval is returned without running Terraform.
+	Cached bool
+	// Changed is true if the refresh has changed the state attributes of the
+	// resource since they were last observed by the Workspace.
+	Changed bool
 }
 
 // Refresh makes a blocking terraform apply -refresh-only call where only the state file
@@ -230,26 +914,45 @@ func (w *Workspace) Refresh(ctx context.Context) (RefreshResult, error) {
 	case w.LastOperation.IsEnded():
 		defer w.LastOperation.Flush()
 	}
-	cmd := w.executor.CommandContext(ctx, "terraform", "apply", "-refresh-only", "-auto-approve", "-input=false", "-lock=false", "-json")
-	cmd.SetEnv(append(os.Environ(), w.env...))
+	if res, ok := w.cachedRefresh(); ok {
+		return res, nil
+	}
+	var res RefreshResult
+	var err error
+	if rErr := w.runSync(ctx, PriorityPlan, func() { res, err = w.refresh(ctx) }); rErr != nil {
+		return RefreshResult{}, rErr
+	}
+	return res, err
+}
+
+func (w *Workspace) refresh(ctx context.Context) (RefreshResult, error) {
+	w.lockExec()
+	defer w.unlockExec()
+	ctx, cancel := w.withCommandTimeout(ctx)
+	defer cancel()
+	cmd := w.executor.CommandContext(ctx, w.terraformPath, w.cli.Refresh()...)
+	cmd.SetEnv(w.environ(w.env))
 	cmd.SetDir(w.dir)
-	out, err := cmd.CombinedOutput()
-	w.logger.Debug("refresh ended", "out", string(out))
+	out, err := w.combinedOutput(ctx, "refresh", cmd)
+	w.log("refresh").Debug(msgCommandEnded, "out", string(out))
 	if err != nil {
-		return RefreshResult{}, tferrors.NewRefreshFailed(out)
+		return RefreshResult{}, w.operationFailed("refresh", tferrors.NewRefreshFailed(out, w.errorOptions("refresh", out)...))
 	}
-	raw, err := w.fs.ReadFile(filepath.Join(w.dir, "terraform.tfstate"))
+	s, err := w.readState()
 	if err != nil {
-		return RefreshResult{}, errors.Wrap(err, "cannot read terraform state file")
+		return RefreshResult{}, err
+	}
+	res := RefreshResult{
+		Exists:  s.GetAttributes() != nil,
+		State:   s,
+		Changed: w.observed == nil || !bytes.Equal(w.observed, s.GetAttributes()),
 	}
-	s := &json.StateV4{}
-	if err := json.JSParser.Unmarshal(raw, s); err != nil {
-		return RefreshResult{}, errors.Wrap(err, "cannot unmarshal tfstate file")
+	w.observed = s.GetAttributes()
+	if w.applied == nil {
+		w.applied = w.observed
 	}
-	return RefreshResult{
-		Exists: s.GetAttributes() != nil,
-		State:  s,
-	}, nil
+	w.cacheRefresh(res)
+	return res, nil
 }
 
 // PlanResult returns a summary of comparison between desired and current state
@@ -257,21 +960,58 @@ func (w *Workspace) Refresh(ctx context.Context) (RefreshResult, error) {
 type PlanResult struct {
 	Exists   bool
 	UpToDate bool
+	// Changes is the number of changes the plan would make.
+	Changes PlanChanges
+}
+
+// PlanChanges is the number of changes of each kind a plan would make.
+type PlanChanges struct {
+	Add     int
+	Change  int
+	Destroy int
+}
+
+// HasChanges returns whether the plan would make any change.
+func (c PlanChanges) HasChanges() bool {
+	return c.Add+c.Change+c.Destroy != 0
+}
+
+// String returns a human-readable summary of the changes in the format
+// Terraform uses, e.g. "plan: 0 to add, 2 to change, 1 to destroy".
+func (c PlanChanges) String() string {
+	return fmt.Sprintf("plan: %d to add, %d to change, %d to destroy", c.Add, c.Change, c.Destroy)
 }
 
 // Plan makes a blocking terraform plan call.
 func (w *Workspace) Plan(ctx context.Context) (PlanResult, error) {
+	if err := w.awaitOperation(ctx, "plan"); err != nil {
+		return PlanResult{}, err
+	}
+	var res PlanResult
+	var err error
+	if rErr := w.runSync(ctx, PriorityPlan, func() { res, err = w.plan(ctx) }); rErr != nil {
+		return PlanResult{}, rErr
+	}
+	return res, err
+}
+
+func (w *Workspace) plan(ctx context.Context) (PlanResult, error) {
 	// The last operation is still ongoing.
 	if w.LastOperation.IsRunning() {
 		return PlanResult{}, errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
 	}
-	cmd := w.executor.CommandContext(ctx, "terraform", "plan", "-refresh=false", "-input=false", "-lock=false", "-json")
-	cmd.SetEnv(append(os.Environ(), w.env...))
+	w.lockExec()
+	defer w.unlockExec()
+	ctx, cancel := w.withCommandTimeout(ctx)
+	defer cancel()
+	w.savedPlanKey = ""
+	cmd := w.executor.CommandContext(ctx, w.terraformPath, w.cli.SavedPlan(savedPlanFile)...)
+	cmd.SetEnv(w.environ(w.env))
 	cmd.SetDir(w.dir)
-	out, err := cmd.CombinedOutput()
-	w.logger.Debug("plan ended", "out", string(out))
+	out, err := w.combinedOutput(ctx, "plan", cmd)
+	w.log("plan").Debug(msgCommandEnded, "out", string(out))
 	if err != nil {
-		return PlanResult{}, tferrors.NewPlanFailed(out)
+		return PlanResult{}, w.operationFailed("plan", tferrors.NewPlanFailed(out, w.errorOptions("plan", out)...))
 	}
 	line := ""
 	for _, l := range strings.Split(string(out), "\n") {
@@ -287,14 +1027,46 @@ func (w *Workspace) Plan(ctx context.Context) (PlanResult, error) {
 		Changes struct {
 			Add    float64 `json:"add,omitempty"`
 			Change float64 `json:"change,omitempty"`
+			Remove float64 `json:"remove,omitempty"`
 		} `json:"changes,omitempty"`
 	}
 	p := &plan{}
 	if err := json.JSParser.Unmarshal([]byte(line), p); err != nil {
 		return PlanResult{}, errors.Wrap(err, "cannot unmarshal change summary json")
 	}
-	return PlanResult{
+	res := PlanResult{
 		Exists:   p.Changes.Add == 0,
 		UpToDate: p.Changes.Change == 0,
-	}, nil
+		Changes: PlanChanges{
+			Add:     int(p.Changes.Add),
+			Change:  int(p.Changes.Change),
+			Destroy: int(p.Changes.Remove),
+		},
+	}
+	if res.Changes.HasChanges() {
+		if key, err := w.refreshKey(); err == nil {
+			w.savedPlanKey = key
+		}
+	}
+	return res, nil
+}
+
+// lockExec locks execLock, counting the operation as waiting for it until it
+// is unlocked with unlockExec.
+func (w *Workspace) lockExec() {
+	atomic.AddInt32(&w.execWaiters, 1)
+	w.execLock.Lock()
+}
+
+// unlockExec unlocks execLock locked with lockExec.
+func (w *Workspace) unlockExec() {
+	w.execLock.Unlock()
+	atomic.AddInt32(&w.execWaiters, -1)
+}
+
+// busy returns whether a Terraform process runs or waits to run in the
+// workspace, or an async operation has ended but its result hasn't been
+// consumed yet.
+func (w *Workspace) busy() bool {
+	return atomic.LoadInt32(&w.execWaiters) > 0 || w.LastOperation.IsRunning() || w.LastOperation.IsEnded()
 }
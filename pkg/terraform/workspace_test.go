@@ -18,12 +18,18 @@ package terraform
 
 import (
 	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/util/wait"
 	k8sExec "k8s.io/utils/exec"
 	testingexec "k8s.io/utils/exec/testing"
 
@@ -38,7 +44,7 @@ var (
 	applyType             = "apply"
 	lineage               = "very-cool-lineage"
 	terraformVersion      = "1.0.10"
-	version               = 1
+	version               = 4
 	serial                = 3
 	directory             = "random-dir/"
 	changeSummaryAdd      = `{"@level":"info","@message":"Plan: 1 to add, 0 to change, 0 to destroy.","@module":"terraform.ui","@timestamp":"0000-00-00T00:00:00.000000+03:00","changes":{"add":1,"change":0,"remove":0,"operation":"plan"},"type":"change_summary"}`
@@ -60,7 +66,9 @@ var (
 		Fs: afero.NewMemMapFs(),
 	}
 
-	tfstate = `{"version": 1,"terraform_version": "1.0.10","serial": 3,"lineage": "very-cool-lineage","outputs": {},"resources": []}`
+	tfstate = `{"version": 4,"terraform_version": "1.0.10","serial": 3,"lineage": "very-cool-lineage","outputs": {},"resources": []}`
+
+	tfstateWithResource = `{"version": 4,"terraform_version": "1.0.10","serial": 3,"lineage": "very-cool-lineage","outputs": {},"resources": [{"mode": "managed","type": "very-cool-type","name": "very-cool-name","provider": "provider","instances": [{"schema_version": 0,"attributes": {"id": "very-cool-id"}}]}]}`
 )
 
 func newFakeExec(stdOut string, err error) *testingexec.FakeExec {
@@ -79,6 +87,95 @@ func newFakeExec(stdOut string, err error) *testingexec.FakeExec {
 	}
 }
 
+func TestWorkspaceInit(t *testing.T) {
+	transient := "Error: could not connect to registry.terraform.io: i/o timeout"
+	misconfigured := "Error: Invalid provider version constraint"
+	type args struct {
+		outputs []string
+		steps   int
+	}
+	type want struct {
+		err   error
+		calls int
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"RetriedTransientFailure": {
+			reason: "Init should be retried if it fails due to a transient failure",
+			args: args{
+				outputs: []string{transient, ""},
+				steps:   2,
+			},
+			want: want{
+				calls: 2,
+			},
+		},
+		"ConfigurationError": {
+			reason: "Init should not be retried if it fails due to a configuration error",
+			args: args{
+				outputs: []string{misconfigured, ""},
+				steps:   2,
+			},
+			want: want{
+				err:   tferrors.NewInitFailed([]byte(misconfigured)),
+				calls: 1,
+			},
+		},
+		"RetriesExhausted": {
+			reason: "Init should be retried at most as many times as the steps of the backoff",
+			args: args{
+				outputs: []string{transient, transient, transient},
+				steps:   1,
+			},
+			want: want{
+				err:   tferrors.NewInitFailed([]byte(transient)),
+				calls: 2,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := &testingexec.FakeExec{}
+			envSet := 0
+			for _, o := range tc.args.outputs {
+				o := o
+				e.CommandScript = append(e.CommandScript, func(_ string, _ ...string) k8sExec.Cmd {
+					cmd := &testingexec.FakeCmd{}
+					cmd.CombinedOutputScript = []testingexec.FakeAction{
+						func() ([]byte, []byte, error) {
+							for _, v := range cmd.Env {
+								if v == "TF_CLI_CONFIG_FILE=/terraformrc" {
+									envSet++
+								}
+							}
+							if o == "" {
+								return nil, nil, nil
+							}
+							return []byte(o), nil, errBoom
+						},
+					}
+					return cmd
+				})
+			}
+			w := NewWorkspace(directory, WithExecutor(e), WithAferoFs(afero.NewMemMapFs()), WithInitBackoff(wait.Backoff{Duration: time.Millisecond, Steps: tc.args.steps}))
+			w.env = []string{"TF_CLI_CONFIG_FILE=/terraformrc"}
+			err := w.Init(context.TODO(), "")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nInit(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.calls, e.CommandCalls); diff != "" {
+				t.Errorf("\n%s\nInit(...): -want calls, +got calls:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.calls, envSet); diff != "" {
+				t.Errorf("\n%s\nInit(...): -want calls with the workspace environment, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestWorkspaceApply(t *testing.T) {
 	type args struct {
 		w *Workspace
@@ -107,7 +204,8 @@ func TestWorkspaceApply(t *testing.T) {
 			},
 			want: want{
 				r: ApplyResult{
-					State: state,
+					State:     state,
+					Operation: OperationResult{Type: "apply"},
 				},
 			},
 		},
@@ -116,6 +214,9 @@ func TestWorkspaceApply(t *testing.T) {
 				w: NewWorkspace(directory, WithExecutor(newFakeExec(errBoom.Error(), errBoom)), WithAferoFs(fs)),
 			},
 			want: want{
+				r: ApplyResult{
+					Operation: OperationResult{Type: "apply", ExitCode: -1, OutputBytes: len(errBoom.Error())},
+				},
 				err: tferrors.NewApplyFailed([]byte(errBoom.Error())),
 			},
 		},
@@ -130,7 +231,7 @@ func TestWorkspaceApply(t *testing.T) {
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nApply(...): -want error, +got error:\n%s", name, diff)
 			}
-			if diff := cmp.Diff(tc.want.r, r, test.EquateErrors()); diff != "" {
+			if diff := cmp.Diff(tc.want.r, r, test.EquateErrors(), cmpopts.IgnoreFields(OperationResult{}, "Duration")); diff != "" {
 				t.Errorf("\n%s\nApply(...): -want error, +got error:\n%s", name, diff)
 			}
 		})
@@ -139,7 +240,8 @@ func TestWorkspaceApply(t *testing.T) {
 
 func TestWorkspaceDestroy(t *testing.T) {
 	type args struct {
-		w *Workspace
+		w     *Workspace
+		state string
 	}
 	type want struct {
 		err error
@@ -172,11 +274,34 @@ func TestWorkspaceDestroy(t *testing.T) {
 				err: tferrors.NewDestroyFailed([]byte(errBoom.Error())),
 			},
 		},
+		"VerifiedGone": {
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true}),
+					WithAferoFs(afero.NewMemMapFs()), WithDestroyVerification(true)),
+				state: tfstate,
+			},
+			want: want{},
+		},
+		"VerifiedStillExists": {
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true}),
+					WithAferoFs(afero.NewMemMapFs()), WithDestroyVerification(true)),
+				state: tfstateWithResource,
+			},
+			want: want{
+				err: errors.New(errResourceStillExists),
+			},
+		},
 	}
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			err := tc.w.Destroy(context.TODO())
+			if tc.args.state != "" {
+				if err := tc.w.fs.WriteFile(directory+"terraform.tfstate", []byte(tc.args.state), 0600); err != nil {
+					t.Fatalf("cannot write tfstate: %s", err)
+				}
+			}
+			_, err := tc.w.Destroy(context.TODO())
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nDestroy(...): -want error, +got error:\n%s", name, diff)
 			}
@@ -184,6 +309,167 @@ func TestWorkspaceDestroy(t *testing.T) {
 	}
 }
 
+// savedPlanWorkspace returns a Workspace with a saved plan whose
+// configuration is changed to the given one after the plan, if any.
+func savedPlanWorkspace(t *testing.T, mainTF string) *Workspace {
+	fs := afero.NewMemMapFs()
+	w := NewWorkspace(directory, WithAferoFs(fs))
+	for f, content := range map[string]string{"main.tf.json": `{"resource":{}}`, "terraform.tfstate": tfstate} {
+		if err := afero.WriteFile(fs, directory+f, []byte(content), 0600); err != nil {
+			t.Fatalf("cannot write %s: %s", f, err)
+		}
+	}
+	key, err := w.refreshKey()
+	if err != nil {
+		t.Fatalf("cannot compute workspace key: %s", err)
+	}
+	w.savedPlanKey = key
+	if mainTF != "" {
+		if err := afero.WriteFile(fs, directory+"main.tf.json", []byte(mainTF), 0600); err != nil {
+			t.Fatalf("cannot write main.tf.json: %s", err)
+		}
+	}
+	return w
+}
+
+func TestWorkspaceOperationArgs(t *testing.T) {
+	type want struct {
+		apply   []string
+		destroy []string
+	}
+	cases := map[string]struct {
+		reason string
+		w      *Workspace
+		want
+	}{
+		"Default": {
+			reason: "Only the flags of the commands should be used by default",
+			w:      NewWorkspace(directory),
+			want: want{
+				apply:   []string{"apply", "-auto-approve", "-input=false", "-lock=false", "-json"},
+				destroy: []string{"destroy", "-auto-approve", "-input=false", "-lock=false", "-json"},
+			},
+		},
+		"ReplaceAndParallelism": {
+			reason: "The replaced resource should be passed to apply and the parallelism to both apply and destroy",
+			w:      NewWorkspace(directory, WithReplace("aws_vpc.example"), WithParallelism(2)),
+			want: want{
+				apply:   []string{"apply", "-auto-approve", "-input=false", "-lock=false", "-json", "-replace=aws_vpc.example", "-parallelism=2"},
+				destroy: []string{"destroy", "-auto-approve", "-input=false", "-lock=false", "-json", "-parallelism=2"},
+			},
+		},
+		"SavedPlan": {
+			reason: "The saved plan should be applied if the configuration and the state have not changed since the plan",
+			w:      savedPlanWorkspace(t, ""),
+			want: want{
+				apply:   []string{"apply", "-auto-approve", "-input=false", "-lock=false", "-json", savedPlanFile},
+				destroy: []string{"destroy", "-auto-approve", "-input=false", "-lock=false", "-json"},
+			},
+		},
+		"StaleSavedPlan": {
+			reason: "The saved plan should not be applied if the configuration has changed since the plan",
+			w:      savedPlanWorkspace(t, `{"resource":{"changed":{}}}`),
+			want: want{
+				apply:   []string{"apply", "-auto-approve", "-input=false", "-lock=false", "-json"},
+				destroy: []string{"destroy", "-auto-approve", "-input=false", "-lock=false", "-json"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want.apply, tc.w.applyArgs()); diff != "" {
+				t.Errorf("\n%s\napplyArgs(): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.destroy, tc.w.destroyArgs()); diff != "" {
+				t.Errorf("\n%s\ndestroyArgs(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaceEnviron(t *testing.T) {
+	t.Setenv("TERRAJET_TEST_PATH", "/bin")
+	t.Setenv("TERRAJET_TEST_SECRET", "controller-secret")
+	cases := map[string]struct {
+		reason string
+		w      *Workspace
+		want   []string
+	}{
+		"Inherited": {
+			reason: "The whole environment of the controller should be inherited by default",
+			w:      NewWorkspace(directory),
+			want:   []string{"TERRAJET_TEST_PATH=/bin", "TERRAJET_TEST_SECRET=controller-secret", "TERRAJET_TEST_SECRET=setup-secret"},
+		},
+		"Isolated": {
+			reason: "Only the allowed variables of the controller environment should be inherited",
+			w:      NewWorkspace(directory, WithInheritedEnv("TERRAJET_TEST_PATH")),
+			want:   []string{"TERRAJET_TEST_PATH=/bin", "TERRAJET_TEST_SECRET=setup-secret"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			for _, e := range tc.w.environ([]string{"TERRAJET_TEST_SECRET=setup-secret"}) {
+				if strings.HasPrefix(e, "TERRAJET_TEST_") {
+					got = append(got, e)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("\n%s\nenviron(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaceCommandTimeout(t *testing.T) {
+	type want struct {
+		deadline bool
+		async    time.Duration
+	}
+	cases := map[string]struct {
+		reason string
+		w      *Workspace
+		want
+	}{
+		"Default": {
+			reason: "Blocking operations should not be bounded and async ones should time out after the default timeout",
+			w:      NewWorkspace(directory),
+			want: want{
+				async: defaultAsyncTimeout,
+			},
+		},
+		"Timeout": {
+			reason: "Blocking operations should be bounded by the command timeout while async ones keep the default timeout",
+			w:      NewWorkspace(directory, WithCommandTimeout(10*time.Minute)),
+			want: want{
+				deadline: true,
+				async:    defaultAsyncTimeout,
+			},
+		},
+		"AsyncTimeout": {
+			reason: "Async operations should be bounded by the async timeout",
+			w:      NewWorkspace(directory, WithCommandTimeout(10*time.Minute), WithAsyncTimeout(3*time.Hour)),
+			want: want{
+				deadline: true,
+				async:    3 * time.Hour,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := tc.w.withCommandTimeout(context.TODO())
+			defer cancel()
+			_, deadline := ctx.Deadline()
+			if diff := cmp.Diff(tc.want.deadline, deadline); diff != "" {
+				t.Errorf("\n%s\nwithCommandTimeout(...): -want deadline, +got deadline:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.async, tc.w.asyncTimeout()); diff != "" {
+				t.Errorf("\n%s\nasyncTimeout(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestWorkspaceRefresh(t *testing.T) {
 	type args struct {
 		w *Workspace
@@ -215,7 +501,8 @@ func TestWorkspaceRefresh(t *testing.T) {
 			},
 			want: want{
 				r: RefreshResult{
-					State: state,
+					State:   state,
+					Changed: true,
 				},
 			},
 		},
@@ -245,6 +532,40 @@ func TestWorkspaceRefresh(t *testing.T) {
 	}
 }
 
+func TestWorkspaceRefreshAppliedState(t *testing.T) {
+	applied := []byte(`{"id":"applied"}`)
+	cases := map[string]struct {
+		reason  string
+		applied []byte
+		want    []byte
+	}{
+		"FirstObservation": {
+			reason: "The first observed state should be used to explain the drifts if the workspace hasn't applied yet",
+			want:   state.GetAttributes(),
+		},
+		"Applied": {
+			reason:  "A refresh should not replace the state the latest apply left",
+			applied: applied,
+			want:    applied,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true}), WithAferoFs(fs))
+			w.applied = tc.applied
+			if err := w.fs.WriteFile(directory+"terraform.tfstate", []byte(tfstate), 777); err != nil {
+				panic(err)
+			}
+			if _, err := w.Refresh(context.TODO()); err != nil {
+				t.Fatalf("\n%s\nRefresh(...): unexpected error: %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(string(tc.want), string(w.applied)); diff != "" {
+				t.Errorf("\n%s\nRefresh(...): -want applied, +got applied:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestWorkspacePlan(t *testing.T) {
 	type args struct {
 		w *Workspace
@@ -282,6 +603,7 @@ func TestWorkspacePlan(t *testing.T) {
 				r: PlanResult{
 					Exists:   false,
 					UpToDate: true,
+					Changes:  PlanChanges{Add: 1},
 				},
 			},
 		},
@@ -293,6 +615,7 @@ func TestWorkspacePlan(t *testing.T) {
 				r: PlanResult{
 					Exists:   true,
 					UpToDate: false,
+					Changes:  PlanChanges{Change: 1},
 				},
 			},
 		},
@@ -439,3 +762,127 @@ func TestWorkspaceDestroyAsync(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkspaceExecutionLock(t *testing.T) {
+	const calls = 5
+	var running, maxRunning int32
+	e := &testingexec.FakeExec{}
+	for i := 0; i < calls; i++ {
+		e.CommandScript = append(e.CommandScript, func(_ string, _ ...string) k8sExec.Cmd {
+			return &testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{
+					func() ([]byte, []byte, error) {
+						n := atomic.AddInt32(&running, 1)
+						defer atomic.AddInt32(&running, -1)
+						for {
+							m := atomic.LoadInt32(&maxRunning)
+							if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+								break
+							}
+						}
+						time.Sleep(10 * time.Millisecond)
+						return []byte(changeSummaryNoAction), nil, nil
+					},
+				},
+			}
+		})
+	}
+	w := NewWorkspace(directory, WithExecutor(e))
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := w.Plan(context.TODO()); err != nil {
+				t.Errorf("Plan(...): unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxRunning != 1 {
+		t.Errorf("Plan(...): at most one terraform process should run in the workspace at a time, got %d", maxRunning)
+	}
+}
+
+func TestWorkspaceApplyTrace(t *testing.T) {
+	trace := "[DEBUG] provider: request: PUT /vpcs\n[DEBUG] provider: response: 500 Internal Server Error\n"
+	type args struct {
+		limit int
+		err   error
+	}
+	type want struct {
+		path  string
+		trace string
+		env   []string
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Failed": {
+			reason: "The tail of the debug output of a failed apply should be kept in a file its result points to",
+			args: args{
+				limit: 54,
+				err:   errBoom,
+			},
+			want: want{
+				path:  filepath.Join(directory, failedTraceFile),
+				trace: "[DEBUG] provider: response: 500 Internal Server Error\n",
+				env:   []string{"TF_LOG=debug", "TF_LOG_PATH=" + filepath.Join(directory, traceFile)},
+			},
+		},
+		"Succeeded": {
+			reason: "No trace should be kept for a successful apply",
+			args: args{
+				limit: 1024,
+			},
+			want: want{
+				env: []string{"TF_LOG=debug", "TF_LOG_PATH=" + filepath.Join(directory, traceFile)},
+			},
+		},
+		"Disabled": {
+			reason: "Terraform should not be run in debug mode if traces are not captured",
+			args: args{
+				err: errBoom,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			var env []string
+			e := &testingexec.FakeExec{
+				CommandScript: []testingexec.FakeCommandAction{
+					func(_ string, _ ...string) k8sExec.Cmd {
+						cmd := &testingexec.FakeCmd{}
+						cmd.CombinedOutputScript = []testingexec.FakeAction{
+							func() ([]byte, []byte, error) {
+								for _, v := range cmd.Env {
+									if strings.HasPrefix(v, envTFLog) {
+										env = append(env, v)
+									}
+								}
+								_ = afero.WriteFile(fs, filepath.Join(directory, traceFile), []byte(trace), 0600)
+								return nil, nil, tc.args.err
+							},
+						}
+						return cmd
+					},
+				},
+			}
+			w := NewWorkspace(directory, WithExecutor(e), WithAferoFs(fs), WithTraceCapture(tc.args.limit))
+			res, _ := w.Apply(context.TODO())
+			if diff := cmp.Diff(tc.want.path, res.Operation.TracePath); diff != "" {
+				t.Errorf("\n%s\nApply(...): -want trace path, +got trace path:\n%s", tc.reason, diff)
+			}
+			got, _ := afero.ReadFile(fs, filepath.Join(directory, failedTraceFile))
+			if diff := cmp.Diff(tc.want.trace, string(got)); diff != "" {
+				t.Errorf("\n%s\nApply(...): -want trace, +got trace:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.env, env, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nApply(...): -want env, +got env:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
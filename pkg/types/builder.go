@@ -53,6 +53,10 @@ type Builder struct {
 
 	genTypes []*types.Named
 	comments twtypes.Comments
+	// reserved holds the names given to the types that are being built. They
+	// are inserted to the package scope only once the type is generated,
+	// since a type identical to an existing one is not generated at all.
+	reserved map[string]bool
 }
 
 // NewBuilder returns a new Builder.
@@ -60,6 +64,7 @@ func NewBuilder(pkg *types.Package) *Builder {
 	return &Builder{
 		Package:  pkg,
 		comments: twtypes.Comments{},
+		reserved: map[string]bool{},
 	}
 }
 
@@ -81,7 +86,7 @@ func (g *Builder) buildResource(res *schema.Resource, cfg *config.Resource, tfPa
 	// we need to process all fields in the same order all the time.
 	keys := sortedKeys(res.Schema)
 
-	typeNames, err := NewTypeNames(names, g.Package)
+	typeNames, err := g.newTypeNames(names)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -118,14 +123,20 @@ func (g *Builder) buildResource(res *schema.Resource, cfg *config.Resource, tfPa
 		}
 
 		f.AddToResource(g, r, typeNames)
+		if _, ok := cfg.ObservationListCaps[snakeFieldName]; ok && len(tfPath) == 0 && isObservation(f.Schema) {
+			r.addObservationCountField(g, f, typeNames)
+		}
 	}
 
-	paramType, obsType := g.AddToBuilder(typeNames, r)
+	paramType, obsType := g.AddToBuilder(typeNames, r, names...)
 	return paramType, obsType, nil
 }
 
-// AddToBuilder adds fields to the Builder.
-func (g *Builder) AddToBuilder(typeNames *TypeNames, r *resource) (*types.Named, *types.Named) {
+// AddToBuilder adds fields to the Builder. If a type with the same
+// structure has already been generated for a field with the same name, e.g.
+// the same nested block in another resource of the package, that type is
+// returned instead of generating a duplicate one.
+func (g *Builder) AddToBuilder(typeNames *TypeNames, r *resource, names ...string) (*types.Named, *types.Named) {
 	// NOTE(muvaf): Not every struct has both computed and configurable fields,
 	// so some types we generate here are empty and unnecessary. However,
 	// there are valid types with zero fields and we don't have the information
@@ -133,13 +144,28 @@ func (g *Builder) AddToBuilder(typeNames *TypeNames, r *resource) (*types.Named,
 	// two structs for every complex type.
 	// See usage of wafv2EmptySchema() in aws_wafv2_web_acl here:
 	// https://github.com/hashicorp/terraform-provider-aws/blob/main/aws/wafv2_helper.go#L13
-	paramType := types.NewNamed(typeNames.ParameterTypeName, types.NewStruct(r.paramFields, r.paramTags), nil)
-	g.genTypes = append(g.genTypes, paramType)
-
-	obsType := types.NewNamed(typeNames.ObservationTypeName, types.NewStruct(r.obsFields, r.obsTags), nil)
-	g.genTypes = append(g.genTypes, obsType)
+	return g.namedType(typeNames.ParameterTypeName, types.NewStruct(r.paramFields, r.paramTags), "Parameters", names...),
+		g.namedType(typeNames.ObservationTypeName, types.NewStruct(r.obsFields, r.obsTags), "Observation", names...)
+}
 
-	return paramType, obsType
+func (g *Builder) namedType(tn *types.TypeName, st *types.Struct, suffix string, names ...string) *types.Named {
+	delete(g.reserved, tn.Name())
+	if t := identicalType(g.Package, st, suffix, names...); t != nil {
+		// The name is released, so the comments added for the fields of the
+		// discarded type should not end up in another type given that name.
+		prefix := twtypes.QualifiedFieldPath(tn, "")
+		for k := range g.comments {
+			if strings.HasPrefix(k, prefix) {
+				delete(g.comments, k)
+			}
+		}
+		delete(g.comments, twtypes.QualifiedTypePath(tn))
+		return t
+	}
+	g.Package.Scope().Insert(tn)
+	t := types.NewNamed(tn, st, nil)
+	g.genTypes = append(g.genTypes, t)
+	return t
 }
 
 func (g *Builder) buildSchema(f *Field, cfg *config.Resource, names []string, r *resource) (types.Type, error) { // nolint:gocyclo
@@ -242,24 +268,27 @@ type TypeNames struct {
 	ObservationTypeName *types.TypeName
 }
 
-// NewTypeNames returns a new TypeNames object.
-func NewTypeNames(fieldPaths []string, pkg *types.Package) (*TypeNames, error) {
-	paramTypeName, err := generateTypeName("Parameters", pkg, fieldPaths...)
+// newTypeNames returns a new TypeNames object.
+func (g *Builder) newTypeNames(fieldPaths []string) (*TypeNames, error) {
+	if g.reserved == nil {
+		g.reserved = map[string]bool{}
+	}
+	paramTypeName, err := generateTypeName("Parameters", g.Package, g.reserved, fieldPaths...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot generate parameters type name of %s", fieldPath(fieldPaths))
 	}
-	paramName := types.NewTypeName(token.NoPos, pkg, paramTypeName, nil)
+	paramName := types.NewTypeName(token.NoPos, g.Package, paramTypeName, nil)
 
-	obsTypeName, err := generateTypeName("Observation", pkg, fieldPaths...)
+	obsTypeName, err := generateTypeName("Observation", g.Package, g.reserved, fieldPaths...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot generate observation type name of %s", fieldPath(fieldPaths))
 	}
-	obsName := types.NewTypeName(token.NoPos, pkg, obsTypeName, nil)
+	obsName := types.NewTypeName(token.NoPos, g.Package, obsTypeName, nil)
 
-	// We insert them to the package scope so that the type name calculations in
-	// recursive calls are checked against their upper level type's name as well.
-	pkg.Scope().Insert(paramName)
-	pkg.Scope().Insert(obsName)
+	// We reserve them so that the type name calculations in recursive calls
+	// are checked against their upper level type's name as well.
+	g.reserved[paramTypeName] = true
+	g.reserved[obsTypeName] = true
 
 	return &TypeNames{ParameterTypeName: paramName, ObservationTypeName: obsName}, nil
 }
@@ -288,6 +317,16 @@ func (r *resource) addObservationField(f *Field, field *types.Var) {
 	r.obsTags = append(r.obsTags, fmt.Sprintf(`json:"%s" tf:"%s"`, f.JSONTag, f.TFTag))
 }
 
+// addObservationCountField adds a field that reports the total number of
+// elements of the given observation field whose elements are capped.
+func (r *resource) addObservationCountField(g *Builder, f *Field, typeNames *TypeNames) {
+	n := f.FieldNameCamel + "Count"
+	field := types.NewField(token.NoPos, g.Package, n, types.NewPointer(types.Universe.Lookup("int64").Type()), false)
+	r.obsFields = append(r.obsFields, field)
+	r.obsTags = append(r.obsTags, fmt.Sprintf(`json:"%sCount,omitempty" tf:"%s_count,omitempty"`, f.Name.LowerCamelComputed, f.Name.Snake))
+	g.comments.AddFieldComment(typeNames.ObservationTypeName, n, fmt.Sprintf("// %s is the total number of elements of %s, which keeps only\n// a limited number of them.\n", n, f.FieldNameCamel))
+}
+
 func (r *resource) addReferenceFields(g *Builder, paramName *types.TypeName, field *types.Var, ref config.Reference) {
 	refFields, refTags := g.generateReferenceFields(paramName, field, ref)
 	r.paramTags = append(r.paramTags, refTags...)
@@ -296,27 +335,62 @@ func (r *resource) addReferenceFields(g *Builder, paramName *types.TypeName, fie
 
 // generateTypeName generates a unique name for the type if its original name
 // is used by another one. It adds the former field names recursively until it
-// finds a unique name.
-func generateTypeName(suffix string, pkg *types.Package, names ...string) (string, error) {
-	n := names[len(names)-1] + suffix
-	for i := len(names) - 2; i >= 0; i-- {
-		if pkg.Scope().Lookup(n) == nil {
+// finds a unique name that is neither in the package scope nor reserved.
+func generateTypeName(suffix string, pkg *types.Package, reserved map[string]bool, names ...string) (string, error) {
+	candidates := typeNameCandidates(suffix, names...)
+	for _, n := range candidates {
+		if pkg.Scope().Lookup(n) == nil && !reserved[n] {
 			return n, nil
 		}
-		n = names[i] + n
 	}
-	if pkg.Scope().Lookup(n) == nil {
-		return n, nil
+	return "", errors.Errorf("could not generate a unique name for %s", candidates[len(names)-1])
+}
+
+// typeNameCandidates returns the names that can be given to the type in the
+// order of preference. The field name is prefixed with the names of its
+// parents one by one and once they are exhausted, an index is appended.
+func typeNameCandidates(suffix string, names ...string) []string {
+	n := names[len(names)-1] + suffix
+	candidates := []string{n}
+	for i := len(names) - 2; i >= 0; i-- {
+		n = names[i] + n
+		candidates = append(candidates, n)
 	}
 	// start from 2 considering the 1st of this type is the one without an
 	// index.
 	for i := 2; i < 10; i++ {
-		nn := fmt.Sprintf("%s_%d", n, i)
-		if pkg.Scope().Lookup(nn) == nil {
-			return nn, nil
+		candidates = append(candidates, fmt.Sprintf("%s_%d", n, i))
+	}
+	return candidates
+}
+
+// identicalType returns an already generated type that could have been given
+// the same name as the type of given nested field and has exactly the same
+// structure, including the field tags. If there are more than one, the one
+// with the lexicographically smallest name is returned so that the result does
+// not depend on the order of the candidates.
+func identicalType(pkg *types.Package, st *types.Struct, suffix string, names ...string) *types.Named {
+	// Top level types are referred by name in the templates and need to
+	// exist.
+	if len(names) < 2 {
+		return nil
+	}
+	candidates := typeNameCandidates(suffix, names...)
+	sort.Strings(candidates)
+	for _, n := range candidates {
+		o := pkg.Scope().Lookup(n)
+		if o == nil {
+			continue
+		}
+		named, ok := o.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		if types.Identical(named.Underlying(), st) {
+			return named
 		}
 	}
-	return "", errors.Errorf("could not generate a unique name for %s", n)
+	return nil
 }
 
 func isObservation(s *schema.Schema) bool {
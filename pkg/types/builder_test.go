@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"go/token"
 	"go/types"
+	"strings"
 	"testing"
 
 	"github.com/crossplane/crossplane-runtime/pkg/test"
@@ -192,7 +193,7 @@ func TestBuilder_generateTypeName(t *testing.T) {
 			g := &Builder{
 				Package: p,
 			}
-			got, gotErr := generateTypeName(tc.args.suffix, g.Package, tc.args.names...)
+			got, gotErr := generateTypeName(tc.args.suffix, g.Package, nil, tc.args.names...)
 			if diff := cmp.Diff(tc.want.err, gotErr, test.EquateErrors()); diff != "" {
 				t.Fatalf("generateTypeName(...): -want error, +got error: %s", diff)
 			}
@@ -358,6 +359,30 @@ func TestBuild(t *testing.T) {
 				atProvider:  `type example.Observation struct{}`,
 			},
 		},
+		"Capped_Observation_List": {
+			args: args{
+				cfg: &config.Resource{
+					TerraformResource: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"rules": {
+								Type:     schema.TypeList,
+								Computed: true,
+								Elem: &schema.Schema{
+									Type: schema.TypeString,
+								},
+							},
+						},
+					},
+					ObservationListCaps: map[string]int{
+						"rules": 10,
+					},
+				},
+			},
+			want: want{
+				forProvider: `type example.Parameters struct{}`,
+				atProvider:  `type example.Observation struct{Rules []*string "json:\"rules,omitempty\" tf:\"rules,omitempty\""; RulesCount *int64 "json:\"rulesCount,omitempty\" tf:\"rules_count,omitempty\""}`,
+			},
+		},
 		"Invalid_Schema_Type": {
 			args: args{
 				cfg: &config.Resource{
@@ -397,3 +422,88 @@ func TestBuild(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildIdenticalNestedTypes(t *testing.T) {
+	nested := func(elem map[string]*schema.Schema) *schema.Resource {
+		return &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"rule": {
+					Type:     schema.TypeList,
+					Optional: true,
+					Elem:     &schema.Resource{Schema: elem},
+				},
+			},
+		}
+	}
+	cases := map[string]struct {
+		reason string
+		first  *schema.Resource
+		second *schema.Resource
+		want   string
+		// scope is whether SecondRuleParameters should be in the package
+		// scope after the build.
+		scope bool
+	}{
+		"Identical": {
+			reason: "A nested type identical to one generated for another resource should be reused",
+			first:  nested(map[string]*schema.Schema{"port": {Type: schema.TypeInt, Optional: true}}),
+			second: nested(map[string]*schema.Schema{"port": {Type: schema.TypeInt, Optional: true}}),
+			want:   `type example.SecondParameters struct{Rule []example.RuleParameters "json:\"rule,omitempty\" tf:\"rule,omitempty\""}`,
+		},
+		"Different": {
+			reason: "A nested type different from the one generated for another resource should be prefixed with its parent",
+			first:  nested(map[string]*schema.Schema{"port": {Type: schema.TypeInt, Optional: true}}),
+			second: nested(map[string]*schema.Schema{"protocol": {Type: schema.TypeString, Optional: true}}),
+			want:   `type example.SecondParameters struct{Rule []example.SecondRuleParameters "json:\"rule,omitempty\" tf:\"rule,omitempty\""}`,
+			scope:  true,
+		},
+	}
+	for n, tc := range cases {
+		t.Run(n, func(t *testing.T) {
+			pkg := types.NewPackage("example", "")
+			if _, err := NewBuilder(pkg).Build(&config.Resource{Kind: "First", TerraformResource: tc.first}); err != nil {
+				t.Fatalf("Build(...): unexpected error: %s", err)
+			}
+			g, err := NewBuilder(pkg).Build(&config.Resource{Kind: "Second", TerraformResource: tc.second})
+			if err != nil {
+				t.Fatalf("Build(...): unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(tc.want, g.ForProviderType.Obj().String()); diff != "" {
+				t.Errorf("\n%s\nBuild(...): -want forProvider, +got forProvider:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.scope, pkg.Scope().Lookup("SecondRuleParameters") != nil); diff != "" {
+				t.Errorf("\n%s\nBuild(...): -want SecondRuleParameters in scope, +got SecondRuleParameters in scope:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestBuildImmutableFields(t *testing.T) {
+	res := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {Type: schema.TypeString, Required: true, ForceNew: true},
+			"rule": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+					"port": {Type: schema.TypeInt, Optional: true, ForceNew: true},
+				}},
+			},
+		},
+	}
+	g, err := NewBuilder(types.NewPackage("example", "")).Build(&config.Resource{Kind: "Example", TerraformResource: res})
+	if err != nil {
+		t.Fatalf("Build(...): unexpected error: %s", err)
+	}
+	var immutable []string
+	for path, c := range g.Comments {
+		if strings.Contains(c, "self == oldSelf") {
+			immutable = append(immutable, path[strings.LastIndex(path, ".")+1:])
+		}
+	}
+	// The transition rule cannot be evaluated for the fields of the list
+	// elements, so only the top-level field should be immutable.
+	if diff := cmp.Diff([]string{"ExampleParameters:Name"}, immutable); diff != "" {
+		t.Errorf("Build(...): -want immutable fields, +got immutable fields:\n%s", diff)
+	}
+}
@@ -1,10 +1,13 @@
 package types
 
 import (
+	"encoding/json"
 	"fmt"
 	"go/token"
 	"go/types"
+	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/pkg/errors"
@@ -41,6 +44,28 @@ func NewField(g *Builder, cfg *config.Resource, r *resource, sch *schema.Schema,
 		return nil, errors.Wrapf(err, "cannot build comment for description: %s", f.Schema.Description)
 	}
 	f.Comment = comment
+	// Terraform uses the default value in the schema if the argument is not
+	// set. We make the API server do the same so that the object users see
+	// reflects the value Terraform will actually use.
+	if sch.Default != nil && !isObservation(sch) {
+		d, err := json.Marshal(sch.Default)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot marshal default value of field %s", snakeFieldName)
+		}
+		def := string(d)
+		f.Comment.KubebuilderOptions.Default = &def
+	}
+	if !isObservation(sch) {
+		f.Comment.KubebuilderOptions.Enum = EnumValues(sch)
+	}
+	// Changing a ForceNew argument makes Terraform destroy and recreate the
+	// resource, so we reject such updates at admission instead. The
+	// transition rule is evaluated only if the old value of the field can be
+	// correlated, which is not the case for the fields of list elements, so
+	// it is emitted only for the fields that are not nested in a list.
+	if sch.ForceNew && !isObservation(sch) && !inList(tfPath) {
+		f.Comment.KubebuilderOptions.Immutable = true
+	}
 	f.TFTag = fmt.Sprintf("%s,omitempty", f.Name.Snake)
 	f.JSONTag = fmt.Sprintf("%s,omitempty", f.Name.LowerCamelComputed)
 
@@ -71,6 +96,63 @@ func NewField(g *Builder, cfg *config.Resource, r *resource, sch *schema.Schema,
 	return f, nil
 }
 
+// inList returns whether the field with the given parent Terraform path is
+// nested in the elements of a list or a set.
+func inList(tfPath []string) bool {
+	for _, p := range tfPath {
+		if p == wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// enumProbe is a value that no string validation is expected to accept.
+const enumProbe = "\x00terrajet-enum-probe"
+
+var stringInSliceErrRegex = regexp.MustCompile(`^expected probe to be one of \[(.*)\], got ` + regexp.QuoteMeta(enumProbe) + `$`)
+
+// EnumValues returns the accepted values of the given string schema if it is
+// validated by validation.StringInSlice. Since validation functions are
+// opaque, the values are extracted from the error returned for a value that
+// cannot be accepted and then verified by validating each of them. Nothing is
+// returned if the validation is case-insensitive since an enum would reject
+// values Terraform accepts.
+func EnumValues(sch *schema.Schema) []string {
+	if sch.Type != schema.TypeString || sch.ValidateFunc == nil {
+		return nil
+	}
+	_, errs := sch.ValidateFunc(enumProbe, "probe")
+	if len(errs) != 1 {
+		return nil
+	}
+	m := stringInSliceErrRegex.FindStringSubmatch(errs[0].Error())
+	if m == nil || m[1] == "" {
+		return nil
+	}
+	values := strings.Split(m[1], " ")
+	for _, v := range values {
+		if _, errs := sch.ValidateFunc(v, "probe"); len(errs) != 0 {
+			return nil
+		}
+		if swapped := swapCase(v); swapped != v {
+			if _, errs := sch.ValidateFunc(swapped, "probe"); len(errs) == 0 {
+				return nil
+			}
+		}
+	}
+	return values
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
 // NewSensitiveField returns a constructed sensitive Field object.
 func NewSensitiveField(g *Builder, cfg *config.Resource, r *resource, sch *schema.Schema, snakeFieldName string, tfPath, xpPath, names []string, asBlocksMode bool) (*Field, bool, error) { //nolint:gocyclo
 	f, err := NewField(g, cfg, r, sch, snakeFieldName, tfPath, xpPath, names, asBlocksMode)
@@ -95,6 +177,9 @@ func NewSensitiveField(g *Builder, cfg *config.Resource, r *resource, sch *schem
 	// If it is an observation field, it will be dropped.
 	// Data will be loaded from the referenced secret key.
 	f.FieldNameCamel += sfx
+	// Default values and enums cannot be expressed as secret key selectors.
+	f.Comment.KubebuilderOptions.Default = nil
+	f.Comment.KubebuilderOptions.Enum = nil
 
 	f.TFTag = "-"
 	switch f.FieldType.String() {
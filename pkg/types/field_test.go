@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func TestEnumValues(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		sch    *schema.Schema
+		want   []string
+	}{
+		"StringInSlice": {
+			reason: "Values accepted by StringInSlice should be returned",
+			sch: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringInSlice([]string{"default", "dedicated", "host"}, false),
+			},
+			want: []string{"default", "dedicated", "host"},
+		},
+		"IgnoreCase": {
+			reason: "No values should be returned if the validation is case-insensitive",
+			sch: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringInSlice([]string{"ipv4", "dualstack"}, true),
+			},
+		},
+		"ValueWithSpace": {
+			reason: "No values should be returned if they cannot be extracted reliably",
+			sch: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringInSlice([]string{"a value", "other"}, false),
+			},
+		},
+		"OtherValidation": {
+			reason: "No values should be returned for other validations",
+			sch: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringLenBetween(1, 10),
+			},
+		},
+		"NotString": {
+			reason: "No values should be returned for non-string fields",
+			sch: &schema.Schema{
+				Type:         schema.TypeInt,
+				ValidateFunc: validation.IntBetween(1, 10),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := EnumValues(tc.sch)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nEnumValues(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -29,8 +29,8 @@ func (o CrossplaneOptions) String() string {
 	if o.Type != "" {
 		m += fmt.Sprintf("%s%s\n", markerPrefixRefType, o.Type)
 	}
-	if o.Extractor != "" {
-		m += fmt.Sprintf("%s%s\n", markerPrefixRefExtractor, o.Extractor)
+	if e := o.ExtractorFunc(); e != "" {
+		m += fmt.Sprintf("%s%s\n", markerPrefixRefExtractor, e)
 	}
 	if o.RefFieldName != "" {
 		m += fmt.Sprintf("%s%s\n", markerPrefixRefFieldName, o.RefFieldName)
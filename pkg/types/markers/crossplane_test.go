@@ -14,6 +14,8 @@ func TestCrossplaneOptions_String(t *testing.T) {
 		referenceExtractor         string
 		referenceFieldName         string
 		referenceSelectorFieldName string
+		extractorFieldPath         string
+		extractFromObservation     bool
 	}
 	type want struct {
 		out string
@@ -38,6 +40,30 @@ func TestCrossplaneOptions_String(t *testing.T) {
 				out: "+crossplane:generate:reference:type=SecurityGroup\n",
 			},
 		},
+		"WithExtractorFieldPath": {
+			args: args{
+				referenceToType:        "Role",
+				extractorFieldPath:     "arn",
+				extractFromObservation: true,
+			},
+			want: want{
+				out: `+crossplane:generate:reference:type=Role
++crossplane:generate:reference:extractor=github.com/crossplane/terrajet/pkg/resource.ExtractParamPath("arn",true)
+`,
+			},
+		},
+		"WithResourceIDExtractor": {
+			args: args{
+				referenceToType:        "Role",
+				extractorFieldPath:     "id",
+				extractFromObservation: true,
+			},
+			want: want{
+				out: `+crossplane:generate:reference:type=Role
++crossplane:generate:reference:extractor=github.com/crossplane/terrajet/pkg/resource.ExtractResourceID()
+`,
+			},
+		},
 		"WithAll": {
 			args: args{
 				referenceToType:            "github.com/crossplane/provider-aws/apis/ec2/v1beta1.Subnet",
@@ -62,6 +88,9 @@ func TestCrossplaneOptions_String(t *testing.T) {
 					Extractor:         tc.referenceExtractor,
 					RefFieldName:      tc.referenceFieldName,
 					SelectorFieldName: tc.referenceSelectorFieldName,
+
+					ExtractorFieldPath:     tc.extractorFieldPath,
+					ExtractFromObservation: tc.extractFromObservation,
 				},
 			}
 			got := o.String()
@@ -1,6 +1,13 @@
 package markers
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var enumValueRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._/-]*$`)
 
 // KubebuilderOptions represents the kubebuilder options that terrajet would
 // need to control
@@ -8,6 +15,13 @@ type KubebuilderOptions struct {
 	Required *bool
 	Minimum  *int
 	Maximum  *int
+	// Default is the JSON representation of the default value of the field.
+	Default *string
+	// Immutable makes the API server reject updates that change the value of
+	// the field once it is set.
+	Immutable bool
+	// Enum is the list of values the field accepts.
+	Enum []string
 }
 
 func (o KubebuilderOptions) String() string {
@@ -26,6 +40,24 @@ func (o KubebuilderOptions) String() string {
 	if o.Maximum != nil {
 		m += fmt.Sprintf("+kubebuilder:validation:Maximum=%d\n", *o.Maximum)
 	}
+	if o.Default != nil {
+		m += fmt.Sprintf("+kubebuilder:default=%s\n", *o.Default)
+	}
+	if len(o.Enum) != 0 {
+		values := make([]string, len(o.Enum))
+		for i, v := range o.Enum {
+			// Values that could be parsed as something other than a string
+			// by controller-gen need to be quoted.
+			values[i] = v
+			if !enumValueRegex.MatchString(v) {
+				values[i] = strconv.Quote(v)
+			}
+		}
+		m += fmt.Sprintf("+kubebuilder:validation:Enum=%s\n", strings.Join(values, ";"))
+	}
+	if o.Immutable {
+		m += "+kubebuilder:validation:XValidation:rule=\"self == oldSelf\",message=\"Value is immutable\"\n"
+	}
 
 	return m
 }
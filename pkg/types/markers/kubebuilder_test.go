@@ -11,11 +11,15 @@ func TestKubebuilderOptions_String(t *testing.T) {
 	optional := false
 	min := 1
 	max := 3
+	def := `"us-east-1"`
 
 	type args struct {
-		required *bool
-		minimum  *int
-		maximum  *int
+		required  *bool
+		minimum   *int
+		maximum   *int
+		def       *string
+		immutable bool
+		enum      []string
 	}
 	type want struct {
 		out string
@@ -48,6 +52,39 @@ func TestKubebuilderOptions_String(t *testing.T) {
 				out: `+kubebuilder:validation:Optional
 +kubebuilder:validation:Minimum=1
 +kubebuilder:validation:Maximum=3
+`,
+			},
+		},
+		"OptionalWithDefault": {
+			args: args{
+				required: &optional,
+				def:      &def,
+			},
+			want: want{
+				out: `+kubebuilder:validation:Optional
++kubebuilder:default="us-east-1"
+`,
+			},
+		},
+		"Immutable": {
+			args: args{
+				required:  &required,
+				immutable: true,
+			},
+			want: want{
+				out: `+kubebuilder:validation:Required
++kubebuilder:validation:XValidation:rule="self == oldSelf",message="Value is immutable"
+`,
+			},
+		},
+		"Enum": {
+			args: args{
+				required: &optional,
+				enum:     []string{"default", "dedicated", "1", "with space"},
+			},
+			want: want{
+				out: `+kubebuilder:validation:Optional
++kubebuilder:validation:Enum=default;dedicated;"1";"with space"
 `,
 			},
 		},
@@ -55,9 +92,12 @@ func TestKubebuilderOptions_String(t *testing.T) {
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
 			o := KubebuilderOptions{
-				Required: tc.required,
-				Minimum:  tc.minimum,
-				Maximum:  tc.maximum,
+				Required:  tc.required,
+				Minimum:   tc.minimum,
+				Maximum:   tc.maximum,
+				Default:   tc.def,
+				Immutable: tc.immutable,
+				Enum:      tc.enum,
 			}
 			got := o.String()
 			if diff := cmp.Diff(tc.want.out, got); diff != "" {